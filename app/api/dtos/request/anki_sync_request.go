@@ -0,0 +1,65 @@
+package request
+
+import "github.com/felipesantos/anki-backend/core/interfaces/primary"
+
+// AnkiSyncHeader is the JSON envelope Anki clients send in the anki-sync header of every sync request
+type AnkiSyncHeader struct {
+	Version       int    `json:"v"`
+	HostKey       string `json:"k"`
+	ClientVersion string `json:"c"`
+	SessionKey    string `json:"s"`
+}
+
+// AnkiSyncHostKeyRequest represents the hostKey payload
+type AnkiSyncHostKeyRequest struct {
+	Username string `json:"u"`
+	Password string `json:"p"`
+}
+
+// AnkiSyncMetaRequest represents the meta payload
+type AnkiSyncMetaRequest struct {
+	Version       int    `json:"v"`
+	ClientVersion string `json:"cv"`
+}
+
+// AnkiSyncStartRequest represents the start payload
+type AnkiSyncStartRequest struct {
+	MinUSN       int                     `json:"minUsn"`
+	LocalIsNewer bool                    `json:"lnewer"`
+	Graves       *primary.AnkiSyncGraves `json:"graves"`
+}
+
+// AnkiSyncApplyGravesRequest represents the applyGraves payload
+type AnkiSyncApplyGravesRequest struct {
+	Chunk primary.AnkiSyncGraves `json:"chunk"`
+}
+
+// AnkiSyncApplyChangesRequest represents the applyChanges payload
+type AnkiSyncApplyChangesRequest struct {
+	Changes primary.AnkiSyncChanges `json:"changes"`
+}
+
+// AnkiSyncApplyChunkRequest represents the applyChunk payload
+type AnkiSyncApplyChunkRequest struct {
+	Chunk primary.AnkiSyncChunk `json:"chunk"`
+}
+
+// AnkiSyncSanityCheckRequest represents the sanityCheck2 payload
+type AnkiSyncSanityCheckRequest struct {
+	Client primary.AnkiSyncCounts `json:"client"`
+}
+
+// AnkiMediaChangesRequest represents the mediaChanges payload
+type AnkiMediaChangesRequest struct {
+	LastUSN int `json:"lastUsn"`
+}
+
+// AnkiMediaDownloadRequest represents the downloadFiles payload
+type AnkiMediaDownloadRequest struct {
+	Files []string `json:"files"`
+}
+
+// AnkiMediaSanityRequest represents the mediaSanity payload
+type AnkiMediaSanityRequest struct {
+	Local int `json:"local"`
+}
@@ -0,0 +1,20 @@
+package request
+
+// CustomStudyRequest represents the request payload to start a custom study session
+// @Description Request payload for custom study: extend today's limits or build a temporary filtered deck
+type CustomStudyRequest struct {
+	// Mode of the session (new_limit, review_limit, forgotten, review_ahead, preview_new, cram)
+	Mode string `json:"mode" example:"forgotten" validate:"required,oneof=new_limit review_limit forgotten review_ahead preview_new cram"`
+
+	// Cards added to today's limit (may be negative), number of days, or cards to cram depending on the mode
+	Amount int `json:"amount" example:"7" validate:"required"`
+
+	// Cards to cram: new, due, review or all (cram only, defaults to due)
+	CardState string `json:"card_state,omitempty" example:"due" validate:"omitempty,oneof=new due review all"`
+
+	// Cram only cards with any of these tags
+	TagsInclude []string `json:"tags_include,omitempty" example:"verbs"`
+
+	// Never cram cards with these tags
+	TagsExclude []string `json:"tags_exclude,omitempty" example:"leech"`
+}
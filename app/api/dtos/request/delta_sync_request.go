@@ -0,0 +1,74 @@
+package request
+
+import "time"
+
+// SyncChangesRequest represents the query parameters for fetching changes
+type SyncChangesRequest struct {
+	// USN returned by the previous fetch; omit to fetch every object
+	Since *int `query:"since" validate:"omitempty,min=0"`
+
+	// Client whose last sync USN is recorded (optional)
+	ClientID string `query:"client_id"`
+}
+
+// SyncPushRequest represents the request payload to push client changes
+type SyncPushRequest struct {
+	NoteTypes []SyncNoteTypeChangeRequest `json:"note_types" validate:"dive"`
+	Decks     []SyncDeckChangeRequest     `json:"decks" validate:"dive"`
+	Notes     []SyncNoteChangeRequest     `json:"notes" validate:"dive"`
+	Cards     []SyncCardChangeRequest     `json:"cards" validate:"dive"`
+	Reviews   []SyncReviewChangeRequest   `json:"reviews" validate:"dive"`
+}
+
+// SyncNoteTypeChangeRequest represents a note type created, updated or deleted by a client
+type SyncNoteTypeChangeRequest struct {
+	Action        string    `json:"action" example:"update" validate:"required,oneof=create update delete"`
+	ID            int64     `json:"id,omitempty" example:"1" validate:"required_unless=Action create"`
+	ClientRef     string    `json:"client_ref,omitempty" example:"tmp-1"`
+	Name          string    `json:"name,omitempty" example:"Basic"`
+	FieldsJSON    string    `json:"fields_json,omitempty"`
+	CardTypesJSON string    `json:"card_types_json,omitempty"`
+	TemplatesJSON string    `json:"templates_json,omitempty"`
+	ModifiedAt    time.Time `json:"modified_at" validate:"required"`
+}
+
+// SyncDeckChangeRequest represents a deck created, updated or deleted by a client
+type SyncDeckChangeRequest struct {
+	Action      string    `json:"action" example:"create" validate:"required,oneof=create update delete"`
+	ID          int64     `json:"id,omitempty" example:"1" validate:"required_unless=Action create"`
+	ClientRef   string    `json:"client_ref,omitempty" example:"tmp-1"`
+	Name        string    `json:"name,omitempty" example:"Idiomas::Inglês"`
+	ParentID    *int64    `json:"parent_id,omitempty" example:"1"`
+	OptionsJSON string    `json:"options_json,omitempty" example:"{}"`
+	ModifiedAt  time.Time `json:"modified_at" validate:"required"`
+}
+
+// SyncNoteChangeRequest represents a note created, updated or deleted by a client
+type SyncNoteChangeRequest struct {
+	Action     string    `json:"action" example:"update" validate:"required,oneof=create update delete"`
+	ID         int64     `json:"id,omitempty" example:"1" validate:"required_unless=Action create"`
+	ClientRef  string    `json:"client_ref,omitempty" example:"tmp-1"`
+	NoteTypeID int64     `json:"note_type_id,omitempty" example:"1"`
+	DeckID     int64     `json:"deck_id,omitempty" example:"1"`
+	FieldsJSON string    `json:"fields_json,omitempty" example:"{\"Front\": \"cat\", \"Back\": \"gato\"}"`
+	Tags       []string  `json:"tags,omitempty"`
+	ModifiedAt time.Time `json:"modified_at" validate:"required"`
+}
+
+// SyncCardChangeRequest represents a card flag, suspension or burial change by a client
+type SyncCardChangeRequest struct {
+	ID         int64     `json:"id" example:"1" validate:"required"`
+	Flag       *int      `json:"flag,omitempty" example:"1" validate:"omitempty,min=0,max=7"`
+	Suspended  *bool     `json:"suspended,omitempty"`
+	Buried     *bool     `json:"buried,omitempty"`
+	ModifiedAt time.Time `json:"modified_at" validate:"required"`
+}
+
+// SyncReviewChangeRequest represents an answer a client recorded
+type SyncReviewChangeRequest struct {
+	ClientRef  string    `json:"client_ref,omitempty" example:"review-1"`
+	CardID     int64     `json:"card_id" example:"1" validate:"required"`
+	Rating     int       `json:"rating" example:"3" validate:"required,min=1,max=4"`
+	TimeMs     int       `json:"time_ms" example:"5000" validate:"min=0"`
+	ReviewedAt time.Time `json:"reviewed_at" validate:"required"`
+}
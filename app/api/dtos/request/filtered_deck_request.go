@@ -9,11 +9,17 @@ type CreateFilteredDeckRequest struct {
 	// Search filter string (e.g., "tag:marked" or "is:due")
 	SearchFilter string `json:"search_filter" example:"is:due" validate:"required"`
 
+	// Optional second search, gathering up to SecondLimit more cards
+	SecondFilter *string `json:"second_filter,omitempty" example:"is:new"`
+
 	// Maximum number of cards to include
 	Limit int `json:"limit" example:"100" validate:"required,min=1"`
 
+	// Maximum number of cards gathered by the second search; defaults to Limit
+	SecondLimit int `json:"second_limit,omitempty" example:"20" validate:"omitempty,min=1"`
+
 	// Order by criteria (e.g., "random", "added_desc")
-	OrderBy string `json:"order_by" example:"random" validate:"required"`
+	OrderBy string `json:"order_by" example:"random" validate:"required,oneof=oldest_seen random interval_asc interval_desc lapses added due added_desc relative_overdueness"`
 
 	// Whether to reschedule cards based on reviews in this deck
 	Reschedule bool `json:"reschedule" example:"true"`
@@ -28,11 +34,17 @@ type UpdateFilteredDeckRequest struct {
 	// New search filter string
 	SearchFilter string `json:"search_filter" example:"is:due tag:importante" validate:"required"`
 
+	// New second search; omit to remove it
+	SecondFilter *string `json:"second_filter,omitempty" example:"is:new"`
+
 	// New maximum number of cards
 	Limit int `json:"limit" example:"50" validate:"required,min=1"`
 
+	// New maximum number of cards gathered by the second search; defaults to Limit
+	SecondLimit int `json:"second_limit,omitempty" example:"20" validate:"omitempty,min=1"`
+
 	// New order by criteria
-	OrderBy string `json:"order_by" example:"added_desc" validate:"required"`
+	OrderBy string `json:"order_by" example:"added_desc" validate:"required,oneof=oldest_seen random interval_asc interval_desc lapses added due added_desc relative_overdueness"`
 
 	// New reschedule setting
 	Reschedule bool `json:"reschedule" example:"false"`
@@ -0,0 +1,8 @@
+package request
+
+// FSRSOptimizeRequest represents the request payload to optimize or evaluate a preset's FSRS parameters
+// @Description Request payload for fitting FSRS parameters to the review history
+type FSRSOptimizeRequest struct {
+	// IDs of the decks using the preset, whose reviews are used; on optimize they also receive the new parameters
+	DeckIDs []int64 `json:"deck_ids" validate:"required,min=1" example:"[1, 2]"`
+}
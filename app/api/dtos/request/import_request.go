@@ -0,0 +1,47 @@
+package request
+
+// ImportPackageRequest represents the form fields sent with an imported package
+type ImportPackageRequest struct {
+	// How notes whose GUID matches an existing note are handled: "skip", "if_newer" (default) or "always"
+	UpdateMode string `form:"update_mode" example:"if_newer" validate:"omitempty,oneof=skip if_newer always"`
+}
+
+// ImportTextRequest represents the form fields sent with an imported CSV/TSV file
+// Header directives of the file (#separator:, #html:, #notetype column:, ...) take precedence
+type ImportTextRequest struct {
+	// Column separator: a character or tab, comma, semicolon, pipe, colon, space; guessed when empty
+	Delimiter string `form:"delimiter" example:"tab"`
+
+	// Keep HTML in fields; otherwise it is escaped and newlines become <br>
+	HTML bool `form:"html" example:"false"`
+
+	// Note type of rows without a note type column
+	NoteTypeID int64 `form:"note_type_id" example:"1" validate:"omitempty,gt=0"`
+
+	// Deck of rows without a deck column
+	DeckID int64 `form:"deck_id" example:"1" validate:"omitempty,gt=0"`
+
+	// Field filled by each column, one value per column, empty to ignore the column
+	FieldMap []string `form:"field_map" example:"Front"`
+
+	// The first row holds column names, as written by the text export
+	HeaderRow bool `form:"header_row" example:"false"`
+
+	// 1-based column matching existing notes by GUID
+	GUIDColumn int `form:"guid_column" example:"0" validate:"omitempty,gt=0"`
+
+	// 1-based column of space-separated tags
+	TagsColumn int `form:"tags_column" example:"3" validate:"omitempty,gt=0"`
+
+	// 1-based column of deck names or IDs
+	DeckColumn int `form:"deck_column" example:"0" validate:"omitempty,gt=0"`
+
+	// 1-based column of note type names or IDs
+	NoteTypeColumn int `form:"notetype_column" example:"0" validate:"omitempty,gt=0"`
+
+	// Space-separated tags added to every imported note
+	Tags string `form:"tags" example:"imported vocab"`
+
+	// Handling of rows matching a note by GUID or first field: "update" (default), "skip" or "add"
+	DuplicateMode string `form:"duplicate_mode" example:"update" validate:"omitempty,oneof=update skip add"`
+}
@@ -74,6 +74,30 @@ type FindDuplicatesRequest struct {
 	UseGUID bool `json:"use_guid" example:"false"`
 }
 
+// FindReplaceRequest represents the request payload to find and replace text across notes
+type FindReplaceRequest struct {
+	// Search query selecting the notes (Anki syntax); empty for all notes
+	Query string `json:"query" example:"deck:Spanish tag:verbs"`
+
+	// Field to edit (optional); if empty, all fields are edited. Ignored when in_tags is true
+	FieldName string `json:"field_name" example:"Back" validate:"omitempty"`
+
+	// Rename or replace tags instead of editing fields
+	InTags bool `json:"in_tags" example:"false"`
+
+	// Text to find, or a regular expression if regex is true
+	Find string `json:"find" example:"colour" validate:"required"`
+
+	// Replacement text; with regex, $1 and ${name} refer to the regex groups
+	Replace string `json:"replace" example:"color"`
+
+	// Treat find as a regular expression
+	Regex bool `json:"regex" example:"false"`
+
+	// Match case (case-insensitive by default)
+	MatchCase bool `json:"match_case" example:"false"`
+}
+
 // ExportNotesRequest represents the request payload to export selected notes
 type ExportNotesRequest struct {
 	// List of note IDs to export
@@ -11,7 +11,16 @@ type AdvancedSearchRequest struct {
 	// Maximum number of results
 	Limit int `json:"limit" example:"20"`
 
-	// Pagination offset
+	// Pagination offset (ignored when cursor is set)
 	Offset int `json:"offset" example:"0"`
+
+	// Cursor returned as next_cursor by the previous page
+	Cursor string `json:"cursor,omitempty" example:""`
+
+	// Browser column to sort by; defaults to the browser config's sort column
+	SortColumn string `json:"sort_column,omitempty" example:"due" validate:"omitempty,oneof=created modified note sort_field tags deck card due interval ease lapses reps"`
+
+	// Sort direction: "asc" or "desc"; defaults to the browser config's sort direction
+	SortDirection string `json:"sort_direction,omitempty" example:"asc" validate:"omitempty,oneof=asc desc"`
 }
 
@@ -8,6 +8,7 @@ type UpdateUserPreferencesRequest struct {
 	Theme                   string    `json:"theme" example:"dark"`
 	AutoSync                bool      `json:"auto_sync"`
 	NextDayStartsAt         time.Time `json:"next_day_starts_at"`
+	Timezone                string    `json:"timezone" example:"America/Sao_Paulo"`
 	LearnAheadLimit         int       `json:"learn_ahead_limit"`
 	TimeboxTimeLimit        int       `json:"timebox_time_limit"`
 	VideoDriver             string    `json:"video_driver"`
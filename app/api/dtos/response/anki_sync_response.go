@@ -0,0 +1,13 @@
+package response
+
+// AnkiSyncHostKeyResponse represents the hostKey response payload
+type AnkiSyncHostKeyResponse struct {
+	Key string `json:"key"`
+}
+
+// AnkiMediaSyncResponse wraps the JSON results of the media sync protocol
+// Errors clients should show are sent in Err with no data
+type AnkiMediaSyncResponse struct {
+	Data interface{} `json:"data"`
+	Err  string      `json:"err"`
+}
@@ -0,0 +1,37 @@
+package response
+
+// SyncChangesResponse represents the response payload for fetching changes
+type SyncChangesResponse struct {
+	USN       int                    `json:"usn"`
+	Full      bool                   `json:"full"`
+	Notes     []*NoteResponse        `json:"notes"`
+	Cards     []*CardResponse        `json:"cards"`
+	Decks     []*DeckResponse        `json:"decks"`
+	NoteTypes []*NoteTypeResponse    `json:"note_types"`
+	Reviews   []*ReviewResponse      `json:"reviews"`
+	Media     []*MediaResponse       `json:"media"`
+	Deleted   []*DeletionLogResponse `json:"deleted"`
+}
+
+// SyncCreatedResponse maps a client reference to the ID of the object created for it
+type SyncCreatedResponse struct {
+	ObjectType string `json:"object_type"`
+	ClientRef  string `json:"client_ref"`
+	ID         int64  `json:"id"`
+}
+
+// SyncConflictResponse represents a pushed change that was not applied
+type SyncConflictResponse struct {
+	ObjectType string `json:"object_type"`
+	ID         int64  `json:"id,omitempty"`
+	ClientRef  string `json:"client_ref,omitempty"`
+	Reason     string `json:"reason"`
+	Message    string `json:"message,omitempty"`
+}
+
+// SyncPushResponse represents the response payload for pushing changes
+type SyncPushResponse struct {
+	Applied   int                     `json:"applied"`
+	Created   []*SyncCreatedResponse  `json:"created"`
+	Conflicts []*SyncConflictResponse `json:"conflicts"`
+}
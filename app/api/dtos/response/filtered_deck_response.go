@@ -17,9 +17,15 @@ type FilteredDeckResponse struct {
 	// Search filter criteria
 	SearchFilter string `json:"search_filter" example:"is:due"`
 
+	// Second search filter
+	SecondFilter *string `json:"second_filter,omitempty" example:"is:new"`
+
 	// Maximum number of cards
 	Limit int `json:"limit" example:"100"`
 
+	// Maximum number of cards gathered by the second search
+	SecondLimit int `json:"second_limit" example:"20"`
+
 	// Order by criteria
 	OrderBy string `json:"order_by" example:"random"`
 
@@ -31,5 +37,15 @@ type FilteredDeckResponse struct {
 
 	// Timestamp when last updated
 	UpdatedAt time.Time `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+
+	// Timestamp when the deck was last built
+	LastRebuildAt *time.Time `json:"last_rebuild_at,omitempty" example:"2024-01-15T10:30:00Z"`
+}
+
+// FilteredDeckBuildResponse represents the result of rebuilding or emptying a filtered deck
+// @Description Number of cards moved by a rebuild or empty
+type FilteredDeckBuildResponse struct {
+	// Cards in the deck after a rebuild, or cards returned to their home decks after emptying
+	CardCount int `json:"card_count" example:"42"`
 }
 
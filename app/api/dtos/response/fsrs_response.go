@@ -0,0 +1,27 @@
+package response
+
+// FSRSMetricsResponse represents how well FSRS parameters predict the review history
+// @Description Prediction error of a set of FSRS parameters
+type FSRSMetricsResponse struct {
+	// Mean binary cross-entropy of the predicted recall probability
+	LogLoss float64 `json:"log_loss" example:"0.3512"`
+
+	// Root mean square calibration error over retrievability bins
+	RMSE float64 `json:"rmse" example:"0.0421"`
+}
+
+// FSRSEvaluationResponse represents the response payload for an FSRS parameter evaluation
+// @Description Response payload comparing current and fitted FSRS parameters
+type FSRSEvaluationResponse struct {
+	// Number of reviews used to evaluate the parameters
+	ReviewCount int `json:"review_count" example:"1520"`
+
+	// Metrics with the preset's current parameters
+	Before FSRSMetricsResponse `json:"before"`
+
+	// Metrics with the fitted parameters
+	After FSRSMetricsResponse `json:"after"`
+
+	// Fitted FSRS parameters
+	Weights []float64 `json:"weights"`
+}
@@ -0,0 +1,49 @@
+package response
+
+// ImportResponse represents the response payload for an import
+// @Description Response payload counting what the import added or changed
+type ImportResponse struct {
+	// Number of note types added
+	NoteTypesAdded int `json:"note_types_added" example:"1"`
+	// Number of decks added
+	DecksAdded int `json:"decks_added" example:"2"`
+	// Number of notes added
+	NotesAdded int `json:"notes_added" example:"120"`
+	// Number of existing notes updated
+	NotesUpdated int `json:"notes_updated" example:"3"`
+	// Number of existing notes left unchanged
+	NotesSkipped int `json:"notes_skipped" example:"0"`
+	// Number of cards added
+	CardsAdded int `json:"cards_added" example:"240"`
+	// Number of review log entries added
+	ReviewsAdded int `json:"reviews_added" example:"1500"`
+	// Number of media files added
+	MediaAdded int `json:"media_added" example:"12"`
+}
+
+// TextImportRowResponse represents the outcome of one imported row
+type TextImportRowResponse struct {
+	// Line of the row in the file
+	Line int `json:"line" example:"2"`
+	// Outcome: added, updated, skipped or error
+	Status string `json:"status" example:"added"`
+	// Added or matched note, omitted on error
+	NoteID int64 `json:"note_id,omitempty" example:"42"`
+	// Reason of skipped and error rows
+	Message string `json:"message,omitempty" example:"note with the same first field exists"`
+}
+
+// TextImportResponse represents the response payload for a text import
+// @Description Response payload counting the outcome of a text import with a report of each row
+type TextImportResponse struct {
+	// Number of notes added
+	Added int `json:"added" example:"95"`
+	// Number of existing notes updated
+	Updated int `json:"updated" example:"3"`
+	// Number of rows skipped
+	Skipped int `json:"skipped" example:"1"`
+	// Number of rows that failed
+	Errors int `json:"errors" example:"1"`
+	// Outcome of each row
+	Rows []TextImportRowResponse `json:"rows"`
+}
@@ -0,0 +1,41 @@
+package response
+
+import "time"
+
+// JobEnqueuedResponse represents the response payload for a job queued for background processing
+// @Description Response payload containing the ID of a queued job
+type JobEnqueuedResponse struct {
+	// ID of the queued job, used to poll its status
+	JobID string `json:"job_id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+
+	// Current status of the job
+	Status string `json:"status" example:"pending"`
+}
+
+// JobStatusResponse represents the response payload for a background job's status
+// @Description Response payload containing the status, progress and result of a job
+type JobStatusResponse struct {
+	// ID of the job
+	ID string `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+
+	// Type of the job
+	Type string `json:"type" example:"fsrs_optimize"`
+
+	// Current status (pending, processing, completed, failed)
+	Status string `json:"status" example:"processing"`
+
+	// Completion percentage (0-100)
+	Progress int `json:"progress" example:"45"`
+
+	// Output of the job, available once it is completed
+	Result map[string]interface{} `json:"result,omitempty"`
+
+	// Error message of the last failed attempt
+	Error string `json:"error,omitempty"`
+
+	// Timestamp when the job was created
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
+
+	// Timestamp when the job completed
+	CompletedAt *time.Time `json:"completed_at,omitempty" example:"2024-01-15T10:31:00Z"`
+}
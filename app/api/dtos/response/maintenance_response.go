@@ -15,3 +15,50 @@ type CleanupEmptyCardsResponse struct {
 	// Number of empty cards that were deleted
 	DeletedCount int `json:"deleted_count" example:"5"`
 }
+
+// MissingMediaResponse represents a media file referenced by notes that doesn't exist
+type MissingMediaResponse struct {
+	// Referenced filename
+	Filename string `json:"filename" example:"audio.mp3"`
+	// Notes referencing the file
+	NoteIDs []int64 `json:"note_ids"`
+}
+
+// MisnamedMediaResponse represents a media file with a name Anki wouldn't store it under
+type MisnamedMediaResponse struct {
+	// The media file
+	Media *MediaResponse `json:"media"`
+	// NFC-normalized name without illegal characters
+	SuggestedName string `json:"suggested_name" example:"cafe.png"`
+}
+
+// CheckMediaResponse represents the response payload for a Check Media run
+// @Description Response payload listing unused, missing and misnamed media and orphaned storage objects
+type CheckMediaResponse struct {
+	// ID of the check_database_log entry of the run
+	LogID int64 `json:"log_id" example:"1"`
+	// Total number of issues found
+	IssuesFound int `json:"issues_found" example:"3"`
+	// Media files no note references
+	Unused []*MediaResponse `json:"unused"`
+	// Referenced files without a media file or stored content
+	Missing []MissingMediaResponse `json:"missing"`
+	// Media files with names that aren't NFC or contain illegal characters
+	Misnamed []MisnamedMediaResponse `json:"misnamed"`
+	// Storage objects no media file points to
+	Orphaned []string `json:"orphaned"`
+}
+
+// DeleteUnusedMediaResponse represents the response payload for deleting unused media
+// @Description Response payload containing the number of deleted media files
+type DeleteUnusedMediaResponse struct {
+	// Number of media files that were deleted
+	DeletedCount int `json:"deleted_count" example:"3"`
+}
+
+// TagMissingMediaResponse represents the response payload for tagging notes with missing media
+// @Description Response payload containing the number of tagged notes
+type TagMissingMediaResponse struct {
+	// Number of notes that were tagged missing-media
+	TaggedCount int `json:"tagged_count" example:"2"`
+}
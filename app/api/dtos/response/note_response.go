@@ -35,6 +35,12 @@ type FindDuplicatesResponse struct {
 	Total int `json:"total_duplicates" example:"1"`
 }
 
+// FindReplaceResponse represents the response payload for find and replace
+type FindReplaceResponse struct {
+	// Number of notes changed
+	Changed int `json:"changed" example:"12"`
+}
+
 // DuplicateGroup represents a group of duplicate notes with the same field value
 type DuplicateGroup struct {
 	// The field value that is duplicated (field value for field-based detection, GUID for GUID-based detection)
@@ -21,3 +21,34 @@ type ReviewResponse struct {
 	CreatedAt time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
 }
 
+// NextIntervalResponse represents the outcome of answering a card with one rating
+// @Description Scheduling outcome of one answer button, computed without saving
+type NextIntervalResponse struct {
+	// Rating of the answer button (1=Again, 2=Hard, 3=Good, 4=Easy)
+	Rating int `json:"rating" example:"3"`
+
+	// Card state after answering (new, learn, review, relearn)
+	State string `json:"state" example:"review"`
+
+	// Interval in days after answering (0 while in learning)
+	Interval int `json:"interval" example:"3"`
+
+	// Seconds until the card would be due again
+	DelaySeconds int `json:"delay_seconds" example:"259200"`
+
+	// Label to show on the answer button
+	Label string `json:"label" example:"3d"`
+
+	// When the card would be due again
+	Due time.Time `json:"due" example:"2024-01-18T10:30:00Z"`
+}
+
+// NextIntervalsResponse represents the response payload for the answer button preview
+// @Description Response payload containing the scheduling outcome of every answer button
+type NextIntervalsResponse struct {
+	// ID of the card
+	CardID int64 `json:"card_id" example:"1"`
+
+	// Outcomes ordered by rating (Again, Hard, Good, Easy)
+	Intervals []NextIntervalResponse `json:"intervals"`
+}
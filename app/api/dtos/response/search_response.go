@@ -7,5 +7,8 @@ type SearchResult struct {
 
 	// Total number of results (for pagination)
 	Total int `json:"total" example:"10"`
+
+	// Cursor of the next page, omitted on the last page
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJjIjoiZHVlIiwiZCI6ImFzYyIsImsiOiIxNzAwMDAwMDAwMDAwIiwiaWQiOjQyfQ"`
 }
 
@@ -0,0 +1,42 @@
+package response
+
+// StudyCountsResponse represents the number of cards left to study today
+type StudyCountsResponse struct {
+	// New cards left within the daily new card limit
+	New int `json:"new" example:"20"`
+
+	// Learning cards that can be studied now
+	Learn int `json:"learn" example:"3"`
+
+	// Review cards left within the daily review limit
+	Review int `json:"review" example:"120"`
+}
+
+// StudyNextResponse represents the response payload for the next card of a study session
+// @Description Response payload containing the next card to study and the cards left for today
+type StudyNextResponse struct {
+	// Next card to study (null when nothing is left for today)
+	Card *CardResponse `json:"card"`
+
+	// Queue the card comes from (new, learn, review), empty when nothing is left
+	Queue string `json:"queue" example:"review"`
+
+	// Cards left to study today, including the returned card
+	Counts StudyCountsResponse `json:"counts"`
+}
+
+// CustomStudyResponse represents the response payload of a custom study session
+// @Description Response payload with today's extended limits or the custom study session deck
+type CustomStudyResponse struct {
+	// New cards added to today's limit of the deck (limit modes)
+	NewLimitExtension *int `json:"new_limit_extension,omitempty" example:"10"`
+
+	// Reviews added to today's limit of the deck (limit modes)
+	ReviewLimitExtension *int `json:"review_limit_extension,omitempty" example:"50"`
+
+	// Filtered deck holding the session (other modes)
+	FilteredDeck *FilteredDeckResponse `json:"filtered_deck,omitempty"`
+
+	// Cards gathered into the session deck
+	CardCount int `json:"card_count" example:"42"`
+}
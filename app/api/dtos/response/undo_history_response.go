@@ -4,10 +4,11 @@ import "time"
 
 // UndoHistoryResponse represents the response payload for an undo history record
 type UndoHistoryResponse struct {
-	ID            int64     `json:"id"`
-	UserID        int64     `json:"user_id"`
-	OperationType string    `json:"operation_type"`
-	OperationData string    `json:"operation_data"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	OperationType string     `json:"operation_type"`
+	OperationData string     `json:"operation_data"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UndoneAt      *time.Time `json:"undone_at,omitempty"` // Set while the operation is undone and can be redone
 }
 
@@ -10,6 +10,7 @@ type UserPreferencesResponse struct {
 	Theme                   string    `json:"theme"`
 	AutoSync                bool      `json:"auto_sync"`
 	NextDayStartsAt         time.Time `json:"next_day_starts_at"`
+	Timezone                string    `json:"timezone"`
 	LearnAheadLimit         int       `json:"learn_ahead_limit"`
 	TimeboxTimeLimit        int       `json:"timebox_time_limit"`
 	VideoDriver             string    `json:"video_driver"`
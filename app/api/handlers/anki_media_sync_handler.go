@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	mediaService "github.com/felipesantos/anki-backend/core/services/media"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
+)
+
+// AnkiMediaSyncHandler handles the media sync protocol HTTP requests sent by Anki clients
+type AnkiMediaSyncHandler struct {
+	syncService  primary.IAnkiSyncService
+	mediaService primary.IAnkiMediaSyncService
+}
+
+// NewAnkiMediaSyncHandler creates a new AnkiMediaSyncHandler instance
+func NewAnkiMediaSyncHandler(syncService primary.IAnkiSyncService, mediaService primary.IAnkiMediaSyncService) *AnkiMediaSyncHandler {
+	return &AnkiMediaSyncHandler{
+		syncService:  syncService,
+		mediaService: mediaService,
+	}
+}
+
+// Handle handles POST /sync/media/:method
+// @Summary Anki media sync protocol
+// @Description Media sync endpoint for Anki clients, authenticated with the host key of the sync protocol. Requests and responses are zstd compressed like sync requests; JSON results are wrapped in {"data": ..., "err": ""}. uploadChanges takes a zip with a _meta list of [filename, zip name] pairs, a null zip name deleting the file; downloadFiles returns a zip with a _meta map from zip names to filenames. Media can't be synced while sync_audio_and_images is off.
+// @Tags sync
+// @Accept octet-stream
+// @Produce octet-stream
+// @Param method path string true "Media sync method" Enums(begin, mediaChanges, uploadChanges, downloadFiles, mediaSanity)
+// @Param anki-sync header string true "Sync envelope: {\"v\":11,\"k\":\"<host key>\",\"c\":\"<client version>\",\"s\":\"<session>\"}"
+// @Success 200 {file} file
+// @Failure 400 {object} response.ErrorResponse "Invalid request"
+// @Failure 403 {object} response.ErrorResponse "Invalid host key"
+// @Failure 404 {object} response.ErrorResponse "Requested file not found"
+// @Failure 413 {object} response.ErrorResponse "Request body too large"
+// @Router /sync/media/{method} [post]
+func (h *AnkiMediaSyncHandler) Handle(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	header, err := ReadAnkiSyncHeader(c)
+	if err != nil {
+		return err
+	}
+	body, err := ReadAnkiSyncBody(c)
+	if err != nil {
+		return err
+	}
+
+	userID, err := h.syncService.Authenticate(ctx, header.HostKey)
+	if err != nil {
+		return ankiSyncError(err)
+	}
+
+	var result interface{}
+	switch c.Param("method") {
+	case "begin":
+		var begin *primary.AnkiMediaSyncBegin
+		if begin, err = h.mediaService.Begin(ctx, userID); err == nil {
+			// Clients send the session key back as their host key
+			begin.SessionKey = header.HostKey
+			result = begin
+		}
+	case "mediaChanges":
+		var req request.AnkiMediaChangesRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		result, err = h.mediaService.Changes(ctx, userID, req.LastUSN)
+	case "uploadChanges":
+		result, err = h.mediaService.Upload(ctx, userID, body)
+	case "downloadFiles":
+		var req request.AnkiMediaDownloadRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		data, err := h.mediaService.Download(ctx, userID, req.Files)
+		if err != nil {
+			return ankiMediaDownloadError(err)
+		}
+		return WriteAnkiSyncBytes(c, data)
+	case "mediaSanity":
+		var req request.AnkiMediaSanityRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		result, err = h.mediaService.Sanity(ctx, userID, req.Local)
+	default:
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown media sync method")
+	}
+	if err != nil {
+		if !isAnkiMediaSyncError(err) {
+			return err
+		}
+		return WriteAnkiSyncJSON(c, response.AnkiMediaSyncResponse{Err: err.Error()})
+	}
+
+	return WriteAnkiSyncJSON(c, response.AnkiMediaSyncResponse{Data: result})
+}
+
+// isAnkiMediaSyncError tells whether an error is reported to the client in the err field of the result
+func isAnkiMediaSyncError(err error) bool {
+	return errors.Is(err, mediaService.ErrMediaSyncDisabled) ||
+		errors.Is(err, mediaService.ErrInvalidMediaZip) ||
+		errors.Is(err, mediaService.ErrInvalidFilename) ||
+		errors.Is(err, mediaService.ErrEmptyMedia) ||
+		errors.Is(err, mediaService.ErrQuotaExceeded)
+}
+
+// ankiMediaDownloadError maps download errors to status codes, as download responses carry no JSON result
+func ankiMediaDownloadError(err error) error {
+	switch {
+	case errors.Is(err, ownership.ErrResourceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case isAnkiMediaSyncError(err):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	syncService "github.com/felipesantos/anki-backend/core/services/sync"
+)
+
+const (
+	// ankiSyncVersion is the only sync protocol version the server speaks
+	ankiSyncVersion = 11
+	// ankiSyncMaxBodySize limits uncompressed request bodies, as Anki's own sync server does
+	ankiSyncMaxBodySize = 250 << 20
+
+	ankiSyncHeader         = "anki-sync"
+	ankiOriginalSizeHeader = "anki-original-size"
+)
+
+// AnkiSyncHandler handles the Anki sync protocol HTTP requests sent by Anki clients
+type AnkiSyncHandler struct {
+	service primary.IAnkiSyncService
+}
+
+// NewAnkiSyncHandler creates a new AnkiSyncHandler instance
+func NewAnkiSyncHandler(service primary.IAnkiSyncService) *AnkiSyncHandler {
+	return &AnkiSyncHandler{
+		service: service,
+	}
+}
+
+// Handle handles POST /sync/:method
+// @Summary Anki sync protocol
+// @Description Sync endpoint for Anki clients (protocol version 11). Requests carry an anki-sync JSON header with the protocol version and host key; bodies and responses are zstd compressed JSON, except for full sync uploads and downloads, which carry the collection database.
+// @Tags sync
+// @Accept octet-stream
+// @Produce octet-stream
+// @Param method path string true "Sync method" Enums(hostKey, meta, start, applyGraves, applyChanges, chunk, applyChunk, sanityCheck2, finish, abort, upload, download)
+// @Param anki-sync header string true "Sync envelope: {\"v\":11,\"k\":\"<host key>\",\"c\":\"<client version>\",\"s\":\"<session>\"}"
+// @Success 200 {file} file
+// @Failure 400 {object} response.ErrorResponse "Invalid request or full sync required"
+// @Failure 403 {object} response.ErrorResponse "Invalid credentials or host key"
+// @Failure 409 {object} response.ErrorResponse "No sync in progress"
+// @Failure 413 {object} response.ErrorResponse "Request body too large"
+// @Router /sync/{method} [post]
+func (h *AnkiSyncHandler) Handle(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	header, err := ReadAnkiSyncHeader(c)
+	if err != nil {
+		return err
+	}
+	body, err := ReadAnkiSyncBody(c)
+	if err != nil {
+		return err
+	}
+
+	method := c.Param("method")
+	if method == "hostKey" {
+		var req request.AnkiSyncHostKeyRequest
+		if err := decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		key, err := h.service.HostKey(ctx, req.Username, req.Password)
+		if err != nil {
+			return ankiSyncError(err)
+		}
+		return WriteAnkiSyncJSON(c, response.AnkiSyncHostKeyResponse{Key: key})
+	}
+
+	userID, err := h.service.Authenticate(ctx, header.HostKey)
+	if err != nil {
+		return ankiSyncError(err)
+	}
+
+	var result interface{}
+	switch method {
+	case "meta":
+		var req request.AnkiSyncMetaRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		result, err = h.service.Meta(ctx, userID)
+	case "start":
+		var req request.AnkiSyncStartRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		result, err = h.service.Start(ctx, userID, req.MinUSN, req.LocalIsNewer, req.Graves)
+	case "applyGraves":
+		var req request.AnkiSyncApplyGravesRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		err = h.service.ApplyGraves(ctx, userID, &req.Chunk)
+	case "applyChanges":
+		var req request.AnkiSyncApplyChangesRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		result, err = h.service.ApplyChanges(ctx, userID, &req.Changes)
+	case "chunk":
+		result, err = h.service.Chunk(ctx, userID)
+	case "applyChunk":
+		var req request.AnkiSyncApplyChunkRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		err = h.service.ApplyChunk(ctx, userID, &req.Chunk)
+	case "sanityCheck2":
+		var req request.AnkiSyncSanityCheckRequest
+		if err = decodeAnkiSyncJSON(body, &req); err != nil {
+			return err
+		}
+		result, err = h.service.SanityCheck(ctx, userID, &req.Client)
+	case "finish":
+		result, err = h.service.Finish(ctx, userID)
+	case "abort":
+		err = h.service.Abort(ctx, userID)
+	case "upload":
+		if err = h.service.Upload(ctx, userID, body); err != nil {
+			return ankiSyncError(err)
+		}
+		return WriteAnkiSyncBytes(c, []byte("OK"))
+	case "download":
+		data, err := h.service.Download(ctx, userID)
+		if err != nil {
+			return ankiSyncError(err)
+		}
+		return WriteAnkiSyncBytes(c, data)
+	default:
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown sync method")
+	}
+	if err != nil {
+		return ankiSyncError(err)
+	}
+
+	return WriteAnkiSyncJSON(c, result)
+}
+
+// ReadAnkiSyncHeader parses the anki-sync header of a sync request
+func ReadAnkiSyncHeader(c echo.Context) (*request.AnkiSyncHeader, error) {
+	var header request.AnkiSyncHeader
+	if err := json.Unmarshal([]byte(c.Request().Header.Get(ankiSyncHeader)), &header); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid anki-sync header")
+	}
+	if header.Version != ankiSyncVersion {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, "Unsupported sync protocol version")
+	}
+	return &header, nil
+}
+
+// ReadAnkiSyncBody reads and decompresses the zstd body of a sync request
+func ReadAnkiSyncBody(c echo.Context) ([]byte, error) {
+	compressed, err := io.ReadAll(io.LimitReader(c.Request().Body, ankiSyncMaxBodySize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if len(compressed) > ankiSyncMaxBodySize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Request body too large")
+	}
+	if len(compressed) == 0 {
+		return nil, nil
+	}
+
+	decoder, err := zstd.NewReader(bytes.NewReader(compressed), zstd.WithDecoderMaxMemory(ankiSyncMaxBodySize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	defer decoder.Close()
+
+	body, err := io.ReadAll(io.LimitReader(decoder, ankiSyncMaxBodySize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if len(body) > ankiSyncMaxBodySize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Request body too large")
+	}
+	return body, nil
+}
+
+// WriteAnkiSyncJSON writes a zstd compressed JSON sync response
+func WriteAnkiSyncJSON(c echo.Context, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return WriteAnkiSyncBytes(c, data)
+}
+
+// WriteAnkiSyncBytes writes a zstd compressed sync response
+// Clients check the anki-original-size header against the decompressed size
+func WriteAnkiSyncBytes(c echo.Context, data []byte) error {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return err
+	}
+	defer encoder.Close()
+
+	c.Response().Header().Set(ankiOriginalSizeHeader, strconv.Itoa(len(data)))
+	return c.Blob(http.StatusOK, echo.MIMEOctetStream, encoder.EncodeAll(data, nil))
+}
+
+// decodeAnkiSyncJSON decodes a sync request payload; an empty body decodes as an empty object
+func decodeAnkiSyncJSON(body []byte, v interface{}) error {
+	if len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	return nil
+}
+
+// ankiSyncError maps sync service errors to the status codes Anki clients expect
+func ankiSyncError(err error) error {
+	switch {
+	case errors.Is(err, syncService.ErrSyncAuthFailed), errors.Is(err, syncService.ErrInvalidHostKey):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, syncService.ErrSyncSessionNotFound):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, syncService.ErrFullSyncRequired), errors.Is(err, syncService.ErrInvalidCollection):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return err
+}
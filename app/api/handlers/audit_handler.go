@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 
 	"github.com/felipesantos/anki-backend/app/api/mappers"
 	"github.com/felipesantos/anki-backend/app/api/middlewares"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	auditService "github.com/felipesantos/anki-backend/core/services/audit"
 )
 
 // AuditHandler handles audit log related HTTP requests (deletion logs, undo history)
@@ -87,3 +90,57 @@ func (h *AuditHandler) DeleteUndoHistory(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// Undo handles POST /api/v1/audit/undo
+// @Summary Undo the last operation
+// @Description Reverts the most recent note, card or deck operation. Fails with 409 if an object it changed was modified since
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.UndoHistoryResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Router /api/v1/audit/undo [post]
+func (h *AuditHandler) Undo(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	entry, err := h.undoHistoryService.Undo(ctx, userID)
+	if err != nil {
+		return undoError(err)
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToUndoHistoryResponse(entry))
+}
+
+// Redo handles POST /api/v1/audit/redo
+// @Summary Redo the last undone operation
+// @Description Applies again the most recently undone operation. Fails with 409 if an object it changed was modified since the undo
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.UndoHistoryResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Router /api/v1/audit/redo [post]
+func (h *AuditHandler) Redo(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	entry, err := h.undoHistoryService.Redo(ctx, userID)
+	if err != nil {
+		return undoError(err)
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToUndoHistoryResponse(entry))
+}
+
+// undoError maps undo and redo errors to HTTP errors
+func undoError(err error) error {
+	if errors.Is(err, auditService.ErrUndoConflict) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if strings.Contains(err.Error(), "not found") {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
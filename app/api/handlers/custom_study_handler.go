@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/mappers"
+	"github.com/felipesantos/anki-backend/app/api/middlewares"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	deckSvc "github.com/felipesantos/anki-backend/core/services/deck"
+	studySvc "github.com/felipesantos/anki-backend/core/services/study"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
+)
+
+// CustomStudyHandler handles custom study HTTP requests
+type CustomStudyHandler struct {
+	service primary.ICustomStudyService
+}
+
+// NewCustomStudyHandler creates a new CustomStudyHandler instance
+func NewCustomStudyHandler(service primary.ICustomStudyService) *CustomStudyHandler {
+	return &CustomStudyHandler{
+		service: service,
+	}
+}
+
+// CustomStudy handles POST /api/v1/decks/:id/custom-study
+// @Summary Start a custom study session
+// @Description Extends today's new card or review limit of the deck, or builds the "Custom Study Session" filtered deck from the deck and its subdecks: forgotten cards, review ahead, preview new cards, or cram by card state and tags
+// @Tags study
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deck ID"
+// @Param request body request.CustomStudyRequest true "Custom study request"
+// @Success 200 {object} response.CustomStudyResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/decks/{id}/custom-study [post]
+func (h *CustomStudyHandler) CustomStudy(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	deckID, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	var req request.CustomStudyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	result, err := h.service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{
+		Mode:        req.Mode,
+		Amount:      req.Amount,
+		CardState:   req.CardState,
+		TagsInclude: req.TagsInclude,
+		TagsExclude: req.TagsExclude,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, deckSvc.ErrDeckNotFound), errors.Is(err, ownership.ErrResourceNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Deck not found")
+		case errors.Is(err, studySvc.ErrInvalidCustomStudy), errors.Is(err, deckSvc.ErrInvalidFilteredDeckSearch):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToCustomStudyResponse(result))
+}
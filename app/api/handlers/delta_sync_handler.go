@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/mappers"
+	"github.com/felipesantos/anki-backend/app/api/middlewares"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// DeltaSyncHandler handles delta synchronization HTTP requests
+type DeltaSyncHandler struct {
+	service primary.IDeltaSyncService
+}
+
+// NewDeltaSyncHandler creates a new DeltaSyncHandler instance
+func NewDeltaSyncHandler(service primary.IDeltaSyncService) *DeltaSyncHandler {
+	return &DeltaSyncHandler{
+		service: service,
+	}
+}
+
+// Changes handles GET /api/v1/sync/changes
+// @Summary Get changes since a USN
+// @Description Returns the notes, cards, decks, note types, reviews and media changed since the given update sequence number (USN), plus tombstones of deleted objects. Pass the returned usn as since on the next call. Without since, or when since is ahead of the server (after a full upload from an Anki client), every object is returned with full set to true.
+// @Tags sync
+// @Produce json
+// @Security BearerAuth
+// @Param since query int false "USN returned by the previous call"
+// @Param client_id query string false "Client whose last sync USN is recorded"
+// @Success 200 {object} response.SyncChangesResponse
+// @Router /api/v1/sync/changes [get]
+func (h *DeltaSyncHandler) Changes(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	var req request.SyncChangesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	since := -1
+	if req.Since != nil {
+		since = *req.Since
+	}
+
+	changes, err := h.service.FindChanges(ctx, userID, req.ClientID, since)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToSyncChangesResponse(changes))
+}
+
+// Push handles POST /api/v1/sync/push
+// @Summary Push client changes
+// @Description Applies a batch of client changes with last-writer-wins conflict resolution. Note types are applied first, then decks, notes, cards and reviews. Changes older than the server's object, to objects deleted on the server, or rejected as invalid are reported as conflicts and don't stop the batch.
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.SyncPushRequest true "Client changes"
+// @Success 200 {object} response.SyncPushResponse
+// @Router /api/v1/sync/push [post]
+func (h *DeltaSyncHandler) Push(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	var req request.SyncPushRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	result, err := h.service.Push(ctx, userID, mappers.ToSyncPush(&req))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToSyncPushResponse(result))
+}
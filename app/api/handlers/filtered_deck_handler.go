@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
 
 	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
 	"github.com/felipesantos/anki-backend/app/api/mappers"
 	"github.com/felipesantos/anki-backend/app/api/middlewares"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	deckSvc "github.com/felipesantos/anki-backend/core/services/deck"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
 )
 
 // FilteredDeckHandler handles filtered deck-related HTTP requests
@@ -47,9 +51,9 @@ func (h *FilteredDeckHandler) Create(c echo.Context) error {
 		return err // Returns HTTP 400 with validation error message
 	}
 
-	fd, err := h.service.Create(ctx, userID, req.Name, req.SearchFilter, req.Limit, req.OrderBy, req.Reschedule)
+	fd, err := h.service.Create(ctx, userID, req.Name, req.SearchFilter, req.SecondFilter, req.Limit, req.SecondLimit, req.OrderBy, req.Reschedule)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return handleFilteredDeckError(err)
 	}
 
 	return c.JSON(http.StatusCreated, mappers.ToFilteredDeckResponse(fd))
@@ -99,9 +103,9 @@ func (h *FilteredDeckHandler) Update(c echo.Context) error {
 		return err // Returns HTTP 400 with validation error message
 	}
 
-	fd, err := h.service.Update(ctx, userID, id, req.Name, req.SearchFilter, req.Limit, req.OrderBy, req.Reschedule)
+	fd, err := h.service.Update(ctx, userID, id, req.Name, req.SearchFilter, req.SecondFilter, req.Limit, req.SecondLimit, req.OrderBy, req.Reschedule)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return handleFilteredDeckError(err)
 	}
 
 	return c.JSON(http.StatusOK, mappers.ToFilteredDeckResponse(fd))
@@ -120,9 +124,85 @@ func (h *FilteredDeckHandler) Delete(c echo.Context) error {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 
 	if err := h.service.Delete(ctx, userID, id); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return handleFilteredDeckError(err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+// Rebuild handles POST /api/v1/filtered-decks/:id/rebuild
+// @Summary Rebuild filtered deck
+// @Description Returns the deck's cards to their home decks and gathers the cards matching its searches again
+// @Tags filtered-decks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Filtered Deck ID"
+// @Success 200 {object} response.FilteredDeckBuildResponse
+// @Router /api/v1/filtered-decks/{id}/rebuild [post]
+func (h *FilteredDeckHandler) Rebuild(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	count, err := h.service.Rebuild(ctx, userID, id)
+	if err != nil {
+		return handleFilteredDeckError(err)
+	}
+
+	return c.JSON(http.StatusOK, response.FilteredDeckBuildResponse{CardCount: count})
+}
+
+// Empty handles POST /api/v1/filtered-decks/:id/empty
+// @Summary Empty filtered deck
+// @Description Returns the deck's cards to their home decks
+// @Tags filtered-decks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Filtered Deck ID"
+// @Success 200 {object} response.FilteredDeckBuildResponse
+// @Router /api/v1/filtered-decks/{id}/empty [post]
+func (h *FilteredDeckHandler) Empty(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	count, err := h.service.Empty(ctx, userID, id)
+	if err != nil {
+		return handleFilteredDeckError(err)
+	}
+
+	return c.JSON(http.StatusOK, response.FilteredDeckBuildResponse{CardCount: count})
+}
+
+// FindCards handles GET /api/v1/filtered-decks/:id/cards
+// @Summary List filtered deck cards
+// @Description Lists the cards in a filtered deck, in the deck's order
+// @Tags filtered-decks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Filtered Deck ID"
+// @Success 200 {array} response.CardResponse
+// @Router /api/v1/filtered-decks/{id}/cards [get]
+func (h *FilteredDeckHandler) FindCards(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	cards, err := h.service.FindCards(ctx, userID, id)
+	if err != nil {
+		return handleFilteredDeckError(err)
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToCardResponseList(cards))
+}
+
+// handleFilteredDeckError maps service-level filtered deck errors to HTTP errors
+func handleFilteredDeckError(err error) error {
+	if errors.Is(err, deckSvc.ErrFilteredDeckNotFound) || errors.Is(err, ownership.ErrResourceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Filtered deck not found")
+	}
+	if errors.Is(err, deckSvc.ErrInvalidFilteredDeckSearch) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
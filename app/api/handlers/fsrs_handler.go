@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/app/api/mappers"
+	"github.com/felipesantos/anki-backend/app/api/middlewares"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	deckSvc "github.com/felipesantos/anki-backend/core/services/deck"
+	jobHandlers "github.com/felipesantos/anki-backend/infra/jobs/handlers"
+)
+
+// FSRSHandler handles FSRS parameter optimization HTTP requests
+type FSRSHandler struct {
+	optimizer  primary.IFSRSOptimizerService
+	jobService primary.IJobService
+}
+
+// NewFSRSHandler creates a new FSRSHandler instance
+func NewFSRSHandler(optimizer primary.IFSRSOptimizerService, jobService primary.IJobService) *FSRSHandler {
+	return &FSRSHandler{
+		optimizer:  optimizer,
+		jobService: jobService,
+	}
+}
+
+// Optimize handles POST /api/v1/deck-options-presets/:id/fsrs/optimize
+// @Summary Optimize FSRS parameters
+// @Description Queues a background job that fits FSRS parameters to the review history and saves them to the preset. Poll GET /api/v1/jobs/{id} for progress and the before/after evaluation.
+// @Tags deck-options-presets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Preset ID"
+// @Param request body request.FSRSOptimizeRequest true "Decks using the preset"
+// @Success 202 {object} response.JobEnqueuedResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Router /api/v1/deck-options-presets/{id}/fsrs/optimize [post]
+func (h *FSRSHandler) Optimize(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	var req request.FSRSOptimizeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"user_id":   userID,
+		"preset_id": id,
+		"deck_ids":  req.DeckIDs,
+	}
+
+	// Optimization is deterministic, retrying a failed run would fail the same way
+	jobID, err := h.jobService.EnqueueWithRetries(ctx, jobHandlers.FSRSOptimizeJobType, payload, 0)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusAccepted, response.JobEnqueuedResponse{
+		JobID:  jobID,
+		Status: string(secondary.JobStatusPending),
+	})
+}
+
+// Evaluate handles POST /api/v1/deck-options-presets/:id/fsrs/evaluate
+// @Summary Evaluate FSRS parameters
+// @Description Fits FSRS parameters without saving them and returns log loss and RMSE with the current and fitted parameters
+// @Tags deck-options-presets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Preset ID"
+// @Param request body request.FSRSOptimizeRequest true "Decks using the preset"
+// @Success 200 {object} response.FSRSEvaluationResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Router /api/v1/deck-options-presets/{id}/fsrs/evaluate [post]
+func (h *FSRSHandler) Evaluate(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	var req request.FSRSOptimizeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	result, err := h.optimizer.Evaluate(ctx, userID, id, req.DeckIDs)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrNotEnoughReviews) || errors.Is(err, deckSvc.ErrFSRSDecksRequired) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToFSRSEvaluationResponse(result))
+}
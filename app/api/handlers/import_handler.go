@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/mappers"
+	"github.com/felipesantos/anki-backend/app/api/middlewares"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	mediaService "github.com/felipesantos/anki-backend/core/services/media"
+)
+
+// ImportHandler handles import-related HTTP requests
+type ImportHandler struct {
+	service primary.IImportService
+}
+
+// NewImportHandler creates a new ImportHandler instance
+func NewImportHandler(service primary.IImportService) *ImportHandler {
+	return &ImportHandler{
+		service: service,
+	}
+}
+
+// ImportPackage handles POST /api/v1/import/package
+// @Summary Import an Anki package
+// @Description Import an Anki deck package (.apkg) or collection package (.colpkg) with its note types, decks, notes, cards, review log and media. Notes matching an existing note by GUID are skipped or updated according to update_mode.
+// @Tags import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Package file (.apkg or .colpkg)"
+// @Param update_mode formData string false "Update mode for existing notes: skip, if_newer (default) or always"
+// @Success 200 {object} response.ImportResponse
+// @Failure 400 {object} response.ErrorResponse "Invalid package or update mode"
+// @Failure 413 {object} response.ErrorResponse "Media quota exceeded"
+// @Router /api/v1/import/package [post]
+func (h *ImportHandler) ImportPackage(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	var req request.ImportPackageRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	// Validate request using validator middleware
+	if err := c.Validate(&req); err != nil {
+		return err // Returns HTTP 400 with validation error message
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file")
+	}
+	defer file.Close()
+
+	result, err := h.service.ImportPackage(ctx, userID, file, header.Size, primary.ImportOptions{UpdateMode: req.UpdateMode})
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, mediaService.ErrQuotaExceeded) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToImportResponse(result))
+}
+
+// ImportText handles POST /api/v1/notes/import/text
+// @Summary Import notes from a CSV or TSV file
+// @Description Import notes from a CSV or TSV file with column-to-field mapping, tags, deck and note type columns, and Anki's header directives (#separator:, #html:, #columns:, #notetype column:, ...). Rows matching a note by GUID or first field are updated, skipped or added according to duplicate_mode. Each row is reported.
+// @Tags import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV or TSV file"
+// @Param delimiter formData string false "Column separator, guessed when empty"
+// @Param html formData bool false "Keep HTML in fields"
+// @Param note_type_id formData int false "Note type of rows without a note type column"
+// @Param deck_id formData int false "Deck of rows without a deck column"
+// @Param field_map formData []string false "Field filled by each column, empty to ignore the column" collectionFormat(multi)
+// @Param header_row formData bool false "The first row holds column names"
+// @Param guid_column formData int false "1-based GUID column"
+// @Param tags_column formData int false "1-based tags column"
+// @Param deck_column formData int false "1-based deck column"
+// @Param notetype_column formData int false "1-based note type column"
+// @Param tags formData string false "Space-separated tags added to every note"
+// @Param duplicate_mode formData string false "Handling of duplicates: update (default), skip or add"
+// @Success 200 {object} response.TextImportResponse
+// @Failure 400 {object} response.ErrorResponse "Invalid file or options"
+// @Router /api/v1/notes/import/text [post]
+func (h *ImportHandler) ImportText(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	var req request.ImportTextRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	// Validate request using validator middleware
+	if err := c.Validate(&req); err != nil {
+		return err // Returns HTTP 400 with validation error message
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file")
+	}
+	defer file.Close()
+
+	result, err := h.service.ImportText(ctx, userID, file, primary.TextImportOptions{
+		Delimiter:      req.Delimiter,
+		HTML:           req.HTML,
+		NoteTypeID:     req.NoteTypeID,
+		DeckID:         req.DeckID,
+		FieldMap:       req.FieldMap,
+		HeaderRow:      req.HeaderRow,
+		GUIDColumn:     req.GUIDColumn,
+		TagsColumn:     req.TagsColumn,
+		DeckColumn:     req.DeckColumn,
+		NoteTypeColumn: req.NoteTypeColumn,
+		Tags:           strings.Fields(req.Tags),
+		DuplicateMode:  req.DuplicateMode,
+	})
+	if err != nil {
+		msg := err.Error()
+		if strings.HasPrefix(msg, "invalid") || strings.Contains(msg, "not found") || strings.Contains(msg, "required") {
+			return echo.NewHTTPError(http.StatusBadRequest, msg)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, msg)
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToTextImportResponse(result))
+}
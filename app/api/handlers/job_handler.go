@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/mappers"
+	"github.com/felipesantos/anki-backend/app/api/middlewares"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// JobHandler handles background job HTTP requests
+type JobHandler struct {
+	service primary.IJobService
+}
+
+// NewJobHandler creates a new JobHandler instance
+func NewJobHandler(service primary.IJobService) *JobHandler {
+	return &JobHandler{
+		service: service,
+	}
+}
+
+// GetStatus handles GET /api/v1/jobs/:id
+// @Summary Get job status
+// @Description Returns the status, progress and result of a background job started by the authenticated user
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Job ID"
+// @Success 200 {object} response.JobStatusResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/jobs/{id} [get]
+func (h *JobHandler) GetStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	job, err := h.service.GetStatus(ctx, c.Param("id"))
+	if err != nil || job == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Job not found")
+	}
+
+	// Job payloads are decoded from JSON, so numbers are float64
+	owner, ok := job.Payload["user_id"].(float64)
+	if !ok || int64(owner) != userID {
+		return echo.NewHTTPError(http.StatusNotFound, "Job not found")
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToJobStatusResponse(job))
+}
@@ -9,17 +9,23 @@ import (
 	"github.com/felipesantos/anki-backend/app/api/mappers"
 	"github.com/felipesantos/anki-backend/app/api/middlewares"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	jobHandlers "github.com/felipesantos/anki-backend/infra/jobs/handlers"
 )
 
 // MaintenanceHandler handles maintenance-related HTTP requests
 type MaintenanceHandler struct {
-	cardService primary.ICardService
+	cardService       primary.ICardService
+	mediaCheckService primary.IMediaCheckService
+	jobService        primary.IJobService
 }
 
 // NewMaintenanceHandler creates a new MaintenanceHandler instance
-func NewMaintenanceHandler(cardService primary.ICardService) *MaintenanceHandler {
+func NewMaintenanceHandler(cardService primary.ICardService, mediaCheckService primary.IMediaCheckService, jobService primary.IJobService) *MaintenanceHandler {
 	return &MaintenanceHandler{
-		cardService: cardService,
+		cardService:       cardService,
+		mediaCheckService: mediaCheckService,
+		jobService:        jobService,
 	}
 }
 
@@ -67,3 +73,94 @@ func (h *MaintenanceHandler) CleanupEmptyCards(c echo.Context) error {
 		DeletedCount: count,
 	})
 }
+
+// CheckMedia handles POST /api/v1/maintenance/check-media
+// @Summary Check media
+// @Description Find unused media files, references to missing media, misnamed media files and orphaned storage objects; the result is logged in the check database log
+// @Tags maintenance
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.CheckMediaResponse
+// @Router /api/v1/maintenance/check-media [post]
+func (h *MaintenanceHandler) CheckMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	result, err := h.mediaCheckService.CheckMedia(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToCheckMediaResponse(result))
+}
+
+// DeleteUnusedMedia handles POST /api/v1/maintenance/check-media/delete-unused
+// @Summary Delete unused media
+// @Description Delete media files no note references and remove storage objects no media file points to
+// @Tags maintenance
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.DeleteUnusedMediaResponse
+// @Router /api/v1/maintenance/check-media/delete-unused [post]
+func (h *MaintenanceHandler) DeleteUnusedMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	count, err := h.mediaCheckService.DeleteUnused(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.DeleteUnusedMediaResponse{
+		DeletedCount: count,
+	})
+}
+
+// TagMissingMedia handles POST /api/v1/maintenance/check-media/tag-missing
+// @Summary Tag notes with missing media
+// @Description Add the missing-media tag to notes that reference media files that don't exist
+// @Tags maintenance
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.TagMissingMediaResponse
+// @Router /api/v1/maintenance/check-media/tag-missing [post]
+func (h *MaintenanceHandler) TagMissingMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	count, err := h.mediaCheckService.TagMissing(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.TagMissingMediaResponse{
+		TaggedCount: count,
+	})
+}
+
+// CheckDatabase handles POST /api/v1/maintenance/check-database
+// @Summary Check database
+// @Description Enqueue a job that finds and repairs cards of deleted notes, cards in deleted decks, filtered cards without a home deck, notes with the wrong field count, cards of removed card types, invalid due/interval values and duplicate GUIDs; the repairs are reported in the check database log
+// @Tags maintenance
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} response.JobEnqueuedResponse
+// @Router /api/v1/maintenance/check-database [post]
+func (h *MaintenanceHandler) CheckDatabase(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	payload := map[string]interface{}{
+		"user_id": userID,
+	}
+
+	jobID, err := h.jobService.Enqueue(ctx, jobHandlers.CheckDatabaseJobType, payload)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusAccepted, response.JobEnqueuedResponse{
+		JobID:  jobID,
+		Status: string(secondary.JobStatusPending),
+	})
+}
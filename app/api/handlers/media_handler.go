@@ -1,15 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"mime"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
 
-	"github.com/felipesantos/anki-backend/app/api/dtos/request"
 	"github.com/felipesantos/anki-backend/app/api/mappers"
 	"github.com/felipesantos/anki-backend/app/api/middlewares"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	mediaService "github.com/felipesantos/anki-backend/core/services/media"
 )
 
 // MediaHandler handles media file-related HTTP requests
@@ -24,37 +26,87 @@ func NewMediaHandler(service primary.IMediaService) *MediaHandler {
 	}
 }
 
-// Create handles POST /api/v1/media
-// @Summary Record a media file
+// Upload handles POST /api/v1/media
+// @Summary Upload a media file
+// @Description Upload a media file's content. The server computes the SHA-1 hash, detects the MIME type and stores the content once per user; uploading content the user already has returns the existing media record.
 // @Tags media
-// @Accept json
+// @Accept multipart/form-data
 // @Produce json
 // @Security BearerAuth
-// @Param request body request.CreateMediaRequest true "Media creation request"
+// @Param file formData file true "Media file"
+// @Param filename formData string false "Filename referenced by notes (defaults to the uploaded file's name)"
 // @Success 201 {object} response.MediaResponse
+// @Failure 400 {object} response.ErrorResponse "Missing, empty or invalid file"
+// @Failure 409 {object} response.ErrorResponse "Filename taken by other files"
+// @Failure 413 {object} response.ErrorResponse "Media quota exceeded"
 // @Router /api/v1/media [post]
-func (h *MediaHandler) Create(c echo.Context) error {
+func (h *MediaHandler) Upload(c echo.Context) error {
 	ctx := c.Request().Context()
 	userID := middlewares.GetUserID(c)
 
-	var req request.CreateMediaRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	header, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file")
 	}
+	defer file.Close()
 
-	// Validate request using validator middleware
-	if err := c.Validate(&req); err != nil {
-		return err // Returns HTTP 400 with validation error message
+	filename := c.FormValue("filename")
+	if filename == "" {
+		filename = header.Filename
 	}
 
-	m, err := h.service.Create(ctx, userID, req.Filename, req.Hash, req.Size, req.MimeType, req.StoragePath)
+	m, err := h.service.Upload(ctx, userID, filename, file)
 	if err != nil {
+		if errors.Is(err, mediaService.ErrInvalidFilename) || errors.Is(err, mediaService.ErrEmptyMedia) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, mediaService.ErrFilenameTaken) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, mediaService.ErrQuotaExceeded) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+		}
 		return err
 	}
 
 	return c.JSON(http.StatusCreated, mappers.ToMediaResponse(m))
 }
 
+// Content handles GET /api/v1/media/:id/content
+// @Summary Download a media file
+// @Description Stream a media file's content, or redirect to a presigned URL when the storage serves files directly
+// @Tags media
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "Media ID"
+// @Success 200 {file} file
+// @Success 302 "Redirect to a presigned URL"
+// @Failure 400 {object} response.ErrorResponse "Invalid media ID"
+// @Router /api/v1/media/{id}/content [get]
+func (h *MediaHandler) Content(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid media ID")
+	}
+
+	content, err := h.service.GetContent(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if content.URL != "" {
+		return c.Redirect(http.StatusFound, content.URL)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("inline", map[string]string{"filename": content.Media.GetFilename()}))
+	return c.Stream(http.StatusOK, content.Media.GetMimeType(), content.Data)
+}
+
 // FindByID handles GET /api/v1/media/:id
 // @Summary Get media record by ID
 // @Tags media
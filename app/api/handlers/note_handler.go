@@ -309,6 +309,46 @@ func (h *NoteHandler) FindDuplicates(c echo.Context) error {
 	return c.JSON(http.StatusOK, mappers.ToFindDuplicatesResponse(result))
 }
 
+// FindReplace handles POST /api/v1/notes/find-replace
+// @Summary Find and replace across notes
+// @Description Find and replace text in the fields (or tags, if in_tags is true) of the notes matching a search query. All notes are changed in one operation that can be undone.
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.FindReplaceRequest true "Find and replace request"
+// @Success 200 {object} response.FindReplaceResponse
+// @Router /api/v1/notes/find-replace [post]
+func (h *NoteHandler) FindReplace(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	var req request.FindReplaceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	// Validate request using validator middleware
+	if err := c.Validate(&req); err != nil {
+		return err // Returns HTTP 400 with validation error message
+	}
+
+	changed, err := h.service.FindReplace(ctx, userID, note.FindReplace{
+		Query:     req.Query,
+		Field:     req.FieldName,
+		InTags:    req.InTags,
+		Find:      req.Find,
+		Replace:   req.Replace,
+		Regex:     req.Regex,
+		MatchCase: req.MatchCase,
+	})
+	if err != nil {
+		return handleNoteError(err)
+	}
+
+	return c.JSON(http.StatusOK, response.FindReplaceResponse{Changed: changed})
+}
+
 // Export handles POST /api/v1/notes/export
 // @Summary Export selected notes
 // @Description Export selected notes in the specified format (apkg or text). Optionally include media files and scheduling information.
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 
@@ -55,6 +56,30 @@ func (h *ReviewHandler) Create(c echo.Context) error {
 	return c.JSON(http.StatusCreated, mappers.ToReviewResponse(review))
 }
 
+// Undo handles POST /api/v1/reviews/undo
+// @Summary Undo the last review
+// @Description Deletes the most recent review and restores the card's scheduling state from before it was answered
+// @Tags reviews
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.CardResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/reviews/undo [post]
+func (h *ReviewHandler) Undo(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+
+	card, err := h.service.Undo(ctx, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToCardResponse(card))
+}
+
 // FindByCardID handles GET /api/v1/cards/:cardID/reviews
 // @Summary List reviews for a card
 // @Tags reviews
@@ -76,3 +101,29 @@ func (h *ReviewHandler) FindByCardID(c echo.Context) error {
 	return c.JSON(http.StatusOK, mappers.ToReviewResponseList(reviews))
 }
 
+
+// NextIntervals handles GET /api/v1/cards/:id/next-intervals
+// @Summary Preview answer button intervals
+// @Description Runs the card's scheduler for every rating without saving and returns the resulting state, interval and due date
+// @Tags reviews
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Card ID"
+// @Success 200 {object} response.NextIntervalsResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/cards/{id}/next-intervals [get]
+func (h *ReviewHandler) NextIntervals(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	cardID, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	previews, err := h.service.PreviewIntervals(ctx, userID, cardID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToNextIntervalsResponse(cardID, previews))
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/felipesantos/anki-backend/app/api/middlewares"
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 )
 
@@ -59,7 +60,13 @@ func (h *SearchHandler) SearchAdvanced(c echo.Context) error {
 	}
 
 	// Call service
-	result, err := h.service.SearchAdvanced(ctx, userID, req.Query, req.Type, req.Limit, req.Offset)
+	page := search.PageRequest{
+		Sort:   search.SortOrder{Column: req.SortColumn, Direction: req.SortDirection},
+		Limit:  req.Limit,
+		Offset: req.Offset,
+		Cursor: req.Cursor,
+	}
+	result, err := h.service.SearchAdvanced(ctx, userID, req.Query, req.Type, page)
 	if err != nil {
 		return handleSearchError(err)
 	}
@@ -79,8 +86,9 @@ func (h *SearchHandler) SearchAdvanced(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, response.SearchResult{
-		Data:  responseData,
-		Total: result.Total,
+		Data:       responseData,
+		Total:      result.Total,
+		NextCursor: result.NextCursor,
 	})
 }
 
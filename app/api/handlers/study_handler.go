@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/felipesantos/anki-backend/app/api/mappers"
+	"github.com/felipesantos/anki-backend/app/api/middlewares"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// StudyHandler handles study session HTTP requests
+type StudyHandler struct {
+	service primary.IStudyService
+}
+
+// NewStudyHandler creates a new StudyHandler instance
+func NewStudyHandler(service primary.IStudyService) *StudyHandler {
+	return &StudyHandler{
+		service: service,
+	}
+}
+
+// Next handles GET /api/v1/decks/:id/study/next
+// @Summary Get the next card to study
+// @Description Returns the next card to study in a deck and its subdecks, honouring the deck's daily limits, with the number of new, learning and review cards left for today
+// @Tags study
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deck ID"
+// @Success 200 {object} response.StudyNextResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/decks/{id}/study/next [get]
+func (h *StudyHandler) Next(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	deckID, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	queue, err := h.service.Next(ctx, userID, deckID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToStudyNextResponse(queue))
+}
+
+// NextFiltered handles GET /api/v1/filtered-decks/:id/study/next
+// @Summary Get the next card to study in a filtered deck
+// @Description Returns the next card to study in a filtered deck, in the deck's order and without daily limits, with the number of new, learning and review cards left in it
+// @Tags study
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Filtered Deck ID"
+// @Success 200 {object} response.StudyNextResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/filtered-decks/{id}/study/next [get]
+func (h *StudyHandler) NextFiltered(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := middlewares.GetUserID(c)
+	filteredDeckID, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	queue, err := h.service.NextFiltered(ctx, userID, filteredDeckID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, mappers.ToStudyNextResponse(queue))
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -9,6 +10,7 @@ import (
 	"github.com/felipesantos/anki-backend/app/api/mappers"
 	"github.com/felipesantos/anki-backend/app/api/middlewares"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	syncService "github.com/felipesantos/anki-backend/core/services/sync"
 )
 
 // SyncMetaHandler handles synchronization metadata HTTP requests
@@ -50,6 +52,7 @@ func (h *SyncMetaHandler) FindMe(c echo.Context) error {
 // @Security BearerAuth
 // @Param request body request.UpdateSyncMetaRequest true "Update request"
 // @Success 200 {object} response.SyncMetaResponse
+// @Failure 400 {object} response.ErrorResponse "USN ahead of the server"
 // @Router /api/v1/sync/meta [put]
 func (h *SyncMetaHandler) Update(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -67,6 +70,9 @@ func (h *SyncMetaHandler) Update(c echo.Context) error {
 
 	sm, err := h.service.Update(ctx, userID, req.ClientID, req.LastSyncUSN)
 	if err != nil {
+		if errors.Is(err, syncService.ErrInvalidSyncUSN) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
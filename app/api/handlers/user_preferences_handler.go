@@ -78,6 +78,7 @@ func (h *UserPreferencesHandler) Update(c echo.Context) error {
 		WithTheme(valueobjects.ThemeType(req.Theme)).
 		WithAutoSync(req.AutoSync).
 		WithNextDayStartsAt(req.NextDayStartsAt).
+		WithTimezone(req.Timezone).
 		WithLearnAheadLimit(req.LearnAheadLimit).
 		WithTimeboxTimeLimit(req.TimeboxTimeLimit).
 		WithVideoDriver(req.VideoDriver).
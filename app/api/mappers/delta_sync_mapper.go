@@ -0,0 +1,113 @@
+package mappers
+
+import (
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// ToSyncPush converts a push request DTO to the changes it holds
+func ToSyncPush(req *request.SyncPushRequest) *primary.SyncPush {
+	push := &primary.SyncPush{}
+	for _, c := range req.NoteTypes {
+		push.NoteTypes = append(push.NoteTypes, primary.SyncNoteTypeChange{
+			Action:        c.Action,
+			ID:            c.ID,
+			ClientRef:     c.ClientRef,
+			Name:          c.Name,
+			FieldsJSON:    c.FieldsJSON,
+			CardTypesJSON: c.CardTypesJSON,
+			TemplatesJSON: c.TemplatesJSON,
+			ModifiedAt:    c.ModifiedAt,
+		})
+	}
+	for _, c := range req.Decks {
+		push.Decks = append(push.Decks, primary.SyncDeckChange{
+			Action:      c.Action,
+			ID:          c.ID,
+			ClientRef:   c.ClientRef,
+			Name:        c.Name,
+			ParentID:    c.ParentID,
+			OptionsJSON: c.OptionsJSON,
+			ModifiedAt:  c.ModifiedAt,
+		})
+	}
+	for _, c := range req.Notes {
+		push.Notes = append(push.Notes, primary.SyncNoteChange{
+			Action:     c.Action,
+			ID:         c.ID,
+			ClientRef:  c.ClientRef,
+			NoteTypeID: c.NoteTypeID,
+			DeckID:     c.DeckID,
+			FieldsJSON: c.FieldsJSON,
+			Tags:       c.Tags,
+			ModifiedAt: c.ModifiedAt,
+		})
+	}
+	for _, c := range req.Cards {
+		push.Cards = append(push.Cards, primary.SyncCardChange{
+			ID:         c.ID,
+			Flag:       c.Flag,
+			Suspended:  c.Suspended,
+			Buried:     c.Buried,
+			ModifiedAt: c.ModifiedAt,
+		})
+	}
+	for _, c := range req.Reviews {
+		push.Reviews = append(push.Reviews, primary.SyncReviewChange{
+			ClientRef:  c.ClientRef,
+			CardID:     c.CardID,
+			Rating:     c.Rating,
+			TimeMs:     c.TimeMs,
+			ReviewedAt: c.ReviewedAt,
+		})
+	}
+	return push
+}
+
+// ToSyncChangesResponse converts fetched changes to Response DTO
+func ToSyncChangesResponse(changes *primary.SyncChanges) *response.SyncChangesResponse {
+	if changes == nil {
+		return nil
+	}
+	return &response.SyncChangesResponse{
+		USN:       changes.USN,
+		Full:      changes.Full,
+		Notes:     ToNoteResponseList(changes.Notes),
+		Cards:     ToCardResponseList(changes.Cards),
+		Decks:     ToDeckResponseList(changes.Decks),
+		NoteTypes: ToNoteTypeResponseList(changes.NoteTypes),
+		Reviews:   ToReviewResponseList(changes.Reviews),
+		Media:     ToMediaResponseList(changes.Media),
+		Deleted:   ToDeletionLogResponseList(changes.Deleted),
+	}
+}
+
+// ToSyncPushResponse converts a push result to Response DTO
+func ToSyncPushResponse(result *primary.SyncPushResult) *response.SyncPushResponse {
+	if result == nil {
+		return nil
+	}
+	res := &response.SyncPushResponse{
+		Applied:   result.Applied,
+		Created:   make([]*response.SyncCreatedResponse, len(result.Created)),
+		Conflicts: make([]*response.SyncConflictResponse, len(result.Conflicts)),
+	}
+	for i, c := range result.Created {
+		res.Created[i] = &response.SyncCreatedResponse{
+			ObjectType: c.ObjectType,
+			ClientRef:  c.ClientRef,
+			ID:         c.ID,
+		}
+	}
+	for i, c := range result.Conflicts {
+		res.Conflicts[i] = &response.SyncConflictResponse{
+			ObjectType: c.ObjectType,
+			ID:         c.ID,
+			ClientRef:  c.ClientRef,
+			Reason:     c.Reason,
+			Message:    c.Message,
+		}
+	}
+	return res
+}
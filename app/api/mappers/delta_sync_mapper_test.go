@@ -0,0 +1,96 @@
+package mappers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/felipesantos/anki-backend/app/api/dtos/request"
+	deletionlog "github.com/felipesantos/anki-backend/core/domain/entities/deletion_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+func TestToSyncPush(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	flag := 3
+	req := &request.SyncPushRequest{
+		Notes: []request.SyncNoteChangeRequest{
+			{Action: "create", ClientRef: "n1", NoteTypeID: 2, DeckID: 5, FieldsJSON: `{"Front":"a"}`, Tags: []string{"x"}, ModifiedAt: modified},
+		},
+		Cards: []request.SyncCardChangeRequest{
+			{ID: 9, Flag: &flag, ModifiedAt: modified},
+		},
+		Reviews: []request.SyncReviewChangeRequest{
+			{ClientRef: "r1", CardID: 9, Rating: 3, TimeMs: 1500, ReviewedAt: modified},
+		},
+	}
+
+	push := ToSyncPush(req)
+
+	require.Len(t, push.Notes, 1)
+	assert.Equal(t, primary.SyncNoteChange{
+		Action:     primary.SyncActionCreate,
+		ClientRef:  "n1",
+		NoteTypeID: 2,
+		DeckID:     5,
+		FieldsJSON: `{"Front":"a"}`,
+		Tags:       []string{"x"},
+		ModifiedAt: modified,
+	}, push.Notes[0])
+	require.Len(t, push.Cards, 1)
+	assert.Equal(t, &flag, push.Cards[0].Flag)
+	assert.Nil(t, push.Cards[0].Suspended)
+	require.Len(t, push.Reviews, 1)
+	assert.Equal(t, "r1", push.Reviews[0].ClientRef)
+	assert.Empty(t, push.Decks)
+	assert.Empty(t, push.NoteTypes)
+}
+
+func TestToSyncChangesResponse(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		n, _ := note.NewBuilder().WithID(10).WithUserID(1).Build()
+		dl, _ := deletionlog.NewBuilder().WithID(4).WithUserID(1).WithObjectType(deletionlog.ObjectTypeCard).WithObjectID(21).Build()
+
+		res := ToSyncChangesResponse(&primary.SyncChanges{
+			USN:     7,
+			Notes:   []*note.Note{n},
+			Deleted: []*deletionlog.DeletionLog{dl},
+		})
+
+		require.NotNil(t, res)
+		assert.Equal(t, 7, res.USN)
+		assert.False(t, res.Full)
+		require.Len(t, res.Notes, 1)
+		assert.Equal(t, int64(10), res.Notes[0].ID)
+		require.Len(t, res.Deleted, 1)
+		assert.Equal(t, int64(21), res.Deleted[0].ObjectID)
+		assert.NotNil(t, res.Cards)
+		assert.Empty(t, res.Cards)
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		assert.Nil(t, ToSyncChangesResponse(nil))
+	})
+}
+
+func TestToSyncPushResponse(t *testing.T) {
+	res := ToSyncPushResponse(&primary.SyncPushResult{
+		Applied: 2,
+		Created: []primary.SyncCreated{{ObjectType: "note", ClientRef: "n1", ID: 40}},
+		Conflicts: []primary.SyncConflict{
+			{ObjectType: "deck", ID: 30, Reason: primary.SyncConflictDeleted},
+		},
+	})
+
+	require.NotNil(t, res)
+	assert.Equal(t, 2, res.Applied)
+	require.Len(t, res.Created, 1)
+	assert.Equal(t, "n1", res.Created[0].ClientRef)
+	assert.Equal(t, int64(40), res.Created[0].ID)
+	require.Len(t, res.Conflicts, 1)
+	assert.Equal(t, primary.SyncConflictDeleted, res.Conflicts[0].Reason)
+	assert.Nil(t, ToSyncPushResponse(nil))
+}
@@ -11,15 +11,18 @@ func ToFilteredDeckResponse(fd *filtereddeck.FilteredDeck) *response.FilteredDec
 		return nil
 	}
 	return &response.FilteredDeckResponse{
-		ID:           fd.GetID(),
-		UserID:       fd.GetUserID(),
-		Name:         fd.GetName(),
-		SearchFilter: fd.GetSearchFilter(),
-		Limit:        fd.GetLimitCards(),
-		OrderBy:      fd.GetOrderBy(),
-		Reschedule:   fd.GetReschedule(),
-		CreatedAt:    fd.GetCreatedAt(),
-		UpdatedAt:    fd.GetUpdatedAt(),
+		ID:            fd.GetID(),
+		UserID:        fd.GetUserID(),
+		Name:          fd.GetName(),
+		SearchFilter:  fd.GetSearchFilter(),
+		SecondFilter:  fd.GetSecondFilter(),
+		Limit:         fd.GetLimitCards(),
+		SecondLimit:   fd.GetSecondLimitCards(),
+		OrderBy:       fd.GetOrderBy(),
+		Reschedule:    fd.GetReschedule(),
+		CreatedAt:     fd.GetCreatedAt(),
+		UpdatedAt:     fd.GetUpdatedAt(),
+		LastRebuildAt: fd.GetLastRebuildAt(),
 	}
 }
 
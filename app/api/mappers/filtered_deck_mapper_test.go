@@ -35,6 +35,8 @@ func TestToFilteredDeckResponse(t *testing.T) {
 		assert.Equal(t, fd.GetReschedule(), res.Reschedule)
 		assert.Equal(t, fd.GetCreatedAt(), res.CreatedAt)
 		assert.Equal(t, fd.GetUpdatedAt(), res.UpdatedAt)
+		assert.Nil(t, res.SecondFilter)
+		assert.Nil(t, res.LastRebuildAt)
 	})
 
 	t.Run("NilEntity", func(t *testing.T) {
@@ -0,0 +1,27 @@
+package mappers
+
+import (
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+)
+
+// ToFSRSEvaluationResponse converts an FSRSOptimizationResult to an FSRSEvaluationResponse DTO
+func ToFSRSEvaluationResponse(r *scheduler.FSRSOptimizationResult) *response.FSRSEvaluationResponse {
+	if r == nil {
+		return nil
+	}
+	return &response.FSRSEvaluationResponse{
+		ReviewCount: r.ReviewCount,
+		Before:      toFSRSMetricsResponse(r.Before),
+		After:       toFSRSMetricsResponse(r.After),
+		Weights:     r.Weights,
+	}
+}
+
+// toFSRSMetricsResponse converts FSRSMetrics to an FSRSMetricsResponse DTO
+func toFSRSMetricsResponse(m scheduler.FSRSMetrics) response.FSRSMetricsResponse {
+	return response.FSRSMetricsResponse{
+		LogLoss: m.LogLoss,
+		RMSE:    m.RMSE,
+	}
+}
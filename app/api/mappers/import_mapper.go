@@ -0,0 +1,46 @@
+package mappers
+
+import (
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// ToImportResponse converts an ImportResult to an ImportResponse DTO
+func ToImportResponse(result *primary.ImportResult) *response.ImportResponse {
+	if result == nil {
+		return nil
+	}
+	return &response.ImportResponse{
+		NoteTypesAdded: result.NoteTypesAdded,
+		DecksAdded:     result.DecksAdded,
+		NotesAdded:     result.NotesAdded,
+		NotesUpdated:   result.NotesUpdated,
+		NotesSkipped:   result.NotesSkipped,
+		CardsAdded:     result.CardsAdded,
+		ReviewsAdded:   result.ReviewsAdded,
+		MediaAdded:     result.MediaAdded,
+	}
+}
+
+// ToTextImportResponse converts a TextImportResult to a TextImportResponse DTO
+func ToTextImportResponse(result *primary.TextImportResult) *response.TextImportResponse {
+	if result == nil {
+		return nil
+	}
+	rows := make([]response.TextImportRowResponse, len(result.Rows))
+	for i, row := range result.Rows {
+		rows[i] = response.TextImportRowResponse{
+			Line:    row.Line,
+			Status:  row.Status,
+			NoteID:  row.NoteID,
+			Message: row.Message,
+		}
+	}
+	return &response.TextImportResponse{
+		Added:   result.Added,
+		Updated: result.Updated,
+		Skipped: result.Skipped,
+		Errors:  result.Errors,
+		Rows:    rows,
+	}
+}
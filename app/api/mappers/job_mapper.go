@@ -0,0 +1,23 @@
+package mappers
+
+import (
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// ToJobStatusResponse converts a Job to a JobStatusResponse DTO
+func ToJobStatusResponse(job *secondary.Job) *response.JobStatusResponse {
+	if job == nil {
+		return nil
+	}
+	return &response.JobStatusResponse{
+		ID:          job.ID,
+		Type:        job.Type,
+		Status:      string(job.Status),
+		Progress:    job.Progress,
+		Result:      job.Result,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+}
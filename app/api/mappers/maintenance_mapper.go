@@ -0,0 +1,39 @@
+package mappers
+
+import (
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// ToCheckMediaResponse converts a MediaCheckResult to a CheckMediaResponse DTO
+func ToCheckMediaResponse(result *primary.MediaCheckResult) *response.CheckMediaResponse {
+	if result == nil {
+		return nil
+	}
+	missing := make([]response.MissingMediaResponse, len(result.Missing))
+	for i, m := range result.Missing {
+		missing[i] = response.MissingMediaResponse{
+			Filename: m.Filename,
+			NoteIDs:  m.NoteIDs,
+		}
+	}
+	misnamed := make([]response.MisnamedMediaResponse, len(result.Misnamed))
+	for i, m := range result.Misnamed {
+		misnamed[i] = response.MisnamedMediaResponse{
+			Media:         ToMediaResponse(m.Media),
+			SuggestedName: m.SuggestedName,
+		}
+	}
+	orphaned := result.Orphaned
+	if orphaned == nil {
+		orphaned = []string{}
+	}
+	return &response.CheckMediaResponse{
+		LogID:       result.LogID,
+		IssuesFound: result.IssuesFound(),
+		Unused:      ToMediaResponseList(result.Unused),
+		Missing:     missing,
+		Misnamed:    misnamed,
+		Orphaned:    orphaned,
+	}
+}
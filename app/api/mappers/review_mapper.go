@@ -1,8 +1,11 @@
 package mappers
 
 import (
+	"time"
+
 	"github.com/felipesantos/anki-backend/app/api/dtos/response"
 	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
 )
 
 // ToReviewResponse converts a Review domain entity to a ReviewResponse DTO
@@ -28,3 +31,21 @@ func ToReviewResponseList(reviews []*review.Review) []*response.ReviewResponse {
 	return res
 }
 
+// ToNextIntervalsResponse converts interval previews to a NextIntervalsResponse DTO
+func ToNextIntervalsResponse(cardID int64, previews []scheduler.IntervalPreview) *response.NextIntervalsResponse {
+	intervals := make([]response.NextIntervalResponse, len(previews))
+	for i, p := range previews {
+		intervals[i] = response.NextIntervalResponse{
+			Rating:       p.Rating,
+			State:        string(p.State),
+			Interval:     p.Interval,
+			DelaySeconds: p.DelaySeconds,
+			Label:        scheduler.IntervalLabel(p.DelaySeconds),
+			Due:          time.UnixMilli(p.Due).UTC(),
+		}
+	}
+	return &response.NextIntervalsResponse{
+		CardID:    cardID,
+		Intervals: intervals,
+	}
+}
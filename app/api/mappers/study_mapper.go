@@ -0,0 +1,39 @@
+package mappers
+
+import (
+	"github.com/felipesantos/anki-backend/app/api/dtos/response"
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// ToStudyNextResponse converts a StudyQueue to a StudyNextResponse DTO
+func ToStudyNextResponse(q *card.StudyQueue) *response.StudyNextResponse {
+	if q == nil {
+		return nil
+	}
+	return &response.StudyNextResponse{
+		Card:  ToCardResponse(q.Next),
+		Queue: string(q.Queue),
+		Counts: response.StudyCountsResponse{
+			New:    q.NewCount,
+			Learn:  q.LearnCount,
+			Review: q.ReviewCount,
+		},
+	}
+}
+
+// ToCustomStudyResponse converts a CustomStudyResult to a CustomStudyResponse DTO
+func ToCustomStudyResponse(r *primary.CustomStudyResult) *response.CustomStudyResponse {
+	if r == nil {
+		return nil
+	}
+	resp := &response.CustomStudyResponse{CardCount: r.CardCount}
+	if r.Extension != nil {
+		resp.NewLimitExtension = &r.Extension.NewCards
+		resp.ReviewLimitExtension = &r.Extension.Reviews
+	}
+	if r.FilteredDeck != nil {
+		resp.FilteredDeck = ToFilteredDeckResponse(r.FilteredDeck)
+	}
+	return resp
+}
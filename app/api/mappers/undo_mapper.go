@@ -16,6 +16,7 @@ func ToUndoHistoryResponse(uh *undohistory.UndoHistory) *response.UndoHistoryRes
 		OperationType: uh.GetOperationType(),
 		OperationData: uh.GetOperationData(),
 		CreatedAt:     uh.GetCreatedAt(),
+		UndoneAt:      uh.GetUndoneAt(),
 	}
 }
 
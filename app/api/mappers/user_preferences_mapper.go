@@ -17,6 +17,7 @@ func ToUserPreferencesResponse(up *userpreferences.UserPreferences) *response.Us
 		Theme:                   string(up.GetTheme()),
 		AutoSync:                up.GetAutoSync(),
 		NextDayStartsAt:         up.GetNextDayStartsAt(),
+		Timezone:                up.Location().String(),
 		LearnAheadLimit:         up.GetLearnAheadLimit(),
 		TimeboxTimeLimit:        up.GetTimeboxTimeLimit(),
 		VideoDriver:             up.GetVideoDriver(),
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/felipesantos/anki-backend/app/api/handlers"
+	"github.com/felipesantos/anki-backend/dicontainer"
+)
+
+// RegisterAnkiSyncRoutes registers the Anki sync and media sync protocol routes
+// Anki clients authenticate with the host key in the anki-sync header instead of a bearer token
+func (r *Router) RegisterAnkiSyncRoutes() {
+	ankiSyncService := dicontainer.GetAnkiSyncService()
+	ankiMediaSyncService := dicontainer.GetAnkiMediaSyncService()
+	ankiSyncHandler := handlers.NewAnkiSyncHandler(ankiSyncService)
+	ankiMediaSyncHandler := handlers.NewAnkiMediaSyncHandler(ankiSyncService, ankiMediaSyncService)
+
+	sync := r.echo.Group("/sync")
+	sync.POST("/:method", ankiSyncHandler.Handle)
+	sync.POST("/media/:method", ankiMediaSyncHandler.Handle)
+
+	// Anki clients send media requests to msync/ next to the sync/ endpoint
+	r.echo.POST("/msync/:method", ankiMediaSyncHandler.Handle)
+}
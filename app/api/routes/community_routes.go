@@ -41,5 +41,7 @@ func (r *Router) RegisterCommunityRoutes() {
 	audit.GET("/deletions", auditHandler.GetDeletionLogs)
 	audit.GET("/undo", auditHandler.GetUndoHistory)
 	audit.DELETE("/undo/:id", auditHandler.DeleteUndoHistory)
+	audit.POST("/undo", auditHandler.Undo)
+	audit.POST("/redo", auditHandler.Redo)
 }
 
@@ -12,9 +12,11 @@ func (r *Router) RegisterContentRoutes() {
 	noteTypeService := dicontainer.GetNoteTypeService()
 	exportService := dicontainer.GetExportService()
 	deletionLogService := dicontainer.GetDeletionLogService()
+	importService := dicontainer.GetImportService()
 
 	noteHandler := handlers.NewNoteHandler(noteService, exportService, deletionLogService)
 	noteTypeHandler := handlers.NewNoteTypeHandler(noteTypeService)
+	importHandler := handlers.NewImportHandler(importService)
 
 	// Auth middleware
 	authMiddleware := middlewares.AuthMiddleware(r.jwtSvc, r.rdb)
@@ -44,6 +46,12 @@ func (r *Router) RegisterContentRoutes() {
 	
 	// Note Find Duplicates (must be before all other routes to avoid route conflicts)
 	notes.POST("/find-duplicates", noteHandler.FindDuplicates)
+
+	// Note Text Import (must be before /:id routes to avoid route conflicts)
+	notes.POST("/import/text", importHandler.ImportText)
+
+	// Note Find and Replace (must be before /:id routes to avoid route conflicts)
+	notes.POST("/find-replace", noteHandler.FindReplace)
 	
 	notes.POST("", noteHandler.Create)
 	notes.GET("", noteHandler.FindAll)
@@ -57,5 +65,9 @@ func (r *Router) RegisterContentRoutes() {
 
 	// Note Copy
 	notes.POST("/:id/copy", noteHandler.Copy)
+
+	// Import
+	imports := v1.Group("/import")
+	imports.POST("/package", importHandler.ImportPackage)
 }
 
@@ -9,7 +9,9 @@ import (
 // RegisterMaintenanceRoutes registers maintenance-related routes on the Router
 func (r *Router) RegisterMaintenanceRoutes() {
 	cardService := dicontainer.GetCardService()
-	handler := handlers.NewMaintenanceHandler(cardService)
+	mediaCheckService := dicontainer.GetMediaCheckService()
+	jobService := dicontainer.GetJobService()
+	handler := handlers.NewMaintenanceHandler(cardService, mediaCheckService, jobService)
 
 	// Create maintenance group
 	maintenanceGroup := r.echo.Group("/api/v1/maintenance")
@@ -21,4 +23,8 @@ func (r *Router) RegisterMaintenanceRoutes() {
 	// Register routes
 	maintenanceGroup.GET("/empty-cards", handler.GetEmptyCards)
 	maintenanceGroup.POST("/empty-cards/cleanup", handler.CleanupEmptyCards)
+	maintenanceGroup.POST("/check-database", handler.CheckDatabase)
+	maintenanceGroup.POST("/check-media", handler.CheckMedia)
+	maintenanceGroup.POST("/check-media/delete-unused", handler.DeleteUnusedMedia)
+	maintenanceGroup.POST("/check-media/tag-missing", handler.TagMissingMedia)
 }
@@ -75,6 +75,7 @@ func (r *Router) RegisterAll() {
 	r.RegisterContentRoutes()
 	r.RegisterUserRoutes()
 	r.RegisterSystemRoutes()
+	r.RegisterAnkiSyncRoutes()
 	r.RegisterCommunityRoutes()
 	r.RegisterSearchRoutes()
 	r.RegisterMaintenanceRoutes()
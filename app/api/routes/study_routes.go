@@ -14,6 +14,10 @@ func (r *Router) RegisterStudyRoutes() {
 	filteredDeckService := dicontainer.GetFilteredDeckService()
 	cardService := dicontainer.GetCardService()
 	reviewService := dicontainer.GetReviewService()
+	studyService := dicontainer.GetStudyService()
+	customStudyService := dicontainer.GetCustomStudyService()
+	fsrsOptimizerService := dicontainer.GetFSRSOptimizerService()
+	jobService := dicontainer.GetJobService()
 
 	deckHandler := handlers.NewDeckHandler(deckService)
 	presetHandler := handlers.NewDeckOptionsPresetHandler(presetService)
@@ -21,6 +25,9 @@ func (r *Router) RegisterStudyRoutes() {
 	filteredDeckHandler := handlers.NewFilteredDeckHandler(filteredDeckService)
 	cardHandler := handlers.NewCardHandler(cardService)
 	reviewHandler := handlers.NewReviewHandler(reviewService)
+	studyHandler := handlers.NewStudyHandler(studyService)
+	customStudyHandler := handlers.NewCustomStudyHandler(customStudyService)
+	fsrsHandler := handlers.NewFSRSHandler(fsrsOptimizerService, jobService)
 
 	// Auth middleware
 	authMiddleware := middlewares.AuthMiddleware(r.jwtSvc, r.rdb)
@@ -34,6 +41,8 @@ func (r *Router) RegisterStudyRoutes() {
 	decks.GET("", deckHandler.FindAll)
 	decks.GET("/:id", deckHandler.FindByID)
 	decks.GET("/:id/stats", deckStatsHandler.GetStats)
+	decks.GET("/:id/study/next", studyHandler.Next)
+	decks.POST("/:id/custom-study", customStudyHandler.CustomStudy)
 	decks.GET("/:id/options", deckHandler.GetOptions)
 	decks.PUT("/:id/options", deckHandler.UpdateOptions)
 	decks.PUT("/:id", deckHandler.Update)
@@ -46,6 +55,8 @@ func (r *Router) RegisterStudyRoutes() {
 	presets.PUT("/:id", presetHandler.Update)
 	presets.DELETE("/:id", presetHandler.Delete)
 	presets.POST("/:id/apply", presetHandler.ApplyToDecks)
+	presets.POST("/:id/fsrs/optimize", fsrsHandler.Optimize)
+	presets.POST("/:id/fsrs/evaluate", fsrsHandler.Evaluate)
 
 	// Filtered Decks
 	filteredDecks := v1.Group("/filtered-decks")
@@ -53,6 +64,10 @@ func (r *Router) RegisterStudyRoutes() {
 	filteredDecks.GET("", filteredDeckHandler.FindAll)
 	filteredDecks.PUT("/:id", filteredDeckHandler.Update)
 	filteredDecks.DELETE("/:id", filteredDeckHandler.Delete)
+	filteredDecks.POST("/:id/rebuild", filteredDeckHandler.Rebuild)
+	filteredDecks.POST("/:id/empty", filteredDeckHandler.Empty)
+	filteredDecks.GET("/:id/cards", filteredDeckHandler.FindCards)
+	filteredDecks.GET("/:id/study/next", studyHandler.NextFiltered)
 
 	// Cards (via Decks)
 	decks.GET("/:deckID/cards", cardHandler.FindByDeckID)
@@ -65,6 +80,7 @@ func (r *Router) RegisterStudyRoutes() {
 	cards.POST("/reposition", cardHandler.Reposition)
 	cards.GET("/:id/position", cardHandler.GetPosition)
 	cards.GET("/:id/info", cardHandler.GetInfo) // Must be before /:id to avoid route conflicts
+	cards.GET("/:id/next-intervals", reviewHandler.NextIntervals)
 	cards.GET("/:id", cardHandler.FindByID)
 	cards.POST("/:id/suspend", cardHandler.Suspend)
 	cards.POST("/:id/unsuspend", cardHandler.Unsuspend)
@@ -78,6 +94,7 @@ func (r *Router) RegisterStudyRoutes() {
 	// Reviews
 	reviews := v1.Group("/reviews")
 	reviews.POST("", reviewHandler.Create)
+	reviews.POST("/undo", reviewHandler.Undo)
 
 	// Card Reviews
 	cards.GET("/:cardID/reviews", reviewHandler.FindByCardID)
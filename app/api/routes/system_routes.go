@@ -6,17 +6,21 @@ import (
 	"github.com/felipesantos/anki-backend/dicontainer"
 )
 
-// RegisterSystemRoutes registers system-related routes (addons, backups, media, sync)
+// RegisterSystemRoutes registers system-related routes (addons, backups, media, jobs, sync)
 func (r *Router) RegisterSystemRoutes() {
 	addOnService := dicontainer.GetAddOnService()
 	backupService := dicontainer.GetBackupService()
 	mediaService := dicontainer.GetMediaService()
 	syncMetaService := dicontainer.GetSyncMetaService()
+	deltaSyncService := dicontainer.GetDeltaSyncService()
+	jobService := dicontainer.GetJobService()
 
 	addOnHandler := handlers.NewAddOnHandler(addOnService)
 	backupHandler := handlers.NewBackupHandler(backupService)
 	mediaHandler := handlers.NewMediaHandler(mediaService)
 	syncMetaHandler := handlers.NewSyncMetaHandler(syncMetaService)
+	deltaSyncHandler := handlers.NewDeltaSyncHandler(deltaSyncService)
+	jobHandler := handlers.NewJobHandler(jobService)
 
 	// Auth middleware
 	authMiddleware := middlewares.AuthMiddleware(r.jwtSvc, r.rdb)
@@ -40,14 +44,21 @@ func (r *Router) RegisterSystemRoutes() {
 
 	// Media
 	media := v1.Group("/media")
-	media.POST("", mediaHandler.Create)
+	media.POST("", mediaHandler.Upload)
 	media.GET("", mediaHandler.FindAll)
 	media.GET("/:id", mediaHandler.FindByID)
+	media.GET("/:id/content", mediaHandler.Content)
 	media.DELETE("/:id", mediaHandler.Delete)
 
+	// Jobs
+	jobs := v1.Group("/jobs")
+	jobs.GET("/:id", jobHandler.GetStatus)
+
 	// Sync
 	sync := v1.Group("/sync")
 	sync.GET("/meta", syncMetaHandler.FindMe)
 	sync.PUT("/meta", syncMetaHandler.Update)
+	sync.GET("/changes", deltaSyncHandler.Changes)
+	sync.POST("/push", deltaSyncHandler.Push)
 }
 
@@ -178,8 +178,14 @@ func initJobs(cfg *config.Config, rdb *redis.RedisRepository, log *slog.Logger)
 	jobQueue := infraJobs.NewRedisQueue(rdb.Client, cfg.Jobs.RedisQueueKey)
 	jobRegistry := infraJobs.NewJobRegistry()
 	jobRegistry.Register(handlers.NewExampleHandler("example_job"))
+	jobRegistry.Register(handlers.NewFSRSOptimizeHandler(dicontainer.GetFSRSOptimizerService(), jobQueue))
+	jobRegistry.Register(handlers.NewUnburyHandler(dicontainer.GetStudyService(), jobQueue))
+	jobRegistry.Register(handlers.NewCheckDatabaseHandler(dicontainer.GetCheckDatabaseService(), jobQueue))
 	workerPool := infraJobs.NewWorkerPool(cfg.Jobs.WorkerCount, jobQueue, jobRegistry, log, cfg.Jobs.MaxRetries, cfg.Jobs.RetryDelaySeconds)
 	scheduler := infraJobs.NewScheduler(jobQueue, log)
+	if err := scheduler.Schedule(handlers.UnburyCronExpr, handlers.UnburyJobType, nil); err != nil {
+		log.Error("Failed to schedule unbury job", "error", err)
+	}
 	workerPool.Start()
 	scheduler.Start()
 	return workerPool, scheduler
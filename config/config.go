@@ -107,6 +107,7 @@ type StorageConfig struct {
 	CloudflareR2Key     string // Cloudflare R2 Access Key ID
 	CloudflareR2Secret  string // Cloudflare R2 Secret Access Key
 	CloudflareR2Endpoint string // Cloudflare R2 endpoint (optional, defaults to https://<account-id>.r2.cloudflarestorage.com)
+	MediaQuotaMB        int    // Maximum total media size per user in megabytes (0 = unlimited)
 }
 
 // LoggerConfig holds logger-related configuration
@@ -275,6 +276,7 @@ func loadConfig() (*Config, error) {
 			CloudflareR2Key:     getEnv("STORAGE_CLOUDFLARE_R2_KEY", ""),
 			CloudflareR2Secret:  getEnv("STORAGE_CLOUDFLARE_R2_SECRET", ""),
 			CloudflareR2Endpoint: getEnv("STORAGE_CLOUDFLARE_R2_ENDPOINT", ""),
+			MediaQuotaMB:        getEnvAsInt("STORAGE_MEDIA_QUOTA_MB", 1024),
 		},
 		Logger: LoggerConfig{
 			Level:       validateLogLevel(getEnv("LOG_LEVEL", "info")),
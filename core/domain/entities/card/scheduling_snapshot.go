@@ -0,0 +1,52 @@
+package card
+
+import (
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+// SchedulingSnapshot is a copy of a card's scheduling state, used to revert an answer
+type SchedulingSnapshot struct {
+	Due          int64                  `json:"due"`
+	Interval     int                    `json:"interval"`
+	Ease         int                    `json:"ease"`
+	Lapses       int                    `json:"lapses"`
+	Reps         int                    `json:"reps"`
+	State        valueobjects.CardState `json:"state"`
+	Suspended    bool                   `json:"suspended"`
+	Stability    *float64               `json:"stability,omitempty"`
+	Difficulty   *float64               `json:"difficulty,omitempty"`
+	LastReviewAt *time.Time             `json:"last_review_at,omitempty"`
+}
+
+// SchedulingSnapshot captures the card's current scheduling state
+func (c *Card) SchedulingSnapshot() SchedulingSnapshot {
+	return SchedulingSnapshot{
+		Due:          c.due,
+		Interval:     c.interval,
+		Ease:         c.ease,
+		Lapses:       c.lapses,
+		Reps:         c.reps,
+		State:        c.state,
+		Suspended:    c.suspended,
+		Stability:    c.stability,
+		Difficulty:   c.difficulty,
+		LastReviewAt: c.lastReviewAt,
+	}
+}
+
+// RestoreScheduling puts the card back into a previously captured scheduling state
+func (c *Card) RestoreScheduling(s SchedulingSnapshot) {
+	c.due = s.Due
+	c.interval = s.Interval
+	c.ease = s.Ease
+	c.lapses = s.Lapses
+	c.reps = s.Reps
+	c.state = s.State
+	c.suspended = s.Suspended
+	c.stability = s.Stability
+	c.difficulty = s.Difficulty
+	c.lastReviewAt = s.LastReviewAt
+	c.updatedAt = time.Now()
+}
@@ -0,0 +1,62 @@
+package card
+
+import (
+	"time"
+)
+
+// Snapshot is a copy of every user-visible property of a card, used to undo and redo card changes
+// Values are normalized to the precision stored in the database so that a snapshot taken in memory
+// compares equal to one taken after the card is loaded again
+type Snapshot struct {
+	NoteID     int64  `json:"note_id"`
+	CardTypeID int    `json:"card_type_id"`
+	DeckID     int64  `json:"deck_id"`
+	HomeDeckID *int64 `json:"home_deck_id,omitempty"`
+	Position   int    `json:"position"`
+	Flag       int    `json:"flag"`
+	Buried     bool   `json:"buried"`
+	SchedulingSnapshot
+}
+
+// Snapshot captures the card's current state
+func (c *Card) Snapshot() *Snapshot {
+	scheduling := c.SchedulingSnapshot()
+	scheduling.Stability = storedFloat(scheduling.Stability)
+	scheduling.Difficulty = storedFloat(scheduling.Difficulty)
+	if scheduling.LastReviewAt != nil {
+		t := scheduling.LastReviewAt.UTC().Truncate(time.Microsecond)
+		scheduling.LastReviewAt = &t
+	}
+
+	return &Snapshot{
+		NoteID:             c.noteID,
+		CardTypeID:         c.cardTypeID,
+		DeckID:             c.deckID,
+		HomeDeckID:         c.homeDeckID,
+		Position:           c.position,
+		Flag:               c.flag,
+		Buried:             c.buried,
+		SchedulingSnapshot: scheduling,
+	}
+}
+
+// RestoreSnapshot puts the card back into a previously captured state
+func (c *Card) RestoreSnapshot(s *Snapshot) {
+	c.noteID = s.NoteID
+	c.cardTypeID = s.CardTypeID
+	c.deckID = s.DeckID
+	c.homeDeckID = s.HomeDeckID
+	c.position = s.Position
+	c.flag = s.Flag
+	c.buried = s.Buried
+	c.RestoreScheduling(s.SchedulingSnapshot)
+}
+
+// storedFloat rounds an FSRS memory value to the REAL precision of its column
+func storedFloat(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	rounded := float64(float32(*v))
+	return &rounded
+}
@@ -0,0 +1,43 @@
+package card
+
+import (
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+// QueueType identifies the study queue a card is shown from
+type QueueType string
+
+const (
+	QueueTypeLearn  QueueType = "learn"
+	QueueTypeReview QueueType = "review"
+	QueueTypeNew    QueueType = "new"
+)
+
+// QueueFilters selects unsuspended, unburied cards for a study queue
+// New cards are ordered by position, other cards by due
+type QueueFilters struct {
+	DeckIDs   []int64
+	States    []valueobjects.CardState
+	DueBefore *int64 // Exclusive upper bound on due (milliseconds), ignored for new cards
+	Limit     int    // 0 = no limit
+}
+
+// StudyQueue is the next card to study in a deck and the number of cards left for today
+type StudyQueue struct {
+	Next        *Card
+	Queue       QueueType // Queue the next card comes from (empty if nothing is left)
+	NewCount    int
+	LearnCount  int
+	ReviewCount int
+}
+
+// IsInterdayLearning reports whether a learning or relearning card was last answered before
+// the given study day start, i.e. its current step spans at least one day
+func (c *Card) IsInterdayLearning(dayStart time.Time) bool {
+	if c.state != valueobjects.CardStateLearn && c.state != valueobjects.CardStateRelearn {
+		return false
+	}
+	return c.lastReviewAt == nil || c.lastReviewAt.Before(dayStart)
+}
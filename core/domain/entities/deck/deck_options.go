@@ -0,0 +1,179 @@
+package deck
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+// DeckOptions represents the scheduling configuration stored in a deck's optionsJSON
+// Missing keys fall back to the same defaults used by desktop Anki
+type DeckOptions struct {
+	// New cards
+	NewSteps           []float64 `json:"new_steps"`           // Learning steps in minutes
+	GraduatingInterval int       `json:"graduating_interval"` // Days
+	EasyInterval       int       `json:"easy_interval"`       // Days
+	StartingEase       float64   `json:"starting_ease"`       // Multiplier (2.5 = 250%)
+	NewPerDay          int       `json:"new_per_day"`
+
+	// Lapses
+	RelearnSteps     []float64                `json:"relearn_steps"`      // Relearning steps in minutes
+	LapseNewInterval float64                  `json:"lapse_new_interval"` // Multiplier applied to the interval on lapse
+	MinimumInterval  int                      `json:"minimum_interval"`   // Days
+	LeechThreshold   int                      `json:"leech_threshold"`    // Lapses (0 = disabled)
+	LeechAction      valueobjects.LeechAction `json:"leech_action"`
+
+	// Reviews
+	ReviewsPerDay    int     `json:"reviews_per_day"`
+	EasyBonus        float64 `json:"easy_bonus"`
+	HardInterval     float64 `json:"hard_interval"`
+	IntervalModifier float64 `json:"interval_modifier"`
+	MaximumInterval  int     `json:"maximum_interval"` // Days
+
+	// Burying (siblings are the other cards of the same note)
+	BuryNew              bool `json:"bury_new"`               // Bury new siblings after answering a card
+	BuryReviews          bool `json:"bury_reviews"`           // Bury review siblings due today
+	BuryInterdayLearning bool `json:"bury_interday_learning"` // Bury interday learning siblings due today
+
+	// Scheduler
+	SchedulerType    valueobjects.SchedulerType `json:"scheduler_type"`
+	DesiredRetention float64                    `json:"desired_retention"` // FSRS target recall probability
+	FSRSWeights      []float64                  `json:"fsrs_weights"`      // FSRS parameters (empty = defaults)
+}
+
+// DefaultDeckOptions returns the default deck options
+func DefaultDeckOptions() *DeckOptions {
+	return &DeckOptions{
+		NewSteps:           []float64{1, 10},
+		GraduatingInterval: 1,
+		EasyInterval:       4,
+		StartingEase:       2.5,
+		NewPerDay:          20,
+		RelearnSteps:       []float64{10},
+		LapseNewInterval:   0,
+		MinimumInterval:    1,
+		LeechThreshold:     8,
+		LeechAction:        valueobjects.LeechActionTagOnly,
+		ReviewsPerDay:      200,
+		EasyBonus:          1.3,
+		HardInterval:       1.2,
+		IntervalModifier:   1.0,
+		MaximumInterval:    36500,
+		SchedulerType:      valueobjects.SchedulerTypeSM2,
+		DesiredRetention:   0.9,
+	}
+}
+
+// ParseDeckOptions parses a deck optionsJSON string, applying defaults for missing or invalid values
+func ParseDeckOptions(optionsJSON string) (*DeckOptions, error) {
+	opts := DefaultDeckOptions()
+	if strings.TrimSpace(optionsJSON) == "" {
+		return opts, nil
+	}
+
+	if err := json.Unmarshal([]byte(optionsJSON), opts); err != nil {
+		return nil, fmt.Errorf("invalid deck options: %w", err)
+	}
+
+	opts.normalize()
+	return opts, nil
+}
+
+// IsLeech reports whether a card that just lapsed for the given total number of lapses becomes a leech
+// A card becomes a leech when it reaches the threshold, then again every half threshold lapses
+func (o *DeckOptions) IsLeech(lapses int) bool {
+	if o.LeechThreshold <= 0 || lapses < o.LeechThreshold {
+		return false
+	}
+	return (lapses-o.LeechThreshold)%max(o.LeechThreshold/2, 1) == 0
+}
+
+// normalize replaces out-of-range values with their defaults
+func (o *DeckOptions) normalize() {
+	defaults := DefaultDeckOptions()
+
+	if o.GraduatingInterval < 1 {
+		o.GraduatingInterval = defaults.GraduatingInterval
+	}
+	if o.EasyInterval < 1 {
+		o.EasyInterval = defaults.EasyInterval
+	}
+	if o.StartingEase < 1.3 {
+		o.StartingEase = defaults.StartingEase
+	}
+	if o.NewPerDay < 0 {
+		o.NewPerDay = defaults.NewPerDay
+	}
+	if o.LapseNewInterval < 0 || o.LapseNewInterval > 1 {
+		o.LapseNewInterval = defaults.LapseNewInterval
+	}
+	if o.MinimumInterval < 1 {
+		o.MinimumInterval = defaults.MinimumInterval
+	}
+	if o.LeechThreshold < 0 {
+		o.LeechThreshold = defaults.LeechThreshold
+	}
+	if o.ReviewsPerDay < 0 {
+		o.ReviewsPerDay = defaults.ReviewsPerDay
+	}
+	if o.EasyBonus < 1 {
+		o.EasyBonus = defaults.EasyBonus
+	}
+	if o.HardInterval <= 0 {
+		o.HardInterval = defaults.HardInterval
+	}
+	if o.IntervalModifier <= 0 {
+		o.IntervalModifier = defaults.IntervalModifier
+	}
+	if o.MaximumInterval < 1 {
+		o.MaximumInterval = defaults.MaximumInterval
+	}
+
+	if !o.LeechAction.IsValid() {
+		o.LeechAction = defaults.LeechAction
+	}
+	if !o.SchedulerType.IsValid() {
+		o.SchedulerType = defaults.SchedulerType
+	}
+	if o.DesiredRetention < 0.7 || o.DesiredRetention > 0.99 {
+		o.DesiredRetention = defaults.DesiredRetention
+	}
+
+	o.NewSteps = positiveSteps(o.NewSteps)
+	o.RelearnSteps = positiveSteps(o.RelearnSteps)
+}
+
+// positiveSteps drops non-positive learning steps
+func positiveSteps(steps []float64) []float64 {
+	result := make([]float64, 0, len(steps))
+	for _, step := range steps {
+		if step > 0 {
+			result = append(result, step)
+		}
+	}
+	return result
+}
+
+// SetFSRSWeights returns optionsJSON with fsrs_weights replaced, keeping all other keys untouched
+func SetFSRSWeights(optionsJSON string, weights []float64) (string, error) {
+	raw := make(map[string]interface{})
+	if strings.TrimSpace(optionsJSON) != "" {
+		if err := json.Unmarshal([]byte(optionsJSON), &raw); err != nil {
+			return "", fmt.Errorf("invalid deck options: %w", err)
+		}
+	}
+
+	raw["fsrs_weights"] = weights
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode deck options: %w", err)
+	}
+	return string(data), nil
+}
+
+// GetOptions parses the deck's optionsJSON into DeckOptions
+func (d *Deck) GetOptions() (*DeckOptions, error) {
+	return ParseDeckOptions(d.optionsJSON)
+}
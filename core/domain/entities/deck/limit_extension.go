@@ -0,0 +1,7 @@
+package deck
+
+// LimitExtension holds the cards added to a deck's daily limits for the current study day
+type LimitExtension struct {
+	NewCards int
+	Reviews  int
+}
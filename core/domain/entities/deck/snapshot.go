@@ -0,0 +1,36 @@
+package deck
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Snapshot is a copy of the editable properties of a deck, used to undo and redo deck changes
+type Snapshot struct {
+	Name     string          `json:"name"`
+	ParentID *int64          `json:"parent_id,omitempty"`
+	Options  json.RawMessage `json:"options"`
+}
+
+// Snapshot captures the deck's current state
+func (d *Deck) Snapshot() *Snapshot {
+	options := json.RawMessage(d.optionsJSON)
+	if strings.TrimSpace(d.optionsJSON) == "" {
+		options = json.RawMessage("{}")
+	}
+
+	return &Snapshot{
+		Name:     d.name,
+		ParentID: d.parentID,
+		Options:  options,
+	}
+}
+
+// RestoreSnapshot puts the deck back into a previously captured state
+func (d *Deck) RestoreSnapshot(s *Snapshot) {
+	d.name = s.Name
+	d.parentID = s.ParentID
+	d.optionsJSON = string(s.Options)
+	d.updatedAt = time.Now()
+}
@@ -48,10 +48,12 @@ func (b *DeletionLogBuilder) WithObjectType(objectType string) *DeletionLogBuild
 		return b
 	}
 	validTypes := map[string]bool{
-		ObjectTypeNote:     true,
-		ObjectTypeCard:     true,
-		ObjectTypeDeck:     true,
-		ObjectTypeNoteType: true,
+		ObjectTypeNote:         true,
+		ObjectTypeCard:         true,
+		ObjectTypeDeck:         true,
+		ObjectTypeNoteType:     true,
+		ObjectTypeFilteredDeck: true,
+		ObjectTypeMedia:        true,
 	}
 	if !validTypes[objectType] {
 		b.errs = append(b.errs, ErrInvalidObjectType)
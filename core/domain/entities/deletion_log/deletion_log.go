@@ -6,10 +6,12 @@ import (
 
 // ObjectType represents the type of deleted object
 const (
-	ObjectTypeNote     = "note"
-	ObjectTypeCard     = "card"
-	ObjectTypeDeck     = "deck"
-	ObjectTypeNoteType = "note_type"
+	ObjectTypeNote         = "note"
+	ObjectTypeCard         = "card"
+	ObjectTypeDeck         = "deck"
+	ObjectTypeNoteType     = "note_type"
+	ObjectTypeFilteredDeck = "filtered_deck"
+	ObjectTypeMedia        = "media"
 )
 
 // DeletionLog represents a deletion log entry entity in the domain
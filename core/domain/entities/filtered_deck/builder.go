@@ -65,6 +65,11 @@ func (b *FilteredDeckBuilder) WithLimitCards(limitCards int) *FilteredDeckBuilde
 	return b
 }
 
+func (b *FilteredDeckBuilder) WithSecondLimitCards(secondLimitCards int) *FilteredDeckBuilder {
+	b.filteredDeck.secondLimitCards = secondLimitCards
+	return b
+}
+
 func (b *FilteredDeckBuilder) WithOrderBy(orderBy string) *FilteredDeckBuilder {
 	b.filteredDeck.orderBy = orderBy
 	return b
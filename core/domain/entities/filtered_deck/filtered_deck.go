@@ -7,18 +7,19 @@ import (
 // FilteredDeck represents a filtered deck entity in the domain
 // Filtered decks are dynamically generated based on search criteria
 type FilteredDeck struct {
-	id            int64
-	userID        int64
-	name          string
-	searchFilter  string
-	secondFilter  *string
-	limitCards    int
-	orderBy       string
-	reschedule    bool
-	createdAt     time.Time
-	updatedAt     time.Time
-	lastRebuildAt *time.Time
-	deletedAt     *time.Time
+	id               int64
+	userID           int64
+	name             string
+	searchFilter     string
+	secondFilter     *string
+	limitCards       int
+	secondLimitCards int
+	orderBy          string
+	reschedule       bool
+	createdAt        time.Time
+	updatedAt        time.Time
+	lastRebuildAt    *time.Time
+	deletedAt        *time.Time
 }
 
 // Getters
@@ -46,6 +47,14 @@ func (fd *FilteredDeck) GetLimitCards() int {
 	return fd.limitCards
 }
 
+// GetSecondLimitCards returns the limit of the second search, which is the deck's limit when unset
+func (fd *FilteredDeck) GetSecondLimitCards() int {
+	if fd.secondLimitCards <= 0 {
+		return fd.limitCards
+	}
+	return fd.secondLimitCards
+}
+
 func (fd *FilteredDeck) GetOrderBy() string {
 	return fd.orderBy
 }
@@ -95,6 +104,10 @@ func (fd *FilteredDeck) SetLimitCards(limitCards int) {
 	fd.limitCards = limitCards
 }
 
+func (fd *FilteredDeck) SetSecondLimitCards(secondLimitCards int) {
+	fd.secondLimitCards = secondLimitCards
+}
+
 func (fd *FilteredDeck) SetOrderBy(orderBy string) {
 	fd.orderBy = orderBy
 }
@@ -129,4 +142,3 @@ func (fd *FilteredDeck) IsActive() bool {
 func (fd *FilteredDeck) NeedsRebuild() bool {
 	return fd.IsActive() && (fd.lastRebuildAt == nil || fd.updatedAt.After(*fd.lastRebuildAt))
 }
-
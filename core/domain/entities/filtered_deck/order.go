@@ -0,0 +1,30 @@
+package filtereddeck
+
+// Orders in which a filtered deck gathers cards, as in Anki's filtered deck options
+const (
+	OrderOldestSeen          = "oldest_seen"          // Least recently reviewed first
+	OrderRandom              = "random"               // Random
+	OrderIntervalAsc         = "interval_asc"         // Shortest interval first
+	OrderIntervalDesc        = "interval_desc"        // Longest interval first
+	OrderLapses              = "lapses"               // Most lapses first
+	OrderAdded               = "added"                // Oldest cards first
+	OrderDue                 = "due"                  // Earliest due first, then new cards by position
+	OrderAddedDesc           = "added_desc"           // Newest cards first
+	OrderRelativeOverdueness = "relative_overdueness" // Most overdue relative to their interval first
+)
+
+// Orders lists the filtered deck orders, indexed by Anki's search order
+var Orders = []string{
+	OrderOldestSeen, OrderRandom, OrderIntervalAsc, OrderIntervalDesc, OrderLapses,
+	OrderAdded, OrderDue, OrderAddedDesc, OrderRelativeOverdueness,
+}
+
+// IsValidOrder checks if order is a filtered deck order
+func IsValidOrder(order string) bool {
+	for _, o := range Orders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
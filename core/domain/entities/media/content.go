@@ -0,0 +1,43 @@
+package media
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDuplicateContent is returned when saving media whose content a user already has
+var ErrDuplicateContent = errors.New("media with the same content already exists")
+
+// ContentHash returns the SHA-1 hex digest media content is addressed by, the same hash Anki's media sync uses
+func ContentHash(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentPath returns the storage path of a user's media content with the given hash
+// Identical files of a user share one stored object
+func ContentPath(userID int64, hash string) string {
+	return fmt.Sprintf("media/%d/%s", userID, hash)
+}
+
+// HashedFilename returns the filename with a short hash suffix before the extension,
+// used when a different file already has the name
+func HashedFilename(filename, hash string) string {
+	return withSuffix(filename, hash[:min(8, len(hash))])
+}
+
+// FullHashedFilename returns the filename with the whole hash before the extension,
+// used when the name given by HashedFilename is taken too
+func FullHashedFilename(filename, hash string) string {
+	return withSuffix(filename, hash)
+}
+
+// withSuffix adds a suffix to a filename before its extension
+func withSuffix(filename, suffix string) string {
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(filename, ext), suffix, ext)
+}
@@ -12,7 +12,7 @@ type Media struct {
 	id          int64
 	userID      int64
 	filename    string
-	hash        string // SHA-256 hash for deduplication
+	hash        string // SHA-1 hash of the content for deduplication
 	size        int64
 	mimeType    string
 	storagePath string
@@ -0,0 +1,49 @@
+package media
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MissingMediaTag is the tag Anki adds to notes that reference media files that don't exist
+const MissingMediaTag = "missing-media"
+
+var (
+	htmlReferencePattern   = regexp.MustCompile(`(?i)<(?:img|audio|video|source|embed)\b[^>]*?\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	objectReferencePattern = regexp.MustCompile(`(?i)<object\b[^>]*?\bdata\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	soundReferencePattern  = regexp.MustCompile(`\[sound:([^\]]+)\]`)
+	illegalFilenameChars   = regexp.MustCompile(`[\[\]<>:"/?*^\\|\x00-\x1f]`)
+)
+
+// ReferencedFilenames returns the media filenames a note field references through HTML tags and [sound:] tags
+// Remote URLs and inline data are skipped, and percent-encoded names are decoded
+func ReferencedFilenames(field string) []string {
+	var filenames []string
+	for _, pattern := range []*regexp.Regexp{htmlReferencePattern, objectReferencePattern} {
+		for _, match := range pattern.FindAllStringSubmatch(field, -1) {
+			ref := html.UnescapeString(match[1] + match[2] + match[3])
+			if ref == "" || strings.Contains(ref, "://") || strings.HasPrefix(ref, "//") || strings.HasPrefix(strings.ToLower(ref), "data:") {
+				continue
+			}
+			if decoded, err := url.PathUnescape(ref); err == nil {
+				ref = decoded
+			}
+			filenames = append(filenames, ref)
+		}
+	}
+	for _, match := range soundReferencePattern.FindAllStringSubmatch(field, -1) {
+		filenames = append(filenames, html.UnescapeString(match[1]))
+	}
+	return filenames
+}
+
+// NormalizeFilename returns the name Anki stores a media file under: NFC normalized, without characters
+// that are illegal on common filesystems and without trailing dots or spaces
+func NormalizeFilename(filename string) string {
+	name := illegalFilenameChars.ReplaceAllString(norm.NFC.String(filename), "")
+	return strings.TrimRight(name, ". ")
+}
@@ -216,3 +216,13 @@ type DuplicateResult struct {
 	Total      int
 }
 
+// FindReplace describes a find and replace over the fields or tags of the notes matching a search
+type FindReplace struct {
+	Query     string // Search query selecting the notes, empty for all notes
+	Field     string // Field to edit, empty for all fields (ignored when InTags is set)
+	InTags    bool   // Rename or replace tags instead of editing fields
+	Find      string // Text to find, or a regular expression when Regex is set
+	Replace   string // Replacement text; with Regex, $1 and ${name} expand the groups
+	Regex     bool
+	MatchCase bool
+}
@@ -0,0 +1,38 @@
+package note
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Snapshot is a copy of the editable properties of a note, used to undo and redo note changes
+type Snapshot struct {
+	Fields json.RawMessage `json:"fields"`
+	Tags   []string        `json:"tags"`
+	Marked bool            `json:"marked"`
+}
+
+// Snapshot captures the note's current state
+func (n *Note) Snapshot() *Snapshot {
+	fields := json.RawMessage(n.fieldsJSON)
+	if strings.TrimSpace(n.fieldsJSON) == "" {
+		fields = json.RawMessage("{}")
+	}
+	tags := make([]string, len(n.tags))
+	copy(tags, n.tags)
+
+	return &Snapshot{
+		Fields: fields,
+		Tags:   tags,
+		Marked: n.marked,
+	}
+}
+
+// RestoreSnapshot puts the note back into a previously captured state
+func (n *Note) RestoreSnapshot(s *Snapshot) {
+	n.fieldsJSON = string(s.Fields)
+	n.tags = append([]string{}, s.Tags...)
+	n.marked = s.Marked
+	n.updatedAt = time.Now()
+}
@@ -132,6 +132,38 @@ func (nt *NoteType) GetCardTypeCount() int {
 	return len(cardTypes)
 }
 
+// GetFieldNames returns the field names of the note type in order, nil when FieldsJSON is invalid
+func (nt *NoteType) GetFieldNames() []string {
+	var fields []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(nt.fieldsJSON), &fields); err != nil {
+		return nil
+	}
+
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// IsCloze checks if the note type is a cloze note type
+// Cloze card types are marked with "cloze": true in CardTypesJSON; their cards are numbered by cloze deletion
+func (nt *NoteType) IsCloze() bool {
+	var cardTypes []map[string]interface{}
+	if err := json.Unmarshal([]byte(nt.cardTypesJSON), &cardTypes); err != nil {
+		return false
+	}
+
+	for _, ct := range cardTypes {
+		if ct["cloze"] == true {
+			return true
+		}
+	}
+	return false
+}
+
 // GetFirstFieldName returns the name of the first field in the note type
 // Returns error if fields array is empty or invalid
 func (nt *NoteType) GetFirstFieldName() (string, error) {
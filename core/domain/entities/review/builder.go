@@ -15,8 +15,9 @@ var (
 )
 
 type ReviewBuilder struct {
-	review *Review
-	errs   []error // Lista de erros acumulados
+	review  *Review
+	errs    []error // Lista de erros acumulados
+	unrated bool    // Rating 0, only valid for manual reschedules
 }
 
 func NewBuilder() *ReviewBuilder {
@@ -44,12 +45,14 @@ func (b *ReviewBuilder) WithCardID(cardID int64) *ReviewBuilder {
 	return b
 }
 
+// WithRating sets the answer rating (1-4); manual reschedules have rating 0
 func (b *ReviewBuilder) WithRating(rating int) *ReviewBuilder {
-	if rating < 1 || rating > 4 {
+	if rating < 0 || rating > 4 {
 		b.errs = append(b.errs, ErrInvalidRating)
 		return b
 	}
 	b.review.rating = rating // Acesso direto ao campo privado
+	b.unrated = rating == 0
 	return b
 }
 
@@ -83,6 +86,9 @@ func (b *ReviewBuilder) WithCreatedAt(createdAt time.Time) *ReviewBuilder {
 }
 
 func (b *ReviewBuilder) Build() (*Review, error) {
+	if b.unrated && b.review.reviewType != valueobjects.ReviewTypeManual {
+		b.errs = append(b.errs, ErrInvalidRating)
+	}
 	if len(b.errs) > 0 {
 		// Retornar todos os erros acumulados
 		return nil, fmt.Errorf("validation errors: %v", b.errs)
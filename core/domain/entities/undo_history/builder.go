@@ -55,6 +55,14 @@ func (b *UndoHistoryBuilder) WithOperationType(operationType string) *UndoHistor
 		OperationTypeAddTag:     true,
 		OperationTypeRemoveTag:  true,
 		OperationTypeChangeDeck: true,
+		OperationTypeReviewCard: true,
+		OperationTypeAddNote:    true,
+		OperationTypeEditCard:   true,
+		OperationTypeDeleteCard: true,
+		OperationTypeAddDeck:    true,
+		OperationTypeEditDeck:   true,
+		OperationTypeDeleteDeck: true,
+		OperationTypeFindReplace: true,
 	}
 	if !validTypes[operationType] {
 		b.errs = append(b.errs, ErrInvalidOperationType)
@@ -74,6 +82,11 @@ func (b *UndoHistoryBuilder) WithCreatedAt(createdAt time.Time) *UndoHistoryBuil
 	return b
 }
 
+func (b *UndoHistoryBuilder) WithUndoneAt(undoneAt *time.Time) *UndoHistoryBuilder {
+	b.undoHistory.undoneAt = undoneAt
+	return b
+}
+
 func (b *UndoHistoryBuilder) Build() (*UndoHistory, error) {
 	if len(b.errs) > 0 {
 		return nil, fmt.Errorf("validation errors: %v", b.errs)
@@ -0,0 +1,109 @@
+package undohistory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+)
+
+// ObjectKind identifies the kind of object changed by an operation
+type ObjectKind string
+
+const (
+	ObjectKindNote ObjectKind = "note"
+	ObjectKindCard ObjectKind = "card"
+	ObjectKindDeck ObjectKind = "deck"
+)
+
+// Change is the state of one object before and after an operation
+// A missing Before means the operation created the object, a missing After means it deleted it
+type Change struct {
+	Kind   ObjectKind      `json:"kind"`
+	ID     int64           `json:"id"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// ChangeSet is the operation data of an entry that can be undone and redone by replaying snapshots
+// Changes are kept in the order they were made: undo replays them backwards, redo forwards
+type ChangeSet struct {
+	Changes []Change `json:"changes"`
+	err     error
+}
+
+// NewChangeSet creates an empty ChangeSet
+func NewChangeSet() *ChangeSet {
+	return &ChangeSet{Changes: make([]Change, 0)}
+}
+
+// AddNote records the state of a note before and after the operation
+func (cs *ChangeSet) AddNote(id int64, before, after *note.Snapshot) {
+	addChange(cs, ObjectKindNote, id, before, after)
+}
+
+// AddCard records the state of a card before and after the operation
+func (cs *ChangeSet) AddCard(id int64, before, after *card.Snapshot) {
+	addChange(cs, ObjectKindCard, id, before, after)
+}
+
+// AddDeck records the state of a deck before and after the operation
+func (cs *ChangeSet) AddDeck(id int64, before, after *deck.Snapshot) {
+	addChange(cs, ObjectKindDeck, id, before, after)
+}
+
+// IsEmpty checks if the operation changed nothing
+func (cs *ChangeSet) IsEmpty() bool {
+	return len(cs.Changes) == 0
+}
+
+// Encode returns the JSON operation data, or the first error met while recording changes
+func (cs *ChangeSet) Encode() (string, error) {
+	if cs.err != nil {
+		return "", cs.err
+	}
+	data, err := json.Marshal(cs)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode undo changes: %w", err)
+	}
+	return string(data), nil
+}
+
+// ParseChangeSet parses the operation data of a ChangeSet entry
+func ParseChangeSet(operationData string) (*ChangeSet, error) {
+	var cs ChangeSet
+	if err := json.Unmarshal([]byte(operationData), &cs); err != nil {
+		return nil, fmt.Errorf("invalid undo changes: %w", err)
+	}
+	return &cs, nil
+}
+
+// addChange appends a change, leaving the missing side of a creation or deletion empty
+func addChange[T any](cs *ChangeSet, kind ObjectKind, id int64, before, after *T) {
+	change := Change{Kind: kind, ID: id}
+	if before != nil {
+		data, err := json.Marshal(before)
+		if err != nil {
+			cs.fail(err)
+			return
+		}
+		change.Before = data
+	}
+	if after != nil {
+		data, err := json.Marshal(after)
+		if err != nil {
+			cs.fail(err)
+			return
+		}
+		change.After = data
+	}
+	cs.Changes = append(cs.Changes, change)
+}
+
+func (cs *ChangeSet) fail(err error) {
+	if cs.err == nil {
+		cs.err = fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+}
@@ -0,0 +1,44 @@
+package undohistory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+)
+
+// ReviewCardData is the operation data of a review_card entry
+// It holds everything needed to revert an answer: the review to delete, the card's
+// scheduling state before the answer and the side effects of the answer
+type ReviewCardData struct {
+	ReviewID         int64                   `json:"review_id"`
+	CardID           int64                   `json:"card_id"`
+	Before           card.SchedulingSnapshot `json:"before"`
+	BuriedSiblingIDs []int64                 `json:"buried_sibling_ids,omitempty"`
+	LeechTagged      bool                    `json:"leech_tagged,omitempty"` // Whether the answer added the leech tag to the note
+	ReturnedFrom     *FilteredDeckPlace      `json:"returned_from,omitempty"` // Filtered deck the answer returned the card from
+}
+
+// FilteredDeckPlace is a card's place in a filtered deck
+type FilteredDeckPlace struct {
+	FilteredDeckID int64 `json:"filtered_deck_id"`
+	Position       int   `json:"position"`
+}
+
+// Encode returns the JSON operation data
+func (d *ReviewCardData) Encode() (string, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode review undo data: %w", err)
+	}
+	return string(data), nil
+}
+
+// ParseReviewCardData parses the operation data of a review_card entry
+func ParseReviewCardData(operationData string) (*ReviewCardData, error) {
+	var d ReviewCardData
+	if err := json.Unmarshal([]byte(operationData), &d); err != nil {
+		return nil, fmt.Errorf("invalid review undo data: %w", err)
+	}
+	return &d, nil
+}
@@ -13,8 +13,35 @@ const (
 	OperationTypeAddTag      = "add_tag"
 	OperationTypeRemoveTag   = "remove_tag"
 	OperationTypeChangeDeck  = "change_deck"
+	OperationTypeReviewCard  = "review_card"
+	OperationTypeAddNote     = "add_note"
+	OperationTypeEditCard    = "edit_card"
+	OperationTypeDeleteCard  = "delete_card"
+	OperationTypeAddDeck     = "add_deck"
+	OperationTypeEditDeck    = "edit_deck"
+	OperationTypeDeleteDeck  = "delete_deck"
+	OperationTypeFindReplace = "find_replace"
 )
 
+// ChangeSetOperationTypes are the operation types whose data is a ChangeSet
+// They are undone and redone by the generic undo engine
+var ChangeSetOperationTypes = []string{
+	OperationTypeAddNote,
+	OperationTypeEditNote,
+	OperationTypeDeleteNote,
+	OperationTypeAddTag,
+	OperationTypeRemoveTag,
+	OperationTypeMoveCard,
+	OperationTypeChangeFlag,
+	OperationTypeEditCard,
+	OperationTypeDeleteCard,
+	OperationTypeChangeDeck,
+	OperationTypeAddDeck,
+	OperationTypeEditDeck,
+	OperationTypeDeleteDeck,
+	OperationTypeFindReplace,
+}
+
 // UndoHistory represents an undo history entry entity in the domain
 // It stores operation history for undo/redo functionality
 type UndoHistory struct {
@@ -23,6 +50,7 @@ type UndoHistory struct {
 	operationType string // edit_note, delete_note, move_card, etc.
 	operationData string // JSONB in database
 	createdAt     time.Time
+	undoneAt      *time.Time // Set while the operation is undone and can be redone
 }
 
 // Getters
@@ -42,6 +70,10 @@ func (uh *UndoHistory) GetCreatedAt() time.Time {
 	return uh.createdAt
 }
 
+func (uh *UndoHistory) GetUndoneAt() *time.Time {
+	return uh.undoneAt
+}
+
 // Setters
 func (uh *UndoHistory) SetID(id int64) {
 	uh.id = id
@@ -63,6 +95,10 @@ func (uh *UndoHistory) SetCreatedAt(createdAt time.Time) {
 	uh.createdAt = createdAt
 }
 
+func (uh *UndoHistory) SetUndoneAt(undoneAt *time.Time) {
+	uh.undoneAt = undoneAt
+}
+
 // GetOperationType returns the operation type
 func (uh *UndoHistory) GetOperationType() string {
 	return uh.operationType
@@ -74,3 +110,17 @@ func (uh *UndoHistory) CanUndo() bool {
 	return uh.operationData != ""
 }
 
+// IsUndone checks if the operation has been undone and not redone yet
+func (uh *UndoHistory) IsUndone() bool {
+	return uh.undoneAt != nil
+}
+
+// MarkUndone records that the operation was undone
+func (uh *UndoHistory) MarkUndone(now time.Time) {
+	uh.undoneAt = &now
+}
+
+// MarkRedone records that the operation was applied again
+func (uh *UndoHistory) MarkRedone() {
+	uh.undoneAt = nil
+}
@@ -9,8 +9,9 @@ import (
 )
 
 var (
-	ErrUserIDRequired = errors.New("userID is required")
-	ErrInvalidTheme   = errors.New("invalid theme type")
+	ErrUserIDRequired  = errors.New("userID is required")
+	ErrInvalidTheme    = errors.New("invalid theme type")
+	ErrInvalidTimezone = errors.New("invalid timezone")
 )
 
 type UserPreferencesBuilder struct {
@@ -67,6 +68,19 @@ func (b *UserPreferencesBuilder) WithNextDayStartsAt(nextDayStartsAt time.Time)
 	return b
 }
 
+// WithTimezone sets the IANA time zone used for day boundaries (empty = UTC)
+func (b *UserPreferencesBuilder) WithTimezone(timezone string) *UserPreferencesBuilder {
+	if timezone == "" {
+		timezone = DefaultTimezone
+	}
+	if _, err := LoadTimezone(timezone); err != nil {
+		b.errs = append(b.errs, ErrInvalidTimezone)
+		return b
+	}
+	b.userPreferences.timezone = timezone
+	return b
+}
+
 func (b *UserPreferencesBuilder) WithLearnAheadLimit(learnAheadLimit int) *UserPreferencesBuilder {
 	b.userPreferences.learnAheadLimit = learnAheadLimit // Acesso direto ao campo privado
 	return b
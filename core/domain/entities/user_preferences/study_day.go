@@ -0,0 +1,90 @@
+package userpreferences
+
+import "time"
+
+const (
+	// DefaultNextDayStartsAtHour is the hour the study day rolls over when no preferences exist
+	DefaultNextDayStartsAtHour = 4
+	// DefaultLearnAheadLimit is the learn ahead limit in minutes when no preferences exist
+	DefaultLearnAheadLimit = 20
+	// DefaultTimezone is the time zone of day boundaries when none is configured
+	DefaultTimezone = "UTC"
+)
+
+// LoadTimezone returns the location of an IANA time zone name
+// "Local" is rejected: it names the server's time zone, not one a user can live in
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// Location returns the user's time zone, falling back to UTC when unset or unknown
+func (up *UserPreferences) Location() *time.Location {
+	if up == nil {
+		return time.UTC
+	}
+	return timezoneLocation(up.timezone)
+}
+
+// DayStart returns the start of the study day containing now, i.e. the latest
+// occurrence of next_day_starts_at at or before now, in the user's time zone
+func (up *UserPreferences) DayStart(now time.Time) time.Time {
+	if up == nil {
+		return DayStartIn("", DefaultNextDayStartsAtHour*time.Hour, now)
+	}
+	hour, minute, second := up.nextDayStartsAt.Clock()
+	rollover := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second
+	return DayStartIn(up.timezone, rollover, now)
+}
+
+// DayStartIn returns the start of the study day containing now for a time zone name and the time
+// after midnight the day rolls over at, falling back to UTC when the time zone is unset or unknown
+// Day boundaries are always worked out here rather than in SQL, since time zones known to Go
+// may be unknown to the database
+func DayStartIn(timezone string, rollover time.Duration, now time.Time) time.Time {
+	hour := int(rollover / time.Hour)
+	minute := int(rollover % time.Hour / time.Minute)
+	second := int(rollover % time.Minute / time.Second)
+	return StudyDayStart(now.In(timezoneLocation(timezone)), hour, minute, second)
+}
+
+// timezoneLocation returns the location of a time zone name, UTC when unset or unknown
+func timezoneLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// NextDayStart returns the moment the study day containing now ends
+func (up *UserPreferences) NextDayStart(now time.Time) time.Time {
+	return up.DayStart(now).AddDate(0, 0, 1)
+}
+
+// LearnAheadCutoff returns the latest due time of learning cards that may be shown now
+func (up *UserPreferences) LearnAheadCutoff(now time.Time) time.Time {
+	limit := DefaultLearnAheadLimit
+	if up != nil {
+		limit = up.learnAheadLimit
+	}
+	return now.Add(time.Duration(limit) * time.Minute)
+}
+
+// StudyDayStart returns the latest occurrence of the given time of day at or before now
+func StudyDayStart(now time.Time, hour, minute, second int) time.Time {
+	start := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, second, 0, now.Location())
+	if start.After(now) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
@@ -15,6 +15,7 @@ type UserPreferences struct {
 	theme                     valueobjects.ThemeType
 	autoSync                  bool
 	nextDayStartsAt           time.Time // Time of day
+	timezone                  string    // IANA time zone name (e.g. "America/Sao_Paulo")
 	learnAheadLimit           int       // Minutes
 	timeboxTimeLimit          int       // Minutes (0 = disabled)
 	videoDriver               string
@@ -60,6 +61,10 @@ func (up *UserPreferences) GetNextDayStartsAt() time.Time {
 	return up.nextDayStartsAt
 }
 
+func (up *UserPreferences) GetTimezone() string {
+	return up.timezone
+}
+
 func (up *UserPreferences) GetLearnAheadLimit() int {
 	return up.learnAheadLimit
 }
@@ -169,6 +174,10 @@ func (up *UserPreferences) SetNextDayStartsAt(nextDayStartsAt time.Time) {
 	up.nextDayStartsAt = nextDayStartsAt
 }
 
+func (up *UserPreferences) SetTimezone(timezone string) {
+	up.timezone = timezone
+}
+
 func (up *UserPreferences) SetLearnAheadLimit(learnAheadLimit int) {
 	up.learnAheadLimit = learnAheadLimit
 }
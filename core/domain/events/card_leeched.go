@@ -0,0 +1,44 @@
+package events
+
+import (
+	"strconv"
+	"time"
+)
+
+// CardLeechedEventType is the event type constant for CardLeeched events
+const CardLeechedEventType = "card.leeched"
+
+// CardLeeched is published when a card lapses often enough to become a leech
+type CardLeeched struct {
+	CardID    int64
+	NoteID    int64
+	UserID    int64
+	Lapses    int
+	Suspended bool // Whether the leech action suspended the card
+	Timestamp time.Time
+}
+
+// EventType returns the type of the event
+func (e *CardLeeched) EventType() string {
+	return CardLeechedEventType
+}
+
+// AggregateID returns the card ID as the aggregate root ID
+func (e *CardLeeched) AggregateID() string {
+	return strconv.FormatInt(e.CardID, 10)
+}
+
+// OccurredAt returns when the event occurred
+func (e *CardLeeched) OccurredAt() time.Time {
+	return e.Timestamp
+}
+
+// Metadata returns additional metadata about the event
+func (e *CardLeeched) Metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":   e.UserID,
+		"note_id":   e.NoteID,
+		"lapses":    e.Lapses,
+		"suspended": e.Suspended,
+	}
+}
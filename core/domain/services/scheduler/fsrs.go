@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+// FSRSScheduler implements the Free Spaced Repetition Scheduler (FSRS v4/FSRS-5)
+// Learning and relearning steps work as in SM-2; review intervals are derived from the
+// card's memory state (stability/difficulty) and the deck's desired retention
+type FSRSScheduler struct {
+	options *deck.DeckOptions
+	model   *FSRSModel
+}
+
+// NewFSRSScheduler creates a new FSRSScheduler using the given deck options
+func NewFSRSScheduler(options *deck.DeckOptions) *FSRSScheduler {
+	if options == nil {
+		options = deck.DefaultDeckOptions()
+	}
+	return &FSRSScheduler{
+		options: options,
+		model:   NewFSRSModel(options.FSRSWeights),
+	}
+}
+
+// Schedule computes the next scheduling state of a card answered with the given rating
+func (s *FSRSScheduler) Schedule(state SchedulingState, rating int, now time.Time) (*SchedulingResult, error) {
+	if err := ValidateRating(rating); err != nil {
+		return nil, err
+	}
+
+	result := &SchedulingResult{
+		State:      state.State,
+		Interval:   state.Interval,
+		Ease:       state.Ease,
+		Lapses:     state.Lapses,
+		Reps:       state.Reps + 1,
+		Step:       state.Step,
+		ReviewType: ReviewTypeForState(state.State),
+	}
+	if state.State == valueobjects.CardStateNew || result.Ease < minimumEase {
+		result.Ease = int(math.Round(s.options.StartingEase * 1000))
+	}
+
+	elapsed := float64(elapsedDays(state.LastReviewAt, now))
+	memory := s.memoryState(state)
+	next := s.nextState(memory, elapsed, rating)
+	s.setMemory(next, result)
+
+	fuzz := fuzzFactor(state.CardID, state.Reps)
+
+	switch state.State {
+	case valueobjects.CardStateReview:
+		s.answerReview(state, memory, elapsed, rating, now, fuzz, result)
+	case valueobjects.CardStateRelearn:
+		if answerStep(s.options.RelearnSteps, valueobjects.CardStateRelearn, state.Step, rating, now, result) {
+			scheduleReview(s.reviewInterval(next.Stability, 1, fuzz), now, result)
+			return result, nil
+		}
+		result.Interval = s.constrainLapseInterval(next.Stability)
+	default:
+		if state.State == valueobjects.CardStateNew {
+			state.Step = 0
+		}
+		if answerStep(s.options.NewSteps, valueobjects.CardStateLearn, state.Step, rating, now, result) {
+			scheduleReview(s.reviewInterval(next.Stability, 1, fuzz), now, result)
+			return result, nil
+		}
+		result.Interval = 0
+	}
+
+	return result, nil
+}
+
+// answerReview handles cards in the review state
+func (s *FSRSScheduler) answerReview(state SchedulingState, memory *MemoryState, elapsed float64, rating int, now time.Time, fuzz float64, result *SchedulingResult) {
+	if rating == RatingAgain {
+		result.Lapses = state.Lapses + 1
+		lapseInterval := s.constrainLapseInterval(*result.Stability)
+
+		if len(s.options.RelearnSteps) == 0 {
+			scheduleReview(lapseInterval, now, result)
+			return
+		}
+		scheduleStep(valueobjects.CardStateRelearn, 0, stepSeconds(s.options.RelearnSteps[0]), now, result)
+		result.Interval = lapseInterval
+		return
+	}
+
+	// Compute all passing intervals so that Hard < Good < Easy always holds
+	hard := s.reviewInterval(s.nextState(memory, elapsed, RatingHard).Stability, 1, fuzz)
+	good := s.reviewInterval(s.nextState(memory, elapsed, RatingGood).Stability, 1, fuzz)
+	easy := s.reviewInterval(s.nextState(memory, elapsed, RatingEasy).Stability, 1, fuzz)
+
+	hard = min(hard, good)
+	good = max(good, hard+1)
+	easy = max(easy, good+1)
+
+	switch rating {
+	case RatingHard:
+		scheduleReview(s.clampToMaximum(hard), now, result)
+	case RatingGood:
+		scheduleReview(s.clampToMaximum(good), now, result)
+	case RatingEasy:
+		scheduleReview(s.clampToMaximum(easy), now, result)
+	}
+}
+
+// memoryState returns the card's current FSRS memory state, or nil if it has none yet
+// Cards previously scheduled with SM-2 get an approximate state from their interval and ease
+func (s *FSRSScheduler) memoryState(state SchedulingState) *MemoryState {
+	if state.Stability != nil && state.Difficulty != nil && *state.Stability > 0 {
+		return &MemoryState{
+			Stability:  *state.Stability,
+			Difficulty: DifficultyFromStored(*state.Difficulty),
+		}
+	}
+	if state.State == valueobjects.CardStateNew || state.Interval <= 0 {
+		return nil
+	}
+
+	// The current interval approximates stability at the desired retention, and ease maps to difficulty
+	retentionFactor := s.model.Interval(1, s.options.DesiredRetention)
+	ease := float64(state.Ease) / 1000
+	return &MemoryState{
+		Stability:  clampFloat(float64(state.Interval)/retentionFactor, minStability, maxStability),
+		Difficulty: clampFloat(11-(ease-1)*4, minDifficulty, maxDifficulty),
+	}
+}
+
+// nextState returns the memory state after answering with rating
+func (s *FSRSScheduler) nextState(memory *MemoryState, elapsed float64, rating int) MemoryState {
+	if memory == nil {
+		return s.model.InitialState(rating)
+	}
+	return s.model.NextState(*memory, elapsed, rating)
+}
+
+// setMemory stores the memory state on the result, converting difficulty to the stored scale
+func (s *FSRSScheduler) setMemory(memory MemoryState, result *SchedulingResult) {
+	stability := memory.Stability
+	difficulty := DifficultyToStored(memory.Difficulty)
+	result.Stability = &stability
+	result.Difficulty = &difficulty
+}
+
+// reviewInterval converts a stability into a fuzzed interval in days for the desired retention
+func (s *FSRSScheduler) reviewInterval(stability float64, minimum int, fuzz float64) int {
+	maximum := max(s.options.MaximumInterval, 1)
+	interval := s.model.Interval(stability, s.options.DesiredRetention)
+	return applyFuzz(interval, clampInt(minimum, 1, maximum), maximum, fuzz)
+}
+
+// constrainLapseInterval returns the interval used after relearning, bounded by the minimum interval
+func (s *FSRSScheduler) constrainLapseInterval(stability float64) int {
+	maximum := max(s.options.MaximumInterval, 1)
+	interval := int(math.Round(s.model.Interval(stability, s.options.DesiredRetention)))
+	return clampInt(max(interval, s.options.MinimumInterval), 1, maximum)
+}
+
+// clampToMaximum keeps an interval within the deck's maximum interval
+func (s *FSRSScheduler) clampToMaximum(interval int) int {
+	return clampInt(interval, 1, max(s.options.MaximumInterval, 1))
+}
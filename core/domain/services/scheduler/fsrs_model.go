@@ -0,0 +1,176 @@
+package scheduler
+
+import "math"
+
+// DefaultFSRSWeights are the default FSRS-5 parameters
+var DefaultFSRSWeights = []float64{
+	0.40255, 1.18385, 3.173, 15.69105, 7.1949, 0.5345, 1.4604, 0.0046, 1.54575, 0.1192,
+	1.01925, 1.9395, 0.11, 0.29605, 2.2698, 0.2315, 2.9898, 0.51655, 0.6621,
+}
+
+const (
+	// fsrsV4WeightCount is the number of parameters of FSRS v4 (no short-term stability)
+	fsrsV4WeightCount = 17
+	// fsrsV5WeightCount is the number of parameters of FSRS-5
+	fsrsV5WeightCount = 19
+
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0 // 0.9^(1/decay) - 1, so that R = 90% when t = S
+
+	minDifficulty = 1.0
+	maxDifficulty = 10.0
+	minStability  = 0.01
+	maxStability  = 36500.0
+)
+
+// MemoryState is the FSRS memory state of a card
+// Difficulty is on FSRS' native 1-10 scale
+type MemoryState struct {
+	Stability  float64
+	Difficulty float64
+}
+
+// FSRSModel implements the FSRS memory model formulas for a parameter set
+type FSRSModel struct {
+	w  []float64
+	v5 bool
+}
+
+// NewFSRSModel creates a model from FSRS v4 (17) or FSRS-5 (19) parameters
+// Any other parameter count falls back to the FSRS-5 defaults
+func NewFSRSModel(weights []float64) *FSRSModel {
+	switch len(weights) {
+	case fsrsV4WeightCount:
+		return &FSRSModel{w: append([]float64(nil), weights...), v5: false}
+	case fsrsV5WeightCount:
+		return &FSRSModel{w: append([]float64(nil), weights...), v5: true}
+	default:
+		return &FSRSModel{w: append([]float64(nil), DefaultFSRSWeights...), v5: true}
+	}
+}
+
+// Weights returns a copy of the model parameters
+func (m *FSRSModel) Weights() []float64 {
+	return append([]float64(nil), m.w...)
+}
+
+// Retrievability returns the probability of recall after elapsedDays for a given stability
+func (m *FSRSModel) Retrievability(elapsedDays, stability float64) float64 {
+	if stability <= 0 {
+		return 0
+	}
+	return math.Pow(1+fsrsFactor*elapsedDays/stability, fsrsDecay)
+}
+
+// Interval returns the interval in days at which recall probability drops to desiredRetention
+func (m *FSRSModel) Interval(stability, desiredRetention float64) float64 {
+	return stability / fsrsFactor * (math.Pow(desiredRetention, 1/fsrsDecay) - 1)
+}
+
+// InitialState returns the memory state after the first review of a card
+func (m *FSRSModel) InitialState(rating int) MemoryState {
+	return MemoryState{
+		Stability:  clampFloat(m.w[rating-1], minStability, maxStability),
+		Difficulty: clampFloat(m.initDifficulty(rating), minDifficulty, maxDifficulty),
+	}
+}
+
+// NextState returns the memory state after a review given the previous state
+// and the number of days elapsed since the previous review
+func (m *FSRSModel) NextState(prev MemoryState, elapsedDays float64, rating int) MemoryState {
+	difficulty := m.nextDifficulty(prev.Difficulty, rating)
+
+	var stability float64
+	switch {
+	case elapsedDays < 1 && m.v5:
+		stability = m.shortTermStability(prev.Stability, rating)
+	case rating == RatingAgain:
+		r := m.Retrievability(elapsedDays, prev.Stability)
+		stability = m.forgetStability(prev.Difficulty, prev.Stability, r)
+	default:
+		r := m.Retrievability(elapsedDays, prev.Stability)
+		stability = m.recallStability(prev.Difficulty, prev.Stability, r, rating)
+	}
+
+	return MemoryState{
+		Stability:  clampFloat(stability, minStability, maxStability),
+		Difficulty: difficulty,
+	}
+}
+
+// initDifficulty returns the initial difficulty for a first rating
+func (m *FSRSModel) initDifficulty(rating int) float64 {
+	g := float64(rating)
+	if m.v5 {
+		return m.w[4] - math.Exp(m.w[5]*(g-1)) + 1
+	}
+	return m.w[4] - m.w[5]*(g-3)
+}
+
+// nextDifficulty applies the difficulty update and mean reversion
+func (m *FSRSModel) nextDifficulty(d float64, rating int) float64 {
+	delta := -m.w[6] * (float64(rating) - 3)
+
+	next := d + delta
+	reversionTarget := m.initDifficulty(RatingGood)
+	if m.v5 {
+		// FSRS-5 damps the change linearly as difficulty approaches its maximum
+		next = d + delta*(maxDifficulty-d)/9
+		reversionTarget = m.initDifficulty(RatingEasy)
+	}
+
+	next = m.w[7]*reversionTarget + (1-m.w[7])*next
+	return clampFloat(next, minDifficulty, maxDifficulty)
+}
+
+// recallStability returns the new stability after a successful recall
+func (m *FSRSModel) recallStability(d, s, r float64, rating int) float64 {
+	hardPenalty := 1.0
+	if rating == RatingHard {
+		hardPenalty = m.w[15]
+	}
+	easyBonus := 1.0
+	if rating == RatingEasy {
+		easyBonus = m.w[16]
+	}
+
+	return s * (1 + math.Exp(m.w[8])*
+		(11-d)*
+		math.Pow(s, -m.w[9])*
+		(math.Exp((1-r)*m.w[10])-1)*
+		hardPenalty*
+		easyBonus)
+}
+
+// forgetStability returns the new stability after a lapse
+func (m *FSRSModel) forgetStability(d, s, r float64) float64 {
+	next := m.w[11] *
+		math.Pow(d, -m.w[12]) *
+		(math.Pow(s+1, m.w[13]) - 1) *
+		math.Exp((1-r)*m.w[14])
+
+	if m.v5 {
+		next = math.Min(next, s/math.Exp(m.w[17]*m.w[18]))
+	}
+	return math.Min(next, s)
+}
+
+// shortTermStability returns the new stability after a same-day review (FSRS-5 only)
+func (m *FSRSModel) shortTermStability(s float64, rating int) float64 {
+	return s * math.Exp(m.w[17]*(float64(rating)-3+m.w[18]))
+}
+
+// DifficultyToStored converts an FSRS difficulty (1-10) to the 0.0-1.0 scale stored on cards
+func DifficultyToStored(d float64) float64 {
+	return (clampFloat(d, minDifficulty, maxDifficulty) - minDifficulty) / (maxDifficulty - minDifficulty)
+}
+
+// DifficultyFromStored converts a stored 0.0-1.0 difficulty back to the FSRS 1-10 scale
+func DifficultyFromStored(stored float64) float64 {
+	return minDifficulty + clampFloat(stored, 0, 1)*(maxDifficulty-minDifficulty)
+}
+
+// clampFloat limits value to the [minimum, maximum] range
+func clampFloat(value, minimum, maximum float64) float64 {
+	return math.Min(math.Max(value, minimum), maximum)
+}
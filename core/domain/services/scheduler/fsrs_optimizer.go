@@ -0,0 +1,309 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+const (
+	// minTrainingItems is the minimum number of predictable reviews needed to fit parameters
+	minTrainingItems = 16
+
+	defaultOptimizerIterations   = 200
+	defaultOptimizerLearningRate = 0.04
+
+	adamBeta1   = 0.9
+	adamBeta2   = 0.999
+	adamEpsilon = 1e-8
+
+	gradientStep       = 1e-4
+	probabilityEpsilon = 1e-6
+	calibrationBins    = 20
+)
+
+var (
+	// ErrNotEnoughReviews is returned when the review history is too small to fit FSRS parameters
+	ErrNotEnoughReviews = errors.New("not enough review history to optimize FSRS parameters")
+)
+
+// fsrsWeightBounds are the allowed ranges of each FSRS-5 parameter during optimization
+var fsrsWeightBounds = [fsrsV5WeightCount][2]float64{
+	{0.01, 100}, {0.01, 100}, {0.01, 100}, {0.01, 100},
+	{1, 10}, {0.001, 4}, {0.001, 4}, {0.001, 0.75},
+	{0, 4.5}, {0, 0.8}, {0.001, 3.5}, {0.001, 5},
+	{0.001, 0.25}, {0.001, 0.9}, {0, 4}, {0, 1},
+	{1, 6}, {0, 2}, {0, 2},
+}
+
+// FSRSReview is one answer of a card's review history
+type FSRSReview struct {
+	Rating      int
+	ElapsedDays float64 // Days since the previous review of the card (0 for the first one)
+}
+
+// FSRSHistory is the chronological review history of a single card
+type FSRSHistory []FSRSReview
+
+// FSRSMetrics measures how well a parameter set predicts the review history
+type FSRSMetrics struct {
+	LogLoss float64 `json:"log_loss"`
+	RMSE    float64 `json:"rmse"` // Root mean square calibration error over retrievability bins
+}
+
+// FSRSOptimizationResult is the outcome of fitting FSRS parameters to a review history
+type FSRSOptimizationResult struct {
+	Weights     []float64   `json:"weights"`
+	Before      FSRSMetrics `json:"before"`
+	After       FSRSMetrics `json:"after"`
+	ReviewCount int         `json:"review_count"`
+}
+
+// BuildFSRSHistories groups reviews by card into chronological histories
+// Cram and manual reviews are ignored as they don't affect scheduling, and cards reviewed only once are dropped
+func BuildFSRSHistories(reviews []*review.Review) []FSRSHistory {
+	byCard := make(map[int64][]*review.Review)
+	var cardIDs []int64
+	for _, r := range reviews {
+		if r.GetType() == valueobjects.ReviewTypeCram || r.GetType() == valueobjects.ReviewTypeManual || ValidateRating(r.GetRating()) != nil {
+			continue
+		}
+		if _, ok := byCard[r.GetCardID()]; !ok {
+			cardIDs = append(cardIDs, r.GetCardID())
+		}
+		byCard[r.GetCardID()] = append(byCard[r.GetCardID()], r)
+	}
+	sort.Slice(cardIDs, func(i, j int) bool { return cardIDs[i] < cardIDs[j] })
+
+	histories := make([]FSRSHistory, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		cardReviews := byCard[cardID]
+		if len(cardReviews) < 2 {
+			continue
+		}
+		sort.SliceStable(cardReviews, func(i, j int) bool {
+			return cardReviews[i].GetCreatedAt().Before(cardReviews[j].GetCreatedAt())
+		})
+
+		history := make(FSRSHistory, len(cardReviews))
+		for i, r := range cardReviews {
+			history[i].Rating = r.GetRating()
+			if i > 0 {
+				last := cardReviews[i-1].GetCreatedAt()
+				history[i].ElapsedDays = float64(elapsedDays(&last, r.GetCreatedAt()))
+			}
+		}
+		histories = append(histories, history)
+	}
+	return histories
+}
+
+// EvaluateFSRS computes the log loss and calibration RMSE of a parameter set over the histories
+// Only reviews made at least one day after the previous one are predicted, as in FSRS training
+func EvaluateFSRS(weights []float64, histories []FSRSHistory) FSRSMetrics {
+	model := NewFSRSModel(weights)
+
+	var bins [calibrationBins]struct{ count, predicted, actual float64 }
+	var loss, total float64
+
+	model.forEachPrediction(histories, func(predicted, actual float64) {
+		loss += binaryCrossEntropy(predicted, actual)
+		total++
+
+		bin := min(int(predicted*calibrationBins), calibrationBins-1)
+		bins[bin].count++
+		bins[bin].predicted += predicted
+		bins[bin].actual += actual
+	})
+
+	if total == 0 {
+		return FSRSMetrics{}
+	}
+
+	var squaredError float64
+	for _, b := range bins {
+		if b.count == 0 {
+			continue
+		}
+		diff := b.predicted/b.count - b.actual/b.count
+		squaredError += b.count * diff * diff
+	}
+
+	return FSRSMetrics{
+		LogLoss: loss / total,
+		RMSE:    math.Sqrt(squaredError / total),
+	}
+}
+
+// CountFSRSTrainingItems returns the number of reviews the optimizer can learn from
+func CountFSRSTrainingItems(histories []FSRSHistory) int {
+	count := 0
+	for _, history := range histories {
+		for i := 1; i < len(history); i++ {
+			if history[i].ElapsedDays >= 1 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// FSRSOptimizer fits FSRS-5 parameters to a review history by minimizing log loss
+// It uses Adam over numerical gradients so that it has no dependency beyond the model itself
+type FSRSOptimizer struct {
+	iterations   int
+	learningRate float64
+}
+
+// NewFSRSOptimizer creates a new FSRSOptimizer with the default training settings
+func NewFSRSOptimizer() *FSRSOptimizer {
+	return &FSRSOptimizer{
+		iterations:   defaultOptimizerIterations,
+		learningRate: defaultOptimizerLearningRate,
+	}
+}
+
+// WithIterations overrides the number of training iterations
+func (o *FSRSOptimizer) WithIterations(iterations int) *FSRSOptimizer {
+	if iterations > 0 {
+		o.iterations = iterations
+	}
+	return o
+}
+
+// Optimize fits parameters starting from initial (FSRS defaults if empty or not FSRS-5)
+// progress, if not nil, receives the completion percentage after each iteration
+// The returned weights are never worse than the starting parameters on the training data
+func (o *FSRSOptimizer) Optimize(ctx context.Context, histories []FSRSHistory, initial []float64, progress func(percent int)) (*FSRSOptimizationResult, error) {
+	reviewCount := CountFSRSTrainingItems(histories)
+	if reviewCount < minTrainingItems {
+		return nil, ErrNotEnoughReviews
+	}
+
+	// Parameters without a full set of weights schedule with the defaults, so the fit is compared against those
+	current := DefaultFSRSWeights
+	if len(initial) == fsrsV5WeightCount {
+		current = initial
+	}
+	before := EvaluateFSRS(current, histories)
+
+	weights := append([]float64(nil), current...)
+	clampWeights(weights)
+
+	best := append([]float64(nil), weights...)
+	bestLoss := trainingLoss(weights, histories)
+
+	m := make([]float64, len(weights))
+	v := make([]float64, len(weights))
+	for iteration := 1; iteration <= o.iterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		gradient := numericalGradient(weights, histories)
+		for i := range weights {
+			m[i] = adamBeta1*m[i] + (1-adamBeta1)*gradient[i]
+			v[i] = adamBeta2*v[i] + (1-adamBeta2)*gradient[i]*gradient[i]
+			mHat := m[i] / (1 - math.Pow(adamBeta1, float64(iteration)))
+			vHat := v[i] / (1 - math.Pow(adamBeta2, float64(iteration)))
+			weights[i] -= o.learningRate * mHat / (math.Sqrt(vHat) + adamEpsilon)
+		}
+		clampWeights(weights)
+
+		if loss := trainingLoss(weights, histories); loss < bestLoss {
+			bestLoss = loss
+			copy(best, weights)
+		}
+
+		if progress != nil {
+			progress(iteration * 100 / o.iterations)
+		}
+	}
+
+	after := EvaluateFSRS(best, histories)
+	if after.LogLoss >= before.LogLoss {
+		best = append([]float64(nil), current...)
+		after = before
+	}
+
+	return &FSRSOptimizationResult{
+		Weights:     best,
+		Before:      before,
+		After:       after,
+		ReviewCount: reviewCount,
+	}, nil
+}
+
+// forEachPrediction replays each history and calls fn with the predicted recall
+// probability and the actual outcome (1 recalled, 0 forgotten) of every predictable review
+func (m *FSRSModel) forEachPrediction(histories []FSRSHistory, fn func(predicted, actual float64)) {
+	for _, history := range histories {
+		if len(history) == 0 {
+			continue
+		}
+		state := m.InitialState(history[0].Rating)
+		for _, r := range history[1:] {
+			if r.ElapsedDays >= 1 {
+				actual := 1.0
+				if r.Rating == RatingAgain {
+					actual = 0
+				}
+				fn(m.Retrievability(r.ElapsedDays, state.Stability), actual)
+			}
+			state = m.NextState(state, r.ElapsedDays, r.Rating)
+		}
+	}
+}
+
+// trainingLoss returns the mean log loss of a parameter set over the histories
+func trainingLoss(weights []float64, histories []FSRSHistory) float64 {
+	model := &FSRSModel{w: weights, v5: true}
+
+	var loss, total float64
+	model.forEachPrediction(histories, func(predicted, actual float64) {
+		loss += binaryCrossEntropy(predicted, actual)
+		total++
+	})
+	if total == 0 {
+		return 0
+	}
+	return loss / total
+}
+
+// numericalGradient estimates the loss gradient with central differences
+func numericalGradient(weights []float64, histories []FSRSHistory) []float64 {
+	gradient := make([]float64, len(weights))
+	probe := append([]float64(nil), weights...)
+	for i := range weights {
+		h := gradientStep * math.Max(1, math.Abs(weights[i]))
+
+		probe[i] = weights[i] + h
+		up := trainingLoss(probe, histories)
+		probe[i] = weights[i] - h
+		down := trainingLoss(probe, histories)
+		probe[i] = weights[i]
+
+		gradient[i] = (up - down) / (2 * h)
+	}
+	return gradient
+}
+
+// clampWeights keeps parameters within their bounds and initial stabilities ordered by rating
+func clampWeights(weights []float64) {
+	for i := range weights {
+		weights[i] = clampFloat(weights[i], fsrsWeightBounds[i][0], fsrsWeightBounds[i][1])
+	}
+	for i := 1; i < 4; i++ {
+		weights[i] = math.Max(weights[i], weights[i-1])
+	}
+}
+
+// binaryCrossEntropy returns the log loss of a single prediction
+func binaryCrossEntropy(predicted, actual float64) float64 {
+	p := clampFloat(predicted, probabilityEpsilon, 1-probabilityEpsilon)
+	return -(actual*math.Log(p) + (1-actual)*math.Log(1-p))
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"math"
+	"math/rand"
+)
+
+// fuzzRange spreads an interval by factor for the portion of it that falls between start and end
+type fuzzRange struct {
+	start  float64
+	end    float64
+	factor float64
+}
+
+// fuzzRanges mirrors the fuzz ranges used by desktop Anki
+var fuzzRanges = []fuzzRange{
+	{start: 2.5, end: 7.0, factor: 0.15},
+	{start: 7.0, end: 20.0, factor: 0.1},
+	{start: 20.0, end: math.Inf(1), factor: 0.05},
+}
+
+// fuzzFactor returns a deterministic value in [0, 1) for a card, so that answering
+// the same card twice in the same state (e.g. in a preview) yields the same interval
+func fuzzFactor(cardID int64, reps int) float64 {
+	return rand.New(rand.NewSource(cardID + int64(reps))).Float64()
+}
+
+// fuzzDelta returns how many days an interval may be moved in either direction
+func fuzzDelta(interval float64) float64 {
+	if interval < 2.5 {
+		return 0
+	}
+
+	delta := 1.0
+	for _, r := range fuzzRanges {
+		delta += r.factor * math.Max(math.Min(interval, r.end)-r.start, 0)
+	}
+	return delta
+}
+
+// constrainedFuzzBounds returns the lower and upper fuzzed interval, kept within minimum and maximum
+func constrainedFuzzBounds(interval float64, minimum, maximum int) (int, int) {
+	if minimum > maximum {
+		minimum = maximum
+	}
+	interval = math.Min(math.Max(interval, float64(minimum)), float64(maximum))
+
+	delta := fuzzDelta(interval)
+	lower := clampInt(int(math.Round(interval-delta)), minimum, maximum)
+	upper := clampInt(int(math.Round(interval+delta)), minimum, maximum)
+
+	if upper == lower && upper > 2 && upper < maximum {
+		upper = lower + 1
+	}
+	return lower, upper
+}
+
+// applyFuzz picks an interval within the fuzz bounds using the given factor in [0, 1)
+func applyFuzz(interval float64, minimum, maximum int, factor float64) int {
+	lower, upper := constrainedFuzzBounds(interval, minimum, maximum)
+	return lower + int(math.Floor(factor*float64(1+upper-lower)))
+}
+
+// clampInt limits value to the [minimum, maximum] range
+func clampInt(value, minimum, maximum int) int {
+	if value < minimum {
+		return minimum
+	}
+	if value > maximum {
+		return maximum
+	}
+	return value
+}
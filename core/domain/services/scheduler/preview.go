@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+// IntervalPreview is the outcome of answering a card with a rating, computed without applying it
+type IntervalPreview struct {
+	Rating       int
+	State        valueobjects.CardState
+	Interval     int   // Days (0 while in learning)
+	Due          int64 // Timestamp in milliseconds
+	DelaySeconds int   // Time from now until the card is due again
+}
+
+// PreviewIntervals runs the scheduler for every rating on the same state (dry run)
+func PreviewIntervals(s IScheduler, state SchedulingState, now time.Time) ([]IntervalPreview, error) {
+	previews := make([]IntervalPreview, 0, RatingEasy)
+	for rating := RatingAgain; rating <= RatingEasy; rating++ {
+		result, err := s.Schedule(state, rating, now)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, IntervalPreview{
+			Rating:       rating,
+			State:        result.State,
+			Interval:     result.Interval,
+			Due:          result.Due,
+			DelaySeconds: int(max(result.Due-now.UnixMilli(), 0) / 1000),
+		})
+	}
+	return previews, nil
+}
+
+// IntervalLabel formats a delay the way answer buttons show it (e.g. "<1m", "10m", "3d", "1.5mo", "2.1y")
+func IntervalLabel(delaySeconds int) string {
+	const (
+		minute = 60
+		hour   = 60 * minute
+		days   = 24 * hour
+		month  = 30 * days
+		year   = 365 * days
+	)
+
+	switch {
+	case delaySeconds < minute:
+		return "<1m"
+	case delaySeconds < hour:
+		return fmt.Sprintf("%dm", int(math.Round(float64(delaySeconds)/minute)))
+	case delaySeconds < days:
+		return fmt.Sprintf("%dh", int(math.Round(float64(delaySeconds)/hour)))
+	case delaySeconds < month:
+		return fmt.Sprintf("%dd", int(math.Round(float64(delaySeconds)/days)))
+	case delaySeconds < year:
+		return trimDecimal(float64(delaySeconds)/month) + "mo"
+	default:
+		return trimDecimal(float64(delaySeconds)/year) + "y"
+	}
+}
+
+// trimDecimal formats a value with one decimal, dropping a trailing ".0"
+func trimDecimal(value float64) string {
+	rounded := math.Round(value*10) / 10
+	if rounded == math.Trunc(rounded) {
+		return fmt.Sprintf("%d", int(rounded))
+	}
+	return fmt.Sprintf("%.1f", rounded)
+}
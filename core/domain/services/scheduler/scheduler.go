@@ -0,0 +1,204 @@
+package scheduler
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+// Answer button ratings
+const (
+	RatingAgain = 1
+	RatingHard  = 2
+	RatingGood  = 3
+	RatingEasy  = 4
+)
+
+const day = 24 * time.Hour
+
+var (
+	// ErrInvalidRating is returned when the rating is outside the 1-4 range
+	ErrInvalidRating = errors.New("rating must be between 1 and 4")
+)
+
+// SchedulingState is the scheduling-relevant snapshot of a card before it is answered
+type SchedulingState struct {
+	CardID       int64
+	State        valueobjects.CardState
+	Interval     int // Days
+	Ease         int // Permille (2500 = 2.5x)
+	Lapses       int
+	Reps         int
+	Step         int // Index of the current learning/relearning step
+	Stability    *float64
+	Difficulty   *float64
+	LastReviewAt *time.Time
+}
+
+// SchedulingResult is the scheduling state of a card after it has been answered
+type SchedulingResult struct {
+	State       valueobjects.CardState
+	Due         int64 // Timestamp in milliseconds
+	Interval    int   // Days (0 while in learning)
+	Ease        int   // Permille
+	Lapses      int
+	Reps        int
+	Step        int
+	Stability   *float64
+	Difficulty  *float64
+	ReviewType  valueobjects.ReviewType // Type recorded in the review log
+	LogInterval int                     // Interval recorded in the review log (days, or negative seconds for learning steps)
+}
+
+// StateFromCard builds a SchedulingState from a card entity
+// step is the card's current learning step index (ignored for new and review cards)
+func StateFromCard(c *card.Card, step int) SchedulingState {
+	return SchedulingState{
+		CardID:       c.GetID(),
+		State:        c.GetState(),
+		Interval:     c.GetInterval(),
+		Ease:         c.GetEase(),
+		Lapses:       c.GetLapses(),
+		Reps:         c.GetReps(),
+		Step:         step,
+		Stability:    c.GetStability(),
+		Difficulty:   c.GetDifficulty(),
+		LastReviewAt: c.GetLastReviewAt(),
+	}
+}
+
+// ApplyTo copies the scheduling result onto a card entity
+func (r *SchedulingResult) ApplyTo(c *card.Card, now time.Time) {
+	c.SetState(r.State)
+	c.SetDue(r.Due)
+	c.SetInterval(r.Interval)
+	c.SetEase(r.Ease)
+	c.SetLapses(r.Lapses)
+	c.SetReps(r.Reps)
+	c.SetStability(r.Stability)
+	c.SetDifficulty(r.Difficulty)
+	c.SetLastReviewAt(&now)
+	c.SetUpdatedAt(now)
+}
+
+// ReviewTypeForState maps the state a card was in when answered to the review log type
+func ReviewTypeForState(state valueobjects.CardState) valueobjects.ReviewType {
+	switch state {
+	case valueobjects.CardStateReview:
+		return valueobjects.ReviewTypeReview
+	case valueobjects.CardStateRelearn:
+		return valueobjects.ReviewTypeRelearn
+	default:
+		return valueobjects.ReviewTypeLearn
+	}
+}
+
+// StepIndexForDelay returns the index of the learning step a card is on, given the
+// delay (in seconds) it was last scheduled with. The review log stores learning delays
+// as negative seconds, so the step can be recovered from the latest review.
+func StepIndexForDelay(steps []float64, delaySecs int) int {
+	index := 0
+	for i, step := range steps {
+		if stepSeconds(step) <= delaySecs {
+			index = i
+		}
+	}
+	return index
+}
+
+// ValidateRating checks that a rating is one of the four answer buttons
+func ValidateRating(rating int) error {
+	if rating < RatingAgain || rating > RatingEasy {
+		return ErrInvalidRating
+	}
+	return nil
+}
+
+// stepSeconds converts a learning step in minutes to whole seconds
+func stepSeconds(minutes float64) int {
+	return int(math.Round(minutes * 60))
+}
+
+// elapsedDays returns the number of whole days since the last review
+func elapsedDays(lastReviewAt *time.Time, now time.Time) int {
+	if lastReviewAt == nil || now.Before(*lastReviewAt) {
+		return 0
+	}
+	return int(now.Sub(*lastReviewAt) / day)
+}
+
+// dueAfterSeconds returns the due timestamp in milliseconds for a delay in seconds
+func dueAfterSeconds(now time.Time, secs int) int64 {
+	return now.Add(time.Duration(secs) * time.Second).UnixMilli()
+}
+
+// dueAfterDays returns the due timestamp in milliseconds for an interval in days
+func dueAfterDays(now time.Time, days int) int64 {
+	return now.Add(time.Duration(days) * day).UnixMilli()
+}
+
+// NewScheduler returns the scheduler selected by the deck options' scheduler_type
+func NewScheduler(options *deck.DeckOptions) IScheduler {
+	if options != nil && options.SchedulerType == valueobjects.SchedulerTypeFSRS {
+		return NewFSRSScheduler(options)
+	}
+	return NewSM2Scheduler(options)
+}
+
+// answerStep moves a card through its learning or relearning steps
+// It returns true when the card leaves the steps (Easy, Good on the last step, or no steps
+// configured); in that case nothing is scheduled and the caller must move the card to review
+func answerStep(steps []float64, learningState valueobjects.CardState, step, rating int, now time.Time, result *SchedulingResult) bool {
+	if len(steps) == 0 || rating == RatingEasy {
+		return true
+	}
+
+	step = clampInt(step, 0, len(steps)-1)
+	switch rating {
+	case RatingAgain:
+		scheduleStep(learningState, 0, stepSeconds(steps[0]), now, result)
+	case RatingHard:
+		scheduleStep(learningState, step, hardDelaySecs(steps, step), now, result)
+	case RatingGood:
+		if step+1 >= len(steps) {
+			return true
+		}
+		scheduleStep(learningState, step+1, stepSeconds(steps[step+1]), now, result)
+	}
+	return false
+}
+
+// scheduleStep puts the card on a learning step due after delaySecs
+func scheduleStep(state valueobjects.CardState, step, delaySecs int, now time.Time, result *SchedulingResult) {
+	result.State = state
+	result.Step = step
+	result.Due = dueAfterSeconds(now, delaySecs)
+	result.LogInterval = -delaySecs
+}
+
+// scheduleReview puts the card in review due after days
+func scheduleReview(days int, now time.Time, result *SchedulingResult) {
+	result.State = valueobjects.CardStateReview
+	result.Step = 0
+	result.Interval = days
+	result.Due = dueAfterDays(now, days)
+	result.LogInterval = days
+}
+
+// hardDelaySecs returns the delay used when Hard is pressed on a learning step
+// On the first step it is the average of the first two steps (or 1.5x a single step,
+// capped at one extra day); on later steps the current step is repeated
+func hardDelaySecs(steps []float64, step int) int {
+	current := stepSeconds(steps[step])
+	if step > 0 {
+		return current
+	}
+	if len(steps) == 1 {
+		return min(current*3/2, current+secondsInOneDay)
+	}
+	return (current + stepSeconds(steps[1])) / 2
+}
@@ -0,0 +1,9 @@
+package scheduler
+
+import "time"
+
+// IScheduler defines the interface for spaced repetition schedulers
+type IScheduler interface {
+	// Schedule computes the next scheduling state of a card answered with the given rating
+	Schedule(state SchedulingState, rating int, now time.Time) (*SchedulingResult, error)
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+const (
+	minimumEase     = 1300
+	againEaseDelta  = -200
+	hardEaseDelta   = -150
+	easyEaseDelta   = 150
+	secondsInOneDay = 86400
+)
+
+// SM2Scheduler implements Anki's SM-2 based scheduler (v3 scheduler rules)
+// It is a pure domain service: it never touches persistence and never reads the clock
+type SM2Scheduler struct {
+	options *deck.DeckOptions
+}
+
+// NewSM2Scheduler creates a new SM2Scheduler using the given deck options
+func NewSM2Scheduler(options *deck.DeckOptions) *SM2Scheduler {
+	if options == nil {
+		options = deck.DefaultDeckOptions()
+	}
+	return &SM2Scheduler{options: options}
+}
+
+// Schedule computes the next scheduling state of a card answered with the given rating
+func (s *SM2Scheduler) Schedule(state SchedulingState, rating int, now time.Time) (*SchedulingResult, error) {
+	if err := ValidateRating(rating); err != nil {
+		return nil, err
+	}
+
+	result := &SchedulingResult{
+		State:      state.State,
+		Interval:   state.Interval,
+		Ease:       state.Ease,
+		Lapses:     state.Lapses,
+		Reps:       state.Reps + 1,
+		Step:       state.Step,
+		Stability:  state.Stability,
+		Difficulty: state.Difficulty,
+		ReviewType: ReviewTypeForState(state.State),
+	}
+	fuzz := fuzzFactor(state.CardID, state.Reps)
+
+	switch state.State {
+	case valueobjects.CardStateReview:
+		s.answerReview(state, rating, now, fuzz, result)
+	case valueobjects.CardStateRelearn:
+		s.answerRelearning(state, rating, now, result)
+	case valueobjects.CardStateLearn:
+		s.answerLearning(state, rating, now, fuzz, result)
+	default:
+		// New cards enter learning at the first step with the starting ease
+		state.Step = 0
+		result.Step = 0
+		result.Ease = int(math.Round(s.options.StartingEase * 1000))
+		s.answerLearning(state, rating, now, fuzz, result)
+	}
+
+	return result, nil
+}
+
+// answerLearning handles new and learning cards
+func (s *SM2Scheduler) answerLearning(state SchedulingState, rating int, now time.Time, fuzz float64, result *SchedulingResult) {
+	if result.Ease < minimumEase {
+		result.Ease = int(math.Round(s.options.StartingEase * 1000))
+	}
+
+	if answerStep(s.options.NewSteps, valueobjects.CardStateLearn, state.Step, rating, now, result) {
+		s.graduate(rating == RatingEasy, now, fuzz, result)
+		return
+	}
+	result.Interval = 0
+}
+
+// graduate moves a learning card into review with the graduating or easy interval
+func (s *SM2Scheduler) graduate(easy bool, now time.Time, fuzz float64, result *SchedulingResult) {
+	interval := s.options.GraduatingInterval
+	if easy {
+		interval = s.options.EasyInterval
+	}
+
+	maximum := s.options.MaximumInterval
+	days := applyFuzz(float64(interval), 1, maximum, fuzz)
+	scheduleReview(days, now, result)
+}
+
+// answerRelearning handles cards that lapsed and are going through relearning steps
+func (s *SM2Scheduler) answerRelearning(state SchedulingState, rating int, now time.Time, result *SchedulingResult) {
+	lapseInterval := s.constrainInterval(state.Interval, s.options.MinimumInterval)
+
+	if answerStep(s.options.RelearnSteps, valueobjects.CardStateRelearn, state.Step, rating, now, result) {
+		if rating == RatingEasy {
+			lapseInterval = s.constrainInterval(lapseInterval+1, 1)
+		}
+		scheduleReview(lapseInterval, now, result)
+		return
+	}
+	result.Interval = lapseInterval
+}
+
+// answerReview handles cards in the review state
+func (s *SM2Scheduler) answerReview(state SchedulingState, rating int, now time.Time, fuzz float64, result *SchedulingResult) {
+	ease := state.Ease
+	if ease < minimumEase {
+		ease = int(math.Round(s.options.StartingEase * 1000))
+	}
+
+	if rating == RatingAgain {
+		s.answerLapse(state, ease, now, result)
+		return
+	}
+
+	scheduled := state.Interval
+	elapsed := scheduled
+	if state.LastReviewAt != nil {
+		elapsed = elapsedDays(state.LastReviewAt, now)
+	}
+
+	var hard, good, easy int
+	if elapsed < scheduled {
+		hard, good, easy = s.earlyReviewIntervals(scheduled, elapsed, ease)
+	} else {
+		hard, good, easy = s.reviewIntervals(scheduled, elapsed-scheduled, ease, fuzz)
+	}
+
+	switch rating {
+	case RatingHard:
+		result.Ease = max(ease+hardEaseDelta, minimumEase)
+		scheduleReview(hard, now, result)
+	case RatingGood:
+		result.Ease = ease
+		scheduleReview(good, now, result)
+	case RatingEasy:
+		result.Ease = ease + easyEaseDelta
+		scheduleReview(easy, now, result)
+	}
+}
+
+// answerLapse handles a review card answered with Again
+func (s *SM2Scheduler) answerLapse(state SchedulingState, ease int, now time.Time, result *SchedulingResult) {
+	result.Lapses = state.Lapses + 1
+	result.Ease = max(ease+againEaseDelta, minimumEase)
+
+	lapseInterval := int(math.Round(float64(state.Interval) * s.options.LapseNewInterval))
+	lapseInterval = s.constrainInterval(lapseInterval, s.options.MinimumInterval)
+
+	steps := s.options.RelearnSteps
+	if len(steps) == 0 {
+		scheduleReview(lapseInterval, now, result)
+		return
+	}
+
+	scheduleStep(valueobjects.CardStateRelearn, 0, stepSeconds(steps[0]), now, result)
+	result.Interval = lapseInterval
+}
+
+// reviewIntervals returns the Hard, Good and Easy intervals for an on-time or late review
+func (s *SM2Scheduler) reviewIntervals(scheduled, daysLate, ease int, fuzz float64) (int, int, int) {
+	current := float64(scheduled)
+	late := float64(daysLate)
+	easeFactor := float64(ease) / 1000
+	hardFactor := s.options.HardInterval
+
+	hardMinimum := 0
+	if hardFactor > 1 {
+		hardMinimum = scheduled + 1
+	}
+	hard := s.constrainPassing(current*hardFactor, hardMinimum, fuzz, true)
+
+	goodMinimum := hard + 1
+	if hardFactor <= 1 {
+		goodMinimum = scheduled + 1
+	}
+	good := s.constrainPassing((current+late/2)*easeFactor, goodMinimum, fuzz, true)
+
+	easy := s.constrainPassing((current+late)*easeFactor*s.options.EasyBonus, good+1, fuzz, true)
+
+	return hard, good, easy
+}
+
+// earlyReviewIntervals returns the Hard, Good and Easy intervals for a card reviewed before it was due
+func (s *SM2Scheduler) earlyReviewIntervals(scheduled, elapsed, ease int) (int, int, int) {
+	current := float64(scheduled)
+	passed := float64(elapsed)
+	easeFactor := float64(ease) / 1000
+	hardFactor := s.options.HardInterval
+
+	hard := s.constrainPassing(math.Max(passed*hardFactor, current*hardFactor/2), 0, 0, false)
+	good := s.constrainPassing(math.Max(passed*easeFactor, current), 0, 0, false)
+
+	reducedBonus := s.options.EasyBonus - (s.options.EasyBonus-1)/2
+	easy := s.constrainPassing(math.Max(passed*easeFactor, current)*reducedBonus, 0, 0, false)
+
+	return hard, good, easy
+}
+
+// constrainPassing applies the interval modifier, the minimum/maximum bounds and optionally fuzz
+func (s *SM2Scheduler) constrainPassing(interval float64, minimum int, fuzz float64, withFuzz bool) int {
+	interval *= s.options.IntervalModifier
+	maximum := max(s.options.MaximumInterval, 1)
+	minimum = clampInt(minimum, 1, maximum)
+
+	if withFuzz {
+		return applyFuzz(interval, minimum, maximum, fuzz)
+	}
+	return clampInt(int(math.Round(interval)), minimum, maximum)
+}
+
+// constrainInterval keeps an interval in days between minimum and the maximum interval
+func (s *SM2Scheduler) constrainInterval(interval, minimum int) int {
+	maximum := max(s.options.MaximumInterval, 1)
+	return clampInt(max(interval, minimum), 1, maximum)
+}
@@ -0,0 +1,101 @@
+package search
+
+// Node is a node of a parsed search expression tree
+type Node interface {
+	node()
+}
+
+// AndNode matches when all of its children match
+type AndNode struct {
+	Children []Node
+}
+
+// OrNode matches when at least one of its children matches
+type OrNode struct {
+	Children []Node
+}
+
+// NotNode matches when its child does not match (-term, -(group))
+type NotNode struct {
+	Child Node
+}
+
+// TermNode is a single search term
+type TermNode struct {
+	Term Term
+}
+
+func (*AndNode) node()  {}
+func (*OrNode) node()   {}
+func (*NotNode) node()  {}
+func (*TermNode) node() {}
+
+// TermKind identifies what a search term matches
+type TermKind string
+
+const (
+	TermKindText     TermKind = "text"     // Text in any field, or in one field when Text.Field is set
+	TermKindTag      TermKind = "tag"      // tag:name
+	TermKindDeck     TermKind = "deck"     // deck:name
+	TermKindDeckID   TermKind = "did"      // did:123,456
+	TermKindState    TermKind = "state"    // is:new, is:due, is:marked, ...
+	TermKindFlag     TermKind = "flag"     // flag:1
+	TermKindProperty TermKind = "property" // prop:ivl>=10
+	TermKindDate     TermKind = "date"     // rated:7:1, added:3, edited:1, introduced:30, resched:2
+	TermKindNoteID   TermKind = "nid"      // nid:123,456
+	TermKindCardID   TermKind = "cid"      // cid:123,456
+	TermKindNoteType TermKind = "note"     // note:Basic or mid:123
+	TermKindTemplate TermKind = "card"     // card:2 or card:Front
+	TermKindDupe     TermKind = "dupe"     // dupe:123,text
+)
+
+// Term is a single search condition, without negation (negation is a NotNode)
+type Term struct {
+	Kind      TermKind
+	Text      TextSearch      // TermKindText
+	Value     string          // Tag name, deck name, state, note type name or card template
+	IDs       []int64         // TermKindNoteID, TermKindCardID, TermKindDeckID, TermKindNoteType by ID (mid:)
+	Flag      int             // TermKindFlag
+	Property  PropertyFilter  // TermKindProperty
+	Date      DateFilter      // TermKindDate
+	Duplicate DuplicateFilter // TermKindDupe
+}
+
+// IsCardTerm reports whether the term tests a property of a card rather than of its note
+func (t Term) IsCardTerm() bool {
+	switch t.Kind {
+	case TermKindDeck, TermKindDeckID, TermKindFlag, TermKindProperty, TermKindCardID, TermKindTemplate:
+		return true
+	case TermKindState:
+		return t.Value != "marked"
+	case TermKindDate:
+		return t.Date.Kind != "edited"
+	default:
+		return false
+	}
+}
+
+// HasCardTerms reports whether any term of the expression tests card properties
+func HasCardTerms(n Node) bool {
+	switch v := n.(type) {
+	case *AndNode:
+		return anyHasCardTerms(v.Children)
+	case *OrNode:
+		return anyHasCardTerms(v.Children)
+	case *NotNode:
+		return HasCardTerms(v.Child)
+	case *TermNode:
+		return v.Term.IsCardTerm()
+	default:
+		return false
+	}
+}
+
+func anyHasCardTerms(nodes []Node) bool {
+	for _, n := range nodes {
+		if HasCardTerms(n) {
+			return true
+		}
+	}
+	return false
+}
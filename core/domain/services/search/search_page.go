@@ -0,0 +1,102 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Sort columns of the browser, shared by notes and cards results
+// In notes mode, card columns sort by an aggregate of the note's cards (first due, average interval, ...)
+const (
+	SortColumnCreated   = "created"    // Creation time
+	SortColumnModified  = "modified"   // Modification time
+	SortColumnNoteType  = "note"       // Note type name
+	SortColumnSortField = "sort_field" // First field of the note
+	SortColumnTags      = "tags"       // Tags
+	SortColumnDeck      = "deck"       // Deck name
+	SortColumnCard      = "card"       // Card template (number of cards in notes mode)
+	SortColumnDue       = "due"        // Due date
+	SortColumnInterval  = "interval"   // Interval
+	SortColumnEase      = "ease"       // Ease factor
+	SortColumnLapses    = "lapses"     // Lapse count
+	SortColumnReps      = "reps"       // Review count
+)
+
+// Sort directions
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+var sortColumns = map[string]bool{
+	SortColumnCreated: true, SortColumnModified: true, SortColumnNoteType: true, SortColumnSortField: true,
+	SortColumnTags: true, SortColumnDeck: true, SortColumnCard: true, SortColumnDue: true,
+	SortColumnInterval: true, SortColumnEase: true, SortColumnLapses: true, SortColumnReps: true,
+}
+
+// DefaultSortOrder is used when neither the request nor the browser config chooses a column
+var DefaultSortOrder = SortOrder{Column: SortColumnCreated, Direction: SortDesc}
+
+// SortOrder is the browser column search results are ordered by
+// Ties are broken by ID in the same direction, so the order is stable across pages
+type SortOrder struct {
+	Column    string
+	Direction string // "asc" or "desc"
+}
+
+// Validate checks the column and direction
+func (o SortOrder) Validate() error {
+	if !sortColumns[o.Column] {
+		return fmt.Errorf("invalid sort column: %s", o.Column)
+	}
+	if o.Direction != SortAsc && o.Direction != SortDesc {
+		return fmt.Errorf("invalid sort direction: %s (must be 'asc' or 'desc')", o.Direction)
+	}
+	return nil
+}
+
+// PageRequest selects one page of search results
+type PageRequest struct {
+	Sort   SortOrder
+	Limit  int
+	Offset int    // Ignored when Cursor is set
+	Cursor string // NextCursor of the previous page, empty for the first page
+}
+
+// PageInfo describes the page returned by a search
+type PageInfo struct {
+	Total      int    // Number of results matching the search, on all pages
+	NextCursor string // Cursor of the next page, empty on the last page
+}
+
+// Cursor is the decoded position after the last result of a page
+// It keeps the sort order so that a cursor cannot be replayed with another order
+type Cursor struct {
+	Column    string `json:"c"`
+	Direction string `json:"d"`
+	Key       string `json:"k"`  // Sort column value of the last result
+	ID        int64  `json:"id"` // ID of the last result
+}
+
+// EncodeCursor encodes the position after the last result of a page
+func EncodeCursor(sort SortOrder, key string, id int64) string {
+	data, _ := json.Marshal(Cursor{Column: sort.Column, Direction: sort.Direction, Key: key, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes a cursor, checking that it was issued for the same sort order
+func DecodeCursor(value string, sort SortOrder) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if cursor.Column != sort.Column || cursor.Direction != sort.Direction {
+		return nil, fmt.Errorf("invalid cursor: issued for another sort order")
+	}
+	return &cursor, nil
+}
@@ -5,9 +5,12 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Parser parses Anki search syntax into a SearchQuery
+// Terms are combined with implicit AND, "or" and parentheses; AND binds tighter than OR
+// and a leading "-" negates a term or a group, e.g. (tag:a or tag:b) -deck:x -(is:new flag:1)
 type Parser struct{}
 
 // NewParser creates a new search parser
@@ -17,83 +20,268 @@ func NewParser() *Parser {
 
 // Parse parses an Anki search query string into a SearchQuery
 func (p *Parser) Parse(query string) (*SearchQuery, error) {
-	if query == "" {
+	if strings.TrimSpace(query) == "" {
 		return NewSearchQuery(), nil
 	}
 
 	sq := NewSearchQuery()
 
-	// Check for grouping (parentheses)
-	if strings.Contains(query, "(") || strings.Contains(query, ")") {
-		sq.HasGrouping = true
+	// Tokenize the query - quoted strings are kept whole, parentheses and "or" become operators
+	tokens := p.tokenize(query)
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenOpen:
+			sq.HasGrouping = true
+		case tokenOr:
+			sq.HasOR = true
+		}
 	}
 
-	// Check for OR operator
-	if strings.Contains(strings.ToLower(query), " or ") {
-		sq.HasOR = true
+	// Build the expression tree
+	state := &parseState{parser: p, tokens: tokens, query: sq}
+	root, err := state.parseOr()
+	if err != nil {
+		return nil, err
 	}
-
-	// Tokenize the query - split by spaces but preserve quoted strings
-	tokens := p.tokenize(query)
-
-	// Process each token
-	for _, token := range tokens {
-		if err := p.processToken(token, sq); err != nil {
-			return nil, fmt.Errorf("failed to parse token '%s': %w", token, err)
-		}
+	if t := state.peek(); t != nil {
+		return nil, fmt.Errorf("invalid search: unexpected '%s'", t.text)
 	}
+	sq.Root = root
 
 	return sq, nil
 }
 
-// tokenize splits the query into tokens, preserving quoted strings
-func (p *Parser) tokenize(query string) []string {
-	var tokens []string
+// tokenKind identifies the kind of a query token
+type tokenKind int
+
+const (
+	tokenTerm   tokenKind = iota // A search term, e.g. tag:vocab or "exact phrase"
+	tokenOpen                    // (
+	tokenClose                   // )
+	tokenOr                      // or
+	tokenNegate                  // - directly before (
+)
+
+// token is a lexical unit of a search query
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits the query into terms and operators, preserving quoted strings
+// Parentheses inside a term (e.g. re:(a|b)) are kept as part of the term
+func (p *Parser) tokenize(query string) []token {
+	var tokens []token
 	var current strings.Builder
 	inQuotes := false
+	termDepth := 0 // Open parentheses inside the current term
 
-	for i, r := range query {
-		if r == '"' {
-			if inQuotes {
-				// End of quoted string - include the quote and add to current token
-				current.WriteRune(r)
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
-				}
-				inQuotes = false
-			} else {
-				// Start of quoted string - check if current token has a prefix (nc:, re:, etc.)
-				// If so, keep it as part of the same token
-				current.WriteRune(r)
-				inQuotes = true
-			}
-		} else if r == ' ' && !inQuotes {
-			// Space outside quotes - end of token
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		text := current.String()
+		current.Reset()
+		termDepth = 0
+
+		switch strings.ToLower(text) {
+		case "and":
+			// AND is implicit between terms
+		case "or":
+			tokens = append(tokens, token{kind: tokenOr, text: text})
+		default:
+			tokens = append(tokens, token{kind: tokenTerm, text: text})
+		}
+	}
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			current.WriteRune(r)
+			inQuotes = !inQuotes
+		case inQuotes:
+			current.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' && (current.Len() == 0 || current.String() == "-"):
 			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
 				current.Reset()
+				tokens = append(tokens, token{kind: tokenNegate, text: "-"})
 			}
-		} else {
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+		case r == '(':
+			current.WriteRune(r)
+			termDepth++
+		case r == ')' && termDepth > 0:
+			current.WriteRune(r)
+			termDepth--
+		case r == ')':
+			flush()
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+		default:
 			current.WriteRune(r)
 		}
+	}
+	flush()
+
+	return tokens
+}
+
+// parseState is the cursor of the recursive-descent parser over the query tokens
+type parseState struct {
+	parser *Parser
+	tokens []token
+	pos    int
+	query  *SearchQuery
+}
+
+func (s *parseState) peek() *token {
+	if s.pos >= len(s.tokens) {
+		return nil
+	}
+	return &s.tokens[s.pos]
+}
+
+// parseOr parses: and ("or" and)*
+func (s *parseState) parseOr() (Node, error) {
+	first, err := s.parseAnd()
+	if err != nil {
+		return nil, err
+	}
 
-		// Handle last token
-		if i == len(query)-1 && current.Len() > 0 {
-			tokens = append(tokens, current.String())
+	children := []Node{first}
+	for t := s.peek(); t != nil && t.kind == tokenOr; t = s.peek() {
+		s.pos++
+		next, err := s.parseAnd()
+		if err != nil {
+			return nil, err
 		}
+		children = append(children, next)
 	}
 
-	// Filter out empty tokens and operators
-	var filtered []string
-	for _, token := range tokens {
-		token = strings.TrimSpace(token)
-		if token != "" && strings.ToUpper(token) != "AND" {
-			filtered = append(filtered, token)
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &OrNode{Children: children}, nil
+}
+
+// parseAnd parses: unary unary*, stopping at "or", ")" or the end of the query
+func (s *parseState) parseAnd() (Node, error) {
+	var children []Node
+	for t := s.peek(); t != nil && t.kind != tokenOr && t.kind != tokenClose; t = s.peek() {
+		child, err := s.parseUnary()
+		if err != nil {
+			return nil, err
 		}
+		children = append(children, child)
 	}
 
-	return filtered
+	switch len(children) {
+	case 0:
+		if t := s.peek(); t != nil {
+			return nil, fmt.Errorf("invalid search: expected a search term before '%s'", t.text)
+		}
+		return nil, fmt.Errorf("invalid search: expected a search term at the end of the query")
+	case 1:
+		return children[0], nil
+	default:
+		return &AndNode{Children: children}, nil
+	}
+}
+
+// parseUnary parses: "-" unary | "(" or ")" | term
+func (s *parseState) parseUnary() (Node, error) {
+	t := s.peek()
+	s.pos++
+
+	switch t.kind {
+	case tokenNegate:
+		child, err := s.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotNode{Child: child}, nil
+
+	case tokenOpen:
+		inner, err := s.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next := s.peek(); next == nil || next.kind != tokenClose {
+			return nil, fmt.Errorf("invalid search: missing ')'")
+		}
+		s.pos++
+		return inner, nil
+
+	default:
+		return s.parser.parseTerm(t.text, s.query)
+	}
+}
+
+// parseTerm parses a single term token, wrapping it in a NotNode when negated
+// The term is also added to the flat filter lists of sq
+func (p *Parser) parseTerm(text string, sq *SearchQuery) (Node, error) {
+	if err := p.processToken(text, sq); err != nil {
+		return nil, fmt.Errorf("failed to parse token '%s': %w", text, err)
+	}
+
+	negated := len(text) > 1 && strings.HasPrefix(text, "-")
+	single := NewSearchQuery()
+	if err := p.processToken(strings.TrimPrefix(text, "-"), single); err != nil {
+		return nil, fmt.Errorf("failed to parse token '%s': %w", text, err)
+	}
+
+	term, ok := single.singleTerm()
+	if !ok {
+		return nil, fmt.Errorf("invalid search term '%s'", text)
+	}
+
+	var n Node = &TermNode{Term: term}
+	if negated {
+		n = &NotNode{Child: n}
+	}
+	return n, nil
+}
+
+// singleTerm converts a SearchQuery holding a single processed token into a Term
+func (sq *SearchQuery) singleTerm() (Term, bool) {
+	switch {
+	case len(sq.TextSearches) == 1:
+		text := sq.TextSearches[0]
+		text.IsNegated = false
+		return Term{Kind: TermKindText, Text: text}, true
+	case len(sq.FieldSearches) == 1:
+		for field, value := range sq.FieldSearches {
+			return Term{Kind: TermKindText, Text: TextSearch{Text: value, Field: field}}, true
+		}
+	case len(sq.TagsInclude) == 1:
+		return Term{Kind: TermKindTag, Value: sq.TagsInclude[0]}, true
+	case len(sq.DecksInclude) == 1:
+		return Term{Kind: TermKindDeck, Value: sq.DecksInclude[0]}, true
+	case len(sq.States) == 1:
+		return Term{Kind: TermKindState, Value: sq.States[0]}, true
+	case len(sq.Flags) == 1:
+		return Term{Kind: TermKindFlag, Flag: sq.Flags[0]}, true
+	case len(sq.PropertyFilters) == 1:
+		return Term{Kind: TermKindProperty, Property: sq.PropertyFilters[0]}, true
+	case len(sq.DateFilters) == 1:
+		return Term{Kind: TermKindDate, Date: sq.DateFilters[0]}, true
+	case len(sq.NoteIDs) > 0:
+		return Term{Kind: TermKindNoteID, IDs: sq.NoteIDs}, true
+	case len(sq.CardIDs) > 0:
+		return Term{Kind: TermKindCardID, IDs: sq.CardIDs}, true
+	case len(sq.DeckIDs) > 0:
+		return Term{Kind: TermKindDeckID, IDs: sq.DeckIDs}, true
+	case len(sq.NoteTypeIDs) == 1:
+		return Term{Kind: TermKindNoteType, IDs: sq.NoteTypeIDs}, true
+	case len(sq.NoteTypes) == 1:
+		return Term{Kind: TermKindNoteType, Value: sq.NoteTypes[0]}, true
+	case len(sq.CardTemplates) == 1:
+		return Term{Kind: TermKindTemplate, Value: sq.CardTemplates[0]}, true
+	case len(sq.Duplicates) == 1:
+		return Term{Kind: TermKindDupe, Duplicate: sq.Duplicates[0]}, true
+	}
+	return Term{}, false
 }
 
 // processToken processes a single token and updates the SearchQuery
@@ -244,6 +432,60 @@ func (p *Parser) processFieldToken(token string, isNegated bool, sq *SearchQuery
 		}
 		sq.PropertyFilters = append(sq.PropertyFilters, propFilter)
 
+	case "rated", "added", "edited", "introduced", "resched":
+		// Date filter: rated:7, rated:7:1, added:3, edited:1, introduced:30, resched:2
+		dateFilter, err := p.parseDateFilter(field, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s filter: %w", field, err)
+		}
+		sq.DateFilters = append(sq.DateFilters, dateFilter)
+
+	case "nid", "cid", "did", "mid":
+		// ID filters: nid:123,456, cid:123,456, did:123,456, mid:123
+		ids, err := p.parseIDList(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s filter: %w", field, err)
+		}
+		switch field {
+		case "nid":
+			sq.NoteIDs = append(sq.NoteIDs, ids...)
+		case "cid":
+			sq.CardIDs = append(sq.CardIDs, ids...)
+		case "did":
+			sq.DeckIDs = append(sq.DeckIDs, ids...)
+		default:
+			if len(ids) != 1 {
+				return fmt.Errorf("invalid mid filter: %s (expected a single note type ID)", value)
+			}
+			sq.NoteTypeIDs = append(sq.NoteTypeIDs, ids[0])
+		}
+
+	case "note":
+		// Note type filter: note:Basic, note:"Basic (and reversed)"
+		if value == "" {
+			return fmt.Errorf("invalid note filter: note type name is required")
+		}
+		sq.NoteTypes = append(sq.NoteTypes, value)
+
+	case "card":
+		// Card template filter: card:2 (1-based template number) or card:Front (template name)
+		if value == "" {
+			return fmt.Errorf("invalid card filter: card template is required")
+		}
+		if n, err := strconv.Atoi(value); err == nil && n < 1 {
+			return fmt.Errorf("invalid card filter: %s (template numbers start at 1)", value)
+		}
+		sq.CardTemplates = append(sq.CardTemplates, value)
+
+	case "dupe":
+		// Duplicate filter: dupe:noteTypeID,first field text
+		idValue, text, ok := strings.Cut(value, ",")
+		noteTypeID, err := strconv.ParseInt(idValue, 10, 64)
+		if !ok || err != nil || noteTypeID <= 0 || text == "" {
+			return fmt.Errorf("invalid dupe filter: %s (expected dupe:noteTypeID,text)", value)
+		}
+		sq.Duplicates = append(sq.Duplicates, DuplicateFilter{NoteTypeID: noteTypeID, Text: text})
+
 	case "front", "back":
 		// Field search: front:text, back:text
 		// Check if value starts with "re:" for regex search
@@ -331,6 +573,43 @@ func (p *Parser) processFieldToken(token string, isNegated bool, sq *SearchQuery
 	return nil
 }
 
+// parseDateFilter parses the value of a date filter: the number of days, and for rated an optional answer button
+func (p *Parser) parseDateFilter(kind, value string) (DateFilter, error) {
+	daysValue, ratingValue, hasRating := strings.Cut(value, ":")
+	if hasRating && kind != "rated" {
+		return DateFilter{}, fmt.Errorf("unexpected answer button: %s", value)
+	}
+
+	days, err := strconv.Atoi(daysValue)
+	if err != nil || days < 1 {
+		return DateFilter{}, fmt.Errorf("invalid number of days: %s (must be 1 or more)", daysValue)
+	}
+
+	filter := DateFilter{Kind: kind, Days: days}
+	if hasRating {
+		rating, err := strconv.Atoi(ratingValue)
+		if err != nil || rating < 1 || rating > 4 {
+			return DateFilter{}, fmt.Errorf("invalid answer button: %s (must be 1-4)", ratingValue)
+		}
+		filter.Rating = rating
+	}
+	return filter, nil
+}
+
+// parseIDList parses a comma-separated list of IDs (e.g., 123,456)
+func (p *Parser) parseIDList(value string) ([]int64, error) {
+	parts := strings.Split(value, ",")
+	ids := make([]int64, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid ID: %s", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 // parsePropertyFilter parses a property filter (e.g., ivl>=10, due=-1)
 func (p *Parser) parsePropertyFilter(value string) (PropertyFilter, error) {
 	// Match patterns like: ivl>=10, due=-1, lapses>3, reps<10
@@ -7,6 +7,21 @@ type PropertyFilter struct {
 	Value    string // "10", "-1", "3"
 }
 
+// DateFilter represents a search on the history of a card or note (e.g., rated:7:1, added:3)
+// Days counts study days back from today, which start at the user's next_day_starts_at
+type DateFilter struct {
+	Kind   string // "rated", "added", "edited", "introduced", "resched"
+	Days   int    // 1 = today, 2 = today and yesterday, ...
+	Rating int    // rated:n:rating answer button (1-4), 0 for any answer
+}
+
+// DuplicateFilter represents a duplicate search (dupe:noteTypeID,text)
+// It matches the notes of the note type whose first field is text
+type DuplicateFilter struct {
+	NoteTypeID int64
+	Text       string
+}
+
 // TextSearch represents a text search with optional modifiers
 type TextSearch struct {
 	Text         string // The search text
@@ -20,7 +35,12 @@ type TextSearch struct {
 }
 
 // SearchQuery represents a parsed Anki search query
+// Root holds the full boolean expression; the flat filter lists below only summarize
+// which terms appear in the query and ignore OR, grouping and negated groups
 type SearchQuery struct {
+	// Expression tree of the query (nil for an empty query)
+	Root Node
+
 	// Field searches: map[fieldName]searchText
 	// Examples: "front:hello" -> map["front"]="hello"
 	//           "field:name:text" -> map["name"]="text"
@@ -43,11 +63,22 @@ type SearchQuery struct {
 	// Property filters
 	PropertyFilters []PropertyFilter // prop:ivl>=10, prop:due=-1, etc.
 
+	// Date filters
+	DateFilters []DateFilter // rated:7:1, added:3, edited:1, introduced:30, resched:2
+
+	// Identity and structure filters
+	NoteIDs       []int64           // nid:123,456
+	CardIDs       []int64           // cid:123,456
+	DeckIDs       []int64           // did:123,456
+	NoteTypeIDs   []int64           // mid:123
+	NoteTypes     []string          // note:"Basic (and reversed)"
+	CardTemplates []string          // card:2 (template number) or card:Front (template name)
+	Duplicates    []DuplicateFilter // dupe:123,text
+
 	// Text searches (general text search in all fields)
 	TextSearches []TextSearch
 
-	// Operators and grouping (for complex queries)
-	// This is a simplified representation - full AST would be more complex
+	// Operators and grouping (the structure itself is in Root)
 	HasOR bool // Contains OR operator
 	HasGrouping bool // Contains parentheses
 }
@@ -63,6 +94,14 @@ func NewSearchQuery() *SearchQuery {
 		States:         []string{},
 		Flags:          []int{},
 		PropertyFilters: []PropertyFilter{},
+		DateFilters:    []DateFilter{},
+		NoteIDs:        []int64{},
+		CardIDs:        []int64{},
+		DeckIDs:        []int64{},
+		NoteTypeIDs:    []int64{},
+		NoteTypes:      []string{},
+		CardTemplates:  []string{},
+		Duplicates:     []DuplicateFilter{},
 		TextSearches:   []TextSearch{},
 		HasOR:          false,
 		HasGrouping:    false,
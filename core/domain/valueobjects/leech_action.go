@@ -0,0 +1,21 @@
+package valueobjects
+
+// LeechAction represents what happens to a card when it becomes a leech
+type LeechAction string
+
+const (
+	// LeechActionTagOnly only tags the card's note as a leech
+	LeechActionTagOnly LeechAction = "tag_only"
+	// LeechActionSuspend tags the card's note as a leech and suspends the card
+	LeechActionSuspend LeechAction = "suspend"
+)
+
+// IsValid checks if the leech action is valid
+func (a LeechAction) IsValid() bool {
+	return a == LeechActionTagOnly || a == LeechActionSuspend
+}
+
+// String returns the string representation of the leech action
+func (a LeechAction) String() string {
+	return string(a)
+}
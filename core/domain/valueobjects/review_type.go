@@ -12,11 +12,13 @@ const (
 	ReviewTypeRelearn ReviewType = "relearn"
 	// ReviewTypeCram represents a cram session review
 	ReviewTypeCram ReviewType = "cram"
+	// ReviewTypeManual represents a manual reschedule (set due date, forget) rather than an answer
+	ReviewTypeManual ReviewType = "manual"
 )
 
 // IsValid checks if the review type is valid
 func (t ReviewType) IsValid() bool {
-	return t == ReviewTypeLearn || t == ReviewTypeReview || t == ReviewTypeRelearn || t == ReviewTypeCram
+	return t == ReviewTypeLearn || t == ReviewTypeReview || t == ReviewTypeRelearn || t == ReviewTypeCram || t == ReviewTypeManual
 }
 
 // String returns the string representation of the review type
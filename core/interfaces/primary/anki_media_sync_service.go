@@ -0,0 +1,68 @@
+package primary
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// The types below are the JSON messages of Anki's media sync protocol
+// Media changes are identified by filename and carry the SHA-1 of the content, empty for deletions
+
+// AnkiMediaSyncBegin describes the server's media to a client starting a media sync
+type AnkiMediaSyncBegin struct {
+	SessionKey   string `json:"sk"`
+	USN          int    `json:"usn"`          // Clients whose last media USN matches have nothing to fetch
+	PeriodicSync bool   `json:"periodicSync"` // The user's periodically_sync_media preference
+}
+
+// AnkiMediaChange is a media file added, replaced or deleted on the server
+type AnkiMediaChange struct {
+	Filename string
+	USN      int
+	SHA1     string // "" when the file was deleted
+}
+
+// MarshalJSON encodes the change as a [filename, usn, sha1] array
+func (c AnkiMediaChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{c.Filename, c.USN, c.SHA1})
+}
+
+// AnkiMediaUploadResult reports how many uploaded changes were applied and the server's media USN after them
+type AnkiMediaUploadResult struct {
+	Processed int
+	USN       int
+}
+
+// MarshalJSON encodes the result as a [processed, usn] array
+func (r AnkiMediaUploadResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{r.Processed, r.USN})
+}
+
+// Results of a media sanity check
+const (
+	AnkiMediaSanityOK     = "OK"
+	AnkiMediaSanityFailed = "FAILED"
+)
+
+// IAnkiMediaSyncService defines the media sync protocol of Anki clients, backed by the media table and storage
+// Clients fetch the media changed since their last media USN, upload their own changes as zip batches and
+// download the files they are missing; a user who turned sync_audio_and_images off can't sync media
+type IAnkiMediaSyncService interface {
+	// Begin starts a media sync and returns the server's media USN
+	Begin(ctx context.Context, userID int64) (*AnkiMediaSyncBegin, error)
+
+	// Changes returns a batch of the media changes after lastUSN, oldest first
+	// Changes sharing a USN are returned together, so a client can continue from the last USN it received
+	Changes(ctx context.Context, userID int64, lastUSN int) ([]AnkiMediaChange, error)
+
+	// Upload applies a zip of client changes: files named in its _meta list are added or replaced, and
+	// entries without a zip name are deleted
+	Upload(ctx context.Context, userID int64, zipData []byte) (*AnkiMediaUploadResult, error)
+
+	// Download returns a zip holding the first of the requested files, up to the batch size, with a _meta map
+	// from zip names to filenames; clients request the rest in later calls
+	Download(ctx context.Context, userID int64, filenames []string) ([]byte, error)
+
+	// Sanity compares the client's media count with the server's and returns AnkiMediaSanityOK or AnkiMediaSanityFailed
+	Sanity(ctx context.Context, userID int64, localCount int) (string, error)
+}
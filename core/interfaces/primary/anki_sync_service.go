@@ -0,0 +1,271 @@
+package primary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// The types below are the JSON messages of the Anki sync protocol (version 11), as sent by Anki clients
+// Objects are identified by their Anki IDs; notes, cards and review log entries are encoded as JSON arrays
+
+// AnkiSyncMeta describes the server collection to a client about to sync
+type AnkiSyncMeta struct {
+	Modified       int64  `json:"mod"` // Last change in milliseconds
+	SchemaModified int64  `json:"scm"` // Last change requiring a full sync, in milliseconds
+	USN            int    `json:"usn"`
+	ServerTime     int64  `json:"ts"` // Seconds, for the client's clock check
+	Message        string `json:"msg"`
+	Continue       bool   `json:"cont"`
+	HostNumber     int    `json:"hostNum"`
+	Empty          bool   `json:"empty"` // The collection has no cards, so a full sync uploads without asking
+}
+
+// AnkiSyncGraves lists the Anki IDs of deleted objects
+type AnkiSyncGraves struct {
+	Cards []int64 `json:"cards"`
+	Notes []int64 `json:"notes"`
+	Decks []int64 `json:"decks"` // Decks and filtered decks
+}
+
+// AnkiSyncChanges holds the changed note types, decks and deck options, exchanged in one message
+// Conf and Crt are sent by the side whose collection was modified last
+type AnkiSyncChanges struct {
+	Models []map[string]interface{} `json:"models"`
+	Decks  AnkiSyncDecks            `json:"decks"`
+	Tags   json.RawMessage          `json:"tags"`
+	Conf   map[string]interface{}   `json:"conf,omitempty"`
+	Crt    *int64                   `json:"crt,omitempty"` // Collection creation in seconds
+}
+
+// AnkiSyncDecks is the [decks, deck options] pair of AnkiSyncChanges
+type AnkiSyncDecks struct {
+	Decks   []map[string]interface{}
+	Configs []map[string]interface{}
+}
+
+// MarshalJSON encodes the decks as a [decks, deck options] array
+func (d AnkiSyncDecks) MarshalJSON() ([]byte, error) {
+	decks, configs := d.Decks, d.Configs
+	if decks == nil {
+		decks = []map[string]interface{}{}
+	}
+	if configs == nil {
+		configs = []map[string]interface{}{}
+	}
+	return json.Marshal([]interface{}{decks, configs})
+}
+
+// UnmarshalJSON decodes a [decks, deck options] array
+func (d *AnkiSyncDecks) UnmarshalJSON(data []byte) error {
+	return unmarshalTuple(data, &d.Decks, &d.Configs)
+}
+
+// AnkiSyncChunk is a batch of review log entries, cards and notes
+// Done marks the last chunk of the sender
+type AnkiSyncChunk struct {
+	Done   bool                  `json:"done"`
+	Revlog []AnkiSyncRevlogEntry `json:"revlog,omitempty"`
+	Cards  []AnkiSyncCardEntry   `json:"cards,omitempty"`
+	Notes  []AnkiSyncNoteEntry   `json:"notes,omitempty"`
+}
+
+// AnkiSyncCardEntry is a row of Anki's cards table
+type AnkiSyncCardEntry struct {
+	ID       int64
+	NoteID   int64
+	DeckID   int64
+	Ord      int
+	Modified int64 // Seconds
+	USN      int
+	Type     int // 0 new, 1 learn, 2 review, 3 relearn
+	Queue    int // -3/-2 buried, -1 suspended, otherwise the scheduling queue
+	Due      int64
+	Interval int
+	Factor   int
+	Reps     int
+	Lapses   int
+	Left     int
+	OrigDue  int64 // Due in the home deck, for cards in a filtered deck
+	OrigDeck int64 // Home deck, for cards in a filtered deck
+	Flags    int
+	Data     string
+}
+
+// MarshalJSON encodes the card as an array in column order
+func (e AnkiSyncCardEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{
+		e.ID, e.NoteID, e.DeckID, e.Ord, e.Modified, e.USN, e.Type, e.Queue, e.Due, e.Interval,
+		e.Factor, e.Reps, e.Lapses, e.Left, e.OrigDue, e.OrigDeck, e.Flags, e.Data,
+	})
+}
+
+// UnmarshalJSON decodes a card array
+func (e *AnkiSyncCardEntry) UnmarshalJSON(data []byte) error {
+	return unmarshalTuple(data,
+		&e.ID, &e.NoteID, &e.DeckID, &e.Ord, &e.Modified, &e.USN, &e.Type, &e.Queue, &e.Due, &e.Interval,
+		&e.Factor, &e.Reps, &e.Lapses, &e.Left, &e.OrigDue, &e.OrigDeck, &e.Flags, &e.Data,
+	)
+}
+
+// AnkiSyncNoteEntry is a row of Anki's notes table
+// The sort field and checksum columns are left empty; clients compute them
+type AnkiSyncNoteEntry struct {
+	ID       int64
+	GUID     string
+	ModelID  int64
+	Modified int64 // Seconds
+	USN      int
+	Tags     string // Space separated
+	Fields   string // Separated by 0x1f, in the note type's field order
+	Flags    int
+	Data     string
+}
+
+// MarshalJSON encodes the note as an array in column order
+func (e AnkiSyncNoteEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{
+		e.ID, e.GUID, e.ModelID, e.Modified, e.USN, e.Tags, e.Fields, "", "", e.Flags, e.Data,
+	})
+}
+
+// UnmarshalJSON decodes a note array
+func (e *AnkiSyncNoteEntry) UnmarshalJSON(data []byte) error {
+	var sortField, checksum json.RawMessage
+	return unmarshalTuple(data,
+		&e.ID, &e.GUID, &e.ModelID, &e.Modified, &e.USN, &e.Tags, &e.Fields, &sortField, &checksum, &e.Flags, &e.Data,
+	)
+}
+
+// AnkiSyncRevlogEntry is a row of Anki's revlog table
+type AnkiSyncRevlogEntry struct {
+	ID           int64 // Review time in milliseconds
+	CardID       int64
+	USN          int
+	Ease         int // Answer button 1-4, 0 for manual entries
+	Interval     int // Days, or negative seconds for learning steps
+	LastInterval int
+	Factor       int
+	TimeMs       int
+	Type         int // 0 learn, 1 review, 2 relearn, 3 filtered, 4 manual, 5 rescheduled
+}
+
+// MarshalJSON encodes the review as an array in column order
+func (e AnkiSyncRevlogEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{
+		e.ID, e.CardID, e.USN, e.Ease, e.Interval, e.LastInterval, e.Factor, e.TimeMs, e.Type,
+	})
+}
+
+// UnmarshalJSON decodes a review array
+func (e *AnkiSyncRevlogEntry) UnmarshalJSON(data []byte) error {
+	return unmarshalTuple(data,
+		&e.ID, &e.CardID, &e.USN, &e.Ease, &e.Interval, &e.LastInterval, &e.Factor, &e.TimeMs, &e.Type,
+	)
+}
+
+// AnkiSyncCounts counts the objects of a collection for the sanity check after a sync
+type AnkiSyncCounts struct {
+	Cards     int
+	Notes     int
+	Revlog    int
+	Graves    int
+	Models    int
+	Decks     int
+	DeckConfs int
+}
+
+// MarshalJSON encodes the counts as an array, after the due counts clients no longer compare
+func (c AnkiSyncCounts) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{
+		[]int{0, 0, 0}, c.Cards, c.Notes, c.Revlog, c.Graves, c.Models, c.Decks, c.DeckConfs,
+	})
+}
+
+// UnmarshalJSON decodes a counts array
+func (c *AnkiSyncCounts) UnmarshalJSON(data []byte) error {
+	var dueCounts json.RawMessage
+	return unmarshalTuple(data,
+		&dueCounts, &c.Cards, &c.Notes, &c.Revlog, &c.Graves, &c.Models, &c.Decks, &c.DeckConfs,
+	)
+}
+
+// Statuses of a sanity check
+const (
+	AnkiSyncSanityOK  = "ok"
+	AnkiSyncSanityBad = "bad"
+)
+
+// AnkiSyncSanityResult compares the client's counts with the server's; a bad result makes the client
+// abort and ask for a full sync
+type AnkiSyncSanityResult struct {
+	Status string          `json:"status"`
+	Client *AnkiSyncCounts `json:"c,omitempty"`
+	Server *AnkiSyncCounts `json:"s,omitempty"`
+}
+
+// unmarshalTuple decodes a JSON array into dest in order
+// Missing trailing elements and nulls leave their destination unchanged
+func unmarshalTuple(data []byte, dest ...interface{}) error {
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for i, value := range values {
+		if i >= len(dest) {
+			break
+		}
+		if string(value) == "null" {
+			continue
+		}
+		if err := json.Unmarshal(value, dest[i]); err != nil {
+			return fmt.Errorf("invalid element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// IAnkiSyncService defines the Anki sync protocol, which lets Anki clients sync with the user's collection
+// A normal sync runs Start, ApplyGraves, ApplyChanges, Chunk until done, ApplyChunk until done, SanityCheck and Finish;
+// a full sync replaces one side's collection with Upload or Download
+type IAnkiSyncService interface {
+	// HostKey logs a client in and returns the host key it authenticates later requests with
+	HostKey(ctx context.Context, username string, password string) (string, error)
+
+	// Authenticate returns the user of a host key
+	Authenticate(ctx context.Context, hostKey string) (int64, error)
+
+	// Meta describes the user's collection
+	Meta(ctx context.Context, userID int64) (*AnkiSyncMeta, error)
+
+	// Start begins a normal sync of the changes since minUSN and returns the server's graves
+	// Graves sent by legacy clients with the request are applied as with ApplyGraves
+	Start(ctx context.Context, userID int64, minUSN int, localIsNewer bool, graves *AnkiSyncGraves) (*AnkiSyncGraves, error)
+
+	// ApplyGraves deletes the objects the client deleted
+	ApplyGraves(ctx context.Context, userID int64, graves *AnkiSyncGraves) error
+
+	// ApplyChanges merges the client's note types, decks and deck options and returns the server's
+	ApplyChanges(ctx context.Context, userID int64, changes *AnkiSyncChanges) (*AnkiSyncChanges, error)
+
+	// Chunk returns the next chunk of the server's changed review log entries, cards and notes
+	Chunk(ctx context.Context, userID int64) (*AnkiSyncChunk, error)
+
+	// ApplyChunk merges a chunk of the client's review log entries, cards and notes
+	ApplyChunk(ctx context.Context, userID int64, chunk *AnkiSyncChunk) error
+
+	// SanityCheck compares the client's counts with the server's once both sides are merged
+	SanityCheck(ctx context.Context, userID int64, client *AnkiSyncCounts) (*AnkiSyncSanityResult, error)
+
+	// Finish completes the sync and returns the new modification time in milliseconds
+	Finish(ctx context.Context, userID int64) (int64, error)
+
+	// Abort discards the sync in progress
+	Abort(ctx context.Context, userID int64) error
+
+	// Upload replaces the user's collection with a client's collection database
+	Upload(ctx context.Context, userID int64, data []byte) error
+
+	// Download returns the user's collection as a collection database
+	Download(ctx context.Context, userID int64) ([]byte, error)
+}
@@ -0,0 +1,30 @@
+package primary
+
+import (
+	"context"
+)
+
+// CheckDatabaseResult reports the problems a Check Database run found and repaired, by card or note ID
+type CheckDatabaseResult struct {
+	LogID                    int64   // check_database_log entry of the run
+	CardsWithDeletedNote     []int64 // Deleted
+	CardsInDeletedDeck       []int64 // Moved to the Default deck
+	CardsWithMissingHomeDeck []int64 // Filtered cards moved to the Default deck
+	NotesWithWrongFieldCount []int64 // Fields matched to the note type; extra field content is kept in the last field
+	CardsWithInvalidOrdinal  []int64 // Cards of card types that no longer exist, deleted
+	CardsWithInvalidSchedule []int64 // Due and interval reset to values valid for the card's state
+	NotesWithDuplicateGUID   []int64 // Given a new GUID
+}
+
+// IssuesFound returns the total number of problems found
+func (r *CheckDatabaseResult) IssuesFound() int {
+	return len(r.CardsWithDeletedNote) + len(r.CardsInDeletedDeck) + len(r.CardsWithMissingHomeDeck) +
+		len(r.NotesWithWrongFieldCount) + len(r.CardsWithInvalidOrdinal) + len(r.CardsWithInvalidSchedule) +
+		len(r.NotesWithDuplicateGUID)
+}
+
+// ICheckDatabaseService defines the interface for checking and repairing the integrity of a user's collection
+type ICheckDatabaseService interface {
+	// CheckDatabase finds and repairs inconsistent cards and notes, and logs a report of the repairs
+	CheckDatabase(ctx context.Context, userID int64) (*CheckDatabaseResult, error)
+}
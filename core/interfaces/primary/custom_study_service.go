@@ -0,0 +1,49 @@
+package primary
+
+import (
+	"context"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+)
+
+// Custom study modes, as in Anki's Custom Study dialog
+const (
+	CustomStudyNewLimit    = "new_limit"    // Increase today's new card limit by Amount
+	CustomStudyReviewLimit = "review_limit" // Increase today's review limit by Amount
+	CustomStudyForgotten   = "forgotten"    // Review cards forgotten in the last Amount days
+	CustomStudyReviewAhead = "review_ahead" // Review cards due in the next Amount days
+	CustomStudyPreviewNew  = "preview_new"  // Preview new cards added in the last Amount days
+	CustomStudyCram        = "cram"         // Study up to Amount cards by card state or tag
+)
+
+// Card states a cram session can be limited to
+const (
+	CustomStudyCardsNew    = "new"    // New cards only, oldest first
+	CustomStudyCardsDue    = "due"    // Due cards only
+	CustomStudyCardsReview = "review" // All review cards, in random order
+	CustomStudyCardsAll    = "all"    // All cards, in random order and without rescheduling
+)
+
+// CustomStudyOptions describes a custom study session
+type CustomStudyOptions struct {
+	Mode        string   // One of the CustomStudy* modes
+	Amount      int      // Cards added to the limit, days, or cards to cram depending on the mode
+	CardState   string   // One of the CustomStudyCards* states, CustomStudyCardsDue when empty (cram only)
+	TagsInclude []string // Cram only cards with any of these tags
+	TagsExclude []string // Never cram cards with these tags
+}
+
+// CustomStudyResult holds the outcome of a custom study session
+type CustomStudyResult struct {
+	Extension    *deck.LimitExtension       // Today's limit extension of the deck (limit modes)
+	FilteredDeck *filtereddeck.FilteredDeck // The custom study session deck (other modes)
+	CardCount    int                        // Cards gathered into the session deck
+}
+
+// ICustomStudyService defines the interface for custom study sessions
+type ICustomStudyService interface {
+	// CustomStudy extends today's limits of a deck or builds a custom study session deck from it and its subdecks
+	// The session deck is a filtered deck reused by every custom study, so starting a new session replaces the previous one
+	CustomStudy(ctx context.Context, userID int64, deckID int64, opts CustomStudyOptions) (*CustomStudyResult, error)
+}
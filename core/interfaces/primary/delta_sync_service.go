@@ -0,0 +1,140 @@
+package primary
+
+import (
+	"context"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deletionlog "github.com/felipesantos/anki-backend/core/domain/entities/deletion_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+)
+
+// SyncChanges holds the objects changed and deleted since a client's last sync
+type SyncChanges struct {
+	USN       int  // Pass as since on the next call
+	Full      bool // Every active object is included and Deleted is empty: the client should replace its data
+	Notes     []*note.Note
+	Cards     []*card.Card
+	Decks     []*deck.Deck
+	NoteTypes []*notetype.NoteType
+	Reviews   []*review.Review
+	Media     []*media.Media
+	Deleted   []*deletionlog.DeletionLog // Tombstones, oldest first
+}
+
+// Actions of pushed changes
+const (
+	SyncActionCreate = "create"
+	SyncActionUpdate = "update"
+	SyncActionDelete = "delete"
+)
+
+// SyncNoteChange is a note created, updated or deleted by a client
+type SyncNoteChange struct {
+	Action     string
+	ID         int64  // Server ID, for updates and deletes
+	ClientRef  string // Client's reference for a created note, echoed in the result
+	NoteTypeID int64
+	DeckID     int64
+	FieldsJSON string
+	Tags       []string
+	ModifiedAt time.Time // When the client made the change
+}
+
+// SyncDeckChange is a deck created, updated or deleted by a client
+// Deleting a deck deletes its cards
+type SyncDeckChange struct {
+	Action      string
+	ID          int64
+	ClientRef   string
+	Name        string
+	ParentID    *int64
+	OptionsJSON string
+	ModifiedAt  time.Time
+}
+
+// SyncNoteTypeChange is a note type created, updated or deleted by a client
+type SyncNoteTypeChange struct {
+	Action        string
+	ID            int64
+	ClientRef     string
+	Name          string
+	FieldsJSON    string
+	CardTypesJSON string
+	TemplatesJSON string
+	ModifiedAt    time.Time
+}
+
+// SyncCardChange updates the flag, suspension or burial of a card; nil values are left unchanged
+type SyncCardChange struct {
+	ID         int64
+	Flag       *int
+	Suspended  *bool
+	Buried     *bool
+	ModifiedAt time.Time
+}
+
+// SyncReviewChange is an answer a client recorded, possibly offline
+type SyncReviewChange struct {
+	ClientRef  string
+	CardID     int64
+	Rating     int
+	TimeMs     int
+	ReviewedAt time.Time
+}
+
+// SyncPush is a batch of client changes
+// Note types are applied first, then decks, notes, cards and reviews, each in the order given
+type SyncPush struct {
+	NoteTypes []SyncNoteTypeChange
+	Decks     []SyncDeckChange
+	Notes     []SyncNoteChange
+	Cards     []SyncCardChange
+	Reviews   []SyncReviewChange
+}
+
+// SyncCreated maps a client reference to the ID of the object created for it
+type SyncCreated struct {
+	ObjectType string
+	ClientRef  string
+	ID         int64
+}
+
+// Reasons a pushed change is not applied
+const (
+	SyncConflictServerNewer = "server_newer" // The server's object changed after the client's change
+	SyncConflictDeleted     = "deleted"      // The object was deleted on the server
+	SyncConflictRejected    = "rejected"     // The change is invalid
+)
+
+// SyncConflict reports a pushed change that was not applied
+type SyncConflict struct {
+	ObjectType string
+	ID         int64
+	ClientRef  string
+	Reason     string
+	Message    string
+}
+
+// SyncPushResult reports the outcome of a push
+type SyncPushResult struct {
+	Applied   int
+	Created   []SyncCreated
+	Conflicts []SyncConflict
+}
+
+// IDeltaSyncService defines the delta sync API for the web and mobile clients
+// Every change is stamped with the user's update sequence number (USN); clients fetch the changes since the USN
+// of their previous fetch and push their own changes with last-writer-wins conflict resolution
+type IDeltaSyncService interface {
+	// FindChanges returns the objects changed and deleted since a USN, or every object when since is negative
+	// The USN returned is recorded as the client's last sync USN when clientID is set
+	FindChanges(ctx context.Context, userID int64, clientID string, since int) (*SyncChanges, error)
+
+	// Push applies a batch of client changes; changes older than the server's objects are reported as conflicts
+	Push(ctx context.Context, userID int64, push *SyncPush) (*SyncPushResult, error)
+}
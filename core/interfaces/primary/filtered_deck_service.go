@@ -3,21 +3,32 @@ package primary
 import (
 	"context"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
 )
 
 // IFilteredDeckService defines the interface for filtered deck management
+// Building a filtered deck moves the cards matching its searches into it; the cards keep their deck,
+// which becomes their home deck, and return to it when the filtered deck is emptied
 type IFilteredDeckService interface {
-	// Create creates a new filtered deck
-	Create(ctx context.Context, userID int64, name string, searchFilter string, limit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error)
+	// Create creates a new filtered deck and builds it
+	Create(ctx context.Context, userID int64, name string, searchFilter string, secondFilter *string, limit int, secondLimit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error)
 
 	// FindByUserID finds all filtered decks for a user
 	FindByUserID(ctx context.Context, userID int64) ([]*filtereddeck.FilteredDeck, error)
 
-	// Update updates an existing filtered deck
-	Update(ctx context.Context, userID int64, id int64, name string, searchFilter string, limit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error)
+	// Update updates an existing filtered deck and rebuilds it
+	Update(ctx context.Context, userID int64, id int64, name string, searchFilter string, secondFilter *string, limit int, secondLimit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error)
 
-	// Delete deletes a filtered deck
+	// Delete returns the cards of a filtered deck to their home decks and deletes it
 	Delete(ctx context.Context, userID int64, id int64) error
-}
 
+	// Rebuild empties a filtered deck and gathers the matching cards again; returns the number of cards in it
+	Rebuild(ctx context.Context, userID int64, id int64) (int, error)
+
+	// Empty returns the cards of a filtered deck to their home decks; returns the number of cards returned
+	Empty(ctx context.Context, userID int64, id int64) (int, error)
+
+	// FindCards finds the cards in a filtered deck, in the deck's order
+	FindCards(ctx context.Context, userID int64, id int64) ([]*card.Card, error)
+}
@@ -0,0 +1,18 @@
+package primary
+
+import (
+	"context"
+
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+)
+
+// IFSRSOptimizerService defines the interface for fitting FSRS parameters to a user's review history
+type IFSRSOptimizerService interface {
+	// Optimize fits FSRS parameters to the review history of the decks and saves them to the preset
+	// deckIDs are the decks using the preset: only their reviews are used and they receive the new parameters
+	// progress, if not nil, receives the completion percentage while the optimizer runs
+	Optimize(ctx context.Context, userID int64, presetID int64, deckIDs []int64, progress func(percent int)) (*scheduler.FSRSOptimizationResult, error)
+
+	// Evaluate fits FSRS parameters to the review history of the decks without saving them and returns log loss/RMSE before and after
+	Evaluate(ctx context.Context, userID int64, presetID int64, deckIDs []int64) (*scheduler.FSRSOptimizationResult, error)
+}
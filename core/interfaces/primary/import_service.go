@@ -0,0 +1,92 @@
+package primary
+
+import (
+	"context"
+	"io"
+)
+
+// Update modes for imported notes whose GUID matches an existing note
+const (
+	ImportUpdateSkip    = "skip"     // Keep the existing note
+	ImportUpdateIfNewer = "if_newer" // Update the existing note when the imported one was modified later
+	ImportUpdateAlways  = "always"   // Always overwrite the existing note
+)
+
+// ImportOptions controls how imported notes are merged into the collection
+type ImportOptions struct {
+	UpdateMode string // One of the ImportUpdate* modes, ImportUpdateIfNewer when empty
+}
+
+// ImportResult counts what an import added or changed
+type ImportResult struct {
+	NoteTypesAdded int
+	DecksAdded     int
+	NotesAdded     int
+	NotesUpdated   int
+	NotesSkipped   int // GUID matches left unchanged, or whose existing note has another note type
+	CardsAdded     int
+	ReviewsAdded   int
+	MediaAdded     int
+}
+
+// Handling of imported text rows that match an existing note by GUID or first field
+const (
+	ImportDuplicateUpdate = "update" // Update the existing note with the row
+	ImportDuplicateSkip   = "skip"   // Keep the existing note
+	ImportDuplicateAdd    = "add"    // Add the row as a new note anyway
+)
+
+// Statuses of an imported text row
+const (
+	TextImportRowAdded   = "added"
+	TextImportRowUpdated = "updated"
+	TextImportRowSkipped = "skipped"
+	TextImportRowError   = "error"
+)
+
+// TextImportOptions describes the layout of a CSV/TSV file
+// Header directives of the file (#separator:, #html:, #columns:, #notetype column:, ...) take precedence
+// Column numbers are 1-based; 0 means the file has no such column
+type TextImportOptions struct {
+	Delimiter      string   // Column separator: a character or tab, comma, semicolon, pipe, colon, space; guessed when empty
+	HTML           bool     // Keep HTML in fields; otherwise it is escaped and newlines become <br>
+	NoteTypeID     int64    // Note type of rows without a note type column
+	DeckID         int64    // Deck of rows without a deck column
+	FieldMap       []string // Field filled by each column, "" to ignore it; columns fill the fields in order when empty
+	HeaderRow      bool     // The first row holds column names, as written by the text export
+	GUIDColumn     int      // Column matching existing notes by GUID
+	TagsColumn     int      // Column of space-separated tags
+	DeckColumn     int      // Column of deck names or IDs, missing decks are created
+	NoteTypeColumn int      // Column of note type names or IDs
+	Tags           []string // Tags added to every imported note
+	DuplicateMode  string   // One of the ImportDuplicate* modes, ImportDuplicateUpdate when empty
+}
+
+// TextImportRow reports the outcome of one row
+type TextImportRow struct {
+	Line    int    // Line of the row in the file
+	Status  string // One of the TextImportRow* statuses
+	NoteID  int64  // Added or matched note, 0 on error
+	Message string // Reason of skipped and error rows
+}
+
+// TextImportResult counts the outcome of a text import and reports each row
+type TextImportResult struct {
+	Added   int
+	Updated int
+	Skipped int
+	Errors  int
+	Rows    []TextImportRow
+}
+
+// IImportService defines the interface for importing Anki packages
+type IImportService interface {
+	// ImportPackage imports an Anki deck package (.apkg) or collection package (.colpkg)
+	// Note types, decks, notes, cards, review log and media are merged into the user's collection;
+	// notes are matched by GUID and updated according to opts.UpdateMode
+	ImportPackage(ctx context.Context, userID int64, file io.ReaderAt, size int64, opts ImportOptions) (*ImportResult, error)
+
+	// ImportText imports notes from a CSV or TSV file, the counterpart of the text export
+	// Each row is added, updated or skipped on its own: a failing row is reported without stopping the import
+	ImportText(ctx context.Context, userID int64, file io.Reader, opts TextImportOptions) (*TextImportResult, error)
+}
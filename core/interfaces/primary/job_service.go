@@ -0,0 +1,19 @@
+package primary
+
+import (
+	"context"
+
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// IJobService defines the interface for background job management
+type IJobService interface {
+	// Enqueue adds a job to the queue and returns its ID
+	Enqueue(ctx context.Context, jobType string, payload map[string]interface{}) (string, error)
+
+	// EnqueueWithRetries adds a job to the queue with custom max retries and returns its ID
+	EnqueueWithRetries(ctx context.Context, jobType string, payload map[string]interface{}, maxRetries int) (string, error)
+
+	// GetStatus retrieves the status, progress and result of a job by ID
+	GetStatus(ctx context.Context, jobID string) (*secondary.Job, error)
+}
@@ -0,0 +1,48 @@
+package primary
+
+import (
+	"context"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+)
+
+// MissingMediaReference is a filename referenced by notes without a stored media file
+type MissingMediaReference struct {
+	Filename string
+	NoteIDs  []int64
+}
+
+// MisnamedMedia is a media file whose name is not in the form Anki stores media under
+type MisnamedMedia struct {
+	Media         *media.Media
+	SuggestedName string
+}
+
+// MediaCheckResult reports the outcome of a Check Media run
+type MediaCheckResult struct {
+	LogID    int64                   // check_database_log entry of the run
+	Unused   []*media.Media          // Media files no note references
+	Missing  []MissingMediaReference // Referenced files without a media file or stored content
+	Misnamed []MisnamedMedia         // Media files with names that aren't NFC or contain illegal characters
+	Orphaned []string                // Storage objects no media file points to
+}
+
+// IssuesFound returns the total number of problems found
+func (r *MediaCheckResult) IssuesFound() int {
+	return len(r.Unused) + len(r.Missing) + len(r.Misnamed) + len(r.Orphaned)
+}
+
+// IMediaCheckService defines the interface for checking a user's media files against their notes
+type IMediaCheckService interface {
+	// CheckMedia cross-references note fields, media files and storage, and logs the result
+	// Files starting with an underscore are considered used, as they are referenced from templates
+	CheckMedia(ctx context.Context, userID int64) (*MediaCheckResult, error)
+
+	// DeleteUnused deletes the media files no note references and removes orphaned storage objects
+	// Returns the number of media files deleted
+	DeleteUnused(ctx context.Context, userID int64) (int, error)
+
+	// TagMissing adds the missing-media tag to notes that reference missing media
+	// Returns the number of notes tagged
+	TagMissing(ctx context.Context, userID int64) (int, error)
+}
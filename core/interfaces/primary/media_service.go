@@ -2,14 +2,26 @@ package primary
 
 import (
 	"context"
+	"io"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/media"
 )
 
+// MediaContent is the content of a media file, or a URL it can be downloaded from directly
+type MediaContent struct {
+	Media *media.Media
+	URL   string    // Presigned URL when the storage serves files itself
+	Data  io.Reader // File content when URL is empty
+}
+
 // IMediaService defines the interface for media file management
 type IMediaService interface {
-	// Create records a new media file
-	Create(ctx context.Context, userID int64, filename string, hash string, size int64, mimeType string, storagePath string) (*media.Media, error)
+	// Upload stores a media file's content under its content hash and records it
+	// Uploading content the user already has returns the existing media file
+	Upload(ctx context.Context, userID int64, filename string, file io.Reader) (*media.Media, error)
+
+	// GetContent returns a media file's content or a presigned URL to it
+	GetContent(ctx context.Context, userID int64, id int64) (*MediaContent, error)
 
 	// FindByID finds a media file by ID
 	FindByID(ctx context.Context, userID int64, id int64) (*media.Media, error)
@@ -20,4 +32,3 @@ type IMediaService interface {
 	// Delete removes a media file record (soft delete)
 	Delete(ctx context.Context, userID int64, id int64) error
 }
-
@@ -38,5 +38,9 @@ type INoteService interface {
 	// FindDuplicatesByGUID finds duplicate notes based on GUID value
 	// Returns groups of notes that have the same GUID (useful for data integrity checks)
 	FindDuplicatesByGUID(ctx context.Context, userID int64) (*note.DuplicateResult, error)
+
+	// FindReplace finds and replaces text in the fields or tags of the notes matching a search
+	// Returns the number of notes changed; the whole operation is undone as one entry
+	FindReplace(ctx context.Context, userID int64, fr note.FindReplace) (int, error)
 }
 
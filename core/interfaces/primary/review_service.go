@@ -2,8 +2,11 @@ package primary
 
 import (
 	"context"
+	"time"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
 )
 
 // IReviewService defines the interface for card review operations
@@ -11,6 +14,19 @@ type IReviewService interface {
 	// Create records a new review for a card and updates the card's scheduling state
 	Create(ctx context.Context, userID int64, cardID int64, rating int, timeMs int) (*review.Review, error)
 
+	// CreateAt records a review answered at a given time, which must not be in the future
+	// The card is scheduled from the review time instead of the current time
+	CreateAt(ctx context.Context, userID int64, cardID int64, rating int, timeMs int, reviewedAt time.Time) (*review.Review, error)
+
+	// Undo reverts the most recent review, restoring the card's prior scheduling state
+	Undo(ctx context.Context, userID int64) (*card.Card, error)
+
+	// LogManual records a manual reschedule (reset, forget, set due date) of a card in its review history
+	LogManual(ctx context.Context, userID int64, c *card.Card) error
+
+	// PreviewIntervals computes the outcome of each rating for a card without persisting anything
+	PreviewIntervals(ctx context.Context, userID int64, cardID int64) ([]scheduler.IntervalPreview, error)
+
 	// FindByID finds a review by ID
 	FindByID(ctx context.Context, userID int64, id int64) (*review.Review, error)
 
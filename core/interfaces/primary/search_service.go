@@ -2,12 +2,15 @@ package primary
 
 import (
 	"context"
+
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
 )
 
 // SearchResult represents the result of an advanced search
 type SearchResult struct {
-	Data  []interface{} `json:"data"`  // NoteResponse or CardResponse
-	Total int           `json:"total"` // Total count (for pagination)
+	Data       []interface{} `json:"data"`                  // NoteResponse or CardResponse
+	Total      int           `json:"total"`                 // Total count of matches on all pages
+	NextCursor string        `json:"next_cursor,omitempty"` // Cursor of the next page, empty on the last page
 }
 
 // ISearchService defines the interface for advanced search functionality
@@ -15,8 +18,6 @@ type ISearchService interface {
 	// SearchAdvanced performs advanced search using Anki syntax
 	// query: Anki search query string (e.g., "deck:Default tag:vocabulary front:hello")
 	// resultType: "notes" or "cards"
-	// limit: Maximum number of results
-	// offset: Pagination offset
-	SearchAdvanced(ctx context.Context, userID int64, query string, resultType string, limit int, offset int) (*SearchResult, error)
+	// page: Sort order, page size and offset or cursor; an empty sort column uses the browser config's
+	SearchAdvanced(ctx context.Context, userID int64, query string, resultType string, page search.PageRequest) (*SearchResult, error)
 }
-
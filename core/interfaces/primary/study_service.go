@@ -0,0 +1,21 @@
+package primary
+
+import (
+	"context"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+)
+
+// IStudyService defines the interface for building study sessions
+type IStudyService interface {
+	// Next returns the next card to study in a deck (including its subdecks) and the cards left for today
+	// Next is nil in the result when nothing is left to study
+	Next(ctx context.Context, userID int64, deckID int64) (*card.StudyQueue, error)
+
+	// NextFiltered returns the next card to study in a filtered deck and the cards left in it, without daily limits
+	// Next is nil in the result when the deck has nothing left to study
+	NextFiltered(ctx context.Context, userID int64, filteredDeckID int64) (*card.StudyQueue, error)
+
+	// UnburyAtDayRollover unburies the cards of every user whose study day rolled over since they were buried
+	UnburyAtDayRollover(ctx context.Context) (int64, error)
+}
@@ -11,7 +11,7 @@ type ISyncMetaService interface {
 	// FindByUserID finds sync metadata for a user
 	FindByUserID(ctx context.Context, userID int64) (*syncmeta.SyncMeta, error)
 
-	// Update updates sync metadata after a synchronization
+	// Update records the USN a client synced up to, creating the client's metadata on first use
 	Update(ctx context.Context, userID int64, clientID string, lastSyncUSN int64) (*syncmeta.SyncMeta, error)
 }
 
@@ -16,5 +16,15 @@ type IUndoHistoryService interface {
 
 	// Delete removes an undo history record
 	Delete(ctx context.Context, userID int64, id int64) error
+
+	// Record stores the changes made by a note, card or deck operation so that it can be undone
+	// It must run in the operation's transaction. Recording discards the operations that could be redone
+	Record(ctx context.Context, userID int64, operationType string, changes *undohistory.ChangeSet) error
+
+	// Undo reverts the user's most recent operation that has not been undone yet
+	Undo(ctx context.Context, userID int64) (*undohistory.UndoHistory, error)
+
+	// Redo applies again the user's most recently undone operation
+	Redo(ctx context.Context, userID int64) (*undohistory.UndoHistory, error)
 }
 
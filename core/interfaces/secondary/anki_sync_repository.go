@@ -0,0 +1,110 @@
+package secondary
+
+import (
+	"context"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+)
+
+// Object types of synced objects, as stored in anki_sync_ids and deletions_log
+// Media are synced with the delta sync API and the media sync protocol
+const (
+	SyncObjectNote         = "note"
+	SyncObjectCard         = "card"
+	SyncObjectDeck         = "deck"
+	SyncObjectFilteredDeck = "filtered_deck"
+	SyncObjectNoteType     = "note_type"
+	SyncObjectPreset       = "deck_options_preset"
+	SyncObjectReview       = "review"
+	SyncObjectMedia        = "media"
+)
+
+// AnkiSyncState is the state of a user's collection as seen by Anki clients
+type AnkiSyncState struct {
+	USN               int        // Update sequence number given to changes until the next sync
+	ModifiedAt        time.Time  // Last change of the collection (mod)
+	SchemaModifiedAt  time.Time  // Last change requiring a full sync (scm)
+	CollectionCreated *int64     // Collection creation in seconds (crt), nil until a client or download sets it
+	ConfigJSON        string     // Collection configuration of the clients, "" when unknown
+	LastSyncAt        *time.Time // Last completed sync
+}
+
+// AnkiSyncID maps an object to the ID it has in Anki collections
+type AnkiSyncID struct {
+	ObjectType string
+	ObjectID   int64
+	AnkiID     int64
+	AnkiGUID   string // Anki GUID of a note added by a client, "" otherwise
+}
+
+// AnkiSyncCounts counts the active objects of a collection
+type AnkiSyncCounts struct {
+	Cards         int
+	Notes         int
+	Reviews       int
+	NoteTypes     int
+	Decks         int
+	FilteredDecks int
+	Presets       int
+}
+
+// AnkiMediaChange is the latest change of a media filename
+type AnkiMediaChange struct {
+	Filename string
+	USN      int
+	Hash     string // SHA-1 of the content, "" when the file was deleted
+}
+
+// IAnkiSyncRepository defines the persistence of the Anki sync protocol: the collection state, the Anki IDs
+// of synced objects and the objects changed or deleted since a USN
+// USNs are stamped on notes, cards, decks, note types, reviews, presets, filtered decks, media and deletion log
+// entries by the database whenever they are written; the delta sync API shares them
+type IAnkiSyncRepository interface {
+	// FindState finds the sync state of a user's collection, creating it on first use
+	FindState(ctx context.Context, userID int64) (*AnkiSyncState, error)
+
+	// UpdateState saves the sync state of a user's collection
+	UpdateState(ctx context.Context, userID int64, state *AnkiSyncState) error
+
+	// AdvanceUSN moves a user's collection to the next USN and returns the previous one
+	// Changes committed before the call have a USN at most the returned one; later changes have a greater one
+	AdvanceUSN(ctx context.Context, userID int64) (int, error)
+
+	// FindAnkiIDs finds the Anki IDs of objects of a type
+	// Objects without an Anki ID are left out
+	FindAnkiIDs(ctx context.Context, userID int64, objectType string, objectIDs []int64) ([]*AnkiSyncID, error)
+
+	// FindObjectIDs finds the objects with Anki IDs
+	// Decks and filtered decks share Anki's deck IDs: objectType SyncObjectDeck finds both
+	FindObjectIDs(ctx context.Context, userID int64, objectType string, ankiIDs []int64) ([]*AnkiSyncID, error)
+
+	// SaveIDs records the Anki IDs of objects that have none yet
+	// An Anki ID already taken by another object is replaced by the next free one, updating ids
+	SaveIDs(ctx context.Context, userID int64, ids []*AnkiSyncID) error
+
+	// FindChangedIDs finds up to limit active objects of a type whose USN is at least minUSN, after afterID in ID order
+	// Cards are only found while their deck and note are active
+	FindChangedIDs(ctx context.Context, userID int64, objectType string, minUSN int, afterID int64, limit int) ([]int64, error)
+
+	// FindDeletedIDs finds the objects of a type deleted with a USN of at least minUSN
+	FindDeletedIDs(ctx context.Context, userID int64, objectType string, minUSN int) ([]int64, error)
+
+	// FindMediaChanges finds the media filenames changed with a USN after afterUSN and at most maxUSN, in USN order
+	// Beyond limit changes, only those sharing the last USN are found; deletions of filenames in use are left out
+	FindMediaChanges(ctx context.Context, userID int64, afterUSN int, maxUSN int, limit int) ([]*AnkiMediaChange, error)
+
+	// FindCards finds cards by ID, validating ownership via deck
+	FindCards(ctx context.Context, userID int64, ids []int64) ([]*card.Card, error)
+
+	// FindReviews finds reviews by ID, validating ownership via card -> deck
+	FindReviews(ctx context.Context, userID int64, ids []int64) ([]*review.Review, error)
+
+	// Count counts the active objects of a user's collection
+	Count(ctx context.Context, userID int64) (*AnkiSyncCounts, error)
+
+	// DeleteCollection deletes every note, card, deck, note type, preset, filtered deck and review of a user,
+	// with their Anki IDs and deletion log, before a client replaces the collection
+	DeleteCollection(ctx context.Context, userID int64) error
+}
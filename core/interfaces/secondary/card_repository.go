@@ -2,6 +2,7 @@ package secondary
 
 import (
 	"context"
+	"time"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/services/search"
@@ -34,6 +35,10 @@ type ICardRepository interface {
 	// Returns error if card doesn't exist or doesn't belong to user's deck
 	Delete(ctx context.Context, userID int64, id int64) error
 
+	// Restore inserts a deleted card again with its original ID, validating ownership via deck
+	// Returns error if the deck doesn't belong to user
+	Restore(ctx context.Context, userID int64, cardEntity *card.Card) error
+
 	// Exists checks if a card exists and belongs to a user's deck
 	Exists(ctx context.Context, userID int64, id int64) (bool, error)
 
@@ -48,6 +53,18 @@ type ICardRepository interface {
 	// dueTimestamp is the current timestamp in milliseconds
 	FindDueCards(ctx context.Context, userID int64, deckID int64, dueTimestamp int64) ([]*card.Card, error)
 
+	// FindQueueCards finds unsuspended, unburied cards for a study queue across several decks
+	// Cards in filtered decks are left out. New cards are ordered by position, other cards by due
+	FindQueueCards(ctx context.Context, userID int64, filters card.QueueFilters) ([]*card.Card, error)
+
+	// FindByFilteredDeckID finds the cards in a filtered deck, in the deck's order
+	FindByFilteredDeckID(ctx context.Context, userID int64, filteredDeckID int64) ([]*card.Card, error)
+
+	// UnburyBeforeDayStart unburies, for every user, the cards buried before the start of the user's
+	// current study day (next_day_starts_at in the user's time zone) and returns how many were unburied
+	// A failure for one user is returned after the other users' cards were unburied
+	UnburyBeforeDayStart(ctx context.Context, now time.Time) (int64, error)
+
 	// FindByState finds all cards with a specific state in a deck
 	FindByState(ctx context.Context, userID int64, deckID int64, state valueobjects.CardState) ([]*card.Card, error)
 
@@ -60,9 +77,9 @@ type ICardRepository interface {
 	// DeleteByDeckRecursive deletes all cards from a deck and its sub-decks
 	DeleteByDeckRecursive(ctx context.Context, userID int64, deckID int64) error
 
-	// FindByAdvancedSearch finds cards matching advanced search criteria
-	// Used for is:new, is:due, is:review, prop: filters
-	FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery) ([]*card.Card, error)
+	// FindByAdvancedSearch finds one page of the cards matching a search, sorted by a browser column
+	// Returns the total number of matches and the cursor of the next page
+	FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery, page search.PageRequest) ([]*card.Card, search.PageInfo, error)
 
 	// FindAll finds cards for a user based on filters and pagination
 	// Returns: list of cards, total count (for pagination), error
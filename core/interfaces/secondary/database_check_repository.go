@@ -0,0 +1,33 @@
+package secondary
+
+import (
+	"context"
+	"time"
+)
+
+// IDatabaseCheckRepository defines the queries Check Database uses to find and repair inconsistent rows
+// Each method fixes the rows it finds in a single statement and returns their IDs
+// Ownership of cards is resolved through their deck, including soft-deleted decks
+type IDatabaseCheckRepository interface {
+	// DeleteCardsOfDeletedNotes deletes cards whose note is deleted and returns their IDs
+	DeleteCardsOfDeletedNotes(ctx context.Context, userID int64) ([]int64, error)
+
+	// MoveCardsFromDeletedDecks moves cards whose deck is deleted to targetDeckID and returns their IDs
+	MoveCardsFromDeletedDecks(ctx context.Context, userID int64, targetDeckID int64) ([]int64, error)
+
+	// ReturnCardsWithMissingHomeDeck moves filtered cards whose home deck is deleted to targetDeckID,
+	// clearing their home deck, and returns their IDs
+	ReturnCardsWithMissingHomeDeck(ctx context.Context, userID int64, targetDeckID int64) ([]int64, error)
+
+	// DeleteCardsWithInvalidOrdinal deletes the cards of a note type's notes whose card type ordinal
+	// is not below cardTypeCount and returns their IDs
+	DeleteCardsWithInvalidOrdinal(ctx context.Context, userID int64, noteTypeID int64, cardTypeCount int) ([]int64, error)
+
+	// FixInvalidScheduling resets due and interval values that are invalid for the card's state and returns the card IDs:
+	// review cards with an interval under a day get a 1 day interval, new cards lose their interval,
+	// and cards in learning or review that aren't due at a valid timestamp become due at now
+	FixInvalidScheduling(ctx context.Context, userID int64, now time.Time) ([]int64, error)
+
+	// FindDuplicateGUIDNotes returns the IDs of active notes sharing their GUID with an older active note
+	FindDuplicateGUIDNotes(ctx context.Context, userID int64) ([]int64, error)
+}
@@ -2,6 +2,7 @@ package secondary
 
 import (
 	"context"
+	"time"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
 )
@@ -39,9 +40,21 @@ type IDeckRepository interface {
 	// Returns error if deck doesn't exist or doesn't belong to user
 	Delete(ctx context.Context, userID int64, deckID int64) error
 
+	// Restore restores a single soft-deleted deck, validating ownership (its subdecks are not restored)
+	// Returns error if deck isn't deleted or doesn't belong to user
+	Restore(ctx context.Context, userID int64, deckID int64) error
+
 	// Exists checks if a deck with the given name exists for the user at the specified parent level
 	Exists(ctx context.Context, userID int64, name string, parentID *int64) (bool, error)
 
 	// GetStats retrieves study statistics for a deck
 	GetStats(ctx context.Context, userID int64, deckID int64) (*deck.DeckStats, error)
+
+	// ExtendLimits adds cards to a deck's daily limits for the study day starting at dayStart
+	// Extensions from an earlier study day are replaced
+	ExtendLimits(ctx context.Context, userID int64, deckID int64, dayStart time.Time, newCards int, reviews int) (*deck.LimitExtension, error)
+
+	// FindLimitExtension finds the extension of a deck's daily limits for the study day starting at dayStart
+	// Returns an empty extension if the limits were not extended that day
+	FindLimitExtension(ctx context.Context, userID int64, deckID int64, dayStart time.Time) (*deck.LimitExtension, error)
 }
@@ -36,10 +36,14 @@ type IDeletionLogRepository interface {
 	// FindByObjectType finds all deletion logs of a specific object type for a user
 	FindByObjectType(ctx context.Context, userID int64, objectType string) ([]*deletionlog.DeletionLog, error)
 
-	// FindRecent finds recent deletion logs for a user within a specified time period
+	// FindRecent finds recent note deletion logs for a user within a specified time period
 	// limit: maximum number of records to return (must be > 0)
 	// days: number of days to look back (must be > 0)
 	// Returns deletion logs ordered by deleted_at DESC, limited to the specified count
 	FindRecent(ctx context.Context, userID int64, limit int, days int) ([]*deletionlog.DeletionLog, error)
+
+	// FindByMinUSN finds the deletion logs of every object type written with a USN of at least minUSN
+	// Returns deletion logs ordered by ID, oldest first
+	FindByMinUSN(ctx context.Context, userID int64, minUSN int) ([]*deletionlog.DeletionLog, error)
 }
 
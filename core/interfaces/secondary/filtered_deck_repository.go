@@ -4,6 +4,7 @@ import (
 	"context"
 
 	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
 )
 
 // IFilteredDeckRepository defines the interface for filtered deck data persistence
@@ -32,5 +33,25 @@ type IFilteredDeckRepository interface {
 
 	// Exists checks if a filtered deck exists and belongs to the user
 	Exists(ctx context.Context, userID int64, id int64) (bool, error)
+
+	// FindByCardID finds the filtered deck holding a card
+	// Returns nil if the card is in no filtered deck
+	FindByCardID(ctx context.Context, userID int64, cardID int64) (*filtereddeck.FilteredDeck, error)
+
+	// MoveCardsIn moves up to limit cards matching query into a filtered deck, after the cards already in it
+	// Suspended and buried cards and cards in another filtered deck are skipped. Each card keeps its deck,
+	// which is recorded as its home deck. Returns the number of cards moved
+	MoveCardsIn(ctx context.Context, userID int64, id int64, query *search.SearchQuery, orderBy string, limit int) (int, error)
+
+	// ReturnCards returns every card of a filtered deck to its home deck and returns the number of cards
+	ReturnCards(ctx context.Context, userID int64, id int64) (int, error)
+
+	// ReturnCard returns a card to its home deck from the filtered deck holding it
+	// Returns the card's position in the filtered deck, so that the return can be undone with RestoreCard
+	ReturnCard(ctx context.Context, userID int64, cardID int64) (int, error)
+
+	// RestoreCard puts a card returned to its home deck back into a filtered deck at a position
+	// Returns ownership.ErrResourceNotFound if the filtered deck no longer exists or already holds the card
+	RestoreCard(ctx context.Context, userID int64, id int64, cardID int64, position int) error
 }
 
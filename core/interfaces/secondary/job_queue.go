@@ -28,6 +28,8 @@ type Job struct {
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	FailedAt    *time.Time             `json:"failed_at,omitempty"`
 	Error       string                 `json:"error,omitempty"`
+	Progress    int                    `json:"progress"`         // Completion percentage (0-100) reported by the handler
+	Result      map[string]interface{} `json:"result,omitempty"` // Output reported by the handler
 }
 
 // IJobQueue defines the interface for job queue operations
@@ -46,6 +48,9 @@ type IJobQueue interface {
 	// UpdateStatus updates the status of a job
 	UpdateStatus(ctx context.Context, jobID string, status JobStatus) error
 
+	// UpdateProgress updates the progress percentage and, if not nil, the result of a job
+	UpdateProgress(ctx context.Context, jobID string, progress int, result map[string]interface{}) error
+
 	// Retry re-enqueues a failed job for retry
 	Retry(ctx context.Context, job *Job) error
 }
@@ -12,6 +12,7 @@ type IMediaRepository interface {
 	// Save saves or updates a media in the database
 	// If the media has an ID, it updates the existing media
 	// If the media has no ID, it creates a new media and returns it with the ID set
+	// Returns media.ErrDuplicateContent when creating a media whose hash the user's active media already has
 	Save(ctx context.Context, userID int64, mediaEntity *media.Media) error
 
 	// FindByID finds a media by ID, filtering by userID to ensure ownership
@@ -38,5 +39,11 @@ type IMediaRepository interface {
 
 	// FindByFilename finds a media by filename for a user
 	FindByFilename(ctx context.Context, userID int64, filename string) (*media.Media, error)
+
+	// TotalSize returns the total size in bytes of a user's active media
+	TotalSize(ctx context.Context, userID int64) (int64, error)
+
+	// FindLinkedIDs returns the IDs of a user's media linked to active notes through note_media
+	FindLinkedIDs(ctx context.Context, userID int64) ([]int64, error)
 }
 
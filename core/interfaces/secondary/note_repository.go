@@ -49,15 +49,19 @@ type INoteRepository interface {
 	// Returns error if note doesn't exist or doesn't belong to user
 	Delete(ctx context.Context, userID int64, id int64) error
 
+	// Restore restores a soft-deleted note, validating ownership
+	// Returns error if note isn't deleted or doesn't belong to user
+	Restore(ctx context.Context, userID int64, id int64) error
+
 	// Exists checks if a note exists and belongs to the user
 	Exists(ctx context.Context, userID int64, id int64) (bool, error)
 
 	// FindByGUID finds a note by GUID, filtering by userID to ensure ownership
 	FindByGUID(ctx context.Context, userID int64, guid string) (*note.Note, error)
 
-	// FindByAdvancedSearch finds notes matching advanced search criteria
-	// Combines multiple filters: deck, tags, fields, card states, properties
-	FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery, limit int, offset int) ([]*note.Note, error)
+	// FindByAdvancedSearch finds one page of the notes matching a search, sorted by a browser column
+	// Returns the total number of matches and the cursor of the next page
+	FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery, page search.PageRequest) ([]*note.Note, search.PageInfo, error)
 
 	// FindDuplicatesByField finds duplicate notes grouped by field value
 	// Returns groups of notes that have the same value for the specified field
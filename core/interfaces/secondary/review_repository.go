@@ -38,6 +38,14 @@ type IReviewRepository interface {
 	// FindByDateRange finds all reviews within a date range for a user
 	FindByDateRange(ctx context.Context, userID int64, startDate time.Time, endDate time.Time) ([]*review.Review, error)
 
+	// FindHistory finds all reviews of a user's cards ordered by card and review time (oldest first)
+	// If deckIDs is not empty, only reviews of cards in those decks are returned
+	FindHistory(ctx context.Context, userID int64, deckIDs []int64) ([]*review.Review, error)
+
+	// CountStudiedSince counts, for cards in the given decks, the cards introduced (first reviewed)
+	// since the given time and the review-queue answers given since that time
+	CountStudiedSince(ctx context.Context, userID int64, deckIDs []int64, since time.Time) (introduced int, reviewed int, err error)
+
 	// DeleteByCardID deletes all reviews for a specific card, validating ownership
 	DeleteByCardID(ctx context.Context, userID int64, cardID int64) error
 }
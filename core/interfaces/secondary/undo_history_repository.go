@@ -35,5 +35,19 @@ type IUndoHistoryRepository interface {
 
 	// FindLatest finds the latest undo history entries for a user
 	FindLatest(ctx context.Context, userID int64, limit int) ([]*undohistory.UndoHistory, error)
-}
 
+	// FindLatestByType finds the most recent undo history entry of an operation type for a user
+	// Returns nil if the user has no entry of that type
+	FindLatestByType(ctx context.Context, userID int64, operationType string) (*undohistory.UndoHistory, error)
+
+	// FindLatestApplied finds the most recent entry of the given operation types that has not been undone
+	// Returns nil if there is nothing to undo
+	FindLatestApplied(ctx context.Context, userID int64, operationTypes []string) (*undohistory.UndoHistory, error)
+
+	// FindLatestUndone finds the most recently undone entry of the given operation types
+	// Returns nil if there is nothing to redo
+	FindLatestUndone(ctx context.Context, userID int64, operationTypes []string) (*undohistory.UndoHistory, error)
+
+	// DeleteUndone deletes all undone entries of a user, discarding what could be redone
+	DeleteUndone(ctx context.Context, userID int64) error
+}
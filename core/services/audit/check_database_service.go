@@ -0,0 +1,322 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	checkdatabaselog "github.com/felipesantos/anki-backend/core/domain/entities/check_database_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// checkNotesPageSize is the number of notes checked at a time
+const checkNotesPageSize = 1000
+
+// CheckDatabaseService implements ICheckDatabaseService
+type CheckDatabaseService struct {
+	checkRepo    secondary.IDatabaseCheckRepository
+	checkLogRepo secondary.ICheckDatabaseLogRepository
+	noteRepo     secondary.INoteRepository
+	noteTypeRepo secondary.INoteTypeRepository
+	deckRepo     secondary.IDeckRepository
+}
+
+// NewCheckDatabaseService creates a new CheckDatabaseService instance
+func NewCheckDatabaseService(
+	checkRepo secondary.IDatabaseCheckRepository,
+	checkLogRepo secondary.ICheckDatabaseLogRepository,
+	noteRepo secondary.INoteRepository,
+	noteTypeRepo secondary.INoteTypeRepository,
+	deckRepo secondary.IDeckRepository,
+) primary.ICheckDatabaseService {
+	return &CheckDatabaseService{
+		checkRepo:    checkRepo,
+		checkLogRepo: checkLogRepo,
+		noteRepo:     noteRepo,
+		noteTypeRepo: noteTypeRepo,
+		deckRepo:     deckRepo,
+	}
+}
+
+// CheckDatabase finds and repairs inconsistent cards and notes, and logs a report of the repairs
+// A run that fails part way is logged as failed with the repairs made until then
+func (s *CheckDatabaseService) CheckDatabase(ctx context.Context, userID int64) (*primary.CheckDatabaseResult, error) {
+	start := time.Now()
+	result := &primary.CheckDatabaseResult{}
+
+	checkErr := s.repair(ctx, userID, result)
+
+	status := checkdatabaselog.CheckStatusCompleted
+	if checkErr != nil {
+		status = checkdatabaselog.CheckStatusFailed
+	}
+	logID, err := s.logResult(ctx, userID, status, result, checkErr, time.Since(start))
+	if checkErr != nil {
+		return nil, checkErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.LogID = logID
+	return result, nil
+}
+
+// repair runs each check in turn, recording what it fixed in result
+// Cards of deleted notes are removed first, so later checks only see cards that are kept
+func (s *CheckDatabaseService) repair(ctx context.Context, userID int64, result *primary.CheckDatabaseResult) error {
+	var err error
+	if result.CardsWithDeletedNote, err = s.checkRepo.DeleteCardsOfDeletedNotes(ctx, userID); err != nil {
+		return err
+	}
+
+	defaultDeckID, err := s.defaultDeckID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if result.CardsInDeletedDeck, err = s.checkRepo.MoveCardsFromDeletedDecks(ctx, userID, defaultDeckID); err != nil {
+		return err
+	}
+	if result.CardsWithMissingHomeDeck, err = s.checkRepo.ReturnCardsWithMissingHomeDeck(ctx, userID, defaultDeckID); err != nil {
+		return err
+	}
+
+	noteTypes, err := s.noteTypeRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return fmt.Errorf("failed to find note types: %w", err)
+	}
+	for _, nt := range noteTypes {
+		// Cloze cards are numbered by the note's cloze deletions, not by card type
+		if nt.IsCloze() || nt.GetCardTypeCount() == 0 {
+			continue
+		}
+		ids, err := s.checkRepo.DeleteCardsWithInvalidOrdinal(ctx, userID, nt.GetID(), nt.GetCardTypeCount())
+		if err != nil {
+			return err
+		}
+		result.CardsWithInvalidOrdinal = append(result.CardsWithInvalidOrdinal, ids...)
+	}
+
+	if result.NotesWithWrongFieldCount, err = s.fixNoteFields(ctx, userID, noteTypes); err != nil {
+		return err
+	}
+	if result.CardsWithInvalidSchedule, err = s.checkRepo.FixInvalidScheduling(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	if result.NotesWithDuplicateGUID, err = s.fixDuplicateGUIDs(ctx, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// defaultDeckID returns the user's root Default deck, creating it when missing
+func (s *CheckDatabaseService) defaultDeckID(ctx context.Context, userID int64) (int64, error) {
+	decks, err := s.deckRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find decks: %w", err)
+	}
+	for _, d := range decks {
+		if d.GetName() == "Default" && d.IsRoot() {
+			return d.GetID(), nil
+		}
+	}
+	return s.deckRepo.CreateDefaultDeck(ctx, userID)
+}
+
+// fixNoteFields gives notes exactly the fields of their note type and returns the IDs of the notes changed
+func (s *CheckDatabaseService) fixNoteFields(ctx context.Context, userID int64, noteTypes []*notetype.NoteType) ([]int64, error) {
+	fieldNames := make(map[int64][]string, len(noteTypes))
+	for _, nt := range noteTypes {
+		if names := nt.GetFieldNames(); len(names) > 0 {
+			fieldNames[nt.GetID()] = names
+		}
+	}
+
+	fixed := []int64{}
+	for offset := 0; ; offset += checkNotesPageSize {
+		notes, err := s.noteRepo.FindByUserID(ctx, userID, checkNotesPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find notes: %w", err)
+		}
+
+		for _, n := range notes {
+			names, ok := fieldNames[n.GetNoteTypeID()]
+			if !ok {
+				continue
+			}
+			fieldsJSON, changed := matchFields(n.GetFieldsJSON(), names)
+			if !changed {
+				continue
+			}
+			n.SetFieldsJSON(fieldsJSON)
+			n.SetUpdatedAt(time.Now())
+			if err := s.noteRepo.Update(ctx, userID, n.GetID(), n); err != nil {
+				return nil, fmt.Errorf("failed to fix fields of note %d: %w", n.GetID(), err)
+			}
+			fixed = append(fixed, n.GetID())
+		}
+
+		if len(notes) < checkNotesPageSize {
+			return fixed, nil
+		}
+	}
+}
+
+// matchFields returns the note fields with exactly the given field names and whether they changed
+// Missing fields are added empty; the content of fields the note type doesn't have is appended to the last field
+func matchFields(fieldsJSON string, names []string) (string, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(fieldsJSON), &fields); err != nil || fields == nil {
+		fields = map[string]interface{}{}
+	}
+
+	known := make(map[string]bool, len(names))
+	matched := make(map[string]string, len(names))
+	changed := len(fields) != len(names)
+	for _, name := range names {
+		known[name] = true
+		value, ok := fields[name]
+		if !ok {
+			changed = true
+			value = ""
+		}
+		matched[name] = fieldString(value)
+	}
+
+	var extra []string
+	for name := range fields {
+		if !known[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	last := names[len(names)-1]
+	for _, name := range extra {
+		if value := fieldString(fields[name]); value != "" {
+			matched[last] = strings.TrimSpace(matched[last] + " " + value)
+		}
+	}
+
+	if !changed {
+		return fieldsJSON, false
+	}
+	data, _ := json.Marshal(matched)
+	return string(data), true
+}
+
+// fieldString returns a field value as text
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// fixDuplicateGUIDs gives a new GUID to every note sharing its GUID with an older note and returns their IDs
+func (s *CheckDatabaseService) fixDuplicateGUIDs(ctx context.Context, userID int64) ([]int64, error) {
+	ids, err := s.checkRepo.FindDuplicateGUIDNotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		n, err := s.noteRepo.FindByID(ctx, userID, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find note %d: %w", id, err)
+		}
+		if n == nil {
+			continue
+		}
+		if err := s.assignNewGUID(ctx, userID, n); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// assignNewGUID replaces a note's GUID with a newly generated one
+func (s *CheckDatabaseService) assignNewGUID(ctx context.Context, userID int64, n *note.Note) error {
+	guid, err := valueobjects.NewGUID(uuid.New().String())
+	if err != nil {
+		return err
+	}
+	n.SetGUID(guid)
+	n.SetUpdatedAt(time.Now())
+	if err := s.noteRepo.Update(ctx, userID, n.GetID(), n); err != nil {
+		return fmt.Errorf("failed to update GUID of note %d: %w", n.GetID(), err)
+	}
+	return nil
+}
+
+// logResult records a check run in check_database_log with a report of the repairs and returns the entry's ID
+func (s *CheckDatabaseService) logResult(ctx context.Context, userID int64, status string, result *primary.CheckDatabaseResult, checkErr error, elapsed time.Duration) (int64, error) {
+	details := checkDatabaseDetails{
+		Check:                    "database",
+		CardsWithDeletedNote:     nonNilIDs(result.CardsWithDeletedNote),
+		CardsInDeletedDeck:       nonNilIDs(result.CardsInDeletedDeck),
+		CardsWithMissingHomeDeck: nonNilIDs(result.CardsWithMissingHomeDeck),
+		NotesWithWrongFieldCount: nonNilIDs(result.NotesWithWrongFieldCount),
+		CardsWithInvalidOrdinal:  nonNilIDs(result.CardsWithInvalidOrdinal),
+		CardsWithInvalidSchedule: nonNilIDs(result.CardsWithInvalidSchedule),
+		NotesWithDuplicateGUID:   nonNilIDs(result.NotesWithDuplicateGUID),
+	}
+	if checkErr != nil {
+		details.Error = checkErr.Error()
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal database check details: %w", err)
+	}
+
+	executionTimeMs := int(elapsed.Milliseconds())
+	entry, err := checkdatabaselog.NewBuilder().
+		WithUserID(userID).
+		WithStatus(status).
+		WithIssuesFound(result.IssuesFound()).
+		WithIssuesDetails(string(detailsJSON)).
+		WithExecutionTimeMs(&executionTimeMs).
+		WithCreatedAt(time.Now()).
+		Build()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.checkLogRepo.Save(ctx, userID, entry); err != nil {
+		return 0, fmt.Errorf("failed to log database check: %w", err)
+	}
+	return entry.GetID(), nil
+}
+
+// checkDatabaseDetails is the issues_details JSON of a database check log entry
+type checkDatabaseDetails struct {
+	Check                    string  `json:"check"`
+	CardsWithDeletedNote     []int64 `json:"cards_with_deleted_note"`
+	CardsInDeletedDeck       []int64 `json:"cards_in_deleted_deck"`
+	CardsWithMissingHomeDeck []int64 `json:"cards_with_missing_home_deck"`
+	NotesWithWrongFieldCount []int64 `json:"notes_with_wrong_field_count"`
+	CardsWithInvalidOrdinal  []int64 `json:"cards_with_invalid_ordinal"`
+	CardsWithInvalidSchedule []int64 `json:"cards_with_invalid_schedule"`
+	NotesWithDuplicateGUID   []int64 `json:"notes_with_duplicate_guid"`
+	Error                    string  `json:"error,omitempty"`
+}
+
+// nonNilIDs returns ids, or an empty list when nil, so the report lists every check
+func nonNilIDs(ids []int64) []int64 {
+	if ids == nil {
+		return []int64{}
+	}
+	return ids
+}
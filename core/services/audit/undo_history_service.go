@@ -2,22 +2,48 @@ package audit
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"time"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
 	"github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/pkg/database"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
 )
 
+// ErrUndoConflict is returned when an object was changed after the operation being undone or redone
+var ErrUndoConflict = errors.New("undo conflict")
+
 // UndoHistoryService implements IUndoHistoryService
 type UndoHistoryService struct {
-	repo secondary.IUndoHistoryRepository
+	repo     secondary.IUndoHistoryRepository
+	noteRepo secondary.INoteRepository
+	cardRepo secondary.ICardRepository
+	deckRepo secondary.IDeckRepository
+	tm       database.TransactionManager
 }
 
 // NewUndoHistoryService creates a new UndoHistoryService instance
-func NewUndoHistoryService(repo secondary.IUndoHistoryRepository) primary.IUndoHistoryService {
+func NewUndoHistoryService(
+	repo secondary.IUndoHistoryRepository,
+	noteRepo secondary.INoteRepository,
+	cardRepo secondary.ICardRepository,
+	deckRepo secondary.IDeckRepository,
+	tm database.TransactionManager,
+) primary.IUndoHistoryService {
 	return &UndoHistoryService{
-		repo: repo,
+		repo:     repo,
+		noteRepo: noteRepo,
+		cardRepo: cardRepo,
+		deckRepo: deckRepo,
+		tm:       tm,
 	}
 }
 
@@ -50,3 +76,291 @@ func (s *UndoHistoryService) Delete(ctx context.Context, userID int64, id int64)
 	return s.repo.Delete(ctx, userID, id)
 }
 
+// Record stores the changes made by a note, card or deck operation so that it can be undone
+func (s *UndoHistoryService) Record(ctx context.Context, userID int64, operationType string, changes *undohistory.ChangeSet) error {
+	if changes.IsEmpty() {
+		return nil
+	}
+
+	operationData, err := changes.Encode()
+	if err != nil {
+		return err
+	}
+
+	uh, err := undohistory.NewBuilder().
+		WithUserID(userID).
+		WithOperationType(operationType).
+		WithOperationData(operationData).
+		WithCreatedAt(time.Now()).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	// A new operation makes the undone ones impossible to redo
+	if err := s.repo.DeleteUndone(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.repo.Save(ctx, userID, uh)
+}
+
+// Undo reverts the user's most recent operation that has not been undone yet
+// Every object must still be in the state the operation left it in, otherwise ErrUndoConflict is returned
+func (s *UndoHistoryService) Undo(ctx context.Context, userID int64) (*undohistory.UndoHistory, error) {
+	return s.replay(ctx, userID, true)
+}
+
+// Redo applies again the user's most recently undone operation
+// Every object must still be in the state the undo left it in, otherwise ErrUndoConflict is returned
+func (s *UndoHistoryService) Redo(ctx context.Context, userID int64) (*undohistory.UndoHistory, error) {
+	return s.replay(ctx, userID, false)
+}
+
+// replay undoes or redoes the latest eligible entry in a single transaction
+func (s *UndoHistoryService) replay(ctx context.Context, userID int64, undo bool) (*undohistory.UndoHistory, error) {
+	var entry *undohistory.UndoHistory
+
+	err := s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		// 1. Find the entry to replay
+		var err error
+		if undo {
+			entry, err = s.repo.FindLatestApplied(txCtx, userID, undohistory.ChangeSetOperationTypes)
+		} else {
+			entry, err = s.repo.FindLatestUndone(txCtx, userID, undohistory.ChangeSetOperationTypes)
+		}
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			if undo {
+				return fmt.Errorf("operation to undo not found")
+			}
+			return fmt.Errorf("operation to redo not found")
+		}
+
+		changeSet, err := undohistory.ParseChangeSet(entry.GetOperationData())
+		if err != nil {
+			return err
+		}
+
+		// Undo walks the changes backwards, restoring the state before each one
+		changes := make([]undohistory.Change, len(changeSet.Changes))
+		copy(changes, changeSet.Changes)
+		if undo {
+			for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+				changes[i], changes[j] = changes[j], changes[i]
+			}
+		}
+
+		// 2. Check every object before writing anything
+		for _, change := range changes {
+			expected := change.Before
+			if undo {
+				expected = change.After
+			}
+
+			current, err := s.currentState(txCtx, userID, change.Kind, change.ID)
+			if err != nil {
+				return err
+			}
+			if !sameState(current, expected) {
+				return fmt.Errorf("%w: %s %d was changed after this operation", ErrUndoConflict, change.Kind, change.ID)
+			}
+		}
+
+		// 3. Apply the target states
+		for _, change := range changes {
+			target := change.After
+			if undo {
+				target = change.Before
+			}
+
+			if err := s.applyState(txCtx, userID, change.Kind, change.ID, target); err != nil {
+				return fmt.Errorf("failed to restore %s %d: %w", change.Kind, change.ID, err)
+			}
+		}
+
+		// 4. Move the entry between the undo and redo stacks
+		if undo {
+			entry.MarkUndone(time.Now())
+		} else {
+			entry.MarkRedone()
+		}
+		return s.repo.Update(txCtx, userID, entry.GetID(), entry)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// currentState returns the snapshot of an object as it is now, or nil if it doesn't exist
+func (s *UndoHistoryService) currentState(ctx context.Context, userID int64, kind undohistory.ObjectKind, id int64) (json.RawMessage, error) {
+	var snapshot interface{}
+
+	switch kind {
+	case undohistory.ObjectKindNote:
+		n, err := s.noteRepo.FindByID(ctx, userID, id)
+		if err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+			return nil, err
+		}
+		if n == nil {
+			return nil, nil
+		}
+		snapshot = n.Snapshot()
+	case undohistory.ObjectKindCard:
+		c, err := s.cardRepo.FindByID(ctx, userID, id)
+		if err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+			return nil, err
+		}
+		if c == nil {
+			return nil, nil
+		}
+		snapshot = c.Snapshot()
+	case undohistory.ObjectKindDeck:
+		d, err := s.deckRepo.FindByID(ctx, userID, id)
+		if err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+			return nil, err
+		}
+		if d == nil {
+			return nil, nil
+		}
+		snapshot = d.Snapshot()
+	default:
+		return nil, fmt.Errorf("unknown object kind: %s", kind)
+	}
+
+	return json.Marshal(snapshot)
+}
+
+// applyState puts an object into a snapshot state, deleting it if the snapshot is empty
+func (s *UndoHistoryService) applyState(ctx context.Context, userID int64, kind undohistory.ObjectKind, id int64, target json.RawMessage) error {
+	switch kind {
+	case undohistory.ObjectKindNote:
+		return s.applyNoteState(ctx, userID, id, target)
+	case undohistory.ObjectKindCard:
+		return s.applyCardState(ctx, userID, id, target)
+	case undohistory.ObjectKindDeck:
+		return s.applyDeckState(ctx, userID, id, target)
+	default:
+		return fmt.Errorf("unknown object kind: %s", kind)
+	}
+}
+
+func (s *UndoHistoryService) applyNoteState(ctx context.Context, userID int64, id int64, target json.RawMessage) error {
+	n, err := s.noteRepo.FindByID(ctx, userID, id)
+	if err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+		return err
+	}
+
+	if target == nil {
+		if n == nil {
+			return nil
+		}
+		return s.noteRepo.Delete(ctx, userID, id)
+	}
+
+	var snapshot note.Snapshot
+	if err := json.Unmarshal(target, &snapshot); err != nil {
+		return fmt.Errorf("invalid note snapshot: %w", err)
+	}
+
+	if n == nil {
+		if err := s.noteRepo.Restore(ctx, userID, id); err != nil {
+			return err
+		}
+		if n, err = s.noteRepo.FindByID(ctx, userID, id); err != nil {
+			return err
+		}
+	}
+
+	n.RestoreSnapshot(&snapshot)
+	return s.noteRepo.Update(ctx, userID, id, n)
+}
+
+func (s *UndoHistoryService) applyCardState(ctx context.Context, userID int64, id int64, target json.RawMessage) error {
+	c, err := s.cardRepo.FindByID(ctx, userID, id)
+	if err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+		return err
+	}
+
+	if target == nil {
+		if c == nil {
+			return nil
+		}
+		return s.cardRepo.Delete(ctx, userID, id)
+	}
+
+	var snapshot card.Snapshot
+	if err := json.Unmarshal(target, &snapshot); err != nil {
+		return fmt.Errorf("invalid card snapshot: %w", err)
+	}
+
+	// Cards are hard deleted, so a deleted card is inserted again with its original ID
+	if c == nil {
+		c, err = card.NewBuilder().
+			WithID(id).
+			WithNoteID(snapshot.NoteID).
+			WithDeckID(snapshot.DeckID).
+			WithState(snapshot.State).
+			Build()
+		if err != nil {
+			return err
+		}
+		c.RestoreSnapshot(&snapshot)
+		return s.cardRepo.Restore(ctx, userID, c)
+	}
+
+	c.RestoreSnapshot(&snapshot)
+	return s.cardRepo.Update(ctx, userID, id, c)
+}
+
+func (s *UndoHistoryService) applyDeckState(ctx context.Context, userID int64, id int64, target json.RawMessage) error {
+	d, err := s.deckRepo.FindByID(ctx, userID, id)
+	if err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+		return err
+	}
+
+	if target == nil {
+		if d == nil {
+			return nil
+		}
+		return s.deckRepo.Delete(ctx, userID, id)
+	}
+
+	var snapshot deck.Snapshot
+	if err := json.Unmarshal(target, &snapshot); err != nil {
+		return fmt.Errorf("invalid deck snapshot: %w", err)
+	}
+
+	if d == nil {
+		if err := s.deckRepo.Restore(ctx, userID, id); err != nil {
+			return err
+		}
+		if d, err = s.deckRepo.FindByID(ctx, userID, id); err != nil {
+			return err
+		}
+	}
+
+	d.RestoreSnapshot(&snapshot)
+	return s.deckRepo.Update(ctx, userID, id, d)
+}
+
+// sameState compares two snapshots by their JSON values, so that formatting differences are ignored
+func sameState(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 	"github.com/felipesantos/anki-backend/core/services/session"
+	syncService "github.com/felipesantos/anki-backend/core/services/sync"
 	"github.com/felipesantos/anki-backend/pkg/jwt"
 	"github.com/felipesantos/anki-backend/pkg/logger"
 )
@@ -178,6 +179,7 @@ func (s *AuthService) Register(ctx context.Context, email string, password strin
 			WithTheme(valueobjects.ThemeTypeAuto).
 			WithAutoSync(true).
 			WithNextDayStartsAt(nextDayStartsAt).
+			WithTimezone(userpreferences.DefaultTimezone).
 			WithLearnAheadLimit(20).
 			WithTimeboxTimeLimit(0).
 			WithVideoDriver("auto").
@@ -487,13 +489,16 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*r
 }
 
 // Logout invalidates both access token and refresh token
-// It adds the access token to a blacklist in Redis and removes the refresh token
+// It adds the access token to a blacklist in Redis, removes the refresh token and revokes the user's sync host keys
 func (s *AuthService) Logout(ctx context.Context, accessToken string, refreshToken string) error {
+	var userID int64
+
 	// 1. Invalidate access token (add to blacklist)
 	if accessToken != "" {
 		// Validate access token to get expiration time
 		claims, err := s.jwtService.ValidateAccessToken(accessToken)
 		if err == nil && claims != nil {
+			userID = claims.UserID
 			// Calculate TTL: time until token expires
 			ttl := time.Until(claims.ExpiresAt.Time)
 			if ttl > 0 {
@@ -518,7 +523,6 @@ func (s *AuthService) Logout(ctx context.Context, accessToken string, refreshTok
 
 	// 2. Invalidate refresh token and associated session
 	var sessionID string
-	var userID int64
 	if refreshToken != "" {
 		// Validate refresh token to get user ID
 		claims, err := s.jwtService.ValidateRefreshToken(refreshToken)
@@ -556,7 +560,14 @@ func (s *AuthService) Logout(ctx context.Context, accessToken string, refreshTok
 		}
 	}
 
-	// 3. Invalidate specific session (if found)
+	// 3. Revoke the user's sync host keys so Anki clients must log in again
+	if userID > 0 {
+		if err := syncService.RevokeHostKeys(ctx, s.cacheRepo, userID, s.jwtService.GetSyncHostKeyExpiry()); err != nil {
+			return err
+		}
+	}
+
+	// 4. Invalidate specific session (if found)
 	if sessionID != "" && userID > 0 {
 		if err := s.sessionService.DeleteUserSession(ctx, userID, sessionID); err != nil {
 			log := logger.GetLogger()
@@ -725,7 +736,12 @@ func (s *AuthService) ResetPassword(ctx context.Context, token string, newPasswo
 		return fmt.Errorf("failed to update user password: %w", err)
 	}
 
-	// 8. Invalidate all sessions for this user (security: force re-login after password reset)
+	// 8. Revoke the user's sync host keys
+	if err := syncService.RevokeHostKeys(ctx, s.cacheRepo, user.GetID(), s.jwtService.GetSyncHostKeyExpiry()); err != nil {
+		return err
+	}
+
+	// 9. Invalidate all sessions for this user (security: force re-login after password reset)
 	if err := s.sessionService.DeleteAllUserSessions(ctx, user.GetID()); err != nil {
 		log := logger.GetLogger()
 		log.Warn("Failed to delete all user sessions during password reset",
@@ -775,7 +791,12 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID int64, currentP
 		return fmt.Errorf("failed to update user password: %w", err)
 	}
 
-	// 7. Invalidate all sessions for this user (security: force re-login after password change)
+	// 7. Revoke the user's sync host keys
+	if err := syncService.RevokeHostKeys(ctx, s.cacheRepo, userID, s.jwtService.GetSyncHostKeyExpiry()); err != nil {
+		return err
+	}
+
+	// 8. Invalidate all sessions for this user (security: force re-login after password change)
 	if err := s.sessionService.DeleteAllUserSessions(ctx, userID); err != nil {
 		log := logger.GetLogger()
 		log.Warn("Failed to delete all user sessions during password change",
@@ -3,15 +3,18 @@ package card
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/core/domain/services"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 	"github.com/felipesantos/anki-backend/pkg/database"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
 )
 
 // CardService implements ICardService
@@ -22,6 +25,7 @@ type CardService struct {
 	noteTypeService  primary.INoteTypeService
 	reviewService    primary.IReviewService
 	templateRenderer services.ITemplateRenderer
+	undoService      primary.IUndoHistoryService
 	tm               database.TransactionManager
 }
 
@@ -33,6 +37,7 @@ func NewCardService(
 	noteTypeService primary.INoteTypeService,
 	reviewService primary.IReviewService,
 	templateRenderer services.ITemplateRenderer,
+	undoService primary.IUndoHistoryService,
 	tm database.TransactionManager,
 ) primary.ICardService {
 	return &CardService{
@@ -42,6 +47,7 @@ func NewCardService(
 		noteTypeService:  noteTypeService,
 		reviewService:    reviewService,
 		templateRenderer: templateRenderer,
+		undoService:      undoService,
 		tm:               tm,
 	}
 }
@@ -58,85 +64,113 @@ func (s *CardService) FindByDeckID(ctx context.Context, userID int64, deckID int
 
 // Update updates an existing card
 func (s *CardService) Update(ctx context.Context, userID int64, cardEntity *card.Card) error {
-	cardEntity.SetUpdatedAt(time.Now())
-	return s.cardRepo.Update(ctx, userID, cardEntity.GetID(), cardEntity)
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := s.cardRepo.FindByID(txCtx, userID, cardEntity.GetID())
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("card not found")
+		}
+
+		cardEntity.SetUpdatedAt(time.Now())
+		if err := s.cardRepo.Update(txCtx, userID, cardEntity.GetID(), cardEntity); err != nil {
+			return err
+		}
+
+		operationType := undohistory.OperationTypeEditCard
+		if existing.GetDeckID() != cardEntity.GetDeckID() {
+			operationType = undohistory.OperationTypeMoveCard
+		}
+		changes := undohistory.NewChangeSet()
+		changes.AddCard(cardEntity.GetID(), existing.Snapshot(), cardEntity.Snapshot())
+		return s.undoService.Record(txCtx, userID, operationType, changes)
+	})
 }
 
 // Delete deletes a card
 func (s *CardService) Delete(ctx context.Context, userID int64, id int64) error {
-	return s.cardRepo.Delete(ctx, userID, id)
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		c, err := s.cardRepo.FindByID(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return fmt.Errorf("card not found")
+		}
+
+		if err := s.cardRepo.Delete(txCtx, userID, id); err != nil {
+			return err
+		}
+
+		changes := undohistory.NewChangeSet()
+		changes.AddCard(id, c.Snapshot(), nil)
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeDeleteCard, changes)
+	})
 }
 
 // Suspend suspends a card
 func (s *CardService) Suspend(ctx context.Context, userID int64, id int64) error {
-	c, err := s.cardRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if c == nil {
-		return fmt.Errorf("card not found")
-	}
-
-	c.Suspend()
-	return s.cardRepo.Update(ctx, userID, id, c)
+	return s.updateCard(ctx, userID, id, undohistory.OperationTypeEditCard, func(c *card.Card) error {
+		c.Suspend()
+		return nil
+	})
 }
 
 // Unsuspend unsuspends a card
 func (s *CardService) Unsuspend(ctx context.Context, userID int64, id int64) error {
-	c, err := s.cardRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if c == nil {
-		return fmt.Errorf("card not found")
-	}
-
-	c.Unsuspend()
-	return s.cardRepo.Update(ctx, userID, id, c)
+	return s.updateCard(ctx, userID, id, undohistory.OperationTypeEditCard, func(c *card.Card) error {
+		c.Unsuspend()
+		return nil
+	})
 }
 
 // Bury buries a card
 func (s *CardService) Bury(ctx context.Context, userID int64, id int64) error {
-	c, err := s.cardRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if c == nil {
-		return fmt.Errorf("card not found")
-	}
-
-	c.Bury()
-	return s.cardRepo.Update(ctx, userID, id, c)
+	return s.updateCard(ctx, userID, id, undohistory.OperationTypeEditCard, func(c *card.Card) error {
+		c.Bury()
+		return nil
+	})
 }
 
 // Unbury unburies a card
 func (s *CardService) Unbury(ctx context.Context, userID int64, id int64) error {
-	c, err := s.cardRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if c == nil {
-		return fmt.Errorf("card not found")
-	}
-
-	c.Unbury()
-	return s.cardRepo.Update(ctx, userID, id, c)
+	return s.updateCard(ctx, userID, id, undohistory.OperationTypeEditCard, func(c *card.Card) error {
+		c.Unbury()
+		return nil
+	})
 }
 
 // SetFlag sets a colored flag on a card
 func (s *CardService) SetFlag(ctx context.Context, userID int64, id int64, flag int) error {
-	c, err := s.cardRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if c == nil {
-		return fmt.Errorf("card not found")
-	}
+	return s.updateCard(ctx, userID, id, undohistory.OperationTypeChangeFlag, func(c *card.Card) error {
+		return c.SetFlag(flag)
+	})
+}
 
-	if err := c.SetFlag(flag); err != nil {
-		return err
-	}
-	return s.cardRepo.Update(ctx, userID, id, c)
+// updateCard applies a change to a card and records it for undo
+func (s *CardService) updateCard(ctx context.Context, userID int64, id int64, operationType string, change func(*card.Card) error) error {
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		c, err := s.cardRepo.FindByID(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return fmt.Errorf("card not found")
+		}
+
+		before := c.Snapshot()
+		if err := change(c); err != nil {
+			return err
+		}
+		if err := s.cardRepo.Update(txCtx, userID, id, c); err != nil {
+			return err
+		}
+
+		changes := undohistory.NewChangeSet()
+		changes.AddCard(id, before, c.Snapshot())
+		return s.undoService.Record(txCtx, userID, operationType, changes)
+	})
 }
 
 // FindDueCards finds cards that are due for review in a deck
@@ -277,6 +311,7 @@ func (s *CardService) GetInfo(ctx context.Context, userID int64, cardID int64) (
 }
 
 // Reset resets a card (type can be "new" or "forget")
+// The reset is logged as a manual review; undoing it restores the card's scheduling but not the reviews deleted by "forget"
 func (s *CardService) Reset(ctx context.Context, userID int64, id int64, resetType string) error {
 	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
 		c, err := s.cardRepo.FindByID(txCtx, userID, id)
@@ -287,6 +322,7 @@ func (s *CardService) Reset(ctx context.Context, userID int64, id int64, resetTy
 			return fmt.Errorf("card not found")
 		}
 
+		before := c.Snapshot()
 		if resetType == "forget" {
 			c.Forget()
 			if err := s.reviewService.DeleteByCardID(txCtx, userID, id); err != nil {
@@ -296,22 +332,43 @@ func (s *CardService) Reset(ctx context.Context, userID int64, id int64, resetTy
 			c.Reset(true, true)
 		}
 
-		return s.cardRepo.Update(txCtx, userID, id, c)
+		if err := s.cardRepo.Update(txCtx, userID, id, c); err != nil {
+			return err
+		}
+		if err := s.reviewService.LogManual(txCtx, userID, c); err != nil {
+			return fmt.Errorf("failed to log manual review: %w", err)
+		}
+
+		changes := undohistory.NewChangeSet()
+		changes.AddCard(id, before, c.Snapshot())
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeEditCard, changes)
 	})
 }
 
-// SetDueDate manually sets the due date for a card
+// SetDueDate manually sets the due date for a card and logs it as a manual review
 func (s *CardService) SetDueDate(ctx context.Context, userID int64, id int64, due int64) error {
-	c, err := s.cardRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if c == nil {
-		return fmt.Errorf("card not found")
-	}
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		c, err := s.cardRepo.FindByID(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return fmt.Errorf("card not found")
+		}
 
-	c.SetDue(due)
-	return s.cardRepo.Update(ctx, userID, id, c)
+		before := c.Snapshot()
+		c.SetDue(due)
+		if err := s.cardRepo.Update(txCtx, userID, id, c); err != nil {
+			return err
+		}
+		if err := s.reviewService.LogManual(txCtx, userID, c); err != nil {
+			return fmt.Errorf("failed to log manual review: %w", err)
+		}
+
+		changes := undohistory.NewChangeSet()
+		changes.AddCard(id, before, c.Snapshot())
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeEditCard, changes)
+	})
 }
 
 // FindLeeches finds cards that are difficult to memorize (leeches)
@@ -333,10 +390,66 @@ func (s *CardService) Reposition(ctx context.Context, userID int64, cardIDs []in
 	}
 
 	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
-		return s.cardRepo.UpdatePositions(txCtx, userID, cardIDs, start, step, shift)
+		affected, err := s.repositionedCards(txCtx, userID, cardIDs, start, shift)
+		if err != nil {
+			return err
+		}
+
+		if err := s.cardRepo.UpdatePositions(txCtx, userID, cardIDs, start, step, shift); err != nil {
+			return err
+		}
+
+		// Record the cards whose position actually changed
+		changes := undohistory.NewChangeSet()
+		for _, before := range affected {
+			after, err := s.cardRepo.FindByID(txCtx, userID, before.GetID())
+			if err != nil {
+				return err
+			}
+			if after.GetPosition() != before.GetPosition() {
+				changes.AddCard(before.GetID(), before.Snapshot(), after.Snapshot())
+			}
+		}
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeEditCard, changes)
 	})
 }
 
+// repositionedCards returns the cards whose position can change when repositioning:
+// the selected cards and, when shifting, every other card at or after the start position
+// Selected cards that don't belong to the user are skipped, as UpdatePositions ignores them
+func (s *CardService) repositionedCards(ctx context.Context, userID int64, cardIDs []int64, start int, shift bool) ([]*card.Card, error) {
+	cards := make([]*card.Card, 0, len(cardIDs))
+	selected := make(map[int64]bool, len(cardIDs))
+	for _, id := range cardIDs {
+		c, err := s.cardRepo.FindByID(ctx, userID, id)
+		if err != nil {
+			if errors.Is(err, ownership.ErrResourceNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if c == nil || selected[id] {
+			continue
+		}
+		selected[id] = true
+		cards = append(cards, c)
+	}
+
+	if shift {
+		all, _, err := s.cardRepo.FindAll(ctx, userID, card.CardFilters{Limit: 1000000})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range all {
+			if !selected[c.GetID()] && c.GetPosition() >= start {
+				cards = append(cards, c)
+			}
+		}
+	}
+
+	return cards, nil
+}
+
 // GetPosition returns the ordinal position of a card
 func (s *CardService) GetPosition(ctx context.Context, userID int64, cardID int64) (int, error) {
 	c, err := s.cardRepo.FindByID(ctx, userID, cardID)
@@ -411,7 +524,7 @@ func (s *CardService) CleanupEmptyCards(ctx context.Context, userID int64) (int,
 		// We use a transaction for each delete to ensure consistency, 
 		// but since they are independent, we don't wrap the whole loop in one tx 
 		// to avoid long-running locks.
-		if err := s.cardRepo.Delete(ctx, userID, c.GetID()); err == nil {
+		if err := s.Delete(ctx, userID, c.GetID()); err == nil {
 			count++
 		}
 	}
@@ -7,7 +7,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 )
@@ -24,6 +26,7 @@ type DeckService struct {
 	deckRepo   secondary.IDeckRepository
 	cardRepo   secondary.ICardRepository
 	backupSvc  primary.IBackupService
+	undoSvc    primary.IUndoHistoryService
 	tm         secondary.ITransactionManager
 }
 
@@ -32,12 +35,14 @@ func NewDeckService(
 	deckRepo secondary.IDeckRepository,
 	cardRepo secondary.ICardRepository,
 	backupSvc primary.IBackupService,
+	undoSvc primary.IUndoHistoryService,
 	tm secondary.ITransactionManager,
 ) primary.IDeckService {
 	return &DeckService{
 		deckRepo:   deckRepo,
 		cardRepo:   cardRepo,
 		backupSvc:  backupSvc,
+		undoSvc:    undoSvc,
 		tm:         tm,
 	}
 }
@@ -93,9 +98,18 @@ func (s *DeckService) Create(ctx context.Context, userID int64, name string, par
 		return nil, fmt.Errorf("failed to build deck entity: %w", err)
 	}
 
-	// 4. Save to repository
-	if err := s.deckRepo.Save(ctx, userID, deckEntity); err != nil {
-		return nil, fmt.Errorf("failed to save deck: %w", err)
+	// 4. Save to repository and record for undo
+	err = s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.deckRepo.Save(txCtx, userID, deckEntity); err != nil {
+			return fmt.Errorf("failed to save deck: %w", err)
+		}
+
+		changes := undohistory.NewChangeSet()
+		changes.AddDeck(deckEntity.GetID(), nil, deckEntity.Snapshot())
+		return s.undoSvc.Record(txCtx, userID, undohistory.OperationTypeAddDeck, changes)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return deckEntity, nil
@@ -173,6 +187,7 @@ func (s *DeckService) Update(ctx context.Context, userID int64, id int64, name s
 	}
 
 	// 4. Update entity
+	before := existing.Snapshot()
 	existing.SetName(name)
 	existing.SetParentID(parentID)
 	if optionsJSON != "" {
@@ -181,7 +196,7 @@ func (s *DeckService) Update(ctx context.Context, userID int64, id int64, name s
 	existing.SetUpdatedAt(time.Now())
 
 	// 5. Save
-	if err := s.deckRepo.Update(ctx, userID, id, existing); err != nil {
+	if err := s.saveEdit(ctx, userID, existing, before); err != nil {
 		return nil, err
 	}
 
@@ -203,17 +218,31 @@ func (s *DeckService) UpdateOptions(ctx context.Context, userID int64, id int64,
 	if optionsJSON == "" {
 		optionsJSON = "{}"
 	}
+	before := existing.Snapshot()
 	existing.SetOptionsJSON(optionsJSON)
 	existing.SetUpdatedAt(time.Now())
 
 	// 3. Save
-	if err := s.deckRepo.Update(ctx, userID, id, existing); err != nil {
+	if err := s.saveEdit(ctx, userID, existing, before); err != nil {
 		return nil, err
 	}
 
 	return existing, nil
 }
 
+// saveEdit saves an edited deck and records its previous state for undo
+func (s *DeckService) saveEdit(ctx context.Context, userID int64, d *deck.Deck, before *deck.Snapshot) error {
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.deckRepo.Update(txCtx, userID, d.GetID(), d); err != nil {
+			return err
+		}
+
+		changes := undohistory.NewChangeSet()
+		changes.AddDeck(d.GetID(), before, d.Snapshot())
+		return s.undoSvc.Record(txCtx, userID, undohistory.OperationTypeEditDeck, changes)
+	})
+}
+
 // Delete deletes a deck (soft delete) with a strategy for handling cards
 func (s *DeckService) Delete(ctx context.Context, userID int64, id int64, action deck.DeleteAction, targetDeckID *int64) error {
 	// 1. Find deck (validates ownership)
@@ -274,6 +303,20 @@ func (s *DeckService) Delete(ctx context.Context, userID int64, id int64, action
 
 	// 4. Perform operation in transaction
 	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		// Capture the deck tree and its cards for undo
+		tree, err := s.deckTree(txCtx, userID, existing)
+		if err != nil {
+			return err
+		}
+		var cards []*card.Card
+		for _, d := range tree {
+			deckCards, err := s.cardRepo.FindByDeckID(txCtx, userID, d.GetID())
+			if err != nil {
+				return err
+			}
+			cards = append(cards, deckCards...)
+		}
+
 		// A. Execute card action
 		switch action {
 		case deck.ActionDeleteCards:
@@ -287,10 +330,43 @@ func (s *DeckService) Delete(ctx context.Context, userID int64, id int64, action
 		}
 
 		// B. Perform soft delete of the deck tree
-		return s.deckRepo.Delete(txCtx, userID, id)
+		if err := s.deckRepo.Delete(txCtx, userID, id); err != nil {
+			return err
+		}
+
+		// C. Record the cards, then the decks, as they were changed
+		changes := undohistory.NewChangeSet()
+		for _, c := range cards {
+			if action == deck.ActionDeleteCards {
+				changes.AddCard(c.GetID(), c.Snapshot(), nil)
+				continue
+			}
+			moved, err := s.cardRepo.FindByID(txCtx, userID, c.GetID())
+			if err != nil {
+				return err
+			}
+			changes.AddCard(c.GetID(), c.Snapshot(), moved.Snapshot())
+		}
+		for _, d := range tree {
+			changes.AddDeck(d.GetID(), d.Snapshot(), nil)
+		}
+		return s.undoSvc.Record(txCtx, userID, undohistory.OperationTypeDeleteDeck, changes)
 	})
 }
 
+// deckTree returns a deck followed by all its descendants, parents before children
+func (s *DeckService) deckTree(ctx context.Context, userID int64, root *deck.Deck) ([]*deck.Deck, error) {
+	tree := []*deck.Deck{root}
+	for i := 0; i < len(tree); i++ {
+		children, err := s.deckRepo.FindByParentID(ctx, userID, tree[i].GetID())
+		if err != nil {
+			return nil, err
+		}
+		tree = append(tree, children...)
+	}
+	return tree, nil
+}
+
 // CreateDefaultDeck creates the initial "Default" deck for a user
 func (s *DeckService) CreateDefaultDeck(ctx context.Context, userID int64) (*deck.Deck, error) {
 	deckID, err := s.deckRepo.CreateDefaultDeck(ctx, userID)
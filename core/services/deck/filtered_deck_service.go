@@ -2,34 +2,57 @@ package deck
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	searchdomain "github.com/felipesantos/anki-backend/core/domain/services/search"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 )
 
+var (
+	// ErrFilteredDeckNotFound is returned when a filtered deck cannot be found
+	ErrFilteredDeckNotFound = errors.New("filtered deck not found")
+	// ErrInvalidFilteredDeckSearch is returned when a filtered deck's search cannot be parsed
+	ErrInvalidFilteredDeckSearch = errors.New("invalid filtered deck search")
+)
+
 // FilteredDeckService implements IFilteredDeckService
 type FilteredDeckService struct {
-	repo secondary.IFilteredDeckRepository
+	repo     secondary.IFilteredDeckRepository
+	cardRepo secondary.ICardRepository
+	tm       secondary.ITransactionManager
+	parser   *searchdomain.Parser
 }
 
 // NewFilteredDeckService creates a new FilteredDeckService instance
-func NewFilteredDeckService(repo secondary.IFilteredDeckRepository) primary.IFilteredDeckService {
+func NewFilteredDeckService(
+	repo secondary.IFilteredDeckRepository,
+	cardRepo secondary.ICardRepository,
+	tm secondary.ITransactionManager,
+) primary.IFilteredDeckService {
 	return &FilteredDeckService{
-		repo: repo,
+		repo:     repo,
+		cardRepo: cardRepo,
+		tm:       tm,
+		parser:   searchdomain.NewParser(),
 	}
 }
 
-// Create creates a new filtered deck
-func (s *FilteredDeckService) Create(ctx context.Context, userID int64, name string, searchFilter string, limit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
+// Create creates a new filtered deck and builds it
+// A secondLimit of zero gives the second search the same limit as the first
+func (s *FilteredDeckService) Create(ctx context.Context, userID int64, name string, searchFilter string, secondFilter *string, limit int, secondLimit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
 	now := time.Now()
 	fd, err := filtereddeck.NewBuilder().
 		WithUserID(userID).
 		WithName(name).
 		WithSearchFilter(searchFilter).
+		WithSecondFilter(secondFilter).
 		WithLimitCards(limit).
+		WithSecondLimitCards(secondLimit).
 		WithOrderBy(orderBy).
 		WithReschedule(reschedule).
 		WithCreatedAt(now).
@@ -39,7 +62,14 @@ func (s *FilteredDeckService) Create(ctx context.Context, userID int64, name str
 		return nil, err
 	}
 
-	if err := s.repo.Save(ctx, userID, fd); err != nil {
+	err = s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.Save(txCtx, userID, fd); err != nil {
+			return err
+		}
+		_, err := s.build(txCtx, userID, fd)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -51,32 +81,133 @@ func (s *FilteredDeckService) FindByUserID(ctx context.Context, userID int64) ([
 	return s.repo.FindByUserID(ctx, userID)
 }
 
-// Update updates an existing filtered deck
-func (s *FilteredDeckService) Update(ctx context.Context, userID int64, id int64, name string, searchFilter string, limit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
-	existing, err := s.repo.FindByID(ctx, userID, id)
+// Update updates an existing filtered deck and rebuilds it with the new options
+// A secondLimit of zero gives the second search the same limit as the first
+func (s *FilteredDeckService) Update(ctx context.Context, userID int64, id int64, name string, searchFilter string, secondFilter *string, limit int, secondLimit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
+	var existing *filtereddeck.FilteredDeck
+	err := s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		if existing, err = s.find(txCtx, userID, id); err != nil {
+			return err
+		}
+
+		existing.SetName(name)
+		existing.SetSearchFilter(searchFilter)
+		existing.SetSecondFilter(secondFilter)
+		existing.SetLimitCards(limit)
+		existing.SetSecondLimitCards(secondLimit)
+		existing.SetOrderBy(orderBy)
+		existing.SetReschedule(reschedule)
+		existing.SetUpdatedAt(time.Now())
+
+		if err := s.repo.Update(txCtx, userID, id, existing); err != nil {
+			return err
+		}
+		_, err = s.build(txCtx, userID, existing)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if existing == nil {
-		return nil, fmt.Errorf("filtered deck not found")
+
+	return existing, nil
+}
+
+// Delete returns the cards of a filtered deck to their home decks and deletes it
+func (s *FilteredDeckService) Delete(ctx context.Context, userID int64, id int64) error {
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := s.repo.ReturnCards(txCtx, userID, id); err != nil {
+			return err
+		}
+		return s.repo.Delete(txCtx, userID, id)
+	})
+}
+
+// Rebuild empties a filtered deck and gathers the cards matching its searches again
+// Returns the number of cards in the deck
+func (s *FilteredDeckService) Rebuild(ctx context.Context, userID int64, id int64) (int, error) {
+	var count int
+	err := s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		fd, err := s.find(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		count, err = s.build(txCtx, userID, fd)
+		return err
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	existing.SetName(name)
-	existing.SetSearchFilter(searchFilter)
-	existing.SetLimitCards(limit)
-	existing.SetOrderBy(orderBy)
-	existing.SetReschedule(reschedule)
-	existing.SetUpdatedAt(time.Now())
+	return count, nil
+}
 
-	if err := s.repo.Update(ctx, userID, id, existing); err != nil {
-		return nil, err
+// Empty returns the cards of a filtered deck to their home decks and returns the number of cards returned
+func (s *FilteredDeckService) Empty(ctx context.Context, userID int64, id int64) (int, error) {
+	if _, err := s.find(ctx, userID, id); err != nil {
+		return 0, err
 	}
+	return s.repo.ReturnCards(ctx, userID, id)
+}
 
-	return existing, nil
+// FindCards finds the cards in a filtered deck, in the deck's order
+func (s *FilteredDeckService) FindCards(ctx context.Context, userID int64, id int64) ([]*card.Card, error) {
+	if _, err := s.find(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.cardRepo.FindByFilteredDeckID(ctx, userID, id)
 }
 
-// Delete deletes a filtered deck
-func (s *FilteredDeckService) Delete(ctx context.Context, userID int64, id int64) error {
-	return s.repo.Delete(ctx, userID, id)
+// build returns the cards of a filtered deck home, then moves in the cards matching its search and
+// its second search, each up to its own limit and in the deck's order
+// The rebuild time is recorded on the deck. Returns the number of cards moved in
+func (s *FilteredDeckService) build(ctx context.Context, userID int64, fd *filtereddeck.FilteredDeck) (int, error) {
+	filters := []string{fd.GetSearchFilter()}
+	limits := []int{fd.GetLimitCards()}
+	if second := fd.GetSecondFilter(); second != nil && *second != "" {
+		filters = append(filters, *second)
+		limits = append(limits, fd.GetSecondLimitCards())
+	}
+
+	queries := make([]*searchdomain.SearchQuery, len(filters))
+	for i, filter := range filters {
+		query, err := s.parser.Parse(filter)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidFilteredDeckSearch, err)
+		}
+		queries[i] = query
+	}
+
+	if _, err := s.repo.ReturnCards(ctx, userID, fd.GetID()); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i, query := range queries {
+		moved, err := s.repo.MoveCardsIn(ctx, userID, fd.GetID(), query, fd.GetOrderBy(), limits[i])
+		if err != nil {
+			return 0, err
+		}
+		count += moved
+	}
+
+	now := time.Now()
+	fd.SetLastRebuildAt(&now)
+	if err := s.repo.Update(ctx, userID, fd.GetID(), fd); err != nil {
+		return 0, err
+	}
+
+	return count, nil
 }
 
+// find finds a filtered deck, returning ErrFilteredDeckNotFound if it does not exist
+func (s *FilteredDeckService) find(ctx context.Context, userID int64, id int64) (*filtereddeck.FilteredDeck, error) {
+	fd, err := s.repo.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if fd == nil {
+		return nil, ErrFilteredDeckNotFound
+	}
+	return fd, nil
+}
@@ -0,0 +1,129 @@
+package deck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// ErrFSRSDecksRequired is returned when FSRS parameters are fitted without the decks using the preset
+// Presets are not linked to decks, so the decks whose reviews are used must be given explicitly
+var ErrFSRSDecksRequired = errors.New("deck_ids are required")
+
+// FSRSOptimizerService implements IFSRSOptimizerService
+type FSRSOptimizerService struct {
+	reviewRepo secondary.IReviewRepository
+	presetRepo secondary.IDeckOptionsPresetRepository
+	deckRepo   secondary.IDeckRepository
+	tm         secondary.ITransactionManager
+}
+
+// NewFSRSOptimizerService creates a new FSRSOptimizerService instance
+func NewFSRSOptimizerService(
+	reviewRepo secondary.IReviewRepository,
+	presetRepo secondary.IDeckOptionsPresetRepository,
+	deckRepo secondary.IDeckRepository,
+	tm secondary.ITransactionManager,
+) primary.IFSRSOptimizerService {
+	return &FSRSOptimizerService{
+		reviewRepo: reviewRepo,
+		presetRepo: presetRepo,
+		deckRepo:   deckRepo,
+		tm:         tm,
+	}
+}
+
+// Optimize fits FSRS parameters to the review history of the decks and saves them to the preset and the decks
+func (s *FSRSOptimizerService) Optimize(ctx context.Context, userID int64, presetID int64, deckIDs []int64, progress func(percent int)) (*scheduler.FSRSOptimizationResult, error) {
+	result, err := s.fit(ctx, userID, presetID, deckIDs, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tm.WithTransaction(ctx, func(ctx context.Context) error {
+		// Reload the preset inside the transaction so concurrent option edits are not lost
+		preset, err := s.presetRepo.FindByID(ctx, userID, presetID)
+		if err != nil {
+			return err
+		}
+		if preset == nil {
+			return fmt.Errorf("preset not found")
+		}
+
+		optionsJSON, err := deck.SetFSRSWeights(preset.GetOptionsJSON(), result.Weights)
+		if err != nil {
+			return err
+		}
+		preset.SetOptionsJSON(optionsJSON)
+		preset.SetUpdatedAt(time.Now())
+		if err := s.presetRepo.Update(ctx, userID, presetID, preset); err != nil {
+			return err
+		}
+
+		for _, deckID := range deckIDs {
+			d, err := s.deckRepo.FindByID(ctx, userID, deckID)
+			if err != nil {
+				return err
+			}
+			if d == nil {
+				return fmt.Errorf("deck %d not found", deckID)
+			}
+
+			optionsJSON, err := deck.SetFSRSWeights(d.GetOptionsJSON(), result.Weights)
+			if err != nil {
+				return fmt.Errorf("failed to update deck %d: %w", deckID, err)
+			}
+			d.SetOptionsJSON(optionsJSON)
+			d.SetUpdatedAt(time.Now())
+			if err := s.deckRepo.Update(ctx, userID, deckID, d); err != nil {
+				return fmt.Errorf("failed to update deck %d: %w", deckID, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Evaluate fits FSRS parameters without saving them and returns log loss/RMSE before and after
+func (s *FSRSOptimizerService) Evaluate(ctx context.Context, userID int64, presetID int64, deckIDs []int64) (*scheduler.FSRSOptimizationResult, error) {
+	return s.fit(ctx, userID, presetID, deckIDs, nil)
+}
+
+// fit loads the preset and the review history of the decks and runs the optimizer starting from the preset's parameters
+func (s *FSRSOptimizerService) fit(ctx context.Context, userID int64, presetID int64, deckIDs []int64, progress func(percent int)) (*scheduler.FSRSOptimizationResult, error) {
+	if len(deckIDs) == 0 {
+		return nil, ErrFSRSDecksRequired
+	}
+
+	preset, err := s.presetRepo.FindByID(ctx, userID, presetID)
+	if err != nil {
+		return nil, err
+	}
+	if preset == nil {
+		return nil, fmt.Errorf("preset not found")
+	}
+
+	options, err := deck.ParseDeckOptions(preset.GetOptionsJSON())
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := s.reviewRepo.FindHistory(ctx, userID, deckIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	histories := scheduler.BuildFSRSHistories(reviews)
+	return scheduler.NewFSRSOptimizer().Optimize(ctx, histories, options.FSRSWeights, progress)
+}
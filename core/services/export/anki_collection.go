@@ -0,0 +1,793 @@
+package export
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver for Anki collection databases
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deckoptionspreset "github.com/felipesantos/anki-backend/core/domain/entities/deck_options_preset"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	userpreferences "github.com/felipesantos/anki-backend/core/domain/entities/user_preferences"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+)
+
+// AnkiSchema creates the tables of a schema 11 collection, the legacy format every Anki version imports
+const AnkiSchema = `
+CREATE TABLE col (
+	id integer PRIMARY KEY, crt integer NOT NULL, mod integer NOT NULL, scm integer NOT NULL,
+	ver integer NOT NULL, dty integer NOT NULL, usn integer NOT NULL, ls integer NOT NULL,
+	conf text NOT NULL, models text NOT NULL, decks text NOT NULL, dconf text NOT NULL, tags text NOT NULL
+);
+CREATE TABLE notes (
+	id integer PRIMARY KEY, guid text NOT NULL, mid integer NOT NULL, mod integer NOT NULL, usn integer NOT NULL,
+	tags text NOT NULL, flds text NOT NULL, sfld integer NOT NULL, csum integer NOT NULL, flags integer NOT NULL, data text NOT NULL
+);
+CREATE TABLE cards (
+	id integer PRIMARY KEY, nid integer NOT NULL, did integer NOT NULL, ord integer NOT NULL, mod integer NOT NULL,
+	usn integer NOT NULL, type integer NOT NULL, queue integer NOT NULL, due integer NOT NULL, ivl integer NOT NULL,
+	factor integer NOT NULL, reps integer NOT NULL, lapses integer NOT NULL, left integer NOT NULL, odue integer NOT NULL,
+	odid integer NOT NULL, flags integer NOT NULL, data text NOT NULL
+);
+CREATE TABLE revlog (
+	id integer PRIMARY KEY, cid integer NOT NULL, usn integer NOT NULL, ease integer NOT NULL, ivl integer NOT NULL,
+	lastIvl integer NOT NULL, factor integer NOT NULL, time integer NOT NULL, type integer NOT NULL
+);
+CREATE TABLE graves (usn integer NOT NULL, oid integer NOT NULL, type integer NOT NULL);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// Card types, queues and review log types of an Anki collection
+const (
+	AnkiCardNew     = 0
+	AnkiCardLearn   = 1
+	AnkiCardReview  = 2
+	AnkiCardRelearn = 3
+
+	AnkiQueueSuspended = -1
+	AnkiQueueBuried    = -2
+	AnkiQueueNew       = 0
+	AnkiQueueLearn     = 1
+	AnkiQueueReview    = 2
+	AnkiQueueDayLearn  = 3
+
+	AnkiRevlogLearn   = 0
+	AnkiRevlogReview  = 1
+	AnkiRevlogRelearn = 2
+	AnkiRevlogCram    = 3
+	AnkiRevlogManual  = 4
+
+	// AnkiDefaultDeckID is the ID of the Default deck and the Default deck options of every collection
+	AnkiDefaultDeckID = 1
+)
+
+const (
+	ankiDefaultCSS       = ".card {\n font-family: arial;\n font-size: 20px;\n text-align: center;\n color: black;\n background-color: white;\n}\n"
+	ankiDefaultLatexPre  = "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n"
+	ankiDefaultLatexPost = "\\end{document}"
+)
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// createAnkiDatabase writes the content to a schema 11 Anki collection and returns the database file
+func createAnkiDatabase(ctx context.Context, content *APKGContent, includeScheduling bool) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "anki-export-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary database: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	db, err := sql.Open("sqlite", tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+
+	col := newAnkiCollection(content, includeScheduling, time.Now())
+	if err := col.write(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := db.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close collection: %w", err)
+	}
+	return os.ReadFile(tmp.Name())
+}
+
+// ankiCollection converts the exported entities into the rows of an Anki collection
+// Anki IDs are creation timestamps in milliseconds, so every entity gets a new ID derived from its creation time
+type ankiCollection struct {
+	content           *APKGContent
+	includeScheduling bool
+	now               time.Time
+	crt               int64 // Collection creation in seconds, at a study day rollover; day numbers count from it
+	nextDayStart      int64 // Start of the next study day in seconds
+	nextPos           int   // Next new card position
+
+	noteTypes   map[int64]*notetype.NoteType
+	modelIDs    map[int64]int64 // Note type ID -> Anki model ID
+	noteIDs     map[int64]int64 // Note ID -> Anki note ID
+	cardIDs     map[int64]int64 // Card ID -> Anki card ID
+	deckIDs     map[int64]int64 // Deck ID -> Anki deck ID
+	deckConfIDs map[int64]int64 // Deck ID -> Anki deck options ID
+	deckOptions map[int64]*deck.DeckOptions
+	lastReviews map[int64]*review.Review // Card ID -> latest review
+}
+
+// newAnkiCollection prepares the collection of the content
+func newAnkiCollection(content *APKGContent, includeScheduling bool, now time.Time) *ankiCollection {
+	col := &ankiCollection{
+		content:           content,
+		includeScheduling: includeScheduling,
+		now:               now,
+		nextPos:           1,
+		noteTypes:         make(map[int64]*notetype.NoteType),
+		modelIDs:          make(map[int64]int64),
+		noteIDs:           make(map[int64]int64),
+		cardIDs:           make(map[int64]int64),
+		deckIDs:           make(map[int64]int64),
+		deckConfIDs:       make(map[int64]int64),
+		deckOptions:       make(map[int64]*deck.DeckOptions),
+		lastReviews:       make(map[int64]*review.Review),
+	}
+
+	earliest := now
+	for _, n := range content.Notes {
+		if n.GetCreatedAt().Before(earliest) {
+			earliest = n.GetCreatedAt()
+		}
+	}
+	for _, c := range content.Cards {
+		if c.GetCreatedAt().Before(earliest) {
+			earliest = c.GetCreatedAt()
+		}
+		if c.IsNew() && c.GetPosition() >= col.nextPos {
+			col.nextPos = c.GetPosition() + 1
+		}
+	}
+	hour := userpreferences.DefaultNextDayStartsAtHour
+	col.crt = userpreferences.StudyDayStart(earliest.UTC(), hour, 0, 0).Unix()
+	col.nextDayStart = userpreferences.StudyDayStart(now.UTC(), hour, 0, 0).AddDate(0, 0, 1).Unix()
+
+	for _, nt := range content.NoteTypes {
+		if nt != nil {
+			col.noteTypes[nt.GetID()] = nt
+		}
+	}
+	for _, r := range content.Reviews {
+		if last := col.lastReviews[r.GetCardID()]; last == nil || !r.GetCreatedAt().Before(last.GetCreatedAt()) {
+			col.lastReviews[r.GetCardID()] = r
+		}
+	}
+	return col
+}
+
+// write creates the schema and inserts the collection in one transaction
+func (col *ankiCollection) write(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin collection transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, AnkiSchema); err != nil {
+		return fmt.Errorf("failed to create collection schema: %w", err)
+	}
+
+	dconf := col.deckConfigs()
+	decks := col.decks()
+	models := col.models()
+	if err := col.writeNotes(ctx, tx); err != nil {
+		return err
+	}
+	if err := col.writeCards(ctx, tx); err != nil {
+		return err
+	}
+	if col.includeScheduling {
+		if err := col.writeRevlog(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if err := col.writeCol(ctx, tx, models, decks, dconf); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit collection: %w", err)
+	}
+	return nil
+}
+
+// deckConfigs returns the dconf JSON objects and assigns each deck its options
+// Decks whose options equal a preset share a config named after the preset; the remaining distinct
+// options get a config named after the first deck using them
+func (col *ankiCollection) deckConfigs() map[string]interface{} {
+	defaults := deck.DefaultDeckOptions()
+	confs := map[string]interface{}{
+		strconv.Itoa(AnkiDefaultDeckID): AnkiDeckConfig(AnkiDefaultDeckID, "Default", defaults, col.now),
+	}
+	confIDs := map[string]int64{OptionsKey(defaults): AnkiDefaultDeckID}
+	ids := idAllocator{AnkiDefaultDeckID: true}
+
+	presets := make(map[string]*deckoptionspreset.DeckOptionsPreset)
+	for _, p := range col.content.Presets {
+		opts, err := deck.ParseDeckOptions(p.GetOptionsJSON())
+		if err != nil {
+			continue
+		}
+		if key := OptionsKey(opts); presets[key] == nil {
+			presets[key] = p
+		}
+	}
+
+	for _, d := range col.content.Decks {
+		opts, err := d.GetOptions()
+		if err != nil {
+			opts = deck.DefaultDeckOptions()
+		}
+		col.deckOptions[d.GetID()] = opts
+
+		key := OptionsKey(opts)
+		id, ok := confIDs[key]
+		if !ok {
+			name, createdAt, updatedAt := d.GetFullPath(col.content.Decks), d.GetCreatedAt(), d.GetUpdatedAt()
+			if p := presets[key]; p != nil {
+				name, createdAt, updatedAt = p.GetName(), p.GetCreatedAt(), p.GetUpdatedAt()
+			}
+			id = ids.next(createdAt)
+			confIDs[key] = id
+			confs[strconv.FormatInt(id, 10)] = AnkiDeckConfig(id, name, opts, updatedAt)
+		}
+		col.deckConfIDs[d.GetID()] = id
+	}
+	return confs
+}
+
+// decks returns the decks JSON objects; a root deck named Default becomes the collection's Default deck
+func (col *ankiCollection) decks() map[string]interface{} {
+	decks := map[string]interface{}{
+		strconv.Itoa(AnkiDefaultDeckID): AnkiDeck(AnkiDefaultDeckID, "Default", AnkiDefaultDeckID, col.now),
+	}
+	ids := idAllocator{AnkiDefaultDeckID: true}
+
+	for _, d := range col.content.Decks {
+		name := d.GetFullPath(col.content.Decks)
+		id := int64(AnkiDefaultDeckID)
+		if !strings.EqualFold(name, "Default") {
+			id = ids.next(d.GetCreatedAt())
+		}
+		col.deckIDs[d.GetID()] = id
+		decks[strconv.FormatInt(id, 10)] = AnkiDeck(id, name, col.deckConfIDs[d.GetID()], d.GetUpdatedAt())
+	}
+	return decks
+}
+
+// models returns the models JSON objects of the note types
+func (col *ankiCollection) models() map[string]interface{} {
+	models := make(map[string]interface{})
+	ids := idAllocator{}
+	for _, nt := range col.content.NoteTypes {
+		if nt == nil {
+			continue
+		}
+		id := ids.next(nt.GetCreatedAt())
+		col.modelIDs[nt.GetID()] = id
+		models[strconv.FormatInt(id, 10)] = AnkiModel(id, nt)
+	}
+	return models
+}
+
+// writeNotes inserts the notes with their fields in the note type's field order
+func (col *ankiCollection) writeNotes(ctx context.Context, tx *sql.Tx) error {
+	ids := idAllocator{}
+	for _, n := range col.content.Notes {
+		nt := col.noteTypes[n.GetNoteTypeID()]
+		if nt == nil {
+			continue
+		}
+		id := ids.next(n.GetCreatedAt())
+		col.noteIDs[n.GetID()] = id
+
+		values := NoteFieldValues(n, nt)
+		sortField := ""
+		if len(values) > 0 {
+			sortField = StripHTML(values[0])
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data) VALUES (?, ?, ?, ?, 0, ?, ?, ?, ?, 0, '')",
+			id, n.GetGUID().Value(), col.modelIDs[nt.GetID()], n.GetUpdatedAt().Unix(), AnkiTags(n.GetTags()),
+			strings.Join(values, "\x1f"), sortField, FieldChecksum(sortField),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write note: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeCards inserts the cards of the exported notes into their home decks
+func (col *ankiCollection) writeCards(ctx context.Context, tx *sql.Tx) error {
+	ids := idAllocator{}
+	for _, c := range col.content.Cards {
+		noteID, ok := col.noteIDs[c.GetNoteID()]
+		if !ok {
+			continue
+		}
+		id := ids.next(c.GetCreatedAt())
+		col.cardIDs[c.GetID()] = id
+
+		deckID := int64(AnkiDefaultDeckID)
+		if ankiDeckID, ok := col.deckIDs[col.homeDeckID(c)]; ok {
+			deckID = ankiDeckID
+		}
+		s := col.schedule(c)
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0, ?, ?)`,
+			id, noteID, deckID, c.GetCardTypeID(), c.GetUpdatedAt().Unix(),
+			s.Type, s.Queue, s.Due, s.Interval, s.Factor, s.Reps, s.Lapses, s.Left, s.Flags, s.Data,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write card: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeRevlog inserts the review history of the exported cards
+// lastIvl is the interval of the card's previous review, as Anki records it
+func (col *ankiCollection) writeRevlog(ctx context.Context, tx *sql.Tx) error {
+	reviews := append([]*review.Review(nil), col.content.Reviews...)
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].GetCreatedAt().Before(reviews[j].GetCreatedAt())
+	})
+
+	ids := idAllocator{}
+	lastIntervals := make(map[int64]int)
+	for _, r := range reviews {
+		cardID, ok := col.cardIDs[r.GetCardID()]
+		if !ok {
+			continue
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO revlog (id, cid, usn, ease, ivl, lastIvl, factor, time, type) VALUES (?, ?, 0, ?, ?, ?, ?, ?, ?)",
+			ids.next(r.GetCreatedAt()), cardID, r.GetRating(), r.GetInterval(), lastIntervals[r.GetCardID()],
+			r.GetEase(), r.GetTimeMs(), RevlogType(r.GetType()),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write review log: %w", err)
+		}
+		lastIntervals[r.GetCardID()] = r.GetInterval()
+	}
+	return nil
+}
+
+// writeCol inserts the collection row holding the configuration, note types, decks and deck options
+func (col *ankiCollection) writeCol(ctx context.Context, tx *sql.Tx, models, decks, dconf map[string]interface{}) error {
+	var curModel interface{}
+	fsrs := false
+	for _, nt := range col.content.NoteTypes {
+		if nt != nil && curModel == nil {
+			curModel = col.modelIDs[nt.GetID()]
+		}
+	}
+	for _, opts := range col.deckOptions {
+		fsrs = fsrs || opts.SchedulerType == valueobjects.SchedulerTypeFSRS
+	}
+	conf := map[string]interface{}{
+		"nextPos":       col.nextPos,
+		"schedVer":      2,
+		"sched2021":     true,
+		"rollover":      userpreferences.DefaultNextDayStartsAtHour,
+		"fsrs":          fsrs,
+		"curDeck":       AnkiDefaultDeckID,
+		"activeDecks":   []int{AnkiDefaultDeckID},
+		"curModel":      curModel,
+		"sortType":      "noteFld",
+		"sortBackwards": false,
+		"addToCur":      true,
+		"newSpread":     0,
+		"collapseTime":  1200,
+		"timeLim":       0,
+		"estTimes":      true,
+		"dueCounts":     true,
+	}
+
+	values := make([]interface{}, 0, 5)
+	for _, v := range []interface{}{conf, models, decks, dconf, map[string]interface{}{}} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal collection: %w", err)
+		}
+		values = append(values, string(data))
+	}
+
+	mod := col.now.UnixMilli()
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags) VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, ?)",
+		append([]interface{}{col.crt, mod, mod}, values...)...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write collection: %w", err)
+	}
+	return nil
+}
+
+// AnkiSchedule is the scheduling state of a card in Anki's encoding
+type AnkiSchedule struct {
+	Type     int
+	Queue    int
+	Due      int64
+	Interval int
+	Factor   int
+	Reps     int
+	Lapses   int
+	Left     int
+	Flags    int
+	Data     string
+}
+
+// schedule converts a card's scheduling state; without scheduling every card is reset to a new card
+func (col *ankiCollection) schedule(c *card.Card) AnkiSchedule {
+	if !col.includeScheduling || c.IsNew() {
+		return AnkiSchedule{Type: AnkiCardNew, Queue: AnkiQueueNew, Due: col.position(c), Flags: col.flags(c)}
+	}
+	return ScheduleCard(c, col.deckOptions[col.homeDeckID(c)], col.lastReviews[c.GetID()], col.crt, col.nextDayStart)
+}
+
+// flags returns the card's flag when scheduling is exported
+func (col *ankiCollection) flags(c *card.Card) int {
+	if !col.includeScheduling {
+		return 0
+	}
+	return c.GetFlag()
+}
+
+// position returns the queue position of a new card, giving reset cards positions after the existing ones
+func (col *ankiCollection) position(c *card.Card) int64 {
+	if c.IsNew() && c.GetPosition() > 0 {
+		return int64(c.GetPosition())
+	}
+	pos := col.nextPos
+	col.nextPos++
+	return int64(pos)
+}
+
+// ScheduleCard converts a card's scheduling state
+// New cards are due at their position, learning cards at a timestamp in seconds (or a day number once
+// due on a later day) and review cards at a day number counted from the collection creation (crt).
+// opts are the options of the card's home deck and lastReview its latest review, nil when unknown
+func ScheduleCard(c *card.Card, opts *deck.DeckOptions, lastReview *review.Review, crt, nextDayStart int64) AnkiSchedule {
+	s := AnkiSchedule{Type: AnkiCardNew, Queue: AnkiQueueNew, Flags: c.GetFlag()}
+	switch c.GetState() {
+	case valueobjects.CardStateNew:
+		s.Due = int64(max(c.GetPosition(), 0))
+	case valueobjects.CardStateLearn, valueobjects.CardStateRelearn:
+		s.Type, s.Queue = AnkiCardLearn, AnkiQueueLearn
+		if c.IsRelearning() {
+			s.Type = AnkiCardRelearn
+		}
+		s.Due = c.GetDue() / 1000
+		if s.Due >= nextDayStart {
+			s.Queue, s.Due = AnkiQueueDayLearn, dayNumber(s.Due, crt)
+		}
+		s.Left = remainingSteps(c, opts, lastReview)
+	default:
+		s.Type, s.Queue = AnkiCardReview, AnkiQueueReview
+		s.Due = dayNumber(c.GetDue()/1000, crt)
+	}
+	if !c.IsNew() {
+		s.Interval = max(c.GetInterval(), 0)
+		s.Factor = c.GetEase()
+		s.Reps = c.GetReps()
+		s.Lapses = c.GetLapses()
+		s.Data = CardData(c)
+	}
+
+	if c.GetSuspended() {
+		s.Queue = AnkiQueueSuspended
+	} else if c.GetBuried() {
+		s.Queue = AnkiQueueBuried
+	}
+	return s
+}
+
+// remainingSteps returns a learning card's left value: the steps still to go, recovered from the delay
+// of its latest review. Legacy collections store the steps left today in the thousands
+func remainingSteps(c *card.Card, opts *deck.DeckOptions, lastReview *review.Review) int {
+	if opts == nil {
+		opts = deck.DefaultDeckOptions()
+	}
+	steps := opts.NewSteps
+	if c.IsRelearning() {
+		steps = opts.RelearnSteps
+	}
+	if len(steps) == 0 {
+		return 1001
+	}
+
+	step := 0
+	if lastReview != nil && lastReview.GetInterval() < 0 {
+		step = scheduler.StepIndexForDelay(steps, -lastReview.GetInterval())
+	}
+	remaining := len(steps) - step
+	return remaining*1000 + remaining
+}
+
+// homeDeckID returns the deck a card belongs to outside filtered decks
+func (col *ankiCollection) homeDeckID(c *card.Card) int64 {
+	if home := c.GetHomeDeckID(); home != nil {
+		return *home
+	}
+	return c.GetDeckID()
+}
+
+// dayNumber returns the day a timestamp in seconds falls on, counted from the collection creation
+func dayNumber(secs, crt int64) int64 {
+	return int64(math.Floor(float64(secs-crt) / 86400))
+}
+
+// idAllocator hands out unique millisecond IDs derived from creation times
+type idAllocator map[int64]bool
+
+// next returns the first free ID at or after the time
+func (a idAllocator) next(t time.Time) int64 {
+	id := max(t.UnixMilli(), 1)
+	for a[id] {
+		id++
+	}
+	a[id] = true
+	return id
+}
+
+// AnkiModel returns the legacy model JSON object of a note type
+func AnkiModel(id int64, nt *notetype.NoteType) map[string]interface{} {
+	fields := parseFieldsJSON(nt.GetFieldsJSON())
+	cardTypes := parseCardTypesJSON(nt.GetCardTypesJSON())
+	templates := parseTemplatesJSON(nt.GetTemplatesJSON())
+
+	flds := make([]map[string]interface{}, len(fields))
+	for i, f := range fields {
+		flds[i] = map[string]interface{}{
+			"name":   stringValue(f, "name", fmt.Sprintf("Field %d", i+1)),
+			"ord":    i,
+			"sticky": f["sticky"] == true,
+			"rtl":    f["rtl"] == true,
+			"font":   stringValue(f, "font", "Arial"),
+			"size":   numberValue(f, "size", 20),
+			"media":  []string{},
+		}
+	}
+
+	modelType, css := 0, ankiDefaultCSS
+	count := max(len(cardTypes), len(templates))
+	tmpls := make([]map[string]interface{}, count)
+	req := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		var ct, t map[string]interface{}
+		if i < len(cardTypes) {
+			ct = cardTypes[i]
+		}
+		if i < len(templates) {
+			t = templates[i]
+		}
+		if ct["cloze"] == true {
+			modelType = 1
+		}
+		if templateCSS := stringValue(t, "css", ""); templateCSS != "" {
+			css = templateCSS
+		}
+		tmpls[i] = map[string]interface{}{
+			"name":  stringValue(ct, "name", stringValue(t, "name", fmt.Sprintf("Card %d", i+1))),
+			"ord":   i,
+			"qfmt":  stringValue(t, "qfmt", stringValue(t, "Front", "")),
+			"afmt":  stringValue(t, "afmt", stringValue(t, "Back", "")),
+			"bqfmt": stringValue(t, "bqfmt", ""),
+			"bafmt": stringValue(t, "bafmt", ""),
+			"did":   nil,
+			"bfont": "",
+			"bsize": 0,
+		}
+		req = append(req, []interface{}{i, "any", []int{0}})
+	}
+	if modelType == 1 {
+		req = []interface{}{}
+	}
+
+	return map[string]interface{}{
+		"id":        id,
+		"name":      nt.GetName(),
+		"type":      modelType,
+		"mod":       nt.GetUpdatedAt().Unix(),
+		"usn":       0,
+		"sortf":     0,
+		"did":       nil,
+		"tmpls":     tmpls,
+		"flds":      flds,
+		"css":       css,
+		"latexPre":  ankiDefaultLatexPre,
+		"latexPost": ankiDefaultLatexPost,
+		"latexsvg":  false,
+		"req":       req,
+		"tags":      []string{},
+		"vers":      []interface{}{},
+	}
+}
+
+// AnkiDeck returns the legacy deck JSON object
+func AnkiDeck(id int64, name string, confID int64, mod time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               id,
+		"name":             name,
+		"mod":              mod.Unix(),
+		"usn":              0,
+		"desc":             "",
+		"dyn":              0,
+		"conf":             confID,
+		"collapsed":        false,
+		"browserCollapsed": false,
+		"extendNew":        0,
+		"extendRev":        0,
+		"newToday":         []int{0, 0},
+		"revToday":         []int{0, 0},
+		"lrnToday":         []int{0, 0},
+		"timeToday":        []int{0, 0},
+	}
+}
+
+// AnkiDeckConfig returns the legacy deck options JSON object
+func AnkiDeckConfig(id int64, name string, opts *deck.DeckOptions, mod time.Time) map[string]interface{} {
+	leechAction := 1
+	if opts.LeechAction == valueobjects.LeechActionSuspend {
+		leechAction = 0
+	}
+	return map[string]interface{}{
+		"id":       id,
+		"name":     name,
+		"mod":      mod.Unix(),
+		"usn":      0,
+		"dyn":      false,
+		"maxTaken": 60,
+		"autoplay": true,
+		"timer":    0,
+		"replayq":  true,
+		"new": map[string]interface{}{
+			"delays":        append([]float64{}, opts.NewSteps...),
+			"ints":          []int{opts.GraduatingInterval, opts.EasyInterval, 0},
+			"initialFactor": int(math.Round(opts.StartingEase * 1000)),
+			"perDay":        opts.NewPerDay,
+			"bury":          opts.BuryNew,
+			"order":         1,
+			"separate":      true,
+		},
+		"lapse": map[string]interface{}{
+			"delays":      append([]float64{}, opts.RelearnSteps...),
+			"mult":        opts.LapseNewInterval,
+			"minInt":      opts.MinimumInterval,
+			"leechFails":  opts.LeechThreshold,
+			"leechAction": leechAction,
+		},
+		"rev": map[string]interface{}{
+			"perDay":     opts.ReviewsPerDay,
+			"ease4":      opts.EasyBonus,
+			"ivlFct":     opts.IntervalModifier,
+			"maxIvl":     opts.MaximumInterval,
+			"hardFactor": opts.HardInterval,
+			"bury":       opts.BuryReviews,
+			"fuzz":       0.05,
+			"minSpace":   1,
+		},
+		"buryInterdayLearning": opts.BuryInterdayLearning,
+		"desiredRetention":     opts.DesiredRetention,
+		"fsrsWeights":          append([]float64{}, opts.FSRSWeights...),
+	}
+}
+
+// OptionsKey identifies deck options by their normalized JSON
+func OptionsKey(opts *deck.DeckOptions) string {
+	data, _ := json.Marshal(opts)
+	return string(data)
+}
+
+// NoteFieldValues returns the note's field values in the note type's field order
+func NoteFieldValues(n *note.Note, nt *notetype.NoteType) []string {
+	var fields map[string]interface{}
+	json.Unmarshal([]byte(n.GetFieldsJSON()), &fields)
+
+	names := parseFieldsJSON(nt.GetFieldsJSON())
+	values := make([]string, len(names))
+	for i, f := range names {
+		switch v := fields[stringValue(f, "name", "")].(type) {
+		case nil:
+		case string:
+			values[i] = v
+		default:
+			values[i] = fmt.Sprintf("%v", v)
+		}
+	}
+	return values
+}
+
+// CardData returns the card's data column holding its FSRS memory state
+func CardData(c *card.Card) string {
+	if c.GetStability() == nil || c.GetDifficulty() == nil {
+		return ""
+	}
+	data, _ := json.Marshal(map[string]float64{"s": *c.GetStability(), "d": *c.GetDifficulty()})
+	return string(data)
+}
+
+// RevlogType converts a review type to Anki's review log type
+func RevlogType(t valueobjects.ReviewType) int {
+	switch t {
+	case valueobjects.ReviewTypeLearn:
+		return AnkiRevlogLearn
+	case valueobjects.ReviewTypeRelearn:
+		return AnkiRevlogRelearn
+	case valueobjects.ReviewTypeCram:
+		return AnkiRevlogCram
+	case valueobjects.ReviewTypeManual:
+		return AnkiRevlogManual
+	default:
+		return AnkiRevlogReview
+	}
+}
+
+// AnkiTags formats tags the way Anki stores them: space separated with surrounding spaces
+func AnkiTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + strings.Join(tags, " ") + " "
+}
+
+// StripHTML returns the text of a field without markup, as used for sorting and duplicate checks
+func StripHTML(value string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTagPattern.ReplaceAllString(value, "")))
+}
+
+// FieldChecksum returns the first 32 bits of the SHA-1 of a stripped field, Anki's duplicate check key
+func FieldChecksum(value string) int64 {
+	sum := sha1.Sum([]byte(value))
+	checksum, _ := strconv.ParseUint(hex.EncodeToString(sum[:4]), 16, 32)
+	return int64(checksum)
+}
+
+// stringValue returns a string property of a JSON object or the fallback
+func stringValue(m map[string]interface{}, key, fallback string) string {
+	if s, ok := m[key].(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
+
+// numberValue returns a numeric property of a JSON object or the fallback
+func numberValue(m map[string]interface{}, key string, fallback int) int {
+	if n, ok := m[key].(float64); ok && n > 0 {
+		return int(n)
+	}
+	return fallback
+}
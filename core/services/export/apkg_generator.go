@@ -7,34 +7,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"strings"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deckoptionspreset "github.com/felipesantos/anki-backend/core/domain/entities/deck_options_preset"
 	"github.com/felipesantos/anki-backend/core/domain/entities/media"
 	"github.com/felipesantos/anki-backend/core/domain/entities/note"
 	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
 )
 
+// APKGContent is the collection content written to an Anki package
+type APKGContent struct {
+	Notes     []*note.Note
+	Cards     []*card.Card
+	Decks     []*deck.Deck // Home decks of the cards and all their parents
+	NoteTypes []*notetype.NoteType
+	Presets   []*deckoptionspreset.DeckOptionsPreset
+	Reviews   []*review.Review
+	Media     []*media.Media
+}
+
 // GenerateAPKG generates an Anki package (.apkg) file
 // An .apkg file is a ZIP containing:
-// - collection.anki2: SQLite database with notes, cards, decks, note types
+// - collection.anki2: SQLite database with notes, cards, decks, note types, deck options and review log
 // - media: JSON file mapping media filenames
 // - Media files (if includeMedia is true)
-func GenerateAPKG(
-	ctx context.Context,
-	notes []*note.Note,
-	cards []*card.Card,
-	decks []*deck.Deck,
-	noteTypes []*notetype.NoteType,
-	mediaFiles []*media.Media,
-	includeMedia bool,
-) (io.Reader, int64, error) {
+// Without includeScheduling every card is exported as new and the review log is left out,
+// which is how shared decks are distributed
+func GenerateAPKG(ctx context.Context, content *APKGContent, includeMedia, includeScheduling bool) (io.Reader, int64, error) {
 	var buf bytes.Buffer
 	zipWriter := zip.NewWriter(&buf)
 
 	// 1. Create SQLite database (collection.anki2)
-	dbData, err := createAnkiDatabase(ctx, notes, cards, decks, noteTypes)
+	dbData, err := createAnkiDatabase(ctx, content, includeScheduling)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create Anki database: %w", err)
 	}
@@ -49,9 +55,9 @@ func GenerateAPKG(
 	}
 
 	// 2. Create media mapping file
-	if includeMedia && len(mediaFiles) > 0 {
+	if includeMedia && len(content.Media) > 0 {
 		mediaMap := make(map[string]string)
-		for _, m := range mediaFiles {
+		for _, m := range content.Media {
 			// Map original filename to itself (Anki format)
 			mediaMap[m.GetFilename()] = m.GetFilename()
 		}
@@ -72,7 +78,7 @@ func GenerateAPKG(
 		// 3. Add media files to ZIP (if storage paths are available)
 		// Note: This requires access to actual file storage, which may need to be injected
 		// For now, we'll create placeholder entries
-		for _, m := range mediaFiles {
+		for _, m := range content.Media {
 			mediaEntry, err := zipWriter.Create(m.GetFilename())
 			if err != nil {
 				continue // Skip if we can't create the entry
@@ -98,32 +104,6 @@ func GenerateAPKG(
 	return bytes.NewReader(data), int64(len(data)), nil
 }
 
-// createAnkiDatabase creates a SQLite database with Anki's schema
-// This is a simplified version - full implementation would require complete Anki schema and SQLite driver
-// For now, returns a minimal database structure
-// TODO: Add SQLite driver dependency and implement full Anki database schema
-func createAnkiDatabase(
-	ctx context.Context,
-	notes []*note.Note,
-	cards []*card.Card,
-	decks []*deck.Deck,
-	noteTypes []*notetype.NoteType,
-) ([]byte, error) {
-	// For now, create a minimal placeholder database
-	// Full implementation would require:
-	// 1. Add github.com/mattn/go-sqlite3 dependency
-	// 2. Create full Anki schema (col, notes, cards, graves, revlog tables)
-	// 3. Insert all data properly
-	
-	// Placeholder: return minimal SQLite database structure
-	// In production, this should be a proper SQLite database file
-	placeholder := []byte("SQLite format 3\x00") // SQLite file header
-	
-	// For now, we'll create a basic structure
-	// Full implementation should use sql.Open("sqlite3", ...) and create proper schema
-	return placeholder, fmt.Errorf("APKG generation requires SQLite driver - not yet implemented")
-}
-
 // parseFieldsJSON parses fields JSON array
 func parseFieldsJSON(fieldsJSON string) []map[string]interface{} {
 	var fields []map[string]interface{}
@@ -138,12 +118,14 @@ func parseCardTypesJSON(cardTypesJSON string) []map[string]interface{} {
 	return cardTypes
 }
 
-// formatFieldsForAnki formats fields as tab-separated string (Anki format)
-func formatFieldsForAnki(fields map[string]interface{}) string {
-	var parts []string
-	for _, v := range fields {
-		parts = append(parts, fmt.Sprintf("%v", v))
+// parseTemplatesJSON parses templates JSON, which is an array of objects or a single object
+func parseTemplatesJSON(templatesJSON string) []map[string]interface{} {
+	var templates []map[string]interface{}
+	if err := json.Unmarshal([]byte(templatesJSON), &templates); err != nil {
+		var template map[string]interface{}
+		if json.Unmarshal([]byte(templatesJSON), &template) == nil {
+			templates = []map[string]interface{}{template}
+		}
 	}
-	return strings.Join(parts, "\x1f") // Anki uses 0x1f as field separator
+	return templates
 }
-
@@ -6,8 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"path/filepath"
-	"regexp"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
@@ -25,6 +23,8 @@ type ExportService struct {
 	noteRepo     secondary.INoteRepository
 	noteTypeRepo secondary.INoteTypeRepository
 	mediaRepo    secondary.IMediaRepository
+	reviewRepo   secondary.IReviewRepository
+	presetRepo   secondary.IDeckOptionsPresetRepository
 }
 
 // NewExportService creates a new ExportService instance
@@ -34,6 +34,8 @@ func NewExportService(
 	noteRepo secondary.INoteRepository,
 	noteTypeRepo secondary.INoteTypeRepository,
 	mediaRepo secondary.IMediaRepository,
+	reviewRepo secondary.IReviewRepository,
+	presetRepo secondary.IDeckOptionsPresetRepository,
 ) primary.IExportService {
 	return &ExportService{
 		deckRepo:     deckRepo,
@@ -41,6 +43,8 @@ func NewExportService(
 		noteRepo:     noteRepo,
 		noteTypeRepo: noteTypeRepo,
 		mediaRepo:    mediaRepo,
+		reviewRepo:   reviewRepo,
+		presetRepo:   presetRepo,
 	}
 }
 
@@ -115,6 +119,9 @@ func (s *ExportService) ExportNotes(
 	if len(noteIDs) == 0 {
 		return nil, 0, "", fmt.Errorf("note_ids cannot be empty")
 	}
+	if format != "apkg" && format != "text" {
+		return nil, 0, "", fmt.Errorf("unsupported format: %s", format)
+	}
 
 	// 1. Fetch notes with ownership validation
 	notes, err := s.noteRepo.FindByIDs(ctx, userID, noteIDs)
@@ -137,9 +144,9 @@ func (s *ExportService) ExportNotes(
 		}
 	}
 
-	// 2. Fetch cards if scheduling is included
+	// 2. Fetch cards (packages always contain them; text exports only with scheduling)
 	var cards []*card.Card
-	if includeScheduling {
+	if includeScheduling || format == "apkg" {
 		cards, err = s.cardRepo.FindByNoteIDs(ctx, userID, noteIDs)
 		if err != nil {
 			return nil, 0, "", fmt.Errorf("failed to fetch cards: %w", err)
@@ -161,23 +168,7 @@ func (s *ExportService) ExportNotes(
 		noteTypes = append(noteTypes, nt)
 	}
 
-	// 4. Fetch decks for the cards (if scheduling is included)
-	var decks []*deck.Deck
-	if includeScheduling {
-		deckIDs := make(map[int64]bool)
-		for _, c := range cards {
-			deckIDs[c.GetDeckID()] = true
-		}
-		for deckID := range deckIDs {
-			d, err := s.deckRepo.FindByID(ctx, userID, deckID)
-			if err != nil {
-				continue // Skip if deck not found
-			}
-			decks = append(decks, d)
-		}
-	}
-
-	// 5. Extract and fetch media files if requested
+	// 4. Extract and fetch media files if requested
 	var mediaFiles []*media.Media
 	if includeMedia {
 		mediaFiles, err = s.extractMediaFromNotes(ctx, userID, notes)
@@ -186,14 +177,19 @@ func (s *ExportService) ExportNotes(
 		}
 	}
 
-	// 6. Generate export based on format
+	// 5. Generate export based on format
 	var reader io.Reader
 	var size int64
 	var filename string
 
 	switch format {
 	case "apkg":
-		reader, size, err = GenerateAPKG(ctx, notes, cards, decks, noteTypes, mediaFiles, includeMedia)
+		content, err := s.packageContent(ctx, userID, cards, includeScheduling)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		content.Notes, content.NoteTypes, content.Media = notes, noteTypes, mediaFiles
+		reader, size, err = GenerateAPKG(ctx, content, includeMedia, includeScheduling)
 		if err != nil {
 			return nil, 0, "", fmt.Errorf("failed to generate APKG: %w", err)
 		}
@@ -211,6 +207,52 @@ func (s *ExportService) ExportNotes(
 	return reader, size, filename, nil
 }
 
+// packageContent fetches the decks, deck options and review log written to a package with the cards
+// Cards keep their home deck, so the package contains those decks and their parents
+func (s *ExportService) packageContent(ctx context.Context, userID int64, cards []*card.Card, includeScheduling bool) (*APKGContent, error) {
+	allDecks, err := s.deckRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch decks: %w", err)
+	}
+	decksByID := make(map[int64]*deck.Deck, len(allDecks))
+	for _, d := range allDecks {
+		decksByID[d.GetID()] = d
+	}
+
+	content := &APKGContent{Cards: cards}
+	included := make(map[int64]bool)
+	for _, c := range cards {
+		deckID := c.GetDeckID()
+		if home := c.GetHomeDeckID(); home != nil {
+			deckID = *home
+		}
+		for d := decksByID[deckID]; d != nil && !included[d.GetID()]; {
+			included[d.GetID()] = true
+			content.Decks = append(content.Decks, d)
+			if d.GetParentID() == nil {
+				break
+			}
+			d = decksByID[*d.GetParentID()]
+		}
+	}
+
+	content.Presets, err = s.presetRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deck options presets: %w", err)
+	}
+
+	if includeScheduling {
+		for _, c := range cards {
+			reviews, err := s.reviewRepo.FindByCardID(ctx, userID, c.GetID())
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch reviews of card %d: %w", c.GetID(), err)
+			}
+			content.Reviews = append(content.Reviews, reviews...)
+		}
+	}
+	return content, nil
+}
+
 // extractMediaFromNotes extracts media filenames from note fields and fetches media entities
 func (s *ExportService) extractMediaFromNotes(ctx context.Context, userID int64, notes []*note.Note) ([]*media.Media, error) {
 	mediaFilenames := make(map[string]bool)
@@ -225,11 +267,7 @@ func (s *ExportService) extractMediaFromNotes(ctx context.Context, userID int64,
 		// Look for media references in field values (HTML img tags, audio tags, etc.)
 		for _, val := range fields {
 			valStr := fmt.Sprintf("%v", val)
-			// Extract filenames from HTML tags (simplified regex)
-			// Pattern: <img src="filename.jpg"> or <audio src="filename.mp3">
-			// This is a simplified extraction - full implementation would use proper HTML parsing
-			extracted := extractMediaFilenames(valStr)
-			for _, filename := range extracted {
+			for _, filename := range media.ReferencedFilenames(valStr) {
 				mediaFilenames[filename] = true
 			}
 		}
@@ -248,31 +286,3 @@ func (s *ExportService) extractMediaFromNotes(ctx context.Context, userID int64,
 	return mediaFiles, nil
 }
 
-// extractMediaFilenames extracts media filenames from HTML content
-// This is a simplified implementation - full version would use proper HTML parsing
-func extractMediaFilenames(content string) []string {
-	var filenames []string
-	// Simple regex patterns for common media tags
-	patterns := []string{
-		`<img[^>]+src=["']([^"']+)["']`,
-		`<audio[^>]+src=["']([^"']+)["']`,
-		`<video[^>]+src=["']([^"']+)["']`,
-		`\[sound:([^\]]+)\]`, // Anki sound syntax
-	}
-
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindAllStringSubmatch(content, -1)
-		for _, match := range matches {
-			if len(match) > 1 {
-				filename := filepath.Base(match[1]) // Get just the filename
-				if filename != "" {
-					filenames = append(filenames, filename)
-				}
-			}
-		}
-	}
-
-	return filenames
-}
-
@@ -0,0 +1,583 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	_ "modernc.org/sqlite" // SQLite driver for Anki collection databases
+
+	"github.com/felipesantos/anki-backend/pkg/protomsg"
+)
+
+// Entries of an Anki package (.apkg or .colpkg ZIP file)
+const (
+	collectionLatest = "collection.anki21b" // Schema 18 database, zstd compressed
+	collectionV2     = "collection.anki21"  // Schema 11 database of the v2 scheduler
+	collectionLegacy = "collection.anki2"   // Schema 11 database
+	mediaEntry       = "media"              // Media map: zip entry name -> filename
+	metaEntry        = "meta"               // Package metadata (protobuf), absent in legacy packages
+)
+
+// packageVersionLatest is the meta version whose media map and media files are zstd compressed
+const packageVersionLatest = 3
+
+// ankiPackage is the content of an Anki package, as stored in the collection database
+type ankiPackage struct {
+	crt       int64 // Collection creation time in seconds, day 0 of review due numbers
+	noteTypes map[int64]*ankiNoteType
+	decks     map[int64]*ankiDeck
+	notes     []*ankiNote
+	cards     []*ankiCard
+	revlog    []*ankiRevlog
+	media     []*ankiMedia
+}
+
+type ankiNoteType struct {
+	id        int64
+	name      string
+	cloze     bool
+	css       string
+	fields    []ankiField
+	templates []ankiTemplate
+}
+
+type ankiField struct {
+	name   string
+	sticky bool
+	rtl    bool
+	font   string
+	size   int
+}
+
+type ankiTemplate struct {
+	name  string
+	qfmt  string
+	afmt  string
+	bqfmt string
+	bafmt string
+}
+
+type ankiDeck struct {
+	id       int64
+	name     string // Full name, with "::" between levels
+	filtered bool
+}
+
+type ankiNote struct {
+	id     int64 // Creation time in milliseconds
+	guid   string
+	mid    int64
+	mod    int64 // Modification time in seconds
+	tags   []string
+	fields []string
+}
+
+type ankiCard struct {
+	id         int64 // Creation time in milliseconds
+	nid        int64
+	did        int64
+	ord        int
+	mod        int64
+	ctype      int // 0 new, 1 learn, 2 review, 3 relearn
+	queue      int // -3/-2 buried, -1 suspended, otherwise the scheduling queue
+	due        int64
+	ivl        int
+	factor     int
+	reps       int
+	lapses     int
+	odue       int64 // Due in the home deck, for cards in a filtered deck
+	odid       int64 // Home deck, for cards in a filtered deck
+	flags      int
+	stability  *float64
+	difficulty *float64
+}
+
+type ankiRevlog struct {
+	id     int64 // Review time in milliseconds
+	cid    int64
+	ease   int // Answer button 1-4, 0 for manual entries
+	ivl    int // Days, or negative seconds for learning steps
+	factor int
+	timeMs int
+	rtype  int // 0 learn, 1 review, 2 relearn, 3 filtered, 4 manual, 5 rescheduled
+}
+
+// ankiMedia is a media file of the package
+type ankiMedia struct {
+	name       string
+	file       *zip.File
+	compressed bool
+}
+
+// read returns the content of the media file
+func (m *ankiMedia) read() ([]byte, error) {
+	rc, err := openEntry(m.file, m.compressed)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// readPackage reads an .apkg or .colpkg file
+// The newest collection database of the package is used: modern packages also carry a legacy
+// collection.anki2 that only asks older clients to update
+func readPackage(ctx context.Context, file io.ReaderAt, size int64) (*ankiPackage, error) {
+	zr, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package: %w", err)
+	}
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	version, err := readPackageVersion(entries[metaEntry])
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg *ankiPackage
+	switch {
+	case entries[collectionLatest] != nil:
+		pkg, err = readCollection(ctx, entries[collectionLatest], true)
+	case entries[collectionV2] != nil:
+		pkg, err = readCollection(ctx, entries[collectionV2], false)
+	case entries[collectionLegacy] != nil:
+		pkg, err = readCollection(ctx, entries[collectionLegacy], false)
+	default:
+		return nil, fmt.Errorf("invalid package: no collection database found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.media, err = readMediaMap(entries, version >= packageVersionLatest)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// readPackageVersion reads the version of the package metadata, 0 for legacy packages without it
+func readPackageVersion(f *zip.File) (uint64, error) {
+	if f == nil {
+		return 0, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open package metadata: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read package metadata: %w", err)
+	}
+	meta, err := protomsg.Decode(data)
+	if err != nil {
+		return 0, fmt.Errorf("invalid package metadata: %w", err)
+	}
+	return meta.Uint(1), nil
+}
+
+// openEntry opens a ZIP entry, decompressing zstd content
+func openEntry(f *zip.File, compressed bool) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	if !compressed {
+		return rc, nil
+	}
+	decoder, err := zstd.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return &zstdEntry{decoder: decoder, entry: rc}, nil
+}
+
+// zstdEntry closes both the decoder and the ZIP entry it reads
+type zstdEntry struct {
+	decoder *zstd.Decoder
+	entry   io.ReadCloser
+}
+
+func (z *zstdEntry) Read(p []byte) (int, error) {
+	return z.decoder.Read(p)
+}
+
+func (z *zstdEntry) Close() error {
+	z.decoder.Close()
+	return z.entry.Close()
+}
+
+// readMediaMap lists the media files of the package
+// Legacy packages map entry names to filenames in JSON; latest packages store a zstd compressed
+// protobuf list whose position is the entry name
+func readMediaMap(entries map[string]*zip.File, compressed bool) ([]*ankiMedia, error) {
+	f := entries[mediaEntry]
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := openEntry(f, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open media map: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media map: %w", err)
+	}
+
+	var media []*ankiMedia
+	add := func(entryName, filename string) {
+		if entry := entries[entryName]; entry != nil && filename != "" {
+			media = append(media, &ankiMedia{name: filename, file: entry, compressed: compressed})
+		}
+	}
+
+	if !compressed {
+		var names map[string]string
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &names); err != nil {
+				return nil, fmt.Errorf("invalid media map: %w", err)
+			}
+		}
+		for entryName, filename := range names {
+			add(entryName, filename)
+		}
+		sort.Slice(media, func(i, j int) bool { return media[i].name < media[j].name })
+		return media, nil
+	}
+
+	list, err := protomsg.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media map: %w", err)
+	}
+	mediaEntries, err := list.Messages(1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media map: %w", err)
+	}
+	for i, entry := range mediaEntries {
+		entryName := strconv.Itoa(i)
+		if entry.Has(255) {
+			entryName = strconv.FormatUint(entry.Uint(255), 10)
+		}
+		add(entryName, entry.Str(1))
+	}
+	return media, nil
+}
+
+// readCollection copies the collection database to a temporary file and loads it
+func readCollection(ctx context.Context, f *zip.File, compressed bool) (*ankiPackage, error) {
+	rc, err := openEntry(f, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "anki-import-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary collection file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, rc); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to read collection: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temporary collection file: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+tmp.Name()+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+	defer db.Close()
+
+	pkg, err := loadCollection(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collection: %w", err)
+	}
+	return pkg, nil
+}
+
+// loadCollection loads the note types, decks, notes, cards and review log of a collection database
+func loadCollection(ctx context.Context, db *sql.DB) (*ankiPackage, error) {
+	pkg := &ankiPackage{
+		noteTypes: make(map[int64]*ankiNoteType),
+		decks:     make(map[int64]*ankiDeck),
+	}
+	if err := db.QueryRowContext(ctx, "SELECT crt FROM col").Scan(&pkg.crt); err != nil {
+		return nil, err
+	}
+
+	// Schema 18 moved note types and decks from JSON columns of col to their own tables
+	var schema18 bool
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'notetypes'").Scan(&schema18); err != nil {
+		return nil, err
+	}
+	if schema18 {
+		if err := loadNoteTypes(ctx, db, pkg); err != nil {
+			return nil, err
+		}
+		if err := loadDecks(ctx, db, pkg); err != nil {
+			return nil, err
+		}
+	} else if err := loadLegacyModels(ctx, db, pkg); err != nil {
+		return nil, err
+	}
+
+	if err := loadNotes(ctx, db, pkg); err != nil {
+		return nil, err
+	}
+	if err := loadCards(ctx, db, pkg); err != nil {
+		return nil, err
+	}
+	return pkg, loadRevlog(ctx, db, pkg)
+}
+
+// loadLegacyModels reads the note types and decks stored as JSON in the col table of schema 11
+func loadLegacyModels(ctx context.Context, db *sql.DB, pkg *ankiPackage) error {
+	var modelsJSON, decksJSON string
+	if err := db.QueryRowContext(ctx, "SELECT models, decks FROM col").Scan(&modelsJSON, &decksJSON); err != nil {
+		return err
+	}
+
+	var models map[string]struct {
+		Name string `json:"name"`
+		Type int    `json:"type"`
+		CSS  string `json:"css"`
+		Flds []struct {
+			Name   string `json:"name"`
+			Sticky bool   `json:"sticky"`
+			RTL    bool   `json:"rtl"`
+			Font   string `json:"font"`
+			Size   int    `json:"size"`
+		} `json:"flds"`
+		Tmpls []struct {
+			Name  string `json:"name"`
+			Qfmt  string `json:"qfmt"`
+			Afmt  string `json:"afmt"`
+			Bqfmt string `json:"bqfmt"`
+			Bafmt string `json:"bafmt"`
+		} `json:"tmpls"`
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		return fmt.Errorf("invalid note types: %w", err)
+	}
+	for key, m := range models {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid note type id: %s", key)
+		}
+		nt := &ankiNoteType{id: id, name: m.Name, cloze: m.Type == 1, css: m.CSS}
+		// Fields and templates are kept sorted by ord
+		for _, f := range m.Flds {
+			nt.fields = append(nt.fields, ankiField{name: f.Name, sticky: f.Sticky, rtl: f.RTL, font: f.Font, size: f.Size})
+		}
+		for _, t := range m.Tmpls {
+			nt.templates = append(nt.templates, ankiTemplate{name: t.Name, qfmt: t.Qfmt, afmt: t.Afmt, bqfmt: t.Bqfmt, bafmt: t.Bafmt})
+		}
+		pkg.noteTypes[id] = nt
+	}
+
+	var decks map[string]struct {
+		Name string          `json:"name"`
+		Dyn  json.RawMessage `json:"dyn"`
+	}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return fmt.Errorf("invalid decks: %w", err)
+	}
+	for key, d := range decks {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid deck id: %s", key)
+		}
+		dyn := strings.TrimSpace(string(d.Dyn))
+		pkg.decks[id] = &ankiDeck{id: id, name: d.Name, filtered: dyn == "1" || dyn == "true"}
+	}
+	return nil
+}
+
+// loadNoteTypes reads the notetypes, fields and templates tables of schema 18
+func loadNoteTypes(ctx context.Context, db *sql.DB, pkg *ankiPackage) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, config FROM notetypes")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		nt := &ankiNoteType{}
+		var config []byte
+		if err := rows.Scan(&nt.id, &nt.name, &config); err != nil {
+			return err
+		}
+		// Notetype.Config: kind = 1 (1 is cloze), css = 3
+		cfg, err := protomsg.Decode(config)
+		if err != nil {
+			return fmt.Errorf("invalid config of note type %d: %w", nt.id, err)
+		}
+		nt.cloze = cfg.Uint(1) == 1
+		nt.css = cfg.Str(3)
+		pkg.noteTypes[nt.id] = nt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	fieldRows, err := db.QueryContext(ctx, "SELECT ntid, name, config FROM fields ORDER BY ntid, ord")
+	if err != nil {
+		return err
+	}
+	defer fieldRows.Close()
+	for fieldRows.Next() {
+		var ntid int64
+		var field ankiField
+		var config []byte
+		if err := fieldRows.Scan(&ntid, &field.name, &config); err != nil {
+			return err
+		}
+		// Field.Config: sticky = 1, rtl = 2, font_name = 3, font_size = 4
+		cfg, err := protomsg.Decode(config)
+		if err != nil {
+			return fmt.Errorf("invalid config of field %s: %w", field.name, err)
+		}
+		field.sticky = cfg.Uint(1) != 0
+		field.rtl = cfg.Uint(2) != 0
+		field.font = cfg.Str(3)
+		field.size = int(cfg.Uint(4))
+		if nt := pkg.noteTypes[ntid]; nt != nil {
+			nt.fields = append(nt.fields, field)
+		}
+	}
+	if err := fieldRows.Err(); err != nil {
+		return err
+	}
+
+	templateRows, err := db.QueryContext(ctx, "SELECT ntid, name, config FROM templates ORDER BY ntid, ord")
+	if err != nil {
+		return err
+	}
+	defer templateRows.Close()
+	for templateRows.Next() {
+		var ntid int64
+		var template ankiTemplate
+		var config []byte
+		if err := templateRows.Scan(&ntid, &template.name, &config); err != nil {
+			return err
+		}
+		// Template.Config: q_format = 1, a_format = 2, q_format_browser = 3, a_format_browser = 4
+		cfg, err := protomsg.Decode(config)
+		if err != nil {
+			return fmt.Errorf("invalid config of template %s: %w", template.name, err)
+		}
+		template.qfmt = cfg.Str(1)
+		template.afmt = cfg.Str(2)
+		template.bqfmt = cfg.Str(3)
+		template.bafmt = cfg.Str(4)
+		if nt := pkg.noteTypes[ntid]; nt != nil {
+			nt.templates = append(nt.templates, template)
+		}
+	}
+	return templateRows.Err()
+}
+
+// loadDecks reads the decks table of schema 18, whose names separate levels with 0x1f
+func loadDecks(ctx context.Context, db *sql.DB, pkg *ankiPackage) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, kind FROM decks")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d := &ankiDeck{}
+		var kind []byte
+		if err := rows.Scan(&d.id, &d.name, &kind); err != nil {
+			return err
+		}
+		// Deck.kind: normal = 1, filtered = 2
+		k, err := protomsg.Decode(kind)
+		if err != nil {
+			return fmt.Errorf("invalid kind of deck %d: %w", d.id, err)
+		}
+		d.name = strings.ReplaceAll(d.name, "\x1f", "::")
+		d.filtered = k.Has(2)
+		pkg.decks[d.id] = d
+	}
+	return rows.Err()
+}
+
+func loadNotes(ctx context.Context, db *sql.DB, pkg *ankiPackage) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, guid, mid, mod, tags, flds FROM notes ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		n := &ankiNote{}
+		var tags, fields string
+		if err := rows.Scan(&n.id, &n.guid, &n.mid, &n.mod, &tags, &fields); err != nil {
+			return err
+		}
+		n.tags = strings.Fields(tags)
+		n.fields = strings.Split(fields, "\x1f")
+		pkg.notes = append(pkg.notes, n)
+	}
+	return rows.Err()
+}
+
+func loadCards(ctx context.Context, db *sql.DB, pkg *ankiPackage) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, nid, did, ord, mod, type, queue, due, ivl, factor, reps, lapses, odue, odid, flags, data
+		FROM cards ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := &ankiCard{}
+		var data string
+		if err := rows.Scan(&c.id, &c.nid, &c.did, &c.ord, &c.mod, &c.ctype, &c.queue, &c.due, &c.ivl, &c.factor,
+			&c.reps, &c.lapses, &c.odue, &c.odid, &c.flags, &data); err != nil {
+			return err
+		}
+		// FSRS memory state, kept in the card data of recent versions
+		var memory struct {
+			Stability  *float64 `json:"s"`
+			Difficulty *float64 `json:"d"`
+		}
+		if json.Unmarshal([]byte(data), &memory) == nil {
+			c.stability, c.difficulty = memory.Stability, memory.Difficulty
+		}
+		pkg.cards = append(pkg.cards, c)
+	}
+	return rows.Err()
+}
+
+func loadRevlog(ctx context.Context, db *sql.DB, pkg *ankiPackage) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, cid, ease, ivl, factor, time, type FROM revlog ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := &ankiRevlog{}
+		if err := rows.Scan(&r.id, &r.cid, &r.ease, &r.ivl, &r.factor, &r.timeMs, &r.rtype); err != nil {
+			return err
+		}
+		pkg.revlog = append(pkg.revlog, r)
+	}
+	return rows.Err()
+}
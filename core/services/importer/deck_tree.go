@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// deckTree resolves full deck names ("Parent::Child") of imported content to decks of the user,
+// creating the missing levels
+type deckTree struct {
+	deckRepo secondary.IDeckRepository
+	userID   int64
+	paths    map[string]int64 // Full deck name -> deck ID, loaded on first use
+	added    int
+}
+
+func newDeckTree(deckRepo secondary.IDeckRepository, userID int64) *deckTree {
+	return &deckTree{deckRepo: deckRepo, userID: userID}
+}
+
+// find returns the deck with a full name, false when it does not exist
+func (t *deckTree) find(ctx context.Context, fullName string) (int64, bool, error) {
+	if err := t.load(ctx); err != nil {
+		return 0, false, err
+	}
+	id, ok := t.paths[normalizeDeckName(fullName)]
+	return id, ok, nil
+}
+
+// ensure returns the deck with a full name, creating it and its missing parents
+// An empty name resolves to the Default deck
+func (t *deckTree) ensure(ctx context.Context, fullName string) (int64, error) {
+	if err := t.load(ctx); err != nil {
+		return 0, err
+	}
+
+	var parentID *int64
+	path := ""
+	for _, part := range strings.Split(normalizeDeckName(fullName), "::") {
+		if part == "" {
+			continue
+		}
+		if path != "" {
+			path += "::"
+		}
+		path += part
+
+		if id, ok := t.paths[path]; ok {
+			parentID = &id
+			continue
+		}
+		now := time.Now()
+		d, err := deck.NewBuilder().
+			WithUserID(t.userID).
+			WithName(part).
+			WithParentID(parentID).
+			WithOptionsJSON("{}").
+			WithCreatedAt(now).
+			WithUpdatedAt(now).
+			Build()
+		if err != nil {
+			return 0, fmt.Errorf("failed to build deck entity: %w", err)
+		}
+		if err := t.deckRepo.Save(ctx, t.userID, d); err != nil {
+			return 0, fmt.Errorf("failed to save deck %s: %w", path, err)
+		}
+		id := d.GetID()
+		t.paths[path] = id
+		parentID = &id
+		t.added++
+	}
+	if parentID == nil {
+		return t.ensure(ctx, "Default")
+	}
+	return *parentID, nil
+}
+
+func (t *deckTree) load(ctx context.Context) error {
+	if t.paths != nil {
+		return nil
+	}
+	decks, err := t.deckRepo.FindByUserID(ctx, t.userID, "")
+	if err != nil {
+		return fmt.Errorf("failed to find decks: %w", err)
+	}
+
+	byID := make(map[int64]*deck.Deck, len(decks))
+	for _, d := range decks {
+		byID[d.GetID()] = d
+	}
+	t.paths = make(map[string]int64, len(decks))
+	for _, d := range decks {
+		path := d.GetName()
+		seen := map[int64]bool{d.GetID(): true}
+		for parent := d.GetParentID(); parent != nil; {
+			p := byID[*parent]
+			if p == nil || seen[p.GetID()] {
+				break
+			}
+			seen[p.GetID()] = true
+			path = p.GetName() + "::" + path
+			parent = p.GetParentID()
+		}
+		t.paths[path] = d.GetID()
+	}
+	return nil
+}
+
+// normalizeDeckName trims the levels of a full deck name and drops empty ones
+func normalizeDeckName(fullName string) string {
+	parts := strings.Split(fullName, "::")
+	names := parts[:0]
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+	return strings.Join(names, "::")
+}
@@ -0,0 +1,574 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	mediaSvc "github.com/felipesantos/anki-backend/core/services/media"
+)
+
+// guidNamespace derives note GUIDs from Anki GUIDs, which are not UUIDs
+var guidNamespace = uuid.MustParse("5c0b7f0e-6d2a-4f43-9a55-2f1d0c7e8b31")
+
+// ImportService implements IImportService
+type ImportService struct {
+	noteTypeRepo secondary.INoteTypeRepository
+	deckRepo     secondary.IDeckRepository
+	noteRepo     secondary.INoteRepository
+	cardRepo     secondary.ICardRepository
+	reviewRepo   secondary.IReviewRepository
+	mediaRepo    secondary.IMediaRepository
+	mediaService primary.IMediaService
+	noteService  primary.INoteService
+	tm           secondary.ITransactionManager
+}
+
+// NewImportService creates a new ImportService instance
+func NewImportService(
+	noteTypeRepo secondary.INoteTypeRepository,
+	deckRepo secondary.IDeckRepository,
+	noteRepo secondary.INoteRepository,
+	cardRepo secondary.ICardRepository,
+	reviewRepo secondary.IReviewRepository,
+	mediaRepo secondary.IMediaRepository,
+	mediaService primary.IMediaService,
+	noteService primary.INoteService,
+	tm secondary.ITransactionManager,
+) primary.IImportService {
+	return &ImportService{
+		noteTypeRepo: noteTypeRepo,
+		deckRepo:     deckRepo,
+		noteRepo:     noteRepo,
+		cardRepo:     cardRepo,
+		reviewRepo:   reviewRepo,
+		mediaRepo:    mediaRepo,
+		mediaService: mediaService,
+		noteService:  noteService,
+		tm:           tm,
+	}
+}
+
+// ImportPackage imports an Anki deck package (.apkg) or collection package (.colpkg)
+// A collection package is merged like a deck package rather than replacing the collection
+func (s *ImportService) ImportPackage(ctx context.Context, userID int64, file io.ReaderAt, size int64, opts primary.ImportOptions) (*primary.ImportResult, error) {
+	mode := opts.UpdateMode
+	switch mode {
+	case "":
+		mode = primary.ImportUpdateIfNewer
+	case primary.ImportUpdateSkip, primary.ImportUpdateIfNewer, primary.ImportUpdateAlways:
+	default:
+		return nil, fmt.Errorf("invalid update mode: %s (must be 'skip', 'if_newer' or 'always')", mode)
+	}
+
+	pkg, err := readPackage(ctx, file, size)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &primary.ImportResult{}
+	err = s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		imp := &packageImport{
+			ImportService: s,
+			ctx:           txCtx,
+			userID:        userID,
+			mode:          mode,
+			pkg:           pkg,
+			result:        result,
+			now:           time.Now(),
+			decks:         newDeckTree(s.deckRepo, userID),
+			mediaNames:    make(map[string]string),
+			noteTypes:     make(map[int64]*notetype.NoteType),
+			deckIDs:       make(map[int64]int64),
+			notes:         make(map[int64]*importedNote),
+			cardIDs:       make(map[int64]int64),
+		}
+		return imp.run()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// packageImport is the state of one package import, mapping Anki IDs to the collection's
+type packageImport struct {
+	*ImportService
+	ctx    context.Context
+	userID int64
+	mode   string
+	pkg    *ankiPackage
+	result *primary.ImportResult
+	now    time.Time
+
+	decks      *deckTree
+	mediaNames map[string]string            // Package filename -> collection filename, when they differ
+	noteTypes  map[int64]*notetype.NoteType // Anki note type ID -> note type
+	deckIDs    map[int64]int64              // Anki deck ID -> deck ID
+	notes      map[int64]*importedNote      // Anki note ID -> note
+	cardIDs    map[int64]int64              // Anki card ID -> card ID, for added cards only
+}
+
+// importedNote is a note of the package and the card templates it already has
+type importedNote struct {
+	id       int64
+	existing map[int]bool
+}
+
+func (imp *packageImport) run() error {
+	if err := imp.importMedia(); err != nil {
+		return err
+	}
+	if err := imp.importNoteTypes(); err != nil {
+		return err
+	}
+	if err := imp.importNotes(); err != nil {
+		return err
+	}
+	if err := imp.importCards(); err != nil {
+		return err
+	}
+	if err := imp.importRevlog(); err != nil {
+		return err
+	}
+	imp.result.DecksAdded = imp.decks.added
+	return nil
+}
+
+// importMedia stores the media files that are not in the collection yet
+// A file whose content already exists is referenced by the existing filename; a new file whose name is
+// taken by other content is renamed with its hash
+func (imp *packageImport) importMedia() error {
+	for _, m := range imp.pkg.media {
+		if strings.ContainsAny(m.name, `/\`) || m.name == "." || m.name == ".." {
+			continue
+		}
+		data, err := m.read()
+		if err != nil {
+			return fmt.Errorf("failed to read media %s: %w", m.name, err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		hash := media.ContentHash(data)
+
+		existing, err := imp.mediaRepo.FindByHash(imp.ctx, imp.userID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to find media: %w", err)
+		}
+		if existing != nil {
+			if existing.GetFilename() != m.name {
+				imp.mediaNames[m.name] = existing.GetFilename()
+			}
+			continue
+		}
+
+		// Uploads go through the media service so that the user's media quota applies to imports too
+		entity, err := imp.mediaService.Upload(imp.ctx, imp.userID, m.name, bytes.NewReader(data))
+		if errors.Is(err, mediaSvc.ErrInvalidFilename) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import media %s: %w", m.name, err)
+		}
+		if entity.GetFilename() != m.name {
+			imp.mediaNames[m.name] = entity.GetFilename()
+		}
+		imp.result.MediaAdded++
+	}
+	return nil
+}
+
+// importNoteTypes maps the note types used by the package's notes
+// A note type with the same name and fields is reused; otherwise a copy is added under a numbered name
+func (imp *packageImport) importNoteTypes() error {
+	used := make(map[int64]bool)
+	for _, n := range imp.pkg.notes {
+		used[n.mid] = true
+	}
+	ids := make([]int64, 0, len(used))
+	for id := range used {
+		if imp.pkg.noteTypes[id] != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		ant := imp.pkg.noteTypes[id]
+		for i := 1; ; i++ {
+			name := ant.name
+			if i > 1 {
+				name = fmt.Sprintf("%s (%d)", ant.name, i)
+			}
+			existing, err := imp.noteTypeRepo.FindByName(imp.ctx, imp.userID, name)
+			if err != nil {
+				return fmt.Errorf("failed to find note type: %w", err)
+			}
+			if existing == nil {
+				nt, err := imp.addNoteType(ant, name)
+				if err != nil {
+					return err
+				}
+				imp.noteTypes[id] = nt
+				break
+			}
+			if sameFieldNames(existing, ant) {
+				imp.noteTypes[id] = existing
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (imp *packageImport) addNoteType(ant *ankiNoteType, name string) (*notetype.NoteType, error) {
+	fields := make([]map[string]interface{}, len(ant.fields))
+	for i, f := range ant.fields {
+		fields[i] = map[string]interface{}{"name": f.name, "ord": i, "sticky": f.sticky, "rtl": f.rtl, "font": f.font, "size": f.size}
+	}
+	cardTypes := make([]map[string]interface{}, len(ant.templates))
+	templates := make([]map[string]interface{}, len(ant.templates))
+	for i, t := range ant.templates {
+		cardTypes[i] = map[string]interface{}{"name": t.name, "ord": i}
+		templates[i] = map[string]interface{}{"name": t.name, "qfmt": t.qfmt, "afmt": t.afmt, "bqfmt": t.bqfmt, "bafmt": t.bafmt, "css": ant.css}
+		if ant.cloze {
+			cardTypes[i]["cloze"] = true
+		}
+	}
+	fieldsJSON, _ := json.Marshal(fields)
+	cardTypesJSON, _ := json.Marshal(cardTypes)
+	templatesJSON, _ := json.Marshal(templates)
+
+	nt, err := notetype.NewBuilder().
+		WithUserID(imp.userID).
+		WithName(name).
+		WithFieldsJSON(string(fieldsJSON)).
+		WithCardTypesJSON(string(cardTypesJSON)).
+		WithTemplatesJSON(string(templatesJSON)).
+		WithCreatedAt(imp.now).
+		WithUpdatedAt(imp.now).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build note type entity: %w", err)
+	}
+	if err := imp.noteTypeRepo.Save(imp.ctx, imp.userID, nt); err != nil {
+		return nil, fmt.Errorf("failed to save note type %s: %w", name, err)
+	}
+	imp.result.NoteTypesAdded++
+	return nt, nil
+}
+
+// sameFieldNames reports whether a note type has the fields of an Anki note type, in the same order
+func sameFieldNames(nt *notetype.NoteType, ant *ankiNoteType) bool {
+	names := nt.GetFieldNames()
+	if len(names) != len(ant.fields) {
+		return false
+	}
+	for i, name := range names {
+		if name != ant.fields[i].name {
+			return false
+		}
+	}
+	return true
+}
+
+// importNotes adds the package's notes, or updates the notes with the same GUID according to the update mode
+// Notes whose GUID matches a note of another note type are skipped, as their fields cannot be mapped
+func (imp *packageImport) importNotes() error {
+	for _, an := range imp.pkg.notes {
+		nt := imp.noteTypes[an.mid]
+		if nt == nil {
+			continue
+		}
+
+		fieldsJSON, err := imp.fieldsJSON(an, imp.pkg.noteTypes[an.mid])
+		if err != nil {
+			return err
+		}
+		modified := time.Unix(an.mod, 0)
+
+		existing, err := imp.findNote(imp.ctx, imp.userID, an.guid)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			guid, _ := valueobjects.NewGUID(NoteGUID(imp.userID, an.guid))
+			n, err := note.NewBuilder().
+				WithUserID(imp.userID).
+				WithGUID(guid).
+				WithNoteTypeID(nt.GetID()).
+				WithFieldsJSON(fieldsJSON).
+				WithTags(an.tags).
+				WithCreatedAt(time.UnixMilli(an.id)).
+				WithUpdatedAt(modified).
+				Build()
+			if err != nil {
+				return fmt.Errorf("failed to build note entity: %w", err)
+			}
+			if err := imp.noteRepo.Save(imp.ctx, imp.userID, n); err != nil {
+				return fmt.Errorf("failed to save note: %w", err)
+			}
+			imp.notes[an.id] = &importedNote{id: n.GetID(), existing: map[int]bool{}}
+			imp.result.NotesAdded++
+			continue
+		}
+
+		if existing.GetNoteTypeID() != nt.GetID() {
+			imp.result.NotesSkipped++
+			continue
+		}
+		cards, err := imp.cardRepo.FindByNoteID(imp.ctx, imp.userID, existing.GetID())
+		if err != nil {
+			return fmt.Errorf("failed to find cards: %w", err)
+		}
+		ords := make(map[int]bool, len(cards))
+		for _, c := range cards {
+			ords[c.GetCardTypeID()] = true
+		}
+		imp.notes[an.id] = &importedNote{id: existing.GetID(), existing: ords}
+
+		if imp.mode == primary.ImportUpdateSkip || (imp.mode == primary.ImportUpdateIfNewer && !modified.After(existing.GetUpdatedAt())) {
+			imp.result.NotesSkipped++
+			continue
+		}
+		existing.SetFieldsJSON(fieldsJSON)
+		existing.SetTags(an.tags)
+		existing.SetUpdatedAt(modified)
+		if err := imp.noteRepo.Update(imp.ctx, imp.userID, existing.GetID(), existing); err != nil {
+			return fmt.Errorf("failed to update note: %w", err)
+		}
+		imp.result.NotesUpdated++
+	}
+	return nil
+}
+
+// findNote finds the note imported from an Anki GUID
+// GUIDs that are already UUIDs (files exported by this service) are also matched as they are
+func (s *ImportService) findNote(ctx context.Context, userID int64, ankiGUID string) (*note.Note, error) {
+	if guid, err := valueobjects.NewGUID(ankiGUID); err == nil {
+		n, err := s.noteRepo.FindByGUID(ctx, userID, guid.Value())
+		if err != nil || n != nil {
+			return n, err
+		}
+	}
+	return s.noteRepo.FindByGUID(ctx, userID, NoteGUID(userID, ankiGUID))
+}
+
+// NoteGUID derives the GUID of a note imported or synced from an Anki collection
+// GUIDs are unique across users, so the user is part of the name: each user importing the same
+// shared deck gets their own notes, and importing it again matches them
+func NoteGUID(userID int64, ankiGUID string) string {
+	return uuid.NewSHA1(guidNamespace, []byte(fmt.Sprintf("%d:%s", userID, ankiGUID))).String()
+}
+
+// fieldsJSON maps the note's field values to the note type's field names, renaming media references
+func (imp *packageImport) fieldsJSON(an *ankiNote, ant *ankiNoteType) (string, error) {
+	fields := make(map[string]string, len(ant.fields))
+	for i, f := range ant.fields {
+		value := ""
+		if i < len(an.fields) {
+			value = renameMedia(an.fields[i], imp.mediaNames)
+		}
+		fields[f.name] = value
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode fields: %w", err)
+	}
+	return string(data), nil
+}
+
+// mediaReference matches the filename of <img src=...> style attributes and [sound:...] tags
+var mediaReference = regexp.MustCompile(`(?i)(\bsrc=["']?)([^"'\s>]+)|(\[sound:)([^\]]+)`)
+
+// renameMedia replaces the references to renamed media files
+func renameMedia(text string, names map[string]string) string {
+	if len(names) == 0 {
+		return text
+	}
+	return mediaReference.ReplaceAllStringFunc(text, func(ref string) string {
+		m := mediaReference.FindStringSubmatch(ref)
+		prefix, name := m[1], m[2]
+		if m[3] != "" {
+			prefix, name = m[3], m[4]
+		}
+		if renamed, ok := names[name]; ok {
+			return strings.Replace(ref, prefix+name, prefix+renamed, 1)
+		}
+		return ref
+	})
+}
+
+// importCards adds the cards of imported notes that the collection does not have yet, with their scheduling
+// Cards in a filtered deck are moved back to their home deck
+func (imp *packageImport) importCards() error {
+	lastReviews := make(map[int64]int64)
+	for _, r := range imp.pkg.revlog {
+		if r.ease > 0 && r.id > lastReviews[r.cid] {
+			lastReviews[r.cid] = r.id
+		}
+	}
+
+	for _, ac := range imp.pkg.cards {
+		n := imp.notes[ac.nid]
+		if n == nil || n.existing[ac.ord] {
+			continue
+		}
+		deckID, err := imp.deckID(ac)
+		if err != nil {
+			return err
+		}
+
+		builder := card.NewBuilder().
+			WithNoteID(n.id).
+			WithCardTypeID(ac.ord).
+			WithDeckID(deckID).
+			WithInterval(max(ac.ivl, 0)).
+			WithEase(max(ac.factor, 1300)).
+			WithLapses(ac.lapses).
+			WithReps(ac.reps).
+			WithFlag(ac.flags & 7).
+			WithSuspended(ac.queue == -1).
+			WithBuried(ac.queue == -2 || ac.queue == -3).
+			WithStability(ac.stability).
+			WithDifficulty(ac.difficulty).
+			WithCreatedAt(time.UnixMilli(ac.id)).
+			WithUpdatedAt(time.Unix(ac.mod, 0))
+		if ac.factor == 0 {
+			builder.WithEase(2500)
+		}
+		if last, ok := lastReviews[ac.id]; ok {
+			lastReview := time.UnixMilli(last)
+			builder.WithLastReviewAt(&lastReview)
+		}
+		imp.schedule(builder, ac)
+
+		c, err := builder.Build()
+		if err != nil {
+			return fmt.Errorf("failed to build card entity: %w", err)
+		}
+		if err := imp.cardRepo.Save(imp.ctx, imp.userID, c); err != nil {
+			return fmt.Errorf("failed to save card: %w", err)
+		}
+		imp.cardIDs[ac.id] = c.GetID()
+		imp.result.CardsAdded++
+	}
+	return nil
+}
+
+// schedule converts the card's queue position or due date
+// New cards keep their position; learning cards are due at a timestamp in seconds, or on a day number
+// counted from the collection creation like review cards
+func (imp *packageImport) schedule(builder *card.CardBuilder, ac *ankiCard) {
+	due := ac.due
+	if ac.odid != 0 && ac.odue != 0 {
+		due = ac.odue
+	}
+
+	switch ac.ctype {
+	case 0:
+		builder.WithState(valueobjects.CardStateNew).WithPosition(int(max(due, 0))).WithDue(imp.now.UnixMilli())
+		return
+	case 1:
+		builder.WithState(valueobjects.CardStateLearn)
+	case 3:
+		builder.WithState(valueobjects.CardStateRelearn)
+	default:
+		builder.WithState(valueobjects.CardStateReview)
+	}
+
+	if ac.ctype != 2 && due > 1_000_000_000 {
+		builder.WithDue(due * 1000)
+		return
+	}
+	builder.WithDue(time.Unix(imp.pkg.crt, 0).AddDate(0, 0, int(due)).UnixMilli())
+}
+
+// deckID returns the home deck of a card, creating the deck and its parents on first use
+func (imp *packageImport) deckID(ac *ankiCard) (int64, error) {
+	ankiDeckID := ac.did
+	if ac.odid != 0 {
+		ankiDeckID = ac.odid
+	}
+	if id, ok := imp.deckIDs[ankiDeckID]; ok {
+		return id, nil
+	}
+
+	name := "Default"
+	if d := imp.pkg.decks[ankiDeckID]; d != nil && strings.TrimSpace(d.name) != "" {
+		name = d.name
+	}
+	id, err := imp.decks.ensure(imp.ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	imp.deckIDs[ankiDeckID] = id
+	return id, nil
+}
+
+// importRevlog adds the review history of the added cards
+func (imp *packageImport) importRevlog() error {
+	for _, ar := range imp.pkg.revlog {
+		cardID, ok := imp.cardIDs[ar.cid]
+		if !ok {
+			continue
+		}
+
+		reviewType := valueobjects.ReviewTypeReview
+		switch {
+		case ar.rtype >= 4 || ar.ease == 0:
+			reviewType = valueobjects.ReviewTypeManual
+		case ar.rtype == 0:
+			reviewType = valueobjects.ReviewTypeLearn
+		case ar.rtype == 2:
+			reviewType = valueobjects.ReviewTypeRelearn
+		case ar.rtype == 3:
+			reviewType = valueobjects.ReviewTypeCram
+		}
+		rating := ar.ease
+		if reviewType == valueobjects.ReviewTypeManual {
+			rating = 0
+		}
+		// Answers without an interval are malformed in Anki as well
+		if ar.ivl == 0 && reviewType != valueobjects.ReviewTypeManual && reviewType != valueobjects.ReviewTypeCram {
+			continue
+		}
+
+		r, err := review.NewBuilder().
+			WithCardID(cardID).
+			WithRating(rating).
+			WithInterval(ar.ivl).
+			WithEase(ar.factor).
+			WithTimeMs(max(ar.timeMs, 0)).
+			WithType(reviewType).
+			WithCreatedAt(time.UnixMilli(ar.id)).
+			Build()
+		if err != nil {
+			return fmt.Errorf("failed to build review entity: %w", err)
+		}
+		if err := imp.reviewRepo.Save(imp.ctx, imp.userID, r); err != nil {
+			return fmt.Errorf("failed to save review: %w", err)
+		}
+		imp.result.ReviewsAdded++
+	}
+	return nil
+}
@@ -0,0 +1,588 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+)
+
+// firstFieldPageSize is the number of notes loaded at a time to index first fields for duplicate detection
+const firstFieldPageSize = 500
+
+// exportSchedulingLine starts the card lines the text export writes after a note when scheduling is included
+const exportSchedulingLine = "CARD"
+
+// textLayout is the layout of a text file, from the import options and the file's header directives
+type textLayout struct {
+	primary.TextImportOptions
+	delimiter rune
+	noteType  string // Note type name or ID from #notetype:
+	deck      string // Deck name or ID from #deck:
+}
+
+// ImportText imports notes from a CSV or TSV file
+// Rows matching a note by GUID, or by first field within the note type, are updated, skipped or added
+// according to the duplicate mode
+func (s *ImportService) ImportText(ctx context.Context, userID int64, file io.Reader, opts primary.TextImportOptions) (*primary.TextImportResult, error) {
+	switch opts.DuplicateMode {
+	case "":
+		opts.DuplicateMode = primary.ImportDuplicateUpdate
+	case primary.ImportDuplicateUpdate, primary.ImportDuplicateSkip, primary.ImportDuplicateAdd:
+	default:
+		return nil, fmt.Errorf("invalid duplicate mode: %s (must be 'update', 'skip' or 'add')", opts.DuplicateMode)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	layout := &textLayout{TextImportOptions: opts}
+	body, headerLines, err := layout.readDirectives(data)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &textImport{
+		ImportService: s,
+		ctx:           ctx,
+		userID:        userID,
+		layout:        layout,
+		result:        &primary.TextImportResult{Rows: []primary.TextImportRow{}},
+		decks:         newDeckTree(s.deckRepo, userID),
+		noteTypes:     make(map[string]*notetype.NoteType),
+		firstFields:   make(map[int64]map[string]int64),
+	}
+	if err := imp.resolveDefaults(); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.Comma = layout.delimiter
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	headerPending := layout.HeaderRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			imp.report(headerLines+parseErr.StartLine, primary.TextImportRowError, 0, parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if headerPending {
+			headerPending = false
+			layout.setColumns(record)
+			continue
+		}
+		if err := imp.importRow(headerLines+line, record); err != nil {
+			return nil, err
+		}
+	}
+
+	return imp.result, nil
+}
+
+// readDirectives reads the #key:value header lines at the top of the file and returns the rest
+// The delimiter is guessed from the first row when neither the options nor the header set it
+func (l *textLayout) readDirectives(data []byte) ([]byte, int, error) {
+	var columns string
+	lines := 0
+	for len(data) > 0 && data[0] == '#' {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			end = len(data) - 1
+		}
+		line := strings.TrimRight(string(data[1:end+1]), "\r\n")
+		data = data[end+1:]
+		lines++
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "separator":
+			l.Delimiter = value
+		case "html":
+			l.HTML, err = strconv.ParseBool(value)
+		case "tags":
+			l.Tags = append(l.Tags, strings.Fields(value)...)
+		case "columns":
+			columns = value
+		case "notetype":
+			l.noteType = value
+		case "deck":
+			l.deck = value
+		case "notetype column":
+			l.NoteTypeColumn, err = strconv.Atoi(value)
+		case "deck column":
+			l.DeckColumn, err = strconv.Atoi(value)
+		case "tags column":
+			l.TagsColumn, err = strconv.Atoi(value)
+		case "guid column":
+			l.GUIDColumn, err = strconv.Atoi(value)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid header directive #%s: %s", key, value)
+		}
+	}
+
+	delimiter, err := parseDelimiter(l.Delimiter, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	l.delimiter = delimiter
+	if columns != "" {
+		l.setColumns(strings.Split(columns, string(delimiter)))
+	}
+	for _, column := range []int{l.GUIDColumn, l.TagsColumn, l.DeckColumn, l.NoteTypeColumn} {
+		if column < 0 {
+			return nil, 0, fmt.Errorf("invalid column number: %d", column)
+		}
+	}
+	return data, lines, nil
+}
+
+// parseDelimiter resolves a delimiter name or character, guessing it from the first row when empty
+func parseDelimiter(name string, data []byte) (rune, error) {
+	switch strings.ToLower(name) {
+	case "":
+		firstRow, _, _ := bytes.Cut(data, []byte("\n"))
+		for _, candidate := range []byte{'\t', '|', ';', ','} {
+			if bytes.IndexByte(firstRow, candidate) >= 0 {
+				return rune(candidate), nil
+			}
+		}
+		return '\t', nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "pipe":
+		return '|', nil
+	case "colon":
+		return ':', nil
+	case "space":
+		return ' ', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(name)
+	if size != len(name) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid delimiter: %s", name)
+	}
+	return r, nil
+}
+
+// setColumns names the columns, from #columns: or the header row
+// Unless set explicitly, columns named GUID, Tags, Deck and Notetype are the special columns, as written by
+// the text export; the others fill the fields of the same name
+func (l *textLayout) setColumns(names []string) {
+	mapFields := len(l.FieldMap) == 0
+	if mapFields {
+		l.FieldMap = make([]string, len(names))
+	}
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		column := i + 1
+		special := true
+		switch strings.ToLower(name) {
+		case "guid":
+			l.GUIDColumn = orColumn(l.GUIDColumn, column)
+		case "tags":
+			l.TagsColumn = orColumn(l.TagsColumn, column)
+		case "deck":
+			l.DeckColumn = orColumn(l.DeckColumn, column)
+		case "notetype", "note type":
+			l.NoteTypeColumn = orColumn(l.NoteTypeColumn, column)
+		default:
+			special = false
+		}
+		if mapFields && !special {
+			l.FieldMap[i] = name
+		}
+	}
+}
+
+func orColumn(column, fallback int) int {
+	if column != 0 {
+		return column
+	}
+	return fallback
+}
+
+// isSpecialColumn reports whether a 1-based column holds GUIDs, tags, decks or note types
+func (l *textLayout) isSpecialColumn(column int) bool {
+	return column == l.GUIDColumn || column == l.TagsColumn || column == l.DeckColumn || column == l.NoteTypeColumn
+}
+
+// textImport is the state of one text import
+type textImport struct {
+	*ImportService
+	ctx    context.Context
+	userID int64
+	layout *textLayout
+	result *primary.TextImportResult
+
+	defaultNoteType *notetype.NoteType
+	defaultDeckID   int64
+	decks           *deckTree
+	noteTypes       map[string]*notetype.NoteType // Note type column value -> note type
+	firstFields     map[int64]map[string]int64    // Note type ID -> first field value -> note ID
+}
+
+// resolveDefaults finds the note type and deck of rows that do not name their own
+func (imp *textImport) resolveDefaults() error {
+	switch {
+	case imp.layout.noteType != "":
+		nt, err := imp.findNoteType(imp.layout.noteType)
+		if err != nil {
+			return err
+		}
+		if nt == nil {
+			return fmt.Errorf("note type not found: %s", imp.layout.noteType)
+		}
+		imp.defaultNoteType = nt
+	case imp.layout.NoteTypeID != 0:
+		nt, err := imp.noteTypeRepo.FindByID(imp.ctx, imp.userID, imp.layout.NoteTypeID)
+		if err != nil || nt == nil {
+			return fmt.Errorf("note type not found")
+		}
+		imp.defaultNoteType = nt
+	case imp.layout.NoteTypeColumn == 0:
+		return fmt.Errorf("note type is required")
+	}
+
+	switch {
+	case imp.layout.deck != "":
+		id, err := imp.resolveDeck(imp.layout.deck)
+		if err != nil {
+			return err
+		}
+		imp.defaultDeckID = id
+	case imp.layout.DeckID != 0:
+		d, err := imp.deckRepo.FindByID(imp.ctx, imp.userID, imp.layout.DeckID)
+		if err != nil || d == nil {
+			return fmt.Errorf("deck not found")
+		}
+		imp.defaultDeckID = d.GetID()
+	}
+	return nil
+}
+
+// importRow adds, updates or skips the note of one row
+// Only failures to look up existing content abort the import; other failures are reported on the row
+func (imp *textImport) importRow(line int, record []string) error {
+	l := imp.layout
+	cell := func(column int) string {
+		if column < 1 || column > len(record) {
+			return ""
+		}
+		return record[column-1]
+	}
+
+	guid := strings.TrimSpace(cell(l.GUIDColumn))
+	if l.GUIDColumn != 0 && guid == exportSchedulingLine {
+		imp.report(line, primary.TextImportRowSkipped, 0, "card scheduling line of a text export")
+		return nil
+	}
+
+	nt := imp.defaultNoteType
+	if value := strings.TrimSpace(cell(l.NoteTypeColumn)); value != "" {
+		var err error
+		if nt, err = imp.findNoteType(value); err != nil {
+			return err
+		}
+	}
+	if nt == nil {
+		imp.report(line, primary.TextImportRowError, 0, "note type not found")
+		return nil
+	}
+
+	// Fields of the row, only those filled by a column
+	names := nt.GetFieldNames()
+	values := make(map[string]string, len(names))
+	if len(l.FieldMap) > 0 {
+		for i, name := range l.FieldMap {
+			if name != "" && !l.isSpecialColumn(i+1) && containsString(names, name) {
+				values[name] = imp.fieldValue(cell(i + 1))
+			}
+		}
+	} else {
+		field := 0
+		for column := 1; column <= len(record) && field < len(names); column++ {
+			if !l.isSpecialColumn(column) {
+				values[names[field]] = imp.fieldValue(cell(column))
+				field++
+			}
+		}
+	}
+
+	tags := append([]string{}, l.Tags...)
+	if l.TagsColumn != 0 {
+		tags = append(strings.Fields(cell(l.TagsColumn)), tags...)
+	}
+
+	if l.DuplicateMode != primary.ImportDuplicateAdd {
+		existingID, reason, err := imp.findExisting(nt, names, values, guid)
+		if err != nil {
+			return err
+		}
+		switch {
+		case existingID != 0 && l.DuplicateMode == primary.ImportDuplicateSkip:
+			imp.report(line, primary.TextImportRowSkipped, existingID, reason)
+			return nil
+		case existingID != 0:
+			return imp.updateNote(line, existingID, values, tags)
+		}
+	}
+
+	// Decks only matter to the cards of added notes
+	deckID := imp.defaultDeckID
+	if value := strings.TrimSpace(cell(l.DeckColumn)); value != "" {
+		var err error
+		if deckID, err = imp.resolveDeck(value); err != nil {
+			return err
+		}
+	}
+	if deckID == 0 {
+		var err error
+		if deckID, err = imp.decks.ensure(imp.ctx, "Default"); err != nil {
+			return err
+		}
+	}
+
+	fields := make(map[string]string, len(names))
+	for _, name := range names {
+		fields[name] = values[name]
+	}
+	fieldsJSON, _ := json.Marshal(fields)
+	n, err := imp.noteService.Create(imp.ctx, imp.userID, nt.GetID(), deckID, string(fieldsJSON), uniqueTags(tags))
+	if err != nil {
+		imp.report(line, primary.TextImportRowError, 0, err.Error())
+		return nil
+	}
+	if index := imp.firstFields[nt.GetID()]; index != nil && len(names) > 0 {
+		if first := strings.TrimSpace(fields[names[0]]); first != "" {
+			index[first] = n.GetID()
+		}
+	}
+	imp.report(line, primary.TextImportRowAdded, n.GetID(), "")
+	return nil
+}
+
+// findExisting finds the note a row duplicates, by GUID or else by first field within its note type
+func (imp *textImport) findExisting(nt *notetype.NoteType, names []string, values map[string]string, guid string) (int64, string, error) {
+	if guid != "" {
+		n, err := imp.findNote(imp.ctx, imp.userID, guid)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to find note: %w", err)
+		}
+		if n != nil && n.GetNoteTypeID() == nt.GetID() {
+			return n.GetID(), "note with the same GUID exists", nil
+		}
+	}
+
+	byFirstField, err := imp.loadFirstFields(nt, names)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(names) == 0 {
+		return 0, "", nil
+	}
+	if id, ok := byFirstField[strings.TrimSpace(values[names[0]])]; ok {
+		return id, "note with the same first field exists", nil
+	}
+	return 0, "", nil
+}
+
+// loadFirstFields indexes the notes of a note type by first field value, keeping the oldest note of a value
+func (imp *textImport) loadFirstFields(nt *notetype.NoteType, names []string) (map[string]int64, error) {
+	if index, ok := imp.firstFields[nt.GetID()]; ok {
+		return index, nil
+	}
+	index := make(map[string]int64)
+	imp.firstFields[nt.GetID()] = index
+	if len(names) == 0 {
+		return index, nil
+	}
+
+	for offset := 0; ; offset += firstFieldPageSize {
+		notes, err := imp.noteRepo.FindByNoteTypeID(imp.ctx, imp.userID, nt.GetID(), firstFieldPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find notes: %w", err)
+		}
+		for _, n := range notes {
+			var fields map[string]interface{}
+			if err := json.Unmarshal([]byte(n.GetFieldsJSON()), &fields); err != nil {
+				continue
+			}
+			value := strings.TrimSpace(fmt.Sprintf("%v", fields[names[0]]))
+			if _, ok := index[value]; !ok && fields[names[0]] != nil && value != "" {
+				index[value] = n.GetID()
+			}
+		}
+		if len(notes) < firstFieldPageSize {
+			return index, nil
+		}
+	}
+}
+
+// updateNote overwrites the fields filled by the row; the tags column, when present, replaces the note's tags
+func (imp *textImport) updateNote(line int, id int64, values map[string]string, tags []string) error {
+	existing, err := imp.noteRepo.FindByID(imp.ctx, imp.userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to find note: %w", err)
+	}
+	if existing == nil {
+		imp.report(line, primary.TextImportRowError, 0, "note not found")
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(existing.GetFieldsJSON()), &fields); err != nil || fields == nil {
+		fields = make(map[string]interface{})
+	}
+	changed := false
+	for name, value := range values {
+		if current, ok := fields[name]; !ok || fmt.Sprintf("%v", current) != value {
+			fields[name] = value
+			changed = true
+		}
+	}
+	if imp.layout.TagsColumn == 0 {
+		tags = append(append([]string{}, existing.GetTags()...), tags...)
+	}
+	tags = uniqueTags(tags)
+	if !changed && sameTags(existing.GetTags(), tags) {
+		imp.report(line, primary.TextImportRowSkipped, id, "note is unchanged")
+		return nil
+	}
+
+	fieldsJSON, _ := json.Marshal(fields)
+	if _, err := imp.noteService.Update(imp.ctx, imp.userID, id, string(fieldsJSON), tags); err != nil {
+		imp.report(line, primary.TextImportRowError, id, err.Error())
+		return nil
+	}
+	imp.report(line, primary.TextImportRowUpdated, id, "")
+	return nil
+}
+
+// findNoteType finds a note type by name, or else by ID
+func (imp *textImport) findNoteType(value string) (*notetype.NoteType, error) {
+	if nt, ok := imp.noteTypes[value]; ok {
+		return nt, nil
+	}
+	nt, err := imp.noteTypeRepo.FindByName(imp.ctx, imp.userID, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find note type: %w", err)
+	}
+	if nt == nil {
+		if id, parseErr := strconv.ParseInt(value, 10, 64); parseErr == nil {
+			// An unknown ID is reported on the rows rather than aborting the import
+			nt, _ = imp.noteTypeRepo.FindByID(imp.ctx, imp.userID, id)
+		}
+	}
+	imp.noteTypes[value] = nt
+	return nt, nil
+}
+
+// resolveDeck finds a deck by full name, or else by ID, and creates it when neither matches
+func (imp *textImport) resolveDeck(value string) (int64, error) {
+	id, ok, err := imp.decks.find(imp.ctx, value)
+	if err != nil || ok {
+		return id, err
+	}
+	if id, parseErr := strconv.ParseInt(value, 10, 64); parseErr == nil {
+		if d, _ := imp.deckRepo.FindByID(imp.ctx, imp.userID, id); d != nil {
+			return d.GetID(), nil
+		}
+	}
+	return imp.decks.ensure(imp.ctx, value)
+}
+
+// fieldValue converts a cell to a field value, escaping HTML unless the file contains HTML
+func (imp *textImport) fieldValue(value string) string {
+	if imp.layout.HTML {
+		return value
+	}
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	return strings.ReplaceAll(html.EscapeString(value), "\n", "<br>")
+}
+
+func (imp *textImport) report(line int, status string, noteID int64, message string) {
+	imp.result.Rows = append(imp.result.Rows, primary.TextImportRow{Line: line, Status: status, NoteID: noteID, Message: message})
+	switch status {
+	case primary.TextImportRowAdded:
+		imp.result.Added++
+	case primary.TextImportRowUpdated:
+		imp.result.Updated++
+	case primary.TextImportRowSkipped:
+		imp.result.Skipped++
+	case primary.TextImportRowError:
+		imp.result.Errors++
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueTags drops repeated tags, keeping the first occurrence
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, tag := range a {
+		set[tag] = true
+	}
+	for _, tag := range b {
+		if !set[tag] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,370 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
+)
+
+var (
+	// ErrMediaSyncDisabled is returned when the user turned sync_audio_and_images off
+	ErrMediaSyncDisabled = errors.New("media sync is disabled in the user's preferences")
+	// ErrInvalidMediaZip is returned when an uploaded zip or its _meta list can't be read
+	ErrInvalidMediaZip = errors.New("invalid media zip")
+)
+
+const (
+	// mediaSyncChangesBatch is the number of changes returned by a Changes call, as Anki's own server does
+	mediaSyncChangesBatch = 250
+	// mediaSyncZipTarget is the size after which a download zip takes no more files
+	mediaSyncZipTarget = 2_500_000
+	// mediaSyncMaxFiles is the number of files a download zip takes at most
+	mediaSyncMaxFiles = 25
+	// mediaSyncMaxUploadSize limits the uncompressed content of an upload zip
+	mediaSyncMaxUploadSize = 250 << 20
+	// mediaSyncMetaFile is the zip entry describing the other entries
+	mediaSyncMetaFile = "_meta"
+)
+
+// AnkiMediaSyncService implements IAnkiMediaSyncService
+type AnkiMediaSyncService struct {
+	repo        secondary.IMediaRepository
+	storageRepo secondary.IStorageRepository
+	syncRepo    secondary.IAnkiSyncRepository
+	prefsRepo   secondary.IUserPreferencesRepository
+	quotaBytes  int64 // Maximum total media size per user (0 = unlimited)
+}
+
+// NewAnkiMediaSyncService creates a new AnkiMediaSyncService instance
+func NewAnkiMediaSyncService(
+	repo secondary.IMediaRepository,
+	storageRepo secondary.IStorageRepository,
+	syncRepo secondary.IAnkiSyncRepository,
+	prefsRepo secondary.IUserPreferencesRepository,
+	quotaBytes int64,
+) primary.IAnkiMediaSyncService {
+	return &AnkiMediaSyncService{
+		repo:        repo,
+		storageRepo: storageRepo,
+		syncRepo:    syncRepo,
+		prefsRepo:   prefsRepo,
+		quotaBytes:  quotaBytes,
+	}
+}
+
+// Begin starts a media sync and returns the server's media USN
+// The collection moves to the next USN, so every change up to the returned one can be fetched
+func (s *AnkiMediaSyncService) Begin(ctx context.Context, userID int64) (*primary.AnkiMediaSyncBegin, error) {
+	periodic, err := s.checkEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usn, err := s.syncRepo.AdvanceUSN(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &primary.AnkiMediaSyncBegin{USN: usn, PeriodicSync: periodic}, nil
+}
+
+// Changes returns a batch of the media changes after lastUSN
+// Changes stamped with the current USN are left for the next sync, as more may follow with the same USN
+func (s *AnkiMediaSyncService) Changes(ctx context.Context, userID int64, lastUSN int) ([]primary.AnkiMediaChange, error) {
+	if _, err := s.checkEnabled(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.syncRepo.FindMediaChanges(ctx, userID, lastUSN, state.USN-1, mediaSyncChangesBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]primary.AnkiMediaChange, len(found))
+	for i, c := range found {
+		changes[i] = primary.AnkiMediaChange{Filename: c.Filename, USN: c.USN, SHA1: c.Hash}
+	}
+	return changes, nil
+}
+
+// Upload applies a zip of client changes in the order of its _meta list
+// A file whose content is unchanged only counts as processed; a replaced file is deleted and recorded again,
+// so clients see the deletion and the new content with the same USN
+func (s *AnkiMediaSyncService) Upload(ctx context.Context, userID int64, zipData []byte) (*primary.AnkiMediaUploadResult, error) {
+	if _, err := s.checkEnabled(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMediaZip, err)
+	}
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+
+	metaFile, ok := files[mediaSyncMetaFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidMediaZip, mediaSyncMetaFile)
+	}
+	budget := int64(mediaSyncMaxUploadSize)
+	metaData, err := readZipFile(metaFile, &budget)
+	if err != nil {
+		return nil, err
+	}
+	// Each entry is [filename, zip name], with a null or empty zip name for deletions
+	var meta [][2]*string
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMediaZip, err)
+	}
+
+	upload := &mediaUpload{userID: userID, used: -1}
+	for _, entry := range meta {
+		if entry[0] == nil {
+			return nil, fmt.Errorf("%w: entry without a filename", ErrInvalidMediaZip)
+		}
+		filename, ok := cleanFilename(*entry[0])
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidFilename, *entry[0])
+		}
+
+		if entry[1] == nil || *entry[1] == "" {
+			if err := s.deleteFile(ctx, userID, filename); err != nil {
+				return nil, err
+			}
+			upload.processed++
+			continue
+		}
+
+		f, ok := files[*entry[1]]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing entry %q", ErrInvalidMediaZip, *entry[1])
+		}
+		data, err := readZipFile(f, &budget)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addFile(ctx, upload, filename, data); err != nil {
+			return nil, err
+		}
+		upload.processed++
+	}
+
+	usn, err := s.syncRepo.AdvanceUSN(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &primary.AnkiMediaUploadResult{Processed: upload.processed, USN: usn}, nil
+}
+
+// mediaUpload tracks an upload in progress
+type mediaUpload struct {
+	userID    int64
+	processed int
+	used      int64 // Total media size, -1 until the quota is first checked
+}
+
+// addFile records the content of a client's file under its filename
+func (s *AnkiMediaSyncService) addFile(ctx context.Context, upload *mediaUpload, filename string, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: %s", ErrEmptyMedia, filename)
+	}
+	hash := media.ContentHash(data)
+
+	existing, err := s.repo.FindByFilename(ctx, upload.userID, filename)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.GetHash() == hash {
+		return nil
+	}
+
+	size := int64(len(data))
+	if s.quotaBytes > 0 {
+		if upload.used < 0 {
+			if upload.used, err = s.repo.TotalSize(ctx, upload.userID); err != nil {
+				return err
+			}
+		}
+		replaced := int64(0)
+		if existing != nil {
+			replaced = existing.GetSize()
+		}
+		if upload.used-replaced+size > s.quotaBytes {
+			return ErrQuotaExceeded
+		}
+		upload.used += size - replaced
+	}
+
+	mimeType := detectMimeType(filename, data[:min(512, len(data))])
+	storagePath := media.ContentPath(upload.userID, hash)
+	stored, err := s.storageRepo.Exists(ctx, storagePath)
+	if err != nil {
+		return fmt.Errorf("failed to check media storage: %w", err)
+	}
+	if !stored {
+		if _, err := s.storageRepo.Upload(ctx, bytes.NewReader(data), storagePath, mimeType); err != nil {
+			return fmt.Errorf("failed to upload media: %w", err)
+		}
+	}
+
+	if existing != nil {
+		if err := s.repo.Delete(ctx, upload.userID, existing.GetID()); err != nil {
+			return err
+		}
+	}
+
+	m, err := media.NewBuilder().
+		WithUserID(upload.userID).
+		WithFilename(filename).
+		WithHash(hash).
+		WithSize(size).
+		WithMimeType(mimeType).
+		WithStoragePath(storagePath).
+		WithCreatedAt(time.Now()).
+		Build()
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Save(ctx, upload.userID, m); err != nil && !errors.Is(err, media.ErrDuplicateContent) {
+		return err
+	}
+	// Identical content is recorded once per user, under the first name it was uploaded with
+	return nil
+}
+
+// deleteFile deletes a client's file; files the server doesn't have are ignored
+func (s *AnkiMediaSyncService) deleteFile(ctx context.Context, userID int64, filename string) error {
+	existing, err := s.repo.FindByFilename(ctx, userID, filename)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	return s.repo.Delete(ctx, userID, existing.GetID())
+}
+
+// Download returns a zip holding the first of the requested files
+// Files are named by their position in the zip, which keeps names with any characters safe
+func (s *AnkiMediaSyncService) Download(ctx context.Context, userID int64, filenames []string) ([]byte, error) {
+	if _, err := s.checkEnabled(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	meta := make(map[string]string)
+	size := 0
+	for i, filename := range filenames {
+		if i >= mediaSyncMaxFiles || (i > 0 && size >= mediaSyncZipTarget) {
+			break
+		}
+
+		m, err := s.repo.FindByFilename(ctx, userID, filename)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("media file %q: %w", filename, ownership.ErrResourceNotFound)
+		}
+		data, err := s.storageRepo.Download(ctx, m.GetStoragePath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to download media: %w", err)
+		}
+
+		zipName := strconv.Itoa(i)
+		w, err := zw.Create(zipName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		meta[zipName] = filename
+		size += len(data)
+	}
+
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	w, err := zw.Create(mediaSyncMetaFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(metaData); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sanity compares the client's media count with the server's
+func (s *AnkiMediaSyncService) Sanity(ctx context.Context, userID int64, localCount int) (string, error) {
+	if _, err := s.checkEnabled(ctx, userID); err != nil {
+		return "", err
+	}
+
+	mediaFiles, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(mediaFiles) != localCount {
+		return primary.AnkiMediaSanityFailed, nil
+	}
+	return primary.AnkiMediaSanityOK, nil
+}
+
+// checkEnabled returns ErrMediaSyncDisabled unless the user syncs audio and images, and whether
+// clients should sync media periodically
+// Users without preferences get the column defaults: media synced, but not periodically
+func (s *AnkiMediaSyncService) checkEnabled(ctx context.Context, userID int64) (bool, error) {
+	prefs, err := s.prefsRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if prefs == nil {
+		return false, nil
+	}
+	if !prefs.GetSyncAudioAndImages() {
+		return false, ErrMediaSyncDisabled
+	}
+	return prefs.GetPeriodicallySyncMedia(), nil
+}
+
+// readZipFile reads a zip entry, charging its size to the upload's budget
+func readZipFile(f *zip.File, budget *int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMediaZip, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, *budget+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMediaZip, err)
+	}
+	if int64(len(data)) > *budget {
+		return nil, fmt.Errorf("%w: content too large", ErrInvalidMediaZip)
+	}
+	*budget -= int64(len(data))
+	return data, nil
+}
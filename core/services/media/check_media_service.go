@@ -0,0 +1,321 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	checkdatabaselog "github.com/felipesantos/anki-backend/core/domain/entities/check_database_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// checkNotesPageSize is the number of notes scanned for media references at a time
+const checkNotesPageSize = 1000
+
+// MediaCheckService implements IMediaCheckService
+type MediaCheckService struct {
+	repo         secondary.IMediaRepository
+	noteRepo     secondary.INoteRepository
+	storageRepo  secondary.IStorageRepository
+	checkLogRepo secondary.ICheckDatabaseLogRepository
+	noteService  primary.INoteService
+}
+
+// NewMediaCheckService creates a new MediaCheckService instance
+func NewMediaCheckService(
+	repo secondary.IMediaRepository,
+	noteRepo secondary.INoteRepository,
+	storageRepo secondary.IStorageRepository,
+	checkLogRepo secondary.ICheckDatabaseLogRepository,
+	noteService primary.INoteService,
+) primary.IMediaCheckService {
+	return &MediaCheckService{
+		repo:         repo,
+		noteRepo:     noteRepo,
+		storageRepo:  storageRepo,
+		checkLogRepo: checkLogRepo,
+		noteService:  noteService,
+	}
+}
+
+// CheckMedia cross-references note fields, media files and storage, and logs the result
+func (s *MediaCheckService) CheckMedia(ctx context.Context, userID int64) (*primary.MediaCheckResult, error) {
+	start := time.Now()
+
+	result, err := s.check(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	logID, err := s.logResult(ctx, userID, result, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	result.LogID = logID
+	return result, nil
+}
+
+// DeleteUnused deletes the media files no note references and removes orphaned storage objects
+// Stored content is removed along with the last media file pointing to it
+func (s *MediaCheckService) DeleteUnused(ctx context.Context, userID int64) (int, error) {
+	result, err := s.CheckMedia(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	mediaFiles, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list media: %w", err)
+	}
+	pathUsers := make(map[string]int)
+	for _, m := range mediaFiles {
+		pathUsers[m.GetStoragePath()]++
+	}
+
+	deleted := 0
+	for _, m := range result.Unused {
+		if err := s.repo.Delete(ctx, userID, m.GetID()); err != nil {
+			return deleted, fmt.Errorf("failed to delete media %s: %w", m.GetFilename(), err)
+		}
+		deleted++
+
+		path := m.GetStoragePath()
+		if pathUsers[path]--; pathUsers[path] > 0 || path == "" {
+			continue
+		}
+		if err := s.storageRepo.Delete(ctx, path); err != nil {
+			return deleted, fmt.Errorf("failed to delete media content %s: %w", path, err)
+		}
+	}
+
+	for _, path := range result.Orphaned {
+		if err := s.storageRepo.Delete(ctx, path); err != nil {
+			return deleted, fmt.Errorf("failed to delete media content %s: %w", path, err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// TagMissing adds the missing-media tag to notes that reference missing media
+func (s *MediaCheckService) TagMissing(ctx context.Context, userID int64) (int, error) {
+	result, err := s.CheckMedia(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	noteIDs := make(map[int64]bool)
+	for _, missing := range result.Missing {
+		for _, noteID := range missing.NoteIDs {
+			noteIDs[noteID] = true
+		}
+	}
+
+	tagged := 0
+	for _, noteID := range sortedIDs(noteIDs) {
+		if err := s.noteService.AddTag(ctx, userID, noteID, media.MissingMediaTag); err != nil {
+			return tagged, fmt.Errorf("failed to tag note %d: %w", noteID, err)
+		}
+		tagged++
+	}
+	return tagged, nil
+}
+
+// check compares the media referenced by notes with the user's media files and stored objects
+func (s *MediaCheckService) check(ctx context.Context, userID int64) (*primary.MediaCheckResult, error) {
+	references, err := s.collectReferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaFiles, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media: %w", err)
+	}
+
+	linkedIDs, err := s.repo.FindLinkedIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[int64]bool, len(linkedIDs))
+	for _, id := range linkedIDs {
+		linked[id] = true
+	}
+
+	stored, err := s.storedObjects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &primary.MediaCheckResult{}
+	byName := make(map[string]*media.Media, len(mediaFiles))
+	paths := make(map[string]bool, len(mediaFiles))
+	for _, m := range mediaFiles {
+		byName[m.GetFilename()] = m
+		paths[strings.TrimPrefix(m.GetStoragePath(), "/")] = true
+
+		if suggested := media.NormalizeFilename(m.GetFilename()); suggested != "" && suggested != m.GetFilename() {
+			result.Misnamed = append(result.Misnamed, primary.MisnamedMedia{Media: m, SuggestedName: suggested})
+		}
+	}
+
+	// A reference matches a media file by its exact or its normalized name
+	used := make(map[int64]bool)
+	filenames := make([]string, 0, len(references))
+	for filename := range references {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		m := byName[filename]
+		if m == nil {
+			m = byName[media.NormalizeFilename(filename)]
+		}
+		if m != nil {
+			used[m.GetID()] = true
+			if stored[strings.TrimPrefix(m.GetStoragePath(), "/")] {
+				continue
+			}
+		}
+		result.Missing = append(result.Missing, primary.MissingMediaReference{
+			Filename: filename,
+			NoteIDs:  sortedIDs(references[filename]),
+		})
+	}
+
+	for _, m := range mediaFiles {
+		if !used[m.GetID()] && !linked[m.GetID()] && !strings.HasPrefix(m.GetFilename(), "_") {
+			result.Unused = append(result.Unused, m)
+		}
+	}
+	sort.Slice(result.Unused, func(i, j int) bool {
+		return result.Unused[i].GetFilename() < result.Unused[j].GetFilename()
+	})
+
+	for path := range stored {
+		if !paths[path] {
+			result.Orphaned = append(result.Orphaned, path)
+		}
+	}
+	sort.Strings(result.Orphaned)
+
+	return result, nil
+}
+
+// collectReferences maps each filename referenced in the user's note fields to the notes referencing it
+func (s *MediaCheckService) collectReferences(ctx context.Context, userID int64) (map[string]map[int64]bool, error) {
+	references := make(map[string]map[int64]bool)
+	for offset := 0; ; offset += checkNotesPageSize {
+		notes, err := s.noteRepo.FindByUserID(ctx, userID, checkNotesPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list notes: %w", err)
+		}
+
+		for _, n := range notes {
+			var fields map[string]interface{}
+			if err := json.Unmarshal([]byte(n.GetFieldsJSON()), &fields); err != nil {
+				continue // Skip notes whose fields can't be parsed
+			}
+			for _, value := range fields {
+				field, ok := value.(string)
+				if !ok {
+					continue
+				}
+				for _, filename := range media.ReferencedFilenames(field) {
+					if references[filename] == nil {
+						references[filename] = make(map[int64]bool)
+					}
+					references[filename][n.GetID()] = true
+				}
+			}
+		}
+
+		if len(notes) < checkNotesPageSize {
+			return references, nil
+		}
+	}
+}
+
+// storedObjects returns the paths of the objects stored under the user's media prefix
+func (s *MediaCheckService) storedObjects(ctx context.Context, userID int64) (map[string]bool, error) {
+	files, err := s.storageRepo.List(ctx, media.ContentPath(userID, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media storage: %w", err)
+	}
+
+	stored := make(map[string]bool, len(files))
+	for _, f := range files {
+		stored[strings.TrimPrefix(f.Path, "/")] = true
+	}
+	return stored, nil
+}
+
+// logResult records a check run in check_database_log and returns the entry's ID
+func (s *MediaCheckService) logResult(ctx context.Context, userID int64, result *primary.MediaCheckResult, elapsed time.Duration) (int64, error) {
+	details := mediaCheckDetails{
+		Check:    "media",
+		Unused:   []string{},
+		Missing:  []string{},
+		Misnamed: []string{},
+		Orphaned: result.Orphaned,
+	}
+	for _, m := range result.Unused {
+		details.Unused = append(details.Unused, m.GetFilename())
+	}
+	for _, missing := range result.Missing {
+		details.Missing = append(details.Missing, missing.Filename)
+	}
+	for _, misnamed := range result.Misnamed {
+		details.Misnamed = append(details.Misnamed, misnamed.Media.GetFilename())
+	}
+	if details.Orphaned == nil {
+		details.Orphaned = []string{}
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal media check details: %w", err)
+	}
+
+	executionTimeMs := int(elapsed.Milliseconds())
+	entry, err := checkdatabaselog.NewBuilder().
+		WithUserID(userID).
+		WithStatus(checkdatabaselog.CheckStatusCompleted).
+		WithIssuesFound(result.IssuesFound()).
+		WithIssuesDetails(string(detailsJSON)).
+		WithExecutionTimeMs(&executionTimeMs).
+		WithCreatedAt(time.Now()).
+		Build()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.checkLogRepo.Save(ctx, userID, entry); err != nil {
+		return 0, fmt.Errorf("failed to log media check: %w", err)
+	}
+	return entry.GetID(), nil
+}
+
+// mediaCheckDetails is the issues_details JSON of a media check log entry
+type mediaCheckDetails struct {
+	Check    string   `json:"check"`
+	Unused   []string `json:"unused"`
+	Missing  []string `json:"missing"`
+	Misnamed []string `json:"misnamed"`
+	Orphaned []string `json:"orphaned"`
+}
+
+// sortedIDs returns the IDs of a set in ascending order
+func sortedIDs(set map[int64]bool) []int64 {
+	ids := make([]int64, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
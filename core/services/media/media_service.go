@@ -1,29 +1,126 @@
 package media
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/media"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
 )
 
+var (
+	// ErrInvalidFilename is returned when a media filename is empty or contains a path
+	ErrInvalidFilename = errors.New("invalid media filename")
+	// ErrEmptyMedia is returned when an uploaded media file has no content
+	ErrEmptyMedia = errors.New("media file is empty")
+	// ErrQuotaExceeded is returned when an upload would exceed the user's media quota
+	ErrQuotaExceeded = errors.New("media quota exceeded")
+	// ErrFilenameTaken is returned when a media filename and its hash-suffixed alternatives all name other files
+	ErrFilenameTaken = errors.New("media filename already taken")
+)
+
+// contentURLExpiry is how long presigned media URLs stay valid
+const contentURLExpiry = 15 * time.Minute
+
 // MediaService implements IMediaService
 type MediaService struct {
-	repo secondary.IMediaRepository
+	repo        secondary.IMediaRepository
+	storageRepo secondary.IStorageRepository
+	quotaBytes  int64 // Maximum total media size per user (0 = unlimited)
 }
 
 // NewMediaService creates a new MediaService instance
-func NewMediaService(repo secondary.IMediaRepository) primary.IMediaService {
+func NewMediaService(repo secondary.IMediaRepository, storageRepo secondary.IStorageRepository, quotaBytes int64) primary.IMediaService {
 	return &MediaService{
-		repo: repo,
+		repo:        repo,
+		storageRepo: storageRepo,
+		quotaBytes:  quotaBytes,
 	}
 }
 
-// Create records a new media file
-func (s *MediaService) Create(ctx context.Context, userID int64, filename string, hash string, size int64, mimeType string, storagePath string) (*media.Media, error) {
-	now := time.Now()
+// Upload stores a media file's content under its content hash and records it
+// The content is spooled to a temporary file while hashing, so it is streamed to storage without being held in memory
+func (s *MediaService) Upload(ctx context.Context, userID int64, filename string, file io.Reader) (*media.Media, error) {
+	filename, ok := cleanFilename(filename)
+	if !ok {
+		return nil, ErrInvalidFilename
+	}
+
+	tmp, err := os.CreateTemp("", "anki-media-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha1.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media file: %w", err)
+	}
+	if size == 0 {
+		return nil, ErrEmptyMedia
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	// 1. Identical content is stored once
+	existing, err := s.repo.FindByHash(ctx, userID, hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	// 2. Enforce the quota
+	if s.quotaBytes > 0 {
+		used, err := s.repo.TotalSize(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used+size > s.quotaBytes {
+			return nil, ErrQuotaExceeded
+		}
+	}
+
+	// 3. Keep filenames unique, since notes reference media by name
+	filename, err = s.freeFilename(ctx, userID, filename, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 512)
+	n, _ := tmp.ReadAt(head, 0)
+	mimeType := detectMimeType(filename, head[:n])
+
+	// 4. Upload the content unless a previously deleted file left it in storage
+	storagePath := media.ContentPath(userID, hash)
+	stored, err := s.storageRepo.Exists(ctx, storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check media storage: %w", err)
+	}
+	if !stored {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind media file: %w", err)
+		}
+		if _, err := s.storageRepo.Upload(ctx, tmp, storagePath, mimeType); err != nil {
+			return nil, fmt.Errorf("failed to upload media: %w", err)
+		}
+	}
+
 	m, err := media.NewBuilder().
 		WithUserID(userID).
 		WithFilename(filename).
@@ -31,19 +128,59 @@ func (s *MediaService) Create(ctx context.Context, userID int64, filename string
 		WithSize(size).
 		WithMimeType(mimeType).
 		WithStoragePath(storagePath).
-		WithCreatedAt(now).
+		WithCreatedAt(time.Now()).
 		Build()
 	if err != nil {
 		return nil, err
 	}
 
 	if err := s.repo.Save(ctx, userID, m); err != nil {
+		if errors.Is(err, media.ErrDuplicateContent) {
+			// A concurrent upload of the same content was recorded first
+			return s.repo.FindByHash(ctx, userID, hash)
+		}
 		return nil, err
 	}
 
 	return m, nil
 }
 
+// freeFilename returns the filename, or the filename with a hash suffix when another file already has it
+func (s *MediaService) freeFilename(ctx context.Context, userID int64, filename string, hash string) (string, error) {
+	for _, candidate := range []string{filename, media.HashedFilename(filename, hash), media.FullHashedFilename(filename, hash)} {
+		taken, err := s.repo.FindByFilename(ctx, userID, candidate)
+		if err != nil {
+			return "", err
+		}
+		if taken == nil {
+			return candidate, nil
+		}
+	}
+	return "", ErrFilenameTaken
+}
+
+// GetContent returns a media file's content, or a presigned URL when the storage serves files itself
+func (s *MediaService) GetContent(ctx context.Context, userID int64, id int64) (*primary.MediaContent, error) {
+	m, err := s.repo.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ownership.ErrResourceNotFound
+	}
+
+	url, err := s.storageRepo.GetURL(ctx, m.GetStoragePath(), contentURLExpiry)
+	if err == nil && (strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")) {
+		return &primary.MediaContent{Media: m, URL: url}, nil
+	}
+
+	data, err := s.storageRepo.Download(ctx, m.GetStoragePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %w", err)
+	}
+	return &primary.MediaContent{Media: m, Data: bytes.NewReader(data)}, nil
+}
+
 // FindByID finds a media file by ID
 func (s *MediaService) FindByID(ctx context.Context, userID int64, id int64) (*media.Media, error) {
 	return s.repo.FindByID(ctx, userID, id)
@@ -59,3 +196,23 @@ func (s *MediaService) Delete(ctx context.Context, userID int64, id int64) error
 	return s.repo.Delete(ctx, userID, id)
 }
 
+// cleanFilename trims a media filename and tells whether it is a plain filename without a path
+func cleanFilename(filename string) (string, bool) {
+	filename = strings.TrimSpace(filename)
+	if filename == "" || filename == "." || filename == ".." || strings.ContainsAny(filename, `/\`) {
+		return "", false
+	}
+	return filename, true
+}
+
+// detectMimeType sniffs the MIME type from the content, falling back to the extension
+// when sniffing only recognizes generic text or binary data
+func detectMimeType(filename string, head []byte) string {
+	detected := http.DetectContentType(head)
+	if detected == "application/octet-stream" || strings.HasPrefix(detected, "text/plain") {
+		if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))); byExt != "" {
+			return byExt
+		}
+	}
+	return detected
+}
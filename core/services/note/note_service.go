@@ -5,13 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/note"
 	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/core/domain/services"
+	searchdomain "github.com/felipesantos/anki-backend/core/domain/services/search"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
@@ -27,6 +30,7 @@ type NoteService struct {
 	noteTypeRepo     secondary.INoteTypeRepository
 	deckRepo         secondary.IDeckRepository
 	templateRenderer services.ITemplateRenderer
+	undoService      primary.IUndoHistoryService
 	tm               database.TransactionManager
 }
 
@@ -37,6 +41,7 @@ func NewNoteService(
 	noteTypeRepo secondary.INoteTypeRepository,
 	deckRepo secondary.IDeckRepository,
 	templateRenderer services.ITemplateRenderer,
+	undoService primary.IUndoHistoryService,
 	tm database.TransactionManager,
 ) primary.INoteService {
 	return &NoteService{
@@ -45,6 +50,7 @@ func NewNoteService(
 		noteTypeRepo:     noteTypeRepo,
 		deckRepo:         deckRepo,
 		templateRenderer: templateRenderer,
+		undoService:      undoService,
 		tm:               tm,
 	}
 }
@@ -104,11 +110,14 @@ func (s *NoteService) Create(ctx context.Context, userID int64, noteTypeID int64
 		}
 
 		// 3. Generate Cards based on NoteType
-		if err := s.syncCards(txCtx, userID, noteEntity, nt, fieldsJSON, deckID); err != nil {
+		changes := undohistory.NewChangeSet()
+		changes.AddNote(noteEntity.GetID(), nil, noteEntity.Snapshot())
+		if err := s.syncCards(txCtx, userID, noteEntity, nt, fieldsJSON, deckID, changes); err != nil {
 			return fmt.Errorf("failed to generate cards: %w", err)
 		}
 
-		return nil
+		// 4. Record the note and its cards for undo
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeAddNote, changes)
 	})
 
 	if err != nil {
@@ -187,6 +196,8 @@ func (s *NoteService) Update(ctx context.Context, userID int64, id int64, fields
 		}
 
 		// 3. Update note
+		changes := undohistory.NewChangeSet()
+		before := existing.Snapshot()
 		existing.SetFieldsJSON(fieldsJSON)
 		existing.SetTags(tags)
 		existing.SetUpdatedAt(time.Now())
@@ -195,11 +206,18 @@ func (s *NoteService) Update(ctx context.Context, userID int64, id int64, fields
 			return err
 		}
 
+		changes.AddNote(id, before, existing.Snapshot())
+
 		// 4. Regenerate cards based on updated fields
-		if err := s.syncCards(txCtx, userID, existing, nt, fieldsJSON, 0); err != nil {
+		if err := s.syncCards(txCtx, userID, existing, nt, fieldsJSON, 0, changes); err != nil {
 			return fmt.Errorf("failed to sync cards: %w", err)
 		}
 
+		// 5. Record the edit for undo
+		if err := s.undoService.Record(txCtx, userID, undohistory.OperationTypeEditNote, changes); err != nil {
+			return err
+		}
+
 		updatedNote = existing
 		return nil
 	})
@@ -217,7 +235,8 @@ func (s *NoteService) Update(ctx context.Context, userID int64, id int64, fields
 // - Cards for valid templates are maintained or created
 // - Cards for removed card types are deleted
 // If deckID is 0, it tries to determine the deck ID from existing cards
-func (s *NoteService) syncCards(ctx context.Context, userID int64, noteEntity *note.Note, nt *notetype.NoteType, fieldsJSON string, deckID int64) error {
+// Created and deleted cards are added to changes for undo
+func (s *NoteService) syncCards(ctx context.Context, userID int64, noteEntity *note.Note, nt *notetype.NoteType, fieldsJSON string, deckID int64, changes *undohistory.ChangeSet) error {
 	// 1. Parse fieldsJSON to map[string]string
 	var fieldsMap map[string]interface{}
 	if err := json.Unmarshal([]byte(fieldsJSON), &fieldsMap); err != nil {
@@ -250,9 +269,9 @@ func (s *NoteService) syncCards(ctx context.Context, userID int64, noteEntity *n
 	}
 
 	// 4. Create a map of existing cards by card type ID for quick lookup
-	existingCardsByType := make(map[int]int64) // cardTypeID -> cardID
+	existingCardsByType := make(map[int]*card.Card) // cardTypeID -> card
 	for _, c := range existingCards {
-		existingCardsByType[c.GetCardTypeID()] = c.GetID()
+		existingCardsByType[c.GetCardTypeID()] = c
 	}
 
 	// 5. Process each card type in the note type
@@ -271,10 +290,11 @@ func (s *NoteService) syncCards(ctx context.Context, userID int64, noteEntity *n
 		// Check if template rendered to empty
 		if renderedFront == "" {
 			// Front template is empty, delete card if it exists
-			if cardID, exists := existingCardsByType[cardTypeIndex]; exists {
-				if err := s.cardRepo.Delete(ctx, userID, cardID); err != nil {
+			if c, exists := existingCardsByType[cardTypeIndex]; exists {
+				if err := s.cardRepo.Delete(ctx, userID, c.GetID()); err != nil {
 					return fmt.Errorf("failed to delete empty card: %w", err)
 				}
+				changes.AddCard(c.GetID(), c.Snapshot(), nil)
 			}
 			// Remove from map to track processed cards
 			delete(existingCardsByType, cardTypeIndex)
@@ -302,16 +322,18 @@ func (s *NoteService) syncCards(ctx context.Context, userID int64, noteEntity *n
 				if err := s.cardRepo.Save(ctx, userID, cardEntity); err != nil {
 					return fmt.Errorf("failed to create card: %w", err)
 				}
+				changes.AddCard(cardEntity.GetID(), nil, cardEntity.Snapshot())
 			}
 		}
 	}
 
 	// 7. Delete any remaining cards that don't correspond to valid card types
 	// (This handles the case where card types were removed from the note type)
-	for _, cardID := range existingCardsByType {
-		if err := s.cardRepo.Delete(ctx, userID, cardID); err != nil {
+	for _, c := range existingCardsByType {
+		if err := s.cardRepo.Delete(ctx, userID, c.GetID()); err != nil {
 			return fmt.Errorf("failed to delete obsolete card: %w", err)
 		}
+		changes.AddCard(c.GetID(), c.Snapshot(), nil)
 	}
 
 	return nil
@@ -320,10 +342,20 @@ func (s *NoteService) syncCards(ctx context.Context, userID int64, noteEntity *n
 // Delete deletes a note and its associated cards (soft delete)
 func (s *NoteService) Delete(ctx context.Context, userID int64, id int64) error {
 	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := s.noteRepo.FindByID(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("note not found")
+		}
+
 		// Soft delete note
 		if err := s.noteRepo.Delete(txCtx, userID, id); err != nil {
 			return err
 		}
+		changes := undohistory.NewChangeSet()
+		changes.AddNote(id, existing.Snapshot(), nil)
 
 		// Soft delete cards
 		cards, err := s.cardRepo.FindByNoteID(txCtx, userID, id)
@@ -335,38 +367,48 @@ func (s *NoteService) Delete(ctx context.Context, userID int64, id int64) error
 			if err := s.cardRepo.Delete(txCtx, userID, c.GetID()); err != nil {
 				return err
 			}
+			changes.AddCard(c.GetID(), c.Snapshot(), nil)
 		}
 
-		return nil
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeDeleteNote, changes)
 	})
 }
 
 // AddTag adds a tag to a note
 func (s *NoteService) AddTag(ctx context.Context, userID int64, id int64, tag string) error {
-	existing, err := s.noteRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if existing == nil {
-		return fmt.Errorf("note not found")
-	}
-
-	existing.AddTag(tag)
-	return s.noteRepo.Update(ctx, userID, id, existing)
+	return s.updateTags(ctx, userID, id, undohistory.OperationTypeAddTag, func(n *note.Note) {
+		n.AddTag(tag)
+	})
 }
 
 // RemoveTag removes a tag from a note
 func (s *NoteService) RemoveTag(ctx context.Context, userID int64, id int64, tag string) error {
-	existing, err := s.noteRepo.FindByID(ctx, userID, id)
-	if err != nil {
-		return err
-	}
-	if existing == nil {
-		return fmt.Errorf("note not found")
-	}
+	return s.updateTags(ctx, userID, id, undohistory.OperationTypeRemoveTag, func(n *note.Note) {
+		n.RemoveTag(tag)
+	})
+}
+
+// updateTags applies a tag change to a note and records it for undo
+func (s *NoteService) updateTags(ctx context.Context, userID int64, id int64, operationType string, change func(*note.Note)) error {
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := s.noteRepo.FindByID(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("note not found")
+		}
 
-	existing.RemoveTag(tag)
-	return s.noteRepo.Update(ctx, userID, id, existing)
+		before := existing.Snapshot()
+		change(existing)
+		if err := s.noteRepo.Update(txCtx, userID, id, existing); err != nil {
+			return err
+		}
+
+		changes := undohistory.NewChangeSet()
+		changes.AddNote(id, before, existing.Snapshot())
+		return s.undoService.Record(txCtx, userID, operationType, changes)
+	})
 }
 
 // Copy creates a copy of an existing note
@@ -459,7 +501,9 @@ func (s *NoteService) Copy(ctx context.Context, userID int64, noteID int64, deck
 		}
 
 		// 7. Generate Cards based on NoteType (same logic as Create)
-		if err := s.syncCards(txCtx, userID, copiedNote, nt, copiedNote.GetFieldsJSON(), targetDeckID); err != nil {
+		changes := undohistory.NewChangeSet()
+		changes.AddNote(copiedNote.GetID(), nil, copiedNote.Snapshot())
+		if err := s.syncCards(txCtx, userID, copiedNote, nt, copiedNote.GetFieldsJSON(), targetDeckID, changes); err != nil {
 			return fmt.Errorf("failed to generate cards for copy: %w", err)
 		}
 
@@ -467,7 +511,8 @@ func (s *NoteService) Copy(ctx context.Context, userID int64, noteID int64, deck
 		// TODO: If copyMedia is true, parse fieldsJSON, extract media references,
 		// copy media files via storage service, and update references in new note
 
-		return nil
+		// 9. Record the copy for undo
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeAddNote, changes)
 	})
 
 	if err != nil {
@@ -550,6 +595,181 @@ func (s *NoteService) FindDuplicatesByGUID(ctx context.Context, userID int64) (*
 	}, nil
 }
 
+// findReplacePageSize is the number of notes loaded at a time by FindReplace
+const findReplacePageSize = 500
+
+// FindReplace finds and replaces text in the fields or tags of the notes matching a search
+// All notes are edited in one transaction and recorded as a single undo entry; returns the number of notes changed
+func (s *NoteService) FindReplace(ctx context.Context, userID int64, fr note.FindReplace) (int, error) {
+	re, err := findReplacePattern(fr)
+	if err != nil {
+		return 0, err
+	}
+
+	query, err := searchdomain.NewParser().Parse(fr.Query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	changed := 0
+	err = s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		changes := undohistory.NewChangeSet()
+		noteTypes := make(map[int64]*notetype.NoteType)
+		page := searchdomain.PageRequest{Sort: searchdomain.DefaultSortOrder, Limit: findReplacePageSize}
+
+		for {
+			notes, info, err := s.noteRepo.FindByAdvancedSearch(txCtx, userID, query, page)
+			if err != nil {
+				return err
+			}
+
+			for _, n := range notes {
+				before := n.Snapshot()
+				var edited bool
+				if fr.InTags {
+					edited = replaceInTags(n, re, fr)
+				} else if edited, err = replaceInFields(n, re, fr); err != nil {
+					return err
+				}
+				if !edited {
+					continue
+				}
+
+				nt, err := s.findReplaceNoteType(txCtx, userID, n.GetNoteTypeID(), noteTypes)
+				if err != nil {
+					return err
+				}
+				if err := s.validateFirstField(nt, n.GetFieldsJSON()); err != nil {
+					return fmt.Errorf("first field validation failed for note %d: %w", n.GetID(), err)
+				}
+
+				n.SetUpdatedAt(time.Now())
+				if err := s.noteRepo.Update(txCtx, userID, n.GetID(), n); err != nil {
+					return err
+				}
+				changes.AddNote(n.GetID(), before, n.Snapshot())
+
+				if !fr.InTags {
+					if err := s.syncCards(txCtx, userID, n, nt, n.GetFieldsJSON(), 0, changes); err != nil {
+						return fmt.Errorf("failed to sync cards: %w", err)
+					}
+				}
+				changed++
+			}
+
+			if info.NextCursor == "" {
+				break
+			}
+			page.Cursor = info.NextCursor
+		}
+
+		return s.undoService.Record(txCtx, userID, undohistory.OperationTypeFindReplace, changes)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// findReplaceNoteType loads a note type once per find and replace
+func (s *NoteService) findReplaceNoteType(ctx context.Context, userID int64, id int64, cache map[int64]*notetype.NoteType) (*notetype.NoteType, error) {
+	if nt, ok := cache[id]; ok {
+		return nt, nil
+	}
+	nt, err := s.noteTypeRepo.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if nt == nil {
+		return nil, fmt.Errorf("note type not found")
+	}
+	cache[id] = nt
+	return nt, nil
+}
+
+// findReplacePattern compiles the text to find, quoting it unless it is a regular expression
+func findReplacePattern(fr note.FindReplace) (*regexp.Regexp, error) {
+	if fr.Find == "" {
+		return nil, fmt.Errorf("find text is required")
+	}
+
+	pattern := fr.Find
+	if !fr.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !fr.MatchCase {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern '%s': %w", fr.Find, err)
+	}
+	return re, nil
+}
+
+// replaceText replaces every match, expanding regex groups only in regex mode
+func replaceText(re *regexp.Regexp, fr note.FindReplace, text string) string {
+	if fr.Regex {
+		return re.ReplaceAllString(text, fr.Replace)
+	}
+	return re.ReplaceAllLiteralString(text, fr.Replace)
+}
+
+// replaceInFields replaces text in the note's fields, or in the field named by fr.Field (case-insensitive)
+func replaceInFields(n *note.Note, re *regexp.Regexp, fr note.FindReplace) (bool, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(n.GetFieldsJSON()), &fields); err != nil {
+		return false, fmt.Errorf("invalid fields JSON for note %d: %w", n.GetID(), err)
+	}
+
+	edited := false
+	for name, value := range fields {
+		text, ok := value.(string)
+		if !ok || (fr.Field != "" && !strings.EqualFold(name, fr.Field)) {
+			continue
+		}
+		if replaced := replaceText(re, fr, text); replaced != text {
+			fields[name] = replaced
+			edited = true
+		}
+	}
+	if !edited {
+		return false, nil
+	}
+
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode fields: %w", err)
+	}
+	n.SetFieldsJSON(string(fieldsJSON))
+	return true, nil
+}
+
+// replaceInTags renames the note's tags; tags replaced by an empty string are removed and duplicates merged
+func replaceInTags(n *note.Note, re *regexp.Regexp, fr note.FindReplace) bool {
+	tags := make([]string, 0, len(n.GetTags()))
+	seen := make(map[string]bool)
+	edited := false
+	for _, tag := range n.GetTags() {
+		replaced := strings.TrimSpace(replaceText(re, fr, tag))
+		if replaced != tag {
+			edited = true
+		}
+		key := strings.ToLower(replaced)
+		if replaced == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, replaced)
+	}
+	if edited {
+		n.SetTags(tags)
+	}
+	return edited
+}
+
 // validateFieldName validates that a field name exists in the note type
 func (s *NoteService) validateFieldName(nt *notetype.NoteType, fieldName string) error {
 	if nt.GetFieldsJSON() == "" {
@@ -2,39 +2,90 @@ package review
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
 	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
+	"github.com/felipesantos/anki-backend/core/domain/events"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
 	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 	"github.com/felipesantos/anki-backend/pkg/database"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
 )
 
+// LeechTag is the tag added to the notes of leech cards
+const LeechTag = "leech"
+
+// ErrReviewInFuture is returned when a review is recorded with a time after the current time
+var ErrReviewInFuture = errors.New("review time is in the future")
+
 // ReviewService implements IReviewService
 type ReviewService struct {
-	reviewRepo secondary.IReviewRepository
-	cardRepo   secondary.ICardRepository
-	tm         database.TransactionManager
+	reviewRepo       secondary.IReviewRepository
+	cardRepo         secondary.ICardRepository
+	deckRepo         secondary.IDeckRepository
+	filteredDeckRepo secondary.IFilteredDeckRepository
+	noteRepo         secondary.INoteRepository
+	prefsRepo        secondary.IUserPreferencesRepository
+	undoRepo         secondary.IUndoHistoryRepository
+	eventBus         secondary.IEventBus
+	tm               database.TransactionManager
 }
 
 // NewReviewService creates a new ReviewService instance
 func NewReviewService(
 	reviewRepo secondary.IReviewRepository,
 	cardRepo secondary.ICardRepository,
+	deckRepo secondary.IDeckRepository,
+	filteredDeckRepo secondary.IFilteredDeckRepository,
+	noteRepo secondary.INoteRepository,
+	prefsRepo secondary.IUserPreferencesRepository,
+	undoRepo secondary.IUndoHistoryRepository,
+	eventBus secondary.IEventBus,
 	tm database.TransactionManager,
 ) primary.IReviewService {
 	return &ReviewService{
-		reviewRepo: reviewRepo,
-		cardRepo:   cardRepo,
-		tm:         tm,
+		reviewRepo:       reviewRepo,
+		cardRepo:         cardRepo,
+		deckRepo:         deckRepo,
+		filteredDeckRepo: filteredDeckRepo,
+		noteRepo:         noteRepo,
+		prefsRepo:        prefsRepo,
+		undoRepo:         undoRepo,
+		eventBus:         eventBus,
+		tm:               tm,
 	}
 }
 
 // Create records a new review for a card and updates the card's scheduling state
+// Siblings of the card are buried as configured by the deck options, and a card that lapses
+// too often becomes a leech: its note is tagged "leech" and the card is suspended if configured
+// The card's prior state is recorded in the undo history so that the answer can be undone
+// Cards in a filtered deck that does not reschedule are previewed instead, and a rescheduled
+// card in a filtered deck returns to its home deck once it is in review
 func (s *ReviewService) Create(ctx context.Context, userID int64, cardID int64, rating int, timeMs int) (*review.Review, error) {
+	return s.CreateAt(ctx, userID, cardID, rating, timeMs, time.Now())
+}
+
+// CreateAt records a review answered at a given time, such as a review made offline and synced later
+// The card is scheduled from the review time, which is also the time of the review record
+func (s *ReviewService) CreateAt(ctx context.Context, userID int64, cardID int64, rating int, timeMs int, reviewedAt time.Time) (*review.Review, error) {
+	if err := scheduler.ValidateRating(rating); err != nil {
+		return nil, err
+	}
+	if reviewedAt.After(time.Now()) {
+		return nil, ErrReviewInFuture
+	}
+
 	var reviewEntity *review.Review
+	var leeched *events.CardLeeched
 
 	err := s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
 		// 1. Find and validate card
@@ -45,45 +96,46 @@ func (s *ReviewService) Create(ctx context.Context, userID int64, cardID int64,
 		if c == nil {
 			return fmt.Errorf("card not found")
 		}
+		fd, err := s.filteredDeckFor(txCtx, userID, c)
+		if err != nil {
+			return err
+		}
+		if fd != nil && !fd.GetReschedule() {
+			reviewEntity, err = s.answerPreview(txCtx, userID, c, fd, rating, timeMs, reviewedAt)
+			return err
+		}
 
-		// 2. Map current state to review type
-		var reviewType valueobjects.ReviewType
-		switch c.GetState() {
-		case valueobjects.CardStateLearn:
-			reviewType = valueobjects.ReviewTypeLearn
-		case valueobjects.CardStateReview:
-			reviewType = valueobjects.ReviewTypeReview
-		case valueobjects.CardStateRelearn:
-			reviewType = valueobjects.ReviewTypeRelearn
-		default:
-			reviewType = valueobjects.ReviewTypeLearn
-		}
-
-		// 3. Update card state (simplified scheduling logic)
-		now := time.Now()
-		c.SetLastReviewAt(&now)
-		c.SetReps(c.GetReps() + 1)
-
-		// Basic scheduling to satisfy database constraints and provide minimal logic
-		if rating == 1 { // Again
-			c.SetLapses(c.GetLapses() + 1)
-			c.SetState(valueobjects.CardStateLearn)
-			// For learning/relearning cards, Anki often uses negative intervals (seconds)
-			// Here we use -60 as a placeholder for 1 minute to satisfy interval != 0
-			c.SetInterval(-60)
-		} else {
-			// If rating > 1, set a positive interval
-			if c.GetInterval() <= 0 {
-				c.SetInterval(1) // 1 day
+		// 2. Load the scheduler configured by the deck options
+		options, err := s.loadDeckOptions(txCtx, userID, c)
+		if err != nil {
+			return err
+		}
+		sched, state, err := s.schedulerFor(txCtx, userID, c, options)
+		if err != nil {
+			return err
+		}
+
+		// 3. Compute the next scheduling state and apply it to the card
+		result, err := sched.Schedule(state, rating, reviewedAt)
+		if err != nil {
+			return err
+		}
+		undoData := &undohistory.ReviewCardData{CardID: cardID, Before: c.SchedulingSnapshot()}
+		result.ApplyTo(c, reviewedAt)
+		c.SetUpdatedAt(time.Now()) // The card is modified now, even if the review was made earlier
+
+		if c.GetLapses() > undoData.Before.Lapses && options.IsLeech(c.GetLapses()) {
+			if leeched, undoData.LeechTagged, err = s.handleLeech(txCtx, userID, c, options, reviewedAt); err != nil {
+				return err
 			}
-			// Simplified: just keep current interval if it was already positive
 		}
 
-		if c.GetState() == valueobjects.CardStateNew {
-			c.SetState(valueobjects.CardStateLearn)
+		if fd != nil && c.GetState() == valueobjects.CardStateReview {
+			c.SetHomeDeckID(nil)
+			if undoData.ReturnedFrom, err = s.returnCard(txCtx, userID, cardID, fd); err != nil {
+				return err
+			}
 		}
-		
-		c.SetUpdatedAt(now)
 
 		if err := s.cardRepo.Update(txCtx, userID, cardID, c); err != nil {
 			return err
@@ -93,11 +145,11 @@ func (s *ReviewService) Create(ctx context.Context, userID int64, cardID int64,
 		reviewEntity, err = review.NewBuilder().
 			WithCardID(cardID).
 			WithRating(rating).
-			WithInterval(c.GetInterval()).
-			WithEase(c.GetEase()).
+			WithInterval(result.LogInterval).
+			WithEase(result.Ease).
 			WithTimeMs(timeMs).
-			WithType(reviewType).
-			WithCreatedAt(now).
+			WithType(result.ReviewType).
+			WithCreatedAt(reviewedAt).
 			Build()
 		if err != nil {
 			return err
@@ -107,16 +159,403 @@ func (s *ReviewService) Create(ctx context.Context, userID int64, cardID int64,
 			return err
 		}
 
-		return nil
+		// 5. Bury siblings so they are not shown on the same day
+		undoData.ReviewID = reviewEntity.GetID()
+		if undoData.BuriedSiblingIDs, err = s.burySiblings(txCtx, userID, c, options, reviewedAt); err != nil {
+			return err
+		}
+
+		// 6. Record how to revert the answer
+		return s.saveUndo(txCtx, userID, undoData, time.Now())
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	if leeched != nil {
+		// Event publishing failure should not fail the review
+		_ = s.eventBus.Publish(ctx, leeched)
+	}
+
 	return reviewEntity, nil
 }
 
+// Undo reverts the user's most recent answer: the review is deleted and the card, its siblings
+// and its note are restored to their state before the answer. Returns the restored card
+// Answers whose review or card was deleted since are skipped, undoing the previous answer instead
+func (s *ReviewService) Undo(ctx context.Context, userID int64) (*card.Card, error) {
+	var restored *card.Card
+
+	err := s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		for restored == nil {
+			// 1. Find the latest answer
+			entry, err := s.undoRepo.FindLatestByType(txCtx, userID, undohistory.OperationTypeReviewCard)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				return fmt.Errorf("review to undo not found")
+			}
+
+			data, err := undohistory.ParseReviewCardData(entry.GetOperationData())
+			if err != nil {
+				return err
+			}
+
+			// 2. Revert the answer; nil when its review or card no longer exists
+			if restored, err = s.undoReview(txCtx, userID, data); err != nil {
+				return err
+			}
+
+			// 3. Consume the undo entry, moving on to the previous answer if it was stale
+			if err := s.undoRepo.Delete(txCtx, userID, entry.GetID()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// undoReview deletes the logged review, restores the card's scheduling state and reverts the side effects of the answer
+// Returns nil when the review or the card was deleted since (card reset, card deleted): the answer is already undone
+func (s *ReviewService) undoReview(ctx context.Context, userID int64, data *undohistory.ReviewCardData) (*card.Card, error) {
+	if err := s.reviewRepo.Delete(ctx, userID, data.ReviewID); err != nil {
+		if errors.Is(err, ownership.ErrResourceNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	restored, err := s.cardRepo.FindByID(ctx, userID, data.CardID)
+	if err != nil {
+		if errors.Is(err, ownership.ErrResourceNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if restored == nil {
+		return nil, nil
+	}
+	restored.RestoreScheduling(data.Before)
+	if data.ReturnedFrom != nil {
+		if err := s.restoreToFilteredDeck(ctx, userID, restored, data.ReturnedFrom); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.cardRepo.Update(ctx, userID, restored.GetID(), restored); err != nil {
+		return nil, err
+	}
+
+	if err := s.unburySiblings(ctx, userID, data.BuriedSiblingIDs); err != nil {
+		return nil, err
+	}
+	if data.LeechTagged {
+		if err := s.removeLeechTag(ctx, userID, restored.GetNoteID()); err != nil {
+			return nil, err
+		}
+	}
+
+	return restored, nil
+}
+
+// PreviewIntervals computes the outcome of each rating for a card without persisting anything
+func (s *ReviewService) PreviewIntervals(ctx context.Context, userID int64, cardID int64) ([]scheduler.IntervalPreview, error) {
+	c, err := s.cardRepo.FindByID(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("card not found")
+	}
+
+	options, err := s.loadDeckOptions(ctx, userID, c)
+	if err != nil {
+		return nil, err
+	}
+
+	sched, state, err := s.schedulerFor(ctx, userID, c, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduler.PreviewIntervals(sched, state, time.Now())
+}
+
+// filteredDeckFor returns the filtered deck holding a card, or nil if the card is in its home deck
+func (s *ReviewService) filteredDeckFor(ctx context.Context, userID int64, c *card.Card) (*filtereddeck.FilteredDeck, error) {
+	if c.GetHomeDeckID() == nil {
+		return nil, nil
+	}
+	return s.filteredDeckRepo.FindByCardID(ctx, userID, c.GetID())
+}
+
+// answerPreview records a cram review of a card in a filtered deck that does not reschedule
+// The card's scheduling is left untouched; answering Good or Easy returns it to its home deck,
+// while Again and Hard keep it in the filtered deck to be shown again
+func (s *ReviewService) answerPreview(ctx context.Context, userID int64, c *card.Card, fd *filtereddeck.FilteredDeck, rating int, timeMs int, reviewedAt time.Time) (*review.Review, error) {
+	reviewEntity, err := review.NewBuilder().
+		WithCardID(c.GetID()).
+		WithRating(rating).
+		WithInterval(c.GetInterval()).
+		WithEase(c.GetEase()).
+		WithTimeMs(timeMs).
+		WithType(valueobjects.ReviewTypeCram).
+		WithCreatedAt(reviewedAt).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reviewRepo.Save(ctx, userID, reviewEntity); err != nil {
+		return nil, err
+	}
+
+	undoData := &undohistory.ReviewCardData{ReviewID: reviewEntity.GetID(), CardID: c.GetID(), Before: c.SchedulingSnapshot()}
+	if rating >= scheduler.RatingGood {
+		if undoData.ReturnedFrom, err = s.returnCard(ctx, userID, c.GetID(), fd); err != nil {
+			return nil, err
+		}
+	}
+
+	return reviewEntity, s.saveUndo(ctx, userID, undoData, time.Now())
+}
+
+// returnCard returns a card from a filtered deck to its home deck and returns its place in the filtered deck
+func (s *ReviewService) returnCard(ctx context.Context, userID int64, cardID int64, fd *filtereddeck.FilteredDeck) (*undohistory.FilteredDeckPlace, error) {
+	position, err := s.filteredDeckRepo.ReturnCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	return &undohistory.FilteredDeckPlace{FilteredDeckID: fd.GetID(), Position: position}, nil
+}
+
+// restoreToFilteredDeck puts a card returned by an answer that is being undone back into its filtered deck
+// A card whose filtered deck was deleted since, or that was moved into another filtered deck, stays where it is
+func (s *ReviewService) restoreToFilteredDeck(ctx context.Context, userID int64, c *card.Card, place *undohistory.FilteredDeckPlace) error {
+	err := s.filteredDeckRepo.RestoreCard(ctx, userID, place.FilteredDeckID, c.GetID(), place.Position)
+	if errors.Is(err, ownership.ErrResourceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	homeDeckID := c.GetDeckID()
+	c.SetHomeDeckID(&homeDeckID)
+	return nil
+}
+
+// schedulerFor returns the scheduler configured by the deck options and the card's current scheduling state
+func (s *ReviewService) schedulerFor(ctx context.Context, userID int64, c *card.Card, options *deck.DeckOptions) (scheduler.IScheduler, scheduler.SchedulingState, error) {
+	step, err := s.currentStep(ctx, userID, c, options)
+	if err != nil {
+		return nil, scheduler.SchedulingState{}, err
+	}
+
+	return scheduler.NewScheduler(options), scheduler.StateFromCard(c, step), nil
+}
+
+// loadDeckOptions returns the options of the deck that schedules the card
+// Cards in a filtered deck are scheduled with the options of their home deck
+func (s *ReviewService) loadDeckOptions(ctx context.Context, userID int64, c *card.Card) (*deck.DeckOptions, error) {
+	deckID := c.GetDeckID()
+	if c.GetHomeDeckID() != nil {
+		deckID = *c.GetHomeDeckID()
+	}
+
+	d, err := s.deckRepo.FindByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, fmt.Errorf("deck not found")
+	}
+
+	return d.GetOptions()
+}
+
+// handleLeech tags the card's note as a leech and suspends the card if the leech action says so
+// It reports whether the tag was added by this call. The card itself is persisted by the caller
+func (s *ReviewService) handleLeech(ctx context.Context, userID int64, c *card.Card, options *deck.DeckOptions, now time.Time) (*events.CardLeeched, bool, error) {
+	n, err := s.noteRepo.FindByID(ctx, userID, c.GetNoteID())
+	if err != nil {
+		return nil, false, err
+	}
+	if n == nil {
+		return nil, false, fmt.Errorf("note not found")
+	}
+
+	tagged := !n.HasTag(LeechTag)
+	if tagged {
+		n.AddTag(LeechTag)
+		if err := s.noteRepo.Update(ctx, userID, n.GetID(), n); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if options.LeechAction == valueobjects.LeechActionSuspend {
+		c.Suspend()
+	}
+
+	return &events.CardLeeched{
+		CardID:    c.GetID(),
+		NoteID:    c.GetNoteID(),
+		UserID:    userID,
+		Lapses:    c.GetLapses(),
+		Suspended: c.GetSuspended(),
+		Timestamp: now,
+	}, tagged, nil
+}
+
+// removeLeechTag removes the leech tag added to a note by an answer that is being undone
+func (s *ReviewService) removeLeechTag(ctx context.Context, userID int64, noteID int64) error {
+	n, err := s.noteRepo.FindByID(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return fmt.Errorf("note not found")
+	}
+
+	n.RemoveTag(LeechTag)
+	return s.noteRepo.Update(ctx, userID, n.GetID(), n)
+}
+
+// burySiblings buries the other cards of the answered card's note that are enabled in the deck options:
+// new siblings, and review and interday learning siblings due before the next day starts
+// Returns the IDs of the buried siblings
+func (s *ReviewService) burySiblings(ctx context.Context, userID int64, c *card.Card, options *deck.DeckOptions, now time.Time) ([]int64, error) {
+	if !options.BuryNew && !options.BuryReviews && !options.BuryInterdayLearning {
+		return nil, nil
+	}
+
+	siblings, err := s.cardRepo.FindByNoteID(ctx, userID, c.GetNoteID())
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.prefsRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dayStart := prefs.DayStart(now)
+	dayEnd := prefs.NextDayStart(now).UnixMilli()
+
+	var buried []int64
+	for _, sibling := range siblings {
+		if sibling.GetID() == c.GetID() || sibling.GetSuspended() || sibling.GetBuried() {
+			continue
+		}
+
+		var bury bool
+		switch {
+		case sibling.GetState() == valueobjects.CardStateNew:
+			bury = options.BuryNew
+		case sibling.GetState() == valueobjects.CardStateReview:
+			bury = options.BuryReviews && sibling.GetDue() < dayEnd
+		case sibling.IsInterdayLearning(dayStart):
+			bury = options.BuryInterdayLearning && sibling.GetDue() < dayEnd
+		}
+		if !bury {
+			continue
+		}
+
+		sibling.Bury()
+		if err := s.cardRepo.Update(ctx, userID, sibling.GetID(), sibling); err != nil {
+			return nil, err
+		}
+		buried = append(buried, sibling.GetID())
+	}
+
+	return buried, nil
+}
+
+// unburySiblings unburies the siblings buried by an answer that is being undone
+// Siblings deleted since then are skipped
+func (s *ReviewService) unburySiblings(ctx context.Context, userID int64, siblingIDs []int64) error {
+	for _, id := range siblingIDs {
+		sibling, err := s.cardRepo.FindByID(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+		if sibling == nil || !sibling.GetBuried() {
+			continue
+		}
+
+		sibling.Unbury()
+		if err := s.cardRepo.Update(ctx, userID, id, sibling); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveUndo records the data needed to revert an answer in the undo history
+func (s *ReviewService) saveUndo(ctx context.Context, userID int64, data *undohistory.ReviewCardData, now time.Time) error {
+	operationData, err := data.Encode()
+	if err != nil {
+		return err
+	}
+
+	entry, err := undohistory.NewBuilder().
+		WithUserID(userID).
+		WithOperationType(undohistory.OperationTypeReviewCard).
+		WithOperationData(operationData).
+		WithCreatedAt(now).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	return s.undoRepo.Save(ctx, userID, entry)
+}
+
+// currentStep recovers the learning step of a learning/relearning card from its latest review,
+// whose interval holds the step delay as negative seconds
+func (s *ReviewService) currentStep(ctx context.Context, userID int64, c *card.Card, options *deck.DeckOptions) (int, error) {
+	var steps []float64
+	switch c.GetState() {
+	case valueobjects.CardStateLearn:
+		steps = options.NewSteps
+	case valueobjects.CardStateRelearn:
+		steps = options.RelearnSteps
+	default:
+		return 0, nil
+	}
+
+	reviews, err := s.reviewRepo.FindByCardID(ctx, userID, c.GetID())
+	if err != nil {
+		return 0, err
+	}
+	if len(reviews) == 0 || reviews[0].GetInterval() >= 0 {
+		return 0, nil
+	}
+
+	return scheduler.StepIndexForDelay(steps, -reviews[0].GetInterval()), nil
+}
+
+// LogManual records a manual reschedule of a card in its review history, as Anki does
+// The entry has no rating and holds the card's interval and ease after the change
+func (s *ReviewService) LogManual(ctx context.Context, userID int64, c *card.Card) error {
+	reviewEntity, err := review.NewBuilder().
+		WithCardID(c.GetID()).
+		WithRating(0).
+		WithInterval(c.GetInterval()).
+		WithEase(c.GetEase()).
+		WithType(valueobjects.ReviewTypeManual).
+		WithCreatedAt(time.Now()).
+		Build()
+	if err != nil {
+		return err
+	}
+	return s.reviewRepo.Save(ctx, userID, reviewEntity)
+}
+
 // FindByID finds a review by ID
 func (s *ReviewService) FindByID(ctx context.Context, userID int64, id int64) (*review.Review, error) {
 	return s.reviewRepo.FindByID(ctx, userID, id)
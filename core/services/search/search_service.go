@@ -11,31 +11,34 @@ import (
 
 // SearchService implements ISearchService
 type SearchService struct {
-	noteRepo     secondary.INoteRepository
-	cardRepo     secondary.ICardRepository
-	parser       *searchdomain.Parser
+	noteRepo          secondary.INoteRepository
+	cardRepo          secondary.ICardRepository
+	browserConfigRepo secondary.IBrowserConfigRepository
+	parser            *searchdomain.Parser
 }
 
 // NewSearchService creates a new SearchService instance
 func NewSearchService(
 	noteRepo secondary.INoteRepository,
 	cardRepo secondary.ICardRepository,
+	browserConfigRepo secondary.IBrowserConfigRepository,
 ) primary.ISearchService {
 	return &SearchService{
-		noteRepo: noteRepo,
-		cardRepo: cardRepo,
-		parser:   searchdomain.NewParser(),
+		noteRepo:          noteRepo,
+		cardRepo:          cardRepo,
+		browserConfigRepo: browserConfigRepo,
+		parser:            searchdomain.NewParser(),
 	}
 }
 
 // SearchAdvanced performs advanced search using Anki syntax
-func (s *SearchService) SearchAdvanced(ctx context.Context, userID int64, query string, resultType string, limit int, offset int) (*primary.SearchResult, error) {
+func (s *SearchService) SearchAdvanced(ctx context.Context, userID int64, query string, resultType string, page searchdomain.PageRequest) (*primary.SearchResult, error) {
 	// Set defaults
-	if limit <= 0 {
-		limit = 50
+	if page.Limit <= 0 {
+		page.Limit = 50
 	}
-	if offset < 0 {
-		offset = 0
+	if page.Offset < 0 {
+		page.Offset = 0
 	}
 
 	// Validate result type
@@ -49,81 +52,65 @@ func (s *SearchService) SearchAdvanced(ctx context.Context, userID int64, query
 		return nil, fmt.Errorf("failed to parse query: %w", err)
 	}
 
-	// Check if query has card-specific filters (states, flags, properties)
-	hasCardFilters := len(parsedQuery.States) > 0 || len(parsedQuery.Flags) > 0 || len(parsedQuery.PropertyFilters) > 0
+	if page.Sort, err = s.sortOrder(ctx, userID, page.Sort); err != nil {
+		return nil, err
+	}
 
-	var results []interface{}
+	// Both repositories evaluate the whole expression tree, mixing note and card terms
+	results := make([]interface{}, 0, page.Limit)
+	var info searchdomain.PageInfo
 
-	if resultType == "cards" || hasCardFilters {
-		// Query cards first if we need card-specific filters or if result type is cards
-		cards, err := s.cardRepo.FindByAdvancedSearch(ctx, userID, parsedQuery)
+	if resultType == "cards" {
+		cards, cardsInfo, err := s.cardRepo.FindByAdvancedSearch(ctx, userID, parsedQuery, page)
 		if err != nil {
 			return nil, fmt.Errorf("failed to find cards: %w", err)
 		}
-
-		if resultType == "cards" {
-			// Return cards directly
-			for _, c := range cards {
-				results = append(results, c)
-			}
-		} else {
-			// Get note IDs from cards that match card filters
-			noteIDs := make(map[int64]bool)
-			for _, c := range cards {
-				noteIDs[c.GetNoteID()] = true
-			}
-
-			// Query notes with note filters
-			allNotes, err := s.noteRepo.FindByAdvancedSearch(ctx, userID, parsedQuery, 10000, 0) // Large limit to get all matching notes
-			if err != nil {
-				return nil, fmt.Errorf("failed to find notes: %w", err)
-			}
-
-			// If we have card filters, intersect with card results
-			// Otherwise, use all notes from note query
-			if hasCardFilters {
-				// Filter notes that have matching cards
-				for _, n := range allNotes {
-					if noteIDs[n.GetID()] {
-						results = append(results, n)
-					}
-				}
-			} else {
-				// No card filters, use all notes from query
-				for _, n := range allNotes {
-					results = append(results, n)
-				}
-			}
+		for _, c := range cards {
+			results = append(results, c)
 		}
+		info = cardsInfo
 	} else {
-		// Query notes directly
-		notes, err := s.noteRepo.FindByAdvancedSearch(ctx, userID, parsedQuery, limit, offset)
+		notes, notesInfo, err := s.noteRepo.FindByAdvancedSearch(ctx, userID, parsedQuery, page)
 		if err != nil {
 			return nil, fmt.Errorf("failed to find notes: %w", err)
 		}
 		for _, n := range notes {
 			results = append(results, n)
 		}
+		info = notesInfo
 	}
 
-	// Apply pagination if not already applied
-	if hasCardFilters && resultType == "notes" {
-		// Apply pagination manually
-		start := offset
-		end := offset + limit
-		if start > len(results) {
-			results = []interface{}{}
+	return &primary.SearchResult{
+		Data:       results,
+		Total:      info.Total,
+		NextCursor: info.NextCursor,
+	}, nil
+}
+
+// sortOrder completes the requested sort order with the user's browser config
+// Without a column in either, results are sorted by creation time, newest first
+func (s *SearchService) sortOrder(ctx context.Context, userID int64, sort searchdomain.SortOrder) (searchdomain.SortOrder, error) {
+	if sort.Column == "" {
+		cfg, err := s.browserConfigRepo.FindByUserID(ctx, userID)
+		if err != nil {
+			return sort, fmt.Errorf("failed to load browser config: %w", err)
+		}
+
+		if cfg != nil && cfg.GetSortColumn() != nil && *cfg.GetSortColumn() != "" {
+			sort.Column = *cfg.GetSortColumn()
+			if sort.Direction == "" {
+				sort.Direction = cfg.GetSortDirection()
+			}
 		} else {
-			if end > len(results) {
-				end = len(results)
+			sort.Column = searchdomain.DefaultSortOrder.Column
+			if sort.Direction == "" {
+				sort.Direction = searchdomain.DefaultSortOrder.Direction
 			}
-			results = results[start:end]
 		}
 	}
+	if sort.Direction == "" {
+		sort.Direction = searchdomain.SortAsc
+	}
 
-	return &primary.SearchResult{
-		Data:  results,
-		Total: len(results), // Note: This is approximate, full count would require separate query
-	}, nil
+	return sort, sort.Validate()
 }
-
@@ -0,0 +1,219 @@
+package study
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	deckSvc "github.com/felipesantos/anki-backend/core/services/deck"
+)
+
+// CustomStudySessionName is the name of the filtered deck holding the custom study session
+const CustomStudySessionName = "Custom Study Session"
+
+const (
+	// maxCustomStudyDays bounds the days searched by the forgotten, review ahead and preview modes
+	maxCustomStudyDays = 365
+	// maxCustomStudyAmount bounds limit extensions and the cards gathered into the session deck
+	maxCustomStudyAmount = 9999
+)
+
+// ErrInvalidCustomStudy is returned when the custom study options are invalid
+var ErrInvalidCustomStudy = errors.New("invalid custom study")
+
+// CustomStudyService implements ICustomStudyService
+type CustomStudyService struct {
+	deckRepo            secondary.IDeckRepository
+	prefsRepo           secondary.IUserPreferencesRepository
+	filteredDeckService primary.IFilteredDeckService
+}
+
+// NewCustomStudyService creates a new CustomStudyService instance
+func NewCustomStudyService(
+	deckRepo secondary.IDeckRepository,
+	prefsRepo secondary.IUserPreferencesRepository,
+	filteredDeckService primary.IFilteredDeckService,
+) primary.ICustomStudyService {
+	return &CustomStudyService{
+		deckRepo:            deckRepo,
+		prefsRepo:           prefsRepo,
+		filteredDeckService: filteredDeckService,
+	}
+}
+
+// customStudySession is the search and options of a custom study session deck
+type customStudySession struct {
+	search     string
+	limit      int
+	orderBy    string
+	reschedule bool
+}
+
+// CustomStudy extends today's limits of a deck or builds a custom study session deck from it and its subdecks
+func (s *CustomStudyService) CustomStudy(ctx context.Context, userID int64, deckID int64, opts primary.CustomStudyOptions) (*primary.CustomStudyResult, error) {
+	d, err := s.deckRepo.FindByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, deckSvc.ErrDeckNotFound
+	}
+
+	switch opts.Mode {
+	case primary.CustomStudyNewLimit, primary.CustomStudyReviewLimit:
+		return s.extendLimits(ctx, userID, deckID, opts)
+	}
+
+	session, err := customStudySearch(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	deckIDs, err := collectDeckIDs(ctx, s.deckRepo, userID, d)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(deckIDs))
+	for i, id := range deckIDs {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	session.search = strings.TrimSpace("did:" + strings.Join(ids, ",") + " " + session.search)
+
+	return s.buildSession(ctx, userID, session)
+}
+
+// extendLimits adds the amount to today's new card or review limit of the deck
+// Negative amounts lower the limit, as in Anki
+func (s *CustomStudyService) extendLimits(ctx context.Context, userID int64, deckID int64, opts primary.CustomStudyOptions) (*primary.CustomStudyResult, error) {
+	if opts.Amount == 0 || opts.Amount < -maxCustomStudyAmount || opts.Amount > maxCustomStudyAmount {
+		return nil, fmt.Errorf("%w: amount must be between -%d and %d and not 0", ErrInvalidCustomStudy, maxCustomStudyAmount, maxCustomStudyAmount)
+	}
+
+	prefs, err := s.prefsRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dayStart := prefs.DayStart(time.Now())
+
+	newCards, reviews := 0, 0
+	if opts.Mode == primary.CustomStudyNewLimit {
+		newCards = opts.Amount
+	} else {
+		reviews = opts.Amount
+	}
+
+	extension, err := s.deckRepo.ExtendLimits(ctx, userID, deckID, dayStart, newCards, reviews)
+	if err != nil {
+		return nil, err
+	}
+	return &primary.CustomStudyResult{Extension: extension}, nil
+}
+
+// buildSession creates the custom study session deck, or updates and rebuilds it if it already exists
+func (s *CustomStudyService) buildSession(ctx context.Context, userID int64, session customStudySession) (*primary.CustomStudyResult, error) {
+	decks, err := s.filteredDeckService.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing *filtereddeck.FilteredDeck
+	for _, fd := range decks {
+		if fd.GetName() == CustomStudySessionName {
+			existing = fd
+			break
+		}
+	}
+
+	var fd *filtereddeck.FilteredDeck
+	if existing != nil {
+		fd, err = s.filteredDeckService.Update(ctx, userID, existing.GetID(), CustomStudySessionName, session.search, nil, session.limit, 0, session.orderBy, session.reschedule)
+	} else {
+		fd, err = s.filteredDeckService.Create(ctx, userID, CustomStudySessionName, session.search, nil, session.limit, 0, session.orderBy, session.reschedule)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := s.filteredDeckService.FindCards(ctx, userID, fd.GetID())
+	if err != nil {
+		return nil, err
+	}
+	return &primary.CustomStudyResult{FilteredDeck: fd, CardCount: len(cards)}, nil
+}
+
+// customStudySearch returns the session deck options of a custom study mode, the search not yet limited to the deck
+// The searches and orders follow Anki's Custom Study dialog
+func customStudySearch(opts primary.CustomStudyOptions) (customStudySession, error) {
+	switch opts.Mode {
+	case primary.CustomStudyForgotten, primary.CustomStudyReviewAhead, primary.CustomStudyPreviewNew:
+		if opts.Amount < 1 || opts.Amount > maxCustomStudyDays {
+			return customStudySession{}, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidCustomStudy, maxCustomStudyDays)
+		}
+	case primary.CustomStudyCram:
+		if opts.Amount < 1 || opts.Amount > maxCustomStudyAmount {
+			return customStudySession{}, fmt.Errorf("%w: cards must be between 1 and %d", ErrInvalidCustomStudy, maxCustomStudyAmount)
+		}
+	default:
+		return customStudySession{}, fmt.Errorf("%w: unknown mode %q", ErrInvalidCustomStudy, opts.Mode)
+	}
+
+	days := strconv.Itoa(opts.Amount)
+	switch opts.Mode {
+	case primary.CustomStudyForgotten:
+		return customStudySession{search: "rated:" + days + ":1", limit: maxCustomStudyAmount, orderBy: filtereddeck.OrderRandom}, nil
+	case primary.CustomStudyReviewAhead:
+		return customStudySession{search: "is:review prop:due<=" + days, limit: maxCustomStudyAmount, orderBy: filtereddeck.OrderDue, reschedule: true}, nil
+	case primary.CustomStudyPreviewNew:
+		return customStudySession{search: "is:new added:" + days, limit: maxCustomStudyAmount, orderBy: filtereddeck.OrderOldestSeen}, nil
+	}
+
+	session := customStudySession{limit: opts.Amount, orderBy: filtereddeck.OrderRandom, reschedule: true}
+	terms := []string{}
+	switch opts.CardState {
+	case primary.CustomStudyCardsNew:
+		terms = append(terms, "is:new")
+		session.orderBy = filtereddeck.OrderAdded
+	case primary.CustomStudyCardsDue, "":
+		terms = append(terms, "is:due", "-is:new")
+		session.orderBy = filtereddeck.OrderDue
+	case primary.CustomStudyCardsReview:
+		terms = append(terms, "-is:new")
+	case primary.CustomStudyCardsAll:
+		session.reschedule = false
+	default:
+		return customStudySession{}, fmt.Errorf("%w: unknown card state %q", ErrInvalidCustomStudy, opts.CardState)
+	}
+
+	for _, tag := range append(append([]string{}, opts.TagsInclude...), opts.TagsExclude...) {
+		if !isSearchableTag(tag) {
+			return customStudySession{}, fmt.Errorf("%w: invalid tag %q", ErrInvalidCustomStudy, tag)
+		}
+	}
+	if len(opts.TagsInclude) > 0 {
+		tags := make([]string, len(opts.TagsInclude))
+		for i, tag := range opts.TagsInclude {
+			tags[i] = "tag:" + tag
+		}
+		terms = append(terms, "("+strings.Join(tags, " OR ")+")")
+	}
+	for _, tag := range opts.TagsExclude {
+		terms = append(terms, "-tag:"+tag)
+	}
+	session.search = strings.Join(terms, " ")
+	return session, nil
+}
+
+// isSearchableTag reports whether a tag can be written into a search as is
+// Tags never contain whitespace; quotes, parentheses and backslashes would change the search expression
+func isSearchableTag(tag string) bool {
+	return tag != "" && !strings.ContainsFunc(tag, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"'()\`, r)
+	})
+}
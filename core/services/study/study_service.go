@@ -0,0 +1,323 @@
+package study
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// StudyService implements IStudyService
+type StudyService struct {
+	deckRepo         secondary.IDeckRepository
+	filteredDeckRepo secondary.IFilteredDeckRepository
+	cardRepo         secondary.ICardRepository
+	reviewRepo       secondary.IReviewRepository
+	prefsRepo        secondary.IUserPreferencesRepository
+}
+
+// NewStudyService creates a new StudyService instance
+func NewStudyService(
+	deckRepo secondary.IDeckRepository,
+	filteredDeckRepo secondary.IFilteredDeckRepository,
+	cardRepo secondary.ICardRepository,
+	reviewRepo secondary.IReviewRepository,
+	prefsRepo secondary.IUserPreferencesRepository,
+) primary.IStudyService {
+	return &StudyService{
+		deckRepo:         deckRepo,
+		filteredDeckRepo: filteredDeckRepo,
+		cardRepo:         cardRepo,
+		reviewRepo:       reviewRepo,
+		prefsRepo:        prefsRepo,
+	}
+}
+
+// Next returns the next card to study in a deck and its subdecks with the cards left for today
+// Daily limits come from the options of each deck, plus today's custom study extension: a subdeck never gives
+// more cards than its own limits, nor than the limits of the decks above it. Learning cards due now are shown
+// first, then new cards are spread evenly among reviews, then learning cards within the learn
+// ahead limit are shown early
+func (s *StudyService) Next(ctx context.Context, userID int64, deckID int64) (*card.StudyQueue, error) {
+	d, err := s.deckRepo.FindByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, fmt.Errorf("deck not found")
+	}
+
+	tree, err := collectDeckTree(ctx, s.deckRepo, userID, d)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.prefsRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	dayStart := prefs.DayStart(now)
+	dayEnd := prefs.NextDayStart(now).UnixMilli()
+
+	introduced, reviewed, err := s.loadLimits(ctx, userID, tree, dayStart)
+	if err != nil {
+		return nil, err
+	}
+
+	learning, err := s.cardRepo.FindQueueCards(ctx, userID, card.QueueFilters{
+		DeckIDs:   tree.deckIDs(),
+		States:    []valueobjects.CardState{valueobjects.CardStateLearn, valueobjects.CardStateRelearn},
+		DueBefore: &dayEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+	learning = filterLearning(learning, dayStart, prefs.LearnAheadCutoff(now).UnixMilli())
+
+	reviews, err := s.gatherLimited(ctx, userID, tree, card.QueueFilters{
+		States:    []valueobjects.CardState{valueobjects.CardStateReview},
+		DueBefore: &dayEnd,
+	}, func(n *deckNode) *int { return &n.reviewsLeft })
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].GetDue() < reviews[j].GetDue() })
+
+	newCards, err := s.gatherLimited(ctx, userID, tree, card.QueueFilters{
+		States: []valueobjects.CardState{valueobjects.CardStateNew},
+	}, func(n *deckNode) *int { return &n.newLeft })
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(newCards, func(i, j int) bool { return newCards[i].GetPosition() < newCards[j].GetPosition() })
+
+	queue := &card.StudyQueue{
+		NewCount:    len(newCards),
+		LearnCount:  len(learning),
+		ReviewCount: len(reviews),
+	}
+
+	switch {
+	case len(learning) > 0 && learning[0].GetDue() <= now.UnixMilli():
+		queue.Next, queue.Queue = learning[0], card.QueueTypeLearn
+	case len(newCards) > 0 && timeForNewCard(introduced+reviewed, len(newCards), len(reviews)):
+		queue.Next, queue.Queue = newCards[0], card.QueueTypeNew
+	case len(reviews) > 0:
+		queue.Next, queue.Queue = reviews[0], card.QueueTypeReview
+	case len(learning) > 0:
+		queue.Next, queue.Queue = learning[0], card.QueueTypeLearn
+	}
+
+	return queue, nil
+}
+
+// NextFiltered returns the next card to study in a filtered deck and the cards left in it
+// Every card gathered into the deck can be studied, in the deck's order and whatever the daily limits of its
+// home deck. Learning cards due now are shown first, then the new and review cards, then learning cards
+// within the learn ahead limit are shown early
+func (s *StudyService) NextFiltered(ctx context.Context, userID int64, filteredDeckID int64) (*card.StudyQueue, error) {
+	fd, err := s.filteredDeckRepo.FindByID(ctx, userID, filteredDeckID)
+	if err != nil {
+		return nil, err
+	}
+	if fd == nil {
+		return nil, fmt.Errorf("filtered deck not found")
+	}
+
+	cards, err := s.cardRepo.FindByFilteredDeckID(ctx, userID, fd.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.prefsRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	dayStart := prefs.DayStart(now)
+	dayEnd := prefs.NextDayStart(now).UnixMilli()
+
+	queue := &card.StudyQueue{}
+	var learning, others []*card.Card
+	for _, c := range cards {
+		if !c.IsStudyable() {
+			continue
+		}
+		switch c.GetState() {
+		case valueobjects.CardStateLearn, valueobjects.CardStateRelearn:
+			if c.GetDue() < dayEnd {
+				learning = append(learning, c)
+			}
+		case valueobjects.CardStateNew:
+			queue.NewCount++
+			others = append(others, c)
+		case valueobjects.CardStateReview:
+			queue.ReviewCount++
+			others = append(others, c)
+		}
+	}
+	learning = filterLearning(learning, dayStart, prefs.LearnAheadCutoff(now).UnixMilli())
+	sort.SliceStable(learning, func(i, j int) bool { return learning[i].GetDue() < learning[j].GetDue() })
+	queue.LearnCount = len(learning)
+
+	switch {
+	case len(learning) > 0 && learning[0].GetDue() <= now.UnixMilli():
+		queue.Next, queue.Queue = learning[0], card.QueueTypeLearn
+	case len(others) > 0 && others[0].IsNew():
+		queue.Next, queue.Queue = others[0], card.QueueTypeNew
+	case len(others) > 0:
+		queue.Next, queue.Queue = others[0], card.QueueTypeReview
+	case len(learning) > 0:
+		queue.Next, queue.Queue = learning[0], card.QueueTypeLearn
+	}
+
+	return queue, nil
+}
+
+// loadLimits sets what is left of each deck's daily limits from the cards studied today in the deck and its subdecks
+// Returns the cards introduced and reviewed today in the whole tree
+func (s *StudyService) loadLimits(ctx context.Context, userID int64, tree deckTree, dayStart time.Time) (int, int, error) {
+	var introduced, reviewed int
+	for i := range tree {
+		n := &tree[i]
+		options, err := n.deck.GetOptions()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		deckIntroduced, deckReviewed, err := s.reviewRepo.CountStudiedSince(ctx, userID, tree.subtreeIDs(i), dayStart)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		// Custom study extends today's limits by taking cards off the studied counts
+		extension, err := s.deckRepo.FindLimitExtension(ctx, userID, n.deck.GetID(), dayStart)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n.newLeft = options.NewPerDay - (deckIntroduced - extension.NewCards)
+		n.reviewsLeft = options.ReviewsPerDay - (deckReviewed - extension.Reviews)
+		if i == 0 {
+			introduced, reviewed = deckIntroduced, deckReviewed
+		}
+	}
+	return introduced, reviewed, nil
+}
+
+// gatherLimited finds the queue cards of each deck of the tree up to the smallest limit left on its path to the
+// studied deck, and takes the cards found off the limits along that path
+func (s *StudyService) gatherLimited(ctx context.Context, userID int64, tree deckTree, filters card.QueueFilters, left func(n *deckNode) *int) ([]*card.Card, error) {
+	var cards []*card.Card
+	for i := range tree {
+		limit := *left(&tree[i])
+		for p := tree[i].parent; p >= 0; p = tree[p].parent {
+			limit = min(limit, *left(&tree[p]))
+		}
+		if limit <= 0 {
+			continue
+		}
+
+		filters.DeckIDs = []int64{tree[i].deck.GetID()}
+		filters.Limit = limit
+		found, err := s.cardRepo.FindQueueCards(ctx, userID, filters)
+		if err != nil {
+			return nil, err
+		}
+		for p := i; p >= 0; p = tree[p].parent {
+			*left(&tree[p]) -= len(found)
+		}
+		cards = append(cards, found...)
+	}
+	return cards, nil
+}
+
+// UnburyAtDayRollover unburies the cards of every user whose study day rolled over since they were buried
+func (s *StudyService) UnburyAtDayRollover(ctx context.Context) (int64, error) {
+	return s.cardRepo.UnburyBeforeDayStart(ctx, time.Now())
+}
+
+// collectDeckIDs returns the ID of the deck and of all its subdecks
+func collectDeckIDs(ctx context.Context, deckRepo secondary.IDeckRepository, userID int64, root *deck.Deck) ([]int64, error) {
+	tree, err := collectDeckTree(ctx, deckRepo, userID, root)
+	if err != nil {
+		return nil, err
+	}
+	return tree.deckIDs(), nil
+}
+
+// deckNode is a deck of a studied tree with what is left of its daily limits
+type deckNode struct {
+	deck        *deck.Deck
+	parent      int // Index of the parent deck in the tree, -1 for the studied deck
+	newLeft     int
+	reviewsLeft int
+}
+
+// deckTree lists a deck and all its subdecks, each deck before its subdecks
+type deckTree []deckNode
+
+// collectDeckTree returns the deck and all its subdecks
+func collectDeckTree(ctx context.Context, deckRepo secondary.IDeckRepository, userID int64, root *deck.Deck) (deckTree, error) {
+	tree := deckTree{{deck: root, parent: -1}}
+	for i := 0; i < len(tree); i++ {
+		children, err := deckRepo.FindByParentID(ctx, userID, tree[i].deck.GetID())
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			tree = append(tree, deckNode{deck: child, parent: i})
+		}
+	}
+	return tree, nil
+}
+
+// deckIDs returns the IDs of all the decks of the tree
+func (t deckTree) deckIDs() []int64 {
+	return t.subtreeIDs(0)
+}
+
+// subtreeIDs returns the ID of the deck at index i and of all its subdecks
+func (t deckTree) subtreeIDs(i int) []int64 {
+	ids := []int64{t[i].deck.GetID()}
+	for j := i + 1; j < len(t); j++ {
+		for p := t[j].parent; p >= i; p = t[p].parent {
+			if p == i {
+				ids = append(ids, t[j].deck.GetID())
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// filterLearning keeps the learning cards that can be studied now: interday cards (last answered
+// before today) due today, and intraday cards due within the learn ahead cutoff
+func filterLearning(cards []*card.Card, dayStart time.Time, cutoff int64) []*card.Card {
+	result := make([]*card.Card, 0, len(cards))
+	for _, c := range cards {
+		if c.IsInterdayLearning(dayStart) || c.GetDue() <= cutoff {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// timeForNewCard decides whether the next card should be a new one so that new cards are
+// spread evenly among reviews, given the number of cards answered today
+func timeForNewCard(repsToday, newCount, reviewCount int) bool {
+	if reviewCount == 0 {
+		return true
+	}
+	modulus := max((newCount+reviewCount)/newCount, 2)
+	return repsToday > 0 && repsToday%modulus == 0
+}
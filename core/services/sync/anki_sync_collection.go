@@ -0,0 +1,560 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver for Anki collection databases
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	userpreferences "github.com/felipesantos/anki-backend/core/domain/entities/user_preferences"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/services/export"
+	"github.com/felipesantos/anki-backend/pkg/protomsg"
+)
+
+// collectionFile is the content of a collection database exchanged by a full sync
+// Note types, decks and deck options are legacy JSON objects, as in the col table of schema 11
+type collectionFile struct {
+	crt            int64 // Collection creation in seconds
+	usn            int
+	modified       int64 // Milliseconds
+	schemaModified int64 // Milliseconds
+	configJSON     string
+	models         []map[string]interface{}
+	decks          []map[string]interface{}
+	deckConfigs    []map[string]interface{}
+	entries        *primary.AnkiSyncChunk
+}
+
+// readCollection reads an uploaded collection database of schema 11 or 18
+func readCollection(ctx context.Context, data []byte) (*collectionFile, error) {
+	tmp, err := os.CreateTemp("", "anki-sync-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary collection file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temporary collection file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temporary collection file: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+tmp.Name()+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+	defer db.Close()
+
+	col := &collectionFile{entries: &primary.AnkiSyncChunk{Done: true}}
+	err = db.QueryRowContext(ctx, "SELECT crt, mod, scm, usn FROM col").Scan(&col.crt, &col.modified, &col.schemaModified, &col.usn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Schema 18 moved note types, decks, deck options and the configuration out of the col table
+	var schema18 bool
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'notetypes'").Scan(&schema18); err != nil {
+		return nil, err
+	}
+	if schema18 {
+		err = readSchema18(ctx, db, col)
+	} else {
+		err = readLegacyCol(ctx, db, col)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readNotes(ctx, db, col.entries); err != nil {
+		return nil, err
+	}
+	if err := readCards(ctx, db, col.entries); err != nil {
+		return nil, err
+	}
+	return col, readRevlog(ctx, db, col.entries)
+}
+
+// readLegacyCol reads the JSON columns of the col table of schema 11
+func readLegacyCol(ctx context.Context, db *sql.DB, col *collectionFile) error {
+	var conf, models, decks, dconf string
+	if err := db.QueryRowContext(ctx, "SELECT conf, models, decks, dconf FROM col").Scan(&conf, &models, &decks, &dconf); err != nil {
+		return err
+	}
+	col.configJSON = conf
+
+	for _, column := range []struct {
+		name string
+		data string
+		dest *[]map[string]interface{}
+	}{
+		{"note types", models, &col.models},
+		{"decks", decks, &col.decks},
+		{"deck options", dconf, &col.deckConfigs},
+	} {
+		var objects map[string]map[string]interface{}
+		if err := json.Unmarshal([]byte(column.data), &objects); err != nil {
+			return fmt.Errorf("invalid %s: %w", column.name, err)
+		}
+		for _, object := range objects {
+			*column.dest = append(*column.dest, object)
+		}
+	}
+	return nil
+}
+
+// readSchema18 reads the tables of schema 18, converting their protobuf configs to legacy JSON objects
+func readSchema18(ctx context.Context, db *sql.DB, col *collectionFile) error {
+	if err := readNotetypes(ctx, db, col); err != nil {
+		return err
+	}
+	if err := readDecks(ctx, db, col); err != nil {
+		return err
+	}
+	if err := readDeckConfigs(ctx, db, col); err != nil {
+		return err
+	}
+	for _, objects := range []*[]map[string]interface{}{&col.models, &col.decks, &col.deckConfigs} {
+		if err := normalizeJSON(objects); err != nil {
+			return err
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT KEY, val FROM config")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	conf := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var key string
+		var val []byte
+		if err := rows.Scan(&key, &val); err != nil {
+			return err
+		}
+		if json.Valid(val) {
+			conf[key] = val
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	col.configJSON = string(data)
+	return nil
+}
+
+// readNotetypes reads the notetypes, fields and templates tables as legacy model objects
+func readNotetypes(ctx context.Context, db *sql.DB, col *collectionFile) error {
+	models := make(map[int64]map[string]interface{})
+	rows, err := db.QueryContext(ctx, "SELECT id, name, mtime_secs, config FROM notetypes ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, mod int64
+		var name string
+		var config []byte
+		if err := rows.Scan(&id, &name, &mod, &config); err != nil {
+			return err
+		}
+		// Notetype.Config: kind = 1 (1 is cloze), css = 3
+		cfg, err := protomsg.Decode(config)
+		if err != nil {
+			return fmt.Errorf("invalid config of note type %d: %w", id, err)
+		}
+		m := map[string]interface{}{
+			"id": id, "name": name, "mod": mod, "type": cfg.Uint(1), "css": cfg.Str(3),
+			"flds": []interface{}{}, "tmpls": []interface{}{},
+		}
+		models[id] = m
+		col.models = append(col.models, m)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	fieldRows, err := db.QueryContext(ctx, "SELECT ntid, ord, name, config FROM fields ORDER BY ntid, ord")
+	if err != nil {
+		return err
+	}
+	defer fieldRows.Close()
+	for fieldRows.Next() {
+		var ntid int64
+		var ord int
+		var name string
+		var config []byte
+		if err := fieldRows.Scan(&ntid, &ord, &name, &config); err != nil {
+			return err
+		}
+		// Field.Config: sticky = 1, rtl = 2, font_name = 3, font_size = 4
+		cfg, err := protomsg.Decode(config)
+		if err != nil {
+			return fmt.Errorf("invalid config of field %s: %w", name, err)
+		}
+		if m := models[ntid]; m != nil {
+			m["flds"] = append(m["flds"].([]interface{}), map[string]interface{}{
+				"name": name, "ord": ord, "sticky": cfg.Uint(1) != 0, "rtl": cfg.Uint(2) != 0,
+				"font": cfg.Str(3), "size": cfg.Uint(4),
+			})
+		}
+	}
+	if err := fieldRows.Err(); err != nil {
+		return err
+	}
+
+	templateRows, err := db.QueryContext(ctx, "SELECT ntid, ord, name, config FROM templates ORDER BY ntid, ord")
+	if err != nil {
+		return err
+	}
+	defer templateRows.Close()
+	for templateRows.Next() {
+		var ntid int64
+		var ord int
+		var name string
+		var config []byte
+		if err := templateRows.Scan(&ntid, &ord, &name, &config); err != nil {
+			return err
+		}
+		// Template.Config: q_format = 1, a_format = 2, q_format_browser = 3, a_format_browser = 4
+		cfg, err := protomsg.Decode(config)
+		if err != nil {
+			return fmt.Errorf("invalid config of template %s: %w", name, err)
+		}
+		if m := models[ntid]; m != nil {
+			m["tmpls"] = append(m["tmpls"].([]interface{}), map[string]interface{}{
+				"name": name, "ord": ord, "qfmt": cfg.Str(1), "afmt": cfg.Str(2), "bqfmt": cfg.Str(3), "bafmt": cfg.Str(4),
+			})
+		}
+	}
+	return templateRows.Err()
+}
+
+// readDecks reads the decks table as legacy deck objects
+func readDecks(ctx context.Context, db *sql.DB, col *collectionFile) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, mtime_secs, kind FROM decks ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, mod int64
+		var name string
+		var kind []byte
+		if err := rows.Scan(&id, &name, &mod, &kind); err != nil {
+			return err
+		}
+		// Deck kind: normal = 1 {config_id = 1}, filtered = 2 {reschedule = 1, search_terms = 2 {search = 1, limit = 2, order = 3}}
+		k, err := protomsg.Decode(kind)
+		if err != nil {
+			return fmt.Errorf("invalid kind of deck %d: %w", id, err)
+		}
+		m := map[string]interface{}{"id": id, "name": strings.ReplaceAll(name, "\x1f", "::"), "mod": mod, "dyn": 0}
+
+		filtered, err := k.Messages(2)
+		if err != nil {
+			return fmt.Errorf("invalid kind of deck %d: %w", id, err)
+		}
+		if len(filtered) > 0 {
+			f := filtered[len(filtered)-1]
+			searches, err := f.Messages(2)
+			if err != nil {
+				return fmt.Errorf("invalid search terms of deck %d: %w", id, err)
+			}
+			terms := make([]interface{}, 0, len(searches))
+			for _, term := range searches {
+				terms = append(terms, []interface{}{term.Str(1), term.Uint(2), term.Uint(3)})
+			}
+			m["dyn"], m["terms"], m["resched"] = 1, terms, f.Uint(1) != 0
+		} else {
+			normal, err := k.Messages(1)
+			if err != nil {
+				return fmt.Errorf("invalid kind of deck %d: %w", id, err)
+			}
+			confID := uint64(ankiDefaultID)
+			if len(normal) > 0 && normal[len(normal)-1].Uint(1) != 0 {
+				confID = normal[len(normal)-1].Uint(1)
+			}
+			m["conf"] = confID
+		}
+		col.decks = append(col.decks, m)
+	}
+	return rows.Err()
+}
+
+// readDeckConfigs reads the deck_config table as legacy deck options objects
+func readDeckConfigs(ctx context.Context, db *sql.DB, col *collectionFile) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, mtime_secs, config FROM deck_config ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, mod int64
+		var name string
+		var config []byte
+		if err := rows.Scan(&id, &name, &mod, &config); err != nil {
+			return err
+		}
+		// DeckConfig.Config field numbers, see the field comments below
+		cfg, err := protomsg.Decode(config)
+		if err != nil {
+			return fmt.Errorf("invalid config of deck options %d: %w", id, err)
+		}
+		leechAction := 0
+		if cfg.Uint(21) == 1 {
+			leechAction = 1
+		}
+		col.deckConfigs = append(col.deckConfigs, map[string]interface{}{
+			"id":   id,
+			"name": name,
+			"mod":  mod,
+			"new": map[string]interface{}{
+				"delays":        cfg.Floats(1),                           // learn_steps
+				"ints":          []uint64{cfg.Uint(18), cfg.Uint(19), 0}, // graduating_interval_good, graduating_interval_easy
+				"initialFactor": cfg.Float(11) * 1000,                    // initial_ease
+				"perDay":        cfg.Uint(9),                             // new_per_day
+				"bury":          cfg.Uint(27) != 0,                       // bury_new
+			},
+			"lapse": map[string]interface{}{
+				"delays":      cfg.Floats(2), // relearn_steps
+				"mult":        cfg.Float(14), // lapse_multiplier
+				"minInt":      cfg.Uint(17),  // minimum_lapse_interval
+				"leechFails":  cfg.Uint(22),  // leech_threshold
+				"leechAction": leechAction,   // leech_action: 0 suspend, 1 tag only
+			},
+			"rev": map[string]interface{}{
+				"perDay":     cfg.Uint(10),  // reviews_per_day
+				"ease4":      cfg.Float(12), // easy_multiplier
+				"ivlFct":     cfg.Float(15), // interval_multiplier
+				"maxIvl":     cfg.Uint(16),  // maximum_review_interval
+				"hardFactor": cfg.Float(13), // hard_multiplier
+				"bury":       cfg.Uint(28) != 0,
+			},
+			"buryInterdayLearning": cfg.Uint(29) != 0,
+			"desiredRetention":     cfg.Float(37),
+		})
+	}
+	return rows.Err()
+}
+
+// normalizeJSON round trips JSON objects so their numbers are float64, as when decoded from a request
+func normalizeJSON(objects *[]map[string]interface{}) error {
+	data, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, objects)
+}
+
+func readNotes(ctx context.Context, db *sql.DB, entries *primary.AnkiSyncChunk) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, guid, mid, mod, usn, tags, flds, flags, data FROM notes ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e primary.AnkiSyncNoteEntry
+		if err := rows.Scan(&e.ID, &e.GUID, &e.ModelID, &e.Modified, &e.USN, &e.Tags, &e.Fields, &e.Flags, &e.Data); err != nil {
+			return err
+		}
+		entries.Notes = append(entries.Notes, e)
+	}
+	return rows.Err()
+}
+
+func readCards(ctx context.Context, db *sql.DB, entries *primary.AnkiSyncChunk) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data
+		FROM cards ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e primary.AnkiSyncCardEntry
+		if err := rows.Scan(&e.ID, &e.NoteID, &e.DeckID, &e.Ord, &e.Modified, &e.USN, &e.Type, &e.Queue, &e.Due, &e.Interval,
+			&e.Factor, &e.Reps, &e.Lapses, &e.Left, &e.OrigDue, &e.OrigDeck, &e.Flags, &e.Data); err != nil {
+			return err
+		}
+		entries.Cards = append(entries.Cards, e)
+	}
+	return rows.Err()
+}
+
+func readRevlog(ctx context.Context, db *sql.DB, entries *primary.AnkiSyncChunk) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, cid, usn, ease, ivl, lastIvl, factor, time, type FROM revlog ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e primary.AnkiSyncRevlogEntry
+		if err := rows.Scan(&e.ID, &e.CardID, &e.USN, &e.Ease, &e.Interval, &e.LastInterval, &e.Factor, &e.TimeMs, &e.Type); err != nil {
+			return err
+		}
+		entries.Revlog = append(entries.Revlog, e)
+	}
+	return rows.Err()
+}
+
+// writeCollection writes a schema 11 collection database for a full download
+// Every collection needs the Default deck and Default deck options, which are added when not synced
+func writeCollection(ctx context.Context, col *collectionFile, now time.Time) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "anki-sync-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary collection file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	db, err := sql.Open("sqlite", tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+	if err := writeCollectionTables(ctx, db, col, now); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := db.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close collection: %w", err)
+	}
+	return os.ReadFile(tmp.Name())
+}
+
+func writeCollectionTables(ctx context.Context, db *sql.DB, col *collectionFile, now time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin collection transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, export.AnkiSchema); err != nil {
+		return fmt.Errorf("failed to create collection schema: %w", err)
+	}
+
+	for _, n := range col.entries.Notes {
+		sortField := export.StripHTML(strings.SplitN(n.Fields, "\x1f", 2)[0])
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			n.ID, n.GUID, n.ModelID, n.Modified, n.USN, n.Tags, n.Fields, sortField, export.FieldChecksum(sortField), n.Flags, n.Data,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write note: %w", err)
+		}
+	}
+	for _, c := range col.entries.Cards {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.ID, c.NoteID, c.DeckID, c.Ord, c.Modified, c.USN, c.Type, c.Queue, c.Due, c.Interval,
+			c.Factor, c.Reps, c.Lapses, c.Left, c.OrigDue, c.OrigDeck, c.Flags, c.Data,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write card: %w", err)
+		}
+	}
+	for _, r := range col.entries.Revlog {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO revlog (id, cid, usn, ease, ivl, lastIvl, factor, time, type) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			r.ID, r.CardID, r.USN, r.Ease, r.Interval, r.LastInterval, r.Factor, r.TimeMs, r.Type,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write review log: %w", err)
+		}
+	}
+
+	models := keyedByID(col.models)
+	decks := keyedByID(col.decks)
+	dconf := keyedByID(col.deckConfigs)
+	defaultKey := strconv.Itoa(ankiDefaultID)
+	if decks[defaultKey] == nil {
+		decks[defaultKey] = export.AnkiDeck(ankiDefaultID, ankiDefaultDeckName, ankiDefaultID, now)
+	}
+	if dconf[defaultKey] == nil {
+		dconf[defaultKey] = export.AnkiDeckConfig(ankiDefaultID, "Default", deck.DefaultDeckOptions(), now)
+	}
+
+	conf := col.configJSON
+	if conf == "" {
+		data, err := json.Marshal(defaultCollectionConfig(col.models, col.entries.Cards))
+		if err != nil {
+			return fmt.Errorf("failed to marshal collection: %w", err)
+		}
+		conf = string(data)
+	}
+	values := []interface{}{conf}
+	for _, v := range []interface{}{models, decks, dconf, map[string]interface{}{}} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal collection: %w", err)
+		}
+		values = append(values, string(data))
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags) VALUES (1, ?, ?, ?, 11, 0, ?, ?, ?, ?, ?, ?, ?)",
+		append([]interface{}{col.crt, col.modified, col.schemaModified, col.usn, col.modified}, values...)...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write collection: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit collection: %w", err)
+	}
+	return nil
+}
+
+// keyedByID returns JSON objects keyed by their id, as the col table stores them
+func keyedByID(objects []map[string]interface{}) map[string]interface{} {
+	keyed := make(map[string]interface{}, len(objects))
+	for _, object := range objects {
+		keyed[strconv.FormatInt(int64Value(object, "id"), 10)] = object
+	}
+	return keyed
+}
+
+// defaultCollectionConfig returns the configuration of a collection no client has synced yet
+func defaultCollectionConfig(models []map[string]interface{}, cards []primary.AnkiSyncCardEntry) map[string]interface{} {
+	var curModel interface{}
+	if len(models) > 0 {
+		curModel = models[0]["id"]
+	}
+	nextPos := int64(1)
+	for _, c := range cards {
+		if c.Type == export.AnkiCardNew && c.Due >= nextPos {
+			nextPos = c.Due + 1
+		}
+	}
+	return map[string]interface{}{
+		"nextPos":       nextPos,
+		"schedVer":      2,
+		"sched2021":     true,
+		"rollover":      userpreferences.DefaultNextDayStartsAtHour,
+		"curDeck":       ankiDefaultID,
+		"activeDecks":   []int{ankiDefaultID},
+		"curModel":      curModel,
+		"sortType":      "noteFld",
+		"sortBackwards": false,
+		"addToCur":      true,
+		"newSpread":     0,
+		"collapseTime":  1200,
+		"timeLim":       0,
+		"estTimes":      true,
+		"dueCounts":     true,
+	}
+}
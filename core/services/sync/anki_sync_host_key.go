@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// Host keys are signed tokens that are only accepted while they are stored in the cache,
+// so they can be revoked like refresh tokens. Revoking every key of a user records the time
+// of the revocation; keys issued before it are rejected.
+const (
+	hostKeyKeyPrefix        = "anki_sync_host_key:"
+	hostKeyRevokedKeyPrefix = "anki_sync_host_keys_revoked:"
+)
+
+// hostKeyEntry is the cached state of a host key
+type hostKeyEntry struct {
+	UserID   int64 `json:"user_id"`
+	IssuedAt int64 `json:"issued_at"` // Unix nanoseconds
+}
+
+// saveHostKey stores a newly issued host key of a user
+func saveHostKey(ctx context.Context, cacheRepo secondary.ICacheRepository, key string, userID int64, ttl time.Duration) error {
+	data, err := json.Marshal(hostKeyEntry{UserID: userID, IssuedAt: time.Now().UnixNano()})
+	if err != nil {
+		return err
+	}
+	return cacheRepo.Set(ctx, hostKeyCacheKey(key), string(data), ttl)
+}
+
+// findHostKey returns the user of a stored host key, or 0 if the key is unknown or revoked
+func findHostKey(ctx context.Context, cacheRepo secondary.ICacheRepository, key string) (int64, error) {
+	cacheKey := hostKeyCacheKey(key)
+	exists, err := cacheRepo.Exists(ctx, cacheKey)
+	if err != nil || !exists {
+		return 0, err
+	}
+	data, err := cacheRepo.Get(ctx, cacheKey)
+	if err != nil {
+		return 0, err
+	}
+	var entry hostKeyEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return 0, nil
+	}
+
+	revokedKey := hostKeyRevokedCacheKey(entry.UserID)
+	revoked, err := cacheRepo.Exists(ctx, revokedKey)
+	if err != nil {
+		return 0, err
+	}
+	if revoked {
+		value, err := cacheRepo.Get(ctx, revokedKey)
+		if err != nil {
+			return 0, err
+		}
+		if revokedAt, _ := strconv.ParseInt(value, 10, 64); entry.IssuedAt <= revokedAt {
+			return 0, nil
+		}
+	}
+	return entry.UserID, nil
+}
+
+// RevokeHostKeys revokes every sync host key issued to a user, forcing Anki clients to log in again
+// It is called when the user logs out or changes their password
+func RevokeHostKeys(ctx context.Context, cacheRepo secondary.ICacheRepository, userID int64, ttl time.Duration) error {
+	value := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := cacheRepo.Set(ctx, hostKeyRevokedCacheKey(userID), value, ttl); err != nil {
+		return fmt.Errorf("failed to revoke sync host keys: %w", err)
+	}
+	return nil
+}
+
+func hostKeyCacheKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hostKeyKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+func hostKeyRevokedCacheKey(userID int64) string {
+	return fmt.Sprintf("%s%d", hostKeyRevokedKeyPrefix, userID)
+}
@@ -0,0 +1,1158 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/core/services/export"
+	"github.com/felipesantos/anki-backend/core/services/importer"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
+)
+
+// Changes from a client replace the server's unless the server's copy was modified later.
+// Objects the client has never seen are matched by their Anki IDs first, then by name (decks, note
+// types), GUID (notes) or note and template (cards), and are added when nothing matches.
+
+// deleteGraves deletes the objects the client deleted; objects already gone are skipped
+func (s *AnkiSyncService) deleteGraves(ctx context.Context, userID int64, graves *primary.AnkiSyncGraves) error {
+	return s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		ids, err := s.objectIDs(txCtx, userID, secondary.SyncObjectCard, graves.Cards)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := s.cardRepo.Delete(txCtx, userID, id.ObjectID); err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+				return fmt.Errorf("failed to delete card: %w", err)
+			}
+		}
+
+		ids, err = s.objectIDs(txCtx, userID, secondary.SyncObjectNote, graves.Notes)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := s.noteRepo.Delete(txCtx, userID, id.ObjectID); err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+				return fmt.Errorf("failed to delete note: %w", err)
+			}
+		}
+
+		ids, err = s.objectIDs(txCtx, userID, secondary.SyncObjectDeck, graves.Decks)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if id.ObjectType == secondary.SyncObjectFilteredDeck {
+				err = s.filteredDeckRepo.Delete(txCtx, userID, id.ObjectID)
+			} else {
+				err = s.deckRepo.Delete(txCtx, userID, id.ObjectID)
+			}
+			if err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+				return fmt.Errorf("failed to delete deck: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// objectIDs finds the objects with Anki IDs
+func (s *AnkiSyncService) objectIDs(ctx context.Context, userID int64, objectType string, ankiIDs []int64) ([]*secondary.AnkiSyncID, error) {
+	if len(ankiIDs) == 0 {
+		return nil, nil
+	}
+	return s.syncRepo.FindObjectIDs(ctx, userID, objectType, ankiIDs)
+}
+
+// objectIDMap maps Anki IDs to the objects of a type with them
+func (s *AnkiSyncService) objectIDMap(ctx context.Context, userID int64, objectType string, ankiIDs []int64) (map[int64]*secondary.AnkiSyncID, error) {
+	ids, err := s.objectIDs(ctx, userID, objectType, ankiIDs)
+	if err != nil {
+		return nil, err
+	}
+	byAnkiID := make(map[int64]*secondary.AnkiSyncID, len(ids))
+	for _, id := range ids {
+		byAnkiID[id.AnkiID] = id
+	}
+	return byAnkiID, nil
+}
+
+// saveID records the Anki ID of an object
+func (s *AnkiSyncService) saveID(ctx context.Context, userID int64, objectType string, objectID, ankiID int64, ankiGUID string) error {
+	id := &secondary.AnkiSyncID{ObjectType: objectType, ObjectID: objectID, AnkiID: ankiID, AnkiGUID: ankiGUID}
+	return s.syncRepo.SaveIDs(ctx, userID, []*secondary.AnkiSyncID{id})
+}
+
+// applyDeckConfigs merges the client's deck options into presets
+// Decks whose options equal a preset's old options follow the preset's new options; the client's
+// Default deck options are added as a preset the first time, taking over the decks with default options
+func (s *AnkiSyncService) applyDeckConfigs(ctx context.Context, userID int64, configs []map[string]interface{}) error {
+	for _, conf := range configs {
+		ankiID := int64Value(conf, "id")
+		if ankiID == 0 {
+			continue
+		}
+		name := stringValue(conf, "name", "Default")
+		modified := time.Unix(int64Value(conf, "mod"), 0)
+
+		found, err := s.objectIDs(ctx, userID, secondary.SyncObjectPreset, []int64{ankiID})
+		if err != nil {
+			return err
+		}
+		if len(found) > 0 {
+			p, err := s.presetRepo.FindByID(ctx, userID, found[0].ObjectID)
+			if err != nil {
+				return fmt.Errorf("failed to find deck options preset: %w", err)
+			}
+			if p == nil || p.GetUpdatedAt().After(modified) {
+				continue
+			}
+			old, err := deck.ParseDeckOptions(p.GetOptionsJSON())
+			if err != nil {
+				old = deck.DefaultDeckOptions()
+			}
+			opts, err := deckOptionsFromConfig(conf, old)
+			if err != nil {
+				return err
+			}
+			optionsJSON, _ := json.Marshal(opts)
+			if name != p.GetName() {
+				if name, err = s.uniquePresetName(ctx, userID, name); err != nil {
+					return err
+				}
+				p.SetName(name)
+			}
+			p.SetOptionsJSON(string(optionsJSON))
+			p.SetUpdatedAt(modified)
+			if err := s.presetRepo.Update(ctx, userID, p.GetID(), p); err != nil {
+				return fmt.Errorf("failed to update deck options preset: %w", err)
+			}
+			if err := s.updateDeckOptions(ctx, userID, export.OptionsKey(old), opts); err != nil {
+				return err
+			}
+			continue
+		}
+
+		opts, err := deckOptionsFromConfig(conf, deck.DefaultDeckOptions())
+		if err != nil {
+			return err
+		}
+		if ankiID == ankiDefaultID {
+			if err := s.updateDeckOptions(ctx, userID, export.OptionsKey(deck.DefaultDeckOptions()), opts); err != nil {
+				return err
+			}
+		}
+		p, err := s.addPreset(ctx, userID, name, opts)
+		if err != nil {
+			return err
+		}
+		if err := s.saveID(ctx, userID, secondary.SyncObjectPreset, p.GetID(), ankiID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateDeckOptions gives the decks whose options have a key the new options
+func (s *AnkiSyncService) updateDeckOptions(ctx context.Context, userID int64, key string, opts *deck.DeckOptions) error {
+	if export.OptionsKey(opts) == key {
+		return nil
+	}
+	decks, err := s.deckRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return fmt.Errorf("failed to find decks: %w", err)
+	}
+	optionsJSON, _ := json.Marshal(opts)
+	for _, d := range decks {
+		current, err := d.GetOptions()
+		if err != nil || export.OptionsKey(current) != key {
+			continue
+		}
+		d.SetOptionsJSON(string(optionsJSON))
+		if err := s.deckRepo.Update(ctx, userID, d.GetID(), d); err != nil {
+			return fmt.Errorf("failed to update deck: %w", err)
+		}
+	}
+	return nil
+}
+
+// deckOptionsFromConfig overlays the settings of a legacy deck options object onto deck options
+func deckOptionsFromConfig(conf map[string]interface{}, base *deck.DeckOptions) (*deck.DeckOptions, error) {
+	opts := *base
+	if newConf, ok := conf["new"].(map[string]interface{}); ok {
+		if delays, ok := floatList(newConf["delays"]); ok {
+			opts.NewSteps = delays
+		}
+		if ints, ok := floatList(newConf["ints"]); ok && len(ints) >= 2 {
+			opts.GraduatingInterval, opts.EasyInterval = int(ints[0]), int(ints[1])
+		}
+		if factor, ok := newConf["initialFactor"].(float64); ok && factor > 0 {
+			opts.StartingEase = factor / 1000
+		}
+		setInt(&opts.NewPerDay, newConf["perDay"])
+		setBool(&opts.BuryNew, newConf["bury"])
+	}
+	if lapse, ok := conf["lapse"].(map[string]interface{}); ok {
+		if delays, ok := floatList(lapse["delays"]); ok {
+			opts.RelearnSteps = delays
+		}
+		setFloat(&opts.LapseNewInterval, lapse["mult"])
+		setInt(&opts.MinimumInterval, lapse["minInt"])
+		setInt(&opts.LeechThreshold, lapse["leechFails"])
+		if action, ok := lapse["leechAction"].(float64); ok {
+			opts.LeechAction = valueobjects.LeechActionTagOnly
+			if action == 0 {
+				opts.LeechAction = valueobjects.LeechActionSuspend
+			}
+		}
+	}
+	if rev, ok := conf["rev"].(map[string]interface{}); ok {
+		setInt(&opts.ReviewsPerDay, rev["perDay"])
+		setFloat(&opts.EasyBonus, rev["ease4"])
+		setFloat(&opts.IntervalModifier, rev["ivlFct"])
+		setInt(&opts.MaximumInterval, rev["maxIvl"])
+		setFloat(&opts.HardInterval, rev["hardFactor"])
+		setBool(&opts.BuryReviews, rev["bury"])
+	}
+	setBool(&opts.BuryInterdayLearning, conf["buryInterdayLearning"])
+	setFloat(&opts.DesiredRetention, conf["desiredRetention"])
+	if weights, ok := floatList(conf["fsrsWeights"]); ok {
+		opts.FSRSWeights = weights
+	}
+
+	// Round trip through JSON so the options are normalized like stored ones
+	data, err := json.Marshal(&opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode deck options: %w", err)
+	}
+	return deck.ParseDeckOptions(string(data))
+}
+
+// applyDecks merges the client's decks and filtered decks
+// Parents are merged before their children, creating missing levels
+func (s *AnkiSyncService) applyDecks(ctx context.Context, userID int64, decks []map[string]interface{}) error {
+	sorted := append([]map[string]interface{}(nil), decks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return stringValue(sorted[i], "name", "") < stringValue(sorted[j], "name", "")
+	})
+
+	for _, m := range sorted {
+		ankiID := int64Value(m, "id")
+		name := normalizeDeckName(stringValue(m, "name", ""))
+		if ankiID == 0 || name == "" {
+			continue
+		}
+		found, err := s.objectIDs(ctx, userID, secondary.SyncObjectDeck, []int64{ankiID})
+		if err != nil {
+			return err
+		}
+		var mapped *secondary.AnkiSyncID
+		if len(found) > 0 {
+			mapped = found[0]
+		}
+
+		if int64Value(m, "dyn") != 0 || m["dyn"] == true {
+			err = s.applyFilteredDeck(ctx, userID, ankiID, name, m, mapped)
+		} else {
+			err = s.applyDeck(ctx, userID, ankiID, name, m, mapped)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDeck merges a normal deck, renaming or moving the mapped deck when the client's is newer
+func (s *AnkiSyncService) applyDeck(ctx context.Context, userID int64, ankiID int64, name string, m map[string]interface{}, mapped *secondary.AnkiSyncID) error {
+	if mapped != nil && mapped.ObjectType != secondary.SyncObjectDeck {
+		return nil
+	}
+	modified := time.Unix(int64Value(m, "mod"), 0)
+	optionsJSON, err := s.presetOptionsJSON(ctx, userID, int64Value(m, "conf"))
+	if err != nil {
+		return err
+	}
+	tree, err := s.loadDeckTree(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if mapped == nil {
+		d, err := tree.ensure(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := s.saveID(ctx, userID, secondary.SyncObjectDeck, d.GetID(), ankiID, ""); err != nil {
+			return err
+		}
+		if optionsJSON == "" {
+			return nil
+		}
+		d.SetOptionsJSON(optionsJSON)
+		d.SetUpdatedAt(modified)
+		if err := s.deckRepo.Update(ctx, userID, d.GetID(), d); err != nil {
+			return fmt.Errorf("failed to update deck: %w", err)
+		}
+		return nil
+	}
+
+	d, err := s.deckRepo.FindByID(ctx, userID, mapped.ObjectID)
+	if err != nil {
+		return fmt.Errorf("failed to find deck: %w", err)
+	}
+	if d == nil {
+		// A deck deleted on the server while the client changed it comes back
+		if err := s.deckRepo.Restore(ctx, userID, mapped.ObjectID); err != nil && !errors.Is(err, ownership.ErrResourceNotFound) {
+			return fmt.Errorf("failed to restore deck: %w", err)
+		}
+		if d, err = s.deckRepo.FindByID(ctx, userID, mapped.ObjectID); err != nil || d == nil {
+			return err
+		}
+		if tree, err = s.loadDeckTree(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if d.GetUpdatedAt().After(modified) {
+		return nil
+	}
+
+	parentID, leaf := (*int64)(nil), name
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		parent, err := tree.ensure(ctx, name[:i])
+		if err != nil {
+			return err
+		}
+		id := parent.GetID()
+		parentID, leaf = &id, name[i+2:]
+	}
+	d.SetName(leaf)
+	d.SetParentID(parentID)
+	if optionsJSON != "" {
+		d.SetOptionsJSON(optionsJSON)
+	}
+	d.SetUpdatedAt(modified)
+	if err := s.deckRepo.Update(ctx, userID, d.GetID(), d); err != nil {
+		return fmt.Errorf("failed to update deck: %w", err)
+	}
+	return nil
+}
+
+// presetOptionsJSON returns the options of the preset with an Anki deck options ID, "" when unknown
+func (s *AnkiSyncService) presetOptionsJSON(ctx context.Context, userID int64, confID int64) (string, error) {
+	found, err := s.objectIDs(ctx, userID, secondary.SyncObjectPreset, []int64{confID})
+	if err != nil || len(found) == 0 {
+		return "", err
+	}
+	p, err := s.presetRepo.FindByID(ctx, userID, found[0].ObjectID)
+	if err != nil {
+		return "", fmt.Errorf("failed to find deck options preset: %w", err)
+	}
+	if p == nil {
+		return "", nil
+	}
+	return p.GetOptionsJSON(), nil
+}
+
+// applyFilteredDeck merges a filtered deck's search terms, order and rescheduling
+func (s *AnkiSyncService) applyFilteredDeck(ctx context.Context, userID int64, ankiID int64, name string, m map[string]interface{}, mapped *secondary.AnkiSyncID) error {
+	if mapped != nil && mapped.ObjectType != secondary.SyncObjectFilteredDeck {
+		return nil
+	}
+	modified := time.Unix(int64Value(m, "mod"), 0)
+	search, limit, orderBy, second, secondLimit := "", 100, filtereddeck.OrderDue, (*string)(nil), 0
+	if terms, ok := m["terms"].([]interface{}); ok {
+		for i, term := range terms {
+			values, ok := term.([]interface{})
+			if !ok || len(values) < 3 {
+				continue
+			}
+			text, _ := values[0].(string)
+			if i > 0 {
+				second = &text
+				if n, ok := values[1].(float64); ok && n > 0 {
+					secondLimit = int(n)
+				}
+				continue
+			}
+			search = text
+			if n, ok := values[1].(float64); ok && n > 0 {
+				limit = int(n)
+			}
+			if n, ok := values[2].(float64); ok && int(n) >= 0 && int(n) < len(filtereddeck.Orders) {
+				orderBy = filtereddeck.Orders[int(n)]
+			}
+		}
+	}
+	reschedule := m["resched"] != false
+
+	if mapped != nil {
+		fd, err := s.filteredDeckRepo.FindByID(ctx, userID, mapped.ObjectID)
+		if err != nil {
+			return fmt.Errorf("failed to find filtered deck: %w", err)
+		}
+		if fd == nil || fd.GetUpdatedAt().After(modified) {
+			return nil
+		}
+		fd.SetName(name)
+		fd.SetSearchFilter(search)
+		fd.SetSecondFilter(second)
+		fd.SetLimitCards(limit)
+		fd.SetSecondLimitCards(secondLimit)
+		fd.SetOrderBy(orderBy)
+		fd.SetReschedule(reschedule)
+		fd.SetUpdatedAt(modified)
+		if err := s.filteredDeckRepo.Update(ctx, userID, fd.GetID(), fd); err != nil {
+			return fmt.Errorf("failed to update filtered deck: %w", err)
+		}
+		return nil
+	}
+
+	fd, err := filtereddeck.NewBuilder().
+		WithUserID(userID).
+		WithName(name).
+		WithSearchFilter(search).
+		WithSecondFilter(second).
+		WithLimitCards(limit).
+		WithSecondLimitCards(secondLimit).
+		WithOrderBy(orderBy).
+		WithReschedule(reschedule).
+		WithCreatedAt(time.Now()).
+		WithUpdatedAt(modified).
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed to build filtered deck entity: %w", err)
+	}
+	if err := s.filteredDeckRepo.Save(ctx, userID, fd); err != nil {
+		return fmt.Errorf("failed to save filtered deck: %w", err)
+	}
+	return s.saveID(ctx, userID, secondary.SyncObjectFilteredDeck, fd.GetID(), ankiID, "")
+}
+
+// deckTree resolves full deck names ("Parent::Child") to the user's decks
+type deckTree struct {
+	s      *AnkiSyncService
+	userID int64
+	paths  map[string]*deck.Deck
+}
+
+func (s *AnkiSyncService) loadDeckTree(ctx context.Context, userID int64) (*deckTree, error) {
+	decks, err := s.deckRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find decks: %w", err)
+	}
+	tree := &deckTree{s: s, userID: userID, paths: make(map[string]*deck.Deck, len(decks))}
+	for _, d := range decks {
+		tree.paths[d.GetFullPath(decks)] = d
+	}
+	return tree, nil
+}
+
+// ensure returns the deck with a full name, creating it and its missing parents
+func (t *deckTree) ensure(ctx context.Context, fullName string) (*deck.Deck, error) {
+	var parent *deck.Deck
+	path := ""
+	for _, part := range strings.Split(fullName, "::") {
+		if path != "" {
+			path += "::"
+		}
+		path += part
+		if d := t.paths[path]; d != nil {
+			parent = d
+			continue
+		}
+
+		var parentID *int64
+		if parent != nil {
+			id := parent.GetID()
+			parentID = &id
+		}
+		now := time.Now()
+		d, err := deck.NewBuilder().
+			WithUserID(t.userID).
+			WithName(part).
+			WithParentID(parentID).
+			WithOptionsJSON("{}").
+			WithCreatedAt(now).
+			WithUpdatedAt(now).
+			Build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build deck entity: %w", err)
+		}
+		if err := t.s.deckRepo.Save(ctx, t.userID, d); err != nil {
+			return nil, fmt.Errorf("failed to save deck %s: %w", path, err)
+		}
+		t.paths[path] = d
+		parent = d
+	}
+	return parent, nil
+}
+
+// normalizeDeckName trims the levels of a full deck name and drops empty ones
+// Schema 18 collections separate the levels with 0x1f
+func normalizeDeckName(fullName string) string {
+	parts := strings.Split(strings.ReplaceAll(fullName, "\x1f", "::"), "::")
+	names := parts[:0]
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+	return strings.Join(names, "::")
+}
+
+// ankiModel is the part of a legacy model object the server keeps
+type ankiModel struct {
+	ID    int64               `json:"id"`
+	Name  string              `json:"name"`
+	Type  int                 `json:"type"`
+	Mod   int64               `json:"mod"`
+	CSS   string              `json:"css"`
+	Flds  []ankiModelField    `json:"flds"`
+	Tmpls []ankiModelTemplate `json:"tmpls"`
+}
+
+type ankiModelField struct {
+	Name   string `json:"name"`
+	Ord    int    `json:"ord"`
+	Sticky bool   `json:"sticky"`
+	RTL    bool   `json:"rtl"`
+	Font   string `json:"font"`
+	Size   int    `json:"size"`
+}
+
+type ankiModelTemplate struct {
+	Name  string `json:"name"`
+	Ord   int    `json:"ord"`
+	Qfmt  string `json:"qfmt"`
+	Afmt  string `json:"afmt"`
+	Bqfmt string `json:"bqfmt"`
+	Bafmt string `json:"bafmt"`
+}
+
+// parseAnkiModel decodes a legacy model object, with fields and templates in ord order
+func parseAnkiModel(m map[string]interface{}) (*ankiModel, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var model ankiModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("invalid note type: %w", err)
+	}
+	sort.SliceStable(model.Flds, func(i, j int) bool { return model.Flds[i].Ord < model.Flds[j].Ord })
+	sort.SliceStable(model.Tmpls, func(i, j int) bool { return model.Tmpls[i].Ord < model.Tmpls[j].Ord })
+	return &model, nil
+}
+
+// fieldNames returns the names of the model's fields
+func (m *ankiModel) fieldNames() []string {
+	names := make([]string, len(m.Flds))
+	for i, f := range m.Flds {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// noteTypeJSON returns the fields, card types and templates JSON of a note type
+func (m *ankiModel) noteTypeJSON() (string, string, string) {
+	fields := make([]map[string]interface{}, len(m.Flds))
+	for i, f := range m.Flds {
+		fields[i] = map[string]interface{}{"name": f.Name, "ord": i, "sticky": f.Sticky, "rtl": f.RTL, "font": f.Font, "size": f.Size}
+	}
+	cardTypes := make([]map[string]interface{}, len(m.Tmpls))
+	templates := make([]map[string]interface{}, len(m.Tmpls))
+	for i, t := range m.Tmpls {
+		cardTypes[i] = map[string]interface{}{"name": t.Name, "ord": i}
+		templates[i] = map[string]interface{}{"name": t.Name, "qfmt": t.Qfmt, "afmt": t.Afmt, "bqfmt": t.Bqfmt, "bafmt": t.Bafmt, "css": m.CSS}
+		if m.Type == 1 {
+			cardTypes[i]["cloze"] = true
+		}
+	}
+	fieldsJSON, _ := json.Marshal(fields)
+	cardTypesJSON, _ := json.Marshal(cardTypes)
+	templatesJSON, _ := json.Marshal(templates)
+	return string(fieldsJSON), string(cardTypesJSON), string(templatesJSON)
+}
+
+// applyModels merges the client's note types
+// Adding, removing or renaming fields or templates changes the schema, which requires a full sync
+func (s *AnkiSyncService) applyModels(ctx context.Context, userID int64, models []map[string]interface{}) error {
+	for _, raw := range models {
+		m, err := parseAnkiModel(raw)
+		if err != nil {
+			return err
+		}
+		if m.ID == 0 {
+			continue
+		}
+		modified := time.Unix(m.Mod, 0)
+		fieldsJSON, cardTypesJSON, templatesJSON := m.noteTypeJSON()
+
+		found, err := s.objectIDs(ctx, userID, secondary.SyncObjectNoteType, []int64{m.ID})
+		if err != nil {
+			return err
+		}
+		if len(found) > 0 {
+			nt, err := s.noteTypeRepo.FindByID(ctx, userID, found[0].ObjectID)
+			if err != nil {
+				return fmt.Errorf("failed to find note type: %w", err)
+			}
+			if nt == nil || nt.GetUpdatedAt().After(modified) {
+				continue
+			}
+			if !equalStrings(nt.GetFieldNames(), m.fieldNames()) || nt.GetCardTypeCount() != len(m.Tmpls) {
+				return ErrFullSyncRequired
+			}
+			if m.Name != nt.GetName() {
+				existing, err := s.noteTypeRepo.FindByName(ctx, userID, m.Name)
+				if err != nil {
+					return fmt.Errorf("failed to find note type: %w", err)
+				}
+				if existing == nil {
+					nt.SetName(m.Name)
+				}
+			}
+			nt.SetFieldsJSON(fieldsJSON)
+			nt.SetCardTypesJSON(cardTypesJSON)
+			nt.SetTemplatesJSON(templatesJSON)
+			nt.SetUpdatedAt(modified)
+			if err := s.noteTypeRepo.Update(ctx, userID, nt.GetID(), nt); err != nil {
+				return fmt.Errorf("failed to update note type: %w", err)
+			}
+			continue
+		}
+
+		nt, err := s.matchNoteType(ctx, userID, m)
+		if err != nil {
+			return err
+		}
+		if nt == nil {
+			if nt, err = s.addNoteType(ctx, userID, m, fieldsJSON, cardTypesJSON, templatesJSON); err != nil {
+				return err
+			}
+		}
+		if err := s.saveID(ctx, userID, secondary.SyncObjectNoteType, nt.GetID(), m.ID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchNoteType finds an unsynced note type with the model's name and fields, nil when there is none
+func (s *AnkiSyncService) matchNoteType(ctx context.Context, userID int64, m *ankiModel) (*notetype.NoteType, error) {
+	existing, err := s.noteTypeRepo.FindByName(ctx, userID, m.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find note type: %w", err)
+	}
+	if existing == nil || !equalStrings(existing.GetFieldNames(), m.fieldNames()) {
+		return nil, nil
+	}
+	mapped, err := s.syncRepo.FindAnkiIDs(ctx, userID, secondary.SyncObjectNoteType, []int64{existing.GetID()})
+	if err != nil || len(mapped) > 0 {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// addNoteType adds the model as a note type, numbering its name when another note type has it
+func (s *AnkiSyncService) addNoteType(ctx context.Context, userID int64, m *ankiModel, fieldsJSON, cardTypesJSON, templatesJSON string) (*notetype.NoteType, error) {
+	name := m.Name
+	for i := 2; ; i++ {
+		existing, err := s.noteTypeRepo.FindByName(ctx, userID, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find note type: %w", err)
+		}
+		if existing == nil {
+			break
+		}
+		name = fmt.Sprintf("%s (%d)", m.Name, i)
+	}
+
+	nt, err := notetype.NewBuilder().
+		WithUserID(userID).
+		WithName(name).
+		WithFieldsJSON(fieldsJSON).
+		WithCardTypesJSON(cardTypesJSON).
+		WithTemplatesJSON(templatesJSON).
+		WithCreatedAt(time.UnixMilli(m.ID)).
+		WithUpdatedAt(time.Unix(m.Mod, 0)).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build note type entity: %w", err)
+	}
+	if err := s.noteTypeRepo.Save(ctx, userID, nt); err != nil {
+		return nil, fmt.Errorf("failed to save note type %s: %w", name, err)
+	}
+	return nt, nil
+}
+
+// applyNotes merges the client's notes
+// New notes are matched by GUID, so notes imported from the same Anki deck on both sides are merged
+func (s *AnkiSyncService) applyNotes(ctx context.Context, userID int64, entries []primary.AnkiSyncNoteEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	noteTypes, err := s.mappedNoteTypes(ctx, userID, entries)
+	if err != nil {
+		return err
+	}
+	ankiIDs := make([]int64, len(entries))
+	for i, e := range entries {
+		ankiIDs[i] = e.ID
+	}
+	mapped, err := s.objectIDMap(ctx, userID, secondary.SyncObjectNote, ankiIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		nt := noteTypes[e.ModelID]
+		if nt == nil {
+			continue
+		}
+		fieldsJSON, err := noteFieldsJSON(nt, e.Fields)
+		if err != nil {
+			return err
+		}
+		modified := time.Unix(e.Modified, 0)
+		tags := strings.Fields(e.Tags)
+
+		var existing *note.Note
+		if id := mapped[e.ID]; id != nil {
+			if existing, err = s.findOrRestoreNote(ctx, userID, id.ObjectID); err != nil {
+				return err
+			}
+			if existing == nil {
+				continue
+			}
+		} else {
+			if existing, err = s.findNoteByGUID(ctx, userID, e.GUID); err != nil {
+				return err
+			}
+			if existing != nil && existing.GetNoteTypeID() != nt.GetID() {
+				existing = nil
+			}
+		}
+
+		if existing == nil {
+			guid, _ := valueobjects.NewGUID(importer.NoteGUID(userID, e.GUID))
+			n, err := note.NewBuilder().
+				WithUserID(userID).
+				WithGUID(guid).
+				WithNoteTypeID(nt.GetID()).
+				WithFieldsJSON(fieldsJSON).
+				WithTags(tags).
+				WithCreatedAt(time.UnixMilli(e.ID)).
+				WithUpdatedAt(modified).
+				Build()
+			if err != nil {
+				return fmt.Errorf("failed to build note entity: %w", err)
+			}
+			if err := s.noteRepo.Save(ctx, userID, n); err != nil {
+				return fmt.Errorf("failed to save note: %w", err)
+			}
+			existing = n
+		} else if !existing.GetUpdatedAt().After(modified) {
+			existing.SetFieldsJSON(fieldsJSON)
+			existing.SetTags(tags)
+			existing.SetUpdatedAt(modified)
+			if err := s.noteRepo.Update(ctx, userID, existing.GetID(), existing); err != nil {
+				return fmt.Errorf("failed to update note: %w", err)
+			}
+		}
+
+		if mapped[e.ID] == nil {
+			ankiGUID := ""
+			if e.GUID != existing.GetGUID().Value() {
+				ankiGUID = e.GUID
+			}
+			if err := s.saveID(ctx, userID, secondary.SyncObjectNote, existing.GetID(), e.ID, ankiGUID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mappedNoteTypes returns the note types of the entries' models by Anki ID
+func (s *AnkiSyncService) mappedNoteTypes(ctx context.Context, userID int64, entries []primary.AnkiSyncNoteEntry) (map[int64]*notetype.NoteType, error) {
+	modelIDs := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, e := range entries {
+		if !seen[e.ModelID] {
+			seen[e.ModelID] = true
+			modelIDs = append(modelIDs, e.ModelID)
+		}
+	}
+	ids, err := s.objectIDs(ctx, userID, secondary.SyncObjectNoteType, modelIDs)
+	if err != nil {
+		return nil, err
+	}
+	noteTypes := make(map[int64]*notetype.NoteType, len(ids))
+	for _, id := range ids {
+		nt, err := s.noteTypeRepo.FindByID(ctx, userID, id.ObjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find note type: %w", err)
+		}
+		if nt != nil {
+			noteTypes[id.AnkiID] = nt
+		}
+	}
+	return noteTypes, nil
+}
+
+// findOrRestoreNote finds a note, restoring it when it was deleted on the server
+func (s *AnkiSyncService) findOrRestoreNote(ctx context.Context, userID int64, noteID int64) (*note.Note, error) {
+	n, err := s.noteRepo.FindByID(ctx, userID, noteID)
+	if err != nil || n != nil {
+		return n, err
+	}
+	if err := s.noteRepo.Restore(ctx, userID, noteID); err != nil {
+		if errors.Is(err, ownership.ErrResourceNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to restore note: %w", err)
+	}
+	return s.noteRepo.FindByID(ctx, userID, noteID)
+}
+
+// findNoteByGUID finds the note of an Anki GUID, as the importer stores it
+func (s *AnkiSyncService) findNoteByGUID(ctx context.Context, userID int64, ankiGUID string) (*note.Note, error) {
+	if guid, err := valueobjects.NewGUID(ankiGUID); err == nil {
+		n, err := s.noteRepo.FindByGUID(ctx, userID, guid.Value())
+		if err != nil || n != nil {
+			return n, err
+		}
+	}
+	return s.noteRepo.FindByGUID(ctx, userID, importer.NoteGUID(userID, ankiGUID))
+}
+
+// noteFieldsJSON maps field values separated by 0x1f to the note type's field names
+func noteFieldsJSON(nt *notetype.NoteType, fields string) (string, error) {
+	values := strings.Split(fields, "\x1f")
+	names := nt.GetFieldNames()
+	byName := make(map[string]string, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			byName[name] = values[i]
+		} else {
+			byName[name] = ""
+		}
+	}
+	data, err := json.Marshal(byName)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode fields: %w", err)
+	}
+	return string(data), nil
+}
+
+// applyCards merges the client's cards into their home decks
+// Cards of unknown notes or decks are skipped; the review log of the chunk gives their last review time
+func (s *AnkiSyncService) applyCards(ctx context.Context, userID int64, entries []primary.AnkiSyncCardEntry, revlog []primary.AnkiSyncRevlogEntry, crt int64) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var cardIDs, noteIDs, deckIDs []int64
+	for _, e := range entries {
+		cardIDs = append(cardIDs, e.ID)
+		noteIDs = append(noteIDs, e.NoteID)
+		deckIDs = append(deckIDs, homeDeck(e))
+	}
+	cards, err := s.objectIDMap(ctx, userID, secondary.SyncObjectCard, cardIDs)
+	if err != nil {
+		return err
+	}
+	notes, err := s.objectIDMap(ctx, userID, secondary.SyncObjectNote, noteIDs)
+	if err != nil {
+		return err
+	}
+	decks, err := s.objectIDMap(ctx, userID, secondary.SyncObjectDeck, deckIDs)
+	if err != nil {
+		return err
+	}
+	lastReviews := make(map[int64]int64)
+	for _, r := range revlog {
+		if r.Ease > 0 && r.ID > lastReviews[r.CardID] {
+			lastReviews[r.CardID] = r.ID
+		}
+	}
+
+	now := time.Now()
+	for _, e := range entries {
+		noteID, deckID := notes[e.NoteID], decks[homeDeck(e)]
+		if noteID == nil || deckID == nil || deckID.ObjectType != secondary.SyncObjectDeck {
+			continue
+		}
+		modified := time.Unix(e.Modified, 0)
+
+		var existing *card.Card
+		if id := cards[e.ID]; id != nil {
+			if existing, err = s.cardRepo.FindByID(ctx, userID, id.ObjectID); err != nil {
+				return fmt.Errorf("failed to find card: %w", err)
+			}
+			if existing == nil {
+				continue
+			}
+		} else {
+			siblings, err := s.cardRepo.FindByNoteID(ctx, userID, noteID.ObjectID)
+			if err != nil {
+				return fmt.Errorf("failed to find cards: %w", err)
+			}
+			for _, c := range siblings {
+				if c.GetCardTypeID() == e.Ord {
+					existing = c
+				}
+			}
+		}
+
+		if existing == nil {
+			c, err := card.NewBuilder().
+				WithNoteID(noteID.ObjectID).
+				WithCardTypeID(e.Ord).
+				WithDeckID(deckID.ObjectID).
+				WithCreatedAt(time.UnixMilli(e.ID)).
+				Build()
+			if err != nil {
+				return fmt.Errorf("failed to build card entity: %w", err)
+			}
+			if err := setCardSchedule(c, e, deckID.ObjectID, lastReviews[e.ID], crt, now); err != nil {
+				return err
+			}
+			if err := s.cardRepo.Save(ctx, userID, c); err != nil {
+				return fmt.Errorf("failed to save card: %w", err)
+			}
+			existing = c
+		} else if !existing.GetUpdatedAt().After(modified) {
+			if err := setCardSchedule(existing, e, deckID.ObjectID, lastReviews[e.ID], crt, now); err != nil {
+				return err
+			}
+			if err := s.cardRepo.Update(ctx, userID, existing.GetID(), existing); err != nil {
+				return fmt.Errorf("failed to update card: %w", err)
+			}
+		}
+
+		if cards[e.ID] == nil {
+			if err := s.saveID(ctx, userID, secondary.SyncObjectCard, existing.GetID(), e.ID, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// homeDeck returns the Anki deck a card belongs to outside filtered decks
+func homeDeck(e primary.AnkiSyncCardEntry) int64 {
+	if e.OrigDeck != 0 {
+		return e.OrigDeck
+	}
+	return e.DeckID
+}
+
+// setCardSchedule converts an Anki card's scheduling state, like the importer does
+// lastReview is the time of the card's latest answer in milliseconds, 0 when unknown
+func setCardSchedule(c *card.Card, e primary.AnkiSyncCardEntry, deckID int64, lastReview int64, crt int64, now time.Time) error {
+	if err := c.SetFlag(e.Flags & 7); err != nil {
+		return err
+	}
+	ease := max(e.Factor, 1300)
+	if e.Factor == 0 {
+		ease = 2500
+	}
+	c.SetDeckID(deckID)
+	if c.GetHomeDeckID() != nil {
+		// The card stays in its filtered deck on the server, which records its deck as home deck
+		c.SetHomeDeckID(&deckID)
+	}
+	c.SetInterval(max(e.Interval, 0))
+	c.SetEase(ease)
+	c.SetLapses(e.Lapses)
+	c.SetReps(e.Reps)
+	c.SetSuspended(e.Queue == export.AnkiQueueSuspended)
+	c.SetBuried(e.Queue == export.AnkiQueueBuried || e.Queue == export.AnkiQueueBuried-1)
+	c.SetUpdatedAt(time.Unix(e.Modified, 0))
+	if lastReview > 0 {
+		t := time.UnixMilli(lastReview)
+		c.SetLastReviewAt(&t)
+	}
+
+	var memory struct {
+		Stability  *float64 `json:"s"`
+		Difficulty *float64 `json:"d"`
+	}
+	if json.Unmarshal([]byte(e.Data), &memory) == nil {
+		c.SetStability(memory.Stability)
+		c.SetDifficulty(memory.Difficulty)
+	}
+
+	due := e.Due
+	if e.OrigDeck != 0 && e.OrigDue != 0 {
+		due = e.OrigDue
+	}
+	switch e.Type {
+	case export.AnkiCardNew:
+		c.SetState(valueobjects.CardStateNew)
+		c.SetPosition(int(max(due, 0)))
+		c.SetDue(now.UnixMilli())
+		return nil
+	case export.AnkiCardLearn:
+		c.SetState(valueobjects.CardStateLearn)
+	case export.AnkiCardRelearn:
+		c.SetState(valueobjects.CardStateRelearn)
+	default:
+		c.SetState(valueobjects.CardStateReview)
+	}
+	if e.Type != export.AnkiCardReview && due > 1_000_000_000 {
+		c.SetDue(due * 1000)
+		return nil
+	}
+	c.SetDue(time.Unix(crt, 0).AddDate(0, 0, int(due)).UnixMilli())
+	return nil
+}
+
+// applyRevlog adds the client's review log entries the server doesn't have
+func (s *AnkiSyncService) applyRevlog(ctx context.Context, userID int64, entries []primary.AnkiSyncRevlogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var reviewIDs, cardIDs []int64
+	for _, e := range entries {
+		reviewIDs = append(reviewIDs, e.ID)
+		cardIDs = append(cardIDs, e.CardID)
+	}
+	existing, err := s.objectIDMap(ctx, userID, secondary.SyncObjectReview, reviewIDs)
+	if err != nil {
+		return err
+	}
+	cards, err := s.objectIDMap(ctx, userID, secondary.SyncObjectCard, cardIDs)
+	if err != nil {
+		return err
+	}
+
+	var added []*secondary.AnkiSyncID
+	for _, e := range entries {
+		cardID := cards[e.CardID]
+		if existing[e.ID] != nil || cardID == nil {
+			continue
+		}
+
+		reviewType := valueobjects.ReviewTypeReview
+		switch {
+		case e.Type >= export.AnkiRevlogManual || e.Ease == 0:
+			reviewType = valueobjects.ReviewTypeManual
+		case e.Type == export.AnkiRevlogLearn:
+			reviewType = valueobjects.ReviewTypeLearn
+		case e.Type == export.AnkiRevlogRelearn:
+			reviewType = valueobjects.ReviewTypeRelearn
+		case e.Type == export.AnkiRevlogCram:
+			reviewType = valueobjects.ReviewTypeCram
+		}
+		rating := e.Ease
+		if reviewType == valueobjects.ReviewTypeManual {
+			rating = 0
+		}
+
+		r, err := review.NewBuilder().
+			WithCardID(cardID.ObjectID).
+			WithRating(rating).
+			WithInterval(e.Interval).
+			WithEase(e.Factor).
+			WithTimeMs(max(e.TimeMs, 0)).
+			WithType(reviewType).
+			WithCreatedAt(time.UnixMilli(e.ID)).
+			Build()
+		if err != nil {
+			return fmt.Errorf("failed to build review entity: %w", err)
+		}
+		if err := s.reviewRepo.Save(ctx, userID, r); err != nil {
+			return fmt.Errorf("failed to save review: %w", err)
+		}
+		added = append(added, &secondary.AnkiSyncID{ObjectType: secondary.SyncObjectReview, ObjectID: r.GetID(), AnkiID: e.ID})
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return s.syncRepo.SaveIDs(ctx, userID, added)
+}
+
+// equalStrings reports whether two lists hold the same strings in the same order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// stringValue returns a string property of a JSON object or the fallback
+func stringValue(m map[string]interface{}, key, fallback string) string {
+	if s, ok := m[key].(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
+
+// int64Value returns a numeric property of a JSON object, 0 when absent
+func int64Value(m map[string]interface{}, key string) int64 {
+	switch v := m[key].(type) {
+	case float64:
+		return int64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
+
+// floatList returns a JSON array of numbers
+func floatList(value interface{}) ([]float64, bool) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	values := make([]float64, 0, len(items))
+	for _, item := range items {
+		if n, ok := item.(float64); ok {
+			values = append(values, n)
+		}
+	}
+	return values, true
+}
+
+func setInt(dest *int, value interface{}) {
+	if n, ok := value.(float64); ok {
+		*dest = int(n)
+	}
+}
+
+func setFloat(dest *float64, value interface{}) {
+	if n, ok := value.(float64); ok {
+		*dest = n
+	}
+}
+
+func setBool(dest *bool, value interface{}) {
+	if b, ok := value.(bool); ok {
+		*dest = b
+	}
+}
@@ -0,0 +1,762 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deckoptionspreset "github.com/felipesantos/anki-backend/core/domain/entities/deck_options_preset"
+	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	userpreferences "github.com/felipesantos/anki-backend/core/domain/entities/user_preferences"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/core/services/export"
+)
+
+// ankiDefaultID is the Anki ID of the Default deck and the Default deck options
+const ankiDefaultID = export.AnkiDefaultDeckID
+
+// ankiDefaultDeckName is the name of the root deck synced as Anki's Default deck
+const ankiDefaultDeckName = "Default"
+
+// ankiDueOrder is Anki's search order for due cards, used for unknown orders
+const ankiDueOrder = 6
+
+// serverGraves returns the Anki IDs of the cards, notes and decks deleted since minUSN
+// Objects that were never synced have no Anki ID and are left out
+func (s *AnkiSyncService) serverGraves(ctx context.Context, userID int64, minUSN int) (*primary.AnkiSyncGraves, error) {
+	graves := &primary.AnkiSyncGraves{Cards: []int64{}, Notes: []int64{}, Decks: []int64{}}
+	kinds := []struct {
+		objectType string
+		ankiIDs    *[]int64
+	}{
+		{secondary.SyncObjectCard, &graves.Cards},
+		{secondary.SyncObjectNote, &graves.Notes},
+		{secondary.SyncObjectDeck, &graves.Decks},
+		{secondary.SyncObjectFilteredDeck, &graves.Decks},
+	}
+	for _, kind := range kinds {
+		ids, err := s.syncRepo.FindDeletedIDs(ctx, userID, kind.objectType, minUSN)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		found, err := s.syncRepo.FindAnkiIDs(ctx, userID, kind.objectType, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			*kind.ankiIDs = append(*kind.ankiIDs, id.AnkiID)
+		}
+	}
+	return graves, nil
+}
+
+// serverChanges returns the note types, decks and deck options changed since the client's last sync
+func (s *AnkiSyncService) serverChanges(ctx context.Context, userID int64, session *syncSession, state *secondary.AnkiSyncState) (*primary.AnkiSyncChanges, error) {
+	changes := &primary.AnkiSyncChanges{Models: []map[string]interface{}{}, Tags: json.RawMessage("[]")}
+
+	noteTypes, err := s.noteTypeRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find note types: %w", err)
+	}
+	changedNoteTypes, err := s.changedIDs(ctx, userID, secondary.SyncObjectNoteType, session.MinUSN)
+	if err != nil {
+		return nil, err
+	}
+	modelIDs, err := s.noteTypeAnkiIDs(ctx, userID, noteTypes)
+	if err != nil {
+		return nil, err
+	}
+	for _, nt := range noteTypes {
+		if !changedNoteTypes[nt.GetID()] {
+			continue
+		}
+		model := export.AnkiModel(modelIDs[nt.GetID()], nt)
+		model["usn"] = session.USN
+		changes.Models = append(changes.Models, model)
+	}
+
+	decks, configs, err := s.deckChanges(ctx, userID, session)
+	if err != nil {
+		return nil, err
+	}
+	changes.Decks = primary.AnkiSyncDecks{Decks: decks, Configs: configs}
+
+	if !session.LocalIsNewer {
+		if state.ConfigJSON != "" {
+			var conf map[string]interface{}
+			if err := json.Unmarshal([]byte(state.ConfigJSON), &conf); err == nil {
+				changes.Conf = conf
+			}
+		}
+		crt, err := s.collectionCreated(ctx, userID, state)
+		if err != nil {
+			return nil, err
+		}
+		changes.Crt = &crt
+	}
+	return changes, nil
+}
+
+// deckChanges returns the changed decks, filtered decks and deck options
+// Decks use the options of a preset with the same options; other distinct options are sent as new
+// deck options named after the deck, and kept as a preset so they keep their Anki ID
+func (s *AnkiSyncService) deckChanges(ctx context.Context, userID int64, session *syncSession) ([]map[string]interface{}, []map[string]interface{}, error) {
+	allDecks, err := s.deckRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find decks: %w", err)
+	}
+	filteredDecks, err := s.filteredDeckRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find filtered decks: %w", err)
+	}
+	presets, err := s.presetRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find deck options presets: %w", err)
+	}
+
+	changedDecks, err := s.changedIDs(ctx, userID, secondary.SyncObjectDeck, session.MinUSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	changedFiltered, err := s.changedIDs(ctx, userID, secondary.SyncObjectFilteredDeck, session.MinUSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	changedPresets, err := s.changedIDs(ctx, userID, secondary.SyncObjectPreset, session.MinUSN)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deckIDs, err := s.deckAnkiIDs(ctx, userID, allDecks)
+	if err != nil {
+		return nil, nil, err
+	}
+	filteredIDs, err := s.filteredDeckAnkiIDs(ctx, userID, filteredDecks)
+	if err != nil {
+		return nil, nil, err
+	}
+	presetIDs, err := s.presetAnkiIDs(ctx, userID, presets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(presets, func(i, j int) bool { return presets[i].GetID() < presets[j].GetID() })
+	confIDs := make(map[string]int64) // Options key -> Anki deck options ID
+	defaultMapped := false
+	for _, p := range presets {
+		defaultMapped = defaultMapped || presetIDs[p.GetID()] == ankiDefaultID
+		opts, err := deck.ParseDeckOptions(p.GetOptionsJSON())
+		if err != nil {
+			continue
+		}
+		if key := export.OptionsKey(opts); confIDs[key] == 0 {
+			confIDs[key] = presetIDs[p.GetID()]
+		}
+	}
+	// Decks with default options use the Default deck options while no preset holds its ID
+	if defaultKey := export.OptionsKey(deck.DefaultDeckOptions()); !defaultMapped && confIDs[defaultKey] == 0 {
+		confIDs[defaultKey] = ankiDefaultID
+	}
+
+	decks := []map[string]interface{}{}
+	for _, d := range allDecks {
+		if !changedDecks[d.GetID()] {
+			continue
+		}
+		opts, err := d.GetOptions()
+		if err != nil {
+			opts = deck.DefaultDeckOptions()
+		}
+		key := export.OptionsKey(opts)
+		confID := confIDs[key]
+		if confID == 0 {
+			p, err := s.addPreset(ctx, userID, d.GetFullPath(allDecks), opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			ids, err := s.presetAnkiIDs(ctx, userID, []*deckoptionspreset.DeckOptionsPreset{p})
+			if err != nil {
+				return nil, nil, err
+			}
+			confID = ids[p.GetID()]
+			confIDs[key] = confID
+			presetIDs[p.GetID()] = confID
+			presets = append(presets, p)
+			changedPresets[p.GetID()] = true
+		}
+		m := export.AnkiDeck(deckIDs[d.GetID()], d.GetFullPath(allDecks), confID, d.GetUpdatedAt())
+		m["usn"] = session.USN
+		decks = append(decks, m)
+	}
+	for _, fd := range filteredDecks {
+		if changedFiltered[fd.GetID()] {
+			decks = append(decks, ankiFilteredDeck(filteredIDs[fd.GetID()], fd, session.USN))
+		}
+	}
+
+	configs := []map[string]interface{}{}
+	for _, p := range presets {
+		if !changedPresets[p.GetID()] {
+			continue
+		}
+		opts, err := deck.ParseDeckOptions(p.GetOptionsJSON())
+		if err != nil {
+			continue
+		}
+		m := export.AnkiDeckConfig(presetIDs[p.GetID()], p.GetName(), opts, p.GetUpdatedAt())
+		m["usn"] = session.USN
+		configs = append(configs, m)
+	}
+	return decks, configs, nil
+}
+
+// addPreset saves deck options without a preset as a preset with an unused name
+func (s *AnkiSyncService) addPreset(ctx context.Context, userID int64, name string, opts *deck.DeckOptions) (*deckoptionspreset.DeckOptionsPreset, error) {
+	unique, err := s.uniquePresetName(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	optionsJSON, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode deck options: %w", err)
+	}
+
+	now := time.Now()
+	p, err := deckoptionspreset.NewBuilder().
+		WithUserID(userID).
+		WithName(unique).
+		WithOptionsJSON(string(optionsJSON)).
+		WithCreatedAt(now).
+		WithUpdatedAt(now).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build deck options preset entity: %w", err)
+	}
+	if err := s.presetRepo.Save(ctx, userID, p); err != nil {
+		return nil, fmt.Errorf("failed to save deck options preset: %w", err)
+	}
+	return p, nil
+}
+
+// uniquePresetName returns the name, numbered when a preset already has it
+func (s *AnkiSyncService) uniquePresetName(ctx context.Context, userID int64, name string) (string, error) {
+	for i := 1; ; i++ {
+		candidate := name
+		if i > 1 {
+			candidate = fmt.Sprintf("%s (%d)", name, i)
+		}
+		existing, err := s.presetRepo.FindByName(ctx, userID, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to find deck options preset: %w", err)
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+}
+
+// ankiFilteredDeck returns the legacy filtered deck JSON object
+func ankiFilteredDeck(id int64, fd *filtereddeck.FilteredDeck, usn int) map[string]interface{} {
+	order := ankiFilteredDeckOrder(fd.GetOrderBy())
+	terms := []interface{}{[]interface{}{fd.GetSearchFilter(), fd.GetLimitCards(), order}}
+	if second := fd.GetSecondFilter(); second != nil && *second != "" {
+		terms = append(terms, []interface{}{*second, fd.GetSecondLimitCards(), order})
+	}
+
+	m := export.AnkiDeck(id, fd.GetName(), 0, fd.GetUpdatedAt())
+	delete(m, "conf")
+	m["usn"] = usn
+	m["dyn"] = 1
+	m["terms"] = terms
+	m["resched"] = fd.GetReschedule()
+	m["delays"] = nil
+	m["separate"] = true
+	m["previewDelay"] = 0
+	m["previewAgainSecs"] = 60
+	m["previewHardSecs"] = 600
+	m["previewGoodSecs"] = 0
+	return m
+}
+
+// ankiFilteredDeckOrder converts a filtered deck order to Anki's search order
+func ankiFilteredDeckOrder(orderBy string) int {
+	for i, order := range filtereddeck.Orders {
+		if order == orderBy {
+			return i
+		}
+	}
+	return ankiDueOrder
+}
+
+// changedIDs returns the objects of a type changed since minUSN
+func (s *AnkiSyncService) changedIDs(ctx context.Context, userID int64, objectType string, minUSN int) (map[int64]bool, error) {
+	changed := make(map[int64]bool)
+	var afterID int64
+	for {
+		ids, err := s.syncRepo.FindChangedIDs(ctx, userID, objectType, minUSN, afterID, syncChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			changed[id] = true
+		}
+		if len(ids) < syncChunkSize {
+			return changed, nil
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
+
+// ankiIDs returns the Anki IDs of objects of a type
+// Objects synced for the first time get an Anki ID derived from their creation time, like Anki's own IDs
+func (s *AnkiSyncService) ankiIDs(ctx context.Context, userID int64, objectType string, created map[int64]time.Time) (map[int64]*secondary.AnkiSyncID, error) {
+	if len(created) == 0 {
+		return map[int64]*secondary.AnkiSyncID{}, nil
+	}
+	objectIDs := make([]int64, 0, len(created))
+	for id := range created {
+		objectIDs = append(objectIDs, id)
+	}
+	sort.Slice(objectIDs, func(i, j int) bool { return objectIDs[i] < objectIDs[j] })
+
+	found, err := s.syncRepo.FindAnkiIDs(ctx, userID, objectType, objectIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int64]*secondary.AnkiSyncID, len(created))
+	for _, id := range found {
+		ids[id.ObjectID] = id
+	}
+
+	var missing []*secondary.AnkiSyncID
+	for _, objectID := range objectIDs {
+		if ids[objectID] == nil {
+			id := &secondary.AnkiSyncID{
+				ObjectType: objectType,
+				ObjectID:   objectID,
+				AnkiID:     max(created[objectID].UnixMilli(), 1),
+			}
+			missing = append(missing, id)
+			ids[objectID] = id
+		}
+	}
+	if len(missing) > 0 {
+		if err := s.syncRepo.SaveIDs(ctx, userID, missing); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// plainAnkiIDs returns the Anki IDs of objects of a type without their GUIDs
+func (s *AnkiSyncService) plainAnkiIDs(ctx context.Context, userID int64, objectType string, created map[int64]time.Time) (map[int64]int64, error) {
+	ids, err := s.ankiIDs(ctx, userID, objectType, created)
+	if err != nil {
+		return nil, err
+	}
+	plain := make(map[int64]int64, len(ids))
+	for objectID, id := range ids {
+		plain[objectID] = id.AnkiID
+	}
+	return plain, nil
+}
+
+// deckAnkiIDs returns the Anki IDs of decks
+// The root Default deck is synced as Anki's Default deck unless another deck already has its ID
+func (s *AnkiSyncService) deckAnkiIDs(ctx context.Context, userID int64, decks []*deck.Deck) (map[int64]int64, error) {
+	created := make(map[int64]time.Time, len(decks))
+	for _, d := range decks {
+		created[d.GetID()] = d.GetCreatedAt()
+	}
+
+	for _, d := range decks {
+		if d.GetParentID() != nil || !strings.EqualFold(d.GetName(), ankiDefaultDeckName) {
+			continue
+		}
+		mapped, err := s.syncRepo.FindAnkiIDs(ctx, userID, secondary.SyncObjectDeck, []int64{d.GetID()})
+		if err != nil {
+			return nil, err
+		}
+		if len(mapped) > 0 {
+			break
+		}
+		taken, err := s.syncRepo.FindObjectIDs(ctx, userID, secondary.SyncObjectDeck, []int64{ankiDefaultID})
+		if err != nil {
+			return nil, err
+		}
+		if len(taken) == 0 {
+			id := &secondary.AnkiSyncID{ObjectType: secondary.SyncObjectDeck, ObjectID: d.GetID(), AnkiID: ankiDefaultID}
+			if err := s.syncRepo.SaveIDs(ctx, userID, []*secondary.AnkiSyncID{id}); err != nil {
+				return nil, err
+			}
+		}
+		break
+	}
+	return s.plainAnkiIDs(ctx, userID, secondary.SyncObjectDeck, created)
+}
+
+// filteredDeckAnkiIDs returns the Anki IDs of filtered decks
+func (s *AnkiSyncService) filteredDeckAnkiIDs(ctx context.Context, userID int64, decks []*filtereddeck.FilteredDeck) (map[int64]int64, error) {
+	created := make(map[int64]time.Time, len(decks))
+	for _, d := range decks {
+		created[d.GetID()] = d.GetCreatedAt()
+	}
+	return s.plainAnkiIDs(ctx, userID, secondary.SyncObjectFilteredDeck, created)
+}
+
+// presetAnkiIDs returns the Anki IDs of deck options presets
+func (s *AnkiSyncService) presetAnkiIDs(ctx context.Context, userID int64, presets []*deckoptionspreset.DeckOptionsPreset) (map[int64]int64, error) {
+	created := make(map[int64]time.Time, len(presets))
+	for _, p := range presets {
+		created[p.GetID()] = p.GetCreatedAt()
+	}
+	return s.plainAnkiIDs(ctx, userID, secondary.SyncObjectPreset, created)
+}
+
+// noteTypeAnkiIDs returns the Anki IDs of note types
+func (s *AnkiSyncService) noteTypeAnkiIDs(ctx context.Context, userID int64, noteTypes []*notetype.NoteType) (map[int64]int64, error) {
+	created := make(map[int64]time.Time, len(noteTypes))
+	for _, nt := range noteTypes {
+		created[nt.GetID()] = nt.GetCreatedAt()
+	}
+	return s.plainAnkiIDs(ctx, userID, secondary.SyncObjectNoteType, created)
+}
+
+// cardAnkiIDs returns the Anki IDs of cards
+func (s *AnkiSyncService) cardAnkiIDs(ctx context.Context, userID int64, cards []*card.Card) (map[int64]int64, error) {
+	created := make(map[int64]time.Time, len(cards))
+	for _, c := range cards {
+		created[c.GetID()] = c.GetCreatedAt()
+	}
+	return s.plainAnkiIDs(ctx, userID, secondary.SyncObjectCard, created)
+}
+
+// collectionCreated returns the collection creation time (crt) that day numbers count from
+// Until a client sets it, it is the study day start of the user's registration
+func (s *AnkiSyncService) collectionCreated(ctx context.Context, userID int64, state *secondary.AnkiSyncState) (int64, error) {
+	if state.CollectionCreated != nil {
+		return *state.CollectionCreated, nil
+	}
+	created := time.Now()
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user != nil && user.GetCreatedAt().Before(created) {
+		created = user.GetCreatedAt()
+	}
+
+	crt := userpreferences.StudyDayStart(created.UTC(), userpreferences.DefaultNextDayStartsAtHour, 0, 0).Unix()
+	state.CollectionCreated = &crt
+	if err := s.syncRepo.UpdateState(ctx, userID, state); err != nil {
+		return 0, err
+	}
+	return crt, nil
+}
+
+// nextChunk collects the next syncChunkSize changed objects, advancing the session's stage
+func (s *AnkiSyncService) nextChunk(ctx context.Context, userID int64, session *syncSession, crt int64) (*primary.AnkiSyncChunk, error) {
+	chunk := &primary.AnkiSyncChunk{}
+	for remaining := syncChunkSize; remaining > 0 && session.Stage != syncStageDone; {
+		objectType := secondary.SyncObjectReview
+		switch session.Stage {
+		case syncStageCards:
+			objectType = secondary.SyncObjectCard
+		case syncStageNotes:
+			objectType = secondary.SyncObjectNote
+		}
+		ids, err := s.syncRepo.FindChangedIDs(ctx, userID, objectType, session.MinUSN, session.AfterID, remaining)
+		if err != nil {
+			return nil, err
+		}
+
+		switch session.Stage {
+		case syncStageRevlog:
+			entries, err := s.revlogEntries(ctx, userID, ids, session.USN)
+			if err != nil {
+				return nil, err
+			}
+			chunk.Revlog = append(chunk.Revlog, entries...)
+		case syncStageCards:
+			entries, err := s.cardEntries(ctx, userID, ids, session.USN, crt)
+			if err != nil {
+				return nil, err
+			}
+			chunk.Cards = append(chunk.Cards, entries...)
+		default:
+			entries, err := s.noteEntries(ctx, userID, ids, session.USN)
+			if err != nil {
+				return nil, err
+			}
+			chunk.Notes = append(chunk.Notes, entries...)
+		}
+
+		if len(ids) < remaining {
+			session.Stage, session.AfterID = nextSyncStage(session.Stage), 0
+		} else {
+			session.AfterID = ids[len(ids)-1]
+		}
+		remaining -= len(ids)
+	}
+	chunk.Done = session.Stage == syncStageDone
+	return chunk, nil
+}
+
+// allEntries returns every review log entry, card and note of the collection
+func (s *AnkiSyncService) allEntries(ctx context.Context, userID int64, usn int, crt int64) (*primary.AnkiSyncChunk, error) {
+	session := &syncSession{USN: usn, Stage: syncStageRevlog}
+	entries := &primary.AnkiSyncChunk{Done: true}
+	for session.Stage != syncStageDone {
+		chunk, err := s.nextChunk(ctx, userID, session, crt)
+		if err != nil {
+			return nil, err
+		}
+		entries.Revlog = append(entries.Revlog, chunk.Revlog...)
+		entries.Cards = append(entries.Cards, chunk.Cards...)
+		entries.Notes = append(entries.Notes, chunk.Notes...)
+	}
+	return entries, nil
+}
+
+// revlogEntries converts reviews to review log entries
+// lastIvl is the interval of the card's previous review, as Anki records it
+func (s *AnkiSyncService) revlogEntries(ctx context.Context, userID int64, ids []int64, usn int) ([]primary.AnkiSyncRevlogEntry, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	reviews, err := s.syncRepo.FindReviews(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewCreated := make(map[int64]time.Time, len(reviews))
+	cardSet := make(map[int64]bool)
+	for _, r := range reviews {
+		reviewCreated[r.GetID()] = r.GetCreatedAt()
+		cardSet[r.GetCardID()] = true
+	}
+	cardIDs := make([]int64, 0, len(cardSet))
+	for id := range cardSet {
+		cardIDs = append(cardIDs, id)
+	}
+	cards, err := s.syncRepo.FindCards(ctx, userID, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	ankiCardIDs, err := s.cardAnkiIDs(ctx, userID, cards)
+	if err != nil {
+		return nil, err
+	}
+	ankiReviewIDs, err := s.plainAnkiIDs(ctx, userID, secondary.SyncObjectReview, reviewCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	lastIntervals := make(map[int64]int) // Review ID -> interval of the previous review of the card
+	for _, cardID := range cardIDs {
+		history, err := s.reviewRepo.FindByCardID(ctx, userID, cardID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find reviews: %w", err)
+		}
+		sort.SliceStable(history, func(i, j int) bool { return history[i].GetCreatedAt().Before(history[j].GetCreatedAt()) })
+		last := 0
+		for _, r := range history {
+			lastIntervals[r.GetID()] = last
+			last = r.GetInterval()
+		}
+	}
+
+	entries := make([]primary.AnkiSyncRevlogEntry, 0, len(reviews))
+	for _, r := range reviews {
+		cardID, ok := ankiCardIDs[r.GetCardID()]
+		if !ok {
+			continue
+		}
+		entries = append(entries, primary.AnkiSyncRevlogEntry{
+			ID:           ankiReviewIDs[r.GetID()],
+			CardID:       cardID,
+			USN:          usn,
+			Ease:         r.GetRating(),
+			Interval:     r.GetInterval(),
+			LastInterval: lastIntervals[r.GetID()],
+			Factor:       r.GetEase(),
+			TimeMs:       r.GetTimeMs(),
+			Type:         export.RevlogType(r.GetType()),
+		})
+	}
+	return entries, nil
+}
+
+// cardEntries converts cards to Anki cards in their home decks
+func (s *AnkiSyncService) cardEntries(ctx context.Context, userID int64, ids []int64, usn int, crt int64) ([]primary.AnkiSyncCardEntry, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	cards, err := s.syncRepo.FindCards(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	decks, err := s.deckRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find decks: %w", err)
+	}
+	deckIDs, err := s.deckAnkiIDs(ctx, userID, decks)
+	if err != nil {
+		return nil, err
+	}
+	options := make(map[int64]*deck.DeckOptions, len(decks))
+	for _, d := range decks {
+		if opts, err := d.GetOptions(); err == nil {
+			options[d.GetID()] = opts
+		}
+	}
+
+	noteSet := make(map[int64]bool)
+	for _, c := range cards {
+		noteSet[c.GetNoteID()] = true
+	}
+	noteIDs := make([]int64, 0, len(noteSet))
+	for id := range noteSet {
+		noteIDs = append(noteIDs, id)
+	}
+	notes, err := s.noteRepo.FindByIDs(ctx, userID, noteIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notes: %w", err)
+	}
+	noteCreated := make(map[int64]time.Time, len(notes))
+	for _, n := range notes {
+		noteCreated[n.GetID()] = n.GetCreatedAt()
+	}
+	ankiNoteIDs, err := s.ankiIDs(ctx, userID, secondary.SyncObjectNote, noteCreated)
+	if err != nil {
+		return nil, err
+	}
+	ankiCardIDs, err := s.cardAnkiIDs(ctx, userID, cards)
+	if err != nil {
+		return nil, err
+	}
+
+	nextDayStart := userpreferences.StudyDayStart(time.Now().UTC(), userpreferences.DefaultNextDayStartsAtHour, 0, 0).AddDate(0, 0, 1).Unix()
+	entries := make([]primary.AnkiSyncCardEntry, 0, len(cards))
+	for _, c := range cards {
+		noteID := ankiNoteIDs[c.GetNoteID()]
+		homeDeckID := c.GetDeckID()
+		if home := c.GetHomeDeckID(); home != nil {
+			homeDeckID = *home
+		}
+		deckID, ok := deckIDs[homeDeckID]
+		if noteID == nil || !ok {
+			continue
+		}
+
+		var lastReview *review.Review
+		if c.IsLearning() || c.IsRelearning() {
+			if lastReview, err = s.lastReview(ctx, userID, c.GetID()); err != nil {
+				return nil, err
+			}
+		}
+		sched := export.ScheduleCard(c, options[homeDeckID], lastReview, crt, nextDayStart)
+		entries = append(entries, primary.AnkiSyncCardEntry{
+			ID:       ankiCardIDs[c.GetID()],
+			NoteID:   noteID.AnkiID,
+			DeckID:   deckID,
+			Ord:      c.GetCardTypeID(),
+			Modified: c.GetUpdatedAt().Unix(),
+			USN:      usn,
+			Type:     sched.Type,
+			Queue:    sched.Queue,
+			Due:      sched.Due,
+			Interval: sched.Interval,
+			Factor:   sched.Factor,
+			Reps:     sched.Reps,
+			Lapses:   sched.Lapses,
+			Left:     sched.Left,
+			Flags:    sched.Flags,
+			Data:     sched.Data,
+		})
+	}
+	return entries, nil
+}
+
+// lastReview returns the latest review of a card, nil when it has none
+func (s *AnkiSyncService) lastReview(ctx context.Context, userID int64, cardID int64) (*review.Review, error) {
+	history, err := s.reviewRepo.FindByCardID(ctx, userID, cardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reviews: %w", err)
+	}
+	var last *review.Review
+	for _, r := range history {
+		if last == nil || !r.GetCreatedAt().Before(last.GetCreatedAt()) {
+			last = r
+		}
+	}
+	return last, nil
+}
+
+// noteEntries converts notes to Anki notes
+// Notes added by a client keep the Anki GUID they were added with
+func (s *AnkiSyncService) noteEntries(ctx context.Context, userID int64, ids []int64, usn int) ([]primary.AnkiSyncNoteEntry, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	notes, err := s.noteRepo.FindByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notes: %w", err)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].GetID() < notes[j].GetID() })
+
+	noteTypes, err := s.noteTypeRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find note types: %w", err)
+	}
+	byID := make(map[int64]*notetype.NoteType, len(noteTypes))
+	for _, nt := range noteTypes {
+		byID[nt.GetID()] = nt
+	}
+	modelIDs, err := s.noteTypeAnkiIDs(ctx, userID, noteTypes)
+	if err != nil {
+		return nil, err
+	}
+	noteCreated := make(map[int64]time.Time, len(notes))
+	for _, n := range notes {
+		noteCreated[n.GetID()] = n.GetCreatedAt()
+	}
+	ankiNoteIDs, err := s.ankiIDs(ctx, userID, secondary.SyncObjectNote, noteCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]primary.AnkiSyncNoteEntry, 0, len(notes))
+	for _, n := range notes {
+		nt := byID[n.GetNoteTypeID()]
+		if nt == nil {
+			continue
+		}
+		id := ankiNoteIDs[n.GetID()]
+		guid := id.AnkiGUID
+		if guid == "" {
+			guid = n.GetGUID().Value()
+		}
+		entries = append(entries, primary.AnkiSyncNoteEntry{
+			ID:       id.AnkiID,
+			GUID:     guid,
+			ModelID:  modelIDs[nt.GetID()],
+			Modified: n.GetUpdatedAt().Unix(),
+			USN:      usn,
+			Tags:     export.AnkiTags(n.GetTags()),
+			Fields:   strings.Join(export.NoteFieldValues(n, nt), "\x1f"),
+		})
+	}
+	return entries, nil
+}
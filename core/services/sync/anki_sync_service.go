@@ -0,0 +1,550 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/pkg/jwt"
+)
+
+var (
+	// ErrSyncAuthFailed is returned when the username or password of a client is incorrect
+	ErrSyncAuthFailed = errors.New("invalid username or password")
+	// ErrInvalidHostKey is returned when a host key is invalid or expired
+	ErrInvalidHostKey = errors.New("invalid host key")
+	// ErrSyncSessionNotFound is returned when a sync request arrives without a sync in progress
+	ErrSyncSessionNotFound = errors.New("no sync in progress")
+	// ErrFullSyncRequired is returned when the client's changes can't be merged into the collection
+	ErrFullSyncRequired = errors.New("full sync required")
+	// ErrInvalidCollection is returned when an uploaded collection can't be read
+	ErrInvalidCollection = errors.New("invalid collection")
+)
+
+const (
+	syncSessionKeyPrefix = "anki_sync_session:"
+	syncSessionTTL       = 30 * time.Minute
+	syncChunkSize        = 250 // Entries per chunk, as sent by Anki clients
+)
+
+// Stages of the server's chunks: review log entries, then cards, then notes
+const (
+	syncStageRevlog = "revlog"
+	syncStageCards  = "cards"
+	syncStageNotes  = "notes"
+	syncStageDone   = "done"
+)
+
+// AnkiSyncService implements IAnkiSyncService
+type AnkiSyncService struct {
+	userRepo         secondary.IUserRepository
+	jwtService       *jwt.JWTService
+	cacheRepo        secondary.ICacheRepository
+	syncRepo         secondary.IAnkiSyncRepository
+	noteRepo         secondary.INoteRepository
+	cardRepo         secondary.ICardRepository
+	deckRepo         secondary.IDeckRepository
+	noteTypeRepo     secondary.INoteTypeRepository
+	presetRepo       secondary.IDeckOptionsPresetRepository
+	filteredDeckRepo secondary.IFilteredDeckRepository
+	reviewRepo       secondary.IReviewRepository
+	tm               secondary.ITransactionManager
+}
+
+// NewAnkiSyncService creates a new AnkiSyncService instance
+func NewAnkiSyncService(
+	userRepo secondary.IUserRepository,
+	jwtService *jwt.JWTService,
+	cacheRepo secondary.ICacheRepository,
+	syncRepo secondary.IAnkiSyncRepository,
+	noteRepo secondary.INoteRepository,
+	cardRepo secondary.ICardRepository,
+	deckRepo secondary.IDeckRepository,
+	noteTypeRepo secondary.INoteTypeRepository,
+	presetRepo secondary.IDeckOptionsPresetRepository,
+	filteredDeckRepo secondary.IFilteredDeckRepository,
+	reviewRepo secondary.IReviewRepository,
+	tm secondary.ITransactionManager,
+) primary.IAnkiSyncService {
+	return &AnkiSyncService{
+		userRepo:         userRepo,
+		jwtService:       jwtService,
+		cacheRepo:        cacheRepo,
+		syncRepo:         syncRepo,
+		noteRepo:         noteRepo,
+		cardRepo:         cardRepo,
+		deckRepo:         deckRepo,
+		noteTypeRepo:     noteTypeRepo,
+		presetRepo:       presetRepo,
+		filteredDeckRepo: filteredDeckRepo,
+		reviewRepo:       reviewRepo,
+		tm:               tm,
+	}
+}
+
+// syncSession is the state of a normal sync between Start and Finish, kept in the cache
+type syncSession struct {
+	MinUSN       int                    `json:"min_usn"`        // Client's USN: server objects at or above it are sent
+	USN          int                    `json:"usn"`            // Server USN when the sync started, given to every object exchanged
+	LocalIsNewer bool                   `json:"local_is_newer"` // The client's collection was modified last
+	Stage        string                 `json:"stage"`          // Object type of the server's next chunk
+	AfterID      int64                  `json:"after_id"`       // Last object of the stage already sent
+	Received     *primary.AnkiSyncChunk `json:"received"`       // Client chunks, applied once the last one arrives
+}
+
+// HostKey logs a client in with the user's email and password and returns a host key
+func (s *AnkiSyncService) HostKey(ctx context.Context, username string, password string) (string, error) {
+	email, err := valueobjects.NewEmail(username)
+	if err != nil {
+		return "", ErrSyncAuthFailed
+	}
+	user, err := s.userRepo.FindByEmail(ctx, email.Value())
+	if err != nil {
+		return "", fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil || !user.IsActive() || !user.VerifyPassword(password) {
+		return "", ErrSyncAuthFailed
+	}
+
+	key, err := s.jwtService.GenerateSyncHostKey(user.GetID())
+	if err != nil {
+		return "", fmt.Errorf("failed to generate host key: %w", err)
+	}
+	if err := saveHostKey(ctx, s.cacheRepo, key, user.GetID(), s.jwtService.GetSyncHostKeyExpiry()); err != nil {
+		return "", fmt.Errorf("failed to store host key: %w", err)
+	}
+	return key, nil
+}
+
+// Authenticate returns the user of a host key
+// The key must not have been revoked and its user must still be active
+func (s *AnkiSyncService) Authenticate(ctx context.Context, hostKey string) (int64, error) {
+	claims, err := s.jwtService.ValidateSyncHostKey(hostKey)
+	if err != nil {
+		return 0, ErrInvalidHostKey
+	}
+	userID, err := findHostKey(ctx, s.cacheRepo, hostKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find host key: %w", err)
+	}
+	if userID == 0 || userID != claims.UserID {
+		return 0, ErrInvalidHostKey
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil || !user.IsActive() {
+		return 0, ErrInvalidHostKey
+	}
+	return userID, nil
+}
+
+// Meta describes the user's collection
+func (s *AnkiSyncService) Meta(ctx context.Context, userID int64) (*primary.AnkiSyncMeta, error) {
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.syncRepo.Count(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &primary.AnkiSyncMeta{
+		Modified:       state.ModifiedAt.UnixMilli(),
+		SchemaModified: state.SchemaModifiedAt.UnixMilli(),
+		USN:            state.USN,
+		ServerTime:     time.Now().Unix(),
+		Continue:       true,
+		Empty:          counts.Cards == 0,
+	}, nil
+}
+
+// Start begins a normal sync and returns the objects deleted since the client's last sync
+// Server changes are collected before the client's are applied, so they are never sent back
+func (s *AnkiSyncService) Start(ctx context.Context, userID int64, minUSN int, localIsNewer bool, graves *primary.AnkiSyncGraves) (*primary.AnkiSyncGraves, error) {
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	session := &syncSession{MinUSN: minUSN, USN: state.USN, LocalIsNewer: localIsNewer, Stage: syncStageRevlog}
+
+	serverGraves, err := s.serverGraves(ctx, userID, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	if graves != nil {
+		if err := s.deleteGraves(ctx, userID, graves); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.saveSession(ctx, userID, session); err != nil {
+		return nil, err
+	}
+	return serverGraves, nil
+}
+
+// ApplyGraves deletes the objects the client deleted
+func (s *AnkiSyncService) ApplyGraves(ctx context.Context, userID int64, graves *primary.AnkiSyncGraves) error {
+	if _, err := s.loadSession(ctx, userID); err != nil {
+		return err
+	}
+	return s.deleteGraves(ctx, userID, graves)
+}
+
+// ApplyChanges merges the client's note types, decks and deck options and returns the server's
+// The collection configuration and creation time go from the side modified last to the other
+func (s *AnkiSyncService) ApplyChanges(ctx context.Context, userID int64, changes *primary.AnkiSyncChanges) (*primary.AnkiSyncChanges, error) {
+	session, err := s.loadSession(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	serverChanges, err := s.serverChanges(ctx, userID, session, state)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.applyDeckConfigs(txCtx, userID, changes.Decks.Configs); err != nil {
+			return err
+		}
+		if err := s.applyDecks(txCtx, userID, changes.Decks.Decks); err != nil {
+			return err
+		}
+		return s.applyModels(txCtx, userID, changes.Models)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if session.LocalIsNewer && (changes.Conf != nil || changes.Crt != nil) {
+		if err := s.updateCollectionConfig(ctx, userID, changes.Conf, changes.Crt); err != nil {
+			return nil, err
+		}
+	}
+	return serverChanges, nil
+}
+
+// Chunk returns the next chunk of the server's changed review log entries, cards and notes
+func (s *AnkiSyncService) Chunk(ctx context.Context, userID int64) (*primary.AnkiSyncChunk, error) {
+	session, err := s.loadSession(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	crt, err := s.collectionCreated(ctx, userID, state)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk, err := s.nextChunk(ctx, userID, session, crt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveSession(ctx, userID, session); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// nextSyncStage returns the stage after a stage
+func nextSyncStage(stage string) string {
+	switch stage {
+	case syncStageRevlog:
+		return syncStageCards
+	case syncStageCards:
+		return syncStageNotes
+	default:
+		return syncStageDone
+	}
+}
+
+// ApplyChunk buffers the client's chunks and merges them once the last one arrives
+// Clients send review log entries before cards and cards before notes, so they are applied in reverse:
+// notes first, then their cards, then the cards' reviews
+func (s *AnkiSyncService) ApplyChunk(ctx context.Context, userID int64, chunk *primary.AnkiSyncChunk) error {
+	session, err := s.loadSession(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if session.Received == nil {
+		session.Received = &primary.AnkiSyncChunk{}
+	}
+	session.Received.Revlog = append(session.Received.Revlog, chunk.Revlog...)
+	session.Received.Cards = append(session.Received.Cards, chunk.Cards...)
+	session.Received.Notes = append(session.Received.Notes, chunk.Notes...)
+	if !chunk.Done {
+		return s.saveSession(ctx, userID, session)
+	}
+
+	received := session.Received
+	session.Received = nil
+	err = s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		return s.applyEntries(txCtx, userID, received)
+	})
+	if err != nil {
+		return err
+	}
+	return s.saveSession(ctx, userID, session)
+}
+
+// applyEntries merges notes, cards and review log entries in dependency order
+func (s *AnkiSyncService) applyEntries(ctx context.Context, userID int64, entries *primary.AnkiSyncChunk) error {
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return err
+	}
+	crt, err := s.collectionCreated(ctx, userID, state)
+	if err != nil {
+		return err
+	}
+
+	if err := s.applyNotes(ctx, userID, entries.Notes); err != nil {
+		return err
+	}
+	if err := s.applyCards(ctx, userID, entries.Cards, entries.Revlog, crt); err != nil {
+		return err
+	}
+	return s.applyRevlog(ctx, userID, entries.Revlog)
+}
+
+// SanityCheck compares the client's counts with the server's
+// Reviews are deleted with their cards, while Anki keeps the review log of deleted cards, and the server
+// keeps no graves once sent; both counts are taken from the client
+func (s *AnkiSyncService) SanityCheck(ctx context.Context, userID int64, client *primary.AnkiSyncCounts) (*primary.AnkiSyncSanityResult, error) {
+	if _, err := s.loadSession(ctx, userID); err != nil {
+		return nil, err
+	}
+	counts, err := s.syncRepo.Count(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &primary.AnkiSyncCounts{
+		Cards:     counts.Cards,
+		Notes:     counts.Notes,
+		Revlog:    client.Revlog,
+		Graves:    client.Graves,
+		Models:    counts.NoteTypes,
+		Decks:     counts.Decks + counts.FilteredDecks,
+		DeckConfs: counts.Presets,
+	}
+	// Every Anki collection has the Default deck and Default deck options (ID 1), mapped or not
+	defaults, err := s.syncRepo.FindObjectIDs(ctx, userID, secondary.SyncObjectDeck, []int64{ankiDefaultID})
+	if err != nil {
+		return nil, err
+	}
+	if len(defaults) == 0 {
+		server.Decks++
+	}
+	defaults, err = s.syncRepo.FindObjectIDs(ctx, userID, secondary.SyncObjectPreset, []int64{ankiDefaultID})
+	if err != nil {
+		return nil, err
+	}
+	if len(defaults) == 0 {
+		server.DeckConfs++
+	}
+
+	if *server != *client {
+		return &primary.AnkiSyncSanityResult{Status: primary.AnkiSyncSanityBad, Client: client, Server: server}, nil
+	}
+	return &primary.AnkiSyncSanityResult{Status: primary.AnkiSyncSanityOK}, nil
+}
+
+// Finish completes the sync: both sides continue from the next USN
+func (s *AnkiSyncService) Finish(ctx context.Context, userID int64) (int64, error) {
+	session, err := s.loadSession(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// The delta sync API may have moved the USN on during the sync
+	now := time.Now()
+	state.USN = max(state.USN, session.USN+1)
+	state.ModifiedAt = now
+	state.LastSyncAt = &now
+	if err := s.syncRepo.UpdateState(ctx, userID, state); err != nil {
+		return 0, err
+	}
+	if err := s.cacheRepo.Delete(ctx, syncSessionKey(userID)); err != nil {
+		return 0, fmt.Errorf("failed to delete sync session: %w", err)
+	}
+	return now.UnixMilli(), nil
+}
+
+// Abort discards the sync in progress; changes already applied are kept
+func (s *AnkiSyncService) Abort(ctx context.Context, userID int64) error {
+	if err := s.cacheRepo.Delete(ctx, syncSessionKey(userID)); err != nil {
+		return fmt.Errorf("failed to delete sync session: %w", err)
+	}
+	return nil
+}
+
+// Upload replaces the user's collection with a client's collection database
+func (s *AnkiSyncService) Upload(ctx context.Context, userID int64, data []byte) error {
+	col, err := readCollection(ctx, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+
+	err = s.tm.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.syncRepo.DeleteCollection(txCtx, userID); err != nil {
+			return err
+		}
+		// Objects are stamped with the USN before the client's, so the next sync doesn't send them back
+		state, err := s.syncRepo.FindState(txCtx, userID)
+		if err != nil {
+			return err
+		}
+		state.USN = max(col.usn-1, 0)
+		if err := s.syncRepo.UpdateState(txCtx, userID, state); err != nil {
+			return err
+		}
+
+		if err := s.applyDeckConfigs(txCtx, userID, col.deckConfigs); err != nil {
+			return err
+		}
+		if err := s.applyDecks(txCtx, userID, col.decks); err != nil {
+			return err
+		}
+		if err := s.applyModels(txCtx, userID, col.models); err != nil {
+			return err
+		}
+		state.CollectionCreated = &col.crt
+		if err := s.syncRepo.UpdateState(txCtx, userID, state); err != nil {
+			return err
+		}
+		if err := s.applyEntries(txCtx, userID, col.entries); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		state.USN = max(col.usn, state.USN+1)
+		state.ModifiedAt = time.UnixMilli(col.modified)
+		state.SchemaModifiedAt = time.UnixMilli(col.schemaModified)
+		state.ConfigJSON = col.configJSON
+		state.LastSyncAt = &now
+		return s.syncRepo.UpdateState(txCtx, userID, state)
+	})
+	if err != nil {
+		return err
+	}
+	return s.Abort(ctx, userID)
+}
+
+// Download returns the user's collection as a schema 11 collection database
+// The client continues from the collection's USN, so the server moves on to the next one
+func (s *AnkiSyncService) Download(ctx context.Context, userID int64) ([]byte, error) {
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	crt, err := s.collectionCreated(ctx, userID, state)
+	if err != nil {
+		return nil, err
+	}
+	state.USN++
+	if err := s.syncRepo.UpdateState(ctx, userID, state); err != nil {
+		return nil, err
+	}
+
+	session := &syncSession{USN: state.USN}
+	changes, err := s.serverChanges(ctx, userID, session, state)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.allEntries(ctx, userID, session.USN, crt)
+	if err != nil {
+		return nil, err
+	}
+
+	col := &collectionFile{
+		crt:            crt,
+		usn:            state.USN,
+		modified:       state.ModifiedAt.UnixMilli(),
+		schemaModified: state.SchemaModifiedAt.UnixMilli(),
+		configJSON:     state.ConfigJSON,
+		models:         changes.Models,
+		decks:          changes.Decks.Decks,
+		deckConfigs:    changes.Decks.Configs,
+		entries:        entries,
+	}
+	return writeCollection(ctx, col, time.Now())
+}
+
+// updateCollectionConfig stores the collection configuration and creation time sent by a client
+func (s *AnkiSyncService) updateCollectionConfig(ctx context.Context, userID int64, conf map[string]interface{}, crt *int64) error {
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if conf != nil {
+		data, err := json.Marshal(conf)
+		if err != nil {
+			return fmt.Errorf("failed to encode collection config: %w", err)
+		}
+		state.ConfigJSON = string(data)
+	}
+	if crt != nil {
+		state.CollectionCreated = crt
+	}
+	return s.syncRepo.UpdateState(ctx, userID, state)
+}
+
+// loadSession returns the sync in progress
+func (s *AnkiSyncService) loadSession(ctx context.Context, userID int64) (*syncSession, error) {
+	key := syncSessionKey(userID)
+	exists, err := s.cacheRepo.Exists(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sync session: %w", err)
+	}
+	if !exists {
+		return nil, ErrSyncSessionNotFound
+	}
+	data, err := s.cacheRepo.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sync session: %w", err)
+	}
+
+	var session syncSession
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, ErrSyncSessionNotFound
+	}
+	return &session, nil
+}
+
+// saveSession stores the sync in progress, extending its expiry
+func (s *AnkiSyncService) saveSession(ctx context.Context, userID int64, session *syncSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode sync session: %w", err)
+	}
+	if err := s.cacheRepo.Set(ctx, syncSessionKey(userID), string(data), syncSessionTTL); err != nil {
+		return fmt.Errorf("failed to save sync session: %w", err)
+	}
+	return nil
+}
+
+func syncSessionKey(userID int64) string {
+	return fmt.Sprintf("%s%d", syncSessionKeyPrefix, userID)
+}
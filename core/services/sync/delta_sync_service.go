@@ -0,0 +1,514 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deletionlog "github.com/felipesantos/anki-backend/core/domain/entities/deletion_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// DeltaSyncService implements IDeltaSyncService
+type DeltaSyncService struct {
+	syncRepo        secondary.IAnkiSyncRepository
+	deletionLogRepo secondary.IDeletionLogRepository
+	noteRepo        secondary.INoteRepository
+	cardRepo        secondary.ICardRepository
+	deckRepo        secondary.IDeckRepository
+	noteTypeRepo    secondary.INoteTypeRepository
+	mediaRepo       secondary.IMediaRepository
+	noteService     primary.INoteService
+	cardService     primary.ICardService
+	deckService     primary.IDeckService
+	noteTypeService primary.INoteTypeService
+	reviewService   primary.IReviewService
+	syncMetaService primary.ISyncMetaService
+}
+
+// NewDeltaSyncService creates a new DeltaSyncService instance
+func NewDeltaSyncService(
+	syncRepo secondary.IAnkiSyncRepository,
+	deletionLogRepo secondary.IDeletionLogRepository,
+	noteRepo secondary.INoteRepository,
+	cardRepo secondary.ICardRepository,
+	deckRepo secondary.IDeckRepository,
+	noteTypeRepo secondary.INoteTypeRepository,
+	mediaRepo secondary.IMediaRepository,
+	noteService primary.INoteService,
+	cardService primary.ICardService,
+	deckService primary.IDeckService,
+	noteTypeService primary.INoteTypeService,
+	reviewService primary.IReviewService,
+	syncMetaService primary.ISyncMetaService,
+) primary.IDeltaSyncService {
+	return &DeltaSyncService{
+		syncRepo:        syncRepo,
+		deletionLogRepo: deletionLogRepo,
+		noteRepo:        noteRepo,
+		cardRepo:        cardRepo,
+		deckRepo:        deckRepo,
+		noteTypeRepo:    noteTypeRepo,
+		mediaRepo:       mediaRepo,
+		noteService:     noteService,
+		cardService:     cardService,
+		deckService:     deckService,
+		noteTypeService: noteTypeService,
+		reviewService:   reviewService,
+		syncMetaService: syncMetaService,
+	}
+}
+
+// FindChanges returns the objects changed and deleted since a USN
+// The collection moves to the next USN first, so changes made while the client fetches are found next time
+// A since beyond the current USN (after a full upload from an Anki client) returns every object
+func (s *DeltaSyncService) FindChanges(ctx context.Context, userID int64, clientID string, since int) (*primary.SyncChanges, error) {
+	usn, err := s.syncRepo.AdvanceUSN(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &primary.SyncChanges{USN: usn, Full: since < 0 || since > usn}
+	minUSN := since + 1
+	if changes.Full {
+		minUSN = 0
+	}
+
+	noteIDs, err := s.changedIDs(ctx, userID, secondary.SyncObjectNote, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	if changes.Notes, err = s.noteRepo.FindByIDs(ctx, userID, noteIDs); err != nil {
+		return nil, fmt.Errorf("failed to find notes: %w", err)
+	}
+
+	cardIDs, err := s.changedIDs(ctx, userID, secondary.SyncObjectCard, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	if changes.Cards, err = s.syncRepo.FindCards(ctx, userID, cardIDs); err != nil {
+		return nil, err
+	}
+
+	reviewIDs, err := s.changedIDs(ctx, userID, secondary.SyncObjectReview, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	if changes.Reviews, err = s.syncRepo.FindReviews(ctx, userID, reviewIDs); err != nil {
+		return nil, err
+	}
+
+	if changes.Decks, err = s.changedDecks(ctx, userID, minUSN); err != nil {
+		return nil, err
+	}
+	if changes.NoteTypes, err = s.changedNoteTypes(ctx, userID, minUSN); err != nil {
+		return nil, err
+	}
+	if changes.Media, err = s.changedMedia(ctx, userID, minUSN); err != nil {
+		return nil, err
+	}
+
+	changes.Deleted = []*deletionlog.DeletionLog{}
+	if !changes.Full {
+		if changes.Deleted, err = s.deletionLogRepo.FindByMinUSN(ctx, userID, minUSN); err != nil {
+			return nil, err
+		}
+	}
+
+	if clientID != "" {
+		if _, err := s.syncMetaService.Update(ctx, userID, clientID, int64(usn)); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// changedIDs returns the active objects of a type changed since minUSN, in ID order
+func (s *DeltaSyncService) changedIDs(ctx context.Context, userID int64, objectType string, minUSN int) ([]int64, error) {
+	changed := []int64{}
+	var afterID int64
+	for {
+		ids, err := s.syncRepo.FindChangedIDs(ctx, userID, objectType, minUSN, afterID, syncChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, ids...)
+		if len(ids) < syncChunkSize {
+			return changed, nil
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
+
+// changedSet returns the objects of a type changed since minUSN as a set
+func (s *DeltaSyncService) changedSet(ctx context.Context, userID int64, objectType string, minUSN int) (map[int64]bool, error) {
+	ids, err := s.changedIDs(ctx, userID, objectType, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+// changedDecks returns the decks changed since minUSN
+func (s *DeltaSyncService) changedDecks(ctx context.Context, userID int64, minUSN int) ([]*deck.Deck, error) {
+	changed, err := s.changedSet(ctx, userID, secondary.SyncObjectDeck, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	decks := []*deck.Deck{}
+	if len(changed) == 0 {
+		return decks, nil
+	}
+	all, err := s.deckRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find decks: %w", err)
+	}
+	for _, d := range all {
+		if changed[d.GetID()] {
+			decks = append(decks, d)
+		}
+	}
+	return decks, nil
+}
+
+// changedNoteTypes returns the note types changed since minUSN
+func (s *DeltaSyncService) changedNoteTypes(ctx context.Context, userID int64, minUSN int) ([]*notetype.NoteType, error) {
+	changed, err := s.changedSet(ctx, userID, secondary.SyncObjectNoteType, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	noteTypes := []*notetype.NoteType{}
+	if len(changed) == 0 {
+		return noteTypes, nil
+	}
+	all, err := s.noteTypeRepo.FindByUserID(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find note types: %w", err)
+	}
+	for _, nt := range all {
+		if changed[nt.GetID()] {
+			noteTypes = append(noteTypes, nt)
+		}
+	}
+	return noteTypes, nil
+}
+
+// changedMedia returns the media files changed since minUSN
+func (s *DeltaSyncService) changedMedia(ctx context.Context, userID int64, minUSN int) ([]*media.Media, error) {
+	changed, err := s.changedSet(ctx, userID, secondary.SyncObjectMedia, minUSN)
+	if err != nil {
+		return nil, err
+	}
+	mediaFiles := []*media.Media{}
+	if len(changed) == 0 {
+		return mediaFiles, nil
+	}
+	all, err := s.mediaRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find media: %w", err)
+	}
+	for _, m := range all {
+		if changed[m.GetID()] {
+			mediaFiles = append(mediaFiles, m)
+		}
+	}
+	return mediaFiles, nil
+}
+
+// pushState collects the outcome of a push
+// Objects changed earlier in the same push are not checked for conflicts again
+type pushState struct {
+	result  *primary.SyncPushResult
+	touched map[string]bool
+}
+
+func (p *pushState) touch(objectType string, id int64) {
+	p.touched[fmt.Sprintf("%s:%d", objectType, id)] = true
+}
+
+func (p *pushState) isTouched(objectType string, id int64) bool {
+	return p.touched[fmt.Sprintf("%s:%d", objectType, id)]
+}
+
+func (p *pushState) applied(objectType string, id int64) {
+	p.result.Applied++
+	p.touch(objectType, id)
+}
+
+func (p *pushState) created(objectType string, clientRef string, id int64) {
+	p.result.Created = append(p.result.Created, primary.SyncCreated{ObjectType: objectType, ClientRef: clientRef, ID: id})
+	p.applied(objectType, id)
+}
+
+func (p *pushState) conflict(objectType string, id int64, clientRef string, reason string, message string) {
+	p.result.Conflicts = append(p.result.Conflicts, primary.SyncConflict{
+		ObjectType: objectType,
+		ID:         id,
+		ClientRef:  clientRef,
+		Reason:     reason,
+		Message:    message,
+	})
+}
+
+// rejected reports a change the services refused
+func (p *pushState) rejected(objectType string, id int64, clientRef string, err error) {
+	p.conflict(objectType, id, clientRef, primary.SyncConflictRejected, err.Error())
+}
+
+// isStale tells whether the server's object changed after the client's change
+func (p *pushState) isStale(objectType string, id int64, serverUpdatedAt time.Time, clientModifiedAt time.Time) bool {
+	if p.isTouched(objectType, id) || !serverUpdatedAt.After(clientModifiedAt) {
+		return false
+	}
+	p.conflict(objectType, id, "", primary.SyncConflictServerNewer, "")
+	return true
+}
+
+// Push applies a batch of client changes with last-writer-wins conflict resolution
+// A change older than the server's object, or to an object deleted on the server, is not applied
+// and reported as a conflict; invalid changes are reported without stopping the batch
+func (s *DeltaSyncService) Push(ctx context.Context, userID int64, push *primary.SyncPush) (*primary.SyncPushResult, error) {
+	state := &pushState{
+		result:  &primary.SyncPushResult{Created: []primary.SyncCreated{}, Conflicts: []primary.SyncConflict{}},
+		touched: make(map[string]bool),
+	}
+
+	steps := []func(context.Context, int64, *primary.SyncPush, *pushState) error{
+		s.pushNoteTypes,
+		s.pushDecks,
+		s.pushNotes,
+		s.pushCards,
+		s.pushReviews,
+	}
+	for _, step := range steps {
+		if err := step(ctx, userID, push, state); err != nil {
+			return nil, err
+		}
+	}
+	return state.result, nil
+}
+
+// pushNoteTypes applies note type changes
+func (s *DeltaSyncService) pushNoteTypes(ctx context.Context, userID int64, push *primary.SyncPush, state *pushState) error {
+	const objectType = secondary.SyncObjectNoteType
+	for _, change := range push.NoteTypes {
+		if change.Action == primary.SyncActionCreate {
+			nt, err := s.noteTypeService.Create(ctx, userID, change.Name, change.FieldsJSON, change.CardTypesJSON, change.TemplatesJSON)
+			if err != nil {
+				state.rejected(objectType, 0, change.ClientRef, err)
+				continue
+			}
+			state.created(objectType, change.ClientRef, nt.GetID())
+			continue
+		}
+
+		existing, err := s.noteTypeRepo.FindByID(ctx, userID, change.ID)
+		if err != nil {
+			return fmt.Errorf("failed to find note type: %w", err)
+		}
+		if existing == nil {
+			if change.Action != primary.SyncActionDelete {
+				state.conflict(objectType, change.ID, change.ClientRef, primary.SyncConflictDeleted, "")
+			}
+			continue
+		}
+		if state.isStale(objectType, change.ID, existing.GetUpdatedAt(), change.ModifiedAt) {
+			continue
+		}
+
+		switch change.Action {
+		case primary.SyncActionUpdate:
+			_, err = s.noteTypeService.Update(ctx, userID, change.ID, change.Name, change.FieldsJSON, change.CardTypesJSON, change.TemplatesJSON)
+		case primary.SyncActionDelete:
+			err = s.noteTypeService.Delete(ctx, userID, change.ID)
+		default:
+			err = fmt.Errorf("invalid action: %s", change.Action)
+		}
+		if err != nil {
+			state.rejected(objectType, change.ID, change.ClientRef, err)
+			continue
+		}
+		state.applied(objectType, change.ID)
+	}
+	return nil
+}
+
+// pushDecks applies deck changes
+func (s *DeltaSyncService) pushDecks(ctx context.Context, userID int64, push *primary.SyncPush, state *pushState) error {
+	const objectType = secondary.SyncObjectDeck
+	for _, change := range push.Decks {
+		if change.Action == primary.SyncActionCreate {
+			d, err := s.deckService.Create(ctx, userID, change.Name, change.ParentID, change.OptionsJSON)
+			if err != nil {
+				state.rejected(objectType, 0, change.ClientRef, err)
+				continue
+			}
+			state.created(objectType, change.ClientRef, d.GetID())
+			continue
+		}
+
+		existing, err := s.deckRepo.FindByID(ctx, userID, change.ID)
+		if err != nil {
+			return fmt.Errorf("failed to find deck: %w", err)
+		}
+		if existing == nil {
+			if change.Action != primary.SyncActionDelete {
+				state.conflict(objectType, change.ID, change.ClientRef, primary.SyncConflictDeleted, "")
+			}
+			continue
+		}
+		if state.isStale(objectType, change.ID, existing.GetUpdatedAt(), change.ModifiedAt) {
+			continue
+		}
+
+		switch change.Action {
+		case primary.SyncActionUpdate:
+			_, err = s.deckService.Update(ctx, userID, change.ID, change.Name, change.ParentID, change.OptionsJSON)
+		case primary.SyncActionDelete:
+			err = s.deckService.Delete(ctx, userID, change.ID, deck.ActionDeleteCards, nil)
+		default:
+			err = fmt.Errorf("invalid action: %s", change.Action)
+		}
+		if err != nil {
+			state.rejected(objectType, change.ID, change.ClientRef, err)
+			continue
+		}
+		state.applied(objectType, change.ID)
+	}
+	return nil
+}
+
+// pushNotes applies note changes
+func (s *DeltaSyncService) pushNotes(ctx context.Context, userID int64, push *primary.SyncPush, state *pushState) error {
+	const objectType = secondary.SyncObjectNote
+	for _, change := range push.Notes {
+		if change.Action == primary.SyncActionCreate {
+			n, err := s.noteService.Create(ctx, userID, change.NoteTypeID, change.DeckID, change.FieldsJSON, change.Tags)
+			if err != nil {
+				state.rejected(objectType, 0, change.ClientRef, err)
+				continue
+			}
+			state.created(objectType, change.ClientRef, n.GetID())
+			continue
+		}
+
+		existing, err := s.noteRepo.FindByID(ctx, userID, change.ID)
+		if err != nil {
+			return fmt.Errorf("failed to find note: %w", err)
+		}
+		if existing == nil {
+			if change.Action != primary.SyncActionDelete {
+				state.conflict(objectType, change.ID, change.ClientRef, primary.SyncConflictDeleted, "")
+			}
+			continue
+		}
+		if state.isStale(objectType, change.ID, existing.GetUpdatedAt(), change.ModifiedAt) {
+			continue
+		}
+
+		switch change.Action {
+		case primary.SyncActionUpdate:
+			_, err = s.noteService.Update(ctx, userID, change.ID, change.FieldsJSON, change.Tags)
+		case primary.SyncActionDelete:
+			err = s.noteService.Delete(ctx, userID, change.ID)
+		default:
+			err = fmt.Errorf("invalid action: %s", change.Action)
+		}
+		if err != nil {
+			state.rejected(objectType, change.ID, change.ClientRef, err)
+			continue
+		}
+		state.applied(objectType, change.ID)
+	}
+	return nil
+}
+
+// pushCards applies card flag, suspension and burial changes
+func (s *DeltaSyncService) pushCards(ctx context.Context, userID int64, push *primary.SyncPush, state *pushState) error {
+	const objectType = secondary.SyncObjectCard
+	for _, change := range push.Cards {
+		existing, err := s.cardRepo.FindByID(ctx, userID, change.ID)
+		if err != nil {
+			return fmt.Errorf("failed to find card: %w", err)
+		}
+		if existing == nil {
+			state.conflict(objectType, change.ID, "", primary.SyncConflictDeleted, "")
+			continue
+		}
+		if state.isStale(objectType, change.ID, existing.GetUpdatedAt(), change.ModifiedAt) {
+			continue
+		}
+
+		if err := s.applyCardChange(ctx, userID, change); err != nil {
+			state.rejected(objectType, change.ID, "", err)
+			continue
+		}
+		state.applied(objectType, change.ID)
+	}
+	return nil
+}
+
+// applyCardChange sets the values a card change holds
+func (s *DeltaSyncService) applyCardChange(ctx context.Context, userID int64, change primary.SyncCardChange) error {
+	if change.Flag != nil {
+		if err := s.cardService.SetFlag(ctx, userID, change.ID, *change.Flag); err != nil {
+			return err
+		}
+	}
+	if change.Suspended != nil {
+		apply := s.cardService.Unsuspend
+		if *change.Suspended {
+			apply = s.cardService.Suspend
+		}
+		if err := apply(ctx, userID, change.ID); err != nil {
+			return err
+		}
+	}
+	if change.Buried != nil {
+		apply := s.cardService.Unbury
+		if *change.Buried {
+			apply = s.cardService.Bury
+		}
+		if err := apply(ctx, userID, change.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushReviews answers cards with the reviews a client recorded
+// A review is stale when its card changed on the server after the answer, e.g. when another client answered it
+func (s *DeltaSyncService) pushReviews(ctx context.Context, userID int64, push *primary.SyncPush, state *pushState) error {
+	const objectType = secondary.SyncObjectReview
+	for _, change := range push.Reviews {
+		c, err := s.cardRepo.FindByID(ctx, userID, change.CardID)
+		if err != nil {
+			return fmt.Errorf("failed to find card: %w", err)
+		}
+		if c == nil {
+			state.conflict(objectType, 0, change.ClientRef, primary.SyncConflictDeleted, fmt.Sprintf("card %d not found", change.CardID))
+			continue
+		}
+		if !state.isTouched(secondary.SyncObjectCard, change.CardID) && c.GetUpdatedAt().After(change.ReviewedAt) {
+			state.conflict(objectType, 0, change.ClientRef, primary.SyncConflictServerNewer, fmt.Sprintf("card %d changed after the review", change.CardID))
+			continue
+		}
+
+		r, err := s.reviewService.CreateAt(ctx, userID, change.CardID, change.Rating, change.TimeMs, change.ReviewedAt)
+		if err != nil {
+			state.rejected(objectType, 0, change.ClientRef, err)
+			continue
+		}
+		state.created(objectType, change.ClientRef, r.GetID())
+		state.touch(secondary.SyncObjectCard, change.CardID)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/sync_meta"
@@ -9,15 +10,20 @@ import (
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 )
 
+// ErrInvalidSyncUSN is returned when a client reports a USN the server hasn't reached
+var ErrInvalidSyncUSN = errors.New("last sync USN is ahead of the server")
+
 // SyncMetaService implements ISyncMetaService
 type SyncMetaService struct {
-	repo secondary.ISyncMetaRepository
+	repo     secondary.ISyncMetaRepository
+	syncRepo secondary.IAnkiSyncRepository
 }
 
 // NewSyncMetaService creates a new SyncMetaService instance
-func NewSyncMetaService(repo secondary.ISyncMetaRepository) primary.ISyncMetaService {
+func NewSyncMetaService(repo secondary.ISyncMetaRepository, syncRepo secondary.IAnkiSyncRepository) primary.ISyncMetaService {
 	return &SyncMetaService{
-		repo: repo,
+		repo:     repo,
+		syncRepo: syncRepo,
 	}
 }
 
@@ -33,9 +39,18 @@ func (s *SyncMetaService) FindByUserID(ctx context.Context, userID int64) (*sync
 	return metas[0], nil
 }
 
-// Update updates sync metadata after a synchronization
+// Update records the USN a client synced up to
+// Each client keeps its own metadata; the USN can't be ahead of the user's current USN
 func (s *SyncMetaService) Update(ctx context.Context, userID int64, clientID string, lastSyncUSN int64) (*syncmeta.SyncMeta, error) {
-	existing, err := s.FindByUserID(ctx, userID)
+	state, err := s.syncRepo.FindState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if lastSyncUSN > int64(state.USN) {
+		return nil, ErrInvalidSyncUSN
+	}
+
+	existing, err := s.repo.FindByClientID(ctx, userID, clientID)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +77,6 @@ func (s *SyncMetaService) Update(ctx context.Context, userID int64, clientID str
 		return newMeta, nil
 	} else {
 		// Update existing
-		existing.SetClientID(clientID)
 		existing.SetLastSync(now)
 		existing.SetLastSyncUSN(lastSyncUSN)
 		existing.SetUpdatedAt(now)
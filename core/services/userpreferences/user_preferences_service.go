@@ -66,6 +66,7 @@ func (s *UserPreferencesService) createAndSaveDefaults(ctx context.Context, user
 		WithTheme(valueobjects.ThemeTypeAuto).
 		WithAutoSync(true).
 		WithNextDayStartsAt(nextDayStartsAt).
+		WithTimezone(userpreferences.DefaultTimezone).
 		WithLearnAheadLimit(20).
 		WithTimeboxTimeLimit(0).
 		WithVideoDriver("auto").
@@ -16,6 +16,8 @@ import (
 	emailService "github.com/felipesantos/anki-backend/core/services/email"
 	exportService "github.com/felipesantos/anki-backend/core/services/export"
 	"github.com/felipesantos/anki-backend/core/services/health"
+	importService "github.com/felipesantos/anki-backend/core/services/importer"
+	jobService "github.com/felipesantos/anki-backend/core/services/jobs"
 	mediaService "github.com/felipesantos/anki-backend/core/services/media"
 	metricsService "github.com/felipesantos/anki-backend/core/services/metrics"
 	noteService "github.com/felipesantos/anki-backend/core/services/note"
@@ -28,11 +30,13 @@ import (
 	shareddeckService "github.com/felipesantos/anki-backend/core/services/shareddeck"
 	shareddeckratingService "github.com/felipesantos/anki-backend/core/services/shareddeckrating"
 	storageService "github.com/felipesantos/anki-backend/core/services/storage"
+	studyService "github.com/felipesantos/anki-backend/core/services/study"
 	syncService "github.com/felipesantos/anki-backend/core/services/sync"
 	userService "github.com/felipesantos/anki-backend/core/services/user"
 	userpreferencesService "github.com/felipesantos/anki-backend/core/services/userpreferences"
 	"github.com/felipesantos/anki-backend/infra/database/repositories"
 	infraEmail "github.com/felipesantos/anki-backend/infra/email"
+	infraJobs "github.com/felipesantos/anki-backend/infra/jobs"
 	"github.com/felipesantos/anki-backend/infra/redis"
 	"github.com/felipesantos/anki-backend/pkg/database"
 	"github.com/felipesantos/anki-backend/pkg/jwt"
@@ -70,7 +74,7 @@ func GetDeckService() primary.IDeckService {
 	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
 	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
 	tm := database.NewTransactionManager(dbRepo.GetDB())
-	return deckService.NewDeckService(deckRepo, cardRepo, GetBackupService(), tm)
+	return deckService.NewDeckService(deckRepo, cardRepo, GetBackupService(), GetUndoHistoryService(), tm)
 }
 
 // GetDeckOptionsPresetService returns a fresh instance of DeckOptionsPresetService
@@ -81,6 +85,15 @@ func GetDeckOptionsPresetService() primary.IDeckOptionsPresetService {
 	return deckService.NewDeckOptionsPresetService(presetRepo, deckRepo, tm)
 }
 
+// GetFSRSOptimizerService returns a fresh instance of FSRSOptimizerService
+func GetFSRSOptimizerService() primary.IFSRSOptimizerService {
+	reviewRepo := repositories.NewReviewRepository(dbRepo.GetDB())
+	presetRepo := repositories.NewDeckOptionsPresetRepository(dbRepo.GetDB())
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	tm := database.NewTransactionManager(dbRepo.GetDB())
+	return deckService.NewFSRSOptimizerService(reviewRepo, presetRepo, deckRepo, tm)
+}
+
 // GetDeckStatsService returns a fresh instance of DeckStatsService
 func GetDeckStatsService() primary.IDeckStatsService {
 	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
@@ -90,7 +103,9 @@ func GetDeckStatsService() primary.IDeckStatsService {
 // GetFilteredDeckService returns a fresh instance of FilteredDeckService
 func GetFilteredDeckService() primary.IFilteredDeckService {
 	filteredDeckRepo := repositories.NewFilteredDeckRepository(dbRepo.GetDB())
-	return deckService.NewFilteredDeckService(filteredDeckRepo)
+	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
+	tm := database.NewTransactionManager(dbRepo.GetDB())
+	return deckService.NewFilteredDeckService(filteredDeckRepo, cardRepo, tm)
 }
 
 // GetCardService returns a fresh instance of CardService
@@ -102,15 +117,37 @@ func GetCardService() primary.ICardService {
 	reviewService := GetReviewService()
 	tm := database.NewTransactionManager(dbRepo.GetDB())
 	templateRenderer := GetTemplateRenderer()
-	return cardService.NewCardService(cardRepo, noteService, deckService, noteTypeService, reviewService, templateRenderer, tm)
+	return cardService.NewCardService(cardRepo, noteService, deckService, noteTypeService, reviewService, templateRenderer, GetUndoHistoryService(), tm)
 }
 
 // GetReviewService returns a fresh instance of ReviewService
 func GetReviewService() primary.IReviewService {
 	reviewRepo := repositories.NewReviewRepository(dbRepo.GetDB())
 	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	filteredDeckRepo := repositories.NewFilteredDeckRepository(dbRepo.GetDB())
+	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
+	userPreferencesRepo := repositories.NewUserPreferencesRepository(dbRepo.GetDB())
+	undoHistoryRepo := repositories.NewUndoHistoryRepository(dbRepo.GetDB())
 	tm := database.NewTransactionManager(dbRepo.GetDB())
-	return reviewService.NewReviewService(reviewRepo, cardRepo, tm)
+	return reviewService.NewReviewService(reviewRepo, cardRepo, deckRepo, filteredDeckRepo, noteRepo, userPreferencesRepo, undoHistoryRepo, eventBus, tm)
+}
+
+// GetStudyService returns a fresh instance of StudyService
+func GetStudyService() primary.IStudyService {
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	filteredDeckRepo := repositories.NewFilteredDeckRepository(dbRepo.GetDB())
+	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
+	reviewRepo := repositories.NewReviewRepository(dbRepo.GetDB())
+	userPreferencesRepo := repositories.NewUserPreferencesRepository(dbRepo.GetDB())
+	return studyService.NewStudyService(deckRepo, filteredDeckRepo, cardRepo, reviewRepo, userPreferencesRepo)
+}
+
+// GetCustomStudyService returns a fresh instance of CustomStudyService
+func GetCustomStudyService() primary.ICustomStudyService {
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	userPreferencesRepo := repositories.NewUserPreferencesRepository(dbRepo.GetDB())
+	return studyService.NewCustomStudyService(deckRepo, userPreferencesRepo, GetFilteredDeckService())
 }
 
 // GetNoteTypeService returns a fresh instance of NoteTypeService
@@ -127,7 +164,7 @@ func GetNoteService() primary.INoteService {
 	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
 	tm := database.NewTransactionManager(dbRepo.GetDB())
 	templateRenderer := GetTemplateRenderer()
-	return noteService.NewNoteService(noteRepo, cardRepo, noteTypeRepo, deckRepo, templateRenderer, tm)
+	return noteService.NewNoteService(noteRepo, cardRepo, noteTypeRepo, deckRepo, templateRenderer, GetUndoHistoryService(), tm)
 }
 
 // GetTemplateRenderer returns a fresh instance of TemplateRenderer
@@ -139,7 +176,8 @@ func GetTemplateRenderer() services.ITemplateRenderer {
 func GetSearchService() primary.ISearchService {
 	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
 	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
-	return searchService.NewSearchService(noteRepo, cardRepo)
+	browserConfigRepo := repositories.NewBrowserConfigRepository(dbRepo.GetDB())
+	return searchService.NewSearchService(noteRepo, cardRepo, browserConfigRepo)
 }
 
 // GetUserService returns a fresh instance of UserService
@@ -180,7 +218,21 @@ func GetExportService() primary.IExportService {
 	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
 	noteTypeRepo := repositories.NewNoteTypeRepository(dbRepo.GetDB())
 	mediaRepo := repositories.NewMediaRepository(dbRepo.GetDB())
-	return exportService.NewExportService(deckRepo, cardRepo, noteRepo, noteTypeRepo, mediaRepo)
+	reviewRepo := repositories.NewReviewRepository(dbRepo.GetDB())
+	presetRepo := repositories.NewDeckOptionsPresetRepository(dbRepo.GetDB())
+	return exportService.NewExportService(deckRepo, cardRepo, noteRepo, noteTypeRepo, mediaRepo, reviewRepo, presetRepo)
+}
+
+// GetImportService returns a fresh instance of ImportService
+func GetImportService() primary.IImportService {
+	noteTypeRepo := repositories.NewNoteTypeRepository(dbRepo.GetDB())
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
+	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
+	reviewRepo := repositories.NewReviewRepository(dbRepo.GetDB())
+	mediaRepo := repositories.NewMediaRepository(dbRepo.GetDB())
+	tm := database.NewTransactionManager(dbRepo.GetDB())
+	return importService.NewImportService(noteTypeRepo, deckRepo, noteRepo, cardRepo, reviewRepo, mediaRepo, GetMediaService(), GetNoteService(), tm)
 }
 
 // GetStorageRepository returns a storage repository based on configuration
@@ -191,13 +243,99 @@ func GetStorageRepository() (secondary.IStorageRepository, error) {
 // GetMediaService returns a fresh instance of MediaService
 func GetMediaService() primary.IMediaService {
 	mediaRepo := repositories.NewMediaRepository(dbRepo.GetDB())
-	return mediaService.NewMediaService(mediaRepo)
+	storageRepo, _ := GetStorageRepository()
+	return mediaService.NewMediaService(mediaRepo, storageRepo, int64(cfg.Storage.MediaQuotaMB)<<20)
+}
+
+// GetMediaCheckService returns a fresh instance of MediaCheckService
+func GetMediaCheckService() primary.IMediaCheckService {
+	mediaRepo := repositories.NewMediaRepository(dbRepo.GetDB())
+	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
+	checkDatabaseLogRepo := repositories.NewCheckDatabaseLogRepository(dbRepo.GetDB())
+	storageRepo, _ := GetStorageRepository()
+	return mediaService.NewMediaCheckService(mediaRepo, noteRepo, storageRepo, checkDatabaseLogRepo, GetNoteService())
+}
+
+// GetCheckDatabaseService returns a fresh instance of CheckDatabaseService
+func GetCheckDatabaseService() primary.ICheckDatabaseService {
+	checkRepo := repositories.NewDatabaseCheckRepository(dbRepo.GetDB())
+	checkDatabaseLogRepo := repositories.NewCheckDatabaseLogRepository(dbRepo.GetDB())
+	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
+	noteTypeRepo := repositories.NewNoteTypeRepository(dbRepo.GetDB())
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	return auditService.NewCheckDatabaseService(checkRepo, checkDatabaseLogRepo, noteRepo, noteTypeRepo, deckRepo)
 }
 
 // GetSyncMetaService returns a fresh instance of SyncMetaService
 func GetSyncMetaService() primary.ISyncMetaService {
 	syncMetaRepo := repositories.NewSyncMetaRepository(dbRepo.GetDB())
-	return syncService.NewSyncMetaService(syncMetaRepo)
+	ankiSyncRepo := repositories.NewAnkiSyncRepository(dbRepo.GetDB())
+	return syncService.NewSyncMetaService(syncMetaRepo, ankiSyncRepo)
+}
+
+// GetDeltaSyncService returns a fresh instance of DeltaSyncService
+func GetDeltaSyncService() primary.IDeltaSyncService {
+	ankiSyncRepo := repositories.NewAnkiSyncRepository(dbRepo.GetDB())
+	deletionLogRepo := repositories.NewDeletionLogRepository(dbRepo.GetDB())
+	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
+	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	noteTypeRepo := repositories.NewNoteTypeRepository(dbRepo.GetDB())
+	mediaRepo := repositories.NewMediaRepository(dbRepo.GetDB())
+
+	return syncService.NewDeltaSyncService(
+		ankiSyncRepo,
+		deletionLogRepo,
+		noteRepo,
+		cardRepo,
+		deckRepo,
+		noteTypeRepo,
+		mediaRepo,
+		GetNoteService(),
+		GetCardService(),
+		GetDeckService(),
+		GetNoteTypeService(),
+		GetReviewService(),
+		GetSyncMetaService(),
+	)
+}
+
+// GetAnkiSyncService returns a fresh instance of AnkiSyncService
+func GetAnkiSyncService() primary.IAnkiSyncService {
+	userRepo := repositories.NewUserRepository(dbRepo.GetDB())
+	ankiSyncRepo := repositories.NewAnkiSyncRepository(dbRepo.GetDB())
+	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
+	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	noteTypeRepo := repositories.NewNoteTypeRepository(dbRepo.GetDB())
+	presetRepo := repositories.NewDeckOptionsPresetRepository(dbRepo.GetDB())
+	filteredDeckRepo := repositories.NewFilteredDeckRepository(dbRepo.GetDB())
+	reviewRepo := repositories.NewReviewRepository(dbRepo.GetDB())
+	tm := database.NewTransactionManager(dbRepo.GetDB())
+
+	return syncService.NewAnkiSyncService(
+		userRepo,
+		jwtSvc,
+		rdb,
+		ankiSyncRepo,
+		noteRepo,
+		cardRepo,
+		deckRepo,
+		noteTypeRepo,
+		presetRepo,
+		filteredDeckRepo,
+		reviewRepo,
+		tm,
+	)
+}
+
+// GetAnkiMediaSyncService returns a fresh instance of AnkiMediaSyncService
+func GetAnkiMediaSyncService() primary.IAnkiMediaSyncService {
+	mediaRepo := repositories.NewMediaRepository(dbRepo.GetDB())
+	storageRepo, _ := GetStorageRepository()
+	ankiSyncRepo := repositories.NewAnkiSyncRepository(dbRepo.GetDB())
+	prefsRepo := repositories.NewUserPreferencesRepository(dbRepo.GetDB())
+	return mediaService.NewAnkiMediaSyncService(mediaRepo, storageRepo, ankiSyncRepo, prefsRepo, int64(cfg.Storage.MediaQuotaMB)<<20)
 }
 
 // GetSharedDeckService returns a fresh instance of SharedDeckService
@@ -223,7 +361,11 @@ func GetDeletionLogService() primary.IDeletionLogService {
 // GetUndoHistoryService returns a fresh instance of UndoHistoryService
 func GetUndoHistoryService() primary.IUndoHistoryService {
 	undoHistoryRepo := repositories.NewUndoHistoryRepository(dbRepo.GetDB())
-	return auditService.NewUndoHistoryService(undoHistoryRepo)
+	noteRepo := repositories.NewNoteRepository(dbRepo.GetDB())
+	cardRepo := repositories.NewCardRepository(dbRepo.GetDB())
+	deckRepo := repositories.NewDeckRepository(dbRepo.GetDB())
+	tm := database.NewTransactionManager(dbRepo.GetDB())
+	return auditService.NewUndoHistoryService(undoHistoryRepo, noteRepo, cardRepo, deckRepo, tm)
 }
 
 // GetEmailService returns a fresh instance of EmailService
@@ -265,6 +407,16 @@ func GetAuthService() primary.IAuthService {
 	)
 }
 
+// GetJobQueue returns the Redis-backed job queue
+func GetJobQueue() secondary.IJobQueue {
+	return infraJobs.NewRedisQueue(rdb.Client, cfg.Jobs.RedisQueueKey)
+}
+
+// GetJobService returns a fresh instance of JobService
+func GetJobService() primary.IJobService {
+	return jobService.NewJobService(GetJobQueue(), cfg.Jobs.MaxRetries)
+}
+
 // GetHealthService returns a fresh instance of HealthService
 func GetHealthService() primary.IHealthService {
 	return health.NewHealthService(dbRepo, rdb)
@@ -19,6 +19,7 @@ func FilteredDeckToDomain(model *models.FilteredDeckModel) (*filtereddeck.Filter
 		WithName(model.Name).
 		WithSearchFilter(model.SearchFilter).
 		WithLimitCards(model.LimitCards).
+		WithSecondLimitCards(model.SecondLimitCards).
 		WithOrderBy(model.OrderBy).
 		WithReschedule(model.Reschedule).
 		WithCreatedAt(model.CreatedAt).
@@ -45,15 +46,16 @@ func FilteredDeckToDomain(model *models.FilteredDeckModel) (*filtereddeck.Filter
 // FilteredDeckToModel converts a FilteredDeck entity (domain representation) to a FilteredDeckModel (database representation)
 func FilteredDeckToModel(filteredDeckEntity *filtereddeck.FilteredDeck) *models.FilteredDeckModel {
 	model := &models.FilteredDeckModel{
-		ID:           filteredDeckEntity.GetID(),
-		UserID:       filteredDeckEntity.GetUserID(),
-		Name:         filteredDeckEntity.GetName(),
-		SearchFilter: filteredDeckEntity.GetSearchFilter(),
-		LimitCards:   filteredDeckEntity.GetLimitCards(),
-		OrderBy:      filteredDeckEntity.GetOrderBy(),
-		Reschedule:   filteredDeckEntity.GetReschedule(),
-		CreatedAt:    filteredDeckEntity.GetCreatedAt(),
-		UpdatedAt:    filteredDeckEntity.GetUpdatedAt(),
+		ID:               filteredDeckEntity.GetID(),
+		UserID:           filteredDeckEntity.GetUserID(),
+		Name:             filteredDeckEntity.GetName(),
+		SearchFilter:     filteredDeckEntity.GetSearchFilter(),
+		LimitCards:       filteredDeckEntity.GetLimitCards(),
+		SecondLimitCards: filteredDeckEntity.GetSecondLimitCards(),
+		OrderBy:          filteredDeckEntity.GetOrderBy(),
+		Reschedule:       filteredDeckEntity.GetReschedule(),
+		CreatedAt:        filteredDeckEntity.GetCreatedAt(),
+		UpdatedAt:        filteredDeckEntity.GetUpdatedAt(),
 	}
 
 	// Handle nullable second_filter
@@ -82,4 +84,3 @@ func FilteredDeckToModel(filteredDeckEntity *filtereddeck.FilteredDeck) *models.
 
 	return model
 }
-
@@ -1,6 +1,8 @@
 package mappers
 
 import (
+	"database/sql"
+
 	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/infra/database/models"
 )
@@ -18,17 +20,31 @@ func UndoHistoryToDomain(model *models.UndoHistoryModel) (*undohistory.UndoHisto
 		WithOperationData(model.OperationData).
 		WithCreatedAt(model.CreatedAt)
 
+	if model.UndoneAt.Valid {
+		undoneAt := model.UndoneAt.Time
+		builder = builder.WithUndoneAt(&undoneAt)
+	}
+
 	return builder.Build()
 }
 
 // UndoHistoryToModel converts an UndoHistory entity (domain representation) to an UndoHistoryModel (database representation)
 func UndoHistoryToModel(undoHistoryEntity *undohistory.UndoHistory) *models.UndoHistoryModel {
-	return &models.UndoHistoryModel{
+	model := &models.UndoHistoryModel{
 		ID:            undoHistoryEntity.GetID(),
 		UserID:        undoHistoryEntity.GetUserID(),
 		OperationType: undoHistoryEntity.GetOperationType(),
 		OperationData: undoHistoryEntity.GetOperationData(),
 		CreatedAt:     undoHistoryEntity.GetCreatedAt(),
 	}
+
+	if undoHistoryEntity.GetUndoneAt() != nil {
+		model.UndoneAt = sql.NullTime{
+			Time:  *undoHistoryEntity.GetUndoneAt(),
+			Valid: true,
+		}
+	}
+
+	return model
 }
 
@@ -33,6 +33,7 @@ func UserPreferencesToDomain(model *models.UserPreferencesModel) (*userpreferenc
 		WithTheme(theme).
 		WithAutoSync(model.AutoSync).
 		WithNextDayStartsAt(nextDayTime).
+		WithTimezone(model.Timezone).
 		WithLearnAheadLimit(model.LearnAheadLimit).
 		WithTimeboxTimeLimit(model.TimeboxTimeLimit).
 		WithVideoDriver(model.VideoDriver).
@@ -84,6 +85,7 @@ func UserPreferencesToModel(prefsEntity *userpreferences.UserPreferences) *model
 		Theme:                   prefsEntity.GetTheme().String(),
 		AutoSync:                prefsEntity.GetAutoSync(),
 		NextDayStartsAt:         nextDayTime,
+		Timezone:                prefsEntity.Location().String(),
 		LearnAheadLimit:         prefsEntity.GetLearnAheadLimit(),
 		TimeboxTimeLimit:        prefsEntity.GetTimeboxTimeLimit(),
 		VideoDriver:             prefsEntity.GetVideoDriver(),
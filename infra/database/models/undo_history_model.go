@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 )
 
@@ -11,5 +12,6 @@ type UndoHistoryModel struct {
 	OperationType string
 	OperationData string // JSONB stored as string
 	CreatedAt     time.Time
+	UndoneAt      sql.NullTime
 }
 
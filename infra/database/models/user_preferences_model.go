@@ -13,6 +13,7 @@ type UserPreferencesModel struct {
 	Theme                      string // theme_type enum
 	AutoSync                   bool
 	NextDayStartsAt            time.Time // TIME stored as time.Time (using date part as 1970-01-01)
+	Timezone                   string    // IANA time zone name
 	LearnAheadLimit            int
 	TimeboxTimeLimit           int
 	VideoDriver                string
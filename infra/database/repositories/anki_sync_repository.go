@@ -0,0 +1,509 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/infra/database/mappers"
+	"github.com/felipesantos/anki-backend/infra/database/models"
+)
+
+// syncTables are the tables of the objects of each sync object type
+var syncTables = map[string]string{
+	secondary.SyncObjectNote:         "notes",
+	secondary.SyncObjectCard:         "cards",
+	secondary.SyncObjectDeck:         "decks",
+	secondary.SyncObjectFilteredDeck: "filtered_decks",
+	secondary.SyncObjectNoteType:     "note_types",
+	secondary.SyncObjectPreset:       "deck_options_presets",
+	secondary.SyncObjectReview:       "reviews",
+	secondary.SyncObjectMedia:        "media",
+}
+
+// AnkiSyncRepository implements IAnkiSyncRepository using PostgreSQL
+type AnkiSyncRepository struct {
+	db *sql.DB
+}
+
+// NewAnkiSyncRepository creates a new AnkiSyncRepository instance
+func NewAnkiSyncRepository(db *sql.DB) secondary.IAnkiSyncRepository {
+	return &AnkiSyncRepository{
+		db: db,
+	}
+}
+
+// FindState finds the sync state of a user's collection, creating it on first use
+func (r *AnkiSyncRepository) FindState(ctx context.Context, userID int64) (*secondary.AnkiSyncState, error) {
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO sync_state (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+		return nil, fmt.Errorf("failed to create sync state: %w", err)
+	}
+
+	query := `
+		SELECT usn, modified_at, schema_modified_at, collection_created_at, COALESCE(config_json::TEXT, ''), last_sync_at
+		FROM sync_state
+		WHERE user_id = $1
+	`
+
+	var state secondary.AnkiSyncState
+	var crt sql.NullInt64
+	var lastSyncAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&state.USN,
+		&state.ModifiedAt,
+		&state.SchemaModifiedAt,
+		&crt,
+		&state.ConfigJSON,
+		&lastSyncAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sync state: %w", err)
+	}
+	if crt.Valid {
+		state.CollectionCreated = &crt.Int64
+	}
+	if lastSyncAt.Valid {
+		state.LastSyncAt = &lastSyncAt.Time
+	}
+	return &state, nil
+}
+
+// UpdateState saves the sync state of a user's collection
+func (r *AnkiSyncRepository) UpdateState(ctx context.Context, userID int64, state *secondary.AnkiSyncState) error {
+	query := `
+		INSERT INTO sync_state (user_id, usn, modified_at, schema_modified_at, collection_created_at, config_json, last_sync_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::JSONB, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET usn = EXCLUDED.usn,
+		    modified_at = EXCLUDED.modified_at,
+		    schema_modified_at = EXCLUDED.schema_modified_at,
+		    collection_created_at = EXCLUDED.collection_created_at,
+		    config_json = EXCLUDED.config_json,
+		    last_sync_at = EXCLUDED.last_sync_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, state.USN, state.ModifiedAt, state.SchemaModifiedAt,
+		state.CollectionCreated, state.ConfigJSON, state.LastSyncAt)
+	if err != nil {
+		return fmt.Errorf("failed to update sync state: %w", err)
+	}
+	return nil
+}
+
+// AdvanceUSN moves a user's collection to the next USN and returns the previous one
+// Writers lock the state row until they commit, so the update waits for changes stamped with the previous USN
+func (r *AnkiSyncRepository) AdvanceUSN(ctx context.Context, userID int64) (int, error) {
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO sync_state (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+		return 0, fmt.Errorf("failed to create sync state: %w", err)
+	}
+
+	var usn int
+	err := r.db.QueryRowContext(ctx, `UPDATE sync_state SET usn = usn + 1 WHERE user_id = $1 RETURNING usn - 1`, userID).Scan(&usn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance USN: %w", err)
+	}
+	return usn, nil
+}
+
+// FindAnkiIDs finds the Anki IDs of objects of a type
+func (r *AnkiSyncRepository) FindAnkiIDs(ctx context.Context, userID int64, objectType string, objectIDs []int64) ([]*secondary.AnkiSyncID, error) {
+	if len(objectIDs) == 0 {
+		return []*secondary.AnkiSyncID{}, nil
+	}
+
+	query := `
+		SELECT object_type, object_id, anki_id, COALESCE(anki_guid, '')
+		FROM anki_sync_ids
+		WHERE user_id = $1 AND object_type = $2 AND object_id = ANY($3)
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID, objectType, pq.Array(objectIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find anki IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// FindObjectIDs finds the objects with Anki IDs
+func (r *AnkiSyncRepository) FindObjectIDs(ctx context.Context, userID int64, objectType string, ankiIDs []int64) ([]*secondary.AnkiSyncID, error) {
+	if len(ankiIDs) == 0 {
+		return []*secondary.AnkiSyncID{}, nil
+	}
+
+	// anki_table is the object type, with filtered decks counted as decks
+	query := `
+		SELECT object_type, object_id, anki_id, COALESCE(anki_guid, '')
+		FROM anki_sync_ids
+		WHERE user_id = $1 AND anki_table = $2 AND anki_id = ANY($3)
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID, objectType, pq.Array(ankiIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find objects by anki IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// SaveIDs records the Anki IDs of objects that have none yet
+func (r *AnkiSyncRepository) SaveIDs(ctx context.Context, userID int64, ids []*secondary.AnkiSyncID) error {
+	query := `
+		INSERT INTO anki_sync_ids (user_id, object_type, object_id, anki_id, anki_guid)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (user_id, anki_table, anki_id) DO NOTHING
+	`
+
+	for _, id := range ids {
+		for {
+			result, err := r.db.ExecContext(ctx, query, userID, id.ObjectType, id.ObjectID, id.AnkiID, id.AnkiGUID)
+			if err != nil {
+				return fmt.Errorf("failed to save anki ID of %s %d: %w", id.ObjectType, id.ObjectID, err)
+			}
+			inserted, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to get rows affected: %w", err)
+			}
+			if inserted > 0 {
+				break
+			}
+			id.AnkiID++
+		}
+	}
+	return nil
+}
+
+// FindChangedIDs finds up to limit active objects of a type whose USN is at least minUSN, after afterID in ID order
+func (r *AnkiSyncRepository) FindChangedIDs(ctx context.Context, userID int64, objectType string, minUSN int, afterID int64, limit int) ([]int64, error) {
+	var query string
+	switch objectType {
+	case secondary.SyncObjectCard:
+		query = `
+			SELECT c.id
+			FROM cards c
+			INNER JOIN decks d ON c.deck_id = d.id
+			INNER JOIN notes n ON c.note_id = n.id
+			WHERE d.user_id = $1 AND d.deleted_at IS NULL AND n.deleted_at IS NULL
+			  AND c.usn >= $2 AND c.id > $3
+			ORDER BY c.id
+			LIMIT $4
+		`
+	case secondary.SyncObjectReview:
+		query = `
+			SELECT r.id
+			FROM reviews r
+			INNER JOIN cards c ON r.card_id = c.id
+			INNER JOIN decks d ON c.deck_id = d.id
+			WHERE d.user_id = $1 AND d.deleted_at IS NULL
+			  AND r.usn >= $2 AND r.id > $3
+			ORDER BY r.id
+			LIMIT $4
+		`
+	default:
+		table, ok := syncTables[objectType]
+		if !ok {
+			return nil, fmt.Errorf("invalid sync object type: %s", objectType)
+		}
+		query = fmt.Sprintf(`
+			SELECT id
+			FROM %s
+			WHERE user_id = $1 AND deleted_at IS NULL AND usn >= $2 AND id > $3
+			ORDER BY id
+			LIMIT $4
+		`, table)
+	}
+
+	ids, err := queryInt64s(ctx, r.db, query, userID, minUSN, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find changed %s IDs: %w", objectType, err)
+	}
+	return ids, nil
+}
+
+// FindDeletedIDs finds the objects of a type deleted with a USN of at least minUSN
+// Objects restored since their deletion are left out
+func (r *AnkiSyncRepository) FindDeletedIDs(ctx context.Context, userID int64, objectType string, minUSN int) ([]int64, error) {
+	table, ok := syncTables[objectType]
+	if !ok {
+		return nil, fmt.Errorf("invalid sync object type: %s", objectType)
+	}
+
+	active := "TRUE"
+	if objectType != secondary.SyncObjectCard {
+		active = "o.deleted_at IS NULL"
+	}
+	query := fmt.Sprintf(`
+		SELECT DISTINCT l.object_id
+		FROM deletions_log l
+		WHERE l.user_id = $1 AND l.object_type = $2 AND l.usn >= $3
+		  AND NOT EXISTS (SELECT 1 FROM %s o WHERE o.id = l.object_id AND %s)
+		ORDER BY l.object_id
+	`, table, active)
+
+	ids, err := queryInt64s(ctx, r.db, query, userID, objectType, minUSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted %s IDs: %w", objectType, err)
+	}
+	return ids, nil
+}
+
+// FindMediaChanges finds the media filenames changed with a USN after afterUSN and at most maxUSN, in USN order
+// A filename deleted several times is found once, with its last deletion
+func (r *AnkiSyncRepository) FindMediaChanges(ctx context.Context, userID int64, afterUSN int, maxUSN int, limit int) ([]*secondary.AnkiMediaChange, error) {
+	query := `
+		WITH changes AS (
+			(
+				SELECT filename, usn, hash
+				FROM media
+				WHERE user_id = $1 AND deleted_at IS NULL AND usn > $2 AND usn <= $3
+			)
+			UNION ALL
+			(
+				SELECT DISTINCT ON (dl.object_data->>'filename') dl.object_data->>'filename', dl.usn, ''
+				FROM deletions_log dl
+				WHERE dl.user_id = $1 AND dl.object_type = 'media' AND dl.usn > $2 AND dl.usn <= $3
+				  AND NOT EXISTS (
+					SELECT 1 FROM media m
+					WHERE m.user_id = dl.user_id AND m.filename = dl.object_data->>'filename' AND m.deleted_at IS NULL
+				  )
+				ORDER BY dl.object_data->>'filename', dl.usn DESC
+			)
+		)
+		SELECT filename, usn, hash
+		FROM changes
+		WHERE usn <= COALESCE((SELECT usn FROM changes ORDER BY usn OFFSET $4 - 1 LIMIT 1), $3)
+		ORDER BY usn, filename
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, afterUSN, maxUSN, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find media changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*secondary.AnkiMediaChange{}
+	for rows.Next() {
+		var change secondary.AnkiMediaChange
+		if err := rows.Scan(&change.Filename, &change.USN, &change.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan media change: %w", err)
+		}
+		changes = append(changes, &change)
+	}
+	return changes, rows.Err()
+}
+
+// FindCards finds cards by ID, validating ownership via deck
+func (r *AnkiSyncRepository) FindCards(ctx context.Context, userID int64, ids []int64) ([]*card.Card, error) {
+	if len(ids) == 0 {
+		return []*card.Card{}, nil
+	}
+
+	query := `
+		SELECT c.id, c.note_id, c.card_type_id, c.deck_id, c.home_deck_id, c.due, c.interval,
+			c.ease, c.lapses, c.reps, c.state, c.position, c.flag, c.suspended, c.buried,
+			c.stability, c.difficulty, c.last_review_at, c.created_at, c.updated_at
+		FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE c.id = ANY($1) AND d.user_id = $2
+		ORDER BY c.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := []*card.Card{}
+	for rows.Next() {
+		var model models.CardModel
+		err := rows.Scan(
+			&model.ID,
+			&model.NoteID,
+			&model.CardTypeID,
+			&model.DeckID,
+			&model.HomeDeckID,
+			&model.Due,
+			&model.Interval,
+			&model.Ease,
+			&model.Lapses,
+			&model.Reps,
+			&model.State,
+			&model.Position,
+			&model.Flag,
+			&model.Suspended,
+			&model.Buried,
+			&model.Stability,
+			&model.Difficulty,
+			&model.LastReviewAt,
+			&model.CreatedAt,
+			&model.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan card: %w", err)
+		}
+
+		cardEntity, err := mappers.CardToDomain(&model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert card to domain: %w", err)
+		}
+		cards = append(cards, cardEntity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cards: %w", err)
+	}
+	return cards, nil
+}
+
+// FindReviews finds reviews by ID, validating ownership via card -> deck
+func (r *AnkiSyncRepository) FindReviews(ctx context.Context, userID int64, ids []int64) ([]*review.Review, error) {
+	if len(ids) == 0 {
+		return []*review.Review{}, nil
+	}
+
+	query := `
+		SELECT r.id, r.card_id, r.rating, r.interval, r.ease, r.time_ms, r.type, r.created_at
+		FROM reviews r
+		INNER JOIN cards c ON r.card_id = c.id
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE r.id = ANY($1) AND d.user_id = $2
+		ORDER BY r.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []*review.Review{}
+	for rows.Next() {
+		var model models.ReviewModel
+		err := rows.Scan(
+			&model.ID,
+			&model.CardID,
+			&model.Rating,
+			&model.Interval,
+			&model.Ease,
+			&model.TimeMs,
+			&model.Type,
+			&model.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+
+		reviewEntity, err := mappers.ReviewToDomain(&model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert review to domain: %w", err)
+		}
+		reviews = append(reviews, reviewEntity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// Count counts the active objects of a user's collection
+// Cards and reviews are counted like FindChangedIDs finds them
+func (r *AnkiSyncRepository) Count(ctx context.Context, userID int64) (*secondary.AnkiSyncCounts, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM cards c
+			 INNER JOIN decks d ON c.deck_id = d.id
+			 INNER JOIN notes n ON c.note_id = n.id
+			 WHERE d.user_id = $1 AND d.deleted_at IS NULL AND n.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM notes WHERE user_id = $1 AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM reviews r
+			 INNER JOIN cards c ON r.card_id = c.id
+			 INNER JOIN decks d ON c.deck_id = d.id
+			 WHERE d.user_id = $1 AND d.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM note_types WHERE user_id = $1 AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM decks WHERE user_id = $1 AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM filtered_decks WHERE user_id = $1 AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM deck_options_presets WHERE user_id = $1 AND deleted_at IS NULL)
+	`
+
+	var counts secondary.AnkiSyncCounts
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&counts.Cards,
+		&counts.Notes,
+		&counts.Reviews,
+		&counts.NoteTypes,
+		&counts.Decks,
+		&counts.FilteredDecks,
+		&counts.Presets,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count collection: %w", err)
+	}
+	return &counts, nil
+}
+
+// DeleteCollection deletes every note, card, deck, note type, preset, filtered deck and review of a user
+func (r *AnkiSyncRepository) DeleteCollection(ctx context.Context, userID int64) error {
+	// Reviews go with their cards; the deletion log written by the card trigger is cleared last
+	statements := []string{
+		`DELETE FROM cards c USING decks d WHERE c.deck_id = d.id AND d.user_id = $1`,
+		`DELETE FROM notes WHERE user_id = $1`,
+		`DELETE FROM filtered_decks WHERE user_id = $1`,
+		`DELETE FROM decks WHERE user_id = $1`,
+		`DELETE FROM note_types WHERE user_id = $1`,
+		`DELETE FROM deck_options_presets WHERE user_id = $1`,
+		`DELETE FROM deletions_log WHERE user_id = $1`,
+		`DELETE FROM anki_sync_ids WHERE user_id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := r.db.ExecContext(ctx, statement, userID); err != nil {
+			return fmt.Errorf("failed to delete collection: %w", err)
+		}
+	}
+	return nil
+}
+
+// queryIDs runs a query selecting object_type, object_id, anki_id and anki_guid
+func (r *AnkiSyncRepository) queryIDs(ctx context.Context, query string, args ...interface{}) ([]*secondary.AnkiSyncID, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []*secondary.AnkiSyncID{}
+	for rows.Next() {
+		var id secondary.AnkiSyncID
+		if err := rows.Scan(&id.ObjectType, &id.ObjectID, &id.AnkiID, &id.AnkiGUID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, &id)
+	}
+	return ids, rows.Err()
+}
+
+// queryInt64s runs a query selecting a single BIGINT column
+func queryInt64s(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Ensure AnkiSyncRepository implements IAnkiSyncRepository
+var _ secondary.IAnkiSyncRepository = (*AnkiSyncRepository)(nil)
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -55,9 +54,10 @@ func (r *CardRepository) Save(ctx context.Context, userID int64, cardEntity *car
 			INSERT INTO cards (
 				note_id, card_type_id, deck_id, home_deck_id, due, interval, ease, lapses, reps,
 				state, position, flag, suspended, buried, stability, difficulty, last_review_at,
-				created_at, updated_at
+				created_at, updated_at, buried_at
 			)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+				CASE WHEN $14::boolean THEN $19::timestamptz END)
 			RETURNING id
 		`
 
@@ -134,7 +134,8 @@ func (r *CardRepository) Save(ctx context.Context, userID int64, cardEntity *car
 		SET note_id = $1, card_type_id = $2, deck_id = $3, home_deck_id = $4, due = $5,
 			interval = $6, ease = $7, lapses = $8, reps = $9, state = $10, position = $11,
 			flag = $12, suspended = $13, buried = $14, stability = $15, difficulty = $16,
-			last_review_at = $17, updated_at = $18
+			last_review_at = $17, updated_at = $18,
+			buried_at = CASE WHEN $14::boolean THEN COALESCE(buried_at, $18::timestamptz) END
 		WHERE id = $19 AND EXISTS (
 			SELECT 1 FROM decks WHERE decks.id = cards.deck_id AND decks.user_id = $20 AND decks.deleted_at IS NULL
 		)
@@ -369,6 +370,88 @@ func (r *CardRepository) Delete(ctx context.Context, userID int64, id int64) err
 	return nil
 }
 
+// Restore inserts a deleted card again with its original ID, validating ownership via deck
+func (r *CardRepository) Restore(ctx context.Context, userID int64, cardEntity *card.Card) error {
+	model := mappers.CardToModel(cardEntity)
+
+	deckOwnershipQuery := `SELECT user_id FROM decks WHERE id = $1 AND deleted_at IS NULL`
+	var deckUserID int64
+	err := r.db.QueryRowContext(ctx, deckOwnershipQuery, model.DeckID).Scan(&deckUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ownership.ErrResourceNotFound
+		}
+		return fmt.Errorf("failed to validate deck ownership: %w", err)
+	}
+	if err := ownership.EnsureOwnership(userID, deckUserID); err != nil {
+		return ownership.ErrResourceNotFound
+	}
+
+	query := `
+		INSERT INTO cards (
+			id, note_id, card_type_id, deck_id, home_deck_id, due, interval, ease, lapses, reps,
+			state, position, flag, suspended, buried, stability, difficulty, last_review_at,
+			created_at, updated_at, buried_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+			CASE WHEN $15::boolean THEN $20::timestamptz END)
+	`
+
+	now := time.Now()
+	if model.CreatedAt.IsZero() {
+		model.CreatedAt = now
+	}
+	model.UpdatedAt = now
+
+	var homeDeckID interface{}
+	if model.HomeDeckID.Valid {
+		homeDeckID = model.HomeDeckID.Int64
+	}
+
+	var stability interface{}
+	if model.Stability.Valid {
+		stability = model.Stability.Float64
+	}
+
+	var difficulty interface{}
+	if model.Difficulty.Valid {
+		difficulty = model.Difficulty.Float64
+	}
+
+	var lastReviewAt interface{}
+	if model.LastReviewAt.Valid {
+		lastReviewAt = model.LastReviewAt.Time
+	}
+
+	_, err = r.db.ExecContext(ctx, query,
+		model.ID,
+		model.NoteID,
+		model.CardTypeID,
+		model.DeckID,
+		homeDeckID,
+		model.Due,
+		model.Interval,
+		model.Ease,
+		model.Lapses,
+		model.Reps,
+		model.State,
+		model.Position,
+		model.Flag,
+		model.Suspended,
+		model.Buried,
+		stability,
+		difficulty,
+		lastReviewAt,
+		model.CreatedAt,
+		model.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore card: %w", err)
+	}
+
+	return nil
+}
+
 // Exists checks if a card exists and belongs to a user's deck
 func (r *CardRepository) Exists(ctx context.Context, userID int64, id int64) (bool, error) {
 	query := `
@@ -742,172 +825,78 @@ func (r *CardRepository) DeleteByDeckRecursive(ctx context.Context, userID int64
 	return nil
 }
 
-// FindByAdvancedSearch finds cards matching advanced search criteria
-// Used for is:new, is:due, is:review, prop: filters
-func (r *CardRepository) FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery) ([]*card.Card, error) {
+// FindByAdvancedSearch finds one page of the cards matching a search, with the total number of matches
+// The query's expression tree is compiled into a single predicate over the card, its deck and its note
+func (r *CardRepository) FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery, page search.PageRequest) ([]*card.Card, search.PageInfo, error) {
 	if query == nil {
-		return []*card.Card{}, nil
+		return []*card.Card{}, search.PageInfo{}, nil
 	}
 
-	// Build dynamic SQL query
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	// Base condition: ownership via deck JOIN
-	conditions = append(conditions, "d.user_id = $1")
-	args = append(args, userID)
-	argIndex++
+	compiler := newSearchCompiler(userID, dayStartFinder(ctx, r.db, userID))
+	predicate, err := compiler.cardPredicate(query.Root)
+	if err != nil {
+		return nil, search.PageInfo{}, err
+	}
 
-	conditions = append(conditions, "d.deleted_at IS NULL")
+	from := fmt.Sprintf(`
+		FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		INNER JOIN notes n ON c.note_id = n.id
+		WHERE d.user_id = $1 AND d.deleted_at IS NULL AND n.deleted_at IS NULL AND %s`, predicate)
 
-	// Card state filters
-	for _, state := range query.States {
-		switch state {
-		case "new":
-			conditions = append(conditions, "c.state = 'new'")
-		case "review":
-			conditions = append(conditions, "c.state = 'review'")
-		case "learn":
-			conditions = append(conditions, "c.state IN ('learn', 'relearn')")
-		case "suspended":
-			conditions = append(conditions, "c.suspended = TRUE")
-		case "buried":
-			conditions = append(conditions, "c.buried = TRUE")
-		case "due":
-			// Cards that are due: due <= now and not suspended/buried
-			now := time.Now().Unix() * 1000 // Convert to milliseconds
-			conditions = append(conditions, fmt.Sprintf("c.due <= $%d AND c.suspended = FALSE AND c.buried = FALSE", argIndex))
-			args = append(args, now)
-			argIndex++
-		case "marked":
-			// Join with notes table to check marked
-			conditions = append(conditions, "EXISTS (SELECT 1 FROM notes n WHERE n.id = c.note_id AND n.marked = TRUE AND n.user_id = $1)")
-		}
+	var info search.PageInfo
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from, compiler.args...).Scan(&info.Total); err != nil {
+		return nil, search.PageInfo{}, fmt.Errorf("failed to count cards by advanced search: %w", err)
 	}
 
-	// Flag filters
-	if len(query.Flags) > 0 {
-		flagConditions := make([]string, len(query.Flags))
-		for i, flag := range query.Flags {
-			flagConditions[i] = fmt.Sprintf("c.flag = $%d", argIndex)
-			args = append(args, flag)
-			argIndex++
-		}
-		conditions = append(conditions, "("+strings.Join(flagConditions, " OR ")+")")
-	}
-
-	// Property filters
-	for _, propFilter := range query.PropertyFilters {
-		switch propFilter.Property {
-		case "ivl":
-			// Interval filter
-			val, err := strconv.Atoi(propFilter.Value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid interval value: %s", propFilter.Value)
-			}
-			conditions = append(conditions, fmt.Sprintf("c.interval %s $%d", propFilter.Operator, argIndex))
-			args = append(args, val)
-			argIndex++
-		case "due":
-			// Due date filter (relative days)
-			val, err := strconv.Atoi(propFilter.Value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid due value: %s", propFilter.Value)
-			}
-			// Calculate target timestamp (val days from now, in milliseconds)
-			now := time.Now()
-			targetTime := now.AddDate(0, 0, val)
-			targetTimestamp := targetTime.Unix() * 1000
-			conditions = append(conditions, fmt.Sprintf("c.due %s $%d", propFilter.Operator, argIndex))
-			args = append(args, targetTimestamp)
-			argIndex++
-		case "lapses":
-			// Lapses filter
-			val, err := strconv.Atoi(propFilter.Value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid lapses value: %s", propFilter.Value)
-			}
-			conditions = append(conditions, fmt.Sprintf("c.lapses %s $%d", propFilter.Operator, argIndex))
-			args = append(args, val)
-			argIndex++
-		case "reps":
-			// Reps filter
-			val, err := strconv.Atoi(propFilter.Value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid reps value: %s", propFilter.Value)
-			}
-			conditions = append(conditions, fmt.Sprintf("c.reps %s $%d", propFilter.Operator, argIndex))
-			args = append(args, val)
-			argIndex++
-		}
+	order, err := compiler.order(cardSortKeys, "c.id", page)
+	if err != nil {
+		return nil, search.PageInfo{}, err
 	}
 
-	// Build final query
-	baseQuery := `
+	queryStr := fmt.Sprintf(`
 		SELECT c.id, c.note_id, c.card_type_id, c.deck_id, c.home_deck_id, c.due, c.interval, c.ease, 
 		       c.lapses, c.reps, c.state, c.position, c.flag, c.suspended, c.buried, 
-		       c.stability, c.difficulty, c.last_review_at, c.created_at, c.updated_at
-		FROM cards c
-		INNER JOIN decks d ON c.deck_id = d.id
-	`
-
-	whereClause := strings.Join(conditions, " AND ")
-	queryStr := baseQuery + " WHERE " + whereClause + " ORDER BY c.created_at DESC"
-
-	rows, err := r.db.QueryContext(ctx, queryStr, args...)
+		       c.stability, c.difficulty, c.last_review_at, c.created_at, c.updated_at,
+		       %s
+		%s AND %s
+		ORDER BY %s
+		LIMIT %s%s
+	`, order.key, from, order.seek, order.orderBy, order.limit, order.offset)
+
+	rows, err := r.db.QueryContext(ctx, queryStr, compiler.args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find cards by advanced search: %w", err)
+		return nil, search.PageInfo{}, fmt.Errorf("failed to find cards by advanced search: %w", err)
 	}
 	defer rows.Close()
 
-	return r.scanCards(rows)
+	cards := make([]*card.Card, 0, page.Limit)
+	var lastKey string
+	for rows.Next() {
+		if len(cards) == page.Limit {
+			info.NextCursor = search.EncodeCursor(page.Sort, lastKey, cards[len(cards)-1].GetID())
+			break
+		}
+		cardEntity, err := r.scanCard(rows, &lastKey)
+		if err != nil {
+			return nil, search.PageInfo{}, err
+		}
+		cards = append(cards, cardEntity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, search.PageInfo{}, fmt.Errorf("error iterating cards: %w", err)
+	}
+
+	return cards, info, nil
 }
 
 // scanCards scans rows into card entities
 func (r *CardRepository) scanCards(rows *sql.Rows) ([]*card.Card, error) {
 	var cards []*card.Card
 	for rows.Next() {
-		var model models.CardModel
-		var homeDeckID sql.NullInt64
-		var stability sql.NullFloat64
-		var difficulty sql.NullFloat64
-		var lastReviewAt sql.NullTime
-
-		err := rows.Scan(
-			&model.ID,
-			&model.NoteID,
-			&model.CardTypeID,
-			&model.DeckID,
-			&homeDeckID,
-			&model.Due,
-			&model.Interval,
-			&model.Ease,
-			&model.Lapses,
-			&model.Reps,
-			&model.State,
-			&model.Position,
-			&model.Flag,
-			&model.Suspended,
-			&model.Buried,
-			&stability,
-			&difficulty,
-			&lastReviewAt,
-			&model.CreatedAt,
-			&model.UpdatedAt,
-		)
+		cardEntity, err := r.scanCard(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan card: %w", err)
-		}
-
-		model.HomeDeckID = homeDeckID
-		model.Stability = stability
-		model.Difficulty = difficulty
-		model.LastReviewAt = lastReviewAt
-
-		cardEntity, err := mappers.CardToDomain(&model)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert card to domain: %w", err)
+			return nil, err
 		}
 		cards = append(cards, cardEntity)
 	}
@@ -919,6 +908,53 @@ func (r *CardRepository) scanCards(rows *sql.Rows) ([]*card.Card, error) {
 	return cards, nil
 }
 
+// scanCard scans the current row into a card entity
+// extra receives the columns selected after the card columns
+func (r *CardRepository) scanCard(rows *sql.Rows, extra ...interface{}) (*card.Card, error) {
+	var model models.CardModel
+	var homeDeckID sql.NullInt64
+	var stability sql.NullFloat64
+	var difficulty sql.NullFloat64
+	var lastReviewAt sql.NullTime
+
+	dest := []interface{}{
+		&model.ID,
+		&model.NoteID,
+		&model.CardTypeID,
+		&model.DeckID,
+		&homeDeckID,
+		&model.Due,
+		&model.Interval,
+		&model.Ease,
+		&model.Lapses,
+		&model.Reps,
+		&model.State,
+		&model.Position,
+		&model.Flag,
+		&model.Suspended,
+		&model.Buried,
+		&stability,
+		&difficulty,
+		&lastReviewAt,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan card: %w", err)
+	}
+
+	model.HomeDeckID = homeDeckID
+	model.Stability = stability
+	model.Difficulty = difficulty
+	model.LastReviewAt = lastReviewAt
+
+	cardEntity, err := mappers.CardToDomain(&model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert card to domain: %w", err)
+	}
+	return cardEntity, nil
+}
+
 // FindAll finds cards for a user based on filters and pagination
 func (r *CardRepository) FindAll(ctx context.Context, userID int64, filters card.CardFilters) ([]*card.Card, int, error) {
 	// Build dynamic SQL query
@@ -1021,6 +1057,135 @@ func (r *CardRepository) FindAll(ctx context.Context, userID int64, filters card
 	return cards, total, nil
 }
 
+// FindQueueCards finds unsuspended, unburied cards for a study queue
+// Cards in filtered decks are left out. New cards are ordered by position, other cards by due
+func (r *CardRepository) FindQueueCards(ctx context.Context, userID int64, filters card.QueueFilters) ([]*card.Card, error) {
+	states := make([]string, len(filters.States))
+	for i, state := range filters.States {
+		states[i] = string(state)
+	}
+
+	conditions := []string{
+		"d.user_id = $1",
+		"d.deleted_at IS NULL",
+		"c.deck_id = ANY($2)",
+		"c.state = ANY($3::card_state[])",
+		"c.suspended = FALSE",
+		"c.buried = FALSE",
+		"NOT EXISTS (SELECT 1 FROM filtered_deck_cards f WHERE f.card_id = c.id)",
+	}
+	args := []interface{}{userID, pq.Array(filters.DeckIDs), pq.Array(states)}
+
+	if filters.DueBefore != nil {
+		args = append(args, *filters.DueBefore)
+		conditions = append(conditions, fmt.Sprintf("(c.state = 'new' OR c.due < $%d)", len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT c.id, c.note_id, c.card_type_id, c.deck_id, c.home_deck_id, c.due, c.interval,
+			c.ease, c.lapses, c.reps, c.state, c.position, c.flag, c.suspended, c.buried,
+			c.stability, c.difficulty, c.last_review_at, c.created_at, c.updated_at
+		FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE %s
+		ORDER BY CASE WHEN c.state = 'new' THEN c.position::BIGINT ELSE c.due END ASC, c.id ASC
+	`, strings.Join(conditions, " AND "))
+
+	if filters.Limit > 0 {
+		args = append(args, filters.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find queue cards: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanCards(rows)
+}
+
+// FindByFilteredDeckID finds the cards in a filtered deck, in the deck's order
+func (r *CardRepository) FindByFilteredDeckID(ctx context.Context, userID int64, filteredDeckID int64) ([]*card.Card, error) {
+	query := `
+		SELECT c.id, c.note_id, c.card_type_id, c.deck_id, c.home_deck_id, c.due, c.interval,
+			c.ease, c.lapses, c.reps, c.state, c.position, c.flag, c.suspended, c.buried,
+			c.stability, c.difficulty, c.last_review_at, c.created_at, c.updated_at
+		FROM cards c
+		INNER JOIN filtered_deck_cards f ON f.card_id = c.id
+		INNER JOIN filtered_decks fd ON f.filtered_deck_id = fd.id
+		WHERE fd.id = $1 AND fd.user_id = $2 AND fd.deleted_at IS NULL
+		ORDER BY f.position ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, filteredDeckID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cards by filtered deck ID: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanCards(rows)
+}
+
+// UnburyBeforeDayStart unburies the cards buried before the start of their owner's current study day
+// The bury time is the card's buried_at, and users without preferences roll over at 04:00 UTC
+// Each user's cards are unburied by a separate statement, so a failure for one user does not keep
+// the others' cards buried; the failures are returned together after every user was processed
+func (r *CardRepository) UnburyBeforeDayStart(ctx context.Context, now time.Time) (int64, error) {
+	usersQuery := `
+		SELECT DISTINCT d.user_id, ` + studyDayColumns + `
+		FROM cards c
+		INNER JOIN decks d ON d.id = c.deck_id
+		LEFT JOIN user_preferences up ON up.user_id = d.user_id
+		WHERE c.buried = TRUE
+	`
+
+	rows, err := r.db.QueryContext(ctx, usersQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find users with buried cards: %w", err)
+	}
+	dayStarts := make(map[int64]time.Time)
+	for rows.Next() {
+		var userID int64
+		var timezone sql.NullString
+		var rollover sql.NullInt64
+		if err := rows.Scan(&userID, &timezone, &rollover); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user with buried cards: %w", err)
+		}
+		dayStarts[userID] = dayStart(timezone, rollover, now)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to find users with buried cards: %w", err)
+	}
+
+	query := `
+		UPDATE cards c
+		SET buried = FALSE, buried_at = NULL, updated_at = $1
+		FROM decks d
+		WHERE c.deck_id = d.id AND d.user_id = $2 AND c.buried = TRUE AND c.buried_at < $3
+	`
+
+	var unburied int64
+	var errs []error
+	for userID, start := range dayStarts {
+		result, err := r.db.ExecContext(ctx, query, now, userID, start)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to unbury cards of user %d: %w", userID, err))
+			continue
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get rows affected: %w", err))
+			continue
+		}
+		unburied += count
+	}
+
+	return unburied, errors.Join(errs...)
+}
+
 // FindLeeches finds cards that are difficult to memorize (leeches)
 func (r *CardRepository) FindLeeches(ctx context.Context, userID int64, limit, offset int) ([]*card.Card, int, error) {
 	// First, count total leeches
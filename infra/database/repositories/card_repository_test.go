@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sameTime matches a time argument at the same instant, whatever its location
+type sameTime time.Time
+
+func (s sameTime) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && t.Equal(time.Time(s))
+}
+
+func TestCardRepository_UnburyBeforeDayStart(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewCardRepository(db)
+	now := time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT DISTINCT d.user_id, up.timezone, EXTRACT\(EPOCH FROM up.next_day_starts_at\)::integer`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "timezone", "rollover"}).
+			AddRow(int64(1), "America/Sao_Paulo", int64(4*60*60)).
+			AddRow(int64(2), nil, nil))
+	mock.MatchExpectationsInOrder(false)
+
+	// Sao Paulo (UTC-3) is still on the day that started at 04:00 local time yesterday
+	mock.ExpectExec(`UPDATE cards c\s+SET buried = FALSE, buried_at = NULL, updated_at = \$1`).
+		WithArgs(now, int64(1), sameTime(time.Date(2024, 3, 9, 7, 0, 0, 0, time.UTC))).
+		WillReturnError(errors.New("connection reset"))
+	// Users without preferences roll over at 04:00 UTC
+	mock.ExpectExec(`UPDATE cards c\s+SET buried = FALSE, buried_at = NULL, updated_at = \$1`).
+		WithArgs(now, int64(2), sameTime(time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC))).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	count, err := repo.UnburyBeforeDayStart(ctx, now)
+
+	// A failure for one user does not keep the other users' cards buried
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to unbury cards of user 1")
+	assert.Equal(t, int64(3), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+const (
+	// minValidDue is the smallest due timestamp (ms) of a scheduled card; smaller values are
+	// day numbers or second timestamps that were never converted
+	minValidDue int64 = 1_000_000_000_000
+	// maxValidDue is the last millisecond of year 9999
+	maxValidDue int64 = 253_402_300_799_999
+)
+
+// DatabaseCheckRepository implements IDatabaseCheckRepository using PostgreSQL
+type DatabaseCheckRepository struct {
+	db *sql.DB
+}
+
+// NewDatabaseCheckRepository creates a new DatabaseCheckRepository instance
+func NewDatabaseCheckRepository(db *sql.DB) secondary.IDatabaseCheckRepository {
+	return &DatabaseCheckRepository{
+		db: db,
+	}
+}
+
+// DeleteCardsOfDeletedNotes deletes cards whose note is deleted and returns their IDs
+func (r *DatabaseCheckRepository) DeleteCardsOfDeletedNotes(ctx context.Context, userID int64) ([]int64, error) {
+	query := `
+		DELETE FROM cards c
+		USING decks d, notes n
+		WHERE c.deck_id = d.id AND d.user_id = $1
+		  AND n.id = c.note_id AND n.deleted_at IS NOT NULL
+		RETURNING c.id
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete cards of deleted notes: %w", err)
+	}
+	return ids, nil
+}
+
+// MoveCardsFromDeletedDecks moves cards whose deck is deleted to targetDeckID and returns their IDs
+func (r *DatabaseCheckRepository) MoveCardsFromDeletedDecks(ctx context.Context, userID int64, targetDeckID int64) ([]int64, error) {
+	// A card in a filtered deck keeps its deck as home deck, so its home deck becomes the target deck;
+	// a card whose home deck is the target deck is back home once moved
+	query := `
+		UPDATE cards c
+		SET deck_id = $2,
+		    home_deck_id = CASE WHEN c.home_deck_id = c.deck_id THEN $2 WHEN c.home_deck_id = $2 THEN NULL ELSE c.home_deck_id END,
+		    updated_at = $3
+		FROM decks d
+		WHERE c.deck_id = d.id AND d.user_id = $1 AND d.deleted_at IS NOT NULL
+		RETURNING c.id
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID, targetDeckID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to move cards from deleted decks: %w", err)
+	}
+	return ids, nil
+}
+
+// ReturnCardsWithMissingHomeDeck moves filtered cards whose home deck is deleted to targetDeckID and returns their IDs
+func (r *DatabaseCheckRepository) ReturnCardsWithMissingHomeDeck(ctx context.Context, userID int64, targetDeckID int64) ([]int64, error) {
+	query := `
+		UPDATE cards c
+		SET deck_id = $2, home_deck_id = NULL, updated_at = $3
+		FROM decks d, decks h
+		WHERE c.deck_id = d.id AND d.user_id = $1
+		  AND h.id = c.home_deck_id AND h.deleted_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM filtered_deck_cards f WHERE f.card_id = c.id)
+		RETURNING c.id
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID, targetDeckID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to return cards with missing home deck: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteCardsWithInvalidOrdinal deletes the cards of a note type's notes whose card type no longer exists
+func (r *DatabaseCheckRepository) DeleteCardsWithInvalidOrdinal(ctx context.Context, userID int64, noteTypeID int64, cardTypeCount int) ([]int64, error) {
+	query := `
+		DELETE FROM cards c
+		USING decks d, notes n
+		WHERE c.deck_id = d.id AND d.user_id = $1
+		  AND n.id = c.note_id AND n.note_type_id = $2
+		  AND (c.card_type_id < 0 OR c.card_type_id >= $3)
+		RETURNING c.id
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID, noteTypeID, cardTypeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete cards with invalid card type: %w", err)
+	}
+	return ids, nil
+}
+
+// FixInvalidScheduling resets due and interval values that are invalid for the card's state and returns the card IDs
+func (r *DatabaseCheckRepository) FixInvalidScheduling(ctx context.Context, userID int64, now time.Time) ([]int64, error) {
+	query := `
+		UPDATE cards c
+		SET interval = CASE
+		        WHEN c.state = 'new' THEN 0
+		        WHEN c.state = 'review' AND c.interval < 1 THEN 1
+		        ELSE c.interval
+		    END,
+		    due = CASE
+		        WHEN c.state <> 'new' AND (c.due < $3 OR c.due > $4) THEN $2
+		        ELSE c.due
+		    END,
+		    updated_at = $5
+		FROM decks d
+		WHERE c.deck_id = d.id AND d.user_id = $1
+		  AND (
+		      (c.state = 'new' AND c.interval <> 0)
+		      OR (c.state = 'review' AND c.interval < 1)
+		      OR (c.state <> 'new' AND (c.due < $3 OR c.due > $4))
+		  )
+		RETURNING c.id
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID, now.UnixMilli(), minValidDue, maxValidDue, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fix invalid card scheduling: %w", err)
+	}
+	return ids, nil
+}
+
+// FindDuplicateGUIDNotes returns the IDs of active notes sharing their GUID with an older active note
+func (r *DatabaseCheckRepository) FindDuplicateGUIDNotes(ctx context.Context, userID int64) ([]int64, error) {
+	query := `
+		SELECT id
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY guid ORDER BY created_at, id) AS rn
+			FROM notes
+			WHERE user_id = $1 AND deleted_at IS NULL
+		) ranked
+		WHERE rn > 1
+		ORDER BY id
+	`
+
+	ids, err := r.queryIDs(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate note GUIDs: %w", err)
+	}
+	return ids, nil
+}
+
+// queryIDs runs a query returning a single ID column
+func (r *DatabaseCheckRepository) queryIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Ensure DatabaseCheckRepository implements IDatabaseCheckRepository
+var _ secondary.IDatabaseCheckRepository = (*DatabaseCheckRepository)(nil)
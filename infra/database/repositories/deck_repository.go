@@ -343,6 +343,31 @@ func (r *DeckRepository) Delete(ctx context.Context, userID int64, deckID int64)
 	return nil
 }
 
+// Restore restores a single soft-deleted deck, validating ownership
+func (r *DeckRepository) Restore(ctx context.Context, userID int64, deckID int64) error {
+	query := `
+		UPDATE decks
+		SET deleted_at = NULL, updated_at = $1
+		WHERE id = $2 AND user_id = $3 AND deleted_at IS NOT NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), deckID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ownership.ErrResourceNotFound
+	}
+
+	return nil
+}
+
 // Exists checks if a deck with the given name exists for the user at the specified parent level
 func (r *DeckRepository) Exists(ctx context.Context, userID int64, name string, parentID *int64) (bool, error) {
 	var query string
@@ -423,3 +448,49 @@ func (r *DeckRepository) GetStats(ctx context.Context, userID int64, deckID int6
 
 // Ensure DeckRepository implements IDeckRepository
 var _ secondary.IDeckRepository = (*DeckRepository)(nil)
+
+// ExtendLimits adds cards to a deck's daily limits for the study day starting at dayStart
+func (r *DeckRepository) ExtendLimits(ctx context.Context, userID int64, deckID int64, dayStart time.Time, newCards int, reviews int) (*deck.LimitExtension, error) {
+	query := `
+		INSERT INTO deck_limit_extensions (deck_id, day_start, new_cards, reviews)
+		SELECT d.id, $3, $4, $5
+		FROM decks d
+		WHERE d.id = $1 AND d.user_id = $2 AND d.deleted_at IS NULL
+		ON CONFLICT (deck_id) DO UPDATE SET
+			new_cards = CASE WHEN deck_limit_extensions.day_start = EXCLUDED.day_start
+				THEN deck_limit_extensions.new_cards + EXCLUDED.new_cards ELSE EXCLUDED.new_cards END,
+			reviews = CASE WHEN deck_limit_extensions.day_start = EXCLUDED.day_start
+				THEN deck_limit_extensions.reviews + EXCLUDED.reviews ELSE EXCLUDED.reviews END,
+			day_start = EXCLUDED.day_start
+		RETURNING new_cards, reviews
+	`
+
+	var ext deck.LimitExtension
+	err := r.db.QueryRowContext(ctx, query, deckID, userID, dayStart, newCards, reviews).Scan(&ext.NewCards, &ext.Reviews)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ownership.ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to extend deck limits: %w", err)
+	}
+
+	return &ext, nil
+}
+
+// FindLimitExtension finds the extension of a deck's daily limits for the study day starting at dayStart
+func (r *DeckRepository) FindLimitExtension(ctx context.Context, userID int64, deckID int64, dayStart time.Time) (*deck.LimitExtension, error) {
+	query := `
+		SELECT e.new_cards, e.reviews
+		FROM deck_limit_extensions e
+		JOIN decks d ON d.id = e.deck_id
+		WHERE e.deck_id = $1 AND d.user_id = $2 AND e.day_start = $3
+	`
+
+	var ext deck.LimitExtension
+	err := r.db.QueryRowContext(ctx, query, deckID, userID, dayStart).Scan(&ext.NewCards, &ext.Reviews)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to find deck limit extension: %w", err)
+	}
+
+	return &ext, nil
+}
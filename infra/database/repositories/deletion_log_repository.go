@@ -272,7 +272,8 @@ func (r *DeletionLogRepository) FindByObjectType(ctx context.Context, userID int
 	return logs, nil
 }
 
-// FindRecent finds recent deletion logs for a user within a specified time period
+// FindRecent finds recent note deletion logs for a user within a specified time period
+// Only notes can be restored; the other entries are kept as deletion markers for sync
 func (r *DeletionLogRepository) FindRecent(ctx context.Context, userID int64, limit int, days int) ([]*deletionlog.DeletionLog, error) {
 	if limit <= 0 {
 		limit = 20 // Default limit
@@ -285,6 +286,7 @@ func (r *DeletionLogRepository) FindRecent(ctx context.Context, userID int64, li
 		SELECT id, user_id, object_type, object_id, object_data, deleted_at
 		FROM deletions_log
 		WHERE user_id = $1 
+		  AND object_type = 'note'
 		  AND deleted_at >= NOW() - INTERVAL '1 day' * $2
 		ORDER BY deleted_at DESC
 		LIMIT $3
@@ -326,6 +328,51 @@ func (r *DeletionLogRepository) FindRecent(ctx context.Context, userID int64, li
 	return deletionLogs, nil
 }
 
+// FindByMinUSN finds the deletion logs written with a USN of at least minUSN
+func (r *DeletionLogRepository) FindByMinUSN(ctx context.Context, userID int64, minUSN int) ([]*deletionlog.DeletionLog, error) {
+	query := `
+		SELECT id, user_id, object_type, object_id, object_data, deleted_at
+		FROM deletions_log
+		WHERE user_id = $1 AND usn >= $2
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, minUSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deletion logs by USN: %w", err)
+	}
+	defer rows.Close()
+
+	deletionLogs := make([]*deletionlog.DeletionLog, 0)
+	for rows.Next() {
+		var model models.DeletionLogModel
+
+		err := rows.Scan(
+			&model.ID,
+			&model.UserID,
+			&model.ObjectType,
+			&model.ObjectID,
+			&model.ObjectData,
+			&model.DeletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deletion log: %w", err)
+		}
+
+		deletionLogEntity, err := mappers.DeletionLogToDomain(&model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert deletion log to domain: %w", err)
+		}
+		deletionLogs = append(deletionLogs, deletionLogEntity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deletion logs: %w", err)
+	}
+
+	return deletionLogs, nil
+}
+
 // Ensure DeletionLogRepository implements IDeletionLogRepository
 var _ secondary.IDeletionLogRepository = (*DeletionLogRepository)(nil)
 
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 	"github.com/felipesantos/anki-backend/infra/database/mappers"
 	"github.com/felipesantos/anki-backend/infra/database/models"
@@ -33,8 +34,8 @@ func (r *FilteredDeckRepository) Save(ctx context.Context, userID int64, filtere
 	if filteredDeckEntity.GetID() == 0 {
 		// Insert new filtered deck
 		query := `
-			INSERT INTO filtered_decks (user_id, name, search_filter, second_filter, limit_cards, order_by, reschedule, created_at, updated_at, last_rebuild_at, deleted_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			INSERT INTO filtered_decks (user_id, name, search_filter, second_filter, limit_cards, second_limit_cards, order_by, reschedule, created_at, updated_at, last_rebuild_at, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 			RETURNING id
 		`
 
@@ -68,6 +69,7 @@ func (r *FilteredDeckRepository) Save(ctx context.Context, userID int64, filtere
 			model.SearchFilter,
 			secondFilter,
 			model.LimitCards,
+			model.SecondLimitCards,
 			model.OrderBy,
 			model.Reschedule,
 			model.CreatedAt,
@@ -95,8 +97,8 @@ func (r *FilteredDeckRepository) Save(ctx context.Context, userID int64, filtere
 	// Update filtered deck
 	query := `
 		UPDATE filtered_decks
-		SET name = $1, search_filter = $2, second_filter = $3, limit_cards = $4, order_by = $5, reschedule = $6, updated_at = $7, last_rebuild_at = $8, deleted_at = $9
-		WHERE id = $10 AND user_id = $11 AND deleted_at IS NULL
+		SET name = $1, search_filter = $2, second_filter = $3, limit_cards = $4, second_limit_cards = $5, order_by = $6, reschedule = $7, updated_at = $8, last_rebuild_at = $9, deleted_at = $10
+		WHERE id = $11 AND user_id = $12 AND deleted_at IS NULL
 	`
 
 	now := time.Now()
@@ -122,6 +124,7 @@ func (r *FilteredDeckRepository) Save(ctx context.Context, userID int64, filtere
 		model.SearchFilter,
 		secondFilter,
 		model.LimitCards,
+		model.SecondLimitCards,
 		model.OrderBy,
 		model.Reschedule,
 		model.UpdatedAt,
@@ -150,7 +153,7 @@ func (r *FilteredDeckRepository) Save(ctx context.Context, userID int64, filtere
 // FindByID finds a filtered deck by ID, filtering by userID to ensure ownership
 func (r *FilteredDeckRepository) FindByID(ctx context.Context, userID int64, id int64) (*filtereddeck.FilteredDeck, error) {
 	query := `
-		SELECT id, user_id, name, search_filter, second_filter, limit_cards, order_by, reschedule, created_at, updated_at, last_rebuild_at, deleted_at
+		SELECT id, user_id, name, search_filter, second_filter, limit_cards, second_limit_cards, order_by, reschedule, created_at, updated_at, last_rebuild_at, deleted_at
 		FROM filtered_decks
 		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 	`
@@ -167,6 +170,7 @@ func (r *FilteredDeckRepository) FindByID(ctx context.Context, userID int64, id
 		&model.SearchFilter,
 		&secondFilter,
 		&model.LimitCards,
+		&model.SecondLimitCards,
 		&model.OrderBy,
 		&model.Reschedule,
 		&model.CreatedAt,
@@ -199,7 +203,7 @@ func (r *FilteredDeckRepository) FindByID(ctx context.Context, userID int64, id
 // FindByUserID finds all filtered decks for a user
 func (r *FilteredDeckRepository) FindByUserID(ctx context.Context, userID int64) ([]*filtereddeck.FilteredDeck, error) {
 	query := `
-		SELECT id, user_id, name, search_filter, second_filter, limit_cards, order_by, reschedule, created_at, updated_at, last_rebuild_at, deleted_at
+		SELECT id, user_id, name, search_filter, second_filter, limit_cards, second_limit_cards, order_by, reschedule, created_at, updated_at, last_rebuild_at, deleted_at
 		FROM filtered_decks
 		WHERE user_id = $1 AND deleted_at IS NULL
 		ORDER BY name ASC
@@ -225,6 +229,7 @@ func (r *FilteredDeckRepository) FindByUserID(ctx context.Context, userID int64)
 			&model.SearchFilter,
 			&secondFilter,
 			&model.LimitCards,
+			&model.SecondLimitCards,
 			&model.OrderBy,
 			&model.Reschedule,
 			&model.CreatedAt,
@@ -315,6 +320,213 @@ func (r *FilteredDeckRepository) Exists(ctx context.Context, userID int64, id in
 	return exists, nil
 }
 
+// FindByCardID finds the filtered deck holding a card, or nil if the card is in no filtered deck
+func (r *FilteredDeckRepository) FindByCardID(ctx context.Context, userID int64, cardID int64) (*filtereddeck.FilteredDeck, error) {
+	query := `
+		SELECT fd.id, fd.user_id, fd.name, fd.search_filter, fd.second_filter, fd.limit_cards, fd.second_limit_cards, fd.order_by, fd.reschedule,
+			fd.created_at, fd.updated_at, fd.last_rebuild_at, fd.deleted_at
+		FROM filtered_decks fd
+		INNER JOIN filtered_deck_cards f ON f.filtered_deck_id = fd.id
+		WHERE f.card_id = $1 AND fd.user_id = $2 AND fd.deleted_at IS NULL
+	`
+
+	var model models.FilteredDeckModel
+	err := r.db.QueryRowContext(ctx, query, cardID, userID).Scan(
+		&model.ID,
+		&model.UserID,
+		&model.Name,
+		&model.SearchFilter,
+		&model.SecondFilter,
+		&model.LimitCards,
+		&model.SecondLimitCards,
+		&model.OrderBy,
+		&model.Reschedule,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+		&model.LastRebuildAt,
+		&model.DeletedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find filtered deck by card ID: %w", err)
+	}
+
+	return mappers.FilteredDeckToDomain(&model)
+}
+
+// MoveCardsIn moves up to limit cards matching query into a filtered deck, after the cards already in it
+// The cards are numbered in the deck's order, so a random order stays the same until the deck is rebuilt
+func (r *FilteredDeckRepository) MoveCardsIn(ctx context.Context, userID int64, id int64, query *search.SearchQuery, orderBy string, limit int) (int, error) {
+	if query == nil || limit <= 0 {
+		return 0, nil
+	}
+
+	compiler := newSearchCompiler(userID, dayStartFinder(ctx, r.db, userID))
+	predicate, err := compiler.cardPredicate(query.Root)
+	if err != nil {
+		return 0, err
+	}
+	deckArg := compiler.bind(id)
+	limitArg := compiler.bind(limit)
+	nowArg := compiler.bind(time.Now())
+	order := filteredDeckOrder(orderBy, compiler.bind(time.Now().UnixMilli()))
+
+	stmt := fmt.Sprintf(`
+		WITH matched AS (
+			SELECT c.id, ROW_NUMBER() OVER (ORDER BY %[1]s, c.id) AS rn
+			FROM cards c
+			INNER JOIN decks d ON c.deck_id = d.id
+			INNER JOIN notes n ON c.note_id = n.id
+			WHERE d.user_id = $1 AND d.deleted_at IS NULL AND n.deleted_at IS NULL
+			  AND c.suspended = FALSE AND c.buried = FALSE
+			  AND NOT EXISTS (SELECT 1 FROM filtered_deck_cards f WHERE f.card_id = c.id)
+			  AND EXISTS (SELECT 1 FROM filtered_decks fd WHERE fd.id = %[2]s AND fd.user_id = $1 AND fd.deleted_at IS NULL)
+			  AND %[3]s
+		), last AS (
+			SELECT COALESCE(MAX(position), 0) AS position FROM filtered_deck_cards WHERE filtered_deck_id = %[2]s
+		), moved AS (
+			INSERT INTO filtered_deck_cards (card_id, filtered_deck_id, position)
+			SELECT m.id, %[2]s, last.position + m.rn FROM matched m, last
+			WHERE m.rn <= %[4]s
+			RETURNING card_id
+		)
+		UPDATE cards c
+		SET home_deck_id = c.deck_id, updated_at = %[5]s
+		FROM moved
+		WHERE c.id = moved.card_id
+	`, order, deckArg, predicate, limitArg, nowArg)
+
+	result, err := r.db.ExecContext(ctx, stmt, compiler.args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move cards into filtered deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// filteredDeckOrder returns the ORDER BY expression of a filtered deck order over cards c and notes n
+// now is the placeholder of the current time in milliseconds; unknown orders sort by due
+func filteredDeckOrder(orderBy string, now string) string {
+	switch orderBy {
+	case filtereddeck.OrderOldestSeen:
+		return "c.last_review_at ASC NULLS FIRST"
+	case filtereddeck.OrderRandom:
+		return "random()"
+	case filtereddeck.OrderIntervalAsc:
+		return "c.interval ASC"
+	case filtereddeck.OrderIntervalDesc:
+		return "c.interval DESC"
+	case filtereddeck.OrderLapses:
+		return "c.lapses DESC"
+	case filtereddeck.OrderAdded:
+		return "n.created_at ASC, c.card_type_id ASC"
+	case filtereddeck.OrderAddedDesc:
+		return "n.created_at DESC, c.card_type_id ASC"
+	case filtereddeck.OrderRelativeOverdueness:
+		// Days overdue divided by the interval; cards that are not in review come last
+		return fmt.Sprintf(`CASE WHEN c.state = 'review' AND c.interval > 0
+			THEN (%s - c.due)::FLOAT8 / (c.interval * 86400000.0) END DESC NULLS LAST, c.due ASC`, now)
+	default:
+		return "c.state = 'new' ASC, CASE WHEN c.state = 'new' THEN c.position::BIGINT ELSE c.due END ASC"
+	}
+}
+
+// ReturnCards returns every card of a filtered deck to its home deck
+func (r *FilteredDeckRepository) ReturnCards(ctx context.Context, userID int64, id int64) (int, error) {
+	query := `
+		WITH returned AS (
+			DELETE FROM filtered_deck_cards f
+			USING filtered_decks fd
+			WHERE f.filtered_deck_id = fd.id AND fd.id = $1 AND fd.user_id = $2
+			RETURNING f.card_id
+		)
+		UPDATE cards c
+		SET home_deck_id = NULL, updated_at = $3
+		FROM returned
+		WHERE c.id = returned.card_id
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to return filtered deck cards: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// ReturnCard returns a card to its home deck from the filtered deck holding it
+// Returns the card's position in the filtered deck, so that the return can be undone with RestoreCard
+func (r *FilteredDeckRepository) ReturnCard(ctx context.Context, userID int64, cardID int64) (int, error) {
+	query := `
+		WITH returned AS (
+			DELETE FROM filtered_deck_cards f
+			USING filtered_decks fd
+			WHERE f.filtered_deck_id = fd.id AND f.card_id = $1 AND fd.user_id = $2
+			RETURNING f.card_id, f.position
+		)
+		UPDATE cards c
+		SET home_deck_id = NULL, updated_at = $3
+		FROM returned
+		WHERE c.id = returned.card_id
+		RETURNING returned.position
+	`
+
+	var position int
+	err := r.db.QueryRowContext(ctx, query, cardID, userID, time.Now()).Scan(&position)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to return card from filtered deck: %w", err)
+	}
+
+	return position, nil
+}
+
+// RestoreCard puts a card returned to its home deck back into a filtered deck at a position
+func (r *FilteredDeckRepository) RestoreCard(ctx context.Context, userID int64, id int64, cardID int64, position int) error {
+	query := `
+		WITH restored AS (
+			INSERT INTO filtered_deck_cards (card_id, filtered_deck_id, position)
+			SELECT c.id, fd.id, $3
+			FROM filtered_decks fd, cards c
+			INNER JOIN decks d ON d.id = c.deck_id
+			WHERE fd.id = $1 AND fd.user_id = $4 AND fd.deleted_at IS NULL
+			  AND c.id = $2 AND d.user_id = $4
+			ON CONFLICT (card_id) DO NOTHING
+			RETURNING card_id
+		)
+		UPDATE cards c
+		SET home_deck_id = c.deck_id, updated_at = $5
+		FROM restored
+		WHERE c.id = restored.card_id
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, cardID, position, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to restore card to filtered deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ownership.ErrResourceNotFound
+	}
+
+	return nil
+}
+
 // Ensure FilteredDeckRepository implements IFilteredDeckRepository
 var _ secondary.IFilteredDeckRepository = (*FilteredDeckRepository)(nil)
 
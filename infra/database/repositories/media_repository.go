@@ -35,6 +35,7 @@ func (r *MediaRepository) Save(ctx context.Context, userID int64, mediaEntity *m
 		query := `
 			INSERT INTO media (user_id, filename, hash, size, mime_type, storage_path, created_at, deleted_at)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (user_id, hash) WHERE deleted_at IS NULL DO NOTHING
 			RETURNING id
 		`
 
@@ -59,6 +60,10 @@ func (r *MediaRepository) Save(ctx context.Context, userID int64, mediaEntity *m
 			model.CreatedAt,
 			deletedAt,
 		).Scan(&mediaID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Another request recorded the same content first
+			return media.ErrDuplicateContent
+		}
 		if err != nil {
 			return fmt.Errorf("failed to create media: %w", err)
 		}
@@ -353,6 +358,51 @@ func (r *MediaRepository) FindByFilename(ctx context.Context, userID int64, file
 	return mappers.MediaToDomain(&model)
 }
 
+// TotalSize returns the total size in bytes of a user's active media
+func (r *MediaRepository) TotalSize(ctx context.Context, userID int64) (int64, error) {
+	query := `
+		SELECT COALESCE(SUM(size), 0)
+		FROM media
+		WHERE user_id = $1 AND deleted_at IS NULL
+	`
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to sum media size: %w", err)
+	}
+	return total, nil
+}
+
+// FindLinkedIDs returns the IDs of a user's media linked to active notes through note_media
+func (r *MediaRepository) FindLinkedIDs(ctx context.Context, userID int64) ([]int64, error) {
+	query := `
+		SELECT DISTINCT nm.media_id
+		FROM note_media nm
+		INNER JOIN media m ON m.id = nm.media_id
+		INNER JOIN notes n ON n.id = nm.note_id
+		WHERE m.user_id = $1 AND m.deleted_at IS NULL AND n.deleted_at IS NULL
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find linked media: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan linked media: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating linked media: %w", err)
+	}
+	return ids, nil
+}
+
 // Ensure MediaRepository implements IMediaRepository
 var _ secondary.IMediaRepository = (*MediaRepository)(nil)
 
@@ -6,8 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -221,36 +219,9 @@ func (r *NoteRepository) FindByUserID(ctx context.Context, userID int64, limit i
 func (r *NoteRepository) scanNotes(rows *sql.Rows) ([]*note.Note, error) {
 	var notes []*note.Note
 	for rows.Next() {
-		var model models.NoteModel
-		var tagsStr string
-		var deletedAt sql.NullTime
-
-		err := rows.Scan(
-			&model.ID,
-			&model.UserID,
-			&model.GUID,
-			&model.NoteTypeID,
-			&model.FieldsJSON,
-			&tagsStr,
-			&model.Marked,
-			&model.CreatedAt,
-			&model.UpdatedAt,
-			&deletedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan note: %w", err)
-		}
-
-		if tagsStr != "" {
-			model.Tags = sql.NullString{String: tagsStr, Valid: true}
-		}
-		if deletedAt.Valid {
-			model.DeletedAt = deletedAt
-		}
-
-		noteEntity, err := mappers.NoteToDomain(&model)
+		noteEntity, err := r.scanNote(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert note to domain: %w", err)
+			return nil, err
 		}
 		notes = append(notes, noteEntity)
 	}
@@ -262,6 +233,43 @@ func (r *NoteRepository) scanNotes(rows *sql.Rows) ([]*note.Note, error) {
 	return notes, nil
 }
 
+// scanNote scans the current row into a note entity
+// extra receives the columns selected after the note columns
+func (r *NoteRepository) scanNote(rows *sql.Rows, extra ...interface{}) (*note.Note, error) {
+	var model models.NoteModel
+	var tagsStr string
+	var deletedAt sql.NullTime
+
+	dest := []interface{}{
+		&model.ID,
+		&model.UserID,
+		&model.GUID,
+		&model.NoteTypeID,
+		&model.FieldsJSON,
+		&tagsStr,
+		&model.Marked,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+		&deletedAt,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan note: %w", err)
+	}
+
+	if tagsStr != "" {
+		model.Tags = sql.NullString{String: tagsStr, Valid: true}
+	}
+	if deletedAt.Valid {
+		model.DeletedAt = deletedAt
+	}
+
+	noteEntity, err := mappers.NoteToDomain(&model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert note to domain: %w", err)
+	}
+	return noteEntity, nil
+}
+
 // Update updates an existing note, validating ownership
 func (r *NoteRepository) Update(ctx context.Context, userID int64, id int64, noteEntity *note.Note) error {
 	return r.Save(ctx, userID, noteEntity)
@@ -303,6 +311,31 @@ func (r *NoteRepository) Delete(ctx context.Context, userID int64, id int64) err
 	return nil
 }
 
+// Restore restores a soft-deleted note, validating ownership
+func (r *NoteRepository) Restore(ctx context.Context, userID int64, id int64) error {
+	query := `
+		UPDATE notes
+		SET deleted_at = NULL, updated_at = $1
+		WHERE id = $2 AND user_id = $3 AND deleted_at IS NOT NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ownership.ErrResourceNotFound
+	}
+
+	return nil
+}
+
 // Exists checks if a note exists and belongs to the user
 func (r *NoteRepository) Exists(ctx context.Context, userID int64, id int64) (bool, error) {
 	query := `
@@ -466,210 +499,68 @@ func (r *NoteRepository) FindBySearch(ctx context.Context, userID int64, searchT
 	return r.scanNotes(rows)
 }
 
-// FindByAdvancedSearch finds notes matching advanced search criteria
-// Combines multiple filters: deck, tags, fields, card states, properties
-func (r *NoteRepository) FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery, limit int, offset int) ([]*note.Note, error) {
+// FindByAdvancedSearch finds one page of the notes matching a search, with the total number of matches
+// The query's expression tree is compiled into a single predicate, so OR, grouping and negation are honored
+func (r *NoteRepository) FindByAdvancedSearch(ctx context.Context, userID int64, query *search.SearchQuery, page search.PageRequest) ([]*note.Note, search.PageInfo, error) {
 	if query == nil {
-		return []*note.Note{}, nil
+		return []*note.Note{}, search.PageInfo{}, nil
 	}
 
-	// Validate all regex patterns before building query
-	for _, textSearch := range query.TextSearches {
-		if textSearch.IsRegex {
-			if _, err := regexp.Compile(textSearch.Text); err != nil {
-				return nil, fmt.Errorf("invalid regex pattern '%s': %w", textSearch.Text, err)
-			}
-		}
+	compiler := newSearchCompiler(userID, dayStartFinder(ctx, r.db, userID))
+	predicate, err := compiler.notePredicate(query.Root)
+	if err != nil {
+		return nil, search.PageInfo{}, err
 	}
 
-	// Build dynamic SQL query
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	// Base conditions
-	conditions = append(conditions, fmt.Sprintf("n.user_id = $%d", argIndex))
-	args = append(args, userID)
-	argIndex++
-
-	conditions = append(conditions, "n.deleted_at IS NULL")
-
-	// Deck filters
-	if len(query.DecksInclude) > 0 {
-		// Multiple decks with OR logic
-		deckConditions := make([]string, len(query.DecksInclude))
-		for i, deckName := range query.DecksInclude {
-			deckConditions[i] = fmt.Sprintf("EXISTS (SELECT 1 FROM cards c JOIN decks d ON c.deck_id = d.id WHERE c.note_id = n.id AND d.user_id = $%d AND d.name = $%d)", argIndex, argIndex+1)
-			args = append(args, userID, deckName)
-			argIndex += 2
-		}
-		conditions = append(conditions, "("+strings.Join(deckConditions, " OR ")+")")
-	}
-	if len(query.DecksExclude) > 0 {
-		for _, deckName := range query.DecksExclude {
-			conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM cards c JOIN decks d ON c.deck_id = d.id WHERE c.note_id = n.id AND d.user_id = $%d AND d.name = $%d)", argIndex, argIndex+1))
-			args = append(args, userID, deckName)
-			argIndex += 2
-		}
-	}
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM notes n
+		WHERE n.user_id = $1 AND n.deleted_at IS NULL AND %s
+	`, predicate)
 
-	// Tag filters
-	if len(query.TagsInclude) > 0 {
-		conditions = append(conditions, fmt.Sprintf("n.tags && $%d::TEXT[]", argIndex))
-		args = append(args, pq.Array(query.TagsInclude))
-		argIndex++
-	}
-	if len(query.TagsExclude) > 0 {
-		for _, tag := range query.TagsExclude {
-			conditions = append(conditions, fmt.Sprintf("NOT ($%d::TEXT = ANY(n.tags))", argIndex))
-			args = append(args, tag)
-			argIndex++
-		}
+	var info search.PageInfo
+	if err := r.db.QueryRowContext(ctx, countQuery, compiler.args...).Scan(&info.Total); err != nil {
+		return nil, search.PageInfo{}, fmt.Errorf("failed to count notes by advanced search: %w", err)
 	}
 
-	// Field searches (front:, back:, field:name:)
-	for fieldName, searchText := range query.FieldSearches {
-		// Escape search text for ILIKE
-		escapedText := strings.ReplaceAll(searchText, "%", "\\%")
-		escapedText = strings.ReplaceAll(escapedText, "_", "\\_")
-		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE key = $%d AND value ILIKE $%d)", argIndex, argIndex+1))
-		args = append(args, fieldName, "%"+escapedText+"%")
-		argIndex += 2
+	order, err := compiler.order(noteSortKeys, "n.id", page)
+	if err != nil {
+		return nil, search.PageInfo{}, err
 	}
 
-	// Text searches (general text in all fields)
-	for _, textSearch := range query.TextSearches {
-		if textSearch.IsNegated {
-			continue // Handle negation separately if needed
-		}
+	queryStr := fmt.Sprintf(`
+		SELECT n.id, n.user_id, n.guid, n.note_type_id, n.fields_json, n.tags, n.marked, n.created_at, n.updated_at, n.deleted_at,
+		       %s
+		FROM notes n
+		WHERE n.user_id = $1 AND n.deleted_at IS NULL AND %s AND %s
+		ORDER BY %s
+		LIMIT %s%s
+	`, order.key, predicate, order.seek, order.orderBy, order.limit, order.offset)
 
-		// Handle field-specific searches (when textSearch.Field is set)
-		if textSearch.Field != "" {
-			if textSearch.IsExact {
-				// Exact phrase search in specific field
-				escapedText := strings.ReplaceAll(textSearch.Text, "%", "\\%")
-				escapedText = strings.ReplaceAll(escapedText, "_", "\\_")
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE LOWER(key) = LOWER($%d) AND unaccent(LOWER(value)) ILIKE LOWER($%d))", argIndex, argIndex+1))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE key = $%d AND value ILIKE $%d)", argIndex, argIndex+1))
-				}
-				args = append(args, textSearch.Field, "%"+escapedText+"%")
-				argIndex += 2
-			} else if textSearch.IsWildcard {
-				// Wildcard search in specific field
-				wildcardText := strings.ReplaceAll(textSearch.Text, "*", "%")
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE LOWER(key) = LOWER($%d) AND unaccent(LOWER(value)) ILIKE LOWER($%d))", argIndex, argIndex+1))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE key = $%d AND value ILIKE $%d)", argIndex, argIndex+1))
-				}
-				args = append(args, textSearch.Field, wildcardText)
-				argIndex += 2
-			} else if textSearch.IsRegex {
-				// Field-specific regex search
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE LOWER(key) = LOWER($%d) AND unaccent(LOWER(value)) ~ LOWER($%d))", argIndex, argIndex+1))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE LOWER(key) = LOWER($%d) AND value ~ $%d)", argIndex, argIndex+1))
-				}
-				args = append(args, textSearch.Field, textSearch.Text)
-				argIndex += 2
-			} else {
-				// Regular text search in specific field
-				escapedText := strings.ReplaceAll(textSearch.Text, "%", "\\%")
-				escapedText = strings.ReplaceAll(escapedText, "_", "\\_")
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE LOWER(key) = LOWER($%d) AND unaccent(LOWER(value)) ILIKE LOWER($%d))", argIndex, argIndex+1))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE key = $%d AND value ILIKE $%d)", argIndex, argIndex+1))
-				}
-				args = append(args, textSearch.Field, "%"+escapedText+"%")
-				argIndex += 2
-			}
-		} else {
-			// General text searches (all fields)
-			if textSearch.IsExact {
-				// Exact phrase search
-				escapedText := strings.ReplaceAll(textSearch.Text, "%", "\\%")
-				escapedText = strings.ReplaceAll(escapedText, "_", "\\_")
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE unaccent(LOWER(value)) ILIKE LOWER($%d))", argIndex))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE value ILIKE $%d)", argIndex))
-				}
-				args = append(args, "%"+escapedText+"%")
-				argIndex++
-			} else if textSearch.IsWildcard {
-				// Wildcard search - convert * to % and _ to _
-				wildcardText := strings.ReplaceAll(textSearch.Text, "*", "%")
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE unaccent(LOWER(value)) ILIKE LOWER($%d))", argIndex))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE value ILIKE $%d)", argIndex))
-				}
-				args = append(args, wildcardText)
-				argIndex++
-			} else if textSearch.IsRegex {
-				// General regex search (all fields)
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE unaccent(LOWER(value)) ~ LOWER($%d))", argIndex))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE value ~ $%d)", argIndex))
-				}
-				args = append(args, textSearch.Text)
-				argIndex++
-			} else {
-				// Regular text search
-				escapedText := strings.ReplaceAll(textSearch.Text, "%", "\\%")
-				escapedText = strings.ReplaceAll(escapedText, "_", "\\_")
-				if textSearch.IsNoCombining {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE unaccent(LOWER(value)) ILIKE LOWER($%d))", argIndex))
-				} else {
-					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE value ILIKE $%d)", argIndex))
-				}
-				args = append(args, "%"+escapedText+"%")
-				argIndex++
-			}
-		}
+	rows, err := r.db.QueryContext(ctx, queryStr, compiler.args...)
+	if err != nil {
+		return nil, search.PageInfo{}, fmt.Errorf("failed to find notes by advanced search: %w", err)
 	}
+	defer rows.Close()
 
-	// State filters (is:marked) - need to check note.marked
-	for _, state := range query.States {
-		if state == "marked" {
-			conditions = append(conditions, "n.marked = TRUE")
+	notes := make([]*note.Note, 0, page.Limit)
+	var lastKey string
+	for rows.Next() {
+		if len(notes) == page.Limit {
+			info.NextCursor = search.EncodeCursor(page.Sort, lastKey, notes[len(notes)-1].GetID())
+			break
 		}
+		noteEntity, err := r.scanNote(rows, &lastKey)
+		if err != nil {
+			return nil, search.PageInfo{}, err
+		}
+		notes = append(notes, noteEntity)
 	}
-
-	// Build final query
-	baseQuery := `
-		SELECT DISTINCT n.id, n.user_id, n.guid, n.note_type_id, n.fields_json, n.tags, n.marked, n.created_at, n.updated_at, n.deleted_at
-		FROM notes n
-	`
-	
-	// Add JOINs if needed for deck filters
-	needsCardJoin := len(query.DecksInclude) > 0 || len(query.DecksExclude) > 0
-	if needsCardJoin {
-		baseQuery = `
-			SELECT DISTINCT n.id, n.user_id, n.guid, n.note_type_id, n.fields_json, n.tags, n.marked, n.created_at, n.updated_at, n.deleted_at
-			FROM notes n
-		`
-	}
-
-	whereClause := strings.Join(conditions, " AND ")
-	queryStr := baseQuery + " WHERE " + whereClause + " ORDER BY n.created_at DESC"
-
-	// Add pagination
-	queryStr += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	args = append(args, limit, offset)
-
-	rows, err := r.db.QueryContext(ctx, queryStr, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find notes by advanced search: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, search.PageInfo{}, fmt.Errorf("error iterating notes: %w", err)
 	}
-	defer rows.Close()
 
-	return r.scanNotes(rows)
+	return notes, info, nil
 }
 
 // FindDuplicatesByField finds duplicate notes grouped by field value.
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
+
 	"github.com/felipesantos/anki-backend/core/domain/entities/review"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 	"github.com/felipesantos/anki-backend/infra/database/mappers"
@@ -321,6 +323,87 @@ func (r *ReviewRepository) FindByDateRange(ctx context.Context, userID int64, st
 	return reviews, nil
 }
 
+// FindHistory finds all reviews of a user's cards ordered by card and review time
+// If deckIDs is not empty, only reviews of cards in those decks are returned
+func (r *ReviewRepository) FindHistory(ctx context.Context, userID int64, deckIDs []int64) ([]*review.Review, error) {
+	query := `
+		SELECT r.id, r.card_id, r.rating, r.interval, r.ease, r.time_ms, r.type, r.created_at
+		FROM reviews r
+		INNER JOIN cards c ON r.card_id = c.id
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE d.user_id = $1 AND d.deleted_at IS NULL
+			AND (cardinality($2::bigint[]) = 0 OR c.deck_id = ANY($2))
+		ORDER BY r.card_id ASC, r.created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(deckIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find review history: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []*review.Review
+	for rows.Next() {
+		var model models.ReviewModel
+
+		err := rows.Scan(
+			&model.ID,
+			&model.CardID,
+			&model.Rating,
+			&model.Interval,
+			&model.Ease,
+			&model.TimeMs,
+			&model.Type,
+			&model.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+
+		reviewEntity, err := mappers.ReviewToDomain(&model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert review to domain: %w", err)
+		}
+		reviews = append(reviews, reviewEntity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reviews: %w", err)
+	}
+
+	return reviews, nil
+}
+
+// CountStudiedSince counts the cards introduced and the review-queue answers given since a time
+// A card counts as introduced when its first review happened at or after since
+// Manual entries (rescheduling, resetting) are not answers, so they neither introduce a card nor count as an earlier review
+func (r *ReviewRepository) CountStudiedSince(ctx context.Context, userID int64, deckIDs []int64, since time.Time) (int, int, error) {
+	query := `
+		SELECT
+			COUNT(DISTINCT r.card_id) FILTER (
+				WHERE r.type <> 'manual' AND NOT EXISTS (
+					SELECT 1 FROM reviews prev
+					WHERE prev.card_id = r.card_id AND prev.type <> 'manual' AND prev.created_at < $3
+				)
+			),
+			COUNT(*) FILTER (WHERE r.type = 'review')
+		FROM reviews r
+		INNER JOIN cards c ON r.card_id = c.id
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE d.user_id = $1 AND d.deleted_at IS NULL
+			AND c.deck_id = ANY($2)
+			AND r.created_at >= $3
+	`
+
+	var introduced, reviewed int
+	err := r.db.QueryRowContext(ctx, query, userID, pq.Array(deckIDs), since).Scan(&introduced, &reviewed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count studied cards: %w", err)
+	}
+
+	return introduced, reviewed, nil
+}
+
 // DeleteByCardID deletes all reviews for a specific card, validating ownership
 func (r *ReviewRepository) DeleteByCardID(ctx context.Context, userID int64, cardID int64) error {
 	query := `
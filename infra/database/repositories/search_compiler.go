@@ -0,0 +1,332 @@
+package repositories
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
+)
+
+// searchCompiler translates a search expression tree into a parameterized Postgres predicate
+// Note terms use the alias n (notes); card terms use c (cards) and d (decks), so they are only
+// valid where those tables are joined. The user ID is always bound as $1.
+type searchCompiler struct {
+	args         []interface{}
+	now          time.Time
+	findDayStart func(now time.Time) (time.Time, error) // Called by the first date term
+	dayStart     *time.Time
+}
+
+// newSearchCompiler creates a compiler whose first argument is the user ID
+// findDayStart returns the start of the user's study day containing now, for date terms
+func newSearchCompiler(userID int64, findDayStart func(now time.Time) (time.Time, error)) *searchCompiler {
+	return &searchCompiler{
+		args:         []interface{}{userID},
+		now:          time.Now(),
+		findDayStart: findDayStart,
+	}
+}
+
+// notePredicate compiles a search over notes n
+// When the search tests card properties, a note matches if any of its cards matches the whole search,
+// as in Anki's notes mode
+func (sc *searchCompiler) notePredicate(root search.Node) (string, error) {
+	if root == nil {
+		return "TRUE", nil
+	}
+
+	predicate, err := sc.compile(root)
+	if err != nil {
+		return "", err
+	}
+	if !search.HasCardTerms(root) {
+		return predicate, nil
+	}
+
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE c.note_id = n.id AND d.user_id = $1 AND d.deleted_at IS NULL AND (%s)
+	)`, predicate), nil
+}
+
+// cardPredicate compiles a search over cards c joined with their deck d and note n
+func (sc *searchCompiler) cardPredicate(root search.Node) (string, error) {
+	if root == nil {
+		return "TRUE", nil
+	}
+	return sc.compile(root)
+}
+
+// bind adds a query argument and returns its placeholder
+func (sc *searchCompiler) bind(value interface{}) string {
+	sc.args = append(sc.args, value)
+	return fmt.Sprintf("$%d", len(sc.args))
+}
+
+func (sc *searchCompiler) compile(node search.Node) (string, error) {
+	switch n := node.(type) {
+	case *search.AndNode:
+		return sc.join(n.Children, " AND ")
+	case *search.OrNode:
+		return sc.join(n.Children, " OR ")
+	case *search.NotNode:
+		inner, err := sc.compile(n.Child)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + inner, nil
+	case *search.TermNode:
+		predicate, err := sc.term(n.Term)
+		if err != nil {
+			return "", err
+		}
+		return "(" + predicate + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported search node: %T", node)
+	}
+}
+
+func (sc *searchCompiler) join(children []search.Node, operator string) (string, error) {
+	parts := make([]string, len(children))
+	for i, child := range children {
+		part, err := sc.compile(child)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	return "(" + strings.Join(parts, operator) + ")", nil
+}
+
+func (sc *searchCompiler) term(t search.Term) (string, error) {
+	switch t.Kind {
+	case search.TermKindText:
+		return sc.text(t.Text)
+	case search.TermKindTag:
+		return fmt.Sprintf("%s::TEXT = ANY(n.tags)", sc.bind(t.Value)), nil
+	case search.TermKindDeck:
+		return fmt.Sprintf("d.name = %s", sc.bind(t.Value)), nil
+	case search.TermKindState:
+		return sc.state(t.Value)
+	case search.TermKindFlag:
+		return fmt.Sprintf("c.flag = %s", sc.bind(t.Flag)), nil
+	case search.TermKindProperty:
+		return sc.property(t.Property)
+	case search.TermKindDate:
+		return sc.date(t.Date)
+	case search.TermKindNoteID:
+		return fmt.Sprintf("n.id = ANY(%s::BIGINT[])", sc.bind(pq.Array(t.IDs))), nil
+	case search.TermKindCardID:
+		return fmt.Sprintf("c.id = ANY(%s::BIGINT[])", sc.bind(pq.Array(t.IDs))), nil
+	case search.TermKindDeckID:
+		return fmt.Sprintf("c.deck_id = ANY(%s::BIGINT[])", sc.bind(pq.Array(t.IDs))), nil
+	case search.TermKindNoteType:
+		return sc.noteType(t)
+	case search.TermKindTemplate:
+		return sc.template(t.Value), nil
+	case search.TermKindDupe:
+		return sc.duplicate(t.Duplicate), nil
+	default:
+		return "", fmt.Errorf("unsupported search term: %s", t.Kind)
+	}
+}
+
+// text matches the note fields, or a single field when the search names one
+func (sc *searchCompiler) text(ts search.TextSearch) (string, error) {
+	value := "value"
+	if ts.IsNoCombining {
+		value = "unaccent(LOWER(value))"
+	}
+
+	var condition string
+	switch {
+	case ts.IsRegex:
+		if _, err := regexp.Compile(ts.Text); err != nil {
+			return "", fmt.Errorf("invalid regex pattern '%s': %w", ts.Text, err)
+		}
+		pattern := sc.bind(ts.Text)
+		if ts.IsNoCombining {
+			pattern = "LOWER(" + pattern + ")"
+		}
+		condition = fmt.Sprintf("%s ~ %s", value, pattern)
+	case ts.IsWordBoundary:
+		condition = fmt.Sprintf("%s ~* %s", value, sc.bind(`\m`+regexp.QuoteMeta(ts.Text)+`\M`))
+	case ts.IsWildcard && !ts.IsExact:
+		pattern := sc.bind(wildcardLike(ts.Text))
+		if ts.IsNoCombining {
+			pattern = "LOWER(" + pattern + ")"
+		}
+		condition = fmt.Sprintf("%s ILIKE %s", value, pattern)
+	default:
+		pattern := sc.bind("%" + escapeLike(ts.Text) + "%")
+		if ts.IsNoCombining {
+			pattern = "LOWER(" + pattern + ")"
+		}
+		condition = fmt.Sprintf("%s ILIKE %s", value, pattern)
+	}
+
+	if ts.Field != "" {
+		condition = fmt.Sprintf("LOWER(key) = LOWER(%s) AND %s", sc.bind(ts.Field), condition)
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE %s)", condition), nil
+}
+
+func (sc *searchCompiler) state(state string) (string, error) {
+	switch state {
+	case "new":
+		return "c.state = 'new'", nil
+	case "review":
+		return "c.state = 'review'", nil
+	case "learn":
+		return "c.state IN ('learn', 'relearn')", nil
+	case "suspended":
+		return "c.suspended = TRUE", nil
+	case "buried":
+		return "c.buried = TRUE", nil
+	case "due":
+		// Cards that are due: due <= now and not suspended/buried
+		return fmt.Sprintf("c.due <= %s AND c.suspended = FALSE AND c.buried = FALSE", sc.bind(sc.now.Unix()*1000)), nil
+	case "marked":
+		return "n.marked = TRUE", nil
+	default:
+		return "", fmt.Errorf("invalid state: is:%s", state)
+	}
+}
+
+func (sc *searchCompiler) property(prop search.PropertyFilter) (string, error) {
+	val, err := strconv.Atoi(prop.Value)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s value: %s", prop.Property, prop.Value)
+	}
+
+	var column string
+	var arg interface{} = val
+	switch prop.Property {
+	case "ivl":
+		column = "c.interval"
+	case "due":
+		// Relative days from now, compared with the due timestamp in milliseconds
+		column = "c.due"
+		arg = sc.now.AddDate(0, 0, val).Unix() * 1000
+	case "lapses":
+		column = "c.lapses"
+	case "reps":
+		column = "c.reps"
+	default:
+		return "", fmt.Errorf("invalid property: %s", prop.Property)
+	}
+
+	switch prop.Operator {
+	case ">=", "<=", ">", "<", "=":
+	default:
+		return "", fmt.Errorf("invalid property operator: %s", prop.Operator)
+	}
+
+	return fmt.Sprintf("%s %s %s", column, prop.Operator, sc.bind(arg)), nil
+}
+
+// noteType matches the note type by ID (mid:) or by case-insensitive name with * wildcards (note:)
+func (sc *searchCompiler) noteType(t search.Term) (string, error) {
+	if len(t.IDs) > 0 {
+		return fmt.Sprintf("n.note_type_id = %s", sc.bind(t.IDs[0])), nil
+	}
+	pattern := sc.bind(strings.ReplaceAll(escapeLike(t.Value), "*", "%"))
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM note_types nt WHERE nt.id = n.note_type_id AND nt.name ILIKE %s)", pattern), nil
+}
+
+// template matches the card's template by 1-based number, or by name from the note type's card types
+// Card types without an ord are numbered by their position in card_types_json
+func (sc *searchCompiler) template(value string) string {
+	if number, err := strconv.Atoi(value); err == nil {
+		return fmt.Sprintf("c.card_type_id = %s", sc.bind(number-1))
+	}
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM note_types nt, jsonb_array_elements(nt.card_types_json) WITH ORDINALITY AS ct(card_type, position)
+		WHERE nt.id = n.note_type_id AND LOWER(ct.card_type->>'name') = LOWER(%s)
+			AND c.card_type_id = COALESCE((ct.card_type->>'ord')::INTEGER, ct.position::INTEGER - 1)
+	)`, sc.bind(value))
+}
+
+// duplicate matches the notes of a note type whose first field equals the text, as NoteService.FindDuplicates
+func (sc *searchCompiler) duplicate(df search.DuplicateFilter) string {
+	return fmt.Sprintf(
+		"n.note_type_id = %s AND jsonb_extract_path_text(n.fields_json, (SELECT nt.fields_json->0->>'name' FROM note_types nt WHERE nt.id = n.note_type_id)) = %s",
+		sc.bind(df.NoteTypeID), sc.bind(df.Text),
+	)
+}
+
+// date matches the review history and creation/modification times against the user's study days
+// Manual reschedules are logged as reviews of type manual: resched matches them, rated and introduced ignore them
+func (sc *searchCompiler) date(df search.DateFilter) (string, error) {
+	if df.Days < 1 {
+		return "", fmt.Errorf("invalid %s days: %d", df.Kind, df.Days)
+	}
+	cutoff, err := sc.dayCutoff(df.Days)
+	if err != nil {
+		return "", err
+	}
+
+	switch df.Kind {
+	case "rated":
+		condition := fmt.Sprintf("r.created_at >= %s AND r.type <> 'manual'", cutoff)
+		if df.Rating != 0 {
+			condition += fmt.Sprintf(" AND r.rating = %s", sc.bind(df.Rating))
+		}
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM reviews r WHERE r.card_id = c.id AND %s)", condition), nil
+	case "added":
+		return fmt.Sprintf("c.created_at >= %s", cutoff), nil
+	case "edited":
+		return fmt.Sprintf("n.updated_at >= %s", cutoff), nil
+	case "introduced":
+		return fmt.Sprintf("(SELECT MIN(r.created_at) FROM reviews r WHERE r.card_id = c.id AND r.type <> 'manual') >= %s", cutoff), nil
+	case "resched":
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM reviews r WHERE r.card_id = c.id AND r.created_at >= %s AND r.type = 'manual')", cutoff), nil
+	default:
+		return "", fmt.Errorf("invalid date filter: %s", df.Kind)
+	}
+}
+
+// dayCutoff binds the start of the study day days-1 days before today
+// Study days roll over at the user's next_day_starts_at in their time zone, worked out in Go as for the study queue
+func (sc *searchCompiler) dayCutoff(days int) (string, error) {
+	if sc.dayStart == nil {
+		start, err := sc.findDayStart(sc.now)
+		if err != nil {
+			return "", err
+		}
+		sc.dayStart = &start
+	}
+	return sc.bind(sc.dayStart.AddDate(0, 0, -(days - 1))), nil
+}
+
+// escapeLike escapes the LIKE wildcards and escape character of a literal search text
+// The backslash is escaped first so that the escapes added for % and _ are kept
+func escapeLike(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, "%", "\\%")
+	return strings.ReplaceAll(text, "_", "\\_")
+}
+
+// wildcardLike converts a search text with * (any characters) and _ (any single character) wildcards
+// into a LIKE pattern, matching % and backslashes literally
+func wildcardLike(text string) string {
+	var pattern strings.Builder
+	for _, r := range text {
+		switch r {
+		case '*':
+			pattern.WriteByte('%')
+		case '%', '\\':
+			pattern.WriteByte('\\')
+			pattern.WriteRune(r)
+		default:
+			pattern.WriteRune(r)
+		}
+	}
+	return pattern.String()
+}
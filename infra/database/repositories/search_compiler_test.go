@@ -0,0 +1,320 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
+)
+
+// testDayStart is the start of the study day the date terms are compiled against
+var testDayStart = time.Date(2024, time.March, 10, 4, 0, 0, 0, time.UTC)
+
+func findTestDayStart(now time.Time) (time.Time, error) {
+	return testDayStart, nil
+}
+
+func compileNoteSearch(t *testing.T, query string) (string, []interface{}, error) {
+	parsed, err := search.NewParser().Parse(query)
+	require.NoError(t, err)
+
+	compiler := newSearchCompiler(100, findTestDayStart)
+	predicate, err := compiler.notePredicate(parsed.Root)
+	return predicate, compiler.args, err
+}
+
+func TestSearchCompiler_NotePredicate(t *testing.T) {
+	t.Run("Empty query matches everything", func(t *testing.T) {
+		predicate, args, err := compileNoteSearch(t, "")
+		require.NoError(t, err)
+		assert.Equal(t, "TRUE", predicate)
+		assert.Equal(t, []interface{}{int64(100)}, args)
+	})
+
+	t.Run("Note terms keep the boolean structure", func(t *testing.T) {
+		predicate, args, err := compileNoteSearch(t, "(tag:a or tag:b) -is:marked")
+		require.NoError(t, err)
+		assert.Equal(t, "((($2::TEXT = ANY(n.tags)) OR ($3::TEXT = ANY(n.tags))) AND NOT (n.marked = TRUE))", predicate)
+		assert.Equal(t, []interface{}{int64(100), "a", "b"}, args)
+	})
+
+	t.Run("Card terms are matched by any card of the note", func(t *testing.T) {
+		predicate, args, err := compileNoteSearch(t, "tag:a -(deck:x or flag:1)")
+		require.NoError(t, err)
+		assert.Contains(t, predicate, "EXISTS (")
+		assert.Contains(t, predicate, "WHERE c.note_id = n.id AND d.user_id = $1 AND d.deleted_at IS NULL")
+		assert.Contains(t, predicate, "(($2::TEXT = ANY(n.tags)) AND NOT ((d.name = $3) OR (c.flag = $4)))")
+		assert.Equal(t, []interface{}{int64(100), "a", "x", 1}, args)
+	})
+
+	t.Run("Field and text searches", func(t *testing.T) {
+		predicate, args, err := compileNoteSearch(t, "front:50%")
+		require.NoError(t, err)
+		assert.Equal(t, "(EXISTS (SELECT 1 FROM jsonb_each_text(n.fields_json) WHERE LOWER(key) = LOWER($3) AND value ILIKE $2))", predicate)
+		assert.Equal(t, []interface{}{int64(100), `%50\%%`, "front"}, args)
+	})
+
+	t.Run("Literal text escapes LIKE characters", func(t *testing.T) {
+		for query, pattern := range map[string]string{
+			`50%`:   `%50\%%`,
+			`a\b`:   `%a\\b%`,
+			`\%`:    `%\\\%%`,
+			`"a_b"`: `%a\_b%`,
+		} {
+			_, args, err := compileNoteSearch(t, query)
+			require.NoError(t, err)
+			assert.Equal(t, []interface{}{int64(100), pattern}, args, query)
+		}
+	})
+
+	t.Run("Wildcards match literal LIKE characters", func(t *testing.T) {
+		for query, pattern := range map[string]string{
+			`d*g`:     `d%g`,
+			`d_g`:     `d_g`,
+			`50%*`:    `50\%%`,
+			`a\b*`:    `a\\b%`,
+			`nc:*\%_`: `%\\\%_`,
+		} {
+			_, args, err := compileNoteSearch(t, query)
+			require.NoError(t, err)
+			assert.Equal(t, []interface{}{int64(100), pattern}, args, query)
+		}
+	})
+
+	t.Run("Invalid regex", func(t *testing.T) {
+		_, _, err := compileNoteSearch(t, "tag:a or re:[invalid")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid regex pattern")
+	})
+}
+
+func TestSearchCompiler_CardPredicate(t *testing.T) {
+	parsed, err := search.NewParser().Parse("is:new or (prop:ivl>=10 -is:suspended)")
+	require.NoError(t, err)
+
+	compiler := newSearchCompiler(100, findTestDayStart)
+	predicate, err := compiler.cardPredicate(parsed.Root)
+	require.NoError(t, err)
+
+	assert.Equal(t, "((c.state = 'new') OR ((c.interval >= $2) AND NOT (c.suspended = TRUE)))", predicate)
+	assert.Equal(t, []interface{}{int64(100), 10}, compiler.args)
+}
+
+func TestSearchCompiler_DatePredicate(t *testing.T) {
+	t.Run("Rated with answer button", func(t *testing.T) {
+		parsed, err := search.NewParser().Parse("rated:7:1")
+		require.NoError(t, err)
+
+		compiler := newSearchCompiler(100, findTestDayStart)
+		predicate, err := compiler.cardPredicate(parsed.Root)
+		require.NoError(t, err)
+
+		assert.Equal(t, "(EXISTS (SELECT 1 FROM reviews r WHERE r.card_id = c.id AND r.created_at >= $2 AND r.type <> 'manual' AND r.rating = $3))", predicate)
+		assert.Equal(t, []interface{}{int64(100), testDayStart.AddDate(0, 0, -6), 1}, compiler.args)
+	})
+
+	t.Run("Terms share the study day", func(t *testing.T) {
+		parsed, err := search.NewParser().Parse("added:1 or resched:2")
+		require.NoError(t, err)
+
+		calls := 0
+		compiler := newSearchCompiler(100, func(now time.Time) (time.Time, error) {
+			calls++
+			return findTestDayStart(now)
+		})
+		predicate, err := compiler.cardPredicate(parsed.Root)
+		require.NoError(t, err)
+
+		assert.Contains(t, predicate, "((c.created_at >= $2)")
+		assert.Contains(t, predicate, "r.created_at >= $3 AND r.type = 'manual')")
+		assert.Equal(t, []interface{}{int64(100), testDayStart, testDayStart.AddDate(0, 0, -1)}, compiler.args)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Study day lookup fails", func(t *testing.T) {
+		parsed, err := search.NewParser().Parse("rated:1")
+		require.NoError(t, err)
+
+		compiler := newSearchCompiler(100, func(now time.Time) (time.Time, error) {
+			return time.Time{}, assert.AnError
+		})
+		_, err = compiler.cardPredicate(parsed.Root)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("Edited is a note term", func(t *testing.T) {
+		predicate, _, err := compileNoteSearch(t, "edited:3")
+		require.NoError(t, err)
+		assert.NotContains(t, predicate, "FROM cards c")
+		assert.Contains(t, predicate, "(n.updated_at >= $2)")
+	})
+
+	t.Run("Introduced is matched by any card of the note", func(t *testing.T) {
+		predicate, _, err := compileNoteSearch(t, "introduced:30")
+		require.NoError(t, err)
+		assert.Contains(t, predicate, "WHERE c.note_id = n.id")
+		assert.Contains(t, predicate, "(SELECT MIN(r.created_at) FROM reviews r WHERE r.card_id = c.id AND r.type <> 'manual') >= $2)")
+	})
+}
+
+func TestSearchCompiler_StructurePredicate(t *testing.T) {
+	t.Run("Note and note type terms", func(t *testing.T) {
+		predicate, args, err := compileNoteSearch(t, "nid:1,2 (mid:5 or note:Basic*)")
+		require.NoError(t, err)
+		assert.Equal(t, "((n.id = ANY($2::BIGINT[])) AND ((n.note_type_id = $3) OR (EXISTS (SELECT 1 FROM note_types nt WHERE nt.id = n.note_type_id AND nt.name ILIKE $4))))", predicate)
+		assert.Equal(t, []interface{}{int64(100), pq.Array([]int64{1, 2}), int64(5), "Basic%"}, args)
+	})
+
+	t.Run("Note type names match LIKE characters literally", func(t *testing.T) {
+		_, args, err := compileNoteSearch(t, `note:A_1\%*`)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{int64(100), `A\_1\\\%%`}, args)
+	})
+
+	t.Run("Duplicates compare the first field", func(t *testing.T) {
+		predicate, args, err := compileNoteSearch(t, "dupe:5,hello")
+		require.NoError(t, err)
+		assert.Equal(t, "(n.note_type_id = $2 AND jsonb_extract_path_text(n.fields_json, (SELECT nt.fields_json->0->>'name' FROM note_types nt WHERE nt.id = n.note_type_id)) = $3)", predicate)
+		assert.Equal(t, []interface{}{int64(100), int64(5), "hello"}, args)
+	})
+
+	t.Run("Card terms", func(t *testing.T) {
+		parsed, err := search.NewParser().Parse("cid:9 card:2 -card:Reverse")
+		require.NoError(t, err)
+
+		compiler := newSearchCompiler(100, findTestDayStart)
+		predicate, err := compiler.cardPredicate(parsed.Root)
+		require.NoError(t, err)
+
+		assert.Contains(t, predicate, "((c.id = ANY($2::BIGINT[])) AND (c.card_type_id = $3) AND NOT (EXISTS (")
+		assert.Contains(t, predicate, "jsonb_array_elements(nt.card_types_json) WITH ORDINALITY AS ct(card_type, position)")
+		assert.Contains(t, predicate, "LOWER(ct.card_type->>'name') = LOWER($4)")
+		assert.Equal(t, []interface{}{int64(100), pq.Array([]int64{9}), 1, "Reverse"}, compiler.args)
+	})
+
+	t.Run("Deck IDs", func(t *testing.T) {
+		parsed, err := search.NewParser().Parse("did:10,11")
+		require.NoError(t, err)
+
+		compiler := newSearchCompiler(100, findTestDayStart)
+		predicate, err := compiler.cardPredicate(parsed.Root)
+		require.NoError(t, err)
+
+		assert.Equal(t, "(c.deck_id = ANY($2::BIGINT[]))", predicate)
+		assert.Equal(t, []interface{}{int64(100), pq.Array([]int64{10, 11})}, compiler.args)
+	})
+}
+
+func TestNoteRepository_FindByAdvancedSearch(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewNoteRepository(db)
+	userID := int64(100)
+
+	query, err := search.NewParser().Parse("tag:a or tag:b")
+	require.NoError(t, err)
+
+	columns := []string{
+		"id", "user_id", "guid", "note_type_id", "fields_json", "tags", "marked",
+		"created_at", "updated_at", "deleted_at", "sort_key",
+	}
+	addNote := func(rows *sqlmock.Rows, id int64, sortKey string) *sqlmock.Rows {
+		return rows.AddRow(
+			id, userID, "550e8400-e29b-41d4-a716-44665544000"+sortKey, int64(5), `{"Front":"Test"}`, "{b}", false,
+			time.Now(), time.Now(), nil, sortKey,
+		)
+	}
+	sort := search.SortOrder{Column: search.SortColumnDue, Direction: search.SortAsc}
+
+	t.Run("First page", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM notes n\s+WHERE n.user_id = \$1 AND n.deleted_at IS NULL AND \(\(\$2::TEXT = ANY\(n.tags\)\) OR \(\$3::TEXT = ANY\(n.tags\)\)\)`).
+			WithArgs(userID, "a", "b").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		rows := sqlmock.NewRows(columns)
+		addNote(rows, 1, "1")
+		addNote(rows, 2, "2")
+		addNote(rows, 3, "3")
+		mock.ExpectQuery(`SELECT .* FROM notes n\s+WHERE n.user_id = \$1 AND n.deleted_at IS NULL AND \(.*\) AND TRUE\s+ORDER BY COALESCE\(\(SELECT MIN\(c.due\) FROM cards c WHERE c.note_id = n.id\), 0\) ASC, n.id ASC\s+LIMIT \$4$`).
+			WithArgs(userID, "a", "b", 3).
+			WillReturnRows(rows)
+
+		notes, info, err := repo.FindByAdvancedSearch(ctx, userID, query, search.PageRequest{Sort: sort, Limit: 2})
+		require.NoError(t, err)
+		assert.Len(t, notes, 2)
+		assert.Equal(t, 3, info.Total)
+
+		cursor, err := search.DecodeCursor(info.NextCursor, sort)
+		require.NoError(t, err)
+		assert.Equal(t, "2", cursor.Key)
+		assert.Equal(t, int64(2), cursor.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Page after a cursor", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+			WithArgs(userID, "a", "b").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		rows := sqlmock.NewRows(columns)
+		addNote(rows, 3, "3")
+		mock.ExpectQuery(`AND \(COALESCE\(\(SELECT MIN\(c.due\) FROM cards c WHERE c.note_id = n.id\), 0\), n.id\) > \(\$4::BIGINT, \$5\)\s+ORDER BY .* LIMIT \$6$`).
+			WithArgs(userID, "a", "b", "2", int64(2), 3).
+			WillReturnRows(rows)
+
+		page := search.PageRequest{Sort: sort, Limit: 2, Offset: 40, Cursor: search.EncodeCursor(sort, "2", 2)}
+		notes, info, err := repo.FindByAdvancedSearch(ctx, userID, query, page)
+		require.NoError(t, err)
+		assert.Len(t, notes, 1)
+		assert.Equal(t, 3, info.Total)
+		assert.Empty(t, info.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Cursor of another sort order", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+			WithArgs(userID, "a", "b").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		page := search.PageRequest{Sort: search.DefaultSortOrder, Limit: 2, Cursor: search.EncodeCursor(sort, "2", 2)}
+		_, _, err := repo.FindByAdvancedSearch(ctx, userID, query, page)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid cursor")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCardRepository_FindByAdvancedSearch(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewCardRepository(db)
+	userID := int64(100)
+
+	query, err := search.NewParser().Parse("is:new")
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM cards c\s+INNER JOIN decks d ON c.deck_id = d.id\s+INNER JOIN notes n ON c.note_id = n.id\s+WHERE d.user_id = \$1 AND d.deleted_at IS NULL AND n.deleted_at IS NULL AND \(c.state = 'new'\)`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(120))
+
+	mock.ExpectQuery(`ORDER BY d.name DESC, c.id DESC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs(userID, 51, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	page := search.PageRequest{Sort: search.SortOrder{Column: search.SortColumnDeck, Direction: search.SortDesc}, Limit: 50, Offset: 100}
+	cards, info, err := repo.FindByAdvancedSearch(context.Background(), userID, query, page)
+	require.NoError(t, err)
+	assert.Empty(t, cards)
+	assert.Equal(t, 120, info.Total)
+	assert.Empty(t, info.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
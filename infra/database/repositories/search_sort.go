@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
+)
+
+// searchSortKey is the SQL expression of a sort column and the type its cursor value is cast back to
+// Expressions never return NULL, so that keyset comparisons always hold
+type searchSortKey struct {
+	expr    string
+	sqlType string
+}
+
+const (
+	noteTypeNameExpr = "COALESCE((SELECT nt.name FROM note_types nt WHERE nt.id = n.note_type_id), '')"
+	sortFieldExpr    = "COALESCE(jsonb_extract_path_text(n.fields_json, (SELECT nt.fields_json->0->>'name' FROM note_types nt WHERE nt.id = n.note_type_id)), '')"
+	tagsExpr         = "array_to_string(n.tags, ' ')"
+)
+
+// noteSortKeys sorts notes n; card columns aggregate the note's cards
+var noteSortKeys = map[string]searchSortKey{
+	search.SortColumnCreated:   {"n.created_at", "TIMESTAMPTZ"},
+	search.SortColumnModified:  {"n.updated_at", "TIMESTAMPTZ"},
+	search.SortColumnNoteType:  {noteTypeNameExpr, "TEXT"},
+	search.SortColumnSortField: {sortFieldExpr, "TEXT"},
+	search.SortColumnTags:      {tagsExpr, "TEXT"},
+	search.SortColumnDeck:      {"COALESCE((SELECT MIN(d.name) FROM cards c INNER JOIN decks d ON c.deck_id = d.id WHERE c.note_id = n.id), '')", "TEXT"},
+	search.SortColumnCard:      {"(SELECT COUNT(*) FROM cards c WHERE c.note_id = n.id)", "BIGINT"},
+	search.SortColumnDue:       {"COALESCE((SELECT MIN(c.due) FROM cards c WHERE c.note_id = n.id), 0)", "BIGINT"},
+	search.SortColumnInterval:  {"COALESCE((SELECT AVG(c.interval) FROM cards c WHERE c.note_id = n.id), 0)", "NUMERIC"},
+	search.SortColumnEase:      {"COALESCE((SELECT AVG(c.ease) FROM cards c WHERE c.note_id = n.id), 0)", "NUMERIC"},
+	search.SortColumnLapses:    {"COALESCE((SELECT SUM(c.lapses) FROM cards c WHERE c.note_id = n.id), 0)", "BIGINT"},
+	search.SortColumnReps:      {"COALESCE((SELECT SUM(c.reps) FROM cards c WHERE c.note_id = n.id), 0)", "BIGINT"},
+}
+
+// cardSortKeys sorts cards c joined with their deck d and note n
+var cardSortKeys = map[string]searchSortKey{
+	search.SortColumnCreated:   {"c.created_at", "TIMESTAMPTZ"},
+	search.SortColumnModified:  {"c.updated_at", "TIMESTAMPTZ"},
+	search.SortColumnNoteType:  {noteTypeNameExpr, "TEXT"},
+	search.SortColumnSortField: {sortFieldExpr, "TEXT"},
+	search.SortColumnTags:      {tagsExpr, "TEXT"},
+	search.SortColumnDeck:      {"d.name", "TEXT"},
+	search.SortColumnCard:      {"c.card_type_id", "INTEGER"},
+	search.SortColumnDue:       {"c.due", "BIGINT"},
+	search.SortColumnInterval:  {"c.interval", "INTEGER"},
+	search.SortColumnEase:      {"c.ease", "INTEGER"},
+	search.SortColumnLapses:    {"c.lapses", "INTEGER"},
+	search.SortColumnReps:      {"c.reps", "INTEGER"},
+}
+
+// searchOrder is the ordering of a search page
+type searchOrder struct {
+	key     string // Sort key selected with each row to build the next cursor
+	seek    string // Keyset condition resuming after the cursor, TRUE on the first page
+	orderBy string
+	limit   string
+	offset  string // OFFSET clause, empty when resuming from a cursor
+}
+
+// order compiles the sort order, cursor and page size of a search
+// One extra row is fetched to tell whether a next page exists
+func (sc *searchCompiler) order(keys map[string]searchSortKey, idColumn string, page search.PageRequest) (*searchOrder, error) {
+	if err := page.Sort.Validate(); err != nil {
+		return nil, err
+	}
+	if page.Limit <= 0 {
+		return nil, fmt.Errorf("invalid page size: %d", page.Limit)
+	}
+	key := keys[page.Sort.Column]
+
+	direction, comparison := "ASC", ">"
+	if page.Sort.Direction == search.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	o := &searchOrder{
+		key:     key.expr + "::TEXT",
+		seek:    "TRUE",
+		orderBy: fmt.Sprintf("%s %s, %s %s", key.expr, direction, idColumn, direction),
+	}
+	if page.Cursor != "" {
+		cursor, err := search.DecodeCursor(page.Cursor, page.Sort)
+		if err != nil {
+			return nil, err
+		}
+		o.seek = fmt.Sprintf("(%s, %s) %s (%s::%s, %s)", key.expr, idColumn, comparison, sc.bind(cursor.Key), key.sqlType, sc.bind(cursor.ID))
+	}
+	o.limit = sc.bind(page.Limit + 1)
+	if page.Cursor == "" && page.Offset > 0 {
+		o.offset = " OFFSET " + sc.bind(page.Offset)
+	}
+	return o, nil
+}
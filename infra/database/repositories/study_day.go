@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	userpreferences "github.com/felipesantos/anki-backend/core/domain/entities/user_preferences"
+)
+
+// studyDayColumns selects the time zone and the day rollover, in seconds after midnight, of the user
+// preferences joined as up. Both are NULL for users without preferences
+// Day boundaries are worked out in Go from these columns instead of with AT TIME ZONE, since a time zone
+// known to Go may be unknown to Postgres and would fail the whole statement
+const studyDayColumns = "up.timezone, EXTRACT(EPOCH FROM up.next_day_starts_at)::integer"
+
+// dayStart returns the start of the study day containing now from the columns selected by studyDayColumns
+func dayStart(timezone sql.NullString, rollover sql.NullInt64, now time.Time) time.Time {
+	seconds := int64(userpreferences.DefaultNextDayStartsAtHour * 60 * 60)
+	if rollover.Valid {
+		seconds = rollover.Int64
+	}
+	return userpreferences.DayStartIn(timezone.String, time.Duration(seconds)*time.Second, now)
+}
+
+// findDayStart returns the start of the user's study day containing now
+func findDayStart(ctx context.Context, db *sql.DB, userID int64, now time.Time) (time.Time, error) {
+	query := `SELECT ` + studyDayColumns + ` FROM (SELECT 1) AS one LEFT JOIN user_preferences up ON up.user_id = $1`
+
+	var timezone sql.NullString
+	var rollover sql.NullInt64
+	if err := db.QueryRowContext(ctx, query, userID).Scan(&timezone, &rollover); err != nil {
+		return time.Time{}, fmt.Errorf("failed to find study day: %w", err)
+	}
+	return dayStart(timezone, rollover, now), nil
+}
+
+// dayStartFinder returns a function finding the start of the user's study day, for queries that only sometimes need it
+func dayStartFinder(ctx context.Context, db *sql.DB, userID int64) func(now time.Time) (time.Time, error) {
+	return func(now time.Time) (time.Time, error) {
+		return findDayStart(ctx, db, userID, now)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/felipesantos/anki-backend/infra/database/mappers"
 	"github.com/felipesantos/anki-backend/infra/database/models"
 	"github.com/felipesantos/anki-backend/pkg/ownership"
+	"github.com/lib/pq"
 )
 
 // UndoHistoryRepository implements IUndoHistoryRepository using PostgreSQL
@@ -33,8 +34,8 @@ func (r *UndoHistoryRepository) Save(ctx context.Context, userID int64, undoHist
 	if undoHistoryEntity.GetID() == 0 {
 		// Insert new undo history entry
 		query := `
-			INSERT INTO undo_history (user_id, operation_type, operation_data, created_at)
-			VALUES ($1, $2, $3, $4)
+			INSERT INTO undo_history (user_id, operation_type, operation_data, created_at, undone_at)
+			VALUES ($1, $2, $3, $4, $5)
 			RETURNING id
 		`
 
@@ -49,6 +50,7 @@ func (r *UndoHistoryRepository) Save(ctx context.Context, userID int64, undoHist
 			model.OperationType,
 			model.OperationData,
 			model.CreatedAt,
+			model.UndoneAt,
 		).Scan(&undoHistoryID)
 		if err != nil {
 			return fmt.Errorf("failed to create undo history: %w", err)
@@ -70,14 +72,15 @@ func (r *UndoHistoryRepository) Save(ctx context.Context, userID int64, undoHist
 	// Update undo history entry
 	query := `
 		UPDATE undo_history
-		SET operation_type = $1, operation_data = $2, created_at = $3
-		WHERE id = $4 AND user_id = $5
+		SET operation_type = $1, operation_data = $2, created_at = $3, undone_at = $4
+		WHERE id = $5 AND user_id = $6
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
 		model.OperationType,
 		model.OperationData,
 		model.CreatedAt,
+		model.UndoneAt,
 		model.ID,
 		userID,
 	)
@@ -101,7 +104,7 @@ func (r *UndoHistoryRepository) Save(ctx context.Context, userID int64, undoHist
 // FindByID finds an undo history entry by ID, filtering by userID to ensure ownership
 func (r *UndoHistoryRepository) FindByID(ctx context.Context, userID int64, id int64) (*undohistory.UndoHistory, error) {
 	query := `
-		SELECT id, user_id, operation_type, operation_data, created_at
+		SELECT id, user_id, operation_type, operation_data, created_at, undone_at
 		FROM undo_history
 		WHERE id = $1 AND user_id = $2
 	`
@@ -113,6 +116,7 @@ func (r *UndoHistoryRepository) FindByID(ctx context.Context, userID int64, id i
 		&model.OperationType,
 		&model.OperationData,
 		&model.CreatedAt,
+		&model.UndoneAt,
 	)
 
 	if err != nil {
@@ -133,7 +137,7 @@ func (r *UndoHistoryRepository) FindByID(ctx context.Context, userID int64, id i
 // FindByUserID finds all undo history entries for a user
 func (r *UndoHistoryRepository) FindByUserID(ctx context.Context, userID int64) ([]*undohistory.UndoHistory, error) {
 	query := `
-		SELECT id, user_id, operation_type, operation_data, created_at
+		SELECT id, user_id, operation_type, operation_data, created_at, undone_at
 		FROM undo_history
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -155,6 +159,7 @@ func (r *UndoHistoryRepository) FindByUserID(ctx context.Context, userID int64)
 			&model.OperationType,
 			&model.OperationData,
 			&model.CreatedAt,
+			&model.UndoneAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan undo history: %w", err)
@@ -231,7 +236,7 @@ func (r *UndoHistoryRepository) Exists(ctx context.Context, userID int64, id int
 // FindLatest finds the latest undo history entries for a user
 func (r *UndoHistoryRepository) FindLatest(ctx context.Context, userID int64, limit int) ([]*undohistory.UndoHistory, error) {
 	query := `
-		SELECT id, user_id, operation_type, operation_data, created_at
+		SELECT id, user_id, operation_type, operation_data, created_at, undone_at
 		FROM undo_history
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -253,6 +258,7 @@ func (r *UndoHistoryRepository) FindLatest(ctx context.Context, userID int64, li
 			&model.OperationType,
 			&model.OperationData,
 			&model.CreatedAt,
+			&model.UndoneAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan undo history: %w", err)
@@ -268,6 +274,77 @@ func (r *UndoHistoryRepository) FindLatest(ctx context.Context, userID int64, li
 	return logs, nil
 }
 
+// FindLatestByType finds the most recent undo history entry of an operation type for a user
+func (r *UndoHistoryRepository) FindLatestByType(ctx context.Context, userID int64, operationType string) (*undohistory.UndoHistory, error) {
+	query := `
+		SELECT id, user_id, operation_type, operation_data, created_at, undone_at
+		FROM undo_history
+		WHERE user_id = $1 AND operation_type = $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	return r.findOne(ctx, query, userID, operationType)
+}
+
+// FindLatestApplied finds the most recent entry of the given operation types that has not been undone
+func (r *UndoHistoryRepository) FindLatestApplied(ctx context.Context, userID int64, operationTypes []string) (*undohistory.UndoHistory, error) {
+	query := `
+		SELECT id, user_id, operation_type, operation_data, created_at, undone_at
+		FROM undo_history
+		WHERE user_id = $1 AND operation_type = ANY($2) AND undone_at IS NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	return r.findOne(ctx, query, userID, pq.Array(operationTypes))
+}
+
+// FindLatestUndone finds the most recently undone entry of the given operation types
+func (r *UndoHistoryRepository) FindLatestUndone(ctx context.Context, userID int64, operationTypes []string) (*undohistory.UndoHistory, error) {
+	query := `
+		SELECT id, user_id, operation_type, operation_data, created_at, undone_at
+		FROM undo_history
+		WHERE user_id = $1 AND operation_type = ANY($2) AND undone_at IS NOT NULL
+		ORDER BY undone_at DESC, id ASC
+		LIMIT 1
+	`
+
+	return r.findOne(ctx, query, userID, pq.Array(operationTypes))
+}
+
+// DeleteUndone deletes all undone entries of a user
+func (r *UndoHistoryRepository) DeleteUndone(ctx context.Context, userID int64) error {
+	query := `DELETE FROM undo_history WHERE user_id = $1 AND undone_at IS NOT NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to delete undone history: %w", err)
+	}
+
+	return nil
+}
+
+// findOne runs a query selecting at most one entry, returning nil if there is none
+func (r *UndoHistoryRepository) findOne(ctx context.Context, query string, args ...interface{}) (*undohistory.UndoHistory, error) {
+	var model models.UndoHistoryModel
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&model.ID,
+		&model.UserID,
+		&model.OperationType,
+		&model.OperationData,
+		&model.CreatedAt,
+		&model.UndoneAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find latest undo history: %w", err)
+	}
+
+	return mappers.UndoHistoryToDomain(&model)
+}
+
 // Ensure UndoHistoryRepository implements IUndoHistoryRepository
 var _ secondary.IUndoHistoryRepository = (*UndoHistoryRepository)(nil)
-
@@ -40,9 +40,9 @@ func (r *UserPreferencesRepository) Save(ctx context.Context, userID int64, pref
 				show_play_buttons, interrupt_audio_on_answer, show_remaining_count,
 				show_next_review_time, spacebar_answers_card, ignore_accents_in_search,
 				default_search_text, sync_audio_and_images, periodically_sync_media,
-				force_one_way_sync, self_hosted_sync_server_url, created_at, updated_at
+				force_one_way_sync, self_hosted_sync_server_url, created_at, updated_at, timezone
 			)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)
 			RETURNING id
 		`
 
@@ -93,6 +93,7 @@ func (r *UserPreferencesRepository) Save(ctx context.Context, userID int64, pref
 			selfHostedURL,
 			model.CreatedAt,
 			model.UpdatedAt,
+			model.Timezone,
 		).Scan(&prefsID)
 		if err != nil {
 			return fmt.Errorf("failed to create user preferences: %w", err)
@@ -124,7 +125,7 @@ func (r *UserPreferencesRepository) Save(ctx context.Context, userID int64, pref
 			ignore_accents_in_search = $19, default_search_text = $20,
 			sync_audio_and_images = $21, periodically_sync_media = $22,
 			force_one_way_sync = $23, self_hosted_sync_server_url = $24,
-			updated_at = $25
+			updated_at = $25, timezone = $28
 		WHERE id = $26 AND user_id = $27
 	`
 
@@ -169,6 +170,7 @@ func (r *UserPreferencesRepository) Save(ctx context.Context, userID int64, pref
 		model.UpdatedAt,
 		model.ID,
 		userID,
+		model.Timezone,
 	)
 
 	if err != nil {
@@ -197,7 +199,7 @@ func (r *UserPreferencesRepository) FindByID(ctx context.Context, userID int64,
 			show_remaining_count, show_next_review_time, spacebar_answers_card,
 			ignore_accents_in_search, default_search_text, sync_audio_and_images,
 			periodically_sync_media, force_one_way_sync, self_hosted_sync_server_url,
-			created_at, updated_at
+			created_at, updated_at, timezone
 		FROM user_preferences
 		WHERE id = $1 AND user_id = $2
 	`
@@ -235,6 +237,7 @@ func (r *UserPreferencesRepository) FindByID(ctx context.Context, userID int64,
 		&selfHostedURL,
 		&model.CreatedAt,
 		&model.UpdatedAt,
+		&model.Timezone,
 	)
 
 	if err != nil {
@@ -272,7 +275,7 @@ func (r *UserPreferencesRepository) FindByUserID(ctx context.Context, userID int
 			show_remaining_count, show_next_review_time, spacebar_answers_card,
 			ignore_accents_in_search, default_search_text, sync_audio_and_images,
 			periodically_sync_media, force_one_way_sync, self_hosted_sync_server_url,
-			created_at, updated_at
+			created_at, updated_at, timezone
 		FROM user_preferences
 		WHERE user_id = $1
 	`
@@ -310,6 +313,7 @@ func (r *UserPreferencesRepository) FindByUserID(ctx context.Context, userID int
 		&selfHostedURL,
 		&model.CreatedAt,
 		&model.UpdatedAt,
+		&model.Timezone,
 	)
 
 	if err != nil {
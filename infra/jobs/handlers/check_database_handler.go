@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// CheckDatabaseJobType is the job type for checking and repairing a user's collection
+const CheckDatabaseJobType = "check_database"
+
+// CheckDatabaseHandler runs Check Database in the background
+// Payload: user_id
+type CheckDatabaseHandler struct {
+	checkService primary.ICheckDatabaseService
+	queue        secondary.IJobQueue
+}
+
+// NewCheckDatabaseHandler creates a new check database handler
+func NewCheckDatabaseHandler(checkService primary.ICheckDatabaseService, queue secondary.IJobQueue) *CheckDatabaseHandler {
+	return &CheckDatabaseHandler{
+		checkService: checkService,
+		queue:        queue,
+	}
+}
+
+// Handle checks the collection and stores the log entry and the number of repairs of each kind in the job result
+func (h *CheckDatabaseHandler) Handle(ctx context.Context, job *secondary.Job) error {
+	userID, err := payloadInt64(job.Payload, "user_id")
+	if err != nil {
+		return err
+	}
+
+	result, err := h.checkService.CheckDatabase(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("check database failed: %w", err)
+	}
+
+	job.Progress = 100
+	job.Result = map[string]interface{}{
+		"log_id":                       result.LogID,
+		"issues_found":                 result.IssuesFound(),
+		"cards_with_deleted_note":      len(result.CardsWithDeletedNote),
+		"cards_in_deleted_deck":        len(result.CardsInDeletedDeck),
+		"cards_with_missing_home_deck": len(result.CardsWithMissingHomeDeck),
+		"notes_with_wrong_field_count": len(result.NotesWithWrongFieldCount),
+		"cards_with_invalid_ordinal":   len(result.CardsWithInvalidOrdinal),
+		"cards_with_invalid_schedule":  len(result.CardsWithInvalidSchedule),
+		"notes_with_duplicate_guid":    len(result.NotesWithDuplicateGUID),
+	}
+	return h.queue.UpdateProgress(ctx, job.ID, job.Progress, job.Result)
+}
+
+// JobType returns the type of job this handler processes
+func (h *CheckDatabaseHandler) JobType() string {
+	return CheckDatabaseJobType
+}
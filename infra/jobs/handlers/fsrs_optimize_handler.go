@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// FSRSOptimizeJobType is the job type for fitting FSRS parameters to a user's review history
+const FSRSOptimizeJobType = "fsrs_optimize"
+
+// FSRSOptimizeHandler runs the FSRS parameter optimizer in the background
+// Payload: user_id, preset_id and deck_ids (the decks using the preset)
+type FSRSOptimizeHandler struct {
+	optimizer primary.IFSRSOptimizerService
+	queue     secondary.IJobQueue
+}
+
+// NewFSRSOptimizeHandler creates a new FSRS optimize handler
+func NewFSRSOptimizeHandler(optimizer primary.IFSRSOptimizerService, queue secondary.IJobQueue) *FSRSOptimizeHandler {
+	return &FSRSOptimizeHandler{
+		optimizer: optimizer,
+		queue:     queue,
+	}
+}
+
+// Handle fits the parameters, saves them to the preset and stores the evaluation in the job result
+func (h *FSRSOptimizeHandler) Handle(ctx context.Context, job *secondary.Job) error {
+	userID, err := payloadInt64(job.Payload, "user_id")
+	if err != nil {
+		return err
+	}
+	presetID, err := payloadInt64(job.Payload, "preset_id")
+	if err != nil {
+		return err
+	}
+	deckIDs, err := payloadInt64Slice(job.Payload, "deck_ids")
+	if err != nil {
+		return err
+	}
+
+	lastReported := -1
+	progress := func(percent int) {
+		if percent == lastReported {
+			return
+		}
+		lastReported = percent
+		// Progress is informational, a failed update must not abort the optimization
+		_ = h.queue.UpdateProgress(ctx, job.ID, percent, nil)
+	}
+
+	result, err := h.optimizer.Optimize(ctx, userID, presetID, deckIDs, progress)
+	if err != nil {
+		return fmt.Errorf("fsrs optimization failed: %w", err)
+	}
+
+	job.Progress = 100
+	job.Result = map[string]interface{}{
+		"weights":      result.Weights,
+		"review_count": result.ReviewCount,
+		"before": map[string]interface{}{
+			"log_loss": result.Before.LogLoss,
+			"rmse":     result.Before.RMSE,
+		},
+		"after": map[string]interface{}{
+			"log_loss": result.After.LogLoss,
+			"rmse":     result.After.RMSE,
+		},
+	}
+	return h.queue.UpdateProgress(ctx, job.ID, job.Progress, job.Result)
+}
+
+// JobType returns the type of job this handler processes
+func (h *FSRSOptimizeHandler) JobType() string {
+	return FSRSOptimizeJobType
+}
+
+// payloadInt64 reads a required integer from a job payload
+// Numbers decoded from JSON are float64, while payloads built in-process may hold integers
+func payloadInt64(payload map[string]interface{}, key string) (int64, error) {
+	switch v := payload[key].(type) {
+	case float64:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("invalid or missing %s in job payload", key)
+	}
+}
+
+// payloadInt64Slice reads an optional list of integers from a job payload
+func payloadInt64Slice(payload map[string]interface{}, key string) ([]int64, error) {
+	switch v := payload[key].(type) {
+	case nil:
+		return nil, nil
+	case []int64:
+		return v, nil
+	case []interface{}:
+		result := make([]int64, 0, len(v))
+		for i := range v {
+			id, err := payloadInt64(map[string]interface{}{key: v[i]}, key)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, id)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("invalid %s in job payload", key)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+)
+
+// UnburyJobType is the job type for unburying cards once each user's study day rolls over
+const UnburyJobType = "unbury_cards"
+
+// UnburyCronExpr runs the unbury job every 5 minutes, so that cards are unburied shortly
+// after next_day_starts_at whatever the user's time zone offset
+const UnburyCronExpr = "0 */5 * * * *"
+
+// UnburyHandler unburies cards buried on a previous study day
+// Payload: none
+type UnburyHandler struct {
+	studyService primary.IStudyService
+	queue        secondary.IJobQueue
+}
+
+// NewUnburyHandler creates a new unbury handler
+func NewUnburyHandler(studyService primary.IStudyService, queue secondary.IJobQueue) *UnburyHandler {
+	return &UnburyHandler{
+		studyService: studyService,
+		queue:        queue,
+	}
+}
+
+// Handle unburies the cards and stores how many were unburied in the job result
+func (h *UnburyHandler) Handle(ctx context.Context, job *secondary.Job) error {
+	count, err := h.studyService.UnburyAtDayRollover(ctx)
+	if err != nil {
+		return fmt.Errorf("unbury failed: %w", err)
+	}
+
+	job.Progress = 100
+	job.Result = map[string]interface{}{"unburied": count}
+	return h.queue.UpdateProgress(ctx, job.ID, job.Progress, job.Result)
+}
+
+// JobType returns the type of job this handler processes
+func (h *UnburyHandler) JobType() string {
+	return UnburyJobType
+}
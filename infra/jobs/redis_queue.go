@@ -124,6 +124,21 @@ func (q *RedisQueue) UpdateStatus(ctx context.Context, jobID string, status seco
 	return q.storeJobStatus(ctx, job)
 }
 
+// UpdateProgress updates the progress percentage and, if not nil, the result of a job
+func (q *RedisQueue) UpdateProgress(ctx context.Context, jobID string, progress int, result map[string]interface{}) error {
+	job, err := q.GetStatus(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	job.Progress = min(max(progress, 0), 100)
+	if result != nil {
+		job.Result = result
+	}
+
+	return q.storeJobStatus(ctx, job)
+}
+
 // Retry re-enqueues a failed job for retry
 // Note: The retry count should already be incremented by the caller
 func (q *RedisQueue) Retry(ctx context.Context, job *secondary.Job) error {
@@ -177,6 +177,11 @@ func (r *LocalStorageRepository) List(ctx context.Context, prefix string) ([]*se
 
 	var files []*secondary.FileInfo
 
+	// Nothing has been stored under this prefix yet
+	if _, err := os.Stat(searchPath); os.IsNotExist(err) {
+		return files, nil
+	}
+
 	err := filepath.Walk(searchPath, func(fullPath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
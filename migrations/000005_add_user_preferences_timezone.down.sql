@@ -0,0 +1,2 @@
+-- Remove the user's time zone
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,2 @@
+-- Add the user's time zone so that study days roll over at next_day_starts_at local time
+ALTER TABLE user_preferences ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,4 @@
+-- Remove review undo entries and restore the original operation types
+DELETE FROM undo_history WHERE operation_type = 'review_card';
+ALTER TABLE undo_history DROP CONSTRAINT IF EXISTS check_operation_type;
+ALTER TABLE undo_history ADD CONSTRAINT check_operation_type CHECK (operation_type IN ('edit_note', 'delete_note', 'move_card', 'change_flag', 'add_tag', 'remove_tag', 'change_deck'));
@@ -0,0 +1,3 @@
+-- Allow undo history entries for card answers so that reviews can be undone
+ALTER TABLE undo_history DROP CONSTRAINT IF EXISTS check_operation_type;
+ALTER TABLE undo_history ADD CONSTRAINT check_operation_type CHECK (operation_type IN ('edit_note', 'delete_note', 'move_card', 'change_flag', 'add_tag', 'remove_tag', 'change_deck', 'review_card'));
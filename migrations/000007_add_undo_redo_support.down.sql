@@ -0,0 +1,8 @@
+-- Remove the new operation types and the redo state
+DROP INDEX IF EXISTS idx_undo_history_user_undone;
+
+DELETE FROM undo_history WHERE operation_type IN ('add_note', 'edit_card', 'delete_card', 'add_deck', 'edit_deck', 'delete_deck');
+ALTER TABLE undo_history DROP CONSTRAINT IF EXISTS check_operation_type;
+ALTER TABLE undo_history ADD CONSTRAINT check_operation_type CHECK (operation_type IN ('edit_note', 'delete_note', 'move_card', 'change_flag', 'add_tag', 'remove_tag', 'change_deck', 'review_card'));
+
+ALTER TABLE undo_history DROP COLUMN IF EXISTS undone_at;
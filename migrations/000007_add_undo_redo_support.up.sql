@@ -0,0 +1,9 @@
+-- Allow undo history entries for every note, card and deck operation, and track undone entries for redo
+ALTER TABLE undo_history ADD COLUMN undone_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE undo_history DROP CONSTRAINT IF EXISTS check_operation_type;
+ALTER TABLE undo_history ADD CONSTRAINT check_operation_type CHECK (operation_type IN ('edit_note', 'delete_note', 'move_card', 'change_flag', 'add_tag', 'remove_tag', 'change_deck', 'review_card', 'add_note', 'edit_card', 'delete_card', 'add_deck', 'edit_deck', 'delete_deck'));
+
+CREATE INDEX idx_undo_history_user_undone ON undo_history(user_id, undone_at DESC) WHERE undone_at IS NOT NULL;
+
+COMMENT ON COLUMN undo_history.undone_at IS 'When the operation was undone (NULL while applied)';
@@ -0,0 +1,7 @@
+-- Remove the manual review type (enum values cannot be dropped, so the type is recreated)
+DELETE FROM reviews WHERE type = 'manual';
+
+ALTER TYPE review_type RENAME TO review_type_old;
+CREATE TYPE review_type AS ENUM ('learn', 'review', 'relearn', 'cram');
+ALTER TABLE reviews ALTER COLUMN type TYPE review_type USING type::text::review_type;
+DROP TYPE review_type_old;
@@ -0,0 +1,2 @@
+-- Add the review log type of manual reschedules (Anki's set due date and forget entries), searched by resched:n
+ALTER TYPE review_type ADD VALUE IF NOT EXISTS 'manual';
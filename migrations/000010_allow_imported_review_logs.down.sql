@@ -0,0 +1,8 @@
+-- Remove the imported review log entries that break the original constraints and restore them
+DELETE FROM reviews WHERE rating = 0 OR time_ms <= 0 OR interval = 0;
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS check_rating_range;
+ALTER TABLE reviews ADD CONSTRAINT check_rating_range CHECK (rating >= 1 AND rating <= 4);
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS check_time_ms_positive;
+ALTER TABLE reviews ADD CONSTRAINT check_time_ms_positive CHECK (time_ms > 0);
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS check_interval_valid;
+ALTER TABLE reviews ADD CONSTRAINT check_interval_valid CHECK (interval != 0);
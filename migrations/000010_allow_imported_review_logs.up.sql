@@ -0,0 +1,8 @@
+-- Allow the review log entries of imported Anki collections:
+-- manual reschedules have no rating, old entries have no answer time, and forgotten or previewed cards log a zero interval
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS check_rating_range;
+ALTER TABLE reviews ADD CONSTRAINT check_rating_range CHECK ((rating >= 1 AND rating <= 4) OR (type = 'manual' AND rating = 0));
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS check_time_ms_positive;
+ALTER TABLE reviews ADD CONSTRAINT check_time_ms_positive CHECK (time_ms >= 0);
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS check_interval_valid;
+ALTER TABLE reviews ADD CONSTRAINT check_interval_valid CHECK (interval != 0 OR type IN ('manual', 'cram'));
@@ -0,0 +1,40 @@
+DROP TRIGGER IF EXISTS mark_deck_options_presets_schema_modified ON deck_options_presets;
+DROP TRIGGER IF EXISTS mark_note_types_schema_modified ON note_types;
+DROP FUNCTION IF EXISTS mark_sync_schema_modified();
+
+DROP TRIGGER IF EXISTS log_filtered_decks_deletion ON filtered_decks;
+DROP TRIGGER IF EXISTS log_decks_deletion ON decks;
+DROP FUNCTION IF EXISTS log_deck_deletion();
+DROP TRIGGER IF EXISTS log_cards_deletion ON cards;
+DROP FUNCTION IF EXISTS log_card_deletion();
+
+DROP TRIGGER IF EXISTS set_deletions_log_usn ON deletions_log;
+DROP TRIGGER IF EXISTS set_filtered_decks_usn ON filtered_decks;
+DROP TRIGGER IF EXISTS set_deck_options_presets_usn ON deck_options_presets;
+DROP TRIGGER IF EXISTS set_reviews_usn ON reviews;
+DROP TRIGGER IF EXISTS set_note_types_usn ON note_types;
+DROP TRIGGER IF EXISTS set_decks_usn ON decks;
+DROP TRIGGER IF EXISTS set_cards_usn ON cards;
+DROP TRIGGER IF EXISTS set_notes_usn ON notes;
+DROP FUNCTION IF EXISTS set_sync_usn();
+DROP FUNCTION IF EXISTS touch_sync_state(BIGINT);
+
+-- Remove the entries the original constraints don't allow and restore them
+DELETE FROM reviews WHERE interval = 0 AND type NOT IN ('manual', 'cram');
+ALTER TABLE reviews ADD CONSTRAINT check_interval_valid CHECK (interval != 0 OR type IN ('manual', 'cram'));
+
+DELETE FROM deletions_log WHERE object_type = 'filtered_deck';
+ALTER TABLE deletions_log DROP CONSTRAINT IF EXISTS check_object_type;
+ALTER TABLE deletions_log ADD CONSTRAINT check_object_type CHECK (object_type IN ('note', 'card', 'deck', 'note_type'));
+
+ALTER TABLE deletions_log DROP COLUMN IF EXISTS usn;
+ALTER TABLE filtered_decks DROP COLUMN IF EXISTS usn;
+ALTER TABLE deck_options_presets DROP COLUMN IF EXISTS usn;
+ALTER TABLE reviews DROP COLUMN IF EXISTS usn;
+ALTER TABLE note_types DROP COLUMN IF EXISTS usn;
+ALTER TABLE decks DROP COLUMN IF EXISTS usn;
+ALTER TABLE cards DROP COLUMN IF EXISTS usn;
+ALTER TABLE notes DROP COLUMN IF EXISTS usn;
+
+DROP TABLE IF EXISTS anki_sync_ids;
+DROP TABLE IF EXISTS sync_state;
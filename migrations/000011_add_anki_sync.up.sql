@@ -0,0 +1,191 @@
+-- Migration: Add Anki Sync
+-- Description: Per-user update sequence numbers (USN) for the Anki sync protocol, the Anki IDs of synced objects,
+-- and deletion markers (graves) for cards, decks and filtered decks
+
+-- Collection state of each user, as seen by Anki clients
+CREATE TABLE sync_state (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    usn INTEGER NOT NULL DEFAULT 0,
+    modified_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    schema_modified_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    collection_created_at BIGINT,
+    config_json JSONB,
+    last_sync_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_sync_state_updated_at BEFORE UPDATE ON sync_state
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Anki identifies objects by millisecond timestamps; every synced object keeps the ID it has in the clients
+-- Decks and filtered decks share Anki's deck IDs
+CREATE TABLE anki_sync_ids (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    object_type VARCHAR(50) NOT NULL,
+    object_id BIGINT NOT NULL,
+    anki_id BIGINT NOT NULL,
+    anki_guid VARCHAR(255),
+    anki_table VARCHAR(50) GENERATED ALWAYS AS (CASE WHEN object_type = 'filtered_deck' THEN 'deck' ELSE object_type END) STORED,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, anki_table, anki_id),
+    CONSTRAINT unique_anki_sync_object UNIQUE (object_type, object_id),
+    CONSTRAINT check_anki_sync_object_type CHECK (object_type IN ('note', 'card', 'deck', 'filtered_deck', 'note_type', 'deck_options_preset', 'review'))
+);
+
+-- Update sequence numbers: objects changed since a client's last sync have a USN at or above the client's
+ALTER TABLE notes ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE cards ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE decks ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE note_types ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE reviews ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE deck_options_presets ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE filtered_decks ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE deletions_log ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_notes_user_usn ON notes(user_id, usn);
+CREATE INDEX idx_cards_deck_usn ON cards(deck_id, usn);
+CREATE INDEX idx_decks_user_usn ON decks(user_id, usn);
+CREATE INDEX idx_note_types_user_usn ON note_types(user_id, usn);
+CREATE INDEX idx_reviews_card_usn ON reviews(card_id, usn);
+CREATE INDEX idx_deck_options_presets_user_usn ON deck_options_presets(user_id, usn);
+CREATE INDEX idx_filtered_decks_user_usn ON filtered_decks(user_id, usn);
+CREATE INDEX idx_deletions_log_user_usn ON deletions_log(user_id, usn);
+
+-- Filtered decks are logged when deleted, like decks
+ALTER TABLE deletions_log DROP CONSTRAINT IF EXISTS check_object_type;
+ALTER TABLE deletions_log ADD CONSTRAINT check_object_type CHECK (object_type IN ('note', 'card', 'deck', 'note_type', 'filtered_deck'));
+
+-- Anki keeps review log entries as recorded, including answers logged without an interval
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS check_interval_valid;
+
+-- Marks the user's collection as modified and returns its current USN
+-- The state row is created on first use; users being deleted get no row
+CREATE OR REPLACE FUNCTION touch_sync_state(p_user_id BIGINT)
+RETURNS INTEGER AS $$
+DECLARE
+    current_usn INTEGER;
+BEGIN
+    UPDATE sync_state SET modified_at = CURRENT_TIMESTAMP
+    WHERE user_id = p_user_id AND modified_at <> CURRENT_TIMESTAMP;
+
+    SELECT usn INTO current_usn FROM sync_state WHERE user_id = p_user_id;
+    IF current_usn IS NULL THEN
+        INSERT INTO sync_state (user_id)
+        SELECT id FROM users WHERE id = p_user_id
+        ON CONFLICT (user_id) DO NOTHING;
+        SELECT usn INTO current_usn FROM sync_state WHERE user_id = p_user_id;
+    END IF;
+    RETURN COALESCE(current_usn, 0);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Stamps inserted and updated rows with the owner's current USN
+-- Cards and reviews have no user_id; their owner is the user of the card's deck
+CREATE OR REPLACE FUNCTION set_sync_usn()
+RETURNS TRIGGER AS $$
+DECLARE
+    owner_id BIGINT;
+BEGIN
+    IF TG_TABLE_NAME = 'cards' THEN
+        SELECT user_id INTO owner_id FROM decks WHERE id = NEW.deck_id;
+    ELSIF TG_TABLE_NAME = 'reviews' THEN
+        SELECT d.user_id INTO owner_id FROM cards c JOIN decks d ON d.id = c.deck_id WHERE c.id = NEW.card_id;
+    ELSE
+        owner_id := NEW.user_id;
+    END IF;
+
+    IF owner_id IS NOT NULL THEN
+        NEW.usn := touch_sync_state(owner_id);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_notes_usn BEFORE INSERT OR UPDATE ON notes
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+CREATE TRIGGER set_cards_usn BEFORE INSERT OR UPDATE ON cards
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+CREATE TRIGGER set_decks_usn BEFORE INSERT OR UPDATE ON decks
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+CREATE TRIGGER set_note_types_usn BEFORE INSERT OR UPDATE ON note_types
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+CREATE TRIGGER set_reviews_usn BEFORE INSERT OR UPDATE ON reviews
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+CREATE TRIGGER set_deck_options_presets_usn BEFORE INSERT OR UPDATE ON deck_options_presets
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+CREATE TRIGGER set_filtered_decks_usn BEFORE INSERT OR UPDATE ON filtered_decks
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+CREATE TRIGGER set_deletions_log_usn BEFORE INSERT OR UPDATE ON deletions_log
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+
+-- Logs card deletions; cards are deleted for good, so only the ID is kept
+CREATE OR REPLACE FUNCTION log_card_deletion()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO deletions_log (user_id, object_type, object_id, object_data)
+    SELECT d.user_id, 'card', OLD.id, jsonb_build_object('note_id', OLD.note_id, 'deck_id', OLD.deck_id)
+    FROM decks d
+    JOIN users u ON u.id = d.user_id
+    WHERE d.id = OLD.deck_id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER log_cards_deletion AFTER DELETE ON cards
+    FOR EACH ROW EXECUTE FUNCTION log_card_deletion();
+
+-- Logs deck and filtered deck deletions
+CREATE OR REPLACE FUNCTION log_deck_deletion()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        INSERT INTO deletions_log (user_id, object_type, object_id, object_data)
+        VALUES (
+            OLD.user_id,
+            CASE WHEN TG_TABLE_NAME = 'filtered_decks' THEN 'filtered_deck' ELSE 'deck' END,
+            OLD.id,
+            jsonb_build_object('name', OLD.name)
+        );
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER log_decks_deletion BEFORE UPDATE ON decks
+    FOR EACH ROW EXECUTE FUNCTION log_deck_deletion();
+CREATE TRIGGER log_filtered_decks_deletion BEFORE UPDATE ON filtered_decks
+    FOR EACH ROW EXECUTE FUNCTION log_deck_deletion();
+
+-- Deleting note types and deck options presets, or changing the fields or card types of a note type,
+-- can't be merged into a client's collection: clients must do a full sync
+CREATE OR REPLACE FUNCTION mark_sync_schema_modified()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL)
+        OR (TG_TABLE_NAME = 'note_types' AND (
+            jsonb_array_length(CASE WHEN jsonb_typeof(OLD.fields_json) = 'array' THEN OLD.fields_json ELSE '[]'::jsonb END)
+                <> jsonb_array_length(CASE WHEN jsonb_typeof(NEW.fields_json) = 'array' THEN NEW.fields_json ELSE '[]'::jsonb END)
+            OR jsonb_array_length(CASE WHEN jsonb_typeof(OLD.card_types_json) = 'array' THEN OLD.card_types_json ELSE '[]'::jsonb END)
+                <> jsonb_array_length(CASE WHEN jsonb_typeof(NEW.card_types_json) = 'array' THEN NEW.card_types_json ELSE '[]'::jsonb END)
+        ))
+    THEN
+        UPDATE sync_state SET schema_modified_at = CURRENT_TIMESTAMP WHERE user_id = NEW.user_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER mark_note_types_schema_modified BEFORE UPDATE ON note_types
+    FOR EACH ROW EXECUTE FUNCTION mark_sync_schema_modified();
+CREATE TRIGGER mark_deck_options_presets_schema_modified BEFORE UPDATE ON deck_options_presets
+    FOR EACH ROW EXECUTE FUNCTION mark_sync_schema_modified();
+
+COMMENT ON TABLE sync_state IS 'Anki sync state of each user collection';
+COMMENT ON COLUMN sync_state.usn IS 'Update sequence number given to changes until the next sync';
+COMMENT ON COLUMN sync_state.schema_modified_at IS 'Last change requiring a full sync (scm)';
+COMMENT ON COLUMN sync_state.collection_created_at IS 'Collection creation in seconds (crt), day 0 of review due numbers';
+COMMENT ON COLUMN sync_state.config_json IS 'Collection configuration of the Anki clients';
+COMMENT ON TABLE anki_sync_ids IS 'Anki IDs of synced notes, cards, decks, note types, deck options and review log entries';
+COMMENT ON COLUMN anki_sync_ids.anki_guid IS 'Anki GUID of a note added by a client';
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS log_media_deletion ON media;
+DROP TRIGGER IF EXISTS log_note_types_deletion ON note_types;
+DROP FUNCTION IF EXISTS log_soft_deletion();
+
+DELETE FROM deletions_log WHERE object_type = 'media';
+ALTER TABLE deletions_log DROP CONSTRAINT IF EXISTS check_object_type;
+ALTER TABLE deletions_log ADD CONSTRAINT check_object_type CHECK (object_type IN ('note', 'card', 'deck', 'note_type', 'filtered_deck'));
+
+DROP TRIGGER IF EXISTS set_media_usn ON media;
+ALTER TABLE media DROP COLUMN IF EXISTS usn;
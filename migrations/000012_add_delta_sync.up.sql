@@ -0,0 +1,37 @@
+-- Migration: Add Delta Sync
+-- Description: Update sequence numbers (USN) on media and deletion markers for note types and media,
+-- so clients can fetch every change since their last sync
+
+ALTER TABLE media ADD COLUMN usn INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_media_user_usn ON media(user_id, usn);
+
+CREATE TRIGGER set_media_usn BEFORE INSERT OR UPDATE ON media
+    FOR EACH ROW EXECUTE FUNCTION set_sync_usn();
+
+ALTER TABLE deletions_log DROP CONSTRAINT IF EXISTS check_object_type;
+ALTER TABLE deletions_log ADD CONSTRAINT check_object_type CHECK (object_type IN ('note', 'card', 'deck', 'note_type', 'filtered_deck', 'media'));
+
+-- Logs note type and media deletions
+CREATE OR REPLACE FUNCTION log_soft_deletion()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        IF TG_TABLE_NAME = 'media' THEN
+            INSERT INTO deletions_log (user_id, object_type, object_id, object_data)
+            VALUES (OLD.user_id, 'media', OLD.id, jsonb_build_object('filename', OLD.filename, 'hash', OLD.hash));
+        ELSE
+            INSERT INTO deletions_log (user_id, object_type, object_id, object_data)
+            VALUES (OLD.user_id, 'note_type', OLD.id, jsonb_build_object('name', OLD.name));
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER log_note_types_deletion BEFORE UPDATE ON note_types
+    FOR EACH ROW EXECUTE FUNCTION log_soft_deletion();
+CREATE TRIGGER log_media_deletion BEFORE UPDATE ON media
+    FOR EACH ROW EXECUTE FUNCTION log_soft_deletion();
+
+COMMENT ON COLUMN media.usn IS 'Update sequence number of the last change';
@@ -0,0 +1,6 @@
+UPDATE cards SET home_deck_id = NULL
+WHERE id IN (SELECT card_id FROM filtered_deck_cards) OR home_deck_id = deck_id;
+
+DROP TABLE IF EXISTS filtered_deck_cards;
+
+ALTER TABLE cards ADD CONSTRAINT check_home_deck CHECK (home_deck_id IS NULL OR home_deck_id != deck_id);
@@ -0,0 +1,18 @@
+-- Migration: Add Filtered Deck Cards
+-- Description: Cards gathered into filtered decks. Cards must reference a deck, so a card in a filtered deck
+-- keeps its deck_id and records it in home_deck_id, and filtered_deck_cards holds its place in the filtered deck
+
+-- home_deck_id now equals deck_id while the card is in a filtered deck
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS check_home_deck;
+
+CREATE TABLE filtered_deck_cards (
+    card_id BIGINT PRIMARY KEY REFERENCES cards(id) ON DELETE CASCADE,
+    filtered_deck_id BIGINT NOT NULL REFERENCES filtered_decks(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_filtered_deck_cards_deck_position ON filtered_deck_cards(filtered_deck_id, position);
+
+COMMENT ON TABLE filtered_deck_cards IS 'Cards currently in a filtered deck, at most one filtered deck per card';
+COMMENT ON COLUMN filtered_deck_cards.position IS 'Order of the card in the filtered deck, set when the deck is built';
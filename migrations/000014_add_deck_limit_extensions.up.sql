@@ -0,0 +1,12 @@
+-- Migration: Add Deck Limit Extensions
+-- Description: Extra new and review cards allowed today in a deck, set by custom study
+
+CREATE TABLE deck_limit_extensions (
+    deck_id BIGINT PRIMARY KEY REFERENCES decks(id) ON DELETE CASCADE,
+    day_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    new_cards INTEGER NOT NULL DEFAULT 0,
+    reviews INTEGER NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE deck_limit_extensions IS 'Daily limit extensions from custom study, at most one per deck';
+COMMENT ON COLUMN deck_limit_extensions.day_start IS 'Start of the study day the extension applies to; older extensions are ignored';
@@ -0,0 +1,9 @@
+-- Migration: Add Cards Buried At
+-- Description: Time a card was buried, used to unbury it when the user's study day rolls over
+
+ALTER TABLE cards ADD COLUMN buried_at TIMESTAMP WITH TIME ZONE;
+
+-- Cards buried before this migration keep their last update as the bury time
+UPDATE cards SET buried_at = updated_at WHERE buried = TRUE;
+
+COMMENT ON COLUMN cards.buried_at IS 'When the card was buried; NULL when the card is not buried';
@@ -0,0 +1,14 @@
+-- Migration: Add Media Unique Active Hash
+-- Description: Identical content is stored once per user. The original constraint compares deleted_at too,
+-- and NULLs are distinct, so concurrent uploads of the same file could both be recorded
+
+-- Keep the oldest of the active media sharing a hash
+UPDATE media m
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE m.deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM media older
+    WHERE older.user_id = m.user_id AND older.hash = m.hash
+      AND older.deleted_at IS NULL AND older.id < m.id
+);
+
+CREATE UNIQUE INDEX idx_media_user_hash_active ON media(user_id, hash) WHERE deleted_at IS NULL;
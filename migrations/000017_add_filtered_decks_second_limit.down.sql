@@ -0,0 +1 @@
+ALTER TABLE filtered_decks DROP COLUMN IF EXISTS second_limit_cards;
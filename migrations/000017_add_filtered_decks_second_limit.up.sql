@@ -0,0 +1,11 @@
+-- Migration: Add Filtered Decks Second Limit
+-- Description: The second search of a filtered deck gathers up to its own number of cards
+
+ALTER TABLE filtered_decks ADD COLUMN second_limit_cards INTEGER NOT NULL DEFAULT 20;
+
+-- Existing second searches keep sharing the deck's limit
+UPDATE filtered_decks SET second_limit_cards = limit_cards;
+
+ALTER TABLE filtered_decks ADD CONSTRAINT check_second_limit_positive CHECK (second_limit_cards > 0);
+
+COMMENT ON COLUMN filtered_decks.second_limit_cards IS 'Maximum number of cards gathered by the second search';
@@ -21,7 +21,7 @@ var (
 // Claims represents JWT claims structure
 type Claims struct {
 	UserID int64  `json:"user_id"`
-	Type   string `json:"type"` // "access", "refresh", "email_verification", or "password_reset"
+	Type   string `json:"type"` // "access", "refresh", "email_verification", "password_reset", or "sync"
 	jwt.RegisteredClaims
 }
 
@@ -183,6 +183,33 @@ func (s *JWTService) ValidatePasswordResetToken(tokenString string) (*Claims, er
 	return claims, nil
 }
 
+// GenerateSyncHostKey generates the host key Anki clients send with every sync request
+// Clients keep the key until the user logs out, so it expires after a year
+func (s *JWTService) GenerateSyncHostKey(userID int64) (string, error) {
+	return s.generateToken(userID, "sync", s.GetSyncHostKeyExpiry())
+}
+
+// GetSyncHostKeyExpiry returns the sync host key expiry duration
+func (s *JWTService) GetSyncHostKeyExpiry() time.Duration {
+	return 365 * 24 * time.Hour
+}
+
+// ValidateSyncHostKey validates a sync host key
+// Returns an error if the key is invalid, expired, or not of type "sync"
+func (s *JWTService) ValidateSyncHostKey(tokenString string) (*Claims, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify that this is a sync host key
+	if claims.Type != "sync" {
+		return nil, fmt.Errorf("%w: token is not a sync host key", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
 // ValidateAccessToken validates an access token
 // Returns an error if the token is invalid, expired, or not of type "access"
 func (s *JWTService) ValidateAccessToken(tokenString string) (*Claims, error) {
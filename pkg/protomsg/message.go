@@ -0,0 +1,116 @@
+package protomsg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Message holds the decoded top-level fields of a protobuf message
+// Anki stores note type, template, deck and deck options configs as protobuf blobs; only the few fields
+// the callers need are read, so the messages are decoded from the wire format instead of generated types
+type Message map[protowire.Number][]Value
+
+// Value is a varint, 32-bit or length-delimited field value; 64-bit and group values are skipped
+type Value struct {
+	varint  uint64
+	fixed32 uint32
+	bytes   []byte
+}
+
+// Decode decodes the top-level fields of a protobuf message
+func Decode(data []byte) (Message, error) {
+	msg := make(Message)
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid protobuf: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var value Value
+		switch typ {
+		case protowire.VarintType:
+			value.varint, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed32Type:
+			value.fixed32, n = protowire.ConsumeFixed32(data)
+		case protowire.BytesType:
+			value.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("invalid protobuf: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		msg[num] = append(msg[num], value)
+	}
+	return msg, nil
+}
+
+// Has reports whether the field is present
+func (m Message) Has(num protowire.Number) bool {
+	return len(m[num]) > 0
+}
+
+// Uint returns the last value of a varint field, 0 when absent
+func (m Message) Uint(num protowire.Number) uint64 {
+	values := m[num]
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1].varint
+}
+
+// Int returns the last value of an int64 field, 0 when absent
+func (m Message) Int(num protowire.Number) int64 {
+	return int64(m.Uint(num))
+}
+
+// Str returns the last value of a string field, "" when absent
+func (m Message) Str(num protowire.Number) string {
+	values := m[num]
+	if len(values) == 0 {
+		return ""
+	}
+	return string(values[len(values)-1].bytes)
+}
+
+// Float returns the last value of a float field, 0 when absent
+func (m Message) Float(num protowire.Number) float64 {
+	values := m[num]
+	if len(values) == 0 {
+		return 0
+	}
+	return float64(math.Float32frombits(values[len(values)-1].fixed32))
+}
+
+// Floats returns the values of a repeated float field, packed or not
+func (m Message) Floats(num protowire.Number) []float64 {
+	floats := []float64{}
+	for _, value := range m[num] {
+		if value.bytes == nil {
+			floats = append(floats, float64(math.Float32frombits(value.fixed32)))
+			continue
+		}
+		for packed := value.bytes; len(packed) >= 4; packed = packed[4:] {
+			floats = append(floats, float64(math.Float32frombits(binary.LittleEndian.Uint32(packed))))
+		}
+	}
+	return floats
+}
+
+// Messages decodes every value of a repeated message field
+func (m Message) Messages(num protowire.Number) ([]Message, error) {
+	messages := make([]Message, 0, len(m[num]))
+	for _, value := range m[num] {
+		msg, err := Decode(value.bytes)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
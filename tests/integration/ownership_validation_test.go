@@ -286,18 +286,8 @@ func TestOwnership_Validation(t *testing.T) {
 
 	// --- Media Isolation ---
 	t.Run("Media Isolation", func(t *testing.T) {
-		// User A creates media
-		mediaReq := request.CreateMediaRequest{
-			Filename:    "test.png",
-			Hash:        "hashA",
-			Size:        100,
-			MimeType:    "image/png",
-			StoragePath: "/path/a",
-		}
-		b, _ := json.Marshal(mediaReq)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/media", bytes.NewReader(b))
-		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-		req.Header.Set(echo.HeaderAuthorization, "Bearer "+userA.AccessToken)
+		// User A uploads media
+		req := newMediaUploadRequest(t, "test.png", []byte("\x89PNG\r\n\x1a\n user A"), userA.AccessToken)
 		rec := httptest.NewRecorder()
 		e.ServeHTTP(rec, req)
 		require.Equal(t, http.StatusCreated, rec.Code)
@@ -538,3 +538,87 @@ func TestCardRepository_CountByDeckAndState(t *testing.T) {
 	assert.Equal(t, 0, learnCount)
 }
 
+func TestCardRepository_UnburyBeforeDayStart(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userRepo := repositories.NewUserRepository(db.DB)
+	deckRepo := repositories.NewDeckRepository(db.DB)
+	noteRepo := repositories.NewNoteRepository(db.DB)
+	noteTypeRepo := repositories.NewNoteTypeRepository(db.DB)
+	cardRepo := repositories.NewCardRepository(db.DB)
+
+	userID, _ := createTestUser(t, ctx, userRepo, "card_unbury")
+
+	deckID, err := deckRepo.CreateDefaultDeck(ctx, userID)
+	require.NoError(t, err)
+
+	noteType, err := notetype.NewBuilder().
+		WithID(0).
+		WithUserID(userID).
+		WithName("Basic").
+		WithFieldsJSON(`[{"name":"Front"}]`).
+		WithCardTypesJSON(`[{"name":"Card 1"}]`).
+		WithTemplatesJSON(`[{"qfmt":"{{Front}}","afmt":"{{Back}}"}]`).
+		WithCreatedAt(time.Now()).
+		WithUpdatedAt(time.Now()).
+		Build()
+	require.NoError(t, err)
+	err = noteTypeRepo.Save(ctx, userID, noteType)
+	require.NoError(t, err)
+
+	guid, err := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440016")
+	require.NoError(t, err)
+
+	noteEntity, err := note.NewBuilder().
+		WithID(0).
+		WithUserID(userID).
+		WithGUID(guid).
+		WithNoteTypeID(noteType.GetID()).
+		WithFieldsJSON(`{"Front":"Test"}`).
+		WithTags([]string{}).
+		WithCreatedAt(time.Now()).
+		WithUpdatedAt(time.Now()).
+		Build()
+	require.NoError(t, err)
+	err = noteRepo.Save(ctx, userID, noteEntity)
+	require.NoError(t, err)
+
+	cardEntity, err := card.NewBuilder().
+		WithID(0).
+		WithNoteID(noteEntity.GetID()).
+		WithCardTypeID(1).
+		WithDeckID(deckID).
+		WithDue(time.Now().Unix() * 1000).
+		WithState(valueobjects.CardStateNew).
+		WithBuried(true).
+		WithCreatedAt(time.Now()).
+		WithUpdatedAt(time.Now()).
+		Build()
+	require.NoError(t, err)
+	err = cardRepo.Save(ctx, userID, cardEntity)
+	require.NoError(t, err)
+	cardID := cardEntity.GetID()
+
+	// A card buried today stays buried
+	count, err := cardRepo.UnburyBeforeDayStart(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// A card buried two days ago is unburied even if it was changed today
+	_, err = db.DB.Exec(`UPDATE cards SET buried_at = NOW() - INTERVAL '2 days' WHERE id = $1`, cardID)
+	require.NoError(t, err)
+	require.NoError(t, cardEntity.SetFlag(2))
+	err = cardRepo.Update(ctx, userID, cardID, cardEntity)
+	require.NoError(t, err)
+
+	count, err = cardRepo.UnburyBeforeDayStart(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	unburied, err := cardRepo.FindByID(ctx, userID, cardID)
+	require.NoError(t, err)
+	assert.False(t, unburied.GetBuried())
+	assert.Equal(t, 2, unburied.GetFlag())
+}
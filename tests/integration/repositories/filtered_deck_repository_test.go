@@ -71,5 +71,6 @@ func TestFilteredDeckRepository_FindByID(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, found)
 	assert.Equal(t, filteredDeckID, found.GetID())
+	assert.Equal(t, 10, found.GetSecondLimitCards())
 }
 
@@ -18,6 +18,7 @@ import (
 	"github.com/felipesantos/anki-backend/core/domain/services"
 	searchdomain "github.com/felipesantos/anki-backend/core/domain/services/search"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	auditService "github.com/felipesantos/anki-backend/core/services/audit"
 	noteService "github.com/felipesantos/anki-backend/core/services/note"
 	"github.com/felipesantos/anki-backend/infra/database/repositories"
 	"github.com/felipesantos/anki-backend/pkg/database"
@@ -376,6 +377,9 @@ func TestNoteRepository_Exists(t *testing.T) {
 	assert.False(t, exists)
 }
 
+// searchPage is the first page of 100 search results, newest first
+var searchPage = searchdomain.PageRequest{Sort: searchdomain.DefaultSortOrder, Limit: 100}
+
 func TestNoteRepository_FindByAdvancedSearch_Regex(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -441,7 +445,7 @@ func TestNoteRepository_FindByAdvancedSearch_Regex(t *testing.T) {
 		query, err := parser.Parse("re:hello.*world")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.Len(t, notes, 1)
 		assert.Contains(t, notes[0].GetFieldsJSON(), "hello world")
@@ -451,7 +455,7 @@ func TestNoteRepository_FindByAdvancedSearch_Regex(t *testing.T) {
 		query, err := parser.Parse("front:re:[a-c]1")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.Len(t, notes, 3) // Should match a1, b1, c1
 		for _, n := range notes {
@@ -468,7 +472,7 @@ func TestNoteRepository_FindByAdvancedSearch_Regex(t *testing.T) {
 		query, err := parser.Parse("back:re:\\d{3}")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.Len(t, notes, 2) // Should match 123 and 456
 		for _, n := range notes {
@@ -484,7 +488,7 @@ func TestNoteRepository_FindByAdvancedSearch_Regex(t *testing.T) {
 		query, err := parser.Parse("re:[invalid")
 		require.NoError(t, err) // Parser should accept it
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid regex pattern")
 		assert.Nil(t, notes)
@@ -556,7 +560,7 @@ func TestNoteRepository_FindByAdvancedSearch_NoCombining(t *testing.T) {
 		query, err := parser.Parse("nc:cafe")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, len(notes), 1, "Should find at least one note with 'café'")
 		found := false
@@ -573,7 +577,7 @@ func TestNoteRepository_FindByAdvancedSearch_NoCombining(t *testing.T) {
 		query, err := parser.Parse("front:nc:acao")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, len(notes), 1, "Should find at least one note with 'ação' in Front")
 		found := false
@@ -590,7 +594,7 @@ func TestNoteRepository_FindByAdvancedSearch_NoCombining(t *testing.T) {
 		query, err := parser.Parse("back:nc:coffee")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, len(notes), 1, "Should find at least one note with 'coffee' in Back")
 	})
@@ -599,7 +603,7 @@ func TestNoteRepository_FindByAdvancedSearch_NoCombining(t *testing.T) {
 		query, err := parser.Parse("nc:uber*")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, len(notes), 1, "Should find at least one note with 'über'")
 		found := false
@@ -616,7 +620,7 @@ func TestNoteRepository_FindByAdvancedSearch_NoCombining(t *testing.T) {
 		query, err := parser.Parse(`nc:"Sao Paulo"`)
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, len(notes), 1, "Should find at least one note with 'São Paulo'")
 		found := false
@@ -653,7 +657,7 @@ func TestNoteRepository_FindByAdvancedSearch_NoCombining(t *testing.T) {
 		query, err := parser.Parse("nc:resume tag:vocabulary")
 		require.NoError(t, err)
 
-		notes, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, 100, 0)
+		notes, _, err := noteRepo.FindByAdvancedSearch(ctx, userID, query, searchPage)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, len(notes), 1, "Should find note with 'résumé' and tag 'vocabulary'")
 		found := false
@@ -704,7 +708,8 @@ func TestNoteService_Copy(t *testing.T) {
 	// Create original note with service
 	tm := database.NewTransactionManager(db.DB)
 	tr := services.NewTemplateRenderer()
-	service := noteService.NewNoteService(noteRepo, cardRepo, noteTypeRepo, deckRepo, tr, tm)
+	undoService := auditService.NewUndoHistoryService(repositories.NewUndoHistoryRepository(db.DB), noteRepo, cardRepo, deckRepo, tm)
+	service := noteService.NewNoteService(noteRepo, cardRepo, noteTypeRepo, deckRepo, tr, undoService, tm)
 	originalNote, err := service.Create(ctx, userID, noteTypeID, deckID, `{"Front":"Original Question","Back":"Original Answer"}`, []string{"tag1", "tag2"})
 	require.NoError(t, err)
 	originalNoteID := originalNote.GetID()
@@ -897,7 +902,8 @@ func TestNoteRepository_FindDuplicatesByField(t *testing.T) {
 	// Create service for creating notes
 	tm := database.NewTransactionManager(db.DB)
 	tr := services.NewTemplateRenderer()
-	service := noteService.NewNoteService(noteRepo, cardRepo, noteTypeRepo, deckRepo, tr, tm)
+	undoService := auditService.NewUndoHistoryService(repositories.NewUndoHistoryRepository(db.DB), noteRepo, cardRepo, deckRepo, tm)
+	service := noteService.NewNoteService(noteRepo, cardRepo, noteTypeRepo, deckRepo, tr, undoService, tm)
 
 	// Create duplicate notes (3 notes with "Hello" in Front field)
 	note1, err := service.Create(ctx, userID, noteTypeID, deckID, `{"Front":"Hello","Back":"World1"}`, []string{})
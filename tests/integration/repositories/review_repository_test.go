@@ -305,3 +305,98 @@ func TestReviewRepository_FindByCardID(t *testing.T) {
 	assert.Len(t, reviews, 2)
 }
 
+
+func TestReviewRepository_CountStudiedSince(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userRepo := repositories.NewUserRepository(db.DB)
+	deckRepo := repositories.NewDeckRepository(db.DB)
+	noteRepo := repositories.NewNoteRepository(db.DB)
+	noteTypeRepo := repositories.NewNoteTypeRepository(db.DB)
+	cardRepo := repositories.NewCardRepository(db.DB)
+	reviewRepo := repositories.NewReviewRepository(db.DB)
+
+	userID, _ := createTestUser(t, ctx, userRepo, "review_count_studied")
+
+	deckID, err := deckRepo.CreateDefaultDeck(ctx, userID)
+	require.NoError(t, err)
+
+	noteType, err := notetype.NewBuilder().
+		WithID(0).
+		WithUserID(userID).
+		WithName("Basic").
+		WithFieldsJSON(`[{"name":"Front"}]`).
+		WithCardTypesJSON(`[{"name":"Card 1"}]`).
+		WithTemplatesJSON(`[{"qfmt":"{{Front}}","afmt":"{{Back}}"}]`).
+		WithCreatedAt(time.Now()).
+		WithUpdatedAt(time.Now()).
+		Build()
+	require.NoError(t, err)
+	err = noteTypeRepo.Save(ctx, userID, noteType)
+	require.NoError(t, err)
+
+	guid, err := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440023")
+	require.NoError(t, err)
+
+	noteEntity, err := note.NewBuilder().
+		WithID(0).
+		WithUserID(userID).
+		WithGUID(guid).
+		WithNoteTypeID(noteType.GetID()).
+		WithFieldsJSON(`{"Front":"Test"}`).
+		WithTags([]string{}).
+		WithCreatedAt(time.Now()).
+		WithUpdatedAt(time.Now()).
+		Build()
+	require.NoError(t, err)
+	err = noteRepo.Save(ctx, userID, noteEntity)
+	require.NoError(t, err)
+
+	newCard := func() int64 {
+		cardEntity, err := card.NewBuilder().
+			WithID(0).
+			WithNoteID(noteEntity.GetID()).
+			WithCardTypeID(1).
+			WithDeckID(deckID).
+			WithDue(time.Now().Unix() * 1000).
+			WithState(valueobjects.CardStateNew).
+			WithCreatedAt(time.Now()).
+			WithUpdatedAt(time.Now()).
+			Build()
+		require.NoError(t, err)
+		require.NoError(t, cardRepo.Save(ctx, userID, cardEntity))
+		return cardEntity.GetID()
+	}
+	saveReview := func(cardID int64, reviewType valueobjects.ReviewType, rating int, createdAt time.Time) {
+		reviewEntity, err := review.NewBuilder().
+			WithID(0).
+			WithCardID(cardID).
+			WithRating(rating).
+			WithTimeMs(5000).
+			WithType(reviewType).
+			WithCreatedAt(createdAt).
+			Build()
+		require.NoError(t, err)
+		require.NoError(t, reviewRepo.Save(ctx, userID, reviewEntity))
+	}
+
+	since := time.Now().Add(-time.Hour)
+	// Rescheduled yesterday and answered for the first time today: introduced today
+	rescheduled := newCard()
+	saveReview(rescheduled, valueobjects.ReviewTypeManual, 0, since.Add(-24*time.Hour))
+	saveReview(rescheduled, valueobjects.ReviewTypeLearn, 3, since.Add(time.Minute))
+	// Only reset today: not introduced
+	reset := newCard()
+	saveReview(reset, valueobjects.ReviewTypeManual, 0, since.Add(time.Minute))
+	// Answered yesterday: reviewed today, not introduced
+	seen := newCard()
+	saveReview(seen, valueobjects.ReviewTypeLearn, 3, since.Add(-24*time.Hour))
+	saveReview(seen, valueobjects.ReviewTypeReview, 3, since.Add(time.Minute))
+
+	introduced, reviewed, err := reviewRepo.CountStudiedSince(ctx, userID, []int64{deckID}, since)
+	require.NoError(t, err)
+	assert.Equal(t, 1, introduced)
+	assert.Equal(t, 1, reviewed)
+}
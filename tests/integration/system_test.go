@@ -3,6 +3,7 @@ package integration
 import (
 	"bytes"
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -165,25 +166,15 @@ func TestSystem_Integration(t *testing.T) {
 	})
 
 	t.Run("Media", func(t *testing.T) {
-		// Create Media
-		createReq := request.CreateMediaRequest{
-			Filename:    "test_image.jpg",
-			Hash:        "abc123hash",
-			Size:        1024,
-			MimeType:    "image/jpeg",
-			StoragePath: "/storage/test_image.jpg",
-		}
-		b, _ := json.Marshal(createReq)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/media", bytes.NewReader(b))
-		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-		req.Header.Set("Authorization", "Bearer "+token)
+		// Upload Media
+		req := newMediaUploadRequest(t, "test_image.jpg", []byte("\xff\xd8\xff\xe0 jpeg data"), token)
 		rec := httptest.NewRecorder()
 		e.ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusCreated, rec.Code)
 		var mediaRes response.MediaResponse
 		json.Unmarshal(rec.Body.Bytes(), &mediaRes)
-		assert.Equal(t, createReq.Filename, mediaRes.Filename)
+		assert.Equal(t, "test_image.jpg", mediaRes.Filename)
 		mediaID := mediaRes.ID
 
 		// Find All Media
@@ -205,7 +196,7 @@ func TestSystem_Integration(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, rec.Code)
 		json.Unmarshal(rec.Body.Bytes(), &mediaRes)
-		assert.Equal(t, createReq.Filename, mediaRes.Filename)
+		assert.Equal(t, "test_image.jpg", mediaRes.Filename)
 
 		// Delete Media
 		req = httptest.NewRequest(http.MethodDelete, "/api/v1/media/"+strconv.FormatInt(mediaID, 10), nil)
@@ -246,3 +237,19 @@ func TestSystem_Integration(t *testing.T) {
 	})
 }
 
+
+// newMediaUploadRequest builds a multipart POST /api/v1/media request uploading content as filename
+func newMediaUploadRequest(t *testing.T, filename string, content []byte, token string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/media", &body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	return req
+}
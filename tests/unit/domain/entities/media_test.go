@@ -95,3 +95,40 @@ func TestMedia_GetFileExtension(t *testing.T) {
 }
 
 
+
+func TestReferencedFilenames(t *testing.T) {
+	field := `<img src="cat.jpg"> [sound:meow.mp3] <img src='a%20b.png'> <audio src=clip.ogg>` +
+		`<img src="https://example.com/x.png"> <img src="data:image/png;base64,AA"> <img src="tom&amp;jerry.gif">`
+
+	got := media.ReferencedFilenames(field)
+	want := []string{"cat.jpg", "a b.png", "clip.ogg", "tom&jerry.gif", "meow.mp3"}
+	if len(got) != len(want) {
+		t.Fatalf("ReferencedFilenames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ReferencedFilenames()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNormalizeFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		expected string
+	}{
+		{name: "already normalized", filename: "photo.jpg", expected: "photo.jpg"},
+		{name: "decomposed accent", filename: "cafe\u0301.png", expected: "caf\u00e9.png"},
+		{name: "illegal characters", filename: `a:b?c*.mp3`, expected: "abc.mp3"},
+		{name: "trailing dot and space", filename: "sound.mp3. ", expected: "sound.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := media.NormalizeFilename(tt.filename); got != tt.expected {
+				t.Errorf("NormalizeFilename() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
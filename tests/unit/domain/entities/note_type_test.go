@@ -133,6 +133,54 @@ func TestNoteType_GetCardTypeCount(t *testing.T) {
 	}
 }
 
+func TestNoteType_GetFieldNames(t *testing.T) {
+	nt := &notetype.NoteType{}
+	nt.SetFieldsJSON(`[{"name": "Front", "ord": 0}, {"name": "Back", "ord": 1}]`)
+	got := nt.GetFieldNames()
+	if strings.Join(got, ",") != "Front,Back" {
+		t.Errorf("NoteType.GetFieldNames() = %v, want [Front Back]", got)
+	}
+
+	nt.SetFieldsJSON("invalid json")
+	if got := nt.GetFieldNames(); got != nil {
+		t.Errorf("NoteType.GetFieldNames() = %v, want nil", got)
+	}
+}
+
+func TestNoteType_IsCloze(t *testing.T) {
+	tests := []struct {
+		name          string
+		cardTypesJSON string
+		expected      bool
+	}{
+		{
+			name:          "standard card types",
+			cardTypesJSON: `[{"name": "Forward", "ord": 0}, {"name": "Reverse", "ord": 1}]`,
+			expected:      false,
+		},
+		{
+			name:          "cloze card type",
+			cardTypesJSON: `[{"name": "Cloze", "ord": 0, "cloze": true}]`,
+			expected:      true,
+		},
+		{
+			name:          "invalid JSON",
+			cardTypesJSON: "invalid json",
+			expected:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nt := &notetype.NoteType{}
+			nt.SetCardTypesJSON(tt.cardTypesJSON)
+			if got := nt.IsCloze(); got != tt.expected {
+				t.Errorf("NoteType.IsCloze() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestNoteType_GetFirstFieldName(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -119,3 +119,21 @@ func TestReview_ReviewType(t *testing.T) {
 	}
 }
 
+
+func TestReviewBuilder_ManualRescheduleWithoutRating(t *testing.T) {
+	r, err := review.NewBuilder().
+		WithCardID(1).
+		WithRating(0).
+		WithType(valueobjects.ReviewTypeManual).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+	if r.GetRating() != 0 {
+		t.Errorf("Review.GetRating() = %v, want 0", r.GetRating())
+	}
+
+	if _, err := review.NewBuilder().WithCardID(1).WithRating(0).WithType(valueobjects.ReviewTypeReview).Build(); err == nil {
+		t.Error("Build() error = nil, want an invalid rating error for an answered review")
+	}
+}
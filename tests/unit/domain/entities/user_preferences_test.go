@@ -45,3 +45,70 @@ func TestUserPreferences_SetTheme(t *testing.T) {
 	}
 }
 
+
+func TestUserPreferences_DayStart(t *testing.T) {
+	prefs := &userpreferences.UserPreferences{}
+	prefs.SetNextDayStartsAt(time.Date(1970, 1, 1, 4, 0, 0, 0, time.UTC))
+
+	afterRollover := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	if got := prefs.DayStart(afterRollover); !got.Equal(time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("UserPreferences.DayStart() = %v, want 2024-03-10 04:00", got)
+	}
+
+	beforeRollover := time.Date(2024, 3, 10, 2, 0, 0, 0, time.UTC)
+	if got := prefs.DayStart(beforeRollover); !got.Equal(time.Date(2024, 3, 9, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("UserPreferences.DayStart() = %v, want 2024-03-09 04:00", got)
+	}
+	if got := prefs.NextDayStart(beforeRollover); !got.Equal(time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("UserPreferences.NextDayStart() = %v, want 2024-03-10 04:00", got)
+	}
+
+	// Nil preferences fall back to the defaults
+	var missing *userpreferences.UserPreferences
+	if got := missing.LearnAheadCutoff(afterRollover); !got.Equal(afterRollover.Add(20 * time.Minute)) {
+		t.Errorf("UserPreferences.LearnAheadCutoff() = %v, want now + 20m", got)
+	}
+}
+
+func TestUserPreferences_DayStartInTimezone(t *testing.T) {
+	prefs, err := userpreferences.NewBuilder().
+		WithUserID(1).
+		WithTheme(valueobjects.ThemeTypeAuto).
+		WithNextDayStartsAt(time.Date(1970, 1, 1, 4, 0, 0, 0, time.UTC)).
+		WithTimezone("America/Sao_Paulo").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	// 05:00 UTC is 02:00 in Sao Paulo (UTC-3), before the local rollover
+	now := time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)
+	if got := prefs.DayStart(now); !got.Equal(time.Date(2024, 3, 9, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("UserPreferences.DayStart() = %v, want 2024-03-09 07:00 UTC", got)
+	}
+
+	if _, err := userpreferences.NewBuilder().WithUserID(1).WithTimezone("Mars/Olympus").Build(); err == nil {
+		t.Errorf("UserPreferencesBuilder.WithTimezone() should reject unknown time zones")
+	}
+	if _, err := userpreferences.NewBuilder().WithUserID(1).WithTimezone("Local").Build(); err == nil {
+		t.Errorf("UserPreferencesBuilder.WithTimezone() should reject the server's Local time zone")
+	}
+}
+
+func TestDayStartIn(t *testing.T) {
+	now := time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)
+
+	if got := userpreferences.DayStartIn("America/Sao_Paulo", 4*time.Hour, now); !got.Equal(time.Date(2024, 3, 9, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("DayStartIn() = %v, want 2024-03-09 07:00 UTC", got)
+	}
+	if got := userpreferences.DayStartIn("", 4*time.Hour+30*time.Minute, now); !got.Equal(time.Date(2024, 3, 10, 4, 30, 0, 0, time.UTC)) {
+		t.Errorf("DayStartIn() = %v, want 2024-03-10 04:30 UTC", got)
+	}
+
+	// Time zones unknown here, or naming the server's zone, fall back to UTC
+	for _, timezone := range []string{"Mars/Olympus", "Local"} {
+		if got := userpreferences.DayStartIn(timezone, 4*time.Hour, now); !got.Equal(time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC)) {
+			t.Errorf("DayStartIn(%q) = %v, want 2024-03-10 04:00 UTC", timezone, got)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// simulateHistories generates review histories whose outcomes follow the given parameters
+func simulateHistories(weights []float64, cards, reviewsPerCard int, seed int64) []scheduler.FSRSHistory {
+	model := scheduler.NewFSRSModel(weights)
+	rng := rand.New(rand.NewSource(seed))
+
+	histories := make([]scheduler.FSRSHistory, 0, cards)
+	for i := 0; i < cards; i++ {
+		history := scheduler.FSRSHistory{{Rating: scheduler.RatingGood}}
+		state := model.InitialState(scheduler.RatingGood)
+		for j := 1; j < reviewsPerCard; j++ {
+			elapsed := float64(1 + rng.Intn(int(state.Stability*2)+2))
+			rating := scheduler.RatingGood
+			if rng.Float64() > model.Retrievability(elapsed, state.Stability) {
+				rating = scheduler.RatingAgain
+			}
+			history = append(history, scheduler.FSRSReview{Rating: rating, ElapsedDays: elapsed})
+			state = model.NextState(state, elapsed, rating)
+		}
+		histories = append(histories, history)
+	}
+	return histories
+}
+
+func TestBuildFSRSHistories(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	newReview := func(cardID int64, rating int, at time.Time, reviewType valueobjects.ReviewType) *review.Review {
+		r, err := review.NewBuilder().
+			WithCardID(cardID).
+			WithRating(rating).
+			WithEase(2500).
+			WithType(reviewType).
+			WithCreatedAt(at).
+			Build()
+		require.NoError(t, err)
+		return r
+	}
+
+	histories := scheduler.BuildFSRSHistories([]*review.Review{
+		newReview(1, 3, start.Add(72*time.Hour), valueobjects.ReviewTypeReview),
+		newReview(1, 3, start, valueobjects.ReviewTypeLearn),
+		newReview(1, 1, start.Add(30*time.Hour), valueobjects.ReviewTypeCram),
+		newReview(2, 3, start, valueobjects.ReviewTypeLearn),
+	})
+
+	require.Len(t, histories, 1, "single-review cards are dropped")
+	assert.Equal(t, scheduler.FSRSHistory{
+		{Rating: 3, ElapsedDays: 0},
+		{Rating: 3, ElapsedDays: 3},
+	}, histories[0], "cram reviews are ignored and reviews are ordered by time")
+}
+
+func TestEvaluateFSRS(t *testing.T) {
+	histories := simulateHistories(scheduler.DefaultFSRSWeights, 50, 6, 1)
+
+	metrics := scheduler.EvaluateFSRS(scheduler.DefaultFSRSWeights, histories)
+	assert.Greater(t, metrics.LogLoss, 0.0)
+	assert.GreaterOrEqual(t, metrics.RMSE, 0.0)
+	assert.Less(t, metrics.RMSE, 0.2)
+
+	empty := scheduler.EvaluateFSRS(scheduler.DefaultFSRSWeights, nil)
+	assert.Equal(t, scheduler.FSRSMetrics{}, empty)
+}
+
+func TestFSRSOptimizer_Optimize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Fitted parameters reduce log loss", func(t *testing.T) {
+		// Cards that are remembered far longer than the defaults predict
+		trueWeights := append([]float64(nil), scheduler.DefaultFSRSWeights...)
+		trueWeights[2] = 20
+		trueWeights[8] = 2.2
+		histories := simulateHistories(trueWeights, 150, 6, 7)
+
+		var reported []int
+		result, err := scheduler.NewFSRSOptimizer().WithIterations(60).
+			Optimize(ctx, histories, nil, func(percent int) { reported = append(reported, percent) })
+		require.NoError(t, err)
+
+		assert.Len(t, result.Weights, len(scheduler.DefaultFSRSWeights))
+		assert.Less(t, result.After.LogLoss, result.Before.LogLoss)
+		assert.Greater(t, result.Weights[2], scheduler.DefaultFSRSWeights[2])
+		assert.Equal(t, scheduler.CountFSRSTrainingItems(histories), result.ReviewCount)
+		require.NotEmpty(t, reported)
+		assert.Equal(t, 100, reported[len(reported)-1])
+	})
+
+	t.Run("Parameters stay within bounds", func(t *testing.T) {
+		histories := simulateHistories(scheduler.DefaultFSRSWeights, 40, 5, 3)
+		result, err := scheduler.NewFSRSOptimizer().WithIterations(20).Optimize(ctx, histories, nil, nil)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, result.Weights[0], result.Weights[1])
+		assert.LessOrEqual(t, result.Weights[1], result.Weights[2])
+		assert.LessOrEqual(t, result.Weights[2], result.Weights[3])
+		assert.LessOrEqual(t, result.Weights[7], 0.75)
+	})
+
+	t.Run("Empty initial parameters are compared against the defaults", func(t *testing.T) {
+		histories := simulateHistories(scheduler.DefaultFSRSWeights, 40, 5, 3)
+		result, err := scheduler.NewFSRSOptimizer().WithIterations(5).Optimize(ctx, histories, nil, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, scheduler.EvaluateFSRS(scheduler.DefaultFSRSWeights, histories), result.Before)
+		assert.LessOrEqual(t, result.After.LogLoss, result.Before.LogLoss)
+		if result.After == result.Before {
+			assert.Equal(t, scheduler.DefaultFSRSWeights, result.Weights, "a worse fit keeps the defaults")
+		}
+	})
+
+	t.Run("Not enough reviews", func(t *testing.T) {
+		histories := simulateHistories(scheduler.DefaultFSRSWeights, 2, 3, 1)
+		_, err := scheduler.NewFSRSOptimizer().Optimize(ctx, histories, nil, nil)
+		assert.ErrorIs(t, err, scheduler.ErrNotEnoughReviews)
+	})
+
+	t.Run("Cancelled context stops optimization", func(t *testing.T) {
+		histories := simulateHistories(scheduler.DefaultFSRSWeights, 40, 5, 3)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := scheduler.NewFSRSOptimizer().Optimize(cancelled, histories, nil, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fsrsOptions() *deck.DeckOptions {
+	opts := deck.DefaultDeckOptions()
+	opts.SchedulerType = valueobjects.SchedulerTypeFSRS
+	return opts
+}
+
+func TestFSRSModel(t *testing.T) {
+	model := scheduler.NewFSRSModel(nil)
+
+	t.Run("Retrievability is 90% after stability days", func(t *testing.T) {
+		assert.InDelta(t, 0.9, model.Retrievability(10, 10), 1e-9)
+		assert.InDelta(t, 1.0, model.Retrievability(0, 10), 1e-9)
+	})
+
+	t.Run("Interval equals stability at 90% retention", func(t *testing.T) {
+		assert.InDelta(t, 10, model.Interval(10, 0.9), 1e-9)
+		assert.Less(t, model.Interval(10, 0.95), 10.0)
+	})
+
+	t.Run("Initial state uses first four weights", func(t *testing.T) {
+		good := model.InitialState(scheduler.RatingGood)
+		assert.InDelta(t, scheduler.DefaultFSRSWeights[2], good.Stability, 1e-9)
+		again := model.InitialState(scheduler.RatingAgain)
+		assert.Greater(t, again.Difficulty, good.Difficulty)
+	})
+
+	t.Run("Recall increases and lapse decreases stability", func(t *testing.T) {
+		prev := scheduler.MemoryState{Stability: 10, Difficulty: 5}
+		recalled := model.NextState(prev, 10, scheduler.RatingGood)
+		forgotten := model.NextState(prev, 10, scheduler.RatingAgain)
+		assert.Greater(t, recalled.Stability, prev.Stability)
+		assert.Less(t, forgotten.Stability, prev.Stability)
+		assert.Greater(t, forgotten.Difficulty, prev.Difficulty)
+	})
+
+	t.Run("Unknown parameter count falls back to defaults", func(t *testing.T) {
+		assert.Equal(t, scheduler.DefaultFSRSWeights, scheduler.NewFSRSModel([]float64{1, 2}).Weights())
+	})
+
+	t.Run("Difficulty stored scale round trip", func(t *testing.T) {
+		assert.InDelta(t, 0.0, scheduler.DifficultyToStored(1), 1e-9)
+		assert.InDelta(t, 1.0, scheduler.DifficultyToStored(10), 1e-9)
+		assert.InDelta(t, 5.5, scheduler.DifficultyFromStored(0.5), 1e-9)
+	})
+}
+
+func TestFSRSScheduler_Schedule(t *testing.T) {
+	now := time.Date(2024, 1, 11, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Selected by scheduler_type", func(t *testing.T) {
+		_, isFSRS := scheduler.NewScheduler(fsrsOptions()).(*scheduler.FSRSScheduler)
+		assert.True(t, isFSRS)
+		_, isSM2 := scheduler.NewScheduler(deck.DefaultDeckOptions()).(*scheduler.SM2Scheduler)
+		assert.True(t, isSM2)
+	})
+
+	t.Run("New card gets memory state and follows learning steps", func(t *testing.T) {
+		s := scheduler.NewFSRSScheduler(fsrsOptions())
+		res, err := s.Schedule(scheduler.SchedulingState{CardID: 1, State: valueobjects.CardStateNew}, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		require.NotNil(t, res.Stability)
+		require.NotNil(t, res.Difficulty)
+		assert.InDelta(t, scheduler.DefaultFSRSWeights[2], *res.Stability, 1e-9)
+		assert.GreaterOrEqual(t, *res.Difficulty, 0.0)
+		assert.LessOrEqual(t, *res.Difficulty, 1.0)
+		assert.Equal(t, valueobjects.CardStateLearn, res.State)
+		assert.Equal(t, -600, res.LogInterval)
+	})
+
+	t.Run("Graduation uses stability-based interval", func(t *testing.T) {
+		opts := fsrsOptions()
+		opts.NewSteps = []float64{}
+		res, err := scheduler.NewFSRSScheduler(opts).Schedule(scheduler.SchedulingState{CardID: 1, State: valueobjects.CardStateNew}, scheduler.RatingEasy, now)
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.CardStateReview, res.State)
+		// Easy initial stability is ~15.7 days
+		assert.InDelta(t, 16, res.Interval, 3)
+	})
+
+	t.Run("Higher desired retention shortens review intervals", func(t *testing.T) {
+		last := now.Add(-20 * 24 * time.Hour)
+		stability, difficulty := 20.0, 0.5
+		state := scheduler.SchedulingState{
+			CardID: 7, State: valueobjects.CardStateReview, Interval: 20, Ease: 2500, Reps: 4,
+			Stability: &stability, Difficulty: &difficulty, LastReviewAt: &last,
+		}
+
+		low := fsrsOptions()
+		low.DesiredRetention = 0.8
+		high := fsrsOptions()
+		high.DesiredRetention = 0.97
+
+		lowRes, err := scheduler.NewFSRSScheduler(low).Schedule(state, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		highRes, err := scheduler.NewFSRSScheduler(high).Schedule(state, scheduler.RatingGood, now)
+		require.NoError(t, err)
+
+		assert.Greater(t, lowRes.Interval, highRes.Interval)
+		assert.Equal(t, 2500, lowRes.Ease)
+	})
+
+	t.Run("Review passing intervals are ordered", func(t *testing.T) {
+		last := now.Add(-5 * 24 * time.Hour)
+		stability, difficulty := 5.0, 0.5
+		state := scheduler.SchedulingState{
+			CardID: 9, State: valueobjects.CardStateReview, Interval: 5, Ease: 2500,
+			Stability: &stability, Difficulty: &difficulty, LastReviewAt: &last,
+		}
+		s := scheduler.NewFSRSScheduler(fsrsOptions())
+		hard, _ := s.Schedule(state, scheduler.RatingHard, now)
+		good, _ := s.Schedule(state, scheduler.RatingGood, now)
+		easy, _ := s.Schedule(state, scheduler.RatingEasy, now)
+		assert.Less(t, hard.Interval, good.Interval)
+		assert.Less(t, good.Interval, easy.Interval)
+	})
+
+	t.Run("Lapse enters relearning and lowers stability", func(t *testing.T) {
+		last := now.Add(-30 * 24 * time.Hour)
+		stability, difficulty := 30.0, 0.5
+		state := scheduler.SchedulingState{
+			CardID: 3, State: valueobjects.CardStateReview, Interval: 30, Ease: 2500,
+			Stability: &stability, Difficulty: &difficulty, LastReviewAt: &last,
+		}
+		res, err := scheduler.NewFSRSScheduler(fsrsOptions()).Schedule(state, scheduler.RatingAgain, now)
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.CardStateRelearn, res.State)
+		assert.Equal(t, 1, res.Lapses)
+		assert.Less(t, *res.Stability, stability)
+		assert.GreaterOrEqual(t, res.Interval, 1)
+	})
+
+	t.Run("SM-2 card without memory state is converted", func(t *testing.T) {
+		last := now.Add(-10 * 24 * time.Hour)
+		state := scheduler.SchedulingState{
+			CardID: 5, State: valueobjects.CardStateReview, Interval: 10, Ease: 2500, LastReviewAt: &last,
+		}
+		res, err := scheduler.NewFSRSScheduler(fsrsOptions()).Schedule(state, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		require.NotNil(t, res.Stability)
+		assert.Greater(t, *res.Stability, 10.0)
+	})
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeckOptions_Parse(t *testing.T) {
+	t.Run("Empty JSON uses defaults", func(t *testing.T) {
+		opts, err := deck.ParseDeckOptions("{}")
+		require.NoError(t, err)
+		assert.Equal(t, []float64{1, 10}, opts.NewSteps)
+		assert.Equal(t, 1, opts.GraduatingInterval)
+		assert.Equal(t, 4, opts.EasyInterval)
+		assert.Equal(t, 2.5, opts.StartingEase)
+		assert.Equal(t, 36500, opts.MaximumInterval)
+	})
+
+	t.Run("Overrides and invalid values", func(t *testing.T) {
+		opts, err := deck.ParseDeckOptions(`{"new_steps": [5, 30, 0], "graduating_interval": 3, "interval_modifier": -1}`)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{5, 30}, opts.NewSteps)
+		assert.Equal(t, 3, opts.GraduatingInterval)
+		assert.Equal(t, 1.0, opts.IntervalModifier)
+	})
+
+	t.Run("Malformed JSON", func(t *testing.T) {
+		_, err := deck.ParseDeckOptions(`{"new_steps": "x"}`)
+		assert.Error(t, err)
+	})
+}
+
+func TestDeckOptions_IsLeech(t *testing.T) {
+	opts, err := deck.ParseDeckOptions(`{"leech_threshold": 8, "leech_action": "bogus"}`)
+	require.NoError(t, err)
+	assert.Equal(t, valueobjects.LeechActionTagOnly, opts.LeechAction)
+
+	for lapses, expected := range map[int]bool{7: false, 8: true, 9: false, 12: true, 16: true} {
+		assert.Equal(t, expected, opts.IsLeech(lapses), "lapses=%d", lapses)
+	}
+
+	opts.LeechThreshold = 0
+	assert.False(t, opts.IsLeech(8))
+}
+
+func TestSM2Scheduler_Learning(t *testing.T) {
+	s := scheduler.NewSM2Scheduler(deck.DefaultDeckOptions())
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newCard := scheduler.SchedulingState{CardID: 1, State: valueobjects.CardStateNew, Ease: 2500}
+
+	t.Run("Again on new card uses first step", func(t *testing.T) {
+		res, err := s.Schedule(newCard, scheduler.RatingAgain, now)
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.CardStateLearn, res.State)
+		assert.Equal(t, -60, res.LogInterval)
+		assert.Equal(t, now.Add(time.Minute).UnixMilli(), res.Due)
+		assert.Equal(t, 0, res.Interval)
+		assert.Equal(t, 1, res.Reps)
+	})
+
+	t.Run("Hard on first step averages first two steps", func(t *testing.T) {
+		res, err := s.Schedule(newCard, scheduler.RatingHard, now)
+		require.NoError(t, err)
+		assert.Equal(t, -330, res.LogInterval)
+		assert.Equal(t, 0, res.Step)
+	})
+
+	t.Run("Good advances to next step", func(t *testing.T) {
+		res, err := s.Schedule(newCard, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		assert.Equal(t, -600, res.LogInterval)
+		assert.Equal(t, 1, res.Step)
+	})
+
+	t.Run("Good on last step graduates", func(t *testing.T) {
+		state := scheduler.SchedulingState{CardID: 1, State: valueobjects.CardStateLearn, Ease: 2500, Step: 1}
+		res, err := s.Schedule(state, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.CardStateReview, res.State)
+		assert.Equal(t, 1, res.Interval)
+		assert.Equal(t, now.Add(24*time.Hour).UnixMilli(), res.Due)
+	})
+
+	t.Run("Easy graduates with easy interval", func(t *testing.T) {
+		res, err := s.Schedule(newCard, scheduler.RatingEasy, now)
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.CardStateReview, res.State)
+		// 4 days +/- fuzz
+		assert.GreaterOrEqual(t, res.Interval, 3)
+		assert.LessOrEqual(t, res.Interval, 5)
+	})
+
+	t.Run("Invalid rating", func(t *testing.T) {
+		_, err := s.Schedule(newCard, 0, now)
+		assert.ErrorIs(t, err, scheduler.ErrInvalidRating)
+	})
+}
+
+func TestSM2Scheduler_Review(t *testing.T) {
+	opts := deck.DefaultDeckOptions()
+	s := scheduler.NewSM2Scheduler(opts)
+	now := time.Date(2024, 1, 11, 12, 0, 0, 0, time.UTC)
+	lastReview := now.Add(-10 * 24 * time.Hour)
+	state := scheduler.SchedulingState{
+		CardID:       42,
+		State:        valueobjects.CardStateReview,
+		Interval:     10,
+		Ease:         2500,
+		Reps:         5,
+		LastReviewAt: &lastReview,
+	}
+
+	t.Run("Hard Good Easy are ordered and adjust ease", func(t *testing.T) {
+		hard, err := s.Schedule(state, scheduler.RatingHard, now)
+		require.NoError(t, err)
+		good, err := s.Schedule(state, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		easy, err := s.Schedule(state, scheduler.RatingEasy, now)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2350, hard.Ease)
+		assert.Equal(t, 2500, good.Ease)
+		assert.Equal(t, 2650, easy.Ease)
+
+		// Hard ~ 12d, Good ~ 25d, Easy ~ 32.5d (with fuzz)
+		assert.InDelta(t, 12, hard.Interval, 2)
+		assert.InDelta(t, 25, good.Interval, 3)
+		assert.InDelta(t, 32, easy.Interval, 4)
+		assert.Less(t, hard.Interval, good.Interval)
+		assert.Less(t, good.Interval, easy.Interval)
+		assert.Equal(t, valueobjects.ReviewTypeReview, good.ReviewType)
+	})
+
+	t.Run("Fuzz is deterministic per card and reps", func(t *testing.T) {
+		first, _ := s.Schedule(state, scheduler.RatingGood, now)
+		second, _ := s.Schedule(state, scheduler.RatingGood, now)
+		assert.Equal(t, first.Interval, second.Interval)
+	})
+
+	t.Run("Again lapses into relearning", func(t *testing.T) {
+		res, err := s.Schedule(state, scheduler.RatingAgain, now)
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.CardStateRelearn, res.State)
+		assert.Equal(t, 1, res.Lapses)
+		assert.Equal(t, 2300, res.Ease)
+		assert.Equal(t, 1, res.Interval)
+		assert.Equal(t, -600, res.LogInterval)
+	})
+
+	t.Run("Maximum interval caps the result", func(t *testing.T) {
+		capped := deck.DefaultDeckOptions()
+		capped.MaximumInterval = 15
+		res, err := scheduler.NewSM2Scheduler(capped).Schedule(state, scheduler.RatingEasy, now)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, res.Interval, 15)
+	})
+
+	t.Run("Interval modifier scales the result", func(t *testing.T) {
+		modified := deck.DefaultDeckOptions()
+		modified.IntervalModifier = 2.0
+		res, err := scheduler.NewSM2Scheduler(modified).Schedule(state, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		assert.InDelta(t, 50, res.Interval, 4)
+	})
+
+	t.Run("Relearning Good returns to review with lapse interval", func(t *testing.T) {
+		relearn := scheduler.SchedulingState{CardID: 42, State: valueobjects.CardStateRelearn, Interval: 3, Ease: 2300}
+		res, err := s.Schedule(relearn, scheduler.RatingGood, now)
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.CardStateReview, res.State)
+		assert.Equal(t, 3, res.Interval)
+		assert.Equal(t, valueobjects.ReviewTypeRelearn, res.ReviewType)
+	})
+}
+
+func TestSM2Scheduler_StepIndexForDelay(t *testing.T) {
+	steps := []float64{1, 10, 60}
+	assert.Equal(t, 0, scheduler.StepIndexForDelay(steps, 60))
+	assert.Equal(t, 0, scheduler.StepIndexForDelay(steps, 330))
+	assert.Equal(t, 1, scheduler.StepIndexForDelay(steps, 600))
+	assert.Equal(t, 2, scheduler.StepIndexForDelay(steps, 3600))
+}
+
+func TestIntervalLabel(t *testing.T) {
+	assert.Equal(t, "<1m", scheduler.IntervalLabel(30))
+	assert.Equal(t, "10m", scheduler.IntervalLabel(600))
+	assert.Equal(t, "2h", scheduler.IntervalLabel(7200))
+	assert.Equal(t, "3d", scheduler.IntervalLabel(3*86400))
+	assert.Equal(t, "1.5mo", scheduler.IntervalLabel(45*86400))
+	assert.Equal(t, "2y", scheduler.IntervalLabel(730*86400))
+}
+
+func TestPreviewIntervals(t *testing.T) {
+	now := time.Date(2024, 1, 11, 12, 0, 0, 0, time.UTC)
+	lastReview := now.Add(-10 * 24 * time.Hour)
+	state := scheduler.SchedulingState{
+		CardID: 42, State: valueobjects.CardStateReview, Interval: 10, Ease: 2500, Reps: 5, LastReviewAt: &lastReview,
+	}
+
+	previews, err := scheduler.PreviewIntervals(scheduler.NewSM2Scheduler(deck.DefaultDeckOptions()), state, now)
+	require.NoError(t, err)
+	require.Len(t, previews, 4)
+
+	assert.Equal(t, valueobjects.CardStateRelearn, previews[0].State)
+	assert.Equal(t, 600, previews[0].DelaySeconds)
+	for i, p := range previews {
+		assert.Equal(t, i+1, p.Rating)
+		if i > 1 {
+			assert.Greater(t, p.Interval, previews[i-1].Interval)
+			assert.Equal(t, p.Interval*86400, p.DelaySeconds)
+		}
+	}
+}
@@ -32,6 +32,11 @@ func TestReviewType_IsValid(t *testing.T) {
 			reviewType: valueobjects.ReviewTypeCram,
 			want:       true,
 		},
+		{
+			name:       "valid manual",
+			reviewType: valueobjects.ReviewTypeManual,
+			want:       true,
+		},
 		{
 			name:       "invalid type",
 			reviewType: valueobjects.ReviewType("invalid"),
@@ -18,6 +18,7 @@ import (
 
 func TestAddOnHandler_Install(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockAddOnService)
 	handler := handlers.NewAddOnHandler(mockSvc)
 	userID := int64(1)
@@ -47,6 +48,7 @@ func TestAddOnHandler_Install(t *testing.T) {
 
 func TestAddOnHandler_FindAll(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockAddOnService)
 	handler := handlers.NewAddOnHandler(mockSvc)
 	userID := int64(1)
@@ -69,6 +71,7 @@ func TestAddOnHandler_FindAll(t *testing.T) {
 
 func TestAddOnHandler_UpdateConfig(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockAddOnService)
 	handler := handlers.NewAddOnHandler(mockSvc)
 	userID := int64(1)
@@ -98,6 +101,7 @@ func TestAddOnHandler_UpdateConfig(t *testing.T) {
 
 func TestAddOnHandler_Uninstall(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockAddOnService)
 	handler := handlers.NewAddOnHandler(mockSvc)
 	userID := int64(1)
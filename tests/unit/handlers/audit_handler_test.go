@@ -16,6 +16,7 @@ import (
 
 func TestAuditHandler_GetDeletionLogs(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockDlSvc := new(MockDeletionLogService)
 	mockUhSvc := new(MockUndoHistoryService)
 	handler := handlers.NewAuditHandler(mockDlSvc, mockUhSvc)
@@ -39,6 +40,7 @@ func TestAuditHandler_GetDeletionLogs(t *testing.T) {
 
 func TestAuditHandler_GetUndoHistory(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockDlSvc := new(MockDeletionLogService)
 	mockUhSvc := new(MockUndoHistoryService)
 	handler := handlers.NewAuditHandler(mockDlSvc, mockUhSvc)
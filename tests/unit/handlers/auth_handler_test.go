@@ -127,6 +127,7 @@ func TestAuthHandler_Register_Success(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -162,6 +163,7 @@ func TestAuthHandler_Register_InvalidRequest(t *testing.T) {
 
 	// Test with invalid JSON
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -225,6 +227,7 @@ func TestAuthHandler_Register_ValidationErrors(t *testing.T) {
 	handler := handlers.NewAuthHandler(mockService)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 
 	tests := []struct {
 		name     string
@@ -324,6 +327,7 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -373,6 +377,7 @@ func TestAuthHandler_Login_InvalidCredentials(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -425,6 +430,7 @@ func TestAuthHandler_Login_InvalidRequest(t *testing.T) {
 			jsonBody, _ := json.Marshal(tt.reqBody)
 
 			e := echo.New()
+			e.Validator = middlewares.NewCustomValidator()
 			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(jsonBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
@@ -469,6 +475,7 @@ func TestAuthHandler_RefreshToken_Success(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -518,6 +525,7 @@ func TestAuthHandler_RefreshToken_InvalidToken(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -548,6 +556,7 @@ func TestAuthHandler_RefreshToken_EmptyToken(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -583,6 +592,7 @@ func TestAuthHandler_Logout_Success(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	req.Header.Set("Authorization", "Bearer valid-access-token")
@@ -622,6 +632,7 @@ func TestAuthHandler_Logout_AccessTokenOnly(t *testing.T) {
 	handler := handlers.NewAuthHandler(mockService)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	req.Header.Set("Authorization", "Bearer valid-access-token")
@@ -644,6 +655,7 @@ func TestAuthHandler_Logout_NoTokens(t *testing.T) {
 	handler := handlers.NewAuthHandler(mockService)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -679,6 +691,7 @@ func TestAuthHandler_RequestPasswordReset_Success(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/request-password-reset", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -714,6 +727,7 @@ func TestAuthHandler_RequestPasswordReset_EmptyEmail(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/request-password-reset", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -751,6 +765,7 @@ func TestAuthHandler_ResetPassword_Success(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/reset-password", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -793,6 +808,7 @@ func TestAuthHandler_ResetPassword_InvalidToken(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/reset-password", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -818,6 +834,7 @@ func TestAuthHandler_ResetPassword_ValidationErrors(t *testing.T) {
 	handler := handlers.NewAuthHandler(mockService)
 
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 
 	tests := []struct {
 		name     string
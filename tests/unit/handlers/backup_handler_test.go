@@ -18,6 +18,7 @@ import (
 
 func TestBackupHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockBackupService)
 	handler := handlers.NewBackupHandler(mockSvc)
 	userID := int64(1)
@@ -48,6 +49,7 @@ func TestBackupHandler_Create(t *testing.T) {
 
 func TestBackupHandler_FindAll(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockBackupService)
 	handler := handlers.NewBackupHandler(mockSvc)
 	userID := int64(1)
@@ -70,6 +72,7 @@ func TestBackupHandler_FindAll(t *testing.T) {
 
 func TestBackupHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockBackupService)
 	handler := handlers.NewBackupHandler(mockSvc)
 	userID := int64(1)
@@ -22,6 +22,7 @@ import (
 
 func TestCardHandler_FindByID(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockCardService)
 	handler := handlers.NewCardHandler(mockSvc)
 	userID := int64(1)
@@ -178,6 +179,7 @@ func TestCardHandler_FindByID(t *testing.T) {
 
 func TestCardHandler_FindByDeckID(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockCardService)
 	handler := handlers.NewCardHandler(mockSvc)
 	userID := int64(1)
@@ -205,6 +207,7 @@ func TestCardHandler_FindByDeckID(t *testing.T) {
 
 func TestCardHandler_Suspend(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockCardService)
 	handler := handlers.NewCardHandler(mockSvc)
 	userID := int64(1)
@@ -340,6 +343,7 @@ func TestCardHandler_Suspend(t *testing.T) {
 
 func TestCardHandler_Unsuspend(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockCardService)
 	handler := handlers.NewCardHandler(mockSvc)
 	userID := int64(1)
@@ -660,6 +664,7 @@ func TestCardHandler_SetFlag(t *testing.T) {
 
 func TestCardHandler_Bury(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockCardService)
 	handler := handlers.NewCardHandler(mockSvc)
 	userID := int64(1)
@@ -795,6 +800,7 @@ func TestCardHandler_Bury(t *testing.T) {
 
 func TestCardHandler_Unbury(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockCardService)
 	handler := handlers.NewCardHandler(mockSvc)
 	userID := int64(1)
@@ -1281,6 +1287,7 @@ func TestCardHandler_FindAll(t *testing.T) {
 
 func TestCardHandler_FindLeeches(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	userID := int64(1)
 
 	t.Run("Success", func(t *testing.T) {
@@ -1733,6 +1740,7 @@ func TestCardHandler_Reposition(t *testing.T) {
 
 func TestCardHandler_GetPosition(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	userID := int64(1)
 	cardID := int64(123)
 
@@ -1821,6 +1829,7 @@ func TestCardHandler_GetPosition(t *testing.T) {
 
 func TestCardHandler_GetInfo(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	userID := int64(1)
 	cardID := int64(123)
 
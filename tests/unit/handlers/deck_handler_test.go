@@ -18,6 +18,7 @@ import (
 
 func TestDeckHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockDeckService)
 	handler := handlers.NewDeckHandler(mockSvc)
 	userID := int64(1)
@@ -82,6 +83,7 @@ func TestDeckHandler_Create(t *testing.T) {
 
 func TestDeckHandler_FindByID(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockDeckService)
 	handler := handlers.NewDeckHandler(mockSvc)
 	userID := int64(1)
@@ -108,6 +110,7 @@ func TestDeckHandler_FindByID(t *testing.T) {
 
 func TestDeckHandler_FindAll(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockDeckService)
 	handler := handlers.NewDeckHandler(mockSvc)
 	userID := int64(1)
@@ -130,6 +133,7 @@ func TestDeckHandler_FindAll(t *testing.T) {
 
 func TestDeckHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockDeckService)
 	handler := handlers.NewDeckHandler(mockSvc)
 	userID := int64(1)
@@ -161,13 +165,16 @@ func TestDeckHandler_Update(t *testing.T) {
 
 func TestDeckHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockDeckService)
 	handler := handlers.NewDeckHandler(mockSvc)
 	userID := int64(1)
 	deckID := int64(10)
 
 	t.Run("Success", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		body, _ := json.Marshal(request.DeleteDeckRequest{Action: request.DeleteDeckAction(deck.ActionMoveToDefault)})
+		req := httptest.NewRequest(http.MethodDelete, "/", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		c.SetPath("/api/v1/decks/:id")
@@ -175,7 +182,7 @@ func TestDeckHandler_Delete(t *testing.T) {
 		c.SetParamValues("10")
 		c.Set(middlewares.UserIDContextKey, userID)
 
-		mockSvc.On("Delete", mock.Anything, userID, deckID).Return(nil).Once()
+		mockSvc.On("Delete", mock.Anything, userID, deckID, deck.ActionMoveToDefault, (*int64)(nil)).Return(nil).Once()
 
 		if assert.NoError(t, handler.Delete(c)) {
 			assert.Equal(t, http.StatusNoContent, rec.Code)
@@ -18,15 +18,19 @@ import (
 
 func TestFilteredDeckHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockFilteredDeckService)
 	handler := handlers.NewFilteredDeckHandler(mockSvc)
 	userID := int64(1)
 
 	t.Run("Success", func(t *testing.T) {
+		second := "is:new"
 		reqBody := request.CreateFilteredDeckRequest{
 			Name:         "Filtered",
 			SearchFilter: "is:due",
+			SecondFilter: &second,
 			Limit:        100,
+			SecondLimit:  20,
 			OrderBy:      "random",
 			Reschedule:   true,
 		}
@@ -38,7 +42,7 @@ func TestFilteredDeckHandler_Create(t *testing.T) {
 		c.Set(middlewares.UserIDContextKey, userID)
 
 		fd, _ := filtereddeck.NewBuilder().WithID(1).WithUserID(userID).WithName(reqBody.Name).Build()
-		mockSvc.On("Create", mock.Anything, userID, reqBody.Name, reqBody.SearchFilter, reqBody.Limit, reqBody.OrderBy, reqBody.Reschedule).Return(fd, nil).Once()
+		mockSvc.On("Create", mock.Anything, userID, reqBody.Name, reqBody.SearchFilter, reqBody.SecondFilter, reqBody.Limit, reqBody.SecondLimit, reqBody.OrderBy, reqBody.Reschedule).Return(fd, nil).Once()
 
 		if assert.NoError(t, handler.Create(c)) {
 			assert.Equal(t, http.StatusCreated, rec.Code)
@@ -49,6 +53,7 @@ func TestFilteredDeckHandler_Create(t *testing.T) {
 
 func TestFilteredDeckHandler_FindAll(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockFilteredDeckService)
 	handler := handlers.NewFilteredDeckHandler(mockSvc)
 	userID := int64(1)
@@ -71,6 +76,7 @@ func TestFilteredDeckHandler_FindAll(t *testing.T) {
 
 func TestFilteredDeckHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockFilteredDeckService)
 	handler := handlers.NewFilteredDeckHandler(mockSvc)
 	userID := int64(1)
@@ -95,7 +101,7 @@ func TestFilteredDeckHandler_Update(t *testing.T) {
 		c.Set(middlewares.UserIDContextKey, userID)
 
 		fd, _ := filtereddeck.NewBuilder().WithID(deckID).WithUserID(userID).WithName(reqBody.Name).Build()
-		mockSvc.On("Update", mock.Anything, userID, deckID, reqBody.Name, reqBody.SearchFilter, reqBody.Limit, reqBody.OrderBy, reqBody.Reschedule).Return(fd, nil).Once()
+		mockSvc.On("Update", mock.Anything, userID, deckID, reqBody.Name, reqBody.SearchFilter, reqBody.SecondFilter, reqBody.Limit, reqBody.SecondLimit, reqBody.OrderBy, reqBody.Reschedule).Return(fd, nil).Once()
 
 		if assert.NoError(t, handler.Update(c)) {
 			assert.Equal(t, http.StatusOK, rec.Code)
@@ -106,6 +112,7 @@ func TestFilteredDeckHandler_Update(t *testing.T) {
 
 func TestFilteredDeckHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockFilteredDeckService)
 	handler := handlers.NewFilteredDeckHandler(mockSvc)
 	userID := int64(1)
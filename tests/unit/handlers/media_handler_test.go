@@ -2,53 +2,155 @@ package handlers_test
 
 import (
 	"bytes"
-	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
-	"github.com/felipesantos/anki-backend/app/api/dtos/request"
 	"github.com/felipesantos/anki-backend/app/api/handlers"
 	"github.com/felipesantos/anki-backend/app/api/middlewares"
 	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	mediaSvc "github.com/felipesantos/anki-backend/core/services/media"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-func TestMediaHandler_Create(t *testing.T) {
+func TestMediaHandler_Upload(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockMediaService)
 	handler := handlers.NewMediaHandler(mockSvc)
 	userID := int64(1)
 
-	t.Run("Success", func(t *testing.T) {
-		reqBody := request.CreateMediaRequest{
-			Filename:    "image.png",
-			Hash:        "hash123",
-			Size:        512,
-			MimeType:    "image/png",
-			StoragePath: "/path",
-		}
-		body, _ := json.Marshal(reqBody)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/media", bytes.NewReader(body))
-		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	newUploadRequest := func(filename string) (echo.Context, *httptest.ResponseRecorder) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", filename)
+		part.Write([]byte("\x89PNG\r\n\x1a\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/media", body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		c.Set(middlewares.UserIDContextKey, userID)
+		return c, rec
+	}
 
-		m, _ := media.NewBuilder().WithID(1).WithUserID(userID).WithFilename(reqBody.Filename).Build()
-		mockSvc.On("Create", mock.Anything, userID, reqBody.Filename, reqBody.Hash, reqBody.Size, reqBody.MimeType, reqBody.StoragePath).Return(m, nil).Once()
+	t.Run("Success", func(t *testing.T) {
+		c, rec := newUploadRequest("image.png")
+
+		m, _ := media.NewBuilder().WithID(1).WithUserID(userID).WithFilename("image.png").Build()
+		mockSvc.On("Upload", mock.Anything, userID, "image.png", mock.Anything).Return(m, nil).Once()
 
-		if assert.NoError(t, handler.Create(c)) {
+		if assert.NoError(t, handler.Upload(c)) {
 			assert.Equal(t, http.StatusCreated, rec.Code)
 		}
 		mockSvc.AssertExpectations(t)
 	})
+
+	t.Run("Quota exceeded", func(t *testing.T) {
+		c, _ := newUploadRequest("large.png")
+
+		mockSvc.On("Upload", mock.Anything, userID, "large.png", mock.Anything).Return(nil, mediaSvc.ErrQuotaExceeded).Once()
+
+		err := handler.Upload(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		if assert.True(t, ok) {
+			assert.Equal(t, http.StatusRequestEntityTooLarge, httpErr.Code)
+		}
+	})
+
+	t.Run("Filename taken", func(t *testing.T) {
+		c, _ := newUploadRequest("taken.png")
+
+		mockSvc.On("Upload", mock.Anything, userID, "taken.png", mock.Anything).Return(nil, mediaSvc.ErrFilenameTaken).Once()
+
+		err := handler.Upload(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		if assert.True(t, ok) {
+			assert.Equal(t, http.StatusConflict, httpErr.Code)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/media", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(middlewares.UserIDContextKey, userID)
+
+		err := handler.Upload(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		if assert.True(t, ok) {
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		}
+	})
+}
+
+func TestMediaHandler_Content(t *testing.T) {
+	e := echo.New()
+	mockSvc := new(MockMediaService)
+	handler := handlers.NewMediaHandler(mockSvc)
+	userID := int64(1)
+	mediaID := int64(10)
+
+	newContentRequestFor := func(id string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/media/:id/content")
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set(middlewares.UserIDContextKey, userID)
+		return c, rec
+	}
+	newContentRequest := func() (echo.Context, *httptest.ResponseRecorder) {
+		return newContentRequestFor("10")
+	}
+	m, _ := media.NewBuilder().WithID(mediaID).WithUserID(userID).WithFilename("test.png").WithMimeType("image/png").Build()
+
+	t.Run("Streams content", func(t *testing.T) {
+		c, rec := newContentRequest()
+		mockSvc.On("GetContent", mock.Anything, userID, mediaID).Return(&primary.MediaContent{Media: m, Data: strings.NewReader("png data")}, nil).Once()
+
+		if assert.NoError(t, handler.Content(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, "png data", rec.Body.String())
+		}
+	})
+
+	t.Run("Redirects to presigned URL", func(t *testing.T) {
+		c, rec := newContentRequest()
+		url := "https://bucket.example.com/media/1/abc?X-Amz-Signature=sig"
+		mockSvc.On("GetContent", mock.Anything, userID, mediaID).Return(&primary.MediaContent{Media: m, URL: url}, nil).Once()
+
+		if assert.NoError(t, handler.Content(c)) {
+			assert.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, url, rec.Header().Get(echo.HeaderLocation))
+		}
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		for _, id := range []string{"abc", "0"} {
+			c, _ := newContentRequestFor(id)
+
+			err := handler.Content(c)
+			httpErr, ok := err.(*echo.HTTPError)
+			if assert.True(t, ok, id) {
+				assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			}
+		}
+		mockSvc.AssertNotCalled(t, "GetContent", mock.Anything, mock.Anything, int64(0))
+	})
 }
 
 func TestMediaHandler_FindByID(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockMediaService)
 	handler := handlers.NewMediaHandler(mockSvc)
 	userID := int64(1)
@@ -75,6 +177,7 @@ func TestMediaHandler_FindByID(t *testing.T) {
 
 func TestMediaHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockMediaService)
 	handler := handlers.NewMediaHandler(mockSvc)
 	userID := int64(1)
@@ -3,6 +3,7 @@ package handlers_test
 import (
 	"context"
 	"io"
+	"time"
 
 	addon "github.com/felipesantos/anki-backend/core/domain/entities/add_on"
 	"github.com/felipesantos/anki-backend/core/domain/entities/backup"
@@ -21,6 +22,8 @@ import (
 	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/core/domain/entities/user"
 	userpreferences "github.com/felipesantos/anki-backend/core/domain/entities/user_preferences"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -87,8 +90,8 @@ type MockFilteredDeckService struct {
 	mock.Mock
 }
 
-func (m *MockFilteredDeckService) Create(ctx context.Context, userID int64, name string, filter string, limit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
-	args := m.Called(ctx, userID, name, filter, limit, orderBy, reschedule)
+func (m *MockFilteredDeckService) Create(ctx context.Context, userID int64, name string, filter string, secondFilter *string, limit int, secondLimit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
+	args := m.Called(ctx, userID, name, filter, secondFilter, limit, secondLimit, orderBy, reschedule)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -103,8 +106,8 @@ func (m *MockFilteredDeckService) FindByUserID(ctx context.Context, userID int64
 	return args.Get(0).([]*filtereddeck.FilteredDeck), args.Error(1)
 }
 
-func (m *MockFilteredDeckService) Update(ctx context.Context, userID int64, id int64, name string, filter string, limit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
-	args := m.Called(ctx, userID, id, name, filter, limit, orderBy, reschedule)
+func (m *MockFilteredDeckService) Update(ctx context.Context, userID int64, id int64, name string, filter string, secondFilter *string, limit int, secondLimit int, orderBy string, reschedule bool) (*filtereddeck.FilteredDeck, error) {
+	args := m.Called(ctx, userID, id, name, filter, secondFilter, limit, secondLimit, orderBy, reschedule)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -116,6 +119,24 @@ func (m *MockFilteredDeckService) Delete(ctx context.Context, userID int64, id i
 	return args.Error(0)
 }
 
+func (m *MockFilteredDeckService) Rebuild(ctx context.Context, userID int64, id int64) (int, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFilteredDeckService) Empty(ctx context.Context, userID int64, id int64) (int, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFilteredDeckService) FindCards(ctx context.Context, userID int64, id int64) ([]*card.Card, error) {
+	args := m.Called(ctx, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*card.Card), args.Error(1)
+}
+
 // MockCardService is a mock implementation of ICardService
 type MockCardService struct {
 	mock.Mock
@@ -229,6 +250,19 @@ func (m *MockCardService) GetPosition(ctx context.Context, userID int64, cardID
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockCardService) FindEmptyCards(ctx context.Context, userID int64) ([]*card.Card, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*card.Card), args.Error(1)
+}
+
+func (m *MockCardService) CleanupEmptyCards(ctx context.Context, userID int64) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
 // MockReviewService is a mock implementation of IReviewService
 type MockReviewService struct {
 	mock.Mock
@@ -242,6 +276,14 @@ func (m *MockReviewService) Create(ctx context.Context, userID int64, cardID int
 	return args.Get(0).(*review.Review), args.Error(1)
 }
 
+func (m *MockReviewService) CreateAt(ctx context.Context, userID int64, cardID int64, rating int, timeMs int, reviewedAt time.Time) (*review.Review, error) {
+	args := m.Called(ctx, userID, cardID, rating, timeMs, reviewedAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*review.Review), args.Error(1)
+}
+
 func (m *MockReviewService) FindByID(ctx context.Context, userID int64, id int64) (*review.Review, error) {
 	args := m.Called(ctx, userID, id)
 	if args.Get(0) == nil {
@@ -258,11 +300,32 @@ func (m *MockReviewService) FindByCardID(ctx context.Context, userID int64, card
 	return args.Get(0).([]*review.Review), args.Error(1)
 }
 
+func (m *MockReviewService) LogManual(ctx context.Context, userID int64, c *card.Card) error {
+	args := m.Called(ctx, userID, c)
+	return args.Error(0)
+}
+
 func (m *MockReviewService) DeleteByCardID(ctx context.Context, userID int64, cardID int64) error {
 	args := m.Called(ctx, userID, cardID)
 	return args.Error(0)
 }
 
+func (m *MockReviewService) Undo(ctx context.Context, userID int64) (*card.Card, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*card.Card), args.Error(1)
+}
+
+func (m *MockReviewService) PreviewIntervals(ctx context.Context, userID int64, cardID int64) ([]scheduler.IntervalPreview, error) {
+	args := m.Called(ctx, userID, cardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]scheduler.IntervalPreview), args.Error(1)
+}
+
 // MockNoteService is a mock implementation of INoteService
 type MockNoteService struct {
 	mock.Mock
@@ -347,6 +410,11 @@ func (m *MockNoteService) FindDuplicatesByGUID(ctx context.Context, userID int64
 	return args.Get(0).(*note.DuplicateResult), args.Error(1)
 }
 
+func (m *MockNoteService) FindReplace(ctx context.Context, userID int64, fr note.FindReplace) (int, error) {
+	args := m.Called(ctx, userID, fr)
+	return args.Int(0), args.Error(1)
+}
+
 // MockNoteTypeService is a mock implementation of INoteTypeService
 type MockNoteTypeService struct {
 	mock.Mock
@@ -601,14 +669,22 @@ type MockMediaService struct {
 	mock.Mock
 }
 
-func (m *MockMediaService) Create(ctx context.Context, userID int64, filename string, hash string, size int64, mimeType string, storagePath string) (*media.Media, error) {
-	args := m.Called(ctx, userID, filename, hash, size, mimeType, storagePath)
+func (m *MockMediaService) Upload(ctx context.Context, userID int64, filename string, file io.Reader) (*media.Media, error) {
+	args := m.Called(ctx, userID, filename, file)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*media.Media), args.Error(1)
 }
 
+func (m *MockMediaService) GetContent(ctx context.Context, userID int64, id int64) (*primary.MediaContent, error) {
+	args := m.Called(ctx, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*primary.MediaContent), args.Error(1)
+}
+
 func (m *MockMediaService) FindByID(ctx context.Context, userID int64, id int64) (*media.Media, error) {
 	args := m.Called(ctx, userID, id)
 	if args.Get(0) == nil {
@@ -794,3 +870,24 @@ func (m *MockUndoHistoryService) Delete(ctx context.Context, userID int64, id in
 	args := m.Called(ctx, userID, id)
 	return args.Error(0)
 }
+
+func (m *MockUndoHistoryService) Record(ctx context.Context, userID int64, operationType string, changes *undohistory.ChangeSet) error {
+	args := m.Called(ctx, userID, operationType, changes)
+	return args.Error(0)
+}
+
+func (m *MockUndoHistoryService) Undo(ctx context.Context, userID int64) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
+
+func (m *MockUndoHistoryService) Redo(ctx context.Context, userID int64) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
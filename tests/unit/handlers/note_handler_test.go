@@ -23,6 +23,7 @@ import (
 
 func TestNoteHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteService)
 	mockExportSvc := new(MockExportService)
 	mockDeletionLogSvc := new(MockDeletionLogService)
@@ -121,6 +122,7 @@ func TestNoteHandler_Create(t *testing.T) {
 
 func TestNoteHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteService)
 	mockExportSvc := new(MockExportService)
 	mockDeletionLogSvc := new(MockDeletionLogService)
@@ -156,6 +158,7 @@ func TestNoteHandler_Update(t *testing.T) {
 
 func TestNoteHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteService)
 	mockExportSvc := new(MockExportService)
 	mockDeletionLogSvc := new(MockDeletionLogService)
@@ -183,6 +186,7 @@ func TestNoteHandler_Delete(t *testing.T) {
 
 func TestNoteHandler_FindDuplicates(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteService)
 	mockExportSvc := new(MockExportService)
 	mockDeletionLogSvc := new(MockDeletionLogService)
@@ -381,6 +385,7 @@ func TestNoteHandler_FindDuplicates(t *testing.T) {
 
 func TestNoteHandler_GetRecentDeletions(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockNoteSvc := new(MockNoteService)
 	mockExportSvc := new(MockExportService)
 	mockDeletionLogSvc := new(MockDeletionLogService)
@@ -527,6 +532,7 @@ func TestNoteHandler_GetRecentDeletions(t *testing.T) {
 
 func TestNoteHandler_RestoreDeletion(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockNoteSvc := new(MockNoteService)
 	mockExportSvc := new(MockExportService)
 	mockDeletionLogSvc := new(MockDeletionLogService)
@@ -631,7 +637,7 @@ func TestNoteHandler_RestoreDeletion(t *testing.T) {
 		httpError, ok := err.(*echo.HTTPError)
 		assert.True(t, ok)
 		assert.Equal(t, http.StatusBadRequest, httpError.Code)
-		assert.Contains(t, httpError.Message, "deck_id is required")
+		assert.Contains(t, httpError.Message, "DeckID is required")
 	})
 
 	t.Run("Error - deletion log not found", func(t *testing.T) {
@@ -648,7 +654,7 @@ func TestNoteHandler_RestoreDeletion(t *testing.T) {
 		c.SetParamValues(fmt.Sprintf("%d", deletionLogID))
 		c.Set(middlewares.UserIDContextKey, userID)
 
-		mockDeletionLogSvc.On("Restore", mock.Anything, userID, deletionLogID, deckID).Return(nil, fmt.Errorf("not found")).Once()
+		mockDeletionLogSvc.On("Restore", mock.Anything, userID, deletionLogID, deckID).Return(nil, fmt.Errorf("deletion log not found")).Once()
 
 		err := handler.RestoreDeletion(c)
 		assert.Error(t, err)
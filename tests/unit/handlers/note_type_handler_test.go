@@ -18,6 +18,7 @@ import (
 
 func TestNoteTypeHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteTypeService)
 	handler := handlers.NewNoteTypeHandler(mockSvc)
 	userID := int64(1)
@@ -123,6 +124,7 @@ func TestNoteTypeHandler_Create(t *testing.T) {
 
 func TestNoteTypeHandler_FindAll(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteTypeService)
 	handler := handlers.NewNoteTypeHandler(mockSvc)
 	userID := int64(1)
@@ -145,6 +147,7 @@ func TestNoteTypeHandler_FindAll(t *testing.T) {
 
 func TestNoteTypeHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteTypeService)
 	handler := handlers.NewNoteTypeHandler(mockSvc)
 	userID := int64(1)
@@ -179,6 +182,7 @@ func TestNoteTypeHandler_Update(t *testing.T) {
 
 func TestNoteTypeHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockNoteTypeService)
 	handler := handlers.NewNoteTypeHandler(mockSvc)
 	userID := int64(1)
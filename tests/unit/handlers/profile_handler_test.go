@@ -18,6 +18,7 @@ import (
 
 func TestProfileHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockProfileService)
 	handler := handlers.NewProfileHandler(mockSvc)
 	userID := int64(1)
@@ -43,6 +44,7 @@ func TestProfileHandler_Create(t *testing.T) {
 
 func TestProfileHandler_FindAll(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockProfileService)
 	handler := handlers.NewProfileHandler(mockSvc)
 	userID := int64(1)
@@ -65,6 +67,7 @@ func TestProfileHandler_FindAll(t *testing.T) {
 
 func TestProfileHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockProfileService)
 	handler := handlers.NewProfileHandler(mockSvc)
 	userID := int64(1)
@@ -94,6 +97,7 @@ func TestProfileHandler_Update(t *testing.T) {
 
 func TestProfileHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockProfileService)
 	handler := handlers.NewProfileHandler(mockSvc)
 	userID := int64(1)
@@ -18,6 +18,7 @@ import (
 
 func TestReviewHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockReviewService)
 	handler := handlers.NewReviewHandler(mockSvc)
 	userID := int64(1)
@@ -47,6 +48,7 @@ func TestReviewHandler_Create(t *testing.T) {
 
 func TestReviewHandler_FindByCardID(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockReviewService)
 	handler := handlers.NewReviewHandler(mockSvc)
 	userID := int64(1)
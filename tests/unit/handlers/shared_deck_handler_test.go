@@ -18,6 +18,7 @@ import (
 
 func TestSharedDeckHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckService)
 	handler := handlers.NewSharedDeckHandler(mockSvc)
 	userID := int64(1)
@@ -47,6 +48,7 @@ func TestSharedDeckHandler_Create(t *testing.T) {
 
 func TestSharedDeckHandler_FindAll(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckService)
 	handler := handlers.NewSharedDeckHandler(mockSvc)
 
@@ -67,6 +69,7 @@ func TestSharedDeckHandler_FindAll(t *testing.T) {
 
 func TestSharedDeckHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckService)
 	handler := handlers.NewSharedDeckHandler(mockSvc)
 	userID := int64(1)
@@ -99,6 +102,7 @@ func TestSharedDeckHandler_Update(t *testing.T) {
 
 func TestSharedDeckHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckService)
 	handler := handlers.NewSharedDeckHandler(mockSvc)
 	userID := int64(1)
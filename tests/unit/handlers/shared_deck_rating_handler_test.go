@@ -23,6 +23,7 @@ func strPtr(s string) *string {
 
 func TestSharedDeckRatingHandler_Create(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckRatingService)
 	handler := handlers.NewSharedDeckRatingHandler(mockSvc)
 	userID := int64(1)
@@ -54,6 +55,7 @@ func TestSharedDeckRatingHandler_Create(t *testing.T) {
 
 func TestSharedDeckRatingHandler_FindBySharedDeckID(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckRatingService)
 	handler := handlers.NewSharedDeckRatingHandler(mockSvc)
 	sharedDeckID := int64(10)
@@ -79,6 +81,7 @@ func TestSharedDeckRatingHandler_FindBySharedDeckID(t *testing.T) {
 
 func TestSharedDeckRatingHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckRatingService)
 	handler := handlers.NewSharedDeckRatingHandler(mockSvc)
 	userID := int64(1)
@@ -112,6 +115,7 @@ func TestSharedDeckRatingHandler_Update(t *testing.T) {
 
 func TestSharedDeckRatingHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSharedDeckRatingService)
 	handler := handlers.NewSharedDeckRatingHandler(mockSvc)
 	userID := int64(1)
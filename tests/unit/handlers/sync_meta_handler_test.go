@@ -18,6 +18,7 @@ import (
 
 func TestSyncMetaHandler_FindMe(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSyncMetaService)
 	handler := handlers.NewSyncMetaHandler(mockSvc)
 	userID := int64(1)
@@ -40,6 +41,7 @@ func TestSyncMetaHandler_FindMe(t *testing.T) {
 
 func TestSyncMetaHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockSyncMetaService)
 	handler := handlers.NewSyncMetaHandler(mockSvc)
 	userID := int64(1)
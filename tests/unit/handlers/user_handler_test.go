@@ -19,6 +19,7 @@ import (
 
 func TestUserHandler_GetMe(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockUserService)
 	handler := handlers.NewUserHandler(mockSvc)
 	userID := int64(1)
@@ -43,6 +44,7 @@ func TestUserHandler_GetMe(t *testing.T) {
 
 func TestUserHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockUserService)
 	handler := handlers.NewUserHandler(mockSvc)
 	userID := int64(1)
@@ -70,6 +72,7 @@ func TestUserHandler_Update(t *testing.T) {
 
 func TestUserHandler_Delete(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockUserService)
 	handler := handlers.NewUserHandler(mockSvc)
 	userID := int64(1)
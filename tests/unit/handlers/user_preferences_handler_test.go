@@ -18,6 +18,7 @@ import (
 
 func TestUserPreferencesHandler_FindByUserID(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockUserPreferencesService)
 	handler := handlers.NewUserPreferencesHandler(mockSvc)
 	userID := int64(1)
@@ -40,12 +41,13 @@ func TestUserPreferencesHandler_FindByUserID(t *testing.T) {
 
 func TestUserPreferencesHandler_Update(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockUserPreferencesService)
 	handler := handlers.NewUserPreferencesHandler(mockSvc)
 	userID := int64(1)
 
 	t.Run("Success", func(t *testing.T) {
-		reqBody := request.UpdateUserPreferencesRequest{Language: "pt-BR"}
+		reqBody := request.UpdateUserPreferencesRequest{Language: "pt-BR", Theme: "dark"}
 		body, _ := json.Marshal(reqBody)
 		req := httptest.NewRequest(http.MethodPut, "/api/v1/user/preferences", bytes.NewReader(body))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -53,6 +55,8 @@ func TestUserPreferencesHandler_Update(t *testing.T) {
 		c := e.NewContext(req, rec)
 		c.Set(middlewares.UserIDContextKey, userID)
 
+		existing, _ := userpreferences.NewBuilder().WithID(1).WithUserID(userID).Build()
+		mockSvc.On("FindByUserID", mock.Anything, userID).Return(existing, nil).Once()
 		mockSvc.On("Update", mock.Anything, userID, mock.AnythingOfType("*userpreferences.UserPreferences")).Return(nil).Once()
 
 		if assert.NoError(t, handler.Update(c)) {
@@ -64,6 +68,7 @@ func TestUserPreferencesHandler_Update(t *testing.T) {
 
 func TestUserPreferencesHandler_ResetToDefaults(t *testing.T) {
 	e := echo.New()
+	e.Validator = middlewares.NewCustomValidator()
 	mockSvc := new(MockUserPreferencesService)
 	handler := handlers.NewUserPreferencesHandler(mockSvc)
 	userID := int64(1)
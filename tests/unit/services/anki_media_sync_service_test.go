@@ -0,0 +1,290 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	userpreferences "github.com/felipesantos/anki-backend/core/domain/entities/user_preferences"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	mediaSvc "github.com/felipesantos/anki-backend/core/services/media"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
+)
+
+type ankiMediaSyncTestDeps struct {
+	repo      *MockMediaRepository
+	storage   *MockStorageRepository
+	syncRepo  *MockAnkiSyncRepository
+	prefsRepo *MockUserPreferencesRepository
+	service   primary.IAnkiMediaSyncService
+}
+
+// newAnkiMediaSyncTestService creates an AnkiMediaSyncService for a user with the given preferences, nil for none
+func newAnkiMediaSyncTestService(quotaBytes int64, prefs *userpreferences.UserPreferences) *ankiMediaSyncTestDeps {
+	deps := &ankiMediaSyncTestDeps{
+		repo:      new(MockMediaRepository),
+		storage:   new(MockStorageRepository),
+		syncRepo:  new(MockAnkiSyncRepository),
+		prefsRepo: new(MockUserPreferencesRepository),
+	}
+	if prefs == nil {
+		deps.prefsRepo.On("FindByUserID", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	} else {
+		deps.prefsRepo.On("FindByUserID", mock.Anything, mock.Anything).Return(prefs, nil).Maybe()
+	}
+	deps.service = mediaSvc.NewAnkiMediaSyncService(deps.repo, deps.storage, deps.syncRepo, deps.prefsRepo, quotaBytes)
+	return deps
+}
+
+// mediaZip builds a zip with the given entries
+func mediaZip(t *testing.T, entries map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range entries {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestAnkiMediaSyncService_Begin(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	t.Run("Success", func(t *testing.T) {
+		prefs, err := userpreferences.NewBuilder().WithUserID(userID).WithSyncAudioAndImages(true).WithPeriodicallySyncMedia(true).Build()
+		require.NoError(t, err)
+		deps := newAnkiMediaSyncTestService(0, prefs)
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(14, nil).Once()
+
+		begin, err := deps.service.Begin(ctx, userID)
+
+		require.NoError(t, err)
+		assert.Equal(t, 14, begin.USN)
+		assert.True(t, begin.PeriodicSync)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		prefs, err := userpreferences.NewBuilder().WithUserID(userID).WithSyncAudioAndImages(false).Build()
+		require.NoError(t, err)
+		deps := newAnkiMediaSyncTestService(0, prefs)
+
+		_, err = deps.service.Begin(ctx, userID)
+
+		assert.ErrorIs(t, err, mediaSvc.ErrMediaSyncDisabled)
+		deps.syncRepo.AssertNotCalled(t, "AdvanceUSN", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAnkiMediaSyncService_Changes(t *testing.T) {
+	deps := newAnkiMediaSyncTestService(0, nil)
+	ctx := context.Background()
+	userID := int64(1)
+	deps.syncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 10}, nil).Once()
+	deps.syncRepo.On("FindMediaChanges", ctx, userID, 3, 9, mock.Anything).Return([]*secondary.AnkiMediaChange{
+		{Filename: "a.png", USN: 5, Hash: "abc"},
+		{Filename: "b.mp3", USN: 7},
+	}, nil).Once()
+
+	changes, err := deps.service.Changes(ctx, userID, 3)
+
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	data, err := json.Marshal(changes)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[["a.png", 5, "abc"], ["b.mp3", 7, ""]]`, string(data))
+}
+
+func TestAnkiMediaSyncService_Upload(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	content := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	hash := media.ContentHash(content)
+	path := media.ContentPath(userID, hash)
+
+	t.Run("Adds And Deletes", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(1<<20, nil)
+		old, _ := media.NewBuilder().WithID(8).WithUserID(userID).WithFilename("old.mp3").WithHash("def").WithSize(5).WithMimeType("audio/mpeg").WithStoragePath("p").Build()
+		zipData := mediaZip(t, map[string][]byte{
+			"0":     content,
+			"_meta": []byte(`[["img.png", "0"], ["old.mp3", null]]`),
+		})
+
+		var uploaded []byte
+		deps.repo.On("FindByFilename", ctx, userID, "img.png").Return(nil, nil).Once()
+		deps.repo.On("TotalSize", ctx, userID).Return(int64(100), nil).Once()
+		deps.storage.On("Exists", ctx, path).Return(false, nil).Once()
+		deps.storage.On("Upload", ctx, mock.Anything, path, "image/png").Run(func(args mock.Arguments) {
+			uploaded, _ = io.ReadAll(args.Get(1).(io.Reader))
+		}).Return(&secondary.FileInfo{}, nil).Once()
+		deps.repo.On("Save", ctx, userID, mock.MatchedBy(func(m *media.Media) bool {
+			return m.GetFilename() == "img.png" && m.GetHash() == hash
+		})).Return(nil).Once()
+		deps.repo.On("FindByFilename", ctx, userID, "old.mp3").Return(old, nil).Once()
+		deps.repo.On("Delete", ctx, userID, int64(8)).Return(nil).Once()
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(12, nil).Once()
+
+		result, err := deps.service.Upload(ctx, userID, zipData)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Processed)
+		assert.Equal(t, 12, result.USN)
+		assert.Equal(t, content, uploaded)
+		deps.repo.AssertExpectations(t)
+	})
+
+	t.Run("Unchanged Content", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(0, nil)
+		existing, _ := media.NewBuilder().WithID(3).WithUserID(userID).WithFilename("img.png").WithHash(hash).WithSize(16).WithMimeType("image/png").WithStoragePath(path).Build()
+		deps.repo.On("FindByFilename", ctx, userID, "img.png").Return(existing, nil).Once()
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(12, nil).Once()
+
+		result, err := deps.service.Upload(ctx, userID, mediaZip(t, map[string][]byte{
+			"0":     content,
+			"_meta": []byte(`[["img.png", "0"]]`),
+		}))
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Processed)
+		deps.repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+		deps.storage.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Replaced Content", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(0, nil)
+		existing, _ := media.NewBuilder().WithID(3).WithUserID(userID).WithFilename("img.png").WithHash("old").WithSize(16).WithMimeType("image/png").WithStoragePath("p").Build()
+		deps.repo.On("FindByFilename", ctx, userID, "img.png").Return(existing, nil).Once()
+		deps.storage.On("Exists", ctx, path).Return(true, nil).Once()
+		deps.repo.On("Delete", ctx, userID, int64(3)).Return(nil).Once()
+		deps.repo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(12, nil).Once()
+
+		_, err := deps.service.Upload(ctx, userID, mediaZip(t, map[string][]byte{
+			"0":     content,
+			"_meta": []byte(`[["img.png", "0"]]`),
+		}))
+
+		require.NoError(t, err)
+		deps.repo.AssertExpectations(t)
+	})
+
+	t.Run("Content Stored Under Another Name", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(0, nil)
+		deps.repo.On("FindByFilename", ctx, userID, "copy.png").Return(nil, nil).Once()
+		deps.storage.On("Exists", ctx, path).Return(true, nil).Once()
+		deps.repo.On("Save", ctx, userID, mock.Anything).Return(media.ErrDuplicateContent).Once()
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(12, nil).Once()
+
+		result, err := deps.service.Upload(ctx, userID, mediaZip(t, map[string][]byte{
+			"0":     content,
+			"_meta": []byte(`[["copy.png", "0"]]`),
+		}))
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Processed)
+		deps.repo.AssertExpectations(t)
+	})
+
+	t.Run("Quota Exceeded", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(20, nil)
+		deps.repo.On("FindByFilename", ctx, userID, "img.png").Return(nil, nil).Once()
+		deps.repo.On("TotalSize", ctx, userID).Return(int64(10), nil).Once()
+
+		_, err := deps.service.Upload(ctx, userID, mediaZip(t, map[string][]byte{
+			"0":     content,
+			"_meta": []byte(`[["img.png", "0"]]`),
+		}))
+
+		assert.ErrorIs(t, err, mediaSvc.ErrQuotaExceeded)
+	})
+
+	t.Run("Invalid Zip", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(0, nil)
+
+		_, err := deps.service.Upload(ctx, userID, []byte("not a zip"))
+		assert.ErrorIs(t, err, mediaSvc.ErrInvalidMediaZip)
+
+		_, err = deps.service.Upload(ctx, userID, mediaZip(t, map[string][]byte{"0": content}))
+		assert.ErrorIs(t, err, mediaSvc.ErrInvalidMediaZip)
+	})
+
+	t.Run("Invalid Filename", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(0, nil)
+
+		_, err := deps.service.Upload(ctx, userID, mediaZip(t, map[string][]byte{
+			"0":     content,
+			"_meta": []byte(`[["../img.png", "0"]]`),
+		}))
+
+		assert.ErrorIs(t, err, mediaSvc.ErrInvalidFilename)
+	})
+}
+
+func TestAnkiMediaSyncService_Download(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	t.Run("Success", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(0, nil)
+		a, _ := media.NewBuilder().WithID(1).WithUserID(userID).WithFilename("a.png").WithHash("h1").WithSize(3).WithMimeType("image/png").WithStoragePath("media/1/h1").Build()
+		b, _ := media.NewBuilder().WithID(2).WithUserID(userID).WithFilename("b.mp3").WithHash("h2").WithSize(3).WithMimeType("audio/mpeg").WithStoragePath("media/1/h2").Build()
+		deps.repo.On("FindByFilename", ctx, userID, "a.png").Return(a, nil).Once()
+		deps.repo.On("FindByFilename", ctx, userID, "b.mp3").Return(b, nil).Once()
+		deps.storage.On("Download", ctx, "media/1/h1").Return([]byte("aaa"), nil).Once()
+		deps.storage.On("Download", ctx, "media/1/h2").Return([]byte("bbb"), nil).Once()
+
+		data, err := deps.service.Download(ctx, userID, []string{"a.png", "b.mp3"})
+
+		require.NoError(t, err)
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		contents := map[string]string{}
+		for _, f := range reader.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, _ := io.ReadAll(rc)
+			rc.Close()
+			contents[f.Name] = string(content)
+		}
+		assert.Equal(t, "aaa", contents["0"])
+		assert.Equal(t, "bbb", contents["1"])
+		assert.JSONEq(t, `{"0": "a.png", "1": "b.mp3"}`, contents["_meta"])
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		deps := newAnkiMediaSyncTestService(0, nil)
+		deps.repo.On("FindByFilename", ctx, userID, "gone.png").Return(nil, nil).Once()
+
+		_, err := deps.service.Download(ctx, userID, []string{"gone.png"})
+
+		assert.ErrorIs(t, err, ownership.ErrResourceNotFound)
+	})
+}
+
+func TestAnkiMediaSyncService_Sanity(t *testing.T) {
+	deps := newAnkiMediaSyncTestService(0, nil)
+	ctx := context.Background()
+	userID := int64(1)
+	m, _ := media.NewBuilder().WithID(1).WithUserID(userID).WithFilename("a.png").WithHash("h1").WithSize(3).WithMimeType("image/png").WithStoragePath("p").Build()
+	deps.repo.On("FindByUserID", ctx, userID).Return([]*media.Media{m}, nil).Twice()
+
+	status, err := deps.service.Sanity(ctx, userID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, primary.AnkiMediaSanityOK, status)
+
+	status, err = deps.service.Sanity(ctx, userID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, primary.AnkiMediaSanityFailed, status)
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/felipesantos/anki-backend/config"
+	"github.com/felipesantos/anki-backend/core/domain/entities/user"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	syncSvc "github.com/felipesantos/anki-backend/core/services/sync"
+	"github.com/felipesantos/anki-backend/pkg/jwt"
+)
+
+type ankiSyncTestDeps struct {
+	userRepo   *MockUserRepository
+	syncRepo   *MockAnkiSyncRepository
+	cache      map[string]string
+	cacheRepo  secondary.ICacheRepository
+	jwtService *jwt.JWTService
+	tm         *MockTransactionManager
+	service    primary.IAnkiSyncService
+}
+
+// newAnkiSyncTestService creates an AnkiSyncService whose sync sessions are kept in a map
+func newAnkiSyncTestService(t *testing.T) *ankiSyncTestDeps {
+	jwtService, err := jwt.NewJWTService(config.JWTConfig{
+		SecretKey:          "this-is-a-test-secret-key-with-at-least-32-chars",
+		AccessTokenExpiry:  15,
+		RefreshTokenExpiry: 7,
+		Issuer:             "test-issuer",
+	})
+	require.NoError(t, err)
+
+	deps := &ankiSyncTestDeps{
+		userRepo: new(MockUserRepository),
+		syncRepo: new(MockAnkiSyncRepository),
+		cache:    map[string]string{},
+		tm:       new(MockTransactionManager),
+	}
+	cacheRepo := &mockCacheRepository{
+		getFunc: func(ctx context.Context, key string) (string, error) {
+			value, ok := deps.cache[key]
+			if !ok {
+				return "", errors.New("key not found")
+			}
+			return value, nil
+		},
+		setFunc: func(ctx context.Context, key string, value string, ttl time.Duration) error {
+			deps.cache[key] = value
+			return nil
+		},
+		deleteFunc: func(ctx context.Context, key string) error {
+			delete(deps.cache, key)
+			return nil
+		},
+		existsFunc: func(ctx context.Context, key string) (bool, error) {
+			_, ok := deps.cache[key]
+			return ok, nil
+		},
+	}
+
+	deps.cacheRepo, deps.jwtService = cacheRepo, jwtService
+	deps.service = syncSvc.NewAnkiSyncService(
+		deps.userRepo,
+		jwtService,
+		cacheRepo,
+		deps.syncRepo,
+		new(MockNoteRepository),
+		new(MockCardRepository),
+		new(MockDeckRepository),
+		new(MockNoteTypeRepository),
+		new(MockDeckOptionsPresetRepository),
+		new(MockFilteredDeckRepository),
+		new(MockReviewRepository),
+		deps.tm,
+	)
+	return deps
+}
+
+func TestAnkiSyncService_HostKey(t *testing.T) {
+	ctx := context.Background()
+	email, _ := valueobjects.NewEmail("sync@example.com")
+	password, _ := valueobjects.NewPassword("password123")
+	u, _ := user.NewBuilder().WithID(7).WithEmail(email).WithPasswordHash(password).Build()
+
+	t.Run("Success", func(t *testing.T) {
+		deps := newAnkiSyncTestService(t)
+		deps.userRepo.On("FindByEmail", ctx, "sync@example.com").Return(u, nil).Once()
+		deps.userRepo.On("FindByID", ctx, int64(7)).Return(u, nil).Once()
+
+		key, err := deps.service.HostKey(ctx, "sync@example.com", "password123")
+		require.NoError(t, err)
+		assert.NotEmpty(t, key)
+
+		userID, err := deps.service.Authenticate(ctx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), userID)
+	})
+
+	t.Run("Revoked Key", func(t *testing.T) {
+		deps := newAnkiSyncTestService(t)
+		deps.userRepo.On("FindByEmail", ctx, "sync@example.com").Return(u, nil).Once()
+
+		key, err := deps.service.HostKey(ctx, "sync@example.com", "password123")
+		require.NoError(t, err)
+		require.NoError(t, syncSvc.RevokeHostKeys(ctx, deps.cacheRepo, 7, time.Hour))
+
+		_, err = deps.service.Authenticate(ctx, key)
+		assert.ErrorIs(t, err, syncSvc.ErrInvalidHostKey)
+	})
+
+	t.Run("Inactive User", func(t *testing.T) {
+		deps := newAnkiSyncTestService(t)
+		deletedAt := time.Now()
+		deleted, _ := user.NewBuilder().WithID(7).WithEmail(email).WithPasswordHash(password).WithDeletedAt(&deletedAt).Build()
+		deps.userRepo.On("FindByEmail", ctx, "sync@example.com").Return(u, nil).Once()
+		deps.userRepo.On("FindByID", ctx, int64(7)).Return(deleted, nil).Once()
+
+		key, err := deps.service.HostKey(ctx, "sync@example.com", "password123")
+		require.NoError(t, err)
+
+		_, err = deps.service.Authenticate(ctx, key)
+		assert.ErrorIs(t, err, syncSvc.ErrInvalidHostKey)
+	})
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		deps := newAnkiSyncTestService(t)
+		deps.userRepo.On("FindByEmail", ctx, "sync@example.com").Return(u, nil).Once()
+
+		_, err := deps.service.HostKey(ctx, "sync@example.com", "wrong-password1")
+		assert.ErrorIs(t, err, syncSvc.ErrSyncAuthFailed)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		deps := newAnkiSyncTestService(t)
+		deps.userRepo.On("FindByEmail", ctx, "nobody@example.com").Return(nil, nil).Once()
+
+		_, err := deps.service.HostKey(ctx, "nobody@example.com", "password123")
+		assert.ErrorIs(t, err, syncSvc.ErrSyncAuthFailed)
+	})
+}
+
+func TestAnkiSyncService_Authenticate_InvalidKey(t *testing.T) {
+	deps := newAnkiSyncTestService(t)
+
+	_, err := deps.service.Authenticate(context.Background(), "not-a-host-key")
+	assert.ErrorIs(t, err, syncSvc.ErrInvalidHostKey)
+
+	// Signed keys that were never issued by HostKey are rejected as well
+	key, err := deps.jwtService.GenerateSyncHostKey(7)
+	require.NoError(t, err)
+	_, err = deps.service.Authenticate(context.Background(), key)
+	assert.ErrorIs(t, err, syncSvc.ErrInvalidHostKey)
+}
+
+func TestAnkiSyncService_Meta(t *testing.T) {
+	deps := newAnkiSyncTestService(t)
+	ctx := context.Background()
+	userID := int64(1)
+	modified := time.UnixMilli(1700000000000)
+	deps.syncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 12, ModifiedAt: modified, SchemaModifiedAt: modified}, nil).Once()
+	deps.syncRepo.On("Count", ctx, userID).Return(&secondary.AnkiSyncCounts{}, nil).Once()
+
+	meta, err := deps.service.Meta(ctx, userID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 12, meta.USN)
+	assert.Equal(t, int64(1700000000000), meta.Modified)
+	assert.True(t, meta.Continue)
+	assert.True(t, meta.Empty)
+}
+
+func TestAnkiSyncService_Start(t *testing.T) {
+	deps := newAnkiSyncTestService(t)
+	ctx := context.Background()
+	userID := int64(1)
+	deps.syncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 5}, nil)
+	deps.syncRepo.On("FindDeletedIDs", ctx, userID, secondary.SyncObjectCard, 3).Return([]int64{40}, nil).Once()
+	deps.syncRepo.On("FindDeletedIDs", ctx, userID, mock.Anything, 3).Return([]int64{}, nil)
+	deps.syncRepo.On("FindAnkiIDs", ctx, userID, secondary.SyncObjectCard, []int64{40}).
+		Return([]*secondary.AnkiSyncID{{ObjectType: secondary.SyncObjectCard, ObjectID: 40, AnkiID: 1650000000000}}, nil).Once()
+
+	graves, err := deps.service.Start(ctx, userID, 3, false, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1650000000000}, graves.Cards)
+	assert.Empty(t, graves.Notes)
+	assert.Empty(t, graves.Decks)
+	assert.Len(t, deps.cache, 1)
+}
+
+func TestAnkiSyncService_WithoutSession(t *testing.T) {
+	deps := newAnkiSyncTestService(t)
+	ctx := context.Background()
+	userID := int64(1)
+
+	_, err := deps.service.Chunk(ctx, userID)
+	assert.ErrorIs(t, err, syncSvc.ErrSyncSessionNotFound)
+
+	err = deps.service.ApplyChunk(ctx, userID, &primary.AnkiSyncChunk{Done: true})
+	assert.ErrorIs(t, err, syncSvc.ErrSyncSessionNotFound)
+
+	_, err = deps.service.Finish(ctx, userID)
+	assert.ErrorIs(t, err, syncSvc.ErrSyncSessionNotFound)
+}
+
+func TestAnkiSyncService_SanityCheck(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	start := func(t *testing.T) *ankiSyncTestDeps {
+		deps := newAnkiSyncTestService(t)
+		deps.syncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 5}, nil)
+		deps.syncRepo.On("FindDeletedIDs", ctx, userID, mock.Anything, 0).Return([]int64{}, nil)
+		_, err := deps.service.Start(ctx, userID, 0, false, nil)
+		require.NoError(t, err)
+
+		deps.syncRepo.On("Count", ctx, userID).Return(&secondary.AnkiSyncCounts{Cards: 2, Notes: 1, NoteTypes: 1, Decks: 1}, nil)
+		deps.syncRepo.On("FindObjectIDs", ctx, userID, secondary.SyncObjectDeck, []int64{1}).
+			Return([]*secondary.AnkiSyncID{{ObjectType: secondary.SyncObjectDeck, ObjectID: 10, AnkiID: 1}}, nil)
+		deps.syncRepo.On("FindObjectIDs", ctx, userID, secondary.SyncObjectPreset, []int64{1}).Return([]*secondary.AnkiSyncID{}, nil)
+		return deps
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		deps := start(t)
+		client := &primary.AnkiSyncCounts{Cards: 2, Notes: 1, Revlog: 4, Models: 1, Decks: 1, DeckConfs: 1}
+
+		result, err := deps.service.SanityCheck(ctx, userID, client)
+
+		require.NoError(t, err)
+		assert.Equal(t, primary.AnkiSyncSanityOK, result.Status)
+	})
+
+	t.Run("Bad", func(t *testing.T) {
+		deps := start(t)
+		client := &primary.AnkiSyncCounts{Cards: 3, Notes: 1, Models: 1, Decks: 1, DeckConfs: 1}
+
+		result, err := deps.service.SanityCheck(ctx, userID, client)
+
+		require.NoError(t, err)
+		assert.Equal(t, primary.AnkiSyncSanityBad, result.Status)
+		assert.Equal(t, 2, result.Server.Cards)
+	})
+}
+
+func TestAnkiSyncService_Finish(t *testing.T) {
+	deps := newAnkiSyncTestService(t)
+	ctx := context.Background()
+	userID := int64(1)
+	deps.syncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 5}, nil)
+	deps.syncRepo.On("FindDeletedIDs", ctx, userID, mock.Anything, 5).Return([]int64{}, nil)
+	deps.syncRepo.On("UpdateState", ctx, userID, mock.MatchedBy(func(s *secondary.AnkiSyncState) bool {
+		return s.USN == 6 && s.LastSyncAt != nil
+	})).Return(nil).Once()
+
+	_, err := deps.service.Start(ctx, userID, 5, false, nil)
+	require.NoError(t, err)
+
+	mod, err := deps.service.Finish(ctx, userID)
+
+	require.NoError(t, err)
+	assert.Greater(t, mod, int64(0))
+	assert.Empty(t, deps.cache)
+	deps.syncRepo.AssertExpectations(t)
+}
+
+func TestAnkiSyncService_Upload_InvalidCollection(t *testing.T) {
+	deps := newAnkiSyncTestService(t)
+
+	err := deps.service.Upload(context.Background(), 1, []byte("not a collection"))
+
+	assert.ErrorIs(t, err, syncSvc.ErrInvalidCollection)
+	deps.syncRepo.AssertNotCalled(t, "DeleteCollection", mock.Anything, mock.Anything)
+}
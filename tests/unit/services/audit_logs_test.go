@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/check_database_log"
 	deletionlog "github.com/felipesantos/anki-backend/core/domain/entities/deletion_log"
 	"github.com/felipesantos/anki-backend/core/domain/entities/note"
@@ -419,7 +420,7 @@ func TestDeletionLogService_Restore(t *testing.T) {
 
 func TestUndoHistoryService_Create(t *testing.T) {
 	mockRepo := new(MockUndoHistoryRepository)
-	service := auditSvc.NewUndoHistoryService(mockRepo)
+	service := auditSvc.NewUndoHistoryService(mockRepo, new(MockNoteRepository), new(MockCardRepository), new(MockDeckRepository), new(MockTransactionManager))
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -434,6 +435,168 @@ func TestUndoHistoryService_Create(t *testing.T) {
 	})
 }
 
+func TestUndoHistoryService_Record(t *testing.T) {
+	mockRepo := new(MockUndoHistoryRepository)
+	service := auditSvc.NewUndoHistoryService(mockRepo, new(MockNoteRepository), new(MockCardRepository), new(MockDeckRepository), new(MockTransactionManager))
+	ctx := context.Background()
+	userID := int64(1)
+
+	t.Run("Success clears the redo stack", func(t *testing.T) {
+		n, _ := note.NewBuilder().WithID(100).WithFieldsJSON(`{"Front":"a"}`).Build()
+		changes := undohistory.NewChangeSet()
+		changes.AddNote(100, nil, n.Snapshot())
+
+		mockRepo.On("DeleteUndone", ctx, userID).Return(nil).Once()
+		mockRepo.On("Save", ctx, userID, mock.MatchedBy(func(uh *undohistory.UndoHistory) bool {
+			return uh.GetOperationType() == undohistory.OperationTypeAddNote && !uh.IsUndone()
+		})).Return(nil).Once()
+
+		err := service.Record(ctx, userID, undohistory.OperationTypeAddNote, changes)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty change set is not recorded", func(t *testing.T) {
+		err := service.Record(ctx, userID, undohistory.OperationTypeEditCard, undohistory.NewChangeSet())
+
+		assert.NoError(t, err)
+		mockRepo.AssertNumberOfCalls(t, "Save", 1)
+	})
+}
+
+func TestUndoHistoryService_Undo(t *testing.T) {
+	mockRepo := new(MockUndoHistoryRepository)
+	mockNoteRepo := new(MockNoteRepository)
+	mockCardRepo := new(MockCardRepository)
+	mockTM := new(MockTransactionManager)
+	mockTM.ExpectTransaction()
+	service := auditSvc.NewUndoHistoryService(mockRepo, mockNoteRepo, mockCardRepo, new(MockDeckRepository), mockTM)
+	ctx := context.Background()
+	userID := int64(1)
+	noteID := int64(100)
+
+	editEntry := func() *undohistory.UndoHistory {
+		before, _ := note.NewBuilder().WithID(noteID).WithFieldsJSON(`{"Front":"old"}`).WithTags([]string{"a"}).Build()
+		after, _ := note.NewBuilder().WithID(noteID).WithFieldsJSON(`{"Front":"new"}`).WithTags([]string{"a", "b"}).Build()
+		changes := undohistory.NewChangeSet()
+		changes.AddNote(noteID, before.Snapshot(), after.Snapshot())
+		data, _ := changes.Encode()
+		uh, _ := undohistory.NewBuilder().WithID(7).WithUserID(userID).WithOperationType(undohistory.OperationTypeEditNote).WithOperationData(data).Build()
+		return uh
+	}
+
+	t.Run("Success restores the previous note state", func(t *testing.T) {
+		entry := editEntry()
+		current, _ := note.NewBuilder().WithID(noteID).WithFieldsJSON(`{"Front": "new"}`).WithTags([]string{"a", "b"}).Build()
+
+		mockRepo.On("FindLatestApplied", ctx, userID, undohistory.ChangeSetOperationTypes).Return(entry, nil).Once()
+		mockNoteRepo.On("FindByID", ctx, userID, noteID).Return(current, nil).Twice()
+		mockNoteRepo.On("Update", ctx, userID, noteID, current).Return(nil).Once()
+		mockRepo.On("Update", ctx, userID, int64(7), entry).Return(nil).Once()
+
+		result, err := service.Undo(ctx, userID)
+
+		assert.NoError(t, err)
+		assert.True(t, result.IsUndone())
+		assert.Equal(t, `{"Front":"old"}`, current.GetFieldsJSON())
+		assert.Equal(t, []string{"a"}, current.GetTags())
+		mockRepo.AssertExpectations(t)
+		mockNoteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Conflict when the note changed since", func(t *testing.T) {
+		entry := editEntry()
+		current, _ := note.NewBuilder().WithID(noteID).WithFieldsJSON(`{"Front":"edited again"}`).WithTags([]string{"a", "b"}).Build()
+
+		mockRepo.On("FindLatestApplied", ctx, userID, undohistory.ChangeSetOperationTypes).Return(entry, nil).Once()
+		mockNoteRepo.On("FindByID", ctx, userID, noteID).Return(current, nil).Once()
+
+		result, err := service.Undo(ctx, userID)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, auditSvc.ErrUndoConflict))
+		assert.Nil(t, result)
+		assert.False(t, entry.IsUndone())
+		mockRepo.AssertExpectations(t)
+		mockNoteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Deleted card is inserted again", func(t *testing.T) {
+		c, _ := card.NewBuilder().WithID(55).WithNoteID(noteID).WithDeckID(3).WithPosition(9).WithState(valueobjects.CardStateNew).Build()
+		changes := undohistory.NewChangeSet()
+		changes.AddCard(55, c.Snapshot(), nil)
+		data, _ := changes.Encode()
+		entry, _ := undohistory.NewBuilder().WithID(8).WithUserID(userID).WithOperationType(undohistory.OperationTypeDeleteCard).WithOperationData(data).Build()
+
+		mockRepo.On("FindLatestApplied", ctx, userID, undohistory.ChangeSetOperationTypes).Return(entry, nil).Once()
+		mockCardRepo.On("FindByID", ctx, userID, int64(55)).Return(nil, ownership.ErrResourceNotFound).Twice()
+		mockCardRepo.On("Restore", ctx, userID, mock.MatchedBy(func(restored *card.Card) bool {
+			return restored.GetID() == 55 && restored.GetDeckID() == 3 && restored.GetPosition() == 9
+		})).Return(nil).Once()
+		mockRepo.On("Update", ctx, userID, int64(8), entry).Return(nil).Once()
+
+		_, err := service.Undo(ctx, userID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockCardRepo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing to undo", func(t *testing.T) {
+		mockRepo.On("FindLatestApplied", ctx, userID, undohistory.ChangeSetOperationTypes).Return(nil, nil).Once()
+
+		result, err := service.Undo(ctx, userID)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "not found")
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUndoHistoryService_Redo(t *testing.T) {
+	mockRepo := new(MockUndoHistoryRepository)
+	mockCardRepo := new(MockCardRepository)
+	mockTM := new(MockTransactionManager)
+	mockTM.ExpectTransaction()
+	service := auditSvc.NewUndoHistoryService(mockRepo, new(MockNoteRepository), mockCardRepo, new(MockDeckRepository), mockTM)
+	ctx := context.Background()
+	userID := int64(1)
+
+	t.Run("Success deletes the card again", func(t *testing.T) {
+		c, _ := card.NewBuilder().WithID(55).WithNoteID(100).WithDeckID(3).WithState(valueobjects.CardStateNew).Build()
+		changes := undohistory.NewChangeSet()
+		changes.AddCard(55, c.Snapshot(), nil)
+		data, _ := changes.Encode()
+		undoneAt := time.Now()
+		entry, _ := undohistory.NewBuilder().WithID(8).WithUserID(userID).WithOperationType(undohistory.OperationTypeDeleteCard).WithOperationData(data).WithUndoneAt(&undoneAt).Build()
+
+		mockRepo.On("FindLatestUndone", ctx, userID, undohistory.ChangeSetOperationTypes).Return(entry, nil).Once()
+		mockCardRepo.On("FindByID", ctx, userID, int64(55)).Return(c, nil).Twice()
+		mockCardRepo.On("Delete", ctx, userID, int64(55)).Return(nil).Once()
+		mockRepo.On("Update", ctx, userID, int64(8), entry).Return(nil).Once()
+
+		result, err := service.Redo(ctx, userID)
+
+		assert.NoError(t, err)
+		assert.False(t, result.IsUndone())
+		mockRepo.AssertExpectations(t)
+		mockCardRepo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing to redo", func(t *testing.T) {
+		mockRepo.On("FindLatestUndone", ctx, userID, undohistory.ChangeSetOperationTypes).Return(nil, nil).Once()
+
+		result, err := service.Redo(ctx, userID)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "operation to redo not found")
+		mockRepo.AssertExpectations(t)
+	})
+}
+
 func TestCheckDatabaseLogService_Create(t *testing.T) {
 	mockRepo := new(MockCheckDatabaseLogRepository)
 	service := auditSvc.NewCheckDatabaseLogService(mockRepo)
@@ -172,6 +172,10 @@ func (m *mockDeckRepository) Delete(ctx context.Context, userID int64, deckID in
 	return nil
 }
 
+func (m *mockDeckRepository) Restore(ctx context.Context, userID int64, deckID int64) error {
+	return nil
+}
+
 func (m *mockDeckRepository) Exists(ctx context.Context, userID int64, name string, parentID *int64) (bool, error) {
 	return false, nil
 }
@@ -180,6 +184,14 @@ func (m *mockDeckRepository) GetStats(ctx context.Context, userID int64, deckID
 	return nil, nil
 }
 
+func (m *mockDeckRepository) ExtendLimits(ctx context.Context, userID int64, deckID int64, dayStart time.Time, newCards int, reviews int) (*deck.LimitExtension, error) {
+	return nil, nil
+}
+
+func (m *mockDeckRepository) FindLimitExtension(ctx context.Context, userID int64, deckID int64, dayStart time.Time) (*deck.LimitExtension, error) {
+	return nil, nil
+}
+
 // mockEventBus is a mock implementation of IEventBus
 type mockEventBus struct {
 	publishFunc func(ctx context.Context, event domainEvents.DomainEvent) error
@@ -990,10 +1002,15 @@ func TestAuthService_Logout_Success(t *testing.T) {
 
 	accessTokenBlacklisted := false
 	refreshTokenDeleted := false
+	hostKeysRevoked := false
 	cacheRepo := &mockCacheRepository{
 		setFunc: func(ctx context.Context, key string, value string, ttl time.Duration) error {
-			// Track that access token is blacklisted
-			accessTokenBlacklisted = true
+			// Track that access token is blacklisted and sync host keys are revoked
+			if key == "anki_sync_host_keys_revoked:1" {
+				hostKeysRevoked = true
+			} else {
+				accessTokenBlacklisted = true
+			}
 			return nil
 		},
 		deleteFunc: func(ctx context.Context, key string) error {
@@ -1025,6 +1042,10 @@ func TestAuthService_Logout_Success(t *testing.T) {
 	if !refreshTokenDeleted {
 		t.Errorf("Logout() should delete refresh token from cache")
 	}
+
+	if !hostKeysRevoked {
+		t.Errorf("Logout() should revoke sync host keys")
+	}
 }
 
 func TestAuthService_Logout_InvalidToken(t *testing.T) {
@@ -1709,7 +1730,13 @@ func TestAuthService_ChangePassword_Success(t *testing.T) {
 	emailSvc := &mockEmailService{}
 	deckRepo := &mockDeckRepository{}
 	eventBus := &mockEventBus{}
-	cacheRepo := &mockCacheRepository{}
+	hostKeysRevoked := false
+	cacheRepo := &mockCacheRepository{
+		setFunc: func(ctx context.Context, key string, value string, ttl time.Duration) error {
+			hostKeysRevoked = key == "anki_sync_host_keys_revoked:1"
+			return nil
+		},
+	}
 
 	sessionSvc := createTestSessionService()
 	service := authService.NewAuthService(userRepo, deckRepo, &mockProfileRepository{}, &mockUserPreferencesRepository{}, eventBus, jwtSvc, cacheRepo, emailSvc, sessionSvc, &mockTransactionManager{})
@@ -1724,6 +1751,10 @@ func TestAuthService_ChangePassword_Success(t *testing.T) {
 	if !userUpdated {
 		t.Errorf("ChangePassword() should update user password")
 	}
+
+	if !hostKeysRevoked {
+		t.Errorf("ChangePassword() should revoke sync host keys")
+	}
 }
 
 func TestAuthService_ChangePassword_InvalidCurrentPassword(t *testing.T) {
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/felipesantos/anki-backend/core/domain/entities/note"
 	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
 	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
 	cardSvc "github.com/felipesantos/anki-backend/core/services/card"
 	"github.com/stretchr/testify/assert"
@@ -24,7 +26,9 @@ func TestCardService_Suspend(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	mockUndoSvc := new(MockUndoHistoryService)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockUndoSvc, mockTM)
+	mockTM.ExpectTransaction()
 	ctx := context.Background()
 	userID := int64(1)
 	cardID := int64(100)
@@ -35,12 +39,22 @@ func TestCardService_Suspend(t *testing.T) {
 
 		mockRepo.On("FindByID", ctx, userID, cardID).Return(c, nil).Once()
 		mockRepo.On("Update", ctx, userID, cardID, mock.Anything).Return(nil).Once()
+		mockUndoSvc.On("Record", ctx, userID, undohistory.OperationTypeEditCard, mock.MatchedBy(func(cs *undohistory.ChangeSet) bool {
+			if len(cs.Changes) != 1 {
+				return false
+			}
+			var before, after card.Snapshot
+			_ = json.Unmarshal(cs.Changes[0].Before, &before)
+			_ = json.Unmarshal(cs.Changes[0].After, &after)
+			return !before.Suspended && after.Suspended
+		})).Return(nil).Once()
 
 		err := service.Suspend(ctx, userID, cardID)
 
 		assert.NoError(t, err)
 		assert.True(t, c.GetSuspended())
 		mockRepo.AssertExpectations(t)
+		mockUndoSvc.AssertExpectations(t)
 	})
 }
 
@@ -52,7 +66,8 @@ func TestCardService_SetFlag(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
+	mockTM.ExpectTransaction()
 	ctx := context.Background()
 	userID := int64(1)
 	cardID := int64(100)
@@ -92,7 +107,7 @@ func TestCardService_CountByDeckAndState(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 	deckID := int64(10)
@@ -124,7 +139,7 @@ func TestCardService_FindAll(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -323,7 +338,7 @@ func TestCardService_FindLeeches(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -364,7 +379,7 @@ func TestCardService_Reset(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
 
 	ctx := context.Background()
 	userID := int64(1)
@@ -378,6 +393,7 @@ func TestCardService_Reset(t *testing.T) {
 		mockTM.On("WithTransaction", ctx, mock.Anything).Return(nil).Once()
 		mockRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
 		mockRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewSvc.On("LogManual", mock.Anything, userID, c).Return(nil).Once()
 
 		err := service.Reset(ctx, userID, cardID, "new")
 		assert.NoError(t, err)
@@ -395,6 +411,7 @@ func TestCardService_Reset(t *testing.T) {
 		mockRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
 		mockReviewSvc.On("DeleteByCardID", mock.Anything, userID, cardID).Return(nil).Once()
 		mockRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewSvc.On("LogManual", mock.Anything, userID, c).Return(nil).Once()
 
 		err := service.Reset(ctx, userID, cardID, "forget")
 		assert.NoError(t, err)
@@ -422,7 +439,8 @@ func TestCardService_SetDueDate(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
+	mockTM.ExpectTransaction()
 
 	ctx := context.Background()
 	userID := int64(1)
@@ -434,11 +452,13 @@ func TestCardService_SetDueDate(t *testing.T) {
 
 		mockRepo.On("FindByID", ctx, userID, cardID).Return(c, nil).Once()
 		mockRepo.On("Update", ctx, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewSvc.On("LogManual", ctx, userID, c).Return(nil).Once()
 
 		err := service.SetDueDate(ctx, userID, cardID, due)
 		assert.NoError(t, err)
 		assert.Equal(t, due, c.GetDue())
 		mockRepo.AssertExpectations(t)
+		mockReviewSvc.AssertExpectations(t)
 	})
 
 	t.Run("Card not found", func(t *testing.T) {
@@ -459,7 +479,7 @@ func TestCardService_Reposition(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
 
 	ctx := context.Background()
 	userID := int64(1)
@@ -467,6 +487,11 @@ func TestCardService_Reposition(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		mockTM.On("WithTransaction", ctx, mock.Anything).Return(nil).Once()
+		for _, id := range cardIDs {
+			c, _ := card.NewBuilder().WithID(id).WithDeckID(1).WithPosition(int(id)).Build()
+			mockRepo.On("FindByID", mock.Anything, userID, id).Return(c, nil).Twice()
+		}
+		mockRepo.On("FindAll", mock.Anything, userID, mock.Anything).Return([]*card.Card{}, 0, nil).Once()
 		mockRepo.On("UpdatePositions", mock.Anything, userID, cardIDs, 10, 2, true).Return(nil).Once()
 
 		err := service.Reposition(ctx, userID, cardIDs, 10, 2, true)
@@ -489,7 +514,7 @@ func TestCardService_GetPosition(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
 
 	ctx := context.Background()
 	userID := int64(1)
@@ -524,7 +549,7 @@ func TestCardService_GetInfo(t *testing.T) {
 	mockReviewSvc := new(MockReviewService)
 	mockTM := new(MockTransactionManager)
 	mockTR := new(MockTemplateRenderer)
-	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, mockTM)
+	service := cardSvc.NewCardService(mockRepo, mockNoteSvc, mockDeckSvc, mockNoteTypeSvc, mockReviewSvc, mockTR, newRecordingUndoService(), mockTM)
 
 	ctx := context.Background()
 	userID := int64(1)
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	checkdatabaselog "github.com/felipesantos/anki-backend/core/domain/entities/check_database_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	auditSvc "github.com/felipesantos/anki-backend/core/services/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDatabaseService_CheckDatabase(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	basic, _ := notetype.NewBuilder().WithID(10).WithUserID(userID).WithName("Basic").
+		WithFieldsJSON(`[{"name":"Front"},{"name":"Back"}]`).
+		WithCardTypesJSON(`[{"name":"Card 1"}]`).Build()
+	cloze, _ := notetype.NewBuilder().WithID(11).WithUserID(userID).WithName("Cloze").
+		WithFieldsJSON(`[{"name":"Text"}]`).
+		WithCardTypesJSON(`[{"name":"Cloze","cloze":true}]`).Build()
+	defaultDeck, _ := deck.NewBuilder().WithID(5).WithUserID(userID).WithName("Default").Build()
+
+	t.Run("Repairs and logs the report", func(t *testing.T) {
+		checkRepo := new(MockDatabaseCheckRepository)
+		logRepo := new(MockCheckDatabaseLogRepository)
+		noteRepo := new(MockNoteRepository)
+		noteTypeRepo := new(MockNoteTypeRepository)
+		deckRepo := new(MockDeckRepository)
+		service := auditSvc.NewCheckDatabaseService(checkRepo, logRepo, noteRepo, noteTypeRepo, deckRepo)
+
+		guid, _ := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440000")
+		okNote, _ := note.NewBuilder().WithID(100).WithUserID(userID).WithNoteTypeID(10).WithFieldsJSON(`{"Front":"a","Back":"b"}`).Build()
+		missingField, _ := note.NewBuilder().WithID(101).WithUserID(userID).WithNoteTypeID(10).WithFieldsJSON(`{"Front":"a"}`).Build()
+		extraField, _ := note.NewBuilder().WithID(102).WithUserID(userID).WithNoteTypeID(10).WithFieldsJSON(`{"Front":"a","Back":"b","Extra":"c"}`).Build()
+		duplicate, _ := note.NewBuilder().WithID(103).WithUserID(userID).WithGUID(guid).WithNoteTypeID(10).WithFieldsJSON(`{"Front":"x","Back":"y"}`).Build()
+
+		checkRepo.On("DeleteCardsOfDeletedNotes", ctx, userID).Return([]int64{1, 2}, nil).Once()
+		deckRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{defaultDeck}, nil).Once()
+		checkRepo.On("MoveCardsFromDeletedDecks", ctx, userID, int64(5)).Return([]int64{3}, nil).Once()
+		checkRepo.On("ReturnCardsWithMissingHomeDeck", ctx, userID, int64(5)).Return([]int64{}, nil).Once()
+		noteTypeRepo.On("FindByUserID", ctx, userID, "").Return([]*notetype.NoteType{basic, cloze}, nil).Once()
+		checkRepo.On("DeleteCardsWithInvalidOrdinal", ctx, userID, int64(10), 1).Return([]int64{4}, nil).Once()
+		noteRepo.On("FindByUserID", ctx, userID, 1000, 0).Return([]*note.Note{okNote, missingField, extraField}, nil).Once()
+		noteRepo.On("Update", ctx, userID, int64(101), mock.Anything).Return(nil).Once()
+		noteRepo.On("Update", ctx, userID, int64(102), mock.Anything).Return(nil).Once()
+		checkRepo.On("FixInvalidScheduling", ctx, userID, mock.Anything).Return([]int64{6}, nil).Once()
+		checkRepo.On("FindDuplicateGUIDNotes", ctx, userID).Return([]int64{103}, nil).Once()
+		noteRepo.On("FindByID", ctx, userID, int64(103)).Return(duplicate, nil).Once()
+		noteRepo.On("Update", ctx, userID, int64(103), mock.Anything).Return(nil).Once()
+
+		var logged *checkdatabaselog.CheckDatabaseLog
+		logRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			logged = args.Get(2).(*checkdatabaselog.CheckDatabaseLog)
+			logged.SetID(42)
+		}).Return(nil).Once()
+
+		result, err := service.CheckDatabase(ctx, userID)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), result.LogID)
+		assert.Equal(t, []int64{1, 2}, result.CardsWithDeletedNote)
+		assert.Equal(t, []int64{3}, result.CardsInDeletedDeck)
+		assert.Equal(t, []int64{4}, result.CardsWithInvalidOrdinal)
+		assert.Equal(t, []int64{101, 102}, result.NotesWithWrongFieldCount)
+		assert.Equal(t, []int64{6}, result.CardsWithInvalidSchedule)
+		assert.Equal(t, []int64{103}, result.NotesWithDuplicateGUID)
+		assert.Equal(t, 8, result.IssuesFound())
+
+		assert.JSONEq(t, `{"Front":"a","Back":""}`, missingField.GetFieldsJSON())
+		assert.JSONEq(t, `{"Front":"a","Back":"b c"}`, extraField.GetFieldsJSON())
+		assert.NotEqual(t, guid, duplicate.GetGUID())
+
+		require.NotNil(t, logged)
+		assert.Equal(t, checkdatabaselog.CheckStatusCompleted, logged.GetStatus())
+		assert.Equal(t, 8, logged.GetIssuesFound())
+		var details map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(logged.GetIssuesDetails()), &details))
+		assert.Equal(t, []interface{}{float64(1), float64(2)}, details["cards_with_deleted_note"])
+		assert.Equal(t, []interface{}{}, details["cards_with_missing_home_deck"])
+
+		checkRepo.AssertExpectations(t)
+		noteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Failure is logged as failed", func(t *testing.T) {
+		checkRepo := new(MockDatabaseCheckRepository)
+		logRepo := new(MockCheckDatabaseLogRepository)
+		deckRepo := new(MockDeckRepository)
+		service := auditSvc.NewCheckDatabaseService(checkRepo, logRepo, new(MockNoteRepository), new(MockNoteTypeRepository), deckRepo)
+
+		checkRepo.On("DeleteCardsOfDeletedNotes", ctx, userID).Return([]int64{1}, nil).Once()
+		deckRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{}, nil).Once()
+		deckRepo.On("CreateDefaultDeck", ctx, userID).Return(int64(0), errors.New("db down")).Once()
+
+		var logged *checkdatabaselog.CheckDatabaseLog
+		logRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			logged = args.Get(2).(*checkdatabaselog.CheckDatabaseLog)
+		}).Return(nil).Once()
+
+		_, err := service.CheckDatabase(ctx, userID)
+
+		assert.Error(t, err)
+		require.NotNil(t, logged)
+		assert.Equal(t, checkdatabaselog.CheckStatusFailed, logged.GetStatus())
+		assert.Equal(t, 1, logged.GetIssuesFound())
+		assert.Contains(t, logged.GetIssuesDetails(), "db down")
+	})
+}
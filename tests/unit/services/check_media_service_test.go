@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	checkdatabaselog "github.com/felipesantos/anki-backend/core/domain/entities/check_database_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	mediaSvc "github.com/felipesantos/anki-backend/core/services/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mediaCheckFixture sets up a collection with one file of each kind Check Media reports
+type mediaCheckFixture struct {
+	repo     *MockMediaRepository
+	noteRepo *MockNoteRepository
+	storage  *MockStorageRepository
+	logRepo  *MockCheckDatabaseLogRepository
+	noteSvc  *MockNoteService
+	logged   *checkdatabaselog.CheckDatabaseLog
+}
+
+func newMediaCheckFixture(ctx context.Context, userID int64) *mediaCheckFixture {
+	f := &mediaCheckFixture{
+		repo:     new(MockMediaRepository),
+		noteRepo: new(MockNoteRepository),
+		storage:  new(MockStorageRepository),
+		logRepo:  new(MockCheckDatabaseLogRepository),
+		noteSvc:  new(MockNoteService),
+	}
+
+	n1, _ := note.NewBuilder().WithID(1).WithUserID(userID).WithFieldsJSON(`{"Front":"<img src=\"cat.jpg\">","Back":"[sound:gone.mp3]"}`).Build()
+	n2, _ := note.NewBuilder().WithID(2).WithUserID(userID).WithFieldsJSON(`{"Front":"<img src=\"lost.png\"> [sound:gone.mp3]"}`).Build()
+	f.noteRepo.On("FindByUserID", ctx, userID, 1000, 0).Return([]*note.Note{n1, n2}, nil)
+
+	cat, _ := media.NewBuilder().WithID(10).WithUserID(userID).WithFilename("cat.jpg").WithStoragePath("media/1/aaa").Build()
+	unused, _ := media.NewBuilder().WithID(11).WithUserID(userID).WithFilename("old.png").WithStoragePath("media/1/bbb").Build()
+	template, _ := media.NewBuilder().WithID(12).WithUserID(userID).WithFilename("_font.ttf").WithStoragePath("media/1/ccc").Build()
+	linked, _ := media.NewBuilder().WithID(13).WithUserID(userID).WithFilename("linked.png").WithStoragePath("media/1/ddd").Build()
+	lost, _ := media.NewBuilder().WithID(14).WithUserID(userID).WithFilename("lost.png").WithStoragePath("media/1/eee").Build()
+	bad, _ := media.NewBuilder().WithID(15).WithUserID(userID).WithFilename("a:b.png").WithStoragePath("media/1/fff").Build()
+	f.repo.On("FindByUserID", ctx, userID).Return([]*media.Media{cat, unused, template, linked, lost, bad}, nil)
+	f.repo.On("FindLinkedIDs", ctx, userID).Return([]int64{13}, nil)
+
+	// lost.png has a row but no stored content; "zzz" has content but no row
+	f.storage.On("List", ctx, "media/1/").Return([]*secondary.FileInfo{
+		{Path: "/media/1/aaa"}, {Path: "/media/1/bbb"}, {Path: "/media/1/ccc"},
+		{Path: "/media/1/ddd"}, {Path: "/media/1/fff"}, {Path: "/media/1/zzz"},
+	}, nil)
+
+	f.logRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+		f.logged = args.Get(2).(*checkdatabaselog.CheckDatabaseLog)
+		f.logged.SetID(99)
+	}).Return(nil)
+
+	return f
+}
+
+func (f *mediaCheckFixture) service() *mediaSvc.MediaCheckService {
+	return mediaSvc.NewMediaCheckService(f.repo, f.noteRepo, f.storage, f.logRepo, f.noteSvc).(*mediaSvc.MediaCheckService)
+}
+
+func TestMediaCheckService_CheckMedia(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	f := newMediaCheckFixture(ctx, userID)
+
+	result, err := f.service().CheckMedia(ctx, userID)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(99), result.LogID)
+
+	require.Len(t, result.Unused, 2)
+	assert.Equal(t, "a:b.png", result.Unused[0].GetFilename())
+	assert.Equal(t, "old.png", result.Unused[1].GetFilename())
+
+	require.Len(t, result.Missing, 2)
+	assert.Equal(t, "gone.mp3", result.Missing[0].Filename)
+	assert.Equal(t, []int64{1, 2}, result.Missing[0].NoteIDs)
+	assert.Equal(t, "lost.png", result.Missing[1].Filename)
+	assert.Equal(t, []int64{2}, result.Missing[1].NoteIDs)
+
+	require.Len(t, result.Misnamed, 1)
+	assert.Equal(t, "ab.png", result.Misnamed[0].SuggestedName)
+
+	assert.Equal(t, []string{"media/1/zzz"}, result.Orphaned)
+
+	require.NotNil(t, f.logged)
+	assert.Equal(t, checkdatabaselog.CheckStatusCompleted, f.logged.GetStatus())
+	assert.Equal(t, 6, f.logged.GetIssuesFound())
+	var details map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(f.logged.GetIssuesDetails()), &details))
+	assert.Equal(t, "media", details["check"])
+	assert.Equal(t, []interface{}{"gone.mp3", "lost.png"}, details["missing"])
+}
+
+func TestMediaCheckService_DeleteUnused(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	f := newMediaCheckFixture(ctx, userID)
+
+	f.repo.On("Delete", ctx, userID, int64(15)).Return(nil).Once()
+	f.repo.On("Delete", ctx, userID, int64(11)).Return(nil).Once()
+	f.storage.On("Delete", ctx, "media/1/fff").Return(nil).Once()
+	f.storage.On("Delete", ctx, "media/1/bbb").Return(nil).Once()
+	f.storage.On("Delete", ctx, "media/1/zzz").Return(nil).Once()
+
+	deleted, err := f.service().DeleteUnused(ctx, userID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	f.repo.AssertExpectations(t)
+	f.storage.AssertExpectations(t)
+}
+
+func TestMediaCheckService_TagMissing(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	f := newMediaCheckFixture(ctx, userID)
+
+	f.noteSvc.On("AddTag", ctx, userID, int64(1), media.MissingMediaTag).Return(nil).Once()
+	f.noteSvc.On("AddTag", ctx, userID, int64(2), media.MissingMediaTag).Return(nil).Once()
+
+	tagged, err := f.service().TagMissing(ctx, userID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, tagged)
+	f.noteSvc.AssertExpectations(t)
+}
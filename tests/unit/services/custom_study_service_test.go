@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	deckSvc "github.com/felipesantos/anki-backend/core/services/deck"
+	studySvc "github.com/felipesantos/anki-backend/core/services/study"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomStudyService_CustomStudy(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	deckID := int64(10)
+	childID := int64(11)
+
+	root, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Root").Build()
+	child, _ := deck.NewBuilder().WithID(childID).WithUserID(userID).WithName("Root::Child").Build()
+
+	setup := func() (*MockDeckRepository, *MockFilteredDeckRepository, *MockCardRepository, primary.ICustomStudyService) {
+		mockDeckRepo := new(MockDeckRepository)
+		mockFilteredRepo := new(MockFilteredDeckRepository)
+		mockCardRepo := new(MockCardRepository)
+		mockPrefsRepo := new(MockUserPreferencesRepository)
+		mockTM := new(MockTransactionManager)
+		mockTM.ExpectTransaction()
+
+		filteredDeckService := deckSvc.NewFilteredDeckService(mockFilteredRepo, mockCardRepo, mockTM)
+		service := studySvc.NewCustomStudyService(mockDeckRepo, mockPrefsRepo, filteredDeckService)
+
+		mockDeckRepo.On("FindByID", ctx, userID, deckID).Return(root, nil)
+		mockDeckRepo.On("FindByParentID", ctx, userID, deckID).Return([]*deck.Deck{child}, nil)
+		mockDeckRepo.On("FindByParentID", ctx, userID, childID).Return([]*deck.Deck{}, nil)
+		mockPrefsRepo.On("FindByUserID", ctx, userID).Return(nil, nil)
+		return mockDeckRepo, mockFilteredRepo, mockCardRepo, service
+	}
+
+	t.Run("Increase new card limit", func(t *testing.T) {
+		mockDeckRepo, _, _, service := setup()
+		mockDeckRepo.On("ExtendLimits", ctx, userID, deckID, mock.AnythingOfType("time.Time"), 10, 0).
+			Return(&deck.LimitExtension{NewCards: 15}, nil).Once()
+
+		result, err := service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{Mode: primary.CustomStudyNewLimit, Amount: 10})
+
+		require.NoError(t, err)
+		assert.Equal(t, 15, result.Extension.NewCards)
+		assert.Nil(t, result.FilteredDeck)
+		mockDeckRepo.AssertCalled(t, "ExtendLimits", ctx, userID, deckID, mock.Anything, 10, 0)
+	})
+
+	t.Run("Forgotten cards create the session deck", func(t *testing.T) {
+		_, mockFilteredRepo, mockCardRepo, service := setup()
+		mockFilteredRepo.On("FindByUserID", ctx, userID).Return([]*filtereddeck.FilteredDeck{}, nil).Once()
+		mockFilteredRepo.On("Save", ctx, userID, mock.MatchedBy(func(fd *filtereddeck.FilteredDeck) bool {
+			return fd.GetName() == studySvc.CustomStudySessionName && !fd.GetReschedule()
+		})).Return(nil).Once()
+		mockFilteredRepo.On("ReturnCards", ctx, userID, int64(0)).Return(0, nil).Once()
+		mockFilteredRepo.On("MoveCardsIn", ctx, userID, int64(0), mock.MatchedBy(func(q *search.SearchQuery) bool {
+			return assert.ObjectsAreEqual([]int64{deckID, childID}, q.DeckIDs) &&
+				len(q.DateFilters) == 1 && q.DateFilters[0] == search.DateFilter{Kind: "rated", Days: 7, Rating: 1}
+		}), filtereddeck.OrderRandom, 9999).Return(2, nil).Once()
+		mockFilteredRepo.On("Update", ctx, userID, int64(0), mock.Anything).Return(nil).Once()
+		mockFilteredRepo.On("FindByID", ctx, userID, int64(0)).Return(&filtereddeck.FilteredDeck{}, nil).Once()
+		mockCardRepo.On("FindByFilteredDeckID", ctx, userID, int64(0)).Return([]*card.Card{{}, {}}, nil).Once()
+
+		result, err := service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{Mode: primary.CustomStudyForgotten, Amount: 7})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.CardCount)
+		assert.Equal(t, studySvc.CustomStudySessionName, result.FilteredDeck.GetName())
+		mockFilteredRepo.AssertExpectations(t)
+	})
+
+	t.Run("Cram by tag rebuilds the existing session deck", func(t *testing.T) {
+		_, mockFilteredRepo, mockCardRepo, service := setup()
+		session, _ := filtereddeck.NewBuilder().WithID(5).WithUserID(userID).WithName(studySvc.CustomStudySessionName).
+			WithSearchFilter("is:due").WithLimitCards(10).Build()
+		mockFilteredRepo.On("FindByUserID", ctx, userID).Return([]*filtereddeck.FilteredDeck{session}, nil).Once()
+		mockFilteredRepo.On("FindByID", ctx, userID, int64(5)).Return(session, nil)
+		mockFilteredRepo.On("Update", ctx, userID, int64(5), session).Return(nil)
+		mockFilteredRepo.On("ReturnCards", ctx, userID, int64(5)).Return(10, nil).Once()
+		mockFilteredRepo.On("MoveCardsIn", ctx, userID, int64(5), mock.MatchedBy(func(q *search.SearchQuery) bool {
+			return assert.ObjectsAreEqual([]string{"new"}, q.States) && assert.ObjectsAreEqual([]string{"verbs", "nouns"}, q.TagsInclude) &&
+				assert.ObjectsAreEqual([]string{"leech"}, q.TagsExclude)
+		}), filtereddeck.OrderAdded, 20).Return(1, nil).Once()
+		mockCardRepo.On("FindByFilteredDeckID", ctx, userID, int64(5)).Return([]*card.Card{{}}, nil).Once()
+
+		result, err := service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{
+			Mode:        primary.CustomStudyCram,
+			Amount:      20,
+			CardState:   primary.CustomStudyCardsNew,
+			TagsInclude: []string{"verbs", "nouns"},
+			TagsExclude: []string{"leech"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.CardCount)
+		assert.Equal(t, "did:10,11 is:new (tag:verbs OR tag:nouns) -tag:leech", session.GetSearchFilter())
+		assert.True(t, session.GetReschedule())
+		mockFilteredRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, mockFilteredRepo, _, service := setup()
+
+		_, err := service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{Mode: primary.CustomStudyReviewAhead, Amount: 0})
+		assert.ErrorIs(t, err, studySvc.ErrInvalidCustomStudy)
+
+		_, err = service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{Mode: primary.CustomStudyCram, Amount: 5, CardState: "leech"})
+		assert.ErrorIs(t, err, studySvc.ErrInvalidCustomStudy)
+
+		for _, tag := range []string{"a) OR (cid:1", "two words", `say"hi"`, ""} {
+			_, err = service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{Mode: primary.CustomStudyCram, Amount: 5, TagsInclude: []string{tag}})
+			assert.ErrorIs(t, err, studySvc.ErrInvalidCustomStudy, tag)
+			_, err = service.CustomStudy(ctx, userID, deckID, primary.CustomStudyOptions{Mode: primary.CustomStudyCram, Amount: 5, TagsExclude: []string{tag}})
+			assert.ErrorIs(t, err, studySvc.ErrInvalidCustomStudy, tag)
+		}
+		mockFilteredRepo.AssertNotCalled(t, "FindByUserID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Deck not found", func(t *testing.T) {
+		mockDeckRepo := new(MockDeckRepository)
+		service := studySvc.NewCustomStudyService(mockDeckRepo, new(MockUserPreferencesRepository), nil)
+		mockDeckRepo.On("FindByID", ctx, userID, int64(99)).Return(nil, nil).Once()
+
+		_, err := service.CustomStudy(ctx, userID, 99, primary.CustomStudyOptions{Mode: primary.CustomStudyNewLimit, Amount: 5})
+
+		assert.ErrorIs(t, err, deckSvc.ErrDeckNotFound)
+	})
+}
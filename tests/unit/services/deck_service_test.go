@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
 	deckSvc "github.com/felipesantos/anki-backend/core/services/deck"
 	"github.com/stretchr/testify/assert"
@@ -16,7 +17,8 @@ func TestDeckService_Create(t *testing.T) {
 	mockCardRepo := new(MockCardRepository)
 	mockBackupSvc := new(MockBackupService)
 	mockTM := new(MockTransactionManager)
-	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, mockTM)
+	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, newRecordingUndoService(), mockTM)
+	mockTM.ExpectTransaction()
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -81,7 +83,7 @@ func TestDeckService_Delete(t *testing.T) {
 	mockCardRepo := new(MockCardRepository)
 	mockBackupSvc := new(MockBackupService)
 	mockTM := new(MockTransactionManager)
-	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, mockTM)
+	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -92,6 +94,8 @@ func TestDeckService_Delete(t *testing.T) {
 		mockRepo.On("FindByID", ctx, userID, deckID).Return(d, nil).Once()
 		mockBackupSvc.On("CreatePreOperationBackup", ctx, userID).Return(nil, nil).Once()
 		mockTM.ExpectTransaction()
+		mockRepo.On("FindByParentID", mock.Anything, userID, deckID).Return([]*deck.Deck{}, nil).Once()
+		mockCardRepo.On("FindByDeckID", mock.Anything, userID, deckID).Return([]*card.Card{}, nil).Once()
 		mockCardRepo.On("DeleteByDeckRecursive", mock.Anything, userID, deckID).Return(nil).Once()
 		mockRepo.On("Delete", mock.Anything, userID, deckID).Return(nil).Once()
 
@@ -127,6 +131,8 @@ func TestDeckService_Delete(t *testing.T) {
 		mockBackupSvc.On("CreatePreOperationBackup", ctx, userID).Return(nil, nil).Once()
 		mockRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{defaultDeck}, nil).Once()
 		mockTM.ExpectTransaction()
+		mockRepo.On("FindByParentID", mock.Anything, userID, deckID).Return([]*deck.Deck{}, nil).Once()
+		mockCardRepo.On("FindByDeckID", mock.Anything, userID, deckID).Return([]*card.Card{}, nil).Once()
 		mockCardRepo.On("MoveCards", mock.Anything, userID, deckID, defaultDeckID).Return(nil).Once()
 		mockRepo.On("Delete", mock.Anything, userID, deckID).Return(nil).Once()
 
@@ -148,6 +154,8 @@ func TestDeckService_Delete(t *testing.T) {
 		mockBackupSvc.On("CreatePreOperationBackup", ctx, userID).Return(nil, nil).Once()
 		mockRepo.On("FindByID", ctx, userID, targetDeckID).Return(targetDeck, nil).Once()
 		mockTM.ExpectTransaction()
+		mockRepo.On("FindByParentID", mock.Anything, userID, deckID).Return([]*deck.Deck{}, nil).Once()
+		mockCardRepo.On("FindByDeckID", mock.Anything, userID, deckID).Return([]*card.Card{}, nil).Once()
 		mockCardRepo.On("MoveCards", mock.Anything, userID, deckID, targetDeckID).Return(nil).Once()
 		mockRepo.On("Delete", mock.Anything, userID, deckID).Return(nil).Once()
 
@@ -189,7 +197,7 @@ func TestDeckService_FindByUserID(t *testing.T) {
 	mockCardRepo := new(MockCardRepository)
 	mockBackupSvc := new(MockBackupService)
 	mockTM := new(MockTransactionManager)
-	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, mockTM)
+	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -291,7 +299,7 @@ func TestDeckService_FindByID(t *testing.T) {
 	mockCardRepo := new(MockCardRepository)
 	mockBackupSvc := new(MockBackupService)
 	mockTM := new(MockTransactionManager)
-	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, mockTM)
+	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 	deckID := int64(10)
@@ -324,7 +332,8 @@ func TestDeckService_Update(t *testing.T) {
 	mockCardRepo := new(MockCardRepository)
 	mockBackupSvc := new(MockBackupService)
 	mockTM := new(MockTransactionManager)
-	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, mockTM)
+	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, newRecordingUndoService(), mockTM)
+	mockTM.ExpectTransaction()
 	ctx := context.Background()
 	userID := int64(1)
 	deckID := int64(10)
@@ -450,7 +459,8 @@ func TestDeckService_UpdateOptions(t *testing.T) {
 	mockCardRepo := new(MockCardRepository)
 	mockBackupSvc := new(MockBackupService)
 	mockTM := new(MockTransactionManager)
-	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, mockTM)
+	service := deckSvc.NewDeckService(mockRepo, mockCardRepo, mockBackupSvc, newRecordingUndoService(), mockTM)
+	mockTM.ExpectTransaction()
 	ctx := context.Background()
 	userID := int64(1)
 	deckID := int64(10)
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deletionlog "github.com/felipesantos/anki-backend/core/domain/entities/deletion_log"
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	syncSvc "github.com/felipesantos/anki-backend/core/services/sync"
+)
+
+type deltaSyncTestDeps struct {
+	syncRepo        *MockAnkiSyncRepository
+	deletionLogRepo *MockDeletionLogRepository
+	noteRepo        *MockNoteRepository
+	cardRepo        *MockCardRepository
+	deckRepo        *MockDeckRepository
+	noteTypeRepo    *MockNoteTypeRepository
+	mediaRepo       *MockMediaRepository
+	noteService     *MockNoteService
+	cardService     *MockCardService
+	deckService     *MockDeckService
+	noteTypeService *MockNoteTypeService
+	reviewService   *MockReviewService
+	syncMetaRepo    *MockSyncMetaRepository
+	service         primary.IDeltaSyncService
+}
+
+func newDeltaSyncTestService() *deltaSyncTestDeps {
+	deps := &deltaSyncTestDeps{
+		syncRepo:        new(MockAnkiSyncRepository),
+		deletionLogRepo: new(MockDeletionLogRepository),
+		noteRepo:        new(MockNoteRepository),
+		cardRepo:        new(MockCardRepository),
+		deckRepo:        new(MockDeckRepository),
+		noteTypeRepo:    new(MockNoteTypeRepository),
+		mediaRepo:       new(MockMediaRepository),
+		noteService:     new(MockNoteService),
+		cardService:     new(MockCardService),
+		deckService:     new(MockDeckService),
+		noteTypeService: new(MockNoteTypeService),
+		reviewService:   new(MockReviewService),
+		syncMetaRepo:    new(MockSyncMetaRepository),
+	}
+	deps.service = syncSvc.NewDeltaSyncService(
+		deps.syncRepo,
+		deps.deletionLogRepo,
+		deps.noteRepo,
+		deps.cardRepo,
+		deps.deckRepo,
+		deps.noteTypeRepo,
+		deps.mediaRepo,
+		deps.noteService,
+		deps.cardService,
+		deps.deckService,
+		deps.noteTypeService,
+		deps.reviewService,
+		syncSvc.NewSyncMetaService(deps.syncMetaRepo, deps.syncRepo),
+	)
+	return deps
+}
+
+// expectChanged sets the objects of each type changed since minUSN
+func (d *deltaSyncTestDeps) expectChanged(ctx context.Context, userID int64, minUSN int, changed map[string][]int64) {
+	for _, objectType := range []string{
+		secondary.SyncObjectNote,
+		secondary.SyncObjectCard,
+		secondary.SyncObjectReview,
+		secondary.SyncObjectDeck,
+		secondary.SyncObjectNoteType,
+		secondary.SyncObjectMedia,
+	} {
+		ids := changed[objectType]
+		if ids == nil {
+			ids = []int64{}
+		}
+		d.syncRepo.On("FindChangedIDs", ctx, userID, objectType, minUSN, int64(0), mock.Anything).Return(ids, nil).Once()
+	}
+}
+
+func TestDeltaSyncService_FindChanges(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	t.Run("Incremental", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		n, _ := note.NewBuilder().WithID(10).WithUserID(userID).Build()
+		c, _ := card.NewBuilder().WithID(20).WithNoteID(10).WithDeckID(3).WithState(valueobjects.CardStateNew).Build()
+		d1, _ := deck.NewBuilder().WithID(3).WithUserID(userID).WithName("Changed").Build()
+		d2, _ := deck.NewBuilder().WithID(4).WithUserID(userID).WithName("Unchanged").Build()
+		tombstone, _ := deletionlog.NewBuilder().WithUserID(userID).WithObjectType(deletionlog.ObjectTypeCard).WithObjectID(21).Build()
+
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(8, nil).Once()
+		deps.expectChanged(ctx, userID, 6, map[string][]int64{
+			secondary.SyncObjectNote: {10},
+			secondary.SyncObjectCard: {20},
+			secondary.SyncObjectDeck: {3},
+		})
+		deps.noteRepo.On("FindByIDs", ctx, userID, []int64{10}).Return([]*note.Note{n}, nil).Once()
+		deps.syncRepo.On("FindCards", ctx, userID, []int64{20}).Return([]*card.Card{c}, nil).Once()
+		deps.syncRepo.On("FindReviews", ctx, userID, []int64{}).Return([]*review.Review{}, nil).Once()
+		deps.deckRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{d1, d2}, nil).Once()
+		deps.deletionLogRepo.On("FindByMinUSN", ctx, userID, 6).Return([]*deletionlog.DeletionLog{tombstone}, nil).Once()
+
+		changes, err := deps.service.FindChanges(ctx, userID, "", 5)
+
+		require.NoError(t, err)
+		assert.Equal(t, 8, changes.USN)
+		assert.False(t, changes.Full)
+		assert.Len(t, changes.Notes, 1)
+		assert.Len(t, changes.Cards, 1)
+		assert.Equal(t, []*deck.Deck{d1}, changes.Decks)
+		assert.Empty(t, changes.NoteTypes)
+		assert.Empty(t, changes.Media)
+		assert.Equal(t, []*deletionlog.DeletionLog{tombstone}, changes.Deleted)
+		deps.noteTypeRepo.AssertNotCalled(t, "FindByUserID", mock.Anything, mock.Anything, mock.Anything)
+		deps.syncRepo.AssertExpectations(t)
+	})
+
+	t.Run("Full Without Since", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		nt, _ := notetype.NewBuilder().WithID(2).WithUserID(userID).WithName("Basic").Build()
+		m, _ := media.NewBuilder().WithID(7).WithUserID(userID).WithFilename("a.png").WithHash("abc").WithSize(1).WithMimeType("image/png").WithStoragePath("a").Build()
+
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(3, nil).Once()
+		deps.expectChanged(ctx, userID, 0, map[string][]int64{
+			secondary.SyncObjectNoteType: {2},
+			secondary.SyncObjectMedia:    {7},
+		})
+		deps.noteRepo.On("FindByIDs", ctx, userID, []int64{}).Return([]*note.Note{}, nil).Once()
+		deps.syncRepo.On("FindCards", ctx, userID, []int64{}).Return([]*card.Card{}, nil).Once()
+		deps.syncRepo.On("FindReviews", ctx, userID, []int64{}).Return([]*review.Review{}, nil).Once()
+		deps.noteTypeRepo.On("FindByUserID", ctx, userID, "").Return([]*notetype.NoteType{nt}, nil).Once()
+		deps.mediaRepo.On("FindByUserID", ctx, userID).Return([]*media.Media{m}, nil).Once()
+
+		changes, err := deps.service.FindChanges(ctx, userID, "", -1)
+
+		require.NoError(t, err)
+		assert.True(t, changes.Full)
+		assert.Len(t, changes.NoteTypes, 1)
+		assert.Len(t, changes.Media, 1)
+		assert.Empty(t, changes.Deleted)
+		deps.deletionLogRepo.AssertNotCalled(t, "FindByMinUSN", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Full When Since Is Ahead", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(3, nil).Once()
+		deps.expectChanged(ctx, userID, 0, nil)
+		deps.noteRepo.On("FindByIDs", ctx, userID, []int64{}).Return([]*note.Note{}, nil).Once()
+		deps.syncRepo.On("FindCards", ctx, userID, []int64{}).Return([]*card.Card{}, nil).Once()
+		deps.syncRepo.On("FindReviews", ctx, userID, []int64{}).Return([]*review.Review{}, nil).Once()
+
+		changes, err := deps.service.FindChanges(ctx, userID, "", 40)
+
+		require.NoError(t, err)
+		assert.True(t, changes.Full)
+	})
+
+	t.Run("Records Client USN", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		deps.syncRepo.On("AdvanceUSN", ctx, userID).Return(8, nil).Once()
+		deps.expectChanged(ctx, userID, 9, nil)
+		deps.noteRepo.On("FindByIDs", ctx, userID, []int64{}).Return([]*note.Note{}, nil).Once()
+		deps.syncRepo.On("FindCards", ctx, userID, []int64{}).Return([]*card.Card{}, nil).Once()
+		deps.syncRepo.On("FindReviews", ctx, userID, []int64{}).Return([]*review.Review{}, nil).Once()
+		deps.deletionLogRepo.On("FindByMinUSN", ctx, userID, 9).Return([]*deletionlog.DeletionLog{}, nil).Once()
+		deps.syncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 9}, nil).Once()
+		deps.syncMetaRepo.On("FindByClientID", ctx, userID, "phone").Return(nil, nil).Once()
+		deps.syncMetaRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
+
+		changes, err := deps.service.FindChanges(ctx, userID, "phone", 8)
+
+		require.NoError(t, err)
+		assert.False(t, changes.Full)
+		deps.syncMetaRepo.AssertExpectations(t)
+	})
+}
+
+func TestDeltaSyncService_Push(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	serverTime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Create Returns IDs", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		d, _ := deck.NewBuilder().WithID(30).WithUserID(userID).WithName("Spanish").Build()
+		n, _ := note.NewBuilder().WithID(40).WithUserID(userID).Build()
+		deps.deckService.On("Create", ctx, userID, "Spanish", (*int64)(nil), "").Return(d, nil).Once()
+		deps.noteService.On("Create", ctx, userID, int64(2), int64(30), `{"Front":"hola"}`, []string{"es"}).Return(n, nil).Once()
+
+		result, err := deps.service.Push(ctx, userID, &primary.SyncPush{
+			Decks: []primary.SyncDeckChange{{Action: primary.SyncActionCreate, ClientRef: "d1", Name: "Spanish"}},
+			Notes: []primary.SyncNoteChange{{Action: primary.SyncActionCreate, ClientRef: "n1", NoteTypeID: 2, DeckID: 30, FieldsJSON: `{"Front":"hola"}`, Tags: []string{"es"}}},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Applied)
+		assert.Equal(t, []primary.SyncCreated{
+			{ObjectType: secondary.SyncObjectDeck, ClientRef: "d1", ID: 30},
+			{ObjectType: secondary.SyncObjectNote, ClientRef: "n1", ID: 40},
+		}, result.Created)
+		assert.Empty(t, result.Conflicts)
+	})
+
+	t.Run("Server Newer", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		n, _ := note.NewBuilder().WithID(40).WithUserID(userID).WithUpdatedAt(serverTime).Build()
+		deps.noteRepo.On("FindByID", ctx, userID, int64(40)).Return(n, nil).Once()
+
+		result, err := deps.service.Push(ctx, userID, &primary.SyncPush{
+			Notes: []primary.SyncNoteChange{{Action: primary.SyncActionUpdate, ID: 40, FieldsJSON: `{}`, ModifiedAt: serverTime.Add(-time.Minute)}},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Applied)
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, primary.SyncConflictServerNewer, result.Conflicts[0].Reason)
+		deps.noteService.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Client Newer", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		c, _ := card.NewBuilder().WithID(50).WithNoteID(40).WithDeckID(3).WithState(valueobjects.CardStateNew).WithUpdatedAt(serverTime).Build()
+		flag := 2
+		suspended := true
+		deps.cardRepo.On("FindByID", ctx, userID, int64(50)).Return(c, nil).Once()
+		deps.cardService.On("SetFlag", ctx, userID, int64(50), 2).Return(nil).Once()
+		deps.cardService.On("Suspend", ctx, userID, int64(50)).Return(nil).Once()
+
+		result, err := deps.service.Push(ctx, userID, &primary.SyncPush{
+			Cards: []primary.SyncCardChange{{ID: 50, Flag: &flag, Suspended: &suspended, ModifiedAt: serverTime.Add(time.Minute)}},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Applied)
+		assert.Empty(t, result.Conflicts)
+		deps.cardService.AssertExpectations(t)
+	})
+
+	t.Run("Deleted On Server", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		deps.deckRepo.On("FindByID", ctx, userID, int64(30)).Return(nil, nil).Twice()
+
+		result, err := deps.service.Push(ctx, userID, &primary.SyncPush{
+			Decks: []primary.SyncDeckChange{
+				{Action: primary.SyncActionUpdate, ID: 30, Name: "Renamed", ModifiedAt: serverTime},
+				{Action: primary.SyncActionDelete, ID: 30, ModifiedAt: serverTime},
+			},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, result.Conflicts, 1, "deleting a deleted deck is not a conflict")
+		assert.Equal(t, primary.SyncConflictDeleted, result.Conflicts[0].Reason)
+		assert.Equal(t, int64(30), result.Conflicts[0].ID)
+	})
+
+	t.Run("Review Of Card Answered Elsewhere", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		c, _ := card.NewBuilder().WithID(50).WithNoteID(40).WithDeckID(3).WithState(valueobjects.CardStateReview).WithUpdatedAt(serverTime).Build()
+		deps.cardRepo.On("FindByID", ctx, userID, int64(50)).Return(c, nil).Once()
+
+		result, err := deps.service.Push(ctx, userID, &primary.SyncPush{
+			Reviews: []primary.SyncReviewChange{{ClientRef: "r1", CardID: 50, Rating: 3, TimeMs: 4000, ReviewedAt: serverTime.Add(-time.Hour)}},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, "r1", result.Conflicts[0].ClientRef)
+		assert.Equal(t, primary.SyncConflictServerNewer, result.Conflicts[0].Reason)
+		deps.reviewService.AssertNotCalled(t, "CreateAt", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reviews In Order", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		c, _ := card.NewBuilder().WithID(50).WithNoteID(40).WithDeckID(3).WithState(valueobjects.CardStateReview).WithUpdatedAt(serverTime).Build()
+		r1, _ := review.NewBuilder().WithID(60).WithCardID(50).WithRating(3).WithTimeMs(4000).Build()
+		r2, _ := review.NewBuilder().WithID(61).WithCardID(50).WithRating(4).WithTimeMs(3000).Build()
+		deps.cardRepo.On("FindByID", ctx, userID, int64(50)).Return(c, nil).Twice()
+		// Each answer is scheduled from the time it was made on the client
+		deps.reviewService.On("CreateAt", ctx, userID, int64(50), 3, 4000, serverTime.Add(time.Minute)).Return(r1, nil).Once()
+		deps.reviewService.On("CreateAt", ctx, userID, int64(50), 4, 3000, serverTime.Add(2*time.Minute)).Return(r2, nil).Once()
+
+		// The second answer follows the first, which moved the card on the server
+		result, err := deps.service.Push(ctx, userID, &primary.SyncPush{
+			Reviews: []primary.SyncReviewChange{
+				{ClientRef: "r1", CardID: 50, Rating: 3, TimeMs: 4000, ReviewedAt: serverTime.Add(time.Minute)},
+				{ClientRef: "r2", CardID: 50, Rating: 4, TimeMs: 3000, ReviewedAt: serverTime.Add(2 * time.Minute)},
+			},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Applied)
+		assert.Empty(t, result.Conflicts)
+	})
+
+	t.Run("Rejected Change Does Not Stop The Batch", func(t *testing.T) {
+		deps := newDeltaSyncTestService()
+		nt, _ := notetype.NewBuilder().WithID(2).WithUserID(userID).WithName("Basic").Build()
+		deps.noteTypeService.On("Create", ctx, userID, "", "", "", "").Return(nil, assert.AnError).Once()
+		deps.noteTypeService.On("Create", ctx, userID, "Basic", "[]", "[]", "{}").Return(nt, nil).Once()
+
+		result, err := deps.service.Push(ctx, userID, &primary.SyncPush{
+			NoteTypes: []primary.SyncNoteTypeChange{
+				{Action: primary.SyncActionCreate, ClientRef: "bad"},
+				{Action: primary.SyncActionCreate, ClientRef: "good", Name: "Basic", FieldsJSON: "[]", CardTypesJSON: "[]", TemplatesJSON: "{}"},
+			},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Applied)
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, primary.SyncConflictRejected, result.Conflicts[0].Reason)
+		assert.Equal(t, "bad", result.Conflicts[0].ClientRef)
+	})
+}
@@ -1,8 +1,15 @@
 package services
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"io"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -11,9 +18,11 @@ import (
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
 	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deckoptionspreset "github.com/felipesantos/anki-backend/core/domain/entities/deck_options_preset"
 	"github.com/felipesantos/anki-backend/core/domain/entities/media"
 	"github.com/felipesantos/anki-backend/core/domain/entities/note"
 	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
 	exportSvc "github.com/felipesantos/anki-backend/core/services/export"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
 	"github.com/felipesantos/anki-backend/pkg/ownership"
@@ -25,7 +34,9 @@ func TestExportService_ExportNotes_TextFormat(t *testing.T) {
 	mockNoteRepo := new(MockNoteRepository)
 	mockNoteTypeRepo := new(MockNoteTypeRepository)
 	mockMediaRepo := new(MockMediaRepository)
-	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo)
+	mockReviewRepo := new(MockReviewRepository)
+	mockPresetRepo := new(MockDeckOptionsPresetRepository)
+	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo, mockReviewRepo, mockPresetRepo)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -143,19 +154,9 @@ func TestExportService_ExportNotes_TextFormat(t *testing.T) {
 			WithUpdatedAt(time.Now()).
 			Build()
 
-		deck1, _ := deck.NewBuilder().
-			WithID(deckID).
-			WithUserID(userID).
-			WithName("Test Deck").
-			WithOptionsJSON("{}").
-			WithCreatedAt(time.Now()).
-			WithUpdatedAt(time.Now()).
-			Build()
-
 		mockNoteRepo.On("FindByIDs", ctx, userID, noteIDs).Return(notes, nil).Once()
 		mockCardRepo.On("FindByNoteIDs", ctx, userID, noteIDs).Return(cards, nil).Once()
 		mockNoteTypeRepo.On("FindByID", ctx, userID, noteTypeID).Return(noteType, nil).Once()
-		mockDeckRepo.On("FindByID", ctx, userID, deckID).Return(deck1, nil).Once()
 
 		reader, size, filename, err := service.ExportNotes(ctx, userID, noteIDs, "text", false, true)
 		require.NoError(t, err)
@@ -175,60 +176,280 @@ func TestExportService_ExportNotes_APKGFormat(t *testing.T) {
 	mockNoteRepo := new(MockNoteRepository)
 	mockNoteTypeRepo := new(MockNoteTypeRepository)
 	mockMediaRepo := new(MockMediaRepository)
-	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo)
+	mockReviewRepo := new(MockReviewRepository)
+	mockPresetRepo := new(MockDeckOptionsPresetRepository)
+	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo, mockReviewRepo, mockPresetRepo)
 	ctx := context.Background()
 	userID := int64(1)
 
-	t.Run("APKG format - Not yet implemented", func(t *testing.T) {
-		noteIDs := []int64{1}
-		noteTypeID := int64(10)
-
-		guid1, _ := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440001")
-		note1, _ := note.NewBuilder().
-			WithID(1).
-			WithUserID(userID).
-			WithGUID(guid1).
-			WithNoteTypeID(noteTypeID).
-			WithFieldsJSON(`{"Front":"Hello"}`).
-			WithTags([]string{}).
-			WithMarked(false).
-			WithCreatedAt(time.Now()).
-			WithUpdatedAt(time.Now()).
-			Build()
-
-		notes := []*note.Note{note1}
-
-		noteType, _ := notetype.NewBuilder().
-			WithID(noteTypeID).
-			WithUserID(userID).
-			WithName("Basic").
-			WithFieldsJSON(`[{"name":"Front"}]`).
-			WithCardTypesJSON(`[{"name":"Card 1"}]`).
-			WithTemplatesJSON(`[{"name":"Template 1"}]`).
-			WithCreatedAt(time.Now()).
-			WithUpdatedAt(time.Now()).
-			Build()
-
+	now := time.Now()
+	noteIDs := []int64{1, 2}
+	noteTypeID := int64(10)
+	parentID := int64(20)
+	optionsJSON := `{"new_steps":[1,10,60],"graduating_interval":3,"leech_action":"suspend"}`
+
+	guid1, _ := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440001")
+	note1, _ := note.NewBuilder().
+		WithID(1).
+		WithUserID(userID).
+		WithGUID(guid1).
+		WithNoteTypeID(noteTypeID).
+		WithFieldsJSON(`{"Back":"World","Front":"<b>Hello</b>"}`).
+		WithTags([]string{"tag1"}).
+		WithCreatedAt(now.AddDate(0, 0, -30)).
+		WithUpdatedAt(now).
+		Build()
+	guid2, _ := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440002")
+	note2, _ := note.NewBuilder().
+		WithID(2).
+		WithUserID(userID).
+		WithGUID(guid2).
+		WithNoteTypeID(noteTypeID).
+		WithFieldsJSON(`{"Front":"Test","Back":"Card"}`).
+		WithTags([]string{}).
+		WithCreatedAt(now.AddDate(0, 0, -1)).
+		WithUpdatedAt(now).
+		Build()
+	notes := []*note.Note{note1, note2}
+
+	noteType, _ := notetype.NewBuilder().
+		WithID(noteTypeID).
+		WithUserID(userID).
+		WithName("Basic").
+		WithFieldsJSON(`[{"name":"Front"},{"name":"Back"}]`).
+		WithCardTypesJSON(`[{"name":"Card 1"}]`).
+		WithTemplatesJSON(`[{"qfmt":"{{Front}}","afmt":"{{FrontSide}}<hr id=answer>{{Back}}"}]`).
+		WithCreatedAt(now).
+		WithUpdatedAt(now).
+		Build()
+
+	parent, _ := deck.NewBuilder().
+		WithID(parentID).
+		WithUserID(userID).
+		WithName("Languages").
+		WithOptionsJSON("{}").
+		WithCreatedAt(now).
+		WithUpdatedAt(now).
+		Build()
+	child, _ := deck.NewBuilder().
+		WithID(21).
+		WithUserID(userID).
+		WithName("Spanish").
+		WithParentID(&parentID).
+		WithOptionsJSON(optionsJSON).
+		WithCreatedAt(now).
+		WithUpdatedAt(now).
+		Build()
+	unrelated, _ := deck.NewBuilder().
+		WithID(22).
+		WithUserID(userID).
+		WithName("Other").
+		WithOptionsJSON("{}").
+		WithCreatedAt(now).
+		WithUpdatedAt(now).
+		Build()
+	preset, _ := deckoptionspreset.NewBuilder().
+		WithID(5).
+		WithUserID(userID).
+		WithName("Three steps").
+		WithOptionsJSON(optionsJSON).
+		WithCreatedAt(now).
+		WithUpdatedAt(now).
+		Build()
+
+	reviewDue := now.AddDate(0, 0, 3)
+	reviewCard, _ := card.NewBuilder().
+		WithID(100).
+		WithNoteID(1).
+		WithDeckID(21).
+		WithDue(reviewDue.UnixMilli()).
+		WithInterval(10).
+		WithEase(2300).
+		WithLapses(1).
+		WithReps(5).
+		WithState(valueobjects.CardStateReview).
+		WithFlag(2).
+		WithCreatedAt(now.AddDate(0, 0, -30)).
+		WithUpdatedAt(now).
+		Build()
+	learningDue := now.Add(time.Minute)
+	learningCard, _ := card.NewBuilder().
+		WithID(101).
+		WithNoteID(2).
+		WithDeckID(21).
+		WithDue(learningDue.UnixMilli()).
+		WithEase(2500).
+		WithReps(1).
+		WithState(valueobjects.CardStateLearn).
+		WithCreatedAt(now.AddDate(0, 0, -1)).
+		WithUpdatedAt(now).
+		Build()
+	cards := []*card.Card{reviewCard, learningCard}
+
+	learned, _ := review.NewBuilder().WithID(1).WithCardID(100).WithRating(3).WithInterval(-600).WithEase(2500).
+		WithTimeMs(4000).WithType(valueobjects.ReviewTypeLearn).WithCreatedAt(now.AddDate(0, 0, -20)).Build()
+	reviewed, _ := review.NewBuilder().WithID(2).WithCardID(100).WithRating(3).WithInterval(10).WithEase(2300).
+		WithTimeMs(3000).WithType(valueobjects.ReviewTypeReview).WithCreatedAt(now.AddDate(0, 0, -7)).Build()
+	stepped, _ := review.NewBuilder().WithID(3).WithCardID(101).WithRating(3).WithInterval(-600).WithEase(2500).
+		WithTimeMs(2000).WithType(valueobjects.ReviewTypeLearn).WithCreatedAt(now.Add(-time.Minute)).Build()
+
+	setup := func(includeScheduling bool) {
 		mockNoteRepo.On("FindByIDs", ctx, userID, noteIDs).Return(notes, nil).Once()
+		mockCardRepo.On("FindByNoteIDs", ctx, userID, noteIDs).Return(cards, nil).Once()
 		mockNoteTypeRepo.On("FindByID", ctx, userID, noteTypeID).Return(noteType, nil).Once()
+		mockDeckRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{parent, child, unrelated}, nil).Once()
+		mockPresetRepo.On("FindByUserID", ctx, userID).Return([]*deckoptionspreset.DeckOptionsPreset{preset}, nil).Once()
+		if includeScheduling {
+			mockReviewRepo.On("FindByCardID", ctx, userID, int64(100)).Return([]*review.Review{learned, reviewed}, nil).Once()
+			mockReviewRepo.On("FindByCardID", ctx, userID, int64(101)).Return([]*review.Review{stepped}, nil).Once()
+		}
+	}
+
+	t.Run("Success - APKG with scheduling, review log and deck options", func(t *testing.T) {
+		setup(true)
+
+		reader, size, filename, err := service.ExportNotes(ctx, userID, noteIDs, "apkg", false, true)
+		require.NoError(t, err)
+		assert.Greater(t, size, int64(0))
+		assert.Equal(t, "notes_export.apkg", filename)
+		db := openExportedCollection(t, reader)
+
+		var ver int
+		var crt int64
+		var decksJSON, dconfJSON, modelsJSON string
+		require.NoError(t, db.QueryRow("SELECT ver, crt, decks, dconf, models FROM col").Scan(&ver, &crt, &decksJSON, &dconfJSON, &modelsJSON))
+		assert.Equal(t, 11, ver)
+
+		var decks map[string]struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+			Conf int64  `json:"conf"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(decksJSON), &decks))
+		names := make(map[string]int64)
+		var childDeckID, childConfID int64
+		for _, d := range decks {
+			names[d.Name] = d.ID
+			if d.Name == "Languages::Spanish" {
+				childDeckID, childConfID = d.ID, d.Conf
+			}
+		}
+		assert.Len(t, names, 3)
+		assert.Contains(t, names, "Default")
+		assert.Contains(t, names, "Languages")
+		require.NotZero(t, childDeckID)
+
+		var dconf map[string]struct {
+			Name string `json:"name"`
+			New  struct {
+				Delays []float64 `json:"delays"`
+				Ints   []int     `json:"ints"`
+			} `json:"new"`
+			Lapse struct {
+				LeechAction int `json:"leechAction"`
+			} `json:"lapse"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(dconfJSON), &dconf))
+		conf := dconf[strconv.FormatInt(childConfID, 10)]
+		assert.Equal(t, "Three steps", conf.Name)
+		assert.Equal(t, []float64{1, 10, 60}, conf.New.Delays)
+		assert.Equal(t, 3, conf.New.Ints[0])
+		assert.Equal(t, 0, conf.Lapse.LeechAction)
+		assert.Contains(t, modelsJSON, `"name":"Basic"`)
+
+		var flds, sfld, tags string
+		require.NoError(t, db.QueryRow("SELECT flds, sfld, tags FROM notes WHERE guid = ?", guid1.Value()).Scan(&flds, &sfld, &tags))
+		assert.Equal(t, "<b>Hello</b>\x1fWorld", flds)
+		assert.Equal(t, "Hello", sfld)
+		assert.Equal(t, " tag1 ", tags)
+
+		var cardID, did, ctype, queue, ivl, factor, reps, lapses, flags int64
+		var due int64
+		require.NoError(t, db.QueryRow("SELECT id, did, type, queue, due, ivl, factor, reps, lapses, flags FROM cards WHERE ivl = 10").
+			Scan(&cardID, &did, &ctype, &queue, &due, &ivl, &factor, &reps, &lapses, &flags))
+		assert.Equal(t, childDeckID, did)
+		assert.Equal(t, int64(2), ctype)
+		assert.Equal(t, int64(2), queue)
+		assert.Equal(t, (reviewDue.Unix()-crt)/86400, due)
+		assert.Equal(t, int64(2300), factor)
+		assert.Equal(t, int64(5), reps)
+		assert.Equal(t, int64(1), lapses)
+		assert.Equal(t, int64(2), flags)
+
+		var left int
+		require.NoError(t, db.QueryRow("SELECT type, queue, due, left FROM cards WHERE ivl = 0").Scan(&ctype, &queue, &due, &left))
+		assert.Equal(t, int64(1), ctype)
+		assert.Equal(t, int64(1), queue)
+		assert.Equal(t, learningDue.Unix(), due)
+		assert.Equal(t, 2002, left)
+
+		rows, err := db.Query("SELECT ease, ivl, lastIvl, factor, time, type FROM revlog WHERE cid = ? ORDER BY id", cardID)
+		require.NoError(t, err)
+		defer rows.Close()
+		var revlog [][6]int
+		for rows.Next() {
+			var entry [6]int
+			require.NoError(t, rows.Scan(&entry[0], &entry[1], &entry[2], &entry[3], &entry[4], &entry[5]))
+			revlog = append(revlog, entry)
+		}
+		assert.Equal(t, [][6]int{{3, -600, 0, 2500, 4000, 0}, {3, 10, -600, 2300, 3000, 1}}, revlog)
+	})
 
-		reader, size, filename, err := service.ExportNotes(ctx, userID, noteIDs, "apkg", false, false)
-		// APKG generation requires SQLite driver which is not yet implemented
-		require.Error(t, err)
-		assert.Contains(t, err.Error(), "SQLite")
-		assert.Equal(t, int64(0), size)
-		assert.Equal(t, "", filename)
-		assert.Nil(t, reader)
+	t.Run("Success - APKG without scheduling resets cards", func(t *testing.T) {
+		setup(false)
+		reviewCalls := len(mockReviewRepo.Calls)
+
+		reader, _, _, err := service.ExportNotes(ctx, userID, noteIDs, "apkg", false, false)
+		require.NoError(t, err)
+		db := openExportedCollection(t, reader)
+
+		rows, err := db.Query("SELECT type, queue, ivl, factor, reps, lapses, flags FROM cards")
+		require.NoError(t, err)
+		defer rows.Close()
+		count := 0
+		for rows.Next() {
+			var values [7]int
+			require.NoError(t, rows.Scan(&values[0], &values[1], &values[2], &values[3], &values[4], &values[5], &values[6]))
+			assert.Equal(t, [7]int{}, values)
+			count++
+		}
+		assert.Equal(t, 2, count)
+
+		var revlogCount int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM revlog").Scan(&revlogCount))
+		assert.Zero(t, revlogCount)
+		assert.Len(t, mockReviewRepo.Calls, reviewCalls)
 	})
 }
 
+// openExportedCollection extracts collection.anki2 from an exported package and opens it
+func openExportedCollection(t *testing.T, reader io.Reader) *sql.DB {
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	f, err := zr.Open("collection.anki2")
+	require.NoError(t, err)
+	collection, err := io.ReadAll(f)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	require.NoError(t, os.WriteFile(path, collection, 0o600))
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
 func TestExportService_ExportNotes_Validation(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockCardRepo := new(MockCardRepository)
 	mockNoteRepo := new(MockNoteRepository)
 	mockNoteTypeRepo := new(MockNoteTypeRepository)
 	mockMediaRepo := new(MockMediaRepository)
-	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo)
+	mockReviewRepo := new(MockReviewRepository)
+	mockPresetRepo := new(MockDeckOptionsPresetRepository)
+	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo, mockReviewRepo, mockPresetRepo)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -298,7 +519,9 @@ func TestExportService_ExportNotes_MediaExtraction(t *testing.T) {
 	mockNoteRepo := new(MockNoteRepository)
 	mockNoteTypeRepo := new(MockNoteTypeRepository)
 	mockMediaRepo := new(MockMediaRepository)
-	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo)
+	mockReviewRepo := new(MockReviewRepository)
+	mockPresetRepo := new(MockDeckOptionsPresetRepository)
+	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo, mockReviewRepo, mockPresetRepo)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -365,7 +588,9 @@ func TestExportService_ExportNotes_ErrorHandling(t *testing.T) {
 	mockNoteRepo := new(MockNoteRepository)
 	mockNoteTypeRepo := new(MockNoteTypeRepository)
 	mockMediaRepo := new(MockMediaRepository)
-	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo)
+	mockReviewRepo := new(MockReviewRepository)
+	mockPresetRepo := new(MockDeckOptionsPresetRepository)
+	service := exportSvc.NewExportService(mockDeckRepo, mockCardRepo, mockNoteRepo, mockNoteTypeRepo, mockMediaRepo, mockReviewRepo, mockPresetRepo)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -399,20 +624,8 @@ func TestExportService_ExportNotes_ErrorHandling(t *testing.T) {
 
 		notes := []*note.Note{note1}
 
-		noteType, _ := notetype.NewBuilder().
-			WithID(noteTypeID).
-			WithUserID(userID).
-			WithName("Basic").
-			WithFieldsJSON(`[{"name":"Front"}]`).
-			WithCardTypesJSON(`[{"name":"Card 1"}]`).
-			WithTemplatesJSON(`[{"name":"Template 1"}]`).
-			WithCreatedAt(time.Now()).
-			WithUpdatedAt(time.Now()).
-			Build()
-
 		mockNoteRepo.On("FindByIDs", ctx, userID, noteIDs).Return(notes, nil).Once()
 		mockCardRepo.On("FindByNoteIDs", ctx, userID, noteIDs).Return(nil, assert.AnError).Once()
-		mockNoteTypeRepo.On("FindByID", ctx, userID, noteTypeID).Return(noteType, nil).Once()
 
 		reader, size, filename, err := service.ExportNotes(ctx, userID, noteIDs, "text", false, true)
 		require.Error(t, err)
@@ -4,27 +4,151 @@ import (
 	"context"
 	"testing"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/domain/services/search"
 	filteredSvc "github.com/felipesantos/anki-backend/core/services/deck"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFilteredDeckService_Create(t *testing.T) {
 	mockRepo := new(MockFilteredDeckRepository)
-	service := filteredSvc.NewFilteredDeckService(mockRepo)
+	mockTM := new(MockTransactionManager)
+	service := filteredSvc.NewFilteredDeckService(mockRepo, new(MockCardRepository), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 
 	t.Run("Success", func(t *testing.T) {
 		name := "Filtered"
+		mockTM.ExpectTransaction()
 		mockRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
+		mockRepo.On("ReturnCards", ctx, userID, int64(0)).Return(0, nil).Once()
+		mockRepo.On("MoveCardsIn", ctx, userID, int64(0), mock.Anything, "oldest", 20).Return(5, nil).Once()
+		mockRepo.On("Update", ctx, userID, int64(0), mock.Anything).Return(nil).Once()
 
-		result, err := service.Create(ctx, userID, name, "tag:test", 20, "oldest", true)
+		result, err := service.Create(ctx, userID, name, "tag:test", nil, 20, 0, "oldest", true)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 		assert.Equal(t, name, result.GetName())
+		assert.Equal(t, 20, result.GetSecondLimitCards(), "an unset second limit falls back to the deck's limit")
+		assert.NotNil(t, result.GetLastRebuildAt())
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Invalid Search", func(t *testing.T) {
+		mockRepo := new(MockFilteredDeckRepository)
+		service := filteredSvc.NewFilteredDeckService(mockRepo, new(MockCardRepository), mockTM)
+		mockRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
+
+		_, err := service.Create(ctx, userID, "Filtered", "(tag:test", nil, 20, 0, filtereddeck.OrderDue, true)
+
+		assert.ErrorIs(t, err, filteredSvc.ErrInvalidFilteredDeckSearch)
+		mockRepo.AssertNotCalled(t, "MoveCardsIn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestFilteredDeckService_Rebuild(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	deckID := int64(7)
+	second := "is:new"
+	fd, _ := filtereddeck.NewBuilder().
+		WithID(deckID).
+		WithUserID(userID).
+		WithName("Cram").
+		WithSearchFilter("is:due").
+		WithSecondFilter(&second).
+		WithLimitCards(30).
+		WithSecondLimitCards(10).
+		WithOrderBy(filtereddeck.OrderRelativeOverdueness).
+		Build()
+
+	mockRepo := new(MockFilteredDeckRepository)
+	mockTM := new(MockTransactionManager)
+	service := filteredSvc.NewFilteredDeckService(mockRepo, new(MockCardRepository), mockTM)
+
+	mockTM.ExpectTransaction()
+	mockRepo.On("FindByID", ctx, userID, deckID).Return(fd, nil).Once()
+	returned := mockRepo.On("ReturnCards", ctx, userID, deckID).Return(12, nil).Once()
+	first := mockRepo.On("MoveCardsIn", ctx, userID, deckID, mock.MatchedBy(func(q *search.SearchQuery) bool {
+		return len(q.States) == 1 && q.States[0] == "due"
+	}), filtereddeck.OrderRelativeOverdueness, 30).Return(30, nil).Once().NotBefore(returned)
+	mockRepo.On("MoveCardsIn", ctx, userID, deckID, mock.MatchedBy(func(q *search.SearchQuery) bool {
+		return len(q.States) == 1 && q.States[0] == "new"
+	}), filtereddeck.OrderRelativeOverdueness, 10).Return(4, nil).Once().NotBefore(first)
+	mockRepo.On("Update", ctx, userID, deckID, fd).Return(nil).Once()
+
+	count, err := service.Rebuild(ctx, userID, deckID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 34, count)
+	assert.NotNil(t, fd.GetLastRebuildAt())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFilteredDeckService_Empty(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	fd, _ := filtereddeck.NewBuilder().WithID(7).WithUserID(userID).WithName("Cram").Build()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockFilteredDeckRepository)
+		service := filteredSvc.NewFilteredDeckService(mockRepo, new(MockCardRepository), new(MockTransactionManager))
+		mockRepo.On("FindByID", ctx, userID, int64(7)).Return(fd, nil).Once()
+		mockRepo.On("ReturnCards", ctx, userID, int64(7)).Return(3, nil).Once()
+
+		count, err := service.Empty(ctx, userID, 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo := new(MockFilteredDeckRepository)
+		service := filteredSvc.NewFilteredDeckService(mockRepo, new(MockCardRepository), new(MockTransactionManager))
+		mockRepo.On("FindByID", ctx, userID, int64(8)).Return(nil, nil).Once()
+
+		_, err := service.Empty(ctx, userID, 8)
+
+		assert.ErrorIs(t, err, filteredSvc.ErrFilteredDeckNotFound)
+		mockRepo.AssertNotCalled(t, "ReturnCards", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
+func TestFilteredDeckService_Delete(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	mockRepo := new(MockFilteredDeckRepository)
+	mockTM := new(MockTransactionManager)
+	service := filteredSvc.NewFilteredDeckService(mockRepo, new(MockCardRepository), mockTM)
+
+	mockTM.ExpectTransaction()
+	returned := mockRepo.On("ReturnCards", ctx, userID, int64(7)).Return(2, nil).Once()
+	mockRepo.On("Delete", ctx, userID, int64(7)).Return(nil).Once().NotBefore(returned)
+
+	err := service.Delete(ctx, userID, 7)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFilteredDeckService_FindCards(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	fd, _ := filtereddeck.NewBuilder().WithID(7).WithUserID(userID).WithName("Cram").Build()
+	c, _ := card.NewBuilder().WithID(100).WithNoteID(1).WithDeckID(10).Build()
+
+	mockRepo := new(MockFilteredDeckRepository)
+	mockCardRepo := new(MockCardRepository)
+	service := filteredSvc.NewFilteredDeckService(mockRepo, mockCardRepo, new(MockTransactionManager))
+	mockRepo.On("FindByID", ctx, userID, int64(7)).Return(fd, nil).Once()
+	mockCardRepo.On("FindByFilteredDeckID", ctx, userID, int64(7)).Return([]*card.Card{c}, nil).Once()
+
+	cards, err := service.FindCards(ctx, userID, 7)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*card.Card{c}, cards)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	deckoptionspreset "github.com/felipesantos/anki-backend/core/domain/entities/deck_options_preset"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	deckSvc "github.com/felipesantos/anki-backend/core/services/deck"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// reviewHistoryFixture builds a review log where every card is reviewed at growing intervals
+func reviewHistoryFixture(t *testing.T, cards int) []*review.Review {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	offsets := []int{0, 1, 4, 12, 30}
+
+	var reviews []*review.Review
+	for cardID := int64(1); cardID <= int64(cards); cardID++ {
+		for i, offset := range offsets {
+			rating := 3
+			if i == 3 && cardID%4 == 0 {
+				rating = 1
+			}
+			r, err := review.NewBuilder().
+				WithCardID(cardID).
+				WithRating(rating).
+				WithEase(2500).
+				WithType(valueobjects.ReviewTypeReview).
+				WithCreatedAt(start.AddDate(0, 0, offset)).
+				Build()
+			require.NoError(t, err)
+			reviews = append(reviews, r)
+		}
+	}
+	return reviews
+}
+
+func TestFSRSOptimizerService(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	presetID := int64(5)
+	deckID := int64(10)
+
+	newPreset := func() *deckoptionspreset.DeckOptionsPreset {
+		p, _ := deckoptionspreset.NewBuilder().
+			WithID(presetID).
+			WithUserID(userID).
+			WithName("FSRS").
+			WithOptionsJSON(`{"scheduler_type": "fsrs", "new_per_day": 30}`).
+			Build()
+		return p
+	}
+
+	t.Run("Optimize saves parameters to preset and decks", func(t *testing.T) {
+		mockReviewRepo := new(MockReviewRepository)
+		mockPresetRepo := new(MockDeckOptionsPresetRepository)
+		mockDeckRepo := new(MockDeckRepository)
+		mockTM := new(MockTransactionManager)
+		service := deckSvc.NewFSRSOptimizerService(mockReviewRepo, mockPresetRepo, mockDeckRepo, mockTM)
+
+		preset := newPreset()
+		d, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Default").WithOptionsJSON(`{"new_per_day": 10}`).Build()
+
+		mockTM.ExpectTransaction()
+		mockPresetRepo.On("FindByID", mock.Anything, userID, presetID).Return(preset, nil)
+		mockReviewRepo.On("FindHistory", mock.Anything, userID, []int64{deckID}).Return(reviewHistoryFixture(t, 30), nil).Once()
+		mockPresetRepo.On("Update", mock.Anything, userID, presetID, preset).Return(nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(d, nil).Once()
+		mockDeckRepo.On("Update", mock.Anything, userID, deckID, d).Return(nil).Once()
+
+		var progress []int
+		result, err := service.Optimize(ctx, userID, presetID, []int64{deckID}, func(p int) { progress = append(progress, p) })
+		require.NoError(t, err)
+		assert.LessOrEqual(t, result.After.LogLoss, result.Before.LogLoss)
+		assert.NotEmpty(t, progress)
+
+		presetOptions, err := deck.ParseDeckOptions(preset.GetOptionsJSON())
+		require.NoError(t, err)
+		assert.Equal(t, result.Weights, presetOptions.FSRSWeights)
+		assert.Equal(t, valueobjects.SchedulerTypeFSRS, presetOptions.SchedulerType)
+		assert.Equal(t, 30, presetOptions.NewPerDay)
+
+		deckOptions, err := d.GetOptions()
+		require.NoError(t, err)
+		assert.Equal(t, result.Weights, deckOptions.FSRSWeights)
+		assert.Equal(t, 10, deckOptions.NewPerDay)
+
+		mockReviewRepo.AssertExpectations(t)
+		mockPresetRepo.AssertExpectations(t)
+		mockDeckRepo.AssertExpectations(t)
+	})
+
+	t.Run("Evaluate does not persist", func(t *testing.T) {
+		mockReviewRepo := new(MockReviewRepository)
+		mockPresetRepo := new(MockDeckOptionsPresetRepository)
+		mockDeckRepo := new(MockDeckRepository)
+		service := deckSvc.NewFSRSOptimizerService(mockReviewRepo, mockPresetRepo, mockDeckRepo, new(MockTransactionManager))
+
+		mockPresetRepo.On("FindByID", mock.Anything, userID, presetID).Return(newPreset(), nil).Once()
+		mockReviewRepo.On("FindHistory", mock.Anything, userID, []int64{deckID}).Return(reviewHistoryFixture(t, 30), nil).Once()
+
+		result, err := service.Evaluate(ctx, userID, presetID, []int64{deckID})
+		require.NoError(t, err)
+		histories := scheduler.BuildFSRSHistories(reviewHistoryFixture(t, 30))
+		assert.Equal(t, scheduler.EvaluateFSRS(nil, histories), result.Before)
+		assert.LessOrEqual(t, result.After.LogLoss, result.Before.LogLoss)
+		assert.NotEmpty(t, result.Weights)
+		assert.Equal(t, 30*4, result.ReviewCount)
+		mockPresetRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockDeckRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not enough reviews", func(t *testing.T) {
+		mockReviewRepo := new(MockReviewRepository)
+		mockPresetRepo := new(MockDeckOptionsPresetRepository)
+		service := deckSvc.NewFSRSOptimizerService(mockReviewRepo, mockPresetRepo, new(MockDeckRepository), new(MockTransactionManager))
+
+		mockPresetRepo.On("FindByID", mock.Anything, userID, presetID).Return(newPreset(), nil).Once()
+		mockReviewRepo.On("FindHistory", mock.Anything, userID, []int64{deckID}).Return(reviewHistoryFixture(t, 1), nil).Once()
+
+		_, err := service.Optimize(ctx, userID, presetID, []int64{deckID}, nil)
+		assert.ErrorIs(t, err, scheduler.ErrNotEnoughReviews)
+	})
+
+	t.Run("Preset not found", func(t *testing.T) {
+		mockPresetRepo := new(MockDeckOptionsPresetRepository)
+		service := deckSvc.NewFSRSOptimizerService(new(MockReviewRepository), mockPresetRepo, new(MockDeckRepository), new(MockTransactionManager))
+
+		mockPresetRepo.On("FindByID", mock.Anything, userID, presetID).Return(nil, nil).Once()
+
+		_, err := service.Evaluate(ctx, userID, presetID, []int64{deckID})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "preset not found")
+	})
+
+	t.Run("Decks required", func(t *testing.T) {
+		mockReviewRepo := new(MockReviewRepository)
+		mockPresetRepo := new(MockDeckOptionsPresetRepository)
+		service := deckSvc.NewFSRSOptimizerService(mockReviewRepo, mockPresetRepo, new(MockDeckRepository), new(MockTransactionManager))
+
+		_, err := service.Optimize(ctx, userID, presetID, nil, nil)
+		assert.ErrorIs(t, err, deckSvc.ErrFSRSDecksRequired)
+		_, err = service.Evaluate(ctx, userID, presetID, []int64{})
+		assert.ErrorIs(t, err, deckSvc.ErrFSRSDecksRequired)
+		mockReviewRepo.AssertNotCalled(t, "FindHistory", mock.Anything, mock.Anything, mock.Anything)
+		mockPresetRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
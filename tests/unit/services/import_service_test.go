@@ -0,0 +1,529 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	_ "modernc.org/sqlite"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	"github.com/felipesantos/anki-backend/core/interfaces/primary"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/core/services/importer"
+	mediaSvc "github.com/felipesantos/anki-backend/core/services/media"
+)
+
+const ankiCollectionCreated = int64(1700000000)
+
+// buildAnkiCollection writes a schema 11 collection with one Basic note, its review card and two review log entries
+func buildAnkiCollection(t *testing.T, noteMod int64) []byte {
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	models := `{"1001": {"name": "Basic", "type": 0, "css": ".card {}",
+		"flds": [{"name": "Front", "ord": 0}, {"name": "Back", "ord": 1}],
+		"tmpls": [{"name": "Card 1", "ord": 0, "qfmt": "{{Front}}", "afmt": "{{Back}}"}]}}`
+	decks := `{"1": {"name": "Default", "dyn": 0}, "2001": {"name": "Languages::Spanish", "dyn": 0}}`
+	for _, stmt := range []string{
+		"CREATE TABLE col (id integer primary key, crt integer, models text, decks text)",
+		"CREATE TABLE notes (id integer primary key, guid text, mid integer, mod integer, tags text, flds text)",
+		`CREATE TABLE cards (id integer primary key, nid integer, did integer, ord integer, mod integer, type integer, queue integer,
+			due integer, ivl integer, factor integer, reps integer, lapses integer, odue integer, odid integer, flags integer, data text)`,
+		"CREATE TABLE revlog (id integer primary key, cid integer, ease integer, ivl integer, factor integer, time integer, type integer)",
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec("INSERT INTO col VALUES (1, ?, ?, ?)", ankiCollectionCreated, models, decks)
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO notes VALUES (1600000000000, 'f8Kx!A2b', 1001, ?, ' vocab ', ?)",
+		noteMod, "hola <img src=\"cat.jpg\">\x1fhello [sound:hola.mp3]")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO cards VALUES (1600000000001, 1600000000000, 2001, 0, 1700100000, 2, 2, 30, 12, 2300, 5, 1, 0, 0, 3, '{\"s\": 14.5, \"d\": 5.2}')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO revlog VALUES (1700000000000, 1600000000001, 3, 12, 2300, 8000, 1), (1700050000000, 1600000000001, 0, 0, 2300, 0, 4)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+// buildLegacyPackage zips a collection.anki2 with a JSON media map
+func buildLegacyPackage(t *testing.T, collection []byte, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("collection.anki2")
+	w.Write(collection)
+
+	mediaMap := make(map[string]string)
+	i := 0
+	for name, content := range files {
+		key := string(rune('0' + i))
+		mediaMap[key] = name
+		w, _ := zw.Create(key)
+		w.Write(content)
+		i++
+	}
+	mapJSON, _ := json.Marshal(mediaMap)
+	w, _ = zw.Create("media")
+	w.Write(mapJSON)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// buildLatestPackage zips a zstd collection.anki21b with package metadata and a protobuf media map
+func buildLatestPackage(t *testing.T, collection []byte, name string, content []byte) []byte {
+	enc, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer enc.Close()
+
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, name)
+	var mediaMap []byte
+	mediaMap = protowire.AppendTag(mediaMap, 1, protowire.BytesType)
+	mediaMap = protowire.AppendBytes(mediaMap, entry)
+	var meta []byte
+	meta = protowire.AppendTag(meta, 1, protowire.VarintType)
+	meta = protowire.AppendVarint(meta, 3)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for entryName, data := range map[string][]byte{
+		"collection.anki2":   []byte("legacy placeholder"),
+		"collection.anki21b": enc.EncodeAll(collection, nil),
+		"media":              enc.EncodeAll(mediaMap, nil),
+		"0":                  enc.EncodeAll(content, nil),
+		"meta":               meta,
+	} {
+		w, _ := zw.Create(entryName)
+		w.Write(data)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+const importMediaQuota = 1024
+
+type importMocks struct {
+	noteTypeRepo *MockNoteTypeRepository
+	deckRepo     *MockDeckRepository
+	noteRepo     *MockNoteRepository
+	cardRepo     *MockCardRepository
+	reviewRepo   *MockReviewRepository
+	mediaRepo    *MockMediaRepository
+	storageRepo  *MockStorageRepository
+	noteService  *MockNoteService
+	tm           *MockTransactionManager
+}
+
+func setupImportService() (primary.IImportService, *importMocks) {
+	m := &importMocks{
+		noteTypeRepo: new(MockNoteTypeRepository),
+		deckRepo:     new(MockDeckRepository),
+		noteRepo:     new(MockNoteRepository),
+		cardRepo:     new(MockCardRepository),
+		reviewRepo:   new(MockReviewRepository),
+		mediaRepo:    new(MockMediaRepository),
+		storageRepo:  new(MockStorageRepository),
+		noteService:  new(MockNoteService),
+		tm:           new(MockTransactionManager),
+	}
+	mediaService := mediaSvc.NewMediaService(m.mediaRepo, m.storageRepo, importMediaQuota)
+	service := importer.NewImportService(m.noteTypeRepo, m.deckRepo, m.noteRepo, m.cardRepo, m.reviewRepo, m.mediaRepo, mediaService, m.noteService, m.tm)
+	return service, m
+}
+
+func TestImportService_ImportPackage(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	existingNoteType := func() *notetype.NoteType {
+		nt, _ := notetype.NewBuilder().
+			WithID(7).
+			WithUserID(userID).
+			WithName("Basic").
+			WithFieldsJSON(`[{"name":"Front"},{"name":"Back"}]`).
+			WithCardTypesJSON(`[{"name":"Card 1"}]`).
+			WithTemplatesJSON(`[{"qfmt":"{{Front}}","afmt":"{{Back}}"}]`).
+			Build()
+		return nt
+	}
+	existingNote := func(updatedAt time.Time) *note.Note {
+		guid, _ := valueobjects.NewGUID("0b4f2a51-5d3c-4a4e-9c1e-6f7a8b9c0d1e")
+		n, _ := note.NewBuilder().
+			WithID(50).
+			WithUserID(userID).
+			WithGUID(guid).
+			WithNoteTypeID(7).
+			WithFieldsJSON(`{"Front":"old","Back":"old"}`).
+			WithUpdatedAt(updatedAt).
+			Build()
+		return n
+	}
+
+	t.Run("Legacy package adds note type, decks, note, card, review log and media", func(t *testing.T) {
+		service, m := setupImportService()
+		pkg := buildLegacyPackage(t, buildAnkiCollection(t, 1700100000), map[string][]byte{"cat.jpg": []byte("cat picture")})
+
+		m.tm.ExpectTransaction()
+		other, _ := media.NewBuilder().WithID(3).WithUserID(userID).WithFilename("cat.jpg").Build()
+		m.mediaRepo.On("FindByHash", ctx, userID, mock.Anything).Return(nil, nil).Twice()
+		m.mediaRepo.On("TotalSize", ctx, userID).Return(int64(0), nil).Once()
+		m.mediaRepo.On("FindByFilename", ctx, userID, "cat.jpg").Return(other, nil).Once()
+		m.mediaRepo.On("FindByFilename", ctx, userID, mock.Anything).Return(nil, nil).Once()
+		m.storageRepo.On("Exists", ctx, mock.Anything).Return(false, nil).Once()
+		m.storageRepo.On("Upload", ctx, mock.Anything, mock.MatchedBy(func(p string) bool { return len(p) == len("media/1/")+40 }), "image/jpeg").
+			Return(&secondary.FileInfo{}, nil).Once()
+		var savedMedia *media.Media
+		m.mediaRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) { savedMedia = args.Get(2).(*media.Media) }).Return(nil).Once()
+
+		m.noteTypeRepo.On("FindByName", ctx, userID, "Basic").Return(nil, nil).Once()
+		var savedNoteType *notetype.NoteType
+		m.noteTypeRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			savedNoteType = args.Get(2).(*notetype.NoteType)
+			savedNoteType.SetID(9)
+		}).Return(nil).Once()
+
+		m.noteRepo.On("FindByGUID", ctx, userID, mock.Anything).Return(nil, nil).Once()
+		var savedNote *note.Note
+		m.noteRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			savedNote = args.Get(2).(*note.Note)
+			savedNote.SetID(60)
+		}).Return(nil).Once()
+
+		languages, _ := deck.NewBuilder().WithID(20).WithUserID(userID).WithName("Languages").Build()
+		m.deckRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{languages}, nil).Once()
+		var savedDeck *deck.Deck
+		m.deckRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			savedDeck = args.Get(2).(*deck.Deck)
+			savedDeck.SetID(21)
+		}).Return(nil).Once()
+
+		var savedCard *card.Card
+		m.cardRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			savedCard = args.Get(2).(*card.Card)
+			savedCard.SetID(70)
+		}).Return(nil).Once()
+		var savedReviews []*review.Review
+		m.reviewRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			savedReviews = append(savedReviews, args.Get(2).(*review.Review))
+		}).Return(nil).Twice()
+
+		result, err := service.ImportPackage(ctx, userID, bytes.NewReader(pkg), int64(len(pkg)), primary.ImportOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, &primary.ImportResult{NoteTypesAdded: 1, DecksAdded: 1, NotesAdded: 1, CardsAdded: 1, ReviewsAdded: 2, MediaAdded: 1}, result)
+
+		assert.Regexp(t, `^cat-[0-9a-f]{8}\.jpg$`, savedMedia.GetFilename())
+		assert.Equal(t, "Basic", savedNoteType.GetName())
+		assert.Contains(t, savedNoteType.GetTemplatesJSON(), `"css":".card {}"`)
+
+		assert.Equal(t, int64(9), savedNote.GetNoteTypeID())
+		assert.Equal(t, []string{"vocab"}, savedNote.GetTags())
+		var fields map[string]string
+		require.NoError(t, json.Unmarshal([]byte(savedNote.GetFieldsJSON()), &fields))
+		assert.Equal(t, `hola <img src="`+savedMedia.GetFilename()+`">`, fields["Front"])
+		assert.Equal(t, "hello [sound:hola.mp3]", fields["Back"])
+		assert.Equal(t, time.Unix(1700100000, 0), savedNote.GetUpdatedAt())
+
+		assert.Equal(t, "Spanish", savedDeck.GetName())
+		assert.Equal(t, int64(20), *savedDeck.GetParentID())
+
+		assert.Equal(t, int64(60), savedCard.GetNoteID())
+		assert.Equal(t, int64(21), savedCard.GetDeckID())
+		assert.Equal(t, valueobjects.CardStateReview, savedCard.GetState())
+		assert.Equal(t, time.Unix(ankiCollectionCreated, 0).AddDate(0, 0, 30).UnixMilli(), savedCard.GetDue())
+		assert.Equal(t, 12, savedCard.GetInterval())
+		assert.Equal(t, 2300, savedCard.GetEase())
+		assert.Equal(t, 3, savedCard.GetFlag())
+		assert.Equal(t, 14.5, *savedCard.GetStability())
+		assert.Equal(t, time.UnixMilli(1700000000000), *savedCard.GetLastReviewAt())
+
+		require.Len(t, savedReviews, 2)
+		assert.Equal(t, int64(70), savedReviews[0].GetCardID())
+		assert.Equal(t, 3, savedReviews[0].GetRating())
+		assert.Equal(t, valueobjects.ReviewTypeReview, savedReviews[0].GetType())
+		assert.Equal(t, 0, savedReviews[1].GetRating())
+		assert.Equal(t, valueobjects.ReviewTypeManual, savedReviews[1].GetType())
+
+		m.mediaRepo.AssertExpectations(t)
+		m.storageRepo.AssertExpectations(t)
+		m.deckRepo.AssertExpectations(t)
+		m.reviewRepo.AssertExpectations(t)
+	})
+
+	t.Run("Latest zstd package", func(t *testing.T) {
+		service, m := setupImportService()
+		pkg := buildLatestPackage(t, buildAnkiCollection(t, 1700100000), "hola.mp3", []byte("audio"))
+
+		m.tm.ExpectTransaction()
+		m.mediaRepo.On("FindByHash", ctx, userID, mock.Anything).Return(nil, nil).Twice()
+		m.mediaRepo.On("TotalSize", ctx, userID).Return(int64(0), nil).Once()
+		m.mediaRepo.On("FindByFilename", ctx, userID, "hola.mp3").Return(nil, nil).Once()
+		m.storageRepo.On("Exists", ctx, mock.Anything).Return(false, nil).Once()
+		m.storageRepo.On("Upload", ctx, mock.Anything, mock.Anything, "audio/mpeg").Return(&secondary.FileInfo{}, nil).Once()
+		m.mediaRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
+		m.noteTypeRepo.On("FindByName", ctx, userID, "Basic").Return(existingNoteType(), nil).Once()
+		m.noteRepo.On("FindByGUID", ctx, userID, mock.Anything).Return(nil, nil).Once()
+		m.noteRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) { args.Get(2).(*note.Note).SetID(60) }).Return(nil).Once()
+		m.deckRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{}, nil).Once()
+		m.deckRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) { args.Get(2).(*deck.Deck).SetID(21) }).Return(nil).Twice()
+		m.cardRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) { args.Get(2).(*card.Card).SetID(70) }).Return(nil).Once()
+		m.reviewRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Twice()
+
+		result, err := service.ImportPackage(ctx, userID, bytes.NewReader(pkg), int64(len(pkg)), primary.ImportOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, &primary.ImportResult{DecksAdded: 2, NotesAdded: 1, CardsAdded: 1, ReviewsAdded: 2, MediaAdded: 1}, result)
+		m.noteTypeRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Media over quota", func(t *testing.T) {
+		service, m := setupImportService()
+		pkg := buildLatestPackage(t, buildAnkiCollection(t, 1700100000), "hola.mp3", []byte("audio"))
+
+		m.tm.ExpectTransaction()
+		m.mediaRepo.On("FindByHash", ctx, userID, mock.Anything).Return(nil, nil).Twice()
+		m.mediaRepo.On("TotalSize", ctx, userID).Return(int64(importMediaQuota-2), nil).Once()
+
+		result, err := service.ImportPackage(ctx, userID, bytes.NewReader(pkg), int64(len(pkg)), primary.ImportOptions{})
+
+		assert.ErrorIs(t, err, mediaSvc.ErrQuotaExceeded)
+		assert.Nil(t, result)
+		m.storageRepo.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		m.noteRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Update modes for a matching GUID", func(t *testing.T) {
+		tests := []struct {
+			mode      string
+			updatedAt time.Time
+			updated   bool
+		}{
+			{primary.ImportUpdateIfNewer, time.Unix(1700000000, 0), true},
+			{primary.ImportUpdateIfNewer, time.Unix(1800000000, 0), false},
+			{primary.ImportUpdateSkip, time.Unix(1700000000, 0), false},
+			{primary.ImportUpdateAlways, time.Unix(1800000000, 0), true},
+		}
+		pkg := buildLegacyPackage(t, buildAnkiCollection(t, 1700100000), nil)
+
+		for _, tt := range tests {
+			service, m := setupImportService()
+			m.tm.ExpectTransaction()
+			m.noteTypeRepo.On("FindByName", ctx, userID, "Basic").Return(existingNoteType(), nil).Once()
+			m.noteRepo.On("FindByGUID", ctx, userID, mock.Anything).Return(existingNote(tt.updatedAt), nil).Once()
+			existingCard, _ := card.NewBuilder().WithID(80).WithNoteID(50).WithCardTypeID(0).WithDeckID(1).Build()
+			m.cardRepo.On("FindByNoteID", ctx, userID, int64(50)).Return([]*card.Card{existingCard}, nil).Once()
+			if tt.updated {
+				m.noteRepo.On("Update", ctx, userID, int64(50), mock.MatchedBy(func(n *note.Note) bool {
+					return n.GetUpdatedAt().Equal(time.Unix(1700100000, 0)) && n.GetTags()[0] == "vocab"
+				})).Return(nil).Once()
+			}
+
+			result, err := service.ImportPackage(ctx, userID, bytes.NewReader(pkg), int64(len(pkg)), primary.ImportOptions{UpdateMode: tt.mode})
+
+			require.NoError(t, err)
+			if tt.updated {
+				assert.Equal(t, &primary.ImportResult{NotesUpdated: 1}, result, tt.mode)
+			} else {
+				assert.Equal(t, &primary.ImportResult{NotesSkipped: 1}, result, tt.mode)
+				m.noteRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			m.cardRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+			m.noteRepo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("Invalid update mode", func(t *testing.T) {
+		service, _ := setupImportService()
+
+		_, err := service.ImportPackage(ctx, userID, bytes.NewReader(nil), 0, primary.ImportOptions{UpdateMode: "merge"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid update mode")
+	})
+
+	t.Run("Invalid package", func(t *testing.T) {
+		service, m := setupImportService()
+		data := []byte("not a zip file")
+
+		_, err := service.ImportPackage(ctx, userID, bytes.NewReader(data), int64(len(data)), primary.ImportOptions{})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid package")
+		m.tm.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImportService_ImportText(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	basic, _ := notetype.NewBuilder().
+		WithID(7).
+		WithUserID(userID).
+		WithName("Basic").
+		WithFieldsJSON(`[{"name":"Front"},{"name":"Back"}]`).
+		WithCardTypesJSON(`[{"name":"Card 1"}]`).
+		WithTemplatesJSON(`[{"qfmt":"{{Front}}","afmt":"{{Back}}"}]`).
+		Build()
+	defaultDeck, _ := deck.NewBuilder().WithID(3).WithUserID(userID).WithName("Default").Build()
+	existingNote := func(id int64, fieldsJSON string, tags []string) *note.Note {
+		guid, _ := valueobjects.NewGUID("0b4f2a51-5d3c-4a4e-9c1e-6f7a8b9c0d1e")
+		n, _ := note.NewBuilder().WithID(id).WithUserID(userID).WithGUID(guid).WithNoteTypeID(7).WithFieldsJSON(fieldsJSON).WithTags(tags).Build()
+		return n
+	}
+	sameJSON := func(expected string) interface{} {
+		return mock.MatchedBy(func(actual string) bool {
+			var a, b interface{}
+			return json.Unmarshal([]byte(expected), &a) == nil && json.Unmarshal([]byte(actual), &b) == nil && assert.ObjectsAreEqual(a, b)
+		})
+	}
+	createdNote := func(id int64) *note.Note {
+		n := &note.Note{}
+		n.SetID(id)
+		return n
+	}
+
+	t.Run("TSV with header directives adds, updates and reports rows", func(t *testing.T) {
+		service, m := setupImportService()
+		file := "#separator:Tab\n#html:false\n#tags column:3\n" +
+			"hola\thello\tvocab spanish\n" +
+			"adios\t<b>bye</b>\t\n" +
+			"\tno front\t\n"
+
+		m.noteTypeRepo.On("FindByID", ctx, userID, int64(7)).Return(basic, nil).Once()
+		m.deckRepo.On("FindByID", ctx, userID, int64(3)).Return(defaultDeck, nil).Once()
+		m.noteRepo.On("FindByNoteTypeID", ctx, userID, int64(7), 500, 0).
+			Return([]*note.Note{existingNote(50, `{"Front":"adios","Back":"goodbye"}`, []string{"old"})}, nil).Once()
+		m.noteService.On("Create", ctx, userID, int64(7), int64(3), `{"Back":"hello","Front":"hola"}`, []string{"vocab", "spanish", "imported"}).
+			Return(createdNote(60), nil).Once()
+		m.noteRepo.On("FindByID", ctx, userID, int64(50)).Return(existingNote(50, `{"Front":"adios","Back":"goodbye"}`, []string{"old"}), nil).Once()
+		m.noteService.On("Update", ctx, userID, int64(50), sameJSON(`{"Back":"&lt;b&gt;bye&lt;/b&gt;","Front":"adios"}`), []string{"imported"}).
+			Return(createdNote(50), nil).Once()
+		m.noteService.On("Create", ctx, userID, int64(7), int64(3), `{"Back":"no front","Front":""}`, []string{"imported"}).
+			Return(nil, note.ErrFirstFieldRequired).Once()
+
+		result, err := service.ImportText(ctx, userID, bytes.NewReader([]byte(file)), primary.TextImportOptions{
+			NoteTypeID: 7,
+			DeckID:     3,
+			Tags:       []string{"imported"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Added)
+		assert.Equal(t, 1, result.Updated)
+		assert.Equal(t, 1, result.Errors)
+		assert.Equal(t, []primary.TextImportRow{
+			{Line: 4, Status: primary.TextImportRowAdded, NoteID: 60},
+			{Line: 5, Status: primary.TextImportRowUpdated, NoteID: 50},
+			{Line: 6, Status: primary.TextImportRowError, Message: note.ErrFirstFieldRequired.Error()},
+		}, result.Rows)
+		m.noteService.AssertExpectations(t)
+	})
+
+	t.Run("Text export round trip matches notes by GUID", func(t *testing.T) {
+		service, m := setupImportService()
+		file := "GUID\tBack\tFront\tTags\n" +
+			"0b4f2a51-5d3c-4a4e-9c1e-6f7a8b9c0d1e\tgoodbye\tadios\tvocab\n" +
+			"CARD\t1\t3\t1700000000000\t1\t2500\t1\tnew\n"
+
+		m.noteTypeRepo.On("FindByID", ctx, userID, int64(7)).Return(basic, nil).Once()
+		m.noteRepo.On("FindByGUID", ctx, userID, "0b4f2a51-5d3c-4a4e-9c1e-6f7a8b9c0d1e").
+			Return(existingNote(50, `{"Front":"adios","Back":"goodbye"}`, []string{"vocab"}), nil).Once()
+		m.noteRepo.On("FindByID", ctx, userID, int64(50)).Return(existingNote(50, `{"Front":"adios","Back":"goodbye"}`, []string{"vocab"}), nil).Once()
+
+		result, err := service.ImportText(ctx, userID, bytes.NewReader([]byte(file)), primary.TextImportOptions{
+			NoteTypeID: 7,
+			DeckID:     0,
+			HeaderRow:  true,
+			HTML:       true,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Skipped)
+		assert.Equal(t, primary.TextImportRow{Line: 2, Status: primary.TextImportRowSkipped, NoteID: 50, Message: "note is unchanged"}, result.Rows[0])
+		assert.Equal(t, 3, result.Rows[1].Line)
+		m.noteService.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("CSV with note type and deck columns and a field map", func(t *testing.T) {
+		service, m := setupImportService()
+		file := "Basic,Languages::Spanish,ignored,hola,hello\n"
+
+		m.noteTypeRepo.On("FindByName", ctx, userID, "Basic").Return(basic, nil).Once()
+		m.deckRepo.On("FindByUserID", ctx, userID, "").Return([]*deck.Deck{defaultDeck}, nil).Once()
+		m.deckRepo.On("Save", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			d := args.Get(2).(*deck.Deck)
+			d.SetID(10 + int64(len(d.GetName())))
+		}).Return(nil).Twice()
+		m.noteService.On("Create", ctx, userID, int64(7), int64(17), `{"Back":"hello","Front":"hola"}`, []string{}).
+			Return(createdNote(61), nil).Once()
+
+		result, err := service.ImportText(ctx, userID, bytes.NewReader([]byte(file)), primary.TextImportOptions{
+			NoteTypeColumn: 1,
+			DeckColumn:     2,
+			FieldMap:       []string{"", "", "", "Front", "Back"},
+			DuplicateMode:  primary.ImportDuplicateAdd,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []primary.TextImportRow{{Line: 1, Status: primary.TextImportRowAdded, NoteID: 61}}, result.Rows)
+		m.deckRepo.AssertExpectations(t)
+		m.noteRepo.AssertNotCalled(t, "FindByNoteTypeID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Skip mode keeps first field duplicates", func(t *testing.T) {
+		service, m := setupImportService()
+
+		m.noteTypeRepo.On("FindByID", ctx, userID, int64(7)).Return(basic, nil).Once()
+		m.deckRepo.On("FindByID", ctx, userID, int64(3)).Return(defaultDeck, nil).Once()
+		m.noteRepo.On("FindByNoteTypeID", ctx, userID, int64(7), 500, 0).
+			Return([]*note.Note{existingNote(50, `{"Front":"adios","Back":"goodbye"}`, nil)}, nil).Once()
+
+		result, err := service.ImportText(ctx, userID, bytes.NewReader([]byte("adios;bye\n")), primary.TextImportOptions{
+			NoteTypeID:    7,
+			DeckID:        3,
+			DuplicateMode: primary.ImportDuplicateSkip,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []primary.TextImportRow{{Line: 1, Status: primary.TextImportRowSkipped, NoteID: 50, Message: "note with the same first field exists"}}, result.Rows)
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		service, _ := setupImportService()
+
+		_, err := service.ImportText(ctx, userID, bytes.NewReader([]byte("a\tb\n")), primary.TextImportOptions{DeckID: 3})
+		assert.EqualError(t, err, "note type is required")
+
+		_, err = service.ImportText(ctx, userID, bytes.NewReader([]byte("a\tb\n")), primary.TextImportOptions{NoteTypeID: 7, Delimiter: "::"})
+		assert.EqualError(t, err, "invalid delimiter: ::")
+
+		_, err = service.ImportText(ctx, userID, bytes.NewReader([]byte("a\tb\n")), primary.TextImportOptions{NoteTypeID: 7, DuplicateMode: "merge"})
+		assert.ErrorContains(t, err, "invalid duplicate mode")
+	})
+}
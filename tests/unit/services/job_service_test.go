@@ -17,6 +17,7 @@ type mockJobQueue struct {
 	getStatusFunc  func(ctx context.Context, jobID string) (*secondary.Job, error)
 	updateStatusFunc func(ctx context.Context, jobID string, status secondary.JobStatus) error
 	retryFunc      func(ctx context.Context, job *secondary.Job) error
+	updateProgressFunc func(ctx context.Context, jobID string, progress int, result map[string]interface{}) error
 }
 
 func (m *mockJobQueue) Enqueue(ctx context.Context, job *secondary.Job) error {
@@ -47,6 +48,13 @@ func (m *mockJobQueue) UpdateStatus(ctx context.Context, jobID string, status se
 	return errors.New("not implemented")
 }
 
+func (m *mockJobQueue) UpdateProgress(ctx context.Context, jobID string, progress int, result map[string]interface{}) error {
+	if m.updateProgressFunc != nil {
+		return m.updateProgressFunc(ctx, jobID, progress, result)
+	}
+	return errors.New("not implemented")
+}
+
 func (m *mockJobQueue) Retry(ctx context.Context, job *secondary.Job) error {
 	if m.retryFunc != nil {
 		return m.retryFunc(ctx, job)
@@ -2,29 +2,211 @@ package services
 
 import (
 	"context"
+	"io"
+	"strings"
 	"testing"
 
+	"github.com/felipesantos/anki-backend/core/domain/entities/media"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 	mediaSvc "github.com/felipesantos/anki-backend/core/services/media"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-func TestMediaService_Create(t *testing.T) {
-	mockRepo := new(MockMediaRepository)
-	service := mediaSvc.NewMediaService(mockRepo)
+func TestMediaService_Upload(t *testing.T) {
 	ctx := context.Background()
 	userID := int64(1)
+	content := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	hash := media.ContentHash([]byte(content))
+	path := media.ContentPath(userID, hash)
 
 	t.Run("Success", func(t *testing.T) {
-		filename := "img.png"
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 1<<20)
+
+		var uploaded string
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(nil, nil).Once()
+		mockRepo.On("TotalSize", ctx, userID).Return(int64(1000), nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, "img.png").Return(nil, nil).Once()
+		mockStorage.On("Exists", ctx, path).Return(false, nil).Once()
+		mockStorage.On("Upload", ctx, mock.Anything, path, "image/png").Run(func(args mock.Arguments) {
+			data, _ := io.ReadAll(args.Get(1).(io.Reader))
+			uploaded = string(data)
+		}).Return(&secondary.FileInfo{}, nil).Once()
 		mockRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
 
-		result, err := service.Create(ctx, userID, filename, "hash123", 100, "image/png", "/path/to/storage")
+		result, err := service.Upload(ctx, userID, "img.png", strings.NewReader(content))
 
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
-		assert.Equal(t, filename, result.GetFilename())
+		require.NoError(t, err)
+		assert.Equal(t, "img.png", result.GetFilename())
+		assert.Equal(t, hash, result.GetHash())
+		assert.Equal(t, int64(len(content)), result.GetSize())
+		assert.Equal(t, "image/png", result.GetMimeType())
+		assert.Equal(t, path, result.GetStoragePath())
+		assert.Equal(t, content, uploaded)
 		mockRepo.AssertExpectations(t)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Identical content returns the existing media", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 1<<20)
+
+		existing, _ := media.NewBuilder().WithID(7).WithUserID(userID).WithFilename("other.png").WithHash(hash).Build()
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(existing, nil).Once()
+
+		result, err := service.Upload(ctx, userID, "img.png", strings.NewReader(content))
+
+		require.NoError(t, err)
+		assert.Equal(t, existing, result)
+		mockStorage.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Taken filename gets a hash suffix", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 0)
+
+		taken, _ := media.NewBuilder().WithID(7).WithUserID(userID).WithFilename("img.png").Build()
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(nil, nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, "img.png").Return(taken, nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, "img-"+hash[:8]+".png").Return(nil, nil).Once()
+		mockStorage.On("Exists", ctx, path).Return(true, nil).Once()
+		mockRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
+
+		result, err := service.Upload(ctx, userID, "img.png", strings.NewReader(content))
+
+		require.NoError(t, err)
+		assert.Equal(t, "img-"+hash[:8]+".png", result.GetFilename())
+		mockStorage.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Taken hash-suffixed filename gets the whole hash", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 0)
+
+		taken, _ := media.NewBuilder().WithID(7).WithUserID(userID).WithFilename("img.png").Build()
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(nil, nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, "img.png").Return(taken, nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, "img-"+hash[:8]+".png").Return(taken, nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, "img-"+hash+".png").Return(nil, nil).Once()
+		mockStorage.On("Exists", ctx, path).Return(true, nil).Once()
+		mockRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
+
+		result, err := service.Upload(ctx, userID, "img.png", strings.NewReader(content))
+
+		require.NoError(t, err)
+		assert.Equal(t, "img-"+hash+".png", result.GetFilename())
+	})
+
+	t.Run("Every candidate filename taken", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 0)
+
+		taken, _ := media.NewBuilder().WithID(7).WithUserID(userID).WithFilename("img.png").Build()
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(nil, nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, mock.Anything).Return(taken, nil).Times(3)
+
+		_, err := service.Upload(ctx, userID, "img.png", strings.NewReader(content))
+
+		assert.ErrorIs(t, err, mediaSvc.ErrFilenameTaken)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Concurrent upload of the same content returns the recorded media", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 0)
+
+		recorded, _ := media.NewBuilder().WithID(8).WithUserID(userID).WithFilename("img.png").WithHash(hash).Build()
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(nil, nil).Once()
+		mockRepo.On("FindByFilename", ctx, userID, "img.png").Return(nil, nil).Once()
+		mockStorage.On("Exists", ctx, path).Return(true, nil).Once()
+		mockRepo.On("Save", ctx, userID, mock.Anything).Return(media.ErrDuplicateContent).Once()
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(recorded, nil).Once()
+
+		result, err := service.Upload(ctx, userID, "img.png", strings.NewReader(content))
+
+		require.NoError(t, err)
+		assert.Same(t, recorded, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Quota exceeded", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 1<<20)
+
+		mockRepo.On("FindByHash", ctx, userID, hash).Return(nil, nil).Once()
+		mockRepo.On("TotalSize", ctx, userID).Return(int64(1<<20-4), nil).Once()
+
+		_, err := service.Upload(ctx, userID, "img.png", strings.NewReader(content))
+
+		assert.ErrorIs(t, err, mediaSvc.ErrQuotaExceeded)
+		mockStorage.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid filename and empty file", func(t *testing.T) {
+		service := mediaSvc.NewMediaService(new(MockMediaRepository), new(MockStorageRepository), 0)
+
+		_, err := service.Upload(ctx, userID, "../img.png", strings.NewReader(content))
+		assert.ErrorIs(t, err, mediaSvc.ErrInvalidFilename)
+
+		_, err = service.Upload(ctx, userID, "img.png", strings.NewReader(""))
+		assert.ErrorIs(t, err, mediaSvc.ErrEmptyMedia)
 	})
 }
 
+func TestMediaService_GetContent(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	m, _ := media.NewBuilder().WithID(10).WithUserID(userID).WithFilename("img.png").WithStoragePath("media/1/abc").Build()
+
+	t.Run("Presigned URL", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 0)
+
+		mockRepo.On("FindByID", ctx, userID, int64(10)).Return(m, nil).Once()
+		mockStorage.On("GetURL", ctx, "media/1/abc", mock.Anything).Return("https://bucket.example.com/media/1/abc?sig=1", nil).Once()
+
+		content, err := service.GetContent(ctx, userID, 10)
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://bucket.example.com/media/1/abc?sig=1", content.URL)
+		mockStorage.AssertNotCalled(t, "Download", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Local storage streams the content", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		mockStorage := new(MockStorageRepository)
+		service := mediaSvc.NewMediaService(mockRepo, mockStorage, 0)
+
+		mockRepo.On("FindByID", ctx, userID, int64(10)).Return(m, nil).Once()
+		mockStorage.On("GetURL", ctx, "media/1/abc", mock.Anything).Return("file:///storage/media/1/abc", nil).Once()
+		mockStorage.On("Download", ctx, "media/1/abc").Return([]byte("png data"), nil).Once()
+
+		content, err := service.GetContent(ctx, userID, 10)
+
+		require.NoError(t, err)
+		assert.Empty(t, content.URL)
+		data, _ := io.ReadAll(content.Data)
+		assert.Equal(t, "png data", string(data))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo := new(MockMediaRepository)
+		service := mediaSvc.NewMediaService(mockRepo, new(MockStorageRepository), 0)
+
+		mockRepo.On("FindByID", ctx, userID, int64(10)).Return(nil, nil).Once()
+
+		_, err := service.GetContent(ctx, userID, 10)
+		assert.Error(t, err)
+	})
+}
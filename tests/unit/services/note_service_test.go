@@ -10,7 +10,9 @@ import (
 	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
 	"github.com/felipesantos/anki-backend/core/domain/entities/note"
 	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
+	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
 	"github.com/felipesantos/anki-backend/core/domain/services"
+	searchdomain "github.com/felipesantos/anki-backend/core/domain/services/search"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
 	noteSvc "github.com/felipesantos/anki-backend/core/services/note"
 	"github.com/felipesantos/anki-backend/pkg/ownership"
@@ -29,7 +31,7 @@ func TestNoteService_Create(t *testing.T) {
 		mockDeckRepo := new(MockDeckRepository)
 		mockTM := new(MockTransactionManager)
 		tr := services.NewTemplateRenderer()
-		service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM).(*noteSvc.NoteService)
+		service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM).(*noteSvc.NoteService)
 		return service, mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, mockTM
 	}
 
@@ -263,7 +265,7 @@ func TestNoteService_FindAll(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -460,7 +462,7 @@ func TestNoteService_Copy(t *testing.T) {
 		mockDeckRepo := new(MockDeckRepository)
 		mockTM := new(MockTransactionManager)
 		tr := services.NewTemplateRenderer()
-		service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM).(*noteSvc.NoteService)
+		service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM).(*noteSvc.NoteService)
 		return service, mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, mockTM
 	}
 
@@ -791,7 +793,7 @@ func TestNoteService_FindDuplicates(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 	fieldName := "Front"
@@ -1028,7 +1030,7 @@ func TestNoteService_FindDuplicatesByGUID(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -1111,6 +1113,150 @@ func TestNoteService_FindDuplicatesByGUID(t *testing.T) {
 	})
 }
 
+func TestNoteService_FindReplace(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	nt, _ := notetype.NewBuilder().
+		WithID(10).
+		WithUserID(userID).
+		WithFieldsJSON(`[{"name":"Front"},{"name":"Back"}]`).
+		WithCardTypesJSON(`[{"name":"Card 1"}]`).
+		WithTemplatesJSON(`[{"qfmt":"{{Front}}","afmt":"{{FrontSide}}<hr id=answer>{{Back}}"}]`).
+		Build()
+
+	setup := func() (*noteSvc.NoteService, *MockNoteRepository, *MockCardRepository, *MockNoteTypeRepository, *MockUndoHistoryService) {
+		mockNoteRepo := new(MockNoteRepository)
+		mockCardRepo := new(MockCardRepository)
+		mockNoteTypeRepo := new(MockNoteTypeRepository)
+		mockTM := new(MockTransactionManager)
+		mockTM.ExpectTransaction()
+		undoService := newRecordingUndoService()
+		service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, new(MockDeckRepository), services.NewTemplateRenderer(), undoService, mockTM).(*noteSvc.NoteService)
+		return service, mockNoteRepo, mockCardRepo, mockNoteTypeRepo, undoService
+	}
+
+	newNote := func(id int64, fields string, tags ...string) *note.Note {
+		n := &note.Note{}
+		n.SetID(id)
+		n.SetNoteTypeID(10)
+		n.SetFieldsJSON(fields)
+		n.SetTags(tags)
+		return n
+	}
+
+	t.Run("Replace in one field across pages", func(t *testing.T) {
+		service, mockNoteRepo, mockCardRepo, mockNoteTypeRepo, undoService := setup()
+
+		first := newNote(100, `{"Front":"Cat","Back":"a cat"}`)
+		second := newNote(101, `{"Front":"dog","Back":"CAT food"}`)
+		untouched := newNote(102, `{"Front":"cat","Back":"bird"}`)
+		existingCard, _ := card.NewBuilder().WithID(200).WithNoteID(100).WithCardTypeID(0).WithDeckID(20).Build()
+
+		mockNoteRepo.On("FindByAdvancedSearch", mock.Anything, userID, mock.Anything, mock.MatchedBy(func(page searchdomain.PageRequest) bool { return page.Cursor == "" })).
+			Return([]*note.Note{first, untouched}, searchdomain.PageInfo{Total: 3, NextCursor: "next"}, nil).Once()
+		mockNoteRepo.On("FindByAdvancedSearch", mock.Anything, userID, mock.Anything, mock.MatchedBy(func(page searchdomain.PageRequest) bool { return page.Cursor == "next" })).
+			Return([]*note.Note{second}, searchdomain.PageInfo{Total: 3}, nil).Once()
+		mockNoteTypeRepo.On("FindByID", mock.Anything, userID, int64(10)).Return(nt, nil).Once()
+		mockNoteRepo.On("Update", mock.Anything, userID, mock.Anything, mock.Anything).Return(nil).Twice()
+		mockCardRepo.On("FindByNoteID", mock.Anything, userID, mock.Anything).Return([]*card.Card{existingCard}, nil).Twice()
+
+		changed, err := service.FindReplace(ctx, userID, note.FindReplace{Query: "cat", Field: "back", Find: "cat", Replace: "dog"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, changed)
+		assert.JSONEq(t, `{"Front":"Cat","Back":"a dog"}`, first.GetFieldsJSON())
+		assert.JSONEq(t, `{"Front":"dog","Back":"dog food"}`, second.GetFieldsJSON())
+		assert.JSONEq(t, `{"Front":"cat","Back":"bird"}`, untouched.GetFieldsJSON())
+		mockNoteRepo.AssertExpectations(t)
+		mockCardRepo.AssertExpectations(t)
+		undoService.AssertCalled(t, "Record", mock.Anything, userID, undohistory.OperationTypeFindReplace, mock.Anything)
+	})
+
+	t.Run("Regex with groups and match case", func(t *testing.T) {
+		service, mockNoteRepo, mockCardRepo, mockNoteTypeRepo, _ := setup()
+
+		n := newNote(100, `{"Front":"Paris, France","Back":"paris, france"}`)
+		existingCard, _ := card.NewBuilder().WithID(200).WithNoteID(100).WithCardTypeID(0).WithDeckID(20).Build()
+
+		mockNoteRepo.On("FindByAdvancedSearch", mock.Anything, userID, mock.Anything, mock.Anything).Return([]*note.Note{n}, searchdomain.PageInfo{Total: 1}, nil).Once()
+		mockNoteTypeRepo.On("FindByID", mock.Anything, userID, int64(10)).Return(nt, nil).Once()
+		mockNoteRepo.On("Update", mock.Anything, userID, int64(100), n).Return(nil).Once()
+		mockCardRepo.On("FindByNoteID", mock.Anything, userID, int64(100)).Return([]*card.Card{existingCard}, nil).Once()
+
+		changed, err := service.FindReplace(ctx, userID, note.FindReplace{Find: `([A-Z]\w+), ([A-Z]\w+)`, Replace: "$2: $1", Regex: true, MatchCase: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, changed)
+		assert.JSONEq(t, `{"Front":"France: Paris","Back":"paris, france"}`, n.GetFieldsJSON())
+	})
+
+	t.Run("Rename and merge tags", func(t *testing.T) {
+		service, mockNoteRepo, mockCardRepo, mockNoteTypeRepo, _ := setup()
+
+		n := newNote(100, `{"Front":"Q"}`, "Verbs", "verb", "grammar")
+
+		mockNoteRepo.On("FindByAdvancedSearch", mock.Anything, userID, mock.Anything, mock.Anything).Return([]*note.Note{n}, searchdomain.PageInfo{Total: 1}, nil).Once()
+		mockNoteTypeRepo.On("FindByID", mock.Anything, userID, int64(10)).Return(nt, nil).Once()
+		mockNoteRepo.On("Update", mock.Anything, userID, int64(100), n).Return(nil).Once()
+
+		changed, err := service.FindReplace(ctx, userID, note.FindReplace{Query: "tag:verb*", InTags: true, Find: "^verbs?$", Replace: "verb", Regex: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, changed)
+		assert.Equal(t, []string{"verb", "grammar"}, n.GetTags())
+		mockCardRepo.AssertNotCalled(t, "FindByNoteID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Nothing to replace", func(t *testing.T) {
+		service, mockNoteRepo, _, mockNoteTypeRepo, _ := setup()
+
+		mockNoteRepo.On("FindByAdvancedSearch", mock.Anything, userID, mock.Anything, mock.Anything).
+			Return([]*note.Note{newNote(100, `{"Front":"Q"}`)}, searchdomain.PageInfo{Total: 1}, nil).Once()
+
+		changed, err := service.FindReplace(ctx, userID, note.FindReplace{Find: "missing", Replace: "x"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, changed)
+		mockNoteRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockNoteTypeRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Emptying the first field", func(t *testing.T) {
+		service, mockNoteRepo, _, mockNoteTypeRepo, _ := setup()
+
+		mockNoteRepo.On("FindByAdvancedSearch", mock.Anything, userID, mock.Anything, mock.Anything).
+			Return([]*note.Note{newNote(100, `{"Front":"Q","Back":"A"}`)}, searchdomain.PageInfo{Total: 1}, nil).Once()
+		mockNoteTypeRepo.On("FindByID", mock.Anything, userID, int64(10)).Return(nt, nil).Once()
+
+		changed, err := service.FindReplace(ctx, userID, note.FindReplace{Field: "Front", Find: "Q", Replace: ""})
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, changed)
+		assert.Contains(t, err.Error(), "first field validation failed")
+		mockNoteRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid regex", func(t *testing.T) {
+		service, mockNoteRepo, _, _, _ := setup()
+
+		_, err := service.FindReplace(ctx, userID, note.FindReplace{Find: "(", Regex: true})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid regex pattern")
+		mockNoteRepo.AssertNotCalled(t, "FindByAdvancedSearch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		service, _, _, _, _ := setup()
+
+		_, err := service.FindReplace(ctx, userID, note.FindReplace{Query: "(tag:a", Find: "a"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse query")
+	})
+}
+
 func TestNoteService_FindByID(t *testing.T) {
 	mockNoteRepo := new(MockNoteRepository)
 	mockCardRepo := new(MockCardRepository)
@@ -1118,7 +1264,7 @@ func TestNoteService_FindByID(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 	noteID := int64(100)
@@ -1153,7 +1299,7 @@ func TestNoteService_Update(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 	noteID := int64(100)
@@ -1460,7 +1606,8 @@ func TestNoteService_AddTag(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM)
+	mockTM.ExpectTransaction()
 	ctx := context.Background()
 	userID := int64(1)
 	noteID := int64(100)
@@ -1488,7 +1635,8 @@ func TestNoteService_RemoveTag(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, newRecordingUndoService(), mockTM)
+	mockTM.ExpectTransaction()
 	ctx := context.Background()
 	userID := int64(1)
 	noteID := int64(100)
@@ -1517,27 +1665,45 @@ func TestNoteService_Delete(t *testing.T) {
 	mockDeckRepo := new(MockDeckRepository)
 	mockTM := new(MockTransactionManager)
 	tr := services.NewTemplateRenderer()
-	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockTM)
+	mockUndoSvc := new(MockUndoHistoryService)
+	service := noteSvc.NewNoteService(mockNoteRepo, mockCardRepo, mockNoteTypeRepo, mockDeckRepo, tr, mockUndoSvc, mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 	noteID := int64(100)
 
 	t.Run("Success", func(t *testing.T) {
+		existing := &note.Note{}
+		existing.SetID(noteID)
 		cards := []*card.Card{
 			{ /* card 1 */ },
 			{ /* card 2 */ },
 		}
 
 		mockTM.ExpectTransaction()
+		mockNoteRepo.On("FindByID", mock.Anything, userID, noteID).Return(existing, nil).Once()
 		mockNoteRepo.On("Delete", mock.Anything, userID, noteID).Return(nil).Once()
 		mockCardRepo.On("FindByNoteID", mock.Anything, userID, noteID).Return(cards, nil).Once()
 		mockCardRepo.On("Delete", mock.Anything, userID, mock.Anything).Return(nil).Twice()
+		mockUndoSvc.On("Record", mock.Anything, userID, undohistory.OperationTypeDeleteNote, mock.MatchedBy(func(cs *undohistory.ChangeSet) bool {
+			return len(cs.Changes) == 3 && cs.Changes[0].Kind == undohistory.ObjectKindNote && cs.Changes[0].After == nil
+		})).Return(nil).Once()
 
 		err := service.Delete(ctx, userID, noteID)
 
 		assert.NoError(t, err)
 		mockNoteRepo.AssertExpectations(t)
 		mockCardRepo.AssertExpectations(t)
+		mockUndoSvc.AssertExpectations(t)
 		mockTM.AssertExpectations(t)
 	})
+
+	t.Run("Note not found", func(t *testing.T) {
+		mockNoteRepo.On("FindByID", mock.Anything, userID, int64(404)).Return(nil, nil).Once()
+
+		err := service.Delete(ctx, userID, 404)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "note not found")
+		mockNoteRepo.AssertExpectations(t)
+	})
 }
@@ -3,33 +3,56 @@ package services
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	"github.com/felipesantos/anki-backend/core/domain/entities/review"
+	undohistory "github.com/felipesantos/anki-backend/core/domain/entities/undo_history"
+	"github.com/felipesantos/anki-backend/core/domain/events"
 	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
 	reviewSvc "github.com/felipesantos/anki-backend/core/services/review"
+	"github.com/felipesantos/anki-backend/pkg/ownership"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReviewService_Create(t *testing.T) {
 	mockReviewRepo := new(MockReviewRepository)
 	mockCardRepo := new(MockCardRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockNoteRepo := new(MockNoteRepository)
+	mockPrefsRepo := new(MockUserPreferencesRepository)
+	mockUndoRepo := new(MockUndoHistoryRepository)
+	eventBus := newMockEventBus()
 	mockTM := new(MockTransactionManager)
-	service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, mockTM)
+	service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, mockDeckRepo, new(MockFilteredDeckRepository), mockNoteRepo, mockPrefsRepo, mockUndoRepo, eventBus, mockTM)
 	ctx := context.Background()
 	userID := int64(1)
 	cardID := int64(100)
+	deckID := int64(10)
+	d, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Default").WithOptionsJSON("{}").Build()
 
 	t.Run("Success", func(t *testing.T) {
 		c, _ := card.NewBuilder().
 			WithID(cardID).
+			WithNoteID(1).
+			WithDeckID(deckID).
 			WithState(valueobjects.CardStateNew).
 			Build()
 
 		mockTM.ExpectTransaction()
 		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(d, nil).Once()
 		mockCardRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
 		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		var undoEntry *undohistory.UndoHistory
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).
+			Run(func(args mock.Arguments) { undoEntry = args.Get(2).(*undohistory.UndoHistory) }).
+			Return(nil).Once()
 
 		result, err := service.Create(ctx, userID, cardID, 3, 5000) // Rating Good (3), 5 seconds
 
@@ -38,14 +61,167 @@ func TestReviewService_Create(t *testing.T) {
 		assert.Equal(t, cardID, result.GetCardID())
 		assert.Equal(t, 3, result.GetRating())
 		
-		// Verify card state changed from New to Learn
+		// Verify card state changed from New to Learn, on the second step (10m)
 		assert.Equal(t, valueobjects.CardStateLearn, c.GetState())
+		assert.Equal(t, -600, result.GetInterval())
+		assert.Equal(t, valueobjects.ReviewTypeLearn, result.GetType())
+		assert.Equal(t, 2500, c.GetEase())
+		assert.Equal(t, 1, c.GetReps())
+
+		// The state before the answer is recorded for undo
+		require.NotNil(t, undoEntry)
+		assert.Equal(t, undohistory.OperationTypeReviewCard, undoEntry.GetOperationType())
+		undoData, err := undohistory.ParseReviewCardData(undoEntry.GetOperationData())
+		require.NoError(t, err)
+		assert.Equal(t, cardID, undoData.CardID)
+		assert.Equal(t, valueobjects.CardStateNew, undoData.Before.State)
+		assert.Equal(t, 0, undoData.Before.Reps)
 		
 		mockReviewRepo.AssertExpectations(t)
+		mockUndoRepo.AssertExpectations(t)
 		mockCardRepo.AssertExpectations(t)
+		mockDeckRepo.AssertExpectations(t)
 		mockTM.AssertExpectations(t)
 	})
 
+	t.Run("Learning card resumes from its current step", func(t *testing.T) {
+		c, _ := card.NewBuilder().
+			WithID(cardID).
+			WithNoteID(1).
+			WithDeckID(deckID).
+			WithState(valueobjects.CardStateLearn).
+			Build()
+		lastReview, _ := review.NewBuilder().
+			WithCardID(cardID).
+			WithRating(3).
+			WithInterval(-600).
+			WithEase(2500).
+			WithTimeMs(1000).
+			WithType(valueobjects.ReviewTypeLearn).
+			Build()
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(d, nil).Once()
+		mockReviewRepo.On("FindByCardID", mock.Anything, userID, cardID).Return([]*review.Review{lastReview}, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).Return(nil).Once()
+
+		result, err := service.Create(ctx, userID, cardID, 3, 5000)
+
+		assert.NoError(t, err)
+		// Good on the last learning step graduates the card with the 1 day graduating interval
+		assert.Equal(t, valueobjects.CardStateReview, c.GetState())
+		assert.Equal(t, 1, c.GetInterval())
+		assert.Equal(t, 1, result.GetInterval())
+		mockTM.AssertExpectations(t)
+	})
+
+	t.Run("FSRS deck updates memory state", func(t *testing.T) {
+		fsrsDeck, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("FSRS").WithOptionsJSON(`{"scheduler_type": "fsrs"}`).Build()
+		c, _ := card.NewBuilder().
+			WithID(cardID).
+			WithNoteID(1).
+			WithDeckID(deckID).
+			WithState(valueobjects.CardStateNew).
+			Build()
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(fsrsDeck, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).Return(nil).Once()
+
+		_, err := service.Create(ctx, userID, cardID, 3, 5000)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, c.GetStability())
+		assert.NotNil(t, c.GetDifficulty())
+		assert.Greater(t, c.GetDue(), int64(0))
+	})
+
+	t.Run("Buries siblings enabled in deck options", func(t *testing.T) {
+		buryDeck, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Bury").
+			WithOptionsJSON(`{"bury_new": true, "bury_reviews": true}`).Build()
+		newCard := func(id int64, state valueobjects.CardState, due int64) *card.Card {
+			c, _ := card.NewBuilder().WithID(id).WithNoteID(1).WithDeckID(deckID).WithState(state).WithDue(due).Build()
+			return c
+		}
+		c := newCard(cardID, valueobjects.CardStateNew, 0)
+		newSibling := newCard(101, valueobjects.CardStateNew, 0)
+		dueSibling := newCard(102, valueobjects.CardStateReview, time.Now().UnixMilli())
+		laterSibling := newCard(103, valueobjects.CardStateReview, time.Now().AddDate(0, 0, 3).UnixMilli())
+		learnSibling := newCard(104, valueobjects.CardStateRelearn, time.Now().UnixMilli())
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(buryDeck, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).Return(nil).Once()
+		mockCardRepo.On("FindByNoteID", mock.Anything, userID, int64(1)).Return([]*card.Card{c, newSibling, dueSibling, laterSibling, learnSibling}, nil).Once()
+		mockPrefsRepo.On("FindByUserID", mock.Anything, userID).Return(nil, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, int64(101), newSibling).Return(nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, int64(102), dueSibling).Return(nil).Once()
+
+		_, err := service.Create(ctx, userID, cardID, 3, 5000)
+
+		assert.NoError(t, err)
+		assert.False(t, c.GetBuried())
+		assert.True(t, newSibling.GetBuried())
+		assert.True(t, dueSibling.GetBuried())
+		assert.False(t, laterSibling.GetBuried()) // Not due today
+		assert.False(t, learnSibling.GetBuried()) // Interday learning burying disabled
+		mockCardRepo.AssertExpectations(t)
+	})
+
+	t.Run("Lapse reaching the leech threshold", func(t *testing.T) {
+		leechDeck, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Leech").
+			WithOptionsJSON(`{"leech_threshold": 4, "leech_action": "suspend"}`).Build()
+		c, _ := card.NewBuilder().
+			WithID(cardID).
+			WithNoteID(1).
+			WithDeckID(deckID).
+			WithState(valueobjects.CardStateReview).
+			WithInterval(10).
+			WithEase(2500).
+			WithLapses(3).
+			Build()
+		guid, _ := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440000")
+		n, _ := note.NewBuilder().WithID(1).WithUserID(userID).WithGUID(guid).WithNoteTypeID(1).WithFieldsJSON(`{}`).Build()
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(leechDeck, nil).Once()
+		mockNoteRepo.On("FindByID", mock.Anything, userID, int64(1)).Return(n, nil).Once()
+		mockNoteRepo.On("Update", mock.Anything, userID, int64(1), n).Return(nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, c).Return(nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).Return(nil).Once()
+
+		_, err := service.Create(ctx, userID, cardID, 1, 5000) // Again
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, c.GetLapses())
+		assert.True(t, c.GetSuspended())
+		assert.True(t, n.HasTag("leech"))
+		require.Len(t, eventBus.publishedEvents, 1)
+		leeched, ok := eventBus.publishedEvents[0].(*events.CardLeeched)
+		require.True(t, ok)
+		assert.Equal(t, cardID, leeched.CardID)
+		assert.True(t, leeched.Suspended)
+		mockNoteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Rating", func(t *testing.T) {
+		result, err := service.Create(ctx, userID, cardID, 5, 5000)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+
 	t.Run("Card Not Found", func(t *testing.T) {
 		mockTM.ExpectTransaction()
 		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(nil, nil).Once()
@@ -59,3 +235,391 @@ func TestReviewService_Create(t *testing.T) {
 	})
 }
 
+
+func TestReviewService_CreateAt(t *testing.T) {
+	mockReviewRepo := new(MockReviewRepository)
+	mockCardRepo := new(MockCardRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockUndoRepo := new(MockUndoHistoryRepository)
+	mockTM := new(MockTransactionManager)
+	service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, mockDeckRepo, new(MockFilteredDeckRepository), new(MockNoteRepository), new(MockUserPreferencesRepository), mockUndoRepo, newMockEventBus(), mockTM)
+	ctx := context.Background()
+	userID := int64(1)
+	cardID := int64(100)
+	deckID := int64(10)
+	d, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Default").WithOptionsJSON("{}").Build()
+
+	t.Run("Schedules from the review time", func(t *testing.T) {
+		c, _ := card.NewBuilder().WithID(cardID).WithNoteID(1).WithDeckID(deckID).WithState(valueobjects.CardStateNew).Build()
+		reviewedAt := time.Now().Add(-2 * time.Hour).Truncate(time.Millisecond)
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(d, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).Return(nil).Once()
+
+		result, err := service.CreateAt(ctx, userID, cardID, 3, 5000, reviewedAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, reviewedAt, result.GetCreatedAt())
+		require.NotNil(t, c.GetLastReviewAt())
+		assert.Equal(t, reviewedAt, *c.GetLastReviewAt())
+		// Second learning step (10m) after the review, not after now
+		assert.Equal(t, reviewedAt.Add(10*time.Minute).UnixMilli(), c.GetDue())
+	})
+
+	t.Run("Rejects reviews in the future", func(t *testing.T) {
+		_, err := service.CreateAt(ctx, userID, cardID, 3, 5000, time.Now().Add(time.Hour))
+
+		assert.ErrorIs(t, err, reviewSvc.ErrReviewInFuture)
+	})
+}
+
+func TestReviewService_Create_FilteredDeck(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	cardID := int64(100)
+	deckID := int64(10)
+	d, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Default").WithOptionsJSON("{}").Build()
+	newReviewCard := func() *card.Card {
+		home := deckID
+		c, _ := card.NewBuilder().
+			WithID(cardID).
+			WithNoteID(1).
+			WithDeckID(deckID).
+			WithHomeDeckID(&home).
+			WithState(valueobjects.CardStateReview).
+			WithInterval(10).
+			WithDue(time.Now().Add(-24 * time.Hour).UnixMilli()).
+			Build()
+		return c
+	}
+
+	t.Run("Previews without rescheduling", func(t *testing.T) {
+		mockReviewRepo := new(MockReviewRepository)
+		mockCardRepo := new(MockCardRepository)
+		mockFilteredRepo := new(MockFilteredDeckRepository)
+		mockUndoRepo := new(MockUndoHistoryRepository)
+		mockTM := new(MockTransactionManager)
+		service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, new(MockDeckRepository), mockFilteredRepo, new(MockNoteRepository), new(MockUserPreferencesRepository), mockUndoRepo, newMockEventBus(), mockTM)
+		c := newReviewCard()
+		fd, _ := filtereddeck.NewBuilder().WithID(7).WithUserID(userID).WithName("Preview").WithReschedule(false).Build()
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockFilteredRepo.On("FindByCardID", mock.Anything, userID, cardID).Return(fd, nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockFilteredRepo.On("ReturnCard", mock.Anything, userID, cardID).Return(3, nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).Return(nil).Once()
+
+		result, err := service.Create(ctx, userID, cardID, 3, 4000)
+
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.ReviewTypeCram, result.GetType())
+		assert.Equal(t, 10, c.GetInterval())
+		assert.Equal(t, 0, c.GetReps())
+		mockCardRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockFilteredRepo.AssertExpectations(t)
+	})
+
+	t.Run("Again keeps a previewed card in the filtered deck", func(t *testing.T) {
+		mockReviewRepo := new(MockReviewRepository)
+		mockCardRepo := new(MockCardRepository)
+		mockFilteredRepo := new(MockFilteredDeckRepository)
+		mockUndoRepo := new(MockUndoHistoryRepository)
+		mockTM := new(MockTransactionManager)
+		service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, new(MockDeckRepository), mockFilteredRepo, new(MockNoteRepository), new(MockUserPreferencesRepository), mockUndoRepo, newMockEventBus(), mockTM)
+		fd, _ := filtereddeck.NewBuilder().WithID(7).WithUserID(userID).WithName("Preview").WithReschedule(false).Build()
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(newReviewCard(), nil).Once()
+		mockFilteredRepo.On("FindByCardID", mock.Anything, userID, cardID).Return(fd, nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*undohistory.UndoHistory")).Return(nil).Once()
+
+		_, err := service.Create(ctx, userID, cardID, 1, 4000)
+
+		require.NoError(t, err)
+		mockFilteredRepo.AssertNotCalled(t, "ReturnCard", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rescheduled review returns home", func(t *testing.T) {
+		mockReviewRepo := new(MockReviewRepository)
+		mockCardRepo := new(MockCardRepository)
+		mockDeckRepo := new(MockDeckRepository)
+		mockFilteredRepo := new(MockFilteredDeckRepository)
+		mockUndoRepo := new(MockUndoHistoryRepository)
+		mockTM := new(MockTransactionManager)
+		service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, mockDeckRepo, mockFilteredRepo, new(MockNoteRepository), new(MockUserPreferencesRepository), mockUndoRepo, newMockEventBus(), mockTM)
+		c := newReviewCard()
+		fd, _ := filtereddeck.NewBuilder().WithID(7).WithUserID(userID).WithName("Cram").WithReschedule(true).Build()
+
+		mockTM.ExpectTransaction()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockFilteredRepo.On("FindByCardID", mock.Anything, userID, cardID).Return(fd, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(d, nil).Once()
+		mockFilteredRepo.On("ReturnCard", mock.Anything, userID, cardID).Return(3, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, mock.Anything).Return(nil).Once()
+		mockReviewRepo.On("Save", mock.Anything, userID, mock.AnythingOfType("*review.Review")).Return(nil).Once()
+		mockUndoRepo.On("Save", mock.Anything, userID, mock.MatchedBy(func(entry *undohistory.UndoHistory) bool {
+			data, err := undohistory.ParseReviewCardData(entry.GetOperationData())
+			return err == nil && data.ReturnedFrom != nil &&
+				data.ReturnedFrom.FilteredDeckID == 7 && data.ReturnedFrom.Position == 3
+		})).Return(nil).Once()
+
+		result, err := service.Create(ctx, userID, cardID, 3, 4000)
+
+		require.NoError(t, err)
+		assert.Equal(t, valueobjects.ReviewTypeReview, result.GetType())
+		assert.Greater(t, c.GetInterval(), 10)
+		assert.Nil(t, c.GetHomeDeckID())
+		mockFilteredRepo.AssertExpectations(t)
+		mockCardRepo.AssertExpectations(t)
+		mockUndoRepo.AssertExpectations(t)
+	})
+}
+
+func TestReviewService_PreviewIntervals(t *testing.T) {
+	mockReviewRepo := new(MockReviewRepository)
+	mockCardRepo := new(MockCardRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, mockDeckRepo, new(MockFilteredDeckRepository), new(MockNoteRepository), new(MockUserPreferencesRepository), new(MockUndoHistoryRepository), newMockEventBus(), new(MockTransactionManager))
+	ctx := context.Background()
+	userID := int64(1)
+	cardID := int64(100)
+	deckID := int64(10)
+	d, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Default").WithOptionsJSON("{}").Build()
+
+	t.Run("Previews every rating without saving", func(t *testing.T) {
+		c, _ := card.NewBuilder().
+			WithID(cardID).
+			WithNoteID(1).
+			WithDeckID(deckID).
+			WithState(valueobjects.CardStateNew).
+			Build()
+
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockDeckRepo.On("FindByID", mock.Anything, userID, deckID).Return(d, nil).Once()
+
+		previews, err := service.PreviewIntervals(ctx, userID, cardID)
+
+		assert.NoError(t, err)
+		assert.Len(t, previews, 4)
+		// Default steps are 1m 10m, graduating interval 1d and easy interval 4d
+		assert.Equal(t, 60, previews[0].DelaySeconds)
+		assert.Equal(t, valueobjects.CardStateLearn, previews[2].State)
+		assert.Equal(t, 600, previews[2].DelaySeconds)
+		assert.Equal(t, valueobjects.CardStateReview, previews[3].State)
+
+		// The card itself is left untouched
+		assert.Equal(t, valueobjects.CardStateNew, c.GetState())
+		assert.Equal(t, 0, c.GetReps())
+		mockCardRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockReviewRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Card Not Found", func(t *testing.T) {
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(nil, nil).Once()
+
+		_, err := service.PreviewIntervals(ctx, userID, cardID)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "card not found")
+	})
+}
+
+func TestReviewService_LogManual(t *testing.T) {
+	mockReviewRepo := new(MockReviewRepository)
+	service := reviewSvc.NewReviewService(mockReviewRepo, new(MockCardRepository), new(MockDeckRepository), new(MockFilteredDeckRepository), new(MockNoteRepository), new(MockUserPreferencesRepository), new(MockUndoHistoryRepository), newMockEventBus(), new(MockTransactionManager))
+	ctx := context.Background()
+	userID := int64(1)
+	c, _ := card.NewBuilder().WithID(100).WithNoteID(1).WithDeckID(10).WithState(valueobjects.CardStateReview).WithInterval(12).WithEase(2500).Build()
+
+	mockReviewRepo.On("Save", ctx, userID, mock.MatchedBy(func(r *review.Review) bool {
+		return r.GetCardID() == 100 && r.GetRating() == 0 && r.GetType() == valueobjects.ReviewTypeManual &&
+			r.GetInterval() == 12 && r.GetEase() == 2500
+	})).Return(nil).Once()
+
+	err := service.LogManual(ctx, userID, c)
+
+	assert.NoError(t, err)
+	mockReviewRepo.AssertExpectations(t)
+}
+
+func TestReviewService_Undo(t *testing.T) {
+	mockReviewRepo := new(MockReviewRepository)
+	mockCardRepo := new(MockCardRepository)
+	mockNoteRepo := new(MockNoteRepository)
+	mockUndoRepo := new(MockUndoHistoryRepository)
+	mockFilteredRepo := new(MockFilteredDeckRepository)
+	mockTM := new(MockTransactionManager)
+	service := reviewSvc.NewReviewService(mockReviewRepo, mockCardRepo, new(MockDeckRepository), mockFilteredRepo, mockNoteRepo, new(MockUserPreferencesRepository), mockUndoRepo, newMockEventBus(), mockTM)
+	ctx := context.Background()
+	userID := int64(1)
+	cardID := int64(100)
+
+	t.Run("Restores the card and reverts side effects", func(t *testing.T) {
+		data := &undohistory.ReviewCardData{
+			ReviewID: 500,
+			CardID:   cardID,
+			Before: card.SchedulingSnapshot{
+				Due:      1000,
+				Interval: 10,
+				Ease:     2500,
+				Lapses:   3,
+				Reps:     12,
+				State:    valueobjects.CardStateReview,
+			},
+			BuriedSiblingIDs: []int64{101},
+			LeechTagged:      true,
+		}
+		operationData, err := data.Encode()
+		require.NoError(t, err)
+		entry, _ := undohistory.NewBuilder().WithID(7).WithUserID(userID).
+			WithOperationType(undohistory.OperationTypeReviewCard).WithOperationData(operationData).Build()
+
+		c, _ := card.NewBuilder().WithID(cardID).WithNoteID(1).WithDeckID(10).
+			WithState(valueobjects.CardStateRelearn).WithInterval(1).WithEase(2300).WithLapses(4).WithReps(13).WithSuspended(true).Build()
+		sibling, _ := card.NewBuilder().WithID(101).WithNoteID(1).WithDeckID(10).WithState(valueobjects.CardStateNew).WithBuried(true).Build()
+		guid, _ := valueobjects.NewGUID("550e8400-e29b-41d4-a716-446655440000")
+		n, _ := note.NewBuilder().WithID(1).WithUserID(userID).WithGUID(guid).WithNoteTypeID(1).WithFieldsJSON(`{}`).Build()
+		n.AddTag("leech")
+
+		mockTM.ExpectTransaction()
+		mockUndoRepo.On("FindLatestByType", mock.Anything, userID, undohistory.OperationTypeReviewCard).Return(entry, nil).Once()
+		mockReviewRepo.On("Delete", mock.Anything, userID, int64(500)).Return(nil).Once()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, c).Return(nil).Once()
+		mockCardRepo.On("FindByID", mock.Anything, userID, int64(101)).Return(sibling, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, int64(101), sibling).Return(nil).Once()
+		mockNoteRepo.On("FindByID", mock.Anything, userID, int64(1)).Return(n, nil).Once()
+		mockNoteRepo.On("Update", mock.Anything, userID, int64(1), n).Return(nil).Once()
+		mockUndoRepo.On("Delete", mock.Anything, userID, int64(7)).Return(nil).Once()
+
+		result, err := service.Undo(ctx, userID)
+
+		require.NoError(t, err)
+		assert.Same(t, c, result)
+		assert.Equal(t, valueobjects.CardStateReview, c.GetState())
+		assert.Equal(t, int64(1000), c.GetDue())
+		assert.Equal(t, 10, c.GetInterval())
+		assert.Equal(t, 2500, c.GetEase())
+		assert.Equal(t, 3, c.GetLapses())
+		assert.Equal(t, 12, c.GetReps())
+		assert.False(t, c.GetSuspended())
+		assert.False(t, sibling.GetBuried())
+		assert.False(t, n.HasTag("leech"))
+		mockReviewRepo.AssertExpectations(t)
+		mockCardRepo.AssertExpectations(t)
+		mockNoteRepo.AssertExpectations(t)
+		mockUndoRepo.AssertExpectations(t)
+	})
+
+	t.Run("Puts a card returned home back into its filtered deck", func(t *testing.T) {
+		data := &undohistory.ReviewCardData{
+			ReviewID:     503,
+			CardID:       cardID,
+			Before:       card.SchedulingSnapshot{Due: 1000, Interval: 10, State: valueobjects.CardStateReview},
+			ReturnedFrom: &undohistory.FilteredDeckPlace{FilteredDeckID: 7, Position: 3},
+		}
+		operationData, err := data.Encode()
+		require.NoError(t, err)
+		entry, _ := undohistory.NewBuilder().WithID(10).WithUserID(userID).
+			WithOperationType(undohistory.OperationTypeReviewCard).WithOperationData(operationData).Build()
+		c, _ := card.NewBuilder().WithID(cardID).WithNoteID(1).WithDeckID(10).WithState(valueobjects.CardStateReview).WithInterval(25).Build()
+
+		mockTM.ExpectTransaction()
+		mockUndoRepo.On("FindLatestByType", mock.Anything, userID, undohistory.OperationTypeReviewCard).Return(entry, nil).Once()
+		mockReviewRepo.On("Delete", mock.Anything, userID, int64(503)).Return(nil).Once()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockFilteredRepo.On("RestoreCard", mock.Anything, userID, int64(7), cardID, 3).Return(nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, mock.MatchedBy(func(updated *card.Card) bool {
+			return updated.GetHomeDeckID() != nil && *updated.GetHomeDeckID() == 10
+		})).Return(nil).Once()
+		mockUndoRepo.On("Delete", mock.Anything, userID, int64(10)).Return(nil).Once()
+
+		result, err := service.Undo(ctx, userID)
+
+		require.NoError(t, err)
+		assert.Same(t, c, result)
+		assert.Equal(t, 10, c.GetInterval())
+		mockFilteredRepo.AssertExpectations(t)
+		mockCardRepo.AssertExpectations(t)
+	})
+
+	t.Run("Leaves a returned card home when its filtered deck is gone", func(t *testing.T) {
+		data := &undohistory.ReviewCardData{
+			ReviewID:     504,
+			CardID:       cardID,
+			ReturnedFrom: &undohistory.FilteredDeckPlace{FilteredDeckID: 8, Position: 1},
+		}
+		operationData, err := data.Encode()
+		require.NoError(t, err)
+		entry, _ := undohistory.NewBuilder().WithID(11).WithUserID(userID).
+			WithOperationType(undohistory.OperationTypeReviewCard).WithOperationData(operationData).Build()
+		c, _ := card.NewBuilder().WithID(cardID).WithNoteID(1).WithDeckID(10).WithState(valueobjects.CardStateReview).Build()
+
+		mockTM.ExpectTransaction()
+		mockUndoRepo.On("FindLatestByType", mock.Anything, userID, undohistory.OperationTypeReviewCard).Return(entry, nil).Once()
+		mockReviewRepo.On("Delete", mock.Anything, userID, int64(504)).Return(nil).Once()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockFilteredRepo.On("RestoreCard", mock.Anything, userID, int64(8), cardID, 1).Return(ownership.ErrResourceNotFound).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, c).Return(nil).Once()
+		mockUndoRepo.On("Delete", mock.Anything, userID, int64(11)).Return(nil).Once()
+
+		_, err = service.Undo(ctx, userID)
+
+		require.NoError(t, err)
+		assert.Nil(t, c.GetHomeDeckID())
+		mockFilteredRepo.AssertExpectations(t)
+	})
+
+	t.Run("Skips answers whose review or card was deleted", func(t *testing.T) {
+		entryFor := func(id, reviewID, cardID int64) *undohistory.UndoHistory {
+			operationData, err := (&undohistory.ReviewCardData{ReviewID: reviewID, CardID: cardID}).Encode()
+			require.NoError(t, err)
+			entry, _ := undohistory.NewBuilder().WithID(id).WithUserID(userID).
+				WithOperationType(undohistory.OperationTypeReviewCard).WithOperationData(operationData).Build()
+			return entry
+		}
+		c, _ := card.NewBuilder().WithID(cardID).WithNoteID(1).WithDeckID(10).WithState(valueobjects.CardStateReview).Build()
+
+		mockTM.ExpectTransaction()
+		// Reviews deleted by a card reset
+		mockUndoRepo.On("FindLatestByType", mock.Anything, userID, undohistory.OperationTypeReviewCard).Return(entryFor(9, 502, cardID), nil).Once()
+		mockReviewRepo.On("Delete", mock.Anything, userID, int64(502)).Return(ownership.ErrResourceNotFound).Once()
+		mockUndoRepo.On("Delete", mock.Anything, userID, int64(9)).Return(nil).Once()
+		// Card deleted with its reviews kept by the mock
+		mockUndoRepo.On("FindLatestByType", mock.Anything, userID, undohistory.OperationTypeReviewCard).Return(entryFor(8, 501, 200), nil).Once()
+		mockReviewRepo.On("Delete", mock.Anything, userID, int64(501)).Return(nil).Once()
+		mockCardRepo.On("FindByID", mock.Anything, userID, int64(200)).Return(nil, ownership.ErrResourceNotFound).Once()
+		mockUndoRepo.On("Delete", mock.Anything, userID, int64(8)).Return(nil).Once()
+		// Previous answer is undone
+		mockUndoRepo.On("FindLatestByType", mock.Anything, userID, undohistory.OperationTypeReviewCard).Return(entryFor(7, 500, cardID), nil).Once()
+		mockReviewRepo.On("Delete", mock.Anything, userID, int64(500)).Return(nil).Once()
+		mockCardRepo.On("FindByID", mock.Anything, userID, cardID).Return(c, nil).Once()
+		mockCardRepo.On("Update", mock.Anything, userID, cardID, c).Return(nil).Once()
+		mockUndoRepo.On("Delete", mock.Anything, userID, int64(7)).Return(nil).Once()
+
+		result, err := service.Undo(ctx, userID)
+
+		require.NoError(t, err)
+		assert.Same(t, c, result)
+		mockReviewRepo.AssertExpectations(t)
+		mockUndoRepo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing to undo", func(t *testing.T) {
+		mockTM.ExpectTransaction()
+		mockUndoRepo.On("FindLatestByType", mock.Anything, userID, undohistory.OperationTypeReviewCard).Return(nil, nil).Once()
+
+		result, err := service.Undo(ctx, userID)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "not found")
+		mockTM.AssertExpectations(t)
+	})
+}
@@ -79,6 +79,49 @@ func TestParser_Parse(t *testing.T) {
 		assert.Equal(t, "10", query.PropertyFilters[0].Value)
 	})
 
+	t.Run("Date filters", func(t *testing.T) {
+		query, err := parser.Parse("rated:7:1 rated:2 added:3 edited:1 introduced:30 resched:2")
+		assert.NoError(t, err)
+		assert.Equal(t, []searchdomain.DateFilter{
+			{Kind: "rated", Days: 7, Rating: 1},
+			{Kind: "rated", Days: 2},
+			{Kind: "added", Days: 3},
+			{Kind: "edited", Days: 1},
+			{Kind: "introduced", Days: 30},
+			{Kind: "resched", Days: 2},
+		}, query.DateFilters)
+		assert.Empty(t, query.FieldSearches)
+	})
+
+	t.Run("Identity and structure filters", func(t *testing.T) {
+		query, err := parser.Parse(`nid:123,456 cid:7 mid:42 note:"Basic (and reversed)" card:2 card:Front dupe:42,hello, world`)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{123, 456}, query.NoteIDs)
+		assert.Equal(t, []int64{7}, query.CardIDs)
+		assert.Equal(t, []int64{42}, query.NoteTypeIDs)
+		assert.Equal(t, []string{"Basic (and reversed)"}, query.NoteTypes)
+		assert.Equal(t, []string{"2", "Front"}, query.CardTemplates)
+		assert.Equal(t, []searchdomain.DuplicateFilter{{NoteTypeID: 42, Text: "hello,"}}, query.Duplicates)
+
+		and := query.Root.(*searchdomain.AndNode)
+		assert.Equal(t, searchdomain.Term{Kind: searchdomain.TermKindNoteID, IDs: []int64{123, 456}}, and.Children[0].(*searchdomain.TermNode).Term)
+		assert.Equal(t, searchdomain.Term{Kind: searchdomain.TermKindNoteType, Value: "Basic (and reversed)"}, and.Children[3].(*searchdomain.TermNode).Term)
+	})
+
+	t.Run("Invalid identity and structure filters", func(t *testing.T) {
+		for _, q := range []string{"nid:", "nid:1,x", "cid:-3", "mid:1,2", "note:", "card:", "card:0", "dupe:42", "dupe:x,text", "dupe:42,"} {
+			_, err := parser.Parse(q)
+			assert.Error(t, err, q)
+		}
+	})
+
+	t.Run("Invalid date filters", func(t *testing.T) {
+		for _, q := range []string{"rated:0", "rated:7:5", "rated:x", "added:-1", "edited:1:2", "introduced:"} {
+			_, err := parser.Parse(q)
+			assert.Error(t, err, q)
+		}
+	})
+
 	t.Run("Exact phrase", func(t *testing.T) {
 		query, err := parser.Parse(`"exact phrase"`)
 		assert.NoError(t, err)
@@ -244,3 +287,133 @@ func TestParser_Parse(t *testing.T) {
 	})
 }
 
+
+func TestParser_Parse_Expression(t *testing.T) {
+	parser := searchdomain.NewParser()
+
+	tag := func(name string) searchdomain.Node {
+		return &searchdomain.TermNode{Term: searchdomain.Term{Kind: searchdomain.TermKindTag, Value: name}}
+	}
+	deck := func(name string) searchdomain.Node {
+		return &searchdomain.TermNode{Term: searchdomain.Term{Kind: searchdomain.TermKindDeck, Value: name}}
+	}
+
+	t.Run("Empty query has no tree", func(t *testing.T) {
+		query, err := parser.Parse("")
+		assert.NoError(t, err)
+		assert.Nil(t, query.Root)
+	})
+
+	t.Run("Implicit AND", func(t *testing.T) {
+		query, err := parser.Parse("tag:a deck:x")
+		assert.NoError(t, err)
+		assert.Equal(t, &searchdomain.AndNode{Children: []searchdomain.Node{tag("a"), deck("x")}}, query.Root)
+	})
+
+	t.Run("Explicit AND is ignored", func(t *testing.T) {
+		query, err := parser.Parse("tag:a and deck:x")
+		assert.NoError(t, err)
+		assert.Equal(t, &searchdomain.AndNode{Children: []searchdomain.Node{tag("a"), deck("x")}}, query.Root)
+	})
+
+	t.Run("AND binds tighter than OR", func(t *testing.T) {
+		query, err := parser.Parse("tag:a tag:b or tag:c")
+		assert.NoError(t, err)
+		assert.True(t, query.HasOR)
+		assert.Equal(t, &searchdomain.OrNode{Children: []searchdomain.Node{
+			&searchdomain.AndNode{Children: []searchdomain.Node{tag("a"), tag("b")}},
+			tag("c"),
+		}}, query.Root)
+	})
+
+	t.Run("Grouped OR with negated term", func(t *testing.T) {
+		query, err := parser.Parse("(tag:a OR tag:b) -deck:x")
+		assert.NoError(t, err)
+		assert.True(t, query.HasGrouping)
+		assert.Equal(t, &searchdomain.AndNode{Children: []searchdomain.Node{
+			&searchdomain.OrNode{Children: []searchdomain.Node{tag("a"), tag("b")}},
+			&searchdomain.NotNode{Child: deck("x")},
+		}}, query.Root)
+	})
+
+	t.Run("Negated group", func(t *testing.T) {
+		query, err := parser.Parse("tag:a -(deck:x or deck:y)")
+		assert.NoError(t, err)
+		assert.Equal(t, &searchdomain.AndNode{Children: []searchdomain.Node{
+			tag("a"),
+			&searchdomain.NotNode{Child: &searchdomain.OrNode{Children: []searchdomain.Node{deck("x"), deck("y")}}},
+		}}, query.Root)
+	})
+
+	t.Run("Nested groups", func(t *testing.T) {
+		query, err := parser.Parse("((tag:a or tag:b) deck:x) or is:new")
+		assert.NoError(t, err)
+		assert.Equal(t, &searchdomain.OrNode{Children: []searchdomain.Node{
+			&searchdomain.AndNode{Children: []searchdomain.Node{
+				&searchdomain.OrNode{Children: []searchdomain.Node{tag("a"), tag("b")}},
+				deck("x"),
+			}},
+			&searchdomain.TermNode{Term: searchdomain.Term{Kind: searchdomain.TermKindState, Value: "new"}},
+		}}, query.Root)
+	})
+
+	t.Run("Quoted OR and parentheses are text", func(t *testing.T) {
+		query, err := parser.Parse(`"a or (b)"`)
+		assert.NoError(t, err)
+		assert.False(t, query.HasOR)
+		node, ok := query.Root.(*searchdomain.TermNode)
+		assert.True(t, ok)
+		assert.Equal(t, "a or (b)", node.Term.Text.Text)
+		assert.True(t, node.Term.Text.IsExact)
+	})
+
+	t.Run("Parentheses inside a regex term", func(t *testing.T) {
+		query, err := parser.Parse("(re:(cat|dog) tag:a)")
+		assert.NoError(t, err)
+		and, ok := query.Root.(*searchdomain.AndNode)
+		assert.True(t, ok)
+		assert.Len(t, and.Children, 2)
+		assert.Equal(t, "(cat|dog)", and.Children[0].(*searchdomain.TermNode).Term.Text.Text)
+	})
+
+	t.Run("Negated text term is not flagged inside the tree", func(t *testing.T) {
+		query, err := parser.Parse("-hello")
+		assert.NoError(t, err)
+		not, ok := query.Root.(*searchdomain.NotNode)
+		assert.True(t, ok)
+		assert.False(t, not.Child.(*searchdomain.TermNode).Term.Text.IsNegated)
+	})
+
+	t.Run("Card terms are detected", func(t *testing.T) {
+		query, err := parser.Parse("tag:a (is:marked or -flag:1)")
+		assert.NoError(t, err)
+		assert.True(t, searchdomain.HasCardTerms(query.Root))
+
+		query, err = parser.Parse("tag:a or is:marked")
+		assert.NoError(t, err)
+		assert.False(t, searchdomain.HasCardTerms(query.Root))
+
+		query, err = parser.Parse("edited:1")
+		assert.NoError(t, err)
+		assert.False(t, searchdomain.HasCardTerms(query.Root))
+
+		query, err = parser.Parse("edited:1 or rated:1")
+		assert.NoError(t, err)
+		assert.True(t, searchdomain.HasCardTerms(query.Root))
+
+		query, err = parser.Parse("nid:1 mid:2 note:Basic dupe:2,a")
+		assert.NoError(t, err)
+		assert.False(t, searchdomain.HasCardTerms(query.Root))
+
+		query, err = parser.Parse("cid:1 or card:2")
+		assert.NoError(t, err)
+		assert.True(t, searchdomain.HasCardTerms(query.Root))
+	})
+
+	t.Run("Invalid expressions", func(t *testing.T) {
+		for _, q := range []string{"(tag:a", "tag:a)", "tag:a or", "or tag:a", "()", "tag:a or or tag:b", "is:unknown"} {
+			_, err := parser.Parse(q)
+			assert.Error(t, err, q)
+		}
+	})
+}
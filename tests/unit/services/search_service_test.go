@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	browserconfig "github.com/felipesantos/anki-backend/core/domain/entities/browser_config"
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/note"
+	searchdomain "github.com/felipesantos/anki-backend/core/domain/services/search"
+	searchsvc "github.com/felipesantos/anki-backend/core/services/search"
+)
+
+func TestSearchService_SearchAdvanced(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+
+	withPage := func(expected searchdomain.PageRequest) interface{} {
+		return mock.MatchedBy(func(page searchdomain.PageRequest) bool { return page == expected })
+	}
+
+	t.Run("Default sort without browser config", func(t *testing.T) {
+		noteRepo := new(MockNoteRepository)
+		cardRepo := new(MockCardRepository)
+		configRepo := new(MockBrowserConfigRepository)
+		service := searchsvc.NewSearchService(noteRepo, cardRepo, configRepo)
+
+		n := &note.Note{}
+		configRepo.On("FindByUserID", ctx, userID).Return(nil, nil).Once()
+		noteRepo.On("FindByAdvancedSearch", ctx, userID, mock.Anything, withPage(searchdomain.PageRequest{Sort: searchdomain.DefaultSortOrder, Limit: 50})).
+			Return([]*note.Note{n}, searchdomain.PageInfo{Total: 120, NextCursor: "next"}, nil).Once()
+
+		result, err := service.SearchAdvanced(ctx, userID, "tag:a", "notes", searchdomain.PageRequest{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{n}, result.Data)
+		assert.Equal(t, 120, result.Total)
+		assert.Equal(t, "next", result.NextCursor)
+		noteRepo.AssertExpectations(t)
+		configRepo.AssertExpectations(t)
+	})
+
+	t.Run("Sort from browser config", func(t *testing.T) {
+		noteRepo := new(MockNoteRepository)
+		cardRepo := new(MockCardRepository)
+		configRepo := new(MockBrowserConfigRepository)
+		service := searchsvc.NewSearchService(noteRepo, cardRepo, configRepo)
+
+		column := "due"
+		cfg, _ := browserconfig.NewBuilder().WithUserID(userID).WithSortColumn(&column).WithSortDirection("desc").Build()
+		configRepo.On("FindByUserID", ctx, userID).Return(cfg, nil).Once()
+
+		expected := searchdomain.PageRequest{
+			Sort:   searchdomain.SortOrder{Column: "due", Direction: "desc"},
+			Limit:  20,
+			Cursor: "abc",
+		}
+		cardRepo.On("FindByAdvancedSearch", ctx, userID, mock.Anything, withPage(expected)).
+			Return([]*card.Card{}, searchdomain.PageInfo{Total: 0}, nil).Once()
+
+		result, err := service.SearchAdvanced(ctx, userID, "is:due", "cards", searchdomain.PageRequest{Limit: 20, Cursor: "abc"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.Data)
+		cardRepo.AssertExpectations(t)
+	})
+
+	t.Run("Requested sort overrides the browser config", func(t *testing.T) {
+		noteRepo := new(MockNoteRepository)
+		cardRepo := new(MockCardRepository)
+		configRepo := new(MockBrowserConfigRepository)
+		service := searchsvc.NewSearchService(noteRepo, cardRepo, configRepo)
+
+		expected := searchdomain.PageRequest{Sort: searchdomain.SortOrder{Column: "ease", Direction: "asc"}, Limit: 50}
+		cardRepo.On("FindByAdvancedSearch", ctx, userID, mock.Anything, withPage(expected)).
+			Return([]*card.Card{}, searchdomain.PageInfo{}, nil).Once()
+
+		_, err := service.SearchAdvanced(ctx, userID, "is:due", "cards", searchdomain.PageRequest{Sort: searchdomain.SortOrder{Column: "ease"}})
+
+		assert.NoError(t, err)
+		cardRepo.AssertExpectations(t)
+		configRepo.AssertNotCalled(t, "FindByUserID", ctx, userID)
+	})
+
+	t.Run("Invalid sort column", func(t *testing.T) {
+		service := searchsvc.NewSearchService(new(MockNoteRepository), new(MockCardRepository), new(MockBrowserConfigRepository))
+
+		_, err := service.SearchAdvanced(ctx, userID, "tag:a", "notes", searchdomain.PageRequest{Sort: searchdomain.SortOrder{Column: "question"}})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid sort column")
+	})
+}
@@ -20,6 +20,7 @@ import (
 	notetype "github.com/felipesantos/anki-backend/core/domain/entities/note_type"
 	"github.com/felipesantos/anki-backend/core/domain/entities/profile"
 	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
+	"github.com/felipesantos/anki-backend/core/domain/services/scheduler"
 	"github.com/felipesantos/anki-backend/core/domain/services/search"
 	"github.com/felipesantos/anki-backend/core/domain/entities/review"
 	savedsearch "github.com/felipesantos/anki-backend/core/domain/entities/saved_search"
@@ -84,6 +85,7 @@ func (m *MockDeckRepository) FindByParentID(ctx context.Context, uid, pid int64)
 func (m *MockDeckRepository) Save(ctx context.Context, uid int64, d *deck.Deck) error { return m.Called(ctx, uid, d).Error(0) }
 func (m *MockDeckRepository) Update(ctx context.Context, uid, id int64, d *deck.Deck) error { return m.Called(ctx, uid, id, d).Error(0) }
 func (m *MockDeckRepository) Delete(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockDeckRepository) Restore(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
 func (m *MockDeckRepository) Exists(ctx context.Context, uid int64, n string, pid *int64) (bool, error) {
 	args := m.Called(ctx, uid, n, pid)
 	return args.Bool(0), args.Error(1)
@@ -91,6 +93,12 @@ func (m *MockDeckRepository) Exists(ctx context.Context, uid int64, n string, pi
 func (m *MockDeckRepository) GetStats(ctx context.Context, uid, did int64) (*deck.DeckStats, error) {
 	args := m.Called(ctx, uid, did); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*deck.DeckStats), args.Error(1)
 }
+func (m *MockDeckRepository) ExtendLimits(ctx context.Context, uid, did int64, day time.Time, n, r int) (*deck.LimitExtension, error) {
+	args := m.Called(ctx, uid, did, day, n, r); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*deck.LimitExtension), args.Error(1)
+}
+func (m *MockDeckRepository) FindLimitExtension(ctx context.Context, uid, did int64, day time.Time) (*deck.LimitExtension, error) {
+	args := m.Called(ctx, uid, did, day); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*deck.LimitExtension), args.Error(1)
+}
 
 // MockNoteTypeRepository
 type MockNoteTypeRepository struct{ mock.Mock }
@@ -129,6 +137,7 @@ func (m *MockNoteRepository) FindByUserID(ctx context.Context, uid int64, l, o i
 }
 func (m *MockNoteRepository) Update(ctx context.Context, uid, id int64, n *note.Note) error { return m.Called(ctx, uid, id, n).Error(0) }
 func (m *MockNoteRepository) Delete(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockNoteRepository) Restore(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
 func (m *MockNoteRepository) Exists(ctx context.Context, uid, id int64) (bool, error) {
 	args := m.Called(ctx, uid, id)
 	return args.Bool(0), args.Error(1)
@@ -154,8 +163,8 @@ func (m *MockNoteRepository) FindDuplicatesByField(ctx context.Context, uid int6
 func (m *MockNoteRepository) FindDuplicatesByGUID(ctx context.Context, uid int64) ([]*note.DuplicateGroup, error) {
 	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*note.DuplicateGroup), args.Error(1)
 }
-func (m *MockNoteRepository) FindByAdvancedSearch(ctx context.Context, uid int64, q *search.SearchQuery, l, o int) ([]*note.Note, error) {
-	args := m.Called(ctx, uid, q, l, o); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*note.Note), args.Error(1)
+func (m *MockNoteRepository) FindByAdvancedSearch(ctx context.Context, uid int64, q *search.SearchQuery, p search.PageRequest) ([]*note.Note, search.PageInfo, error) {
+	args := m.Called(ctx, uid, q, p); if args.Get(0) == nil { return nil, search.PageInfo{}, args.Error(2) }; return args.Get(0).([]*note.Note), args.Get(1).(search.PageInfo), args.Error(2)
 }
 
 // MockCardRepository
@@ -169,6 +178,7 @@ func (m *MockCardRepository) FindByDeckID(ctx context.Context, uid, did int64) (
 }
 func (m *MockCardRepository) Update(ctx context.Context, uid, id int64, c *card.Card) error { return m.Called(ctx, uid, id, c).Error(0) }
 func (m *MockCardRepository) Delete(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockCardRepository) Restore(ctx context.Context, uid int64, c *card.Card) error { return m.Called(ctx, uid, c).Error(0) }
 func (m *MockCardRepository) Exists(ctx context.Context, uid, id int64) (bool, error) {
 	args := m.Called(ctx, uid, id)
 	return args.Bool(0), args.Error(1)
@@ -195,8 +205,8 @@ func (m *MockCardRepository) MoveCards(ctx context.Context, uid, src, dst int64)
 func (m *MockCardRepository) DeleteByDeckRecursive(ctx context.Context, uid, did int64) error {
 	args := m.Called(ctx, uid, did); return args.Error(0)
 }
-func (m *MockCardRepository) FindByAdvancedSearch(ctx context.Context, uid int64, q *search.SearchQuery) ([]*card.Card, error) {
-	args := m.Called(ctx, uid, q); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*card.Card), args.Error(1)
+func (m *MockCardRepository) FindByAdvancedSearch(ctx context.Context, uid int64, q *search.SearchQuery, p search.PageRequest) ([]*card.Card, search.PageInfo, error) {
+	args := m.Called(ctx, uid, q, p); if args.Get(0) == nil { return nil, search.PageInfo{}, args.Error(2) }; return args.Get(0).([]*card.Card), args.Get(1).(search.PageInfo), args.Error(2)
 }
 func (m *MockCardRepository) FindAll(ctx context.Context, uid int64, f card.CardFilters) ([]*card.Card, int, error) {
 	args := m.Called(ctx, uid, f)
@@ -214,6 +224,16 @@ func (m *MockCardRepository) FindLeeches(ctx context.Context, uid int64, limit,
 	}
 	return cards, args.Int(1), args.Error(2)
 }
+func (m *MockCardRepository) FindQueueCards(ctx context.Context, uid int64, f card.QueueFilters) ([]*card.Card, error) {
+	args := m.Called(ctx, uid, f); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*card.Card), args.Error(1)
+}
+func (m *MockCardRepository) FindByFilteredDeckID(ctx context.Context, uid, fdID int64) ([]*card.Card, error) {
+	args := m.Called(ctx, uid, fdID); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*card.Card), args.Error(1)
+}
+func (m *MockCardRepository) UnburyBeforeDayStart(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
 func (m *MockCardRepository) UpdatePositions(ctx context.Context, uid int64, cids []int64, start, step int, shift bool) error {
 	return m.Called(ctx, uid, cids, start, step, shift).Error(0)
 }
@@ -236,6 +256,13 @@ func (m *MockReviewRepository) FindByCardID(ctx context.Context, uid, cid int64)
 func (m *MockReviewRepository) FindByDateRange(ctx context.Context, uid int64, s, e time.Time) ([]*review.Review, error) {
 	args := m.Called(ctx, uid, s, e); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*review.Review), args.Error(1)
 }
+func (m *MockReviewRepository) FindHistory(ctx context.Context, uid int64, dids []int64) ([]*review.Review, error) {
+	args := m.Called(ctx, uid, dids); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*review.Review), args.Error(1)
+}
+func (m *MockReviewRepository) CountStudiedSince(ctx context.Context, uid int64, dids []int64, since time.Time) (int, int, error) {
+	args := m.Called(ctx, uid, dids, since)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
 func (m *MockReviewRepository) DeleteByCardID(ctx context.Context, uid int64, cid int64) error {
 	return m.Called(ctx, uid, cid).Error(0)
 }
@@ -441,6 +468,13 @@ func (m *MockMediaRepository) FindByHash(ctx context.Context, uid int64, h strin
 func (m *MockMediaRepository) FindByFilename(ctx context.Context, uid int64, f string) (*media.Media, error) {
 	args := m.Called(ctx, uid, f); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*media.Media), args.Error(1)
 }
+func (m *MockMediaRepository) TotalSize(ctx context.Context, uid int64) (int64, error) {
+	args := m.Called(ctx, uid)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockMediaRepository) FindLinkedIDs(ctx context.Context, uid int64) ([]int64, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
 
 // MockSyncMetaRepository
 type MockSyncMetaRepository struct{ mock.Mock }
@@ -482,6 +516,9 @@ func (m *MockDeletionLogRepository) Exists(ctx context.Context, uid, id int64) (
 func (m *MockDeletionLogRepository) FindRecent(ctx context.Context, uid int64, limit, days int) ([]*deletionlog.DeletionLog, error) {
 	args := m.Called(ctx, uid, limit, days); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*deletionlog.DeletionLog), args.Error(1)
 }
+func (m *MockDeletionLogRepository) FindByMinUSN(ctx context.Context, uid int64, usn int) ([]*deletionlog.DeletionLog, error) {
+	args := m.Called(ctx, uid, usn); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*deletionlog.DeletionLog), args.Error(1)
+}
 
 // MockUndoHistoryRepository
 type MockUndoHistoryRepository struct{ mock.Mock }
@@ -497,11 +534,42 @@ func (m *MockUndoHistoryRepository) Delete(ctx context.Context, uid, id int64) e
 func (m *MockUndoHistoryRepository) FindByUserID(ctx context.Context, uid int64) ([]*undohistory.UndoHistory, error) {
 	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*undohistory.UndoHistory), args.Error(1)
 }
+func (m *MockUndoHistoryRepository) FindLatestByType(ctx context.Context, uid int64, t string) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, uid, t); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
+func (m *MockUndoHistoryRepository) FindLatestApplied(ctx context.Context, uid int64, t []string) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, uid, t); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
+func (m *MockUndoHistoryRepository) FindLatestUndone(ctx context.Context, uid int64, t []string) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, uid, t); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
+func (m *MockUndoHistoryRepository) DeleteUndone(ctx context.Context, uid int64) error { return m.Called(ctx, uid).Error(0) }
 func (m *MockUndoHistoryRepository) Exists(ctx context.Context, uid, id int64) (bool, error) {
 	args := m.Called(ctx, uid, id)
 	return args.Bool(0), args.Error(1)
 }
 
+// MockDatabaseCheckRepository
+type MockDatabaseCheckRepository struct{ mock.Mock }
+func (m *MockDatabaseCheckRepository) DeleteCardsOfDeletedNotes(ctx context.Context, uid int64) ([]int64, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+func (m *MockDatabaseCheckRepository) MoveCardsFromDeletedDecks(ctx context.Context, uid, did int64) ([]int64, error) {
+	args := m.Called(ctx, uid, did); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+func (m *MockDatabaseCheckRepository) ReturnCardsWithMissingHomeDeck(ctx context.Context, uid, did int64) ([]int64, error) {
+	args := m.Called(ctx, uid, did); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+func (m *MockDatabaseCheckRepository) DeleteCardsWithInvalidOrdinal(ctx context.Context, uid, ntid int64, c int) ([]int64, error) {
+	args := m.Called(ctx, uid, ntid, c); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+func (m *MockDatabaseCheckRepository) FixInvalidScheduling(ctx context.Context, uid int64, now time.Time) ([]int64, error) {
+	args := m.Called(ctx, uid, now); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+func (m *MockDatabaseCheckRepository) FindDuplicateGUIDNotes(ctx context.Context, uid int64) ([]int64, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+
 // MockCheckDatabaseLogRepository
 type MockCheckDatabaseLogRepository struct{ mock.Mock }
 func (m *MockCheckDatabaseLogRepository) Save(ctx context.Context, uid int64, c *checkdatabaselog.CheckDatabaseLog) error { return m.Called(ctx, uid, c).Error(0) }
@@ -555,6 +623,24 @@ func (m *MockFilteredDeckRepository) Exists(ctx context.Context, uid, id int64)
 	args := m.Called(ctx, uid, id)
 	return args.Bool(0), args.Error(1)
 }
+func (m *MockFilteredDeckRepository) FindByCardID(ctx context.Context, uid, cardID int64) (*filtereddeck.FilteredDeck, error) {
+	args := m.Called(ctx, uid, cardID); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*filtereddeck.FilteredDeck), args.Error(1)
+}
+func (m *MockFilteredDeckRepository) MoveCardsIn(ctx context.Context, uid, id int64, q *search.SearchQuery, orderBy string, limit int) (int, error) {
+	args := m.Called(ctx, uid, id, q, orderBy, limit)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockFilteredDeckRepository) ReturnCards(ctx context.Context, uid, id int64) (int, error) {
+	args := m.Called(ctx, uid, id)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockFilteredDeckRepository) ReturnCard(ctx context.Context, uid, cardID int64) (int, error) {
+	args := m.Called(ctx, uid, cardID)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockFilteredDeckRepository) RestoreCard(ctx context.Context, uid, id, cardID int64, position int) error {
+	return m.Called(ctx, uid, id, cardID, position).Error(0)
+}
 
 // MockBackupService
 type MockBackupService struct{ mock.Mock }
@@ -569,6 +655,30 @@ func (m *MockBackupService) FindByUserID(ctx context.Context, uid int64) ([]*bac
 }
 func (m *MockBackupService) Delete(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
 
+// MockUndoHistoryService
+type MockUndoHistoryService struct{ mock.Mock }
+func (m *MockUndoHistoryService) Create(ctx context.Context, uid int64, t, d string) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, uid, t, d); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
+func (m *MockUndoHistoryService) FindLatest(ctx context.Context, uid int64, l int) ([]*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, uid, l); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*undohistory.UndoHistory), args.Error(1)
+}
+func (m *MockUndoHistoryService) Delete(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockUndoHistoryService) Record(ctx context.Context, uid int64, t string, cs *undohistory.ChangeSet) error { return m.Called(ctx, uid, t, cs).Error(0) }
+func (m *MockUndoHistoryService) Undo(ctx context.Context, uid int64) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
+func (m *MockUndoHistoryService) Redo(ctx context.Context, uid int64) (*undohistory.UndoHistory, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*undohistory.UndoHistory), args.Error(1)
+}
+
+// newRecordingUndoService returns an undo service mock accepting any recorded operation
+func newRecordingUndoService() *MockUndoHistoryService {
+	m := new(MockUndoHistoryService)
+	m.On("Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return m
+}
+
 // MockNoteService
 type MockNoteService struct{ mock.Mock }
 func (m *MockNoteService) Create(ctx context.Context, uid int64, ntid, did int64, fj string, tags []string) (*note.Note, error) {
@@ -595,6 +705,9 @@ func (m *MockNoteService) FindDuplicates(ctx context.Context, uid int64, ntid *i
 func (m *MockNoteService) FindDuplicatesByGUID(ctx context.Context, uid int64) (*note.DuplicateResult, error) {
 	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*note.DuplicateResult), args.Error(1)
 }
+func (m *MockNoteService) FindReplace(ctx context.Context, uid int64, fr note.FindReplace) (int, error) {
+	args := m.Called(ctx, uid, fr); return args.Int(0), args.Error(1)
+}
 
 // MockDeckService
 type MockDeckService struct{ mock.Mock }
@@ -646,6 +759,9 @@ type MockReviewService struct{ mock.Mock }
 func (m *MockReviewService) Create(ctx context.Context, uid, cid int64, r, tms int) (*review.Review, error) {
 	args := m.Called(ctx, uid, cid, r, tms); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*review.Review), args.Error(1)
 }
+func (m *MockReviewService) CreateAt(ctx context.Context, uid, cid int64, r, tms int, at time.Time) (*review.Review, error) {
+	args := m.Called(ctx, uid, cid, r, tms, at); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*review.Review), args.Error(1)
+}
 func (m *MockReviewService) FindByID(ctx context.Context, uid, id int64) (*review.Review, error) {
 	args := m.Called(ctx, uid, id); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*review.Review), args.Error(1)
 }
@@ -655,6 +771,60 @@ func (m *MockReviewService) FindByCardID(ctx context.Context, uid, cid int64) ([
 func (m *MockReviewService) DeleteByCardID(ctx context.Context, uid, cid int64) error {
 	return m.Called(ctx, uid, cid).Error(0)
 }
+func (m *MockReviewService) LogManual(ctx context.Context, uid int64, c *card.Card) error {
+	return m.Called(ctx, uid, c).Error(0)
+}
+func (m *MockReviewService) Undo(ctx context.Context, uid int64) (*card.Card, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*card.Card), args.Error(1)
+}
+func (m *MockReviewService) PreviewIntervals(ctx context.Context, uid, cid int64) ([]scheduler.IntervalPreview, error) {
+	args := m.Called(ctx, uid, cid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]scheduler.IntervalPreview), args.Error(1)
+}
+
+// MockCardService
+type MockCardService struct{ mock.Mock }
+func (m *MockCardService) FindByID(ctx context.Context, uid, id int64) (*card.Card, error) {
+	args := m.Called(ctx, uid, id); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*card.Card), args.Error(1)
+}
+func (m *MockCardService) FindByDeckID(ctx context.Context, uid, did int64) ([]*card.Card, error) {
+	args := m.Called(ctx, uid, did); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*card.Card), args.Error(1)
+}
+func (m *MockCardService) Update(ctx context.Context, uid int64, c *card.Card) error { return m.Called(ctx, uid, c).Error(0) }
+func (m *MockCardService) Delete(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockCardService) Suspend(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockCardService) Unsuspend(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockCardService) Bury(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockCardService) Unbury(ctx context.Context, uid, id int64) error { return m.Called(ctx, uid, id).Error(0) }
+func (m *MockCardService) SetFlag(ctx context.Context, uid, id int64, f int) error { return m.Called(ctx, uid, id, f).Error(0) }
+func (m *MockCardService) FindDueCards(ctx context.Context, uid, did int64) ([]*card.Card, error) {
+	args := m.Called(ctx, uid, did); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*card.Card), args.Error(1)
+}
+func (m *MockCardService) CountByDeckAndState(ctx context.Context, uid, did int64, s string) (int, error) {
+	args := m.Called(ctx, uid, did, s); return args.Int(0), args.Error(1)
+}
+func (m *MockCardService) FindAll(ctx context.Context, uid int64, f card.CardFilters) ([]*card.Card, int, error) {
+	args := m.Called(ctx, uid, f); if args.Get(0) == nil { return nil, args.Int(1), args.Error(2) }; return args.Get(0).([]*card.Card), args.Int(1), args.Error(2)
+}
+func (m *MockCardService) GetInfo(ctx context.Context, uid, cid int64) (*card.CardInfo, error) {
+	args := m.Called(ctx, uid, cid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*card.CardInfo), args.Error(1)
+}
+func (m *MockCardService) Reset(ctx context.Context, uid, id int64, t string) error { return m.Called(ctx, uid, id, t).Error(0) }
+func (m *MockCardService) SetDueDate(ctx context.Context, uid, id int64, due int64) error { return m.Called(ctx, uid, id, due).Error(0) }
+func (m *MockCardService) FindLeeches(ctx context.Context, uid int64, l, o int) ([]*card.Card, int, error) {
+	args := m.Called(ctx, uid, l, o); if args.Get(0) == nil { return nil, args.Int(1), args.Error(2) }; return args.Get(0).([]*card.Card), args.Int(1), args.Error(2)
+}
+func (m *MockCardService) Reposition(ctx context.Context, uid int64, ids []int64, st, sp int, sh bool) error {
+	return m.Called(ctx, uid, ids, st, sp, sh).Error(0)
+}
+func (m *MockCardService) GetPosition(ctx context.Context, uid, cid int64) (int, error) {
+	args := m.Called(ctx, uid, cid); return args.Int(0), args.Error(1)
+}
+func (m *MockCardService) FindEmptyCards(ctx context.Context, uid int64) ([]*card.Card, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*card.Card), args.Error(1)
+}
+func (m *MockCardService) CleanupEmptyCards(ctx context.Context, uid int64) (int, error) {
+	args := m.Called(ctx, uid); return args.Int(0), args.Error(1)
+}
 
 // MockExportService
 type MockExportService struct{ mock.Mock }
@@ -720,3 +890,40 @@ func (m *MockTemplateRenderer) RenderBack(tj string, cti int, f map[string]strin
 	args := m.Called(tj, cti, f)
 	return args.String(0), args.Error(1)
 }
+
+// MockAnkiSyncRepository
+type MockAnkiSyncRepository struct{ mock.Mock }
+func (m *MockAnkiSyncRepository) FindState(ctx context.Context, uid int64) (*secondary.AnkiSyncState, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*secondary.AnkiSyncState), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) UpdateState(ctx context.Context, uid int64, s *secondary.AnkiSyncState) error { return m.Called(ctx, uid, s).Error(0) }
+func (m *MockAnkiSyncRepository) AdvanceUSN(ctx context.Context, uid int64) (int, error) {
+	args := m.Called(ctx, uid)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) FindAnkiIDs(ctx context.Context, uid int64, ot string, ids []int64) ([]*secondary.AnkiSyncID, error) {
+	args := m.Called(ctx, uid, ot, ids); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*secondary.AnkiSyncID), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) FindObjectIDs(ctx context.Context, uid int64, ot string, ids []int64) ([]*secondary.AnkiSyncID, error) {
+	args := m.Called(ctx, uid, ot, ids); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*secondary.AnkiSyncID), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) SaveIDs(ctx context.Context, uid int64, ids []*secondary.AnkiSyncID) error { return m.Called(ctx, uid, ids).Error(0) }
+func (m *MockAnkiSyncRepository) FindChangedIDs(ctx context.Context, uid int64, ot string, usn int, after int64, l int) ([]int64, error) {
+	args := m.Called(ctx, uid, ot, usn, after, l); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) FindDeletedIDs(ctx context.Context, uid int64, ot string, usn int) ([]int64, error) {
+	args := m.Called(ctx, uid, ot, usn); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]int64), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) FindMediaChanges(ctx context.Context, uid int64, after, max, l int) ([]*secondary.AnkiMediaChange, error) {
+	args := m.Called(ctx, uid, after, max, l); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*secondary.AnkiMediaChange), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) FindCards(ctx context.Context, uid int64, ids []int64) ([]*card.Card, error) {
+	args := m.Called(ctx, uid, ids); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*card.Card), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) FindReviews(ctx context.Context, uid int64, ids []int64) ([]*review.Review, error) {
+	args := m.Called(ctx, uid, ids); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).([]*review.Review), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) Count(ctx context.Context, uid int64) (*secondary.AnkiSyncCounts, error) {
+	args := m.Called(ctx, uid); if args.Get(0) == nil { return nil, args.Error(1) }; return args.Get(0).(*secondary.AnkiSyncCounts), args.Error(1)
+}
+func (m *MockAnkiSyncRepository) DeleteCollection(ctx context.Context, uid int64) error { return m.Called(ctx, uid).Error(0) }
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/felipesantos/anki-backend/core/domain/entities/card"
+	"github.com/felipesantos/anki-backend/core/domain/entities/deck"
+	filtereddeck "github.com/felipesantos/anki-backend/core/domain/entities/filtered_deck"
+	"github.com/felipesantos/anki-backend/core/domain/valueobjects"
+	studySvc "github.com/felipesantos/anki-backend/core/services/study"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// queueState matches the queue filters requesting cards in the given state
+func queueState(state valueobjects.CardState) interface{} {
+	return mock.MatchedBy(func(f card.QueueFilters) bool {
+		return len(f.States) > 0 && f.States[0] == state
+	})
+}
+
+// queueDeck matches the queue filters requesting cards in the given state of a single deck
+func queueDeck(deckID int64, state valueobjects.CardState) interface{} {
+	return mock.MatchedBy(func(f card.QueueFilters) bool {
+		return len(f.DeckIDs) == 1 && f.DeckIDs[0] == deckID && f.States[0] == state
+	})
+}
+
+func TestStudyService_Next(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	deckID := int64(10)
+	childID := int64(11)
+	deckIDs := []int64{deckID, childID}
+
+	newCard := func(id int64, state valueobjects.CardState, due time.Time, lastReview *time.Time) *card.Card {
+		c, err := card.NewBuilder().
+			WithID(id).
+			WithNoteID(id).
+			WithDeckID(deckID).
+			WithState(state).
+			WithDue(due.UnixMilli()).
+			WithLastReviewAt(lastReview).
+			Build()
+		require.NoError(t, err)
+		return c
+	}
+
+	var extension deck.LimitExtension
+	childOptionsJSON := ""
+	var childCards []*card.Card
+	setup := func(introduced, reviewed int) (*MockCardRepository, *MockReviewRepository, func() (*card.StudyQueue, error)) {
+		mockDeckRepo := new(MockDeckRepository)
+		mockCardRepo := new(MockCardRepository)
+		mockReviewRepo := new(MockReviewRepository)
+		mockPrefsRepo := new(MockUserPreferencesRepository)
+		service := studySvc.NewStudyService(mockDeckRepo, new(MockFilteredDeckRepository), mockCardRepo, mockReviewRepo, mockPrefsRepo)
+
+		root, _ := deck.NewBuilder().WithID(deckID).WithUserID(userID).WithName("Root").
+			WithOptionsJSON(`{"new_per_day": 5, "reviews_per_day": 10}`).Build()
+		child, _ := deck.NewBuilder().WithID(childID).WithUserID(userID).WithName("Root::Child").WithOptionsJSON(childOptionsJSON).Build()
+
+		mockDeckRepo.On("FindByID", ctx, userID, deckID).Return(root, nil)
+		mockDeckRepo.On("FindByParentID", ctx, userID, deckID).Return([]*deck.Deck{child}, nil)
+		mockDeckRepo.On("FindByParentID", ctx, userID, childID).Return([]*deck.Deck{}, nil)
+		mockPrefsRepo.On("FindByUserID", ctx, userID).Return(nil, nil)
+		mockReviewRepo.On("CountStudiedSince", ctx, userID, deckIDs, mock.Anything).Return(introduced, reviewed, nil)
+		mockDeckRepo.On("FindLimitExtension", ctx, userID, deckID, mock.Anything).Return(&extension, nil)
+		mockReviewRepo.On("CountStudiedSince", ctx, userID, []int64{childID}, mock.Anything).Return(0, 0, nil)
+		mockDeckRepo.On("FindLimitExtension", ctx, userID, childID, mock.Anything).Return(&deck.LimitExtension{}, nil)
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueDeck(childID, valueobjects.CardStateNew)).Return(childCards, nil).Maybe()
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueDeck(childID, valueobjects.CardStateReview)).Return([]*card.Card{}, nil).Maybe()
+
+		return mockCardRepo, mockReviewRepo, func() (*card.StudyQueue, error) {
+			return service.Next(ctx, userID, deckID)
+		}
+	}
+
+	t.Run("Learning card due now comes first", func(t *testing.T) {
+		now := time.Now()
+		mockCardRepo, _, next := setup(2, 4)
+
+		due := newCard(1, valueobjects.CardStateLearn, now.Add(-time.Minute), &now)
+		later := newCard(2, valueobjects.CardStateLearn, now.Add(2*time.Hour), &now)
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateLearn)).Return([]*card.Card{due, later}, nil).Once()
+		mockCardRepo.On("FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateReview && f.Limit == 6
+		})).Return([]*card.Card{newCard(3, valueobjects.CardStateReview, now, &now)}, nil).Once()
+		mockCardRepo.On("FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateNew && f.Limit == 3 && f.DueBefore == nil
+		})).Return([]*card.Card{newCard(4, valueobjects.CardStateNew, now, nil)}, nil).Once()
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), queue.Next.GetID())
+		assert.Equal(t, card.QueueTypeLearn, queue.Queue)
+		assert.Equal(t, 1, queue.LearnCount) // Beyond the learn ahead limit
+		assert.Equal(t, 1, queue.ReviewCount)
+		assert.Equal(t, 1, queue.NewCount)
+		mockCardRepo.AssertExpectations(t)
+	})
+
+	t.Run("New cards are spread among reviews", func(t *testing.T) {
+		now := time.Now()
+		reviews := []*card.Card{
+			newCard(1, valueobjects.CardStateReview, now, &now),
+			newCard(2, valueobjects.CardStateReview, now, &now),
+		}
+		news := []*card.Card{
+			newCard(3, valueobjects.CardStateNew, now, nil),
+			newCard(4, valueobjects.CardStateNew, now, nil),
+		}
+
+		for _, tc := range []struct {
+			introduced, reviewed int
+			expected             card.QueueType
+		}{
+			{0, 0, card.QueueTypeReview},
+			{1, 1, card.QueueTypeNew},
+			{1, 2, card.QueueTypeReview},
+		} {
+			mockCardRepo, _, next := setup(tc.introduced, tc.reviewed)
+			mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateLearn)).Return([]*card.Card{}, nil)
+			mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateReview)).Return(reviews, nil)
+			mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateNew)).Return(news, nil)
+
+			queue, err := next()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, queue.Queue, "introduced=%d reviewed=%d", tc.introduced, tc.reviewed)
+		}
+	})
+
+	t.Run("Daily limits reached", func(t *testing.T) {
+		now := time.Now()
+		ahead := newCard(1, valueobjects.CardStateRelearn, now.Add(10*time.Minute), &now)
+		mockCardRepo, _, next := setup(5, 10)
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateLearn)).Return([]*card.Card{ahead}, nil).Once()
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), queue.Next.GetID()) // Learn ahead once nothing else is left
+		assert.Equal(t, 0, queue.NewCount)
+		assert.Equal(t, 0, queue.ReviewCount)
+		mockCardRepo.AssertNumberOfCalls(t, "FindQueueCards", 1)
+	})
+
+	t.Run("Custom study extends the limits", func(t *testing.T) {
+		extension = deck.LimitExtension{NewCards: 3, Reviews: 5}
+		defer func() { extension = deck.LimitExtension{} }()
+
+		now := time.Now()
+		mockCardRepo, _, next := setup(5, 10)
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateLearn)).Return([]*card.Card{}, nil).Once()
+		mockCardRepo.On("FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateReview && f.Limit == 5
+		})).Return([]*card.Card{newCard(1, valueobjects.CardStateReview, now, &now)}, nil).Once()
+		mockCardRepo.On("FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateNew && f.Limit == 3
+		})).Return([]*card.Card{}, nil).Once()
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), queue.Next.GetID())
+		mockCardRepo.AssertExpectations(t)
+	})
+
+	t.Run("Subdecks keep to their own limits", func(t *testing.T) {
+		childOptionsJSON = `{"new_per_day": 1}`
+		now := time.Now()
+		childCards = []*card.Card{newCard(5, valueobjects.CardStateNew, now, nil)}
+		defer func() { childOptionsJSON, childCards = "", nil }()
+
+		mockCardRepo, _, next := setup(1, 0)
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateLearn)).Return([]*card.Card{}, nil).Once()
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateReview)).Return([]*card.Card{}, nil)
+		mockCardRepo.On("FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateNew && f.DeckIDs[0] == deckID && f.Limit == 4
+		})).Return([]*card.Card{newCard(3, valueobjects.CardStateNew, now, nil)}, nil).Once()
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Equal(t, 2, queue.NewCount)
+		assert.Equal(t, int64(3), queue.Next.GetID())
+		mockCardRepo.AssertCalled(t, "FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateNew && f.DeckIDs[0] == childID && f.Limit == 1
+		}))
+		mockCardRepo.AssertCalled(t, "FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateReview && f.DeckIDs[0] == childID && f.Limit == 10
+		}))
+	})
+
+	t.Run("Parent limits cap their subdecks", func(t *testing.T) {
+		now := time.Now()
+		mockCardRepo, _, next := setup(0, 8)
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateLearn)).Return([]*card.Card{}, nil).Once()
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateNew)).Return([]*card.Card{}, nil)
+		mockCardRepo.On("FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateReview && f.DeckIDs[0] == deckID && f.Limit == 2
+		})).Return([]*card.Card{newCard(1, valueobjects.CardStateReview, now, &now)}, nil).Once()
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Equal(t, 1, queue.ReviewCount)
+		mockCardRepo.AssertCalled(t, "FindQueueCards", ctx, userID, mock.MatchedBy(func(f card.QueueFilters) bool {
+			return f.States[0] == valueobjects.CardStateReview && f.DeckIDs[0] == childID && f.Limit == 1
+		}))
+	})
+
+	t.Run("Nothing left", func(t *testing.T) {
+		mockCardRepo, _, next := setup(5, 10)
+		mockCardRepo.On("FindQueueCards", ctx, userID, queueState(valueobjects.CardStateLearn)).Return([]*card.Card{}, nil).Once()
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Nil(t, queue.Next)
+		assert.Empty(t, queue.Queue)
+	})
+
+	t.Run("Deck not found", func(t *testing.T) {
+		mockDeckRepo := new(MockDeckRepository)
+		service := studySvc.NewStudyService(mockDeckRepo, new(MockFilteredDeckRepository), new(MockCardRepository), new(MockReviewRepository), new(MockUserPreferencesRepository))
+		mockDeckRepo.On("FindByID", ctx, userID, deckID).Return(nil, nil).Once()
+
+		_, err := service.Next(ctx, userID, deckID)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "deck not found")
+	})
+}
+
+func TestStudyService_NextFiltered(t *testing.T) {
+	ctx := context.Background()
+	userID := int64(1)
+	filteredDeckID := int64(7)
+	now := time.Now()
+	fd, _ := filtereddeck.NewBuilder().WithID(filteredDeckID).WithUserID(userID).WithName("Cram").WithLimitCards(100).Build()
+
+	newCard := func(id int64, state valueobjects.CardState, due time.Time, suspended bool) *card.Card {
+		c, err := card.NewBuilder().
+			WithID(id).
+			WithNoteID(id).
+			WithDeckID(10).
+			WithState(state).
+			WithDue(due.UnixMilli()).
+			WithLastReviewAt(&now).
+			WithSuspended(suspended).
+			Build()
+		require.NoError(t, err)
+		return c
+	}
+
+	// The deck and review repositories have no expectations: the home decks' limits are never looked up
+	setup := func(cards []*card.Card) func() (*card.StudyQueue, error) {
+		mockFilteredDeckRepo := new(MockFilteredDeckRepository)
+		mockCardRepo := new(MockCardRepository)
+		mockPrefsRepo := new(MockUserPreferencesRepository)
+		service := studySvc.NewStudyService(new(MockDeckRepository), mockFilteredDeckRepo, mockCardRepo, new(MockReviewRepository), mockPrefsRepo)
+
+		mockFilteredDeckRepo.On("FindByID", ctx, userID, filteredDeckID).Return(fd, nil)
+		mockCardRepo.On("FindByFilteredDeckID", ctx, userID, filteredDeckID).Return(cards, nil)
+		mockPrefsRepo.On("FindByUserID", ctx, userID).Return(nil, nil)
+
+		return func() (*card.StudyQueue, error) {
+			return service.NextFiltered(ctx, userID, filteredDeckID)
+		}
+	}
+
+	t.Run("Cards come in the deck's order without daily limits", func(t *testing.T) {
+		next := setup([]*card.Card{
+			newCard(1, valueobjects.CardStateReview, now.Add(48*time.Hour), false),
+			newCard(2, valueobjects.CardStateNew, now, false),
+			newCard(3, valueobjects.CardStateNew, now, true),
+			newCard(4, valueobjects.CardStateReview, now.Add(-time.Hour), false),
+		})
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), queue.Next.GetID(), "cards not yet due are studied ahead")
+		assert.Equal(t, card.QueueTypeReview, queue.Queue)
+		assert.Equal(t, 1, queue.NewCount)
+		assert.Equal(t, 2, queue.ReviewCount)
+		assert.Equal(t, 0, queue.LearnCount)
+	})
+
+	t.Run("Learning card due now comes first", func(t *testing.T) {
+		next := setup([]*card.Card{
+			newCard(1, valueobjects.CardStateNew, now, false),
+			newCard(2, valueobjects.CardStateLearn, now.Add(2*time.Hour), false),
+			newCard(3, valueobjects.CardStateRelearn, now.Add(-time.Minute), false),
+		})
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), queue.Next.GetID())
+		assert.Equal(t, card.QueueTypeLearn, queue.Queue)
+		assert.Equal(t, 1, queue.LearnCount) // Beyond the learn ahead limit
+		assert.Equal(t, 1, queue.NewCount)
+	})
+
+	t.Run("Empty deck", func(t *testing.T) {
+		next := setup([]*card.Card{})
+
+		queue, err := next()
+		require.NoError(t, err)
+		assert.Nil(t, queue.Next)
+		assert.Empty(t, queue.Queue)
+	})
+
+	t.Run("Filtered deck not found", func(t *testing.T) {
+		mockFilteredDeckRepo := new(MockFilteredDeckRepository)
+		service := studySvc.NewStudyService(new(MockDeckRepository), mockFilteredDeckRepo, new(MockCardRepository), new(MockReviewRepository), new(MockUserPreferencesRepository))
+		mockFilteredDeckRepo.On("FindByID", ctx, userID, filteredDeckID).Return(nil, nil).Once()
+
+		_, err := service.NextFiltered(ctx, userID, filteredDeckID)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "filtered deck not found")
+	})
+}
+
+func TestStudyService_UnburyAtDayRollover(t *testing.T) {
+	mockCardRepo := new(MockCardRepository)
+	service := studySvc.NewStudyService(new(MockDeckRepository), new(MockFilteredDeckRepository), mockCardRepo, new(MockReviewRepository), new(MockUserPreferencesRepository))
+	ctx := context.Background()
+
+	mockCardRepo.On("UnburyBeforeDayStart", ctx, mock.AnythingOfType("time.Time")).Return(int64(3), nil).Once()
+
+	count, err := service.UnburyAtDayRollover(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	mockCardRepo.AssertExpectations(t)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	syncmeta "github.com/felipesantos/anki-backend/core/domain/entities/sync_meta"
+	"github.com/felipesantos/anki-backend/core/interfaces/secondary"
 	syncSvc "github.com/felipesantos/anki-backend/core/services/sync"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -12,7 +13,7 @@ import (
 
 func TestSyncMetaService_FindByUserID(t *testing.T) {
 	mockRepo := new(MockSyncMetaRepository)
-	service := syncSvc.NewSyncMetaService(mockRepo)
+	service := syncSvc.NewSyncMetaService(mockRepo, new(MockAnkiSyncRepository))
 	ctx := context.Background()
 	userID := int64(1)
 
@@ -40,12 +41,14 @@ func TestSyncMetaService_FindByUserID(t *testing.T) {
 
 func TestSyncMetaService_Update(t *testing.T) {
 	mockRepo := new(MockSyncMetaRepository)
-	service := syncSvc.NewSyncMetaService(mockRepo)
+	mockSyncRepo := new(MockAnkiSyncRepository)
+	service := syncSvc.NewSyncMetaService(mockRepo, mockSyncRepo)
 	ctx := context.Background()
 	userID := int64(1)
 
 	t.Run("Create New", func(t *testing.T) {
-		mockRepo.On("FindByUserID", ctx, userID).Return([]*syncmeta.SyncMeta{}, nil).Once()
+		mockSyncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 5}, nil).Once()
+		mockRepo.On("FindByClientID", ctx, userID, "client1").Return(nil, nil).Once()
 		mockRepo.On("Save", ctx, userID, mock.Anything).Return(nil).Once()
 
 		result, err := service.Update(ctx, userID, "client1", 5)
@@ -55,4 +58,27 @@ func TestSyncMetaService_Update(t *testing.T) {
 		assert.Equal(t, "client1", result.GetClientID())
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Update Existing Client", func(t *testing.T) {
+		meta, _ := syncmeta.NewBuilder().WithID(3).WithUserID(userID).WithClientID("client2").WithLastSyncUSN(2).Build()
+		mockSyncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 5}, nil).Once()
+		mockRepo.On("FindByClientID", ctx, userID, "client2").Return(meta, nil).Once()
+		mockRepo.On("Update", ctx, userID, int64(3), meta).Return(nil).Once()
+
+		result, err := service.Update(ctx, userID, "client2", 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), result.GetLastSyncUSN())
+		assert.Equal(t, "client2", result.GetClientID())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("USN Ahead Of Server", func(t *testing.T) {
+		mockSyncRepo.On("FindState", ctx, userID).Return(&secondary.AnkiSyncState{USN: 5}, nil).Once()
+
+		result, err := service.Update(ctx, userID, "client1", 6)
+
+		assert.ErrorIs(t, err, syncSvc.ErrInvalidSyncUSN)
+		assert.Nil(t, result)
+	})
 }